@@ -0,0 +1,104 @@
+// Package dbtest runs tests against a real Postgres database, for the
+// behaviour that lives in the queries. The database is the one
+// TEST_DATABASE_URL points at, it is migrated to the latest schema and
+// emptied before each test. Tests using it are skipped when the variable is
+// not set.
+package dbtest
+
+import (
+	"database/sql"
+	"errors"
+	"os"
+	"path/filepath"
+	"runtime"
+	"sync"
+	"testing"
+
+	"github.com/golang-migrate/migrate/v4"
+	_ "github.com/golang-migrate/migrate/v4/database/postgres"
+	_ "github.com/golang-migrate/migrate/v4/source/file"
+	_ "github.com/lib/pq"
+)
+
+var migrateOnce sync.Once
+
+// Open returns a connection to the test database with every table empty.
+// The database is shared by the packages under test, so they have to be run
+// one at a time, with go test -p 1.
+func Open(t *testing.T) *sql.DB {
+	t.Helper()
+	url := os.Getenv("TEST_DATABASE_URL")
+	if url == "" {
+		t.Skip("TEST_DATABASE_URL is not set")
+	}
+
+	var migrateErr error
+	migrateOnce.Do(func() {
+		migrateErr = migrateUp(url)
+	})
+	if migrateErr != nil {
+		t.Fatalf("migrate test database: %v", migrateErr)
+	}
+
+	conn, err := sql.Open("postgres", url)
+	if err != nil {
+		t.Fatalf("open test database: %v", err)
+	}
+	t.Cleanup(func() { conn.Close() })
+
+	if _, err := conn.Exec(truncateAll); err != nil {
+		t.Fatalf("empty test database: %v", err)
+	}
+	return conn
+}
+
+func migrateUp(url string) error {
+	// the migrations are found from this file, tests run in their own
+	// package directory
+	_, file, _, _ := runtime.Caller(0)
+	dir := filepath.Join(filepath.Dir(file), "..", "migrations")
+
+	m, err := migrate.New("file://"+filepath.ToSlash(dir), url)
+	if err != nil {
+		return err
+	}
+	defer m.Close()
+	if err := m.Up(); err != nil && !errors.Is(err, migrate.ErrNoChange) {
+		return err
+	}
+	return nil
+}
+
+// truncateAll empties every table but the migration version, the sample rows
+// the migrations insert included, and restarts the ids.
+const truncateAll = `
+DO $$
+DECLARE
+    tables TEXT;
+BEGIN
+    SELECT string_agg(quote_ident(tablename), ', ') INTO tables
+    FROM pg_tables
+    WHERE schemaname = current_schema() AND tablename <> 'schema_migrations';
+    IF tables IS NOT NULL THEN
+        EXECUTE 'TRUNCATE ' || tables || ' RESTART IDENTITY CASCADE';
+    END IF;
+END $$;
+`
+
+// Insert runs an INSERT ... RETURNING id and returns the id.
+func Insert(t *testing.T, conn *sql.DB, query string, args ...any) int32 {
+	t.Helper()
+	var id int32
+	if err := conn.QueryRow(query, args...).Scan(&id); err != nil {
+		t.Fatalf("insert: %v\n%s", err, query)
+	}
+	return id
+}
+
+// Exec runs a statement that doesn't return rows.
+func Exec(t *testing.T, conn *sql.DB, query string, args ...any) {
+	t.Helper()
+	if _, err := conn.Exec(query, args...); err != nil {
+		t.Fatalf("exec: %v\n%s", err, query)
+	}
+}
@@ -0,0 +1,73 @@
+package dbtest
+
+import (
+	"database/sql"
+	"fmt"
+	"testing"
+)
+
+// Admin creates an admin with the admin role, the role is created when
+// missing.
+func Admin(t *testing.T, conn *sql.DB, username string) int32 {
+	t.Helper()
+	roleID := Insert(t, conn, `
+		INSERT INTO roles (name) VALUES ('admin')
+		ON CONFLICT (name) DO UPDATE SET name = EXCLUDED.name
+		RETURNING id`)
+	return Insert(t, conn, `
+		INSERT INTO admins (first_name, last_name, username, email, password_hash, role_id)
+		VALUES ('Test', 'Admin', $1, $2, 'x', $3)
+		RETURNING id`, username, username+"@example.com", roleID)
+}
+
+// Business creates a business owned by ownerID with one branch, and returns
+// both ids.
+func Business(t *testing.T, conn *sql.DB, ownerID int32, name string) (int32, int32) {
+	t.Helper()
+	businessID := Insert(t, conn, `
+		INSERT INTO business (owner_id, name, country) VALUES ($1, $2, 'NG')
+		RETURNING id`, ownerID, name)
+	branchID := Insert(t, conn, `
+		INSERT INTO branch (business_id, name) VALUES ($1, 'Main Branch')
+		RETURNING id`, businessID)
+	return businessID, branchID
+}
+
+// Store creates a sub-store in the branch, its code is the name cut to 10
+// characters.
+func Store(t *testing.T, conn *sql.DB, branchID int32, name string) int32 {
+	t.Helper()
+	code := name
+	if len(code) > 10 {
+		code = code[:10]
+	}
+	return Insert(t, conn, `
+		INSERT INTO store (name, branch_id, address, phone, email, store_type, store_code)
+		VALUES ($1, $2, '1 Marina Road', '0800000000', 'store@example.com', 'sub-store', $3)
+		RETURNING id`, name, branchID, code)
+}
+
+// Variation creates an item of the business, in a category of its own, with
+// one variation priced price, and returns the variation id.
+func Variation(t *testing.T, conn *sql.DB, businessID int32, sku, price string) int32 {
+	t.Helper()
+	categoryID := Insert(t, conn, `
+		INSERT INTO category (name, business_id) VALUES ($1, $2)
+		RETURNING id`, "Category "+sku, businessID)
+	itemID := Insert(t, conn, `
+		INSERT INTO item (category_id, name, item_type, business_id) VALUES ($1, $2, 'for_sale', $3)
+		RETURNING id`, categoryID, "Item "+sku, businessID)
+	unitID := Insert(t, conn, `INSERT INTO unit (name, short_code) VALUES ('Piece', 'pcs') RETURNING id`)
+	return Insert(t, conn, `
+		INSERT INTO variation (item_id, sku, name, unit_id, base_price) VALUES ($1, $2, $3, $4, $5)
+		RETURNING id`, itemID, sku, fmt.Sprintf("Variation %s", sku), unitID, price)
+}
+
+// Stock sets the quantity of a variation in a store.
+func Stock(t *testing.T, conn *sql.DB, storeID, variationID, quantity int32) {
+	t.Helper()
+	Exec(t, conn, `
+		INSERT INTO inventory (store_id, variation_id, quantity) VALUES ($1, $2, $3)
+		ON CONFLICT (store_id, variation_id) DO UPDATE SET quantity = EXCLUDED.quantity`,
+		storeID, variationID, quantity)
+}
@@ -0,0 +1,20 @@
+-- Reservation: holds stock for a pending order until it is paid for or expires.
+-- Reserved quantity reduces what is available to sell without touching the
+-- on-hand quantity in inventory; consuming a reservation decrements on-hand.
+CREATE TABLE inventory_reservation (
+    id SERIAL PRIMARY KEY,
+    store_id INT NOT NULL REFERENCES store(id) ON DELETE CASCADE,
+    variation_id INT NOT NULL REFERENCES variation(id) ON DELETE CASCADE,
+    quantity INT NOT NULL CHECK (quantity > 0),
+    status VARCHAR(20) NOT NULL DEFAULT 'active' CHECK (status IN ('active', 'consumed', 'released')),
+    reserved_by INT NOT NULL,
+    expires_at TIMESTAMP NOT NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+-- Active reservation lookup (store + variation)
+CREATE INDEX idx_inventory_reservation_store_variation ON inventory_reservation(store_id, variation_id) WHERE status = 'active';
+
+-- Sweeper lookup for expired reservations
+CREATE INDEX idx_inventory_reservation_expires_at ON inventory_reservation(expires_at) WHERE status = 'active';
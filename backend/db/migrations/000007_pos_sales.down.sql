@@ -0,0 +1,9 @@
+DROP TABLE IF EXISTS refund_item;
+DROP TABLE IF EXISTS refund;
+DROP TABLE IF EXISTS sale_item;
+DROP TABLE IF EXISTS sale;
+
+DELETE FROM role_permissions WHERE permission_id IN (
+    SELECT id FROM permissions WHERE code IN ('pos:sell', 'pos:view', 'pos:manage_items', 'pos:refund')
+);
+DELETE FROM permissions WHERE code IN ('pos:sell', 'pos:view', 'pos:manage_items', 'pos:refund');
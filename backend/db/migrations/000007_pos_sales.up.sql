@@ -0,0 +1,60 @@
+-- POS permissions
+INSERT INTO permissions (code, description) VALUES
+('pos:sell', 'Create new sales in POS'),
+('pos:view', 'View sales history in POS'),
+('pos:manage_items', 'Manage POS items'),
+('pos:refund', 'Refund sales in POS')
+ON CONFLICT (code) DO NOTHING;
+
+INSERT INTO role_permissions (role_id, permission_id)
+SELECT r.id, p.id FROM roles r, permissions p
+WHERE (r.name = 'admin' AND p.code IN ('pos:sell', 'pos:view', 'pos:manage_items', 'pos:refund'))
+   OR (r.name = 'manager' AND p.code IN ('pos:sell', 'pos:view', 'pos:refund'))
+   OR (r.name IN ('cashier', 'pos_staff') AND p.code = 'pos:sell')
+ON CONFLICT DO NOTHING;
+
+CREATE TABLE sale (
+    id SERIAL PRIMARY KEY,
+    store_id INT NOT NULL REFERENCES store(id),
+    customer_id INT NOT NULL,
+    cashier_id INT NOT NULL,
+    subtotal NUMERIC(12,2) NOT NULL,
+    discount_amount NUMERIC(12,2) NOT NULL DEFAULT 0,
+    tax_amount NUMERIC(12,2) NOT NULL DEFAULT 0,
+    total_amount NUMERIC(12,2) NOT NULL,
+    status VARCHAR(20) NOT NULL DEFAULT 'completed' CHECK (status IN ('completed', 'partially_refunded', 'refunded')),
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX idx_sale_store_id ON sale(store_id);
+CREATE INDEX idx_sale_created_at ON sale(created_at);
+
+-- Sale line, variation_id is the sold item
+CREATE TABLE sale_item (
+    id SERIAL PRIMARY KEY,
+    sale_id INT NOT NULL REFERENCES sale(id) ON DELETE CASCADE,
+    variation_id INT NOT NULL REFERENCES variation(id),
+    quantity INT NOT NULL CHECK (quantity > 0),
+    unit_price NUMERIC(12,2) NOT NULL,
+    refunded_quantity INT NOT NULL DEFAULT 0 CHECK (refunded_quantity >= 0 AND refunded_quantity <= quantity),
+    UNIQUE(sale_id, variation_id)
+);
+
+CREATE TABLE refund (
+    id SERIAL PRIMARY KEY,
+    sale_id INT NOT NULL REFERENCES sale(id) ON DELETE CASCADE,
+    refunded_by INT NOT NULL,
+    total_amount NUMERIC(12,2) NOT NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX idx_refund_sale_id ON refund(sale_id);
+
+CREATE TABLE refund_item (
+    id SERIAL PRIMARY KEY,
+    refund_id INT NOT NULL REFERENCES refund(id) ON DELETE CASCADE,
+    sale_item_id INT NOT NULL REFERENCES sale_item(id),
+    quantity INT NOT NULL CHECK (quantity > 0),
+    amount NUMERIC(12,2) NOT NULL
+);
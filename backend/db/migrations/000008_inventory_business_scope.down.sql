@@ -0,0 +1,9 @@
+ALTER TABLE category DROP CONSTRAINT IF EXISTS category_business_name_parent_id_key;
+ALTER TABLE category ADD CONSTRAINT category_name_parent_id_key UNIQUE (name, parent_id);
+
+ALTER TABLE brand DROP CONSTRAINT IF EXISTS brand_business_name_key;
+ALTER TABLE brand ADD CONSTRAINT brand_name_key UNIQUE (name);
+
+ALTER TABLE item DROP COLUMN IF EXISTS business_id;
+ALTER TABLE category DROP COLUMN IF EXISTS business_id;
+ALTER TABLE brand DROP COLUMN IF EXISTS business_id;
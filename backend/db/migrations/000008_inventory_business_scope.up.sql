@@ -0,0 +1,21 @@
+-- Scope catalog entities to a business so each hotel has its own catalog.
+-- Variations, images and inventory are scoped through their item.
+ALTER TABLE brand ADD COLUMN business_id INT REFERENCES business(id) ON DELETE CASCADE;
+ALTER TABLE category ADD COLUMN business_id INT REFERENCES business(id) ON DELETE CASCADE;
+ALTER TABLE item ADD COLUMN business_id INT REFERENCES business(id) ON DELETE CASCADE;
+
+-- Existing rows belong to the default (first) business
+UPDATE brand SET business_id = (SELECT MIN(id) FROM business) WHERE business_id IS NULL;
+UPDATE category SET business_id = (SELECT MIN(id) FROM business) WHERE business_id IS NULL;
+UPDATE item SET business_id = (SELECT MIN(id) FROM business) WHERE business_id IS NULL;
+
+-- Names only need to be unique within a business
+ALTER TABLE brand DROP CONSTRAINT IF EXISTS brand_name_key;
+ALTER TABLE brand ADD CONSTRAINT brand_business_name_key UNIQUE (business_id, name);
+
+ALTER TABLE category DROP CONSTRAINT IF EXISTS category_name_parent_id_key;
+ALTER TABLE category ADD CONSTRAINT category_business_name_parent_id_key UNIQUE (business_id, name, parent_id);
+
+CREATE INDEX idx_brand_business_id ON brand(business_id);
+CREATE INDEX idx_category_business_id ON category(business_id);
+CREATE INDEX idx_item_business_id ON item(business_id);
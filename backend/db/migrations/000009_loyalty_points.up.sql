@@ -0,0 +1,22 @@
+-- Points earning rule of a business, earn_rate points are earned per currency
+-- unit of a sale total and rounded according to rounding
+CREATE TABLE loyalty_rule (
+    business_id INT PRIMARY KEY REFERENCES business(id) ON DELETE CASCADE,
+    earn_rate NUMERIC(10,4) NOT NULL DEFAULT 1 CHECK (earn_rate >= 0),
+    rounding VARCHAR(10) NOT NULL DEFAULT 'down' CHECK (rounding IN ('down', 'nearest', 'up')),
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+-- Points ledger, earned points are positive and reversals on refund negative
+CREATE TABLE loyalty_points (
+    id SERIAL PRIMARY KEY,
+    customer_id INT NOT NULL,
+    sale_id INT NOT NULL REFERENCES sale(id) ON DELETE CASCADE,
+    refund_id INT REFERENCES refund(id) ON DELETE CASCADE,
+    points INT NOT NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX idx_loyalty_points_customer_id ON loyalty_points(customer_id);
+CREATE INDEX idx_loyalty_points_sale_id ON loyalty_points(sale_id);
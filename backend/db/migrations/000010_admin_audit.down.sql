@@ -0,0 +1,6 @@
+DROP INDEX IF EXISTS idx_login_history_login_time;
+
+DELETE FROM role_permissions WHERE permission_id IN (
+    SELECT id FROM permissions WHERE code = 'admin:audit'
+);
+DELETE FROM permissions WHERE code = 'admin:audit';
@@ -0,0 +1,11 @@
+-- Access to audit data such as login history exports
+INSERT INTO permissions (code, description) VALUES
+('admin:audit', 'Export audit data such as login history')
+ON CONFLICT (code) DO NOTHING;
+
+INSERT INTO role_permissions (role_id, permission_id)
+SELECT r.id, p.id FROM roles r, permissions p
+WHERE r.name = 'admin' AND p.code = 'admin:audit'
+ON CONFLICT DO NOTHING;
+
+CREATE INDEX IF NOT EXISTS idx_login_history_login_time ON login_history(login_time);
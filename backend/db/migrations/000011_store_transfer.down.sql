@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS store_transfer_item;
+DROP TABLE IF EXISTS store_transfer;
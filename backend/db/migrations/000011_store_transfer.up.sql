@@ -0,0 +1,20 @@
+-- Stock moved from one store to another
+CREATE TABLE store_transfer (
+    id SERIAL PRIMARY KEY,
+    from_store_id INT NOT NULL REFERENCES store(id) ON DELETE CASCADE,
+    to_store_id INT NOT NULL REFERENCES store(id) ON DELETE CASCADE,
+    transferred_by INT NOT NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    CHECK (from_store_id <> to_store_id)
+);
+
+CREATE TABLE store_transfer_item (
+    id SERIAL PRIMARY KEY,
+    transfer_id INT NOT NULL REFERENCES store_transfer(id) ON DELETE CASCADE,
+    variation_id INT NOT NULL REFERENCES variation(id) ON DELETE CASCADE,
+    quantity INT NOT NULL CHECK (quantity > 0),
+    UNIQUE (transfer_id, variation_id)
+);
+
+CREATE INDEX idx_store_transfer_from_store_id ON store_transfer(from_store_id);
+CREATE INDEX idx_store_transfer_to_store_id ON store_transfer(to_store_id);
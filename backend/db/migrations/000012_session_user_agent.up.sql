@@ -0,0 +1,2 @@
+-- Remember which client a refresh token was issued to so sessions can be listed
+ALTER TABLE refresh_tokens ADD COLUMN user_agent TEXT;
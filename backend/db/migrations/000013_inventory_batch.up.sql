@@ -0,0 +1,16 @@
+-- Batches (lots) of a variation received into a store, so perishable stock
+-- can be tracked by expiry. The inventory row stays the total on hand; sales
+-- and transfers take stock out of the batches that expire first.
+CREATE TABLE inventory_batch (
+    id SERIAL PRIMARY KEY,
+    store_id INT NOT NULL REFERENCES store(id) ON DELETE CASCADE,
+    variation_id INT NOT NULL REFERENCES variation(id) ON DELETE CASCADE,
+    lot_number VARCHAR(50) NOT NULL,
+    expiry_date DATE NOT NULL,
+    quantity INT NOT NULL CHECK (quantity >= 0),
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    UNIQUE (store_id, variation_id, lot_number)
+);
+
+CREATE INDEX idx_inventory_batch_expiry ON inventory_batch(expiry_date) WHERE quantity > 0;
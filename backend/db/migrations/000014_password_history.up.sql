@@ -0,0 +1,15 @@
+-- Previous password hashes of users and admins, so a reset can't reuse them
+CREATE TABLE password_history (
+    id SERIAL PRIMARY KEY,
+    user_id INT REFERENCES users(id) ON DELETE CASCADE,
+    admin_id INT REFERENCES admins(id) ON DELETE CASCADE,
+    password_hash VARCHAR(255) NOT NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    CONSTRAINT user_or_admin CHECK (
+        (user_id IS NOT NULL AND admin_id IS NULL)
+        OR (user_id IS NULL AND admin_id IS NOT NULL)
+    )
+);
+
+CREATE INDEX idx_password_history_user_id ON password_history(user_id, created_at DESC);
+CREATE INDEX idx_password_history_admin_id ON password_history(admin_id, created_at DESC);
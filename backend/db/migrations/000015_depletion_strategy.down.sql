@@ -0,0 +1,3 @@
+DROP TABLE IF EXISTS inventory_batch_movement;
+ALTER TABLE category DROP COLUMN IF EXISTS depletion_strategy;
+ALTER TABLE business DROP COLUMN IF EXISTS depletion_strategy;
@@ -0,0 +1,21 @@
+-- Order in which batches are depleted: first expired first out or first in
+-- first out. Categories can override the business default.
+ALTER TABLE business
+    ADD COLUMN depletion_strategy VARCHAR(10) NOT NULL DEFAULT 'fefo'
+    CHECK (depletion_strategy IN ('fefo', 'fifo'));
+
+ALTER TABLE category
+    ADD COLUMN depletion_strategy VARCHAR(10)
+    CHECK (depletion_strategy IN ('fefo', 'fifo'));
+
+-- What was taken out of each batch and why (sale, transfer, reservation).
+CREATE TABLE inventory_batch_movement (
+    id SERIAL PRIMARY KEY,
+    batch_id INT NOT NULL REFERENCES inventory_batch(id) ON DELETE CASCADE,
+    quantity INT NOT NULL,
+    reason VARCHAR(20) NOT NULL,
+    reference_id INT,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX idx_inventory_batch_movement_batch ON inventory_batch_movement(batch_id);
@@ -0,0 +1,10 @@
+-- Which notifications a user receives and over which channels. Events a user
+-- has no row for fall back to the defaults of their role.
+CREATE TABLE notification_preference (
+    user_id INT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    event VARCHAR(30) NOT NULL CHECK (event IN ('low_stock', 'large_sale', 'security')),
+    email BOOLEAN NOT NULL,
+    in_app BOOLEAN NOT NULL,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (user_id, event)
+);
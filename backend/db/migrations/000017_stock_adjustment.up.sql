@@ -0,0 +1,14 @@
+-- Corrections of on-hand stock, e.g. to match a cycle count. quantity is the
+-- change applied, negative when stock was written off.
+CREATE TABLE stock_adjustment (
+    id SERIAL PRIMARY KEY,
+    store_id INT NOT NULL REFERENCES store(id) ON DELETE CASCADE,
+    variation_id INT NOT NULL REFERENCES variation(id) ON DELETE CASCADE,
+    previous_quantity INT NOT NULL,
+    quantity INT NOT NULL CHECK (quantity <> 0),
+    reason VARCHAR(30) NOT NULL,
+    adjusted_by INT NOT NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX idx_stock_adjustment_store_variation ON stock_adjustment(store_id, variation_id);
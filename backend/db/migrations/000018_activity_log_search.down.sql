@@ -0,0 +1 @@
+DROP INDEX IF EXISTS activity_logs_details_search_idx;
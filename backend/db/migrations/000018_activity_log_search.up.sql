@@ -0,0 +1,4 @@
+-- Full text search over activity log details. The simple configuration
+-- doesn't stem, so names of businesses and people match as written.
+CREATE INDEX activity_logs_details_search_idx ON activity_logs
+    USING GIN (to_tsvector('simple', details));
@@ -0,0 +1,3 @@
+-- Stock a store keeps for itself, transfers out may not take it below this
+-- level unless they are explicitly overridden. 0 means no minimum.
+ALTER TABLE inventory ADD COLUMN min_keep INT NOT NULL DEFAULT 0 CHECK (min_keep >= 0);
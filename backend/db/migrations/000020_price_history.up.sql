@@ -0,0 +1,12 @@
+-- Every change of a variation's base price, written in the same transaction
+-- as the change.
+CREATE TABLE price_history (
+    id SERIAL PRIMARY KEY,
+    variation_id INT NOT NULL REFERENCES variation(id) ON DELETE CASCADE,
+    old_price NUMERIC(12,2) NOT NULL,
+    new_price NUMERIC(12,2) NOT NULL,
+    changed_by INT NOT NULL,
+    changed_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX idx_price_history_variation ON price_history(variation_id, changed_at);
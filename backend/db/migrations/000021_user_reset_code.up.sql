@@ -0,0 +1,7 @@
+-- Password reset codes of users. Admins keep theirs on the admins table, for
+-- users they live apart so user rows never carry them.
+CREATE TABLE user_reset_code (
+    user_id INT PRIMARY KEY REFERENCES users(id) ON DELETE CASCADE,
+    code TEXT NOT NULL,
+    expires_at TIMESTAMP NOT NULL
+);
@@ -0,0 +1,12 @@
+-- How a sale was paid, a sale can be split over several payments. Methods
+-- are the payment types of the business the sale was made in.
+CREATE TABLE sale_payment (
+    id SERIAL PRIMARY KEY,
+    sale_id INT NOT NULL REFERENCES sale(id) ON DELETE CASCADE,
+    method payment_type NOT NULL,
+    amount NUMERIC(12,2) NOT NULL CHECK (amount > 0),
+    reference TEXT,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX idx_sale_payment_sale_id ON sale_payment(sale_id);
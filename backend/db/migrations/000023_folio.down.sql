@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS folio_charge;
+DROP TABLE IF EXISTS folio;
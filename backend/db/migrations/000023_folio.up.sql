@@ -0,0 +1,23 @@
+-- Guest folios of hotel businesses. Sales paid by room charge are posted to
+-- an open folio of the business the sale was made in.
+CREATE TABLE folio (
+    id SERIAL PRIMARY KEY,
+    business_id INT NOT NULL REFERENCES business(id) ON DELETE CASCADE,
+    room_number VARCHAR(20) NOT NULL,
+    guest_name VARCHAR(100) NOT NULL,
+    status VARCHAR(10) NOT NULL DEFAULT 'open' CHECK (status IN ('open', 'closed')),
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    closed_at TIMESTAMP
+);
+
+CREATE INDEX idx_folio_business_id ON folio(business_id);
+
+CREATE TABLE folio_charge (
+    id SERIAL PRIMARY KEY,
+    folio_id INT NOT NULL REFERENCES folio(id) ON DELETE CASCADE,
+    sale_id INT NOT NULL REFERENCES sale(id) ON DELETE CASCADE,
+    amount NUMERIC(12,2) NOT NULL CHECK (amount > 0),
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX idx_folio_charge_folio_id ON folio_charge(folio_id);
@@ -0,0 +1,2 @@
+DROP INDEX IF EXISTS idx_business_owner_id_active;
+ALTER TABLE business DROP COLUMN IF EXISTS deleted_at;
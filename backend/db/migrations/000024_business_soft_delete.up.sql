@@ -0,0 +1,5 @@
+-- Businesses are soft deleted so their history stays readable and they can be
+-- restored for a while. Deleted businesses are hidden from every read.
+ALTER TABLE business ADD COLUMN deleted_at TIMESTAMP;
+
+CREATE INDEX idx_business_owner_id_active ON business(owner_id) WHERE deleted_at IS NULL;
@@ -0,0 +1,3 @@
+-- Users are soft deleted so their activity logs and login history stay
+-- attributable. A deleted user is also inactive and can't log in.
+ALTER TABLE users ADD COLUMN deleted_at TIMESTAMP;
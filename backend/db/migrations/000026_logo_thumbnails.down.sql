@@ -0,0 +1,2 @@
+ALTER TABLE brand DROP COLUMN IF EXISTS logo_thumbnail;
+ALTER TABLE business DROP COLUMN IF EXISTS logo_thumbnail_url;
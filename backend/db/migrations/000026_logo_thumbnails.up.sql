@@ -0,0 +1,4 @@
+-- Thumbnails made of uploaded logos, the SPA shows these instead of the
+-- full size images.
+ALTER TABLE business ADD COLUMN logo_thumbnail_url TEXT;
+ALTER TABLE brand ADD COLUMN logo_thumbnail TEXT;
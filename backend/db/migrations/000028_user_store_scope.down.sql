@@ -0,0 +1,4 @@
+DROP INDEX IF EXISTS idx_users_store_id;
+ALTER TABLE users
+    DROP COLUMN IF EXISTS store_id,
+    DROP COLUMN IF EXISTS branch_id;
@@ -0,0 +1,9 @@
+-- The branch and store a staff member works at. A user assigned to a store
+-- only works with that store, or with every store of its branch when it is
+-- the central one. A user with a branch but no store works with every store
+-- of the branch, and one with neither is not restricted.
+ALTER TABLE users
+    ADD COLUMN branch_id INT REFERENCES branch(id) ON DELETE SET NULL,
+    ADD COLUMN store_id INT REFERENCES store(id) ON DELETE SET NULL;
+
+CREATE INDEX idx_users_store_id ON users(store_id);
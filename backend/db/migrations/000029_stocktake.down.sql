@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS stocktake_line;
+DROP TABLE IF EXISTS stocktake;
@@ -0,0 +1,28 @@
+-- A physical count of a store. Opening it snapshots the stock on hand into
+-- its lines, which then collect the counted quantities until it is committed.
+CREATE TABLE stocktake (
+    id SERIAL PRIMARY KEY,
+    store_id INT NOT NULL REFERENCES store(id) ON DELETE CASCADE,
+    status VARCHAR(20) NOT NULL DEFAULT 'open' CHECK (status IN ('open', 'committed')),
+    opened_by INT NOT NULL,
+    committed_by INT,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    committed_at TIMESTAMP
+);
+
+-- system_quantity is the stock on hand when the count was opened, 0 for
+-- variations the store did not stock. counted_quantity stays NULL until the
+-- variation is counted.
+CREATE TABLE stocktake_line (
+    id SERIAL PRIMARY KEY,
+    stocktake_id INT NOT NULL REFERENCES stocktake(id) ON DELETE CASCADE,
+    variation_id INT NOT NULL REFERENCES variation(id) ON DELETE CASCADE,
+    system_quantity INT NOT NULL,
+    counted_quantity INT CHECK (counted_quantity >= 0),
+    adjustment_id INT REFERENCES stock_adjustment(id) ON DELETE SET NULL,
+    UNIQUE (stocktake_id, variation_id)
+);
+
+-- a store is counted by one session at a time
+CREATE UNIQUE INDEX idx_stocktake_store_open ON stocktake(store_id) WHERE status = 'open';
+CREATE INDEX idx_stocktake_store_id ON stocktake(store_id);
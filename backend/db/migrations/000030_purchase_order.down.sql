@@ -0,0 +1,3 @@
+DROP TABLE IF EXISTS purchase_order_receipt;
+DROP TABLE IF EXISTS purchase_order_line;
+DROP TABLE IF EXISTS purchase_order;
@@ -0,0 +1,42 @@
+-- Stock bought from a supplier for a store. An order is drafted, placed with
+-- the supplier (ordered) and received, possibly over several deliveries.
+CREATE TABLE purchase_order (
+    id SERIAL PRIMARY KEY,
+    store_id INT NOT NULL REFERENCES store(id) ON DELETE CASCADE,
+    supplier VARCHAR(255) NOT NULL,
+    reference VARCHAR(100),
+    notes TEXT,
+    status VARCHAR(20) NOT NULL DEFAULT 'draft' CHECK (status IN ('draft', 'ordered', 'partially_received', 'received')),
+    created_by INT NOT NULL,
+    ordered_at TIMESTAMP,
+    received_at TIMESTAMP,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE TABLE purchase_order_line (
+    id SERIAL PRIMARY KEY,
+    purchase_order_id INT NOT NULL REFERENCES purchase_order(id) ON DELETE CASCADE,
+    variation_id INT NOT NULL REFERENCES variation(id) ON DELETE CASCADE,
+    quantity INT NOT NULL CHECK (quantity > 0),
+    received_quantity INT NOT NULL DEFAULT 0 CHECK (received_quantity >= 0 AND received_quantity <= quantity),
+    unit_cost NUMERIC(12,2) NOT NULL CHECK (unit_cost >= 0),
+    UNIQUE (purchase_order_id, variation_id)
+);
+
+-- Goods received against an order line, one row per delivery. The unit cost
+-- is what the stock was bought at, for margin reporting.
+CREATE TABLE purchase_order_receipt (
+    id SERIAL PRIMARY KEY,
+    purchase_order_id INT NOT NULL REFERENCES purchase_order(id) ON DELETE CASCADE,
+    line_id INT NOT NULL REFERENCES purchase_order_line(id) ON DELETE CASCADE,
+    store_id INT NOT NULL REFERENCES store(id) ON DELETE CASCADE,
+    variation_id INT NOT NULL REFERENCES variation(id) ON DELETE CASCADE,
+    quantity INT NOT NULL CHECK (quantity > 0),
+    unit_cost NUMERIC(12,2) NOT NULL,
+    received_by INT NOT NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX idx_purchase_order_store_id ON purchase_order(store_id);
+CREATE INDEX idx_purchase_order_receipt_store_variation ON purchase_order_receipt(store_id, variation_id);
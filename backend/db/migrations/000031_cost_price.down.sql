@@ -0,0 +1,2 @@
+ALTER TABLE sale_item DROP COLUMN IF EXISTS unit_cost;
+ALTER TABLE variation DROP COLUMN IF EXISTS cost_price;
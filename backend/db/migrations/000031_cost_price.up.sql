@@ -0,0 +1,7 @@
+-- What a variation was last bought at, set when goods are received against a
+-- purchase order. NULL while the cost is unknown.
+ALTER TABLE variation ADD COLUMN cost_price NUMERIC(12,2) CHECK (cost_price >= 0);
+
+-- The cost of the variation when it was sold, so later cost changes don't
+-- alter the margin of past sales. NULL when the cost was unknown.
+ALTER TABLE sale_item ADD COLUMN unit_cost NUMERIC(12,2);
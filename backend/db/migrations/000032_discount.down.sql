@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS sale_discount;
+DROP TABLE IF EXISTS discounts;
@@ -0,0 +1,39 @@
+-- Reusable discount rules of a business, applied to sales automatically. A
+-- rule takes a percentage or a fixed amount off the whole sale, the lines of
+-- one item or the lines of one category, once the sale spends at least
+-- min_spend and while it is within its validity window.
+CREATE TABLE discounts (
+    id SERIAL PRIMARY KEY,
+    business_id INT NOT NULL REFERENCES business(id) ON DELETE CASCADE,
+    name VARCHAR(100) NOT NULL,
+    kind VARCHAR(20) NOT NULL CHECK (kind IN ('percentage', 'fixed')),
+    value NUMERIC(12,2) NOT NULL CHECK (value > 0),
+    scope VARCHAR(20) NOT NULL CHECK (scope IN ('sale', 'item', 'category')),
+    item_id INT REFERENCES item(id) ON DELETE CASCADE,
+    category_id INT REFERENCES category(id) ON DELETE CASCADE,
+    min_spend NUMERIC(12,2) CHECK (min_spend >= 0),
+    starts_at TIMESTAMP,
+    ends_at TIMESTAMP,
+    is_active BOOLEAN NOT NULL DEFAULT TRUE,
+    created_by INT NOT NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    CHECK (kind <> 'percentage' OR value <= 100),
+    CHECK ((scope = 'item') = (item_id IS NOT NULL)),
+    CHECK ((scope = 'category') = (category_id IS NOT NULL)),
+    CHECK (starts_at IS NULL OR ends_at IS NULL OR ends_at > starts_at)
+);
+
+CREATE INDEX idx_discounts_business_id ON discounts(business_id);
+
+-- The discounts taken off a sale. discount_id is NULL for the discount the
+-- cashier entered by hand, name keeps what the rule was called at the time.
+CREATE TABLE sale_discount (
+    id SERIAL PRIMARY KEY,
+    sale_id INT NOT NULL REFERENCES sale(id) ON DELETE CASCADE,
+    discount_id INT REFERENCES discounts(id) ON DELETE SET NULL,
+    name VARCHAR(100) NOT NULL,
+    amount NUMERIC(12,2) NOT NULL CHECK (amount > 0)
+);
+
+CREATE INDEX idx_sale_discount_sale_id ON sale_discount(sale_id);
@@ -0,0 +1,2 @@
+ALTER TABLE sale_discount DROP COLUMN IF EXISTS coupon_id;
+DROP TABLE IF EXISTS coupons;
@@ -0,0 +1,22 @@
+-- Codes that give a sale the discount of a rule when it is redeemed. A rule
+-- with coupons is only applied to sales that redeem one of them. usage_limit
+-- is how many sales can redeem the code, NULL for no limit.
+CREATE TABLE coupons (
+    id SERIAL PRIMARY KEY,
+    business_id INT NOT NULL REFERENCES business(id) ON DELETE CASCADE,
+    discount_id INT NOT NULL REFERENCES discounts(id) ON DELETE CASCADE,
+    code VARCHAR(50) NOT NULL,
+    usage_limit INT CHECK (usage_limit > 0),
+    used_count INT NOT NULL DEFAULT 0 CHECK (usage_limit IS NULL OR used_count <= usage_limit),
+    expires_at TIMESTAMP,
+    is_active BOOLEAN NOT NULL DEFAULT TRUE,
+    created_by INT NOT NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+-- codes are matched without regard to case
+CREATE UNIQUE INDEX idx_coupons_business_code ON coupons(business_id, UPPER(code));
+CREATE INDEX idx_coupons_discount_id ON coupons(discount_id);
+
+ALTER TABLE sale_discount ADD COLUMN coupon_id INT REFERENCES coupons(id) ON DELETE SET NULL;
@@ -0,0 +1 @@
+DROP TABLE IF EXISTS business_settings_history;
@@ -0,0 +1,20 @@
+-- The settings a business had before each change of its tax rate, rounding,
+-- currency, payment types or branding, written in the same transaction as
+-- the change. Explains why older receipts were made with other settings.
+CREATE TABLE business_settings_history (
+    id SERIAL PRIMARY KEY,
+    business_id INT NOT NULL REFERENCES business(id) ON DELETE CASCADE,
+    tax_rate DECIMAL(5,2),
+    rounding VARCHAR(50),
+    currency VARCHAR(10),
+    payment_type payment_type[],
+    motto VARCHAR(50),
+    logo_url VARCHAR(255),
+    logo_thumbnail_url TEXT,
+    font VARCHAR(100),
+    primary_color VARCHAR(7),
+    changed_by INT NOT NULL,
+    changed_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX idx_business_settings_history_business ON business_settings_history(business_id, changed_at);
@@ -0,0 +1,23 @@
+-- Zone names longer than ten characters don't fit the old column. They go
+-- back to the "UTC +1" form businesses used to be created with, taking the
+-- offset the zone has now. At most "UTC +12:45", which fits.
+UPDATE business
+SET timezone = CASE
+        WHEN offsets.minutes = 0 THEN 'UTC'
+        ELSE 'UTC ' || CASE WHEN offsets.minutes < 0 THEN '-' ELSE '+' END
+            || abs(offsets.minutes) / 60
+            || CASE WHEN abs(offsets.minutes) % 60 = 0 THEN '' ELSE ':' || lpad((abs(offsets.minutes) % 60)::text, 2, '0') END
+    END
+FROM (
+    SELECT name, (EXTRACT(EPOCH FROM utc_offset) / 60)::int AS minutes
+    FROM pg_timezone_names
+) offsets
+WHERE offsets.name = business.timezone
+  AND length(business.timezone) > 10;
+
+-- Anything else too long names no zone, the reports read it as UTC.
+UPDATE business
+SET timezone = 'UTC'
+WHERE length(timezone) > 10;
+
+ALTER TABLE business ALTER COLUMN timezone TYPE VARCHAR(10);
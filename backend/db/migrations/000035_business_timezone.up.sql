@@ -0,0 +1,3 @@
+-- Timezones are IANA names now, which run longer than ten characters, e.g.
+-- America/Argentina/Buenos_Aires.
+ALTER TABLE business ALTER COLUMN timezone TYPE VARCHAR(64);
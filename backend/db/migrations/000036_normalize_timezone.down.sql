@@ -0,0 +1,3 @@
+-- The offsets the timezones were normalized from aren't kept, there is
+-- nothing to restore.
+SELECT 1;
@@ -0,0 +1,57 @@
+-- Businesses used to be created with offsets like "UTC +1". Whole hour
+-- offsets become the matching Etc/GMT zone, whose sign is inverted in the tz
+-- database.
+UPDATE business
+SET timezone = CASE
+        WHEN m[2]::int = 0 THEN 'UTC'
+        WHEN m[1] = '+' THEN 'Etc/GMT-' || m[2]::int
+        ELSE 'Etc/GMT+' || m[2]::int
+    END
+FROM (
+    SELECT id, regexp_match(upper(trim(timezone)), '^(?:UTC|GMT)\s*([+-])\s*(\d{1,2})(?::?00)?$') AS m
+    FROM business
+) offsets
+WHERE offsets.id = business.id
+  AND offsets.m IS NOT NULL
+  AND ((offsets.m[1] = '+' AND offsets.m[2]::int <= 14) OR (offsets.m[1] = '-' AND offsets.m[2]::int <= 12));
+
+-- Offsets with minutes have no Etc zone. They become the zone of the places
+-- keeping that offset as standard time, the same as normalizeTimezone in
+-- internal/core/business.
+UPDATE business
+SET timezone = zones.name
+FROM (
+    SELECT id, regexp_match(upper(trim(timezone)), '^(?:UTC|GMT)\s*([+-])\s*(\d{1,2}):?(\d{2})$') AS m
+    FROM business
+) offsets
+JOIN (VALUES
+    ('+', 3, 30, 'Asia/Tehran'),
+    ('+', 4, 30, 'Asia/Kabul'),
+    ('+', 5, 30, 'Asia/Kolkata'),
+    ('+', 5, 45, 'Asia/Kathmandu'),
+    ('+', 6, 30, 'Asia/Yangon'),
+    ('+', 8, 45, 'Australia/Eucla'),
+    ('+', 9, 30, 'Australia/Darwin'),
+    ('+', 10, 30, 'Australia/Lord_Howe'),
+    ('+', 12, 45, 'Pacific/Chatham'),
+    ('-', 3, 30, 'America/St_Johns'),
+    ('-', 9, 30, 'Pacific/Marquesas')
+) AS zones(sign, hours, minutes, name)
+    ON zones.sign = offsets.m[1] AND zones.hours = offsets.m[2]::int AND zones.minutes = offsets.m[3]::int
+WHERE offsets.id = business.id;
+
+-- Anything else names no zone and was read as UTC by the reports. It is set
+-- to UTC, and the value it had is left in the activity log of the business
+-- so its owner can pick the right zone. User 0 is the system.
+INSERT INTO activity_logs (user_id, action, details, entity_id, entity_type)
+SELECT 0, 'timezone_reset',
+       format('timezone %s names no time zone, reports are taken in UTC until one is set', quote_literal(timezone)),
+       id, 'Business'
+FROM business
+WHERE timezone IS NOT NULL
+  AND timezone NOT IN (SELECT name FROM pg_timezone_names);
+
+UPDATE business
+SET timezone = 'UTC'
+WHERE timezone IS NULL
+   OR timezone NOT IN (SELECT name FROM pg_timezone_names);
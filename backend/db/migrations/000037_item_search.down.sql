@@ -0,0 +1,2 @@
+DROP INDEX IF EXISTS variation_search_idx;
+DROP INDEX IF EXISTS item_search_idx;
@@ -0,0 +1,7 @@
+-- Full text search over items and variations. The simple configuration
+-- doesn't stem, so names, SKUs and barcodes match as written. The indexed
+-- expressions have to match the ones in SearchItems to be used.
+CREATE INDEX item_search_idx ON item
+    USING GIN (to_tsvector('simple', name || ' ' || COALESCE(description, '')));
+CREATE INDEX variation_search_idx ON variation
+    USING GIN (to_tsvector('simple', name || ' ' || sku || ' ' || COALESCE(barcode, '')));
@@ -0,0 +1,2 @@
+ALTER TABLE item DROP COLUMN IF EXISTS version;
+ALTER TABLE business DROP COLUMN IF EXISTS version;
@@ -0,0 +1,4 @@
+-- Businesses and items count their updates, so an update made from a stale
+-- copy can be told apart and rejected instead of overwriting newer changes.
+ALTER TABLE business ADD COLUMN version INT NOT NULL DEFAULT 1;
+ALTER TABLE item ADD COLUMN version INT NOT NULL DEFAULT 1;
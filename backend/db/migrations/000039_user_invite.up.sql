@@ -0,0 +1,7 @@
+-- Invites of users created without a password. The token is stored hashed
+-- and the invite is deleted once the user has set their password.
+CREATE TABLE user_invite (
+    user_id INT PRIMARY KEY REFERENCES users(id) ON DELETE CASCADE,
+    token TEXT NOT NULL UNIQUE,
+    expires_at TIMESTAMP NOT NULL
+);
@@ -0,0 +1,2 @@
+DROP INDEX IF EXISTS idx_webhook_subscriptions_business_id;
+ALTER TABLE webhook_subscriptions DROP COLUMN IF EXISTS business_id;
@@ -0,0 +1,14 @@
+-- Webhook subscriptions belong to a business and only receive its events.
+-- Existing subscriptions can only be attributed when there is a single
+-- business, otherwise they are dropped and have to be made again.
+ALTER TABLE webhook_subscriptions ADD COLUMN business_id INT REFERENCES business(id) ON DELETE CASCADE;
+
+UPDATE webhook_subscriptions
+SET business_id = (SELECT id FROM business WHERE deleted_at IS NULL)
+WHERE (SELECT count(*) FROM business WHERE deleted_at IS NULL) = 1;
+
+DELETE FROM webhook_subscriptions WHERE business_id IS NULL;
+
+ALTER TABLE webhook_subscriptions ALTER COLUMN business_id SET NOT NULL;
+
+CREATE INDEX idx_webhook_subscriptions_business_id ON webhook_subscriptions(business_id);
@@ -0,0 +1,17 @@
+-- cancelled orders go back to the status they were cancelled from
+UPDATE purchase_order po
+SET status = CASE
+        WHEN EXISTS (SELECT 1 FROM purchase_order_line l WHERE l.purchase_order_id = po.id AND l.received_quantity > 0) THEN 'partially_received'
+        WHEN po.ordered_at IS NOT NULL THEN 'ordered'
+        ELSE 'draft'
+    END
+WHERE po.status = 'cancelled';
+
+ALTER TABLE purchase_order
+    DROP COLUMN IF EXISTS cancel_reason,
+    DROP COLUMN IF EXISTS cancelled_at,
+    DROP COLUMN IF EXISTS cancelled_by;
+
+ALTER TABLE purchase_order DROP CONSTRAINT purchase_order_status_check;
+ALTER TABLE purchase_order ADD CONSTRAINT purchase_order_status_check
+    CHECK (status IN ('draft', 'ordered', 'partially_received', 'received'));
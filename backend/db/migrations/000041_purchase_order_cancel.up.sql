@@ -0,0 +1,10 @@
+-- Open purchase orders can be cancelled, what was received so far is kept
+-- and nothing more can be received against them.
+ALTER TABLE purchase_order DROP CONSTRAINT purchase_order_status_check;
+ALTER TABLE purchase_order ADD CONSTRAINT purchase_order_status_check
+    CHECK (status IN ('draft', 'ordered', 'partially_received', 'received', 'cancelled'));
+
+ALTER TABLE purchase_order
+    ADD COLUMN cancelled_by INT,
+    ADD COLUMN cancelled_at TIMESTAMP,
+    ADD COLUMN cancel_reason TEXT;
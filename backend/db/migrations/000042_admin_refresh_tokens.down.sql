@@ -0,0 +1,7 @@
+-- admin sessions have no user to fall back to
+DELETE FROM refresh_tokens WHERE admin_id IS NOT NULL;
+
+DROP INDEX IF EXISTS idx_refresh_tokens_admin_id;
+ALTER TABLE refresh_tokens DROP CONSTRAINT IF EXISTS refresh_tokens_user_or_admin;
+ALTER TABLE refresh_tokens DROP COLUMN IF EXISTS admin_id;
+ALTER TABLE refresh_tokens ALTER COLUMN user_id SET NOT NULL;
@@ -0,0 +1,10 @@
+-- Admins log in with refresh tokens too and their ids are not user ids, so a
+-- token belongs to either a user or an admin the way password history does.
+ALTER TABLE refresh_tokens ALTER COLUMN user_id DROP NOT NULL;
+ALTER TABLE refresh_tokens ADD COLUMN admin_id INT REFERENCES admins(id) ON DELETE CASCADE;
+ALTER TABLE refresh_tokens ADD CONSTRAINT refresh_tokens_user_or_admin CHECK (
+    (user_id IS NOT NULL AND admin_id IS NULL)
+    OR (user_id IS NULL AND admin_id IS NOT NULL)
+);
+
+CREATE INDEX idx_refresh_tokens_admin_id ON refresh_tokens(admin_id);
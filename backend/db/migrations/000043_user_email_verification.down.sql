@@ -0,0 +1,3 @@
+ALTER TABLE users DROP COLUMN IF EXISTS verification_expires_at;
+ALTER TABLE users DROP COLUMN IF EXISTS verification_code;
+ALTER TABLE users DROP COLUMN IF EXISTS email_verified;
@@ -0,0 +1,5 @@
+-- Users can change their own email and the new address is verified the way
+-- an admin's is. Users created so far were never asked to, they stay verified.
+ALTER TABLE users ADD COLUMN email_verified BOOLEAN NOT NULL DEFAULT TRUE;
+ALTER TABLE users ADD COLUMN verification_code TEXT;
+ALTER TABLE users ADD COLUMN verification_expires_at TIMESTAMP;
@@ -0,0 +1,43 @@
+-- name: ExportBusinesses :many
+SELECT * FROM business
+ORDER BY id;
+
+-- name: ExportBranches :many
+SELECT * FROM branch
+ORDER BY id;
+
+-- name: ExportStores :many
+SELECT * FROM store
+ORDER BY id;
+
+-- name: ExportCategories :many
+SELECT * FROM category
+ORDER BY id;
+
+-- name: ExportBrands :many
+SELECT * FROM brand
+ORDER BY id;
+
+-- name: ExportUnits :many
+SELECT * FROM unit
+ORDER BY id;
+
+-- name: ExportItems :many
+SELECT * FROM item
+ORDER BY id;
+
+-- name: ExportVariations :many
+SELECT * FROM variation
+ORDER BY id;
+
+-- name: ExportInventory :many
+SELECT * FROM inventory
+ORDER BY id;
+
+-- name: ExportSales :many
+SELECT * FROM sale
+ORDER BY id;
+
+-- name: ExportSaleItems :many
+SELECT * FROM sale_item
+ORDER BY id;
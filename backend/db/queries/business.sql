@@ -3,23 +3,37 @@ INSERT INTO business (
     owner_id, name, motto, email, website, tax_id, tax_rate,
     country, logo_url, rounding, currency, timezone, language,
     low_stock_threshold, allow_overselling, payment_type,
-    font, primary_color
+    font, primary_color, logo_thumbnail_url
 ) VALUES (
     $1, $2, $3, $4, $5, $6, $7,
     $8, $9, $10, $11, $12, $13,
-    $14, $15, $16, $17, $18
+    $14, $15, $16, $17, $18, $19
 ) RETURNING *;
 
 -- name: GetBusiness :one
 SELECT *
 FROM business
-WHERE id = $1 AND owner_id = $2;
+WHERE id = $1 AND owner_id = $2 AND deleted_at IS NULL;
+
+-- name: GetOwnedBusinessID :one
+SELECT id
+FROM business
+WHERE owner_id = sqlc.arg(owner_id)
+  AND deleted_at IS NULL
+  AND (sqlc.narg(business_id)::int IS NULL OR id = sqlc.narg(business_id))
+ORDER BY id
+LIMIT 1;
 
 -- name: ListBusinesses :many
 SELECT *
 FROM business
-WHERE owner_id = $1
-ORDER BY created_at;
+WHERE owner_id = sqlc.arg(owner_id) AND deleted_at IS NULL
+ORDER BY id
+LIMIT sqlc.arg(page_limit) OFFSET sqlc.arg(page_offset);
+
+-- name: CountBusinesses :one
+SELECT COUNT(*) FROM business
+WHERE owner_id = $1 AND deleted_at IS NULL;
 
 -- name: UpdateBusiness :one
 UPDATE business SET
@@ -40,11 +54,47 @@ UPDATE business SET
     font = COALESCE(sqlc.narg(font), font),
     primary_color = COALESCE(sqlc.narg(primary_color), primary_color),
     country = COALESCE(sqlc.narg(country), country),
+    depletion_strategy = COALESCE(sqlc.narg(depletion_strategy), depletion_strategy),
+    logo_thumbnail_url = COALESCE(sqlc.narg(logo_thumbnail_url), logo_thumbnail_url),
+    version = version + 1,
     updated_at = CURRENT_TIMESTAMP
-WHERE id = sqlc.arg(id) AND owner_id = sqlc.arg(owner_id)
+WHERE id = sqlc.arg(id) AND owner_id = sqlc.arg(owner_id) AND deleted_at IS NULL
+  AND (sqlc.narg(expected_version)::int IS NULL OR version = sqlc.narg(expected_version))
 RETURNING *;
 
+-- name: GetBusinessForUpdate :one
+SELECT *
+FROM business
+WHERE id = $1 AND owner_id = $2 AND deleted_at IS NULL
+FOR UPDATE;
+
+-- name: CreateBusinessSettingsHistory :one
+INSERT INTO business_settings_history (
+    business_id, tax_rate, rounding, currency, payment_type, motto,
+    logo_url, logo_thumbnail_url, font, primary_color, changed_by
+) VALUES (
+    $1, $2, $3, $4, $5, $6,
+    $7, $8, $9, $10, $11
+) RETURNING *;
+
+-- name: ListBusinessSettingsHistory :many
+SELECT * FROM business_settings_history
+WHERE business_id = $1
+ORDER BY changed_at, id;
+
 -- name: DeleteBusiness :one
+UPDATE business SET deleted_at = CURRENT_TIMESTAMP
+WHERE id = $1 AND owner_id = $2 AND deleted_at IS NULL
+RETURNING *;
+
+-- name: RestoreBusiness :one
+-- only businesses deleted after sqlc.arg(deleted_after) can be restored
+UPDATE business SET deleted_at = NULL, updated_at = CURRENT_TIMESTAMP
+WHERE id = sqlc.arg(id) AND owner_id = sqlc.arg(owner_id)
+  AND deleted_at IS NOT NULL AND deleted_at >= sqlc.arg(deleted_after)
+RETURNING *;
+
+-- name: HardDeleteBusiness :one
 DELETE FROM business
 WHERE id = $1 AND owner_id = $2
 RETURNING *;
@@ -61,7 +111,18 @@ INSERT INTO branch (
 SELECT * FROM branch WHERE id = $1;
 
 -- name: ListBranches :many
-SELECT * FROM branch ORDER BY created_at DESC;
+SELECT br.* FROM branch br
+JOIN business b ON b.id = br.business_id
+WHERE b.owner_id = sqlc.arg(owner_id) AND b.deleted_at IS NULL
+  AND (sqlc.narg(business_id)::int IS NULL OR br.business_id = sqlc.narg(business_id))
+ORDER BY br.created_at DESC, br.id DESC
+LIMIT sqlc.arg(page_limit) OFFSET sqlc.arg(page_offset);
+
+-- name: CountBranches :one
+SELECT COUNT(*) FROM branch br
+JOIN business b ON b.id = br.business_id
+WHERE b.owner_id = sqlc.arg(owner_id) AND b.deleted_at IS NULL
+  AND (sqlc.narg(business_id)::int IS NULL OR br.business_id = sqlc.narg(business_id));
 
 -- name: DeleteBranch :one
 DELETE FROM branch WHERE id = $1
@@ -82,3 +143,57 @@ UPDATE branch SET
     updated_at = CURRENT_TIMESTAMP
 WHERE id = $1
 RETURNING *;
+
+-- name: ListBusinessActivityLogs :many
+-- activity logs of the owner's businesses and of their branches, stores and
+-- stock transfers, matched by the logged entity
+SELECT al.* FROM activity_logs al
+WHERE (
+    (al.entity_type = 'Business' AND al.entity_id IN (
+        SELECT b.id FROM business b WHERE b.owner_id = sqlc.arg(owner_id)))
+    OR (al.entity_type = 'Branch' AND al.entity_id IN (
+        SELECT br.id FROM branch br
+        JOIN business b ON b.id = br.business_id
+        WHERE b.owner_id = sqlc.arg(owner_id)))
+    OR (al.entity_type = 'Store' AND al.entity_id IN (
+        SELECT s.id FROM store s
+        JOIN branch br ON br.id = s.branch_id
+        JOIN business b ON b.id = br.business_id
+        WHERE b.owner_id = sqlc.arg(owner_id)))
+    OR (al.entity_type = 'StoreTransfer' AND al.entity_id IN (
+        SELECT st.id FROM store_transfer st
+        JOIN store s ON s.id = st.from_store_id
+        JOIN branch br ON br.id = s.branch_id
+        JOIN business b ON b.id = br.business_id
+        WHERE b.owner_id = sqlc.arg(owner_id)))
+  )
+  AND (sqlc.narg(entity_type)::text IS NULL OR al.entity_type = sqlc.narg(entity_type))
+  AND (sqlc.narg(start_time)::timestamp IS NULL OR al.created_at >= sqlc.narg(start_time))
+  AND (sqlc.narg(end_time)::timestamp IS NULL OR al.created_at < sqlc.narg(end_time))
+ORDER BY al.created_at DESC, al.id DESC
+LIMIT sqlc.arg(page_limit) OFFSET sqlc.arg(page_offset);
+
+-- name: CountBusinessActivityLogs :one
+SELECT COUNT(*) FROM activity_logs al
+WHERE (
+    (al.entity_type = 'Business' AND al.entity_id IN (
+        SELECT b.id FROM business b WHERE b.owner_id = sqlc.arg(owner_id)))
+    OR (al.entity_type = 'Branch' AND al.entity_id IN (
+        SELECT br.id FROM branch br
+        JOIN business b ON b.id = br.business_id
+        WHERE b.owner_id = sqlc.arg(owner_id)))
+    OR (al.entity_type = 'Store' AND al.entity_id IN (
+        SELECT s.id FROM store s
+        JOIN branch br ON br.id = s.branch_id
+        JOIN business b ON b.id = br.business_id
+        WHERE b.owner_id = sqlc.arg(owner_id)))
+    OR (al.entity_type = 'StoreTransfer' AND al.entity_id IN (
+        SELECT st.id FROM store_transfer st
+        JOIN store s ON s.id = st.from_store_id
+        JOIN branch br ON br.id = s.branch_id
+        JOIN business b ON b.id = br.business_id
+        WHERE b.owner_id = sqlc.arg(owner_id)))
+  )
+  AND (sqlc.narg(entity_type)::text IS NULL OR al.entity_type = sqlc.narg(entity_type))
+  AND (sqlc.narg(start_time)::timestamp IS NULL OR al.created_at >= sqlc.narg(start_time))
+  AND (sqlc.narg(end_time)::timestamp IS NULL OR al.created_at < sqlc.narg(end_time));
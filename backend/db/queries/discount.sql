@@ -0,0 +1,99 @@
+-- name: CreateDiscount :one
+INSERT INTO discounts (
+    business_id, name, kind, value, scope, item_id, category_id,
+    min_spend, starts_at, ends_at, is_active, created_by
+)
+VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12)
+RETURNING *;
+
+-- name: GetDiscount :one
+SELECT * FROM discounts
+WHERE id = sqlc.arg(id) AND (sqlc.narg(business_id)::int IS NULL OR business_id = sqlc.narg(business_id))
+LIMIT 1;
+
+-- name: ListDiscounts :many
+SELECT * FROM discounts
+WHERE (sqlc.narg(business_id)::int IS NULL OR business_id = sqlc.narg(business_id))
+ORDER BY id;
+
+-- name: UpdateDiscount :one
+UPDATE discounts
+SET name = sqlc.arg(name),
+    kind = sqlc.arg(kind),
+    value = sqlc.arg(value),
+    scope = sqlc.arg(scope),
+    item_id = sqlc.narg(item_id),
+    category_id = sqlc.narg(category_id),
+    min_spend = sqlc.narg(min_spend),
+    starts_at = sqlc.narg(starts_at),
+    ends_at = sqlc.narg(ends_at),
+    is_active = sqlc.arg(is_active),
+    updated_at = NOW()
+WHERE id = sqlc.arg(id)
+RETURNING *;
+
+-- name: DeleteDiscount :execrows
+DELETE FROM discounts
+WHERE id = sqlc.arg(id) AND (sqlc.narg(business_id)::int IS NULL OR business_id = sqlc.narg(business_id));
+
+-- name: ListActiveDiscountsForStore :many
+SELECT d.* FROM discounts d
+JOIN branch br ON br.business_id = d.business_id
+JOIN store s ON s.branch_id = br.id
+WHERE s.id = $1
+  AND d.is_active
+  AND (d.starts_at IS NULL OR d.starts_at <= NOW())
+  AND (d.ends_at IS NULL OR d.ends_at > NOW())
+  AND NOT EXISTS (SELECT 1 FROM coupons c WHERE c.discount_id = d.id)
+ORDER BY d.id;
+
+-- name: GetVariationItemCategory :one
+SELECT i.id AS item_id, i.category_id
+FROM variation v
+JOIN item i ON i.id = v.item_id
+WHERE v.id = $1;
+
+-- name: CreateSaleDiscount :one
+INSERT INTO sale_discount (sale_id, discount_id, name, amount, coupon_id)
+VALUES ($1, $2, $3, $4, $5)
+RETURNING *;
+
+-- name: CreateCoupon :one
+INSERT INTO coupons (business_id, discount_id, code, usage_limit, expires_at, is_active, created_by)
+VALUES ($1, $2, $3, $4, $5, $6, $7)
+RETURNING *;
+
+-- name: ListCoupons :many
+SELECT * FROM coupons
+WHERE (sqlc.narg(business_id)::int IS NULL OR business_id = sqlc.narg(business_id))
+ORDER BY id;
+
+-- name: DeleteCoupon :execrows
+DELETE FROM coupons
+WHERE id = sqlc.arg(id) AND (sqlc.narg(business_id)::int IS NULL OR business_id = sqlc.narg(business_id));
+
+-- name: GetCouponForStore :one
+-- The coupon with a code in the business of a store, with whether it or the
+-- discount it gives has run out and whether the discount is running yet.
+SELECT
+    c.id,
+    c.discount_id,
+    c.is_active,
+    c.usage_limit,
+    c.used_count,
+    ((c.expires_at IS NOT NULL AND c.expires_at <= NOW()) OR (d.ends_at IS NOT NULL AND d.ends_at <= NOW()))::boolean AS expired,
+    (d.is_active AND (d.starts_at IS NULL OR d.starts_at <= NOW()))::boolean AS discount_running
+FROM coupons c
+JOIN discounts d ON d.id = c.discount_id
+JOIN branch br ON br.business_id = c.business_id
+JOIN store s ON s.branch_id = br.id
+WHERE s.id = sqlc.arg(store_id) AND UPPER(c.code) = UPPER(sqlc.arg(code));
+
+-- name: RedeemCoupon :one
+-- Counts a use of a coupon unless it is used up. The row lock makes
+-- concurrent redemptions wait for each other, so the count never passes the
+-- limit.
+UPDATE coupons
+SET used_count = used_count + 1, updated_at = NOW()
+WHERE id = $1 AND (usage_limit IS NULL OR used_count < usage_limit)
+RETURNING *;
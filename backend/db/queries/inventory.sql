@@ -1,14 +1,18 @@
 -- Brand
 -- name: CreateBrand :one
-INSERT INTO brand (name, description, logo)
-VALUES ($1, $2, $3)
+INSERT INTO brand (name, description, logo, business_id, logo_thumbnail)
+VALUES ($1, $2, $3, $4, $5)
 RETURNING *;
 
 -- name: GetBrand :one
-SELECT * FROM brand WHERE id = $1 LIMIT 1;
+SELECT * FROM brand
+WHERE id = sqlc.arg(id) AND (sqlc.narg(business_id)::int IS NULL OR business_id = sqlc.narg(business_id))
+LIMIT 1;
 
 -- name: ListBrands :many
-SELECT * FROM brand ORDER BY name;
+SELECT * FROM brand
+WHERE (sqlc.narg(business_id)::int IS NULL OR business_id = sqlc.narg(business_id))
+ORDER BY name;
 
 -- name: UpdateBrand :one
 UPDATE brand
@@ -17,24 +21,29 @@ SET name = $2,
     logo = $4,
     is_active = $5,
     updated_at = NOW()
-WHERE id = $1
+WHERE id = $1 AND ($6::int IS NULL OR business_id = $6)
 RETURNING *;
 
 -- name: DeleteBrand :exec
-DELETE FROM brand WHERE id = $1;
+DELETE FROM brand
+WHERE id = sqlc.arg(id) AND (sqlc.narg(business_id)::int IS NULL OR business_id = sqlc.narg(business_id));
 
 
 -- Category
 -- name: CreateCategory :one
-INSERT INTO category (name, parent_id, description)
-VALUES ($1, $2, $3)
+INSERT INTO category (name, parent_id, description, business_id, depletion_strategy)
+VALUES ($1, $2, $3, $4, $5)
 RETURNING *;
 
 -- name: GetCategory :one
-SELECT * FROM category WHERE id = $1 LIMIT 1;
+SELECT * FROM category
+WHERE id = sqlc.arg(id) AND (sqlc.narg(business_id)::int IS NULL OR business_id = sqlc.narg(business_id))
+LIMIT 1;
 
 -- name: ListCategories :many
-SELECT * FROM category ORDER BY name;
+SELECT * FROM category
+WHERE (sqlc.narg(business_id)::int IS NULL OR business_id = sqlc.narg(business_id))
+ORDER BY name;
 
 -- name: UpdateCategory :one
 UPDATE category
@@ -42,44 +51,76 @@ SET name = $2,
     parent_id = $3,
     description = $4,
     is_active = $5,
+    depletion_strategy = $7,
     updated_at = NOW()
-WHERE id = $1
+WHERE id = $1 AND ($6::int IS NULL OR business_id = $6)
 RETURNING *;
 
+-- name: GetCategoryAncestors :many
+-- Walks up the parent chain of a category, the category itself included.
+-- UNION stops the walk if the chain already loops.
+WITH RECURSIVE ancestors AS (
+    SELECT id, parent_id FROM category WHERE id = $1
+    UNION
+    SELECT c.id, c.parent_id FROM category c
+    JOIN ancestors a ON c.id = a.parent_id
+)
+SELECT id FROM ancestors;
+
 -- name: DeleteCategory :exec
-DELETE FROM category WHERE id = $1;
+DELETE FROM category
+WHERE id = sqlc.arg(id) AND (sqlc.narg(business_id)::int IS NULL OR business_id = sqlc.narg(business_id));
 
 
 -- Item
 -- name: CreateItem :one
-INSERT INTO item (brand_id, category_id, name, description, item_type, no_variants)
-VALUES ($1, $2, $3, $4, $5, $6)
+INSERT INTO item (brand_id, category_id, name, description, item_type, no_variants, business_id)
+VALUES ($1, $2, $3, $4, $5, $6, $7)
 RETURNING *;
 
 -- name: UpdateItem :one
 UPDATE item
-SET brand_id = $2,
-    category_id = $3,
-    name = $4,
-    description = $5,
-    item_type = $6,
-    no_variants = $7,
-    is_active = $8,
+SET brand_id = COALESCE(sqlc.narg(brand_id), brand_id),
+    category_id = COALESCE(sqlc.narg(category_id), category_id),
+    name = COALESCE(sqlc.narg(name), name),
+    description = COALESCE(sqlc.narg(description), description),
+    item_type = COALESCE(sqlc.narg(item_type), item_type),
+    no_variants = COALESCE(sqlc.narg(no_variants), no_variants),
+    is_active = COALESCE(sqlc.narg(is_active), is_active),
+    version = version + 1,
     updated_at = NOW()
-WHERE id = $1
+WHERE id = sqlc.arg(id) AND (sqlc.narg(business_id)::int IS NULL OR business_id = sqlc.narg(business_id))
+  AND (sqlc.narg(expected_version)::int IS NULL OR version = sqlc.narg(expected_version))
 RETURNING *;
 
 -- name: GetItem :one
-SELECT * FROM item WHERE id = $1 LIMIT 1;
+SELECT * FROM item
+WHERE id = sqlc.arg(id) AND (sqlc.narg(business_id)::int IS NULL OR business_id = sqlc.narg(business_id))
+LIMIT 1;
 
 -- name: ListItems :many
-SELECT * FROM item ORDER BY name;
+SELECT i.*, COUNT(v.id) AS variation_count
+FROM item i
+LEFT JOIN variation v ON v.item_id = i.id
+WHERE (sqlc.narg(business_id)::int IS NULL OR i.business_id = sqlc.narg(business_id))
+  AND (sqlc.narg(category_id)::int IS NULL OR i.category_id = sqlc.narg(category_id))
+  AND (sqlc.narg(brand_id)::int IS NULL OR i.brand_id = sqlc.narg(brand_id))
+GROUP BY i.id
+ORDER BY i.name;
+
+-- name: CountItemsByCategory :many
+SELECT category_id, COUNT(*)::bigint AS item_count FROM item
+WHERE (sqlc.narg(business_id)::int IS NULL OR business_id = sqlc.narg(business_id))
+GROUP BY category_id;
 
 -- name: ListItemsByCategory :many
-SELECT * FROM item WHERE category_id = $1 ORDER BY name;
+SELECT * FROM item
+WHERE category_id = sqlc.arg(category_id) AND (sqlc.narg(business_id)::int IS NULL OR business_id = sqlc.narg(business_id))
+ORDER BY name;
 
 -- name: DeleteItem :exec
-DELETE FROM item WHERE id = $1;
+DELETE FROM item
+WHERE id = sqlc.arg(id) AND (sqlc.narg(business_id)::int IS NULL OR business_id = sqlc.narg(business_id));
 
 
 -- Variation
@@ -111,6 +152,9 @@ SELECT * FROM variation WHERE id = $1 LIMIT 1;
 -- name: ListVariationsByItem :many
 SELECT * FROM variation WHERE item_id = $1 ORDER BY name;
 
+-- name: CountActiveVariationsByItem :one
+SELECT COUNT(*) FROM variation WHERE item_id = $1 AND is_active = TRUE;
+
 -- name: DeleteVariation :exec
 DELETE FROM variation WHERE id = $1;
 
@@ -218,3 +262,199 @@ RETURNING *;
 DELETE FROM color
 WHERE id = $1
 RETURNING *;
+
+-- name: IncrementInventoryQuantity :one
+INSERT INTO inventory (store_id, variation_id, quantity)
+VALUES ($1, $2, $3)
+ON CONFLICT (store_id, variation_id)
+DO UPDATE SET
+    quantity = inventory.quantity + EXCLUDED.quantity,
+    last_updated = NOW()
+RETURNING *;
+
+-- name: SetInventoryMinKeep :one
+INSERT INTO inventory (store_id, variation_id, min_keep)
+VALUES ($1, $2, $3)
+ON CONFLICT (store_id, variation_id)
+DO UPDATE SET
+    min_keep = EXCLUDED.min_keep,
+    last_updated = NOW()
+RETURNING *;
+
+-- name: ListLowStockVariations :many
+SELECT
+    inv.store_id,
+    s.name AS store_name,
+    s.address AS store_address,
+    inv.variation_id,
+    v.sku,
+    v.name AS variation_name,
+    i.name AS item_name,
+    inv.quantity,
+    COALESCE(sqlc.narg(threshold)::int, b.low_stock_threshold, 0)::int AS threshold
+FROM inventory inv
+JOIN store s ON s.id = inv.store_id
+JOIN branch br ON br.id = s.branch_id
+JOIN business b ON b.id = br.business_id
+JOIN variation v ON v.id = inv.variation_id
+JOIN item i ON i.id = v.item_id
+WHERE (sqlc.narg(business_id)::int IS NULL OR b.id = sqlc.narg(business_id))
+  AND (sqlc.narg(store_id)::int IS NULL OR inv.store_id = sqlc.narg(store_id))
+  AND inv.quantity <= COALESCE(sqlc.narg(threshold)::int, b.low_stock_threshold, 0)
+ORDER BY inv.quantity, inv.store_id, inv.variation_id;
+
+-- name: ReceiveInventoryBatch :one
+INSERT INTO inventory_batch (store_id, variation_id, lot_number, expiry_date, quantity)
+VALUES ($1, $2, $3, $4, $5)
+ON CONFLICT (store_id, variation_id, lot_number)
+DO UPDATE SET
+    quantity = inventory_batch.quantity + EXCLUDED.quantity,
+    expiry_date = EXCLUDED.expiry_date,
+    updated_at = NOW()
+RETURNING *;
+
+-- name: ConsumeInventoryBatches :many
+WITH strategy AS (
+    SELECT COALESCE(c.depletion_strategy, bu.depletion_strategy) AS depletion_strategy
+    FROM store s
+    JOIN branch br ON br.id = s.branch_id
+    JOIN business bu ON bu.id = br.business_id
+    JOIN variation v ON v.id = sqlc.arg(variation_id)
+    JOIN item i ON i.id = v.item_id
+    LEFT JOIN category c ON c.id = i.category_id
+    WHERE s.id = sqlc.arg(store_id)
+), ordered AS (
+    SELECT ib.id, ib.quantity,
+        SUM(ib.quantity) OVER (
+            ORDER BY CASE WHEN st.depletion_strategy = 'fifo' THEN ib.created_at ELSE ib.expiry_date END, ib.id
+        ) - ib.quantity AS taken_before
+    FROM inventory_batch ib
+    LEFT JOIN strategy st ON TRUE
+    WHERE ib.store_id = sqlc.arg(store_id) AND ib.variation_id = sqlc.arg(variation_id) AND ib.quantity > 0
+), consumed AS (
+    UPDATE inventory_batch b
+    SET quantity = b.quantity - LEAST(o.quantity, sqlc.arg(quantity)::int - o.taken_before),
+        updated_at = NOW()
+    FROM ordered o
+    WHERE b.id = o.id AND o.taken_before < sqlc.arg(quantity)::int
+    RETURNING b.id, LEAST(o.quantity, sqlc.arg(quantity)::int - o.taken_before) AS taken
+)
+INSERT INTO inventory_batch_movement (batch_id, quantity, reason, reference_id)
+SELECT id, taken, sqlc.arg(reason), sqlc.narg(reference_id)
+FROM consumed
+RETURNING *;
+
+-- name: CreateStockAdjustment :one
+INSERT INTO stock_adjustment (store_id, variation_id, previous_quantity, quantity, reason, adjusted_by)
+VALUES ($1, $2, $3, $4, $5, $6)
+RETURNING *;
+
+-- name: ListExpiringBatches :many
+SELECT
+    ib.id,
+    ib.store_id,
+    s.name AS store_name,
+    ib.variation_id,
+    v.sku,
+    v.name AS variation_name,
+    i.name AS item_name,
+    ib.lot_number,
+    ib.expiry_date,
+    ib.quantity,
+    (ib.expiry_date - (NOW() AT TIME ZONE COALESCE(b.timezone, 'UTC'))::date)::int AS days_left
+FROM inventory_batch ib
+JOIN store s ON s.id = ib.store_id
+JOIN branch br ON br.id = s.branch_id
+JOIN business b ON b.id = br.business_id
+JOIN variation v ON v.id = ib.variation_id
+JOIN item i ON i.id = v.item_id
+WHERE ib.quantity > 0
+  AND ib.expiry_date <= (NOW() AT TIME ZONE COALESCE(b.timezone, 'UTC'))::date + sqlc.arg(within_days)::int
+  AND (sqlc.narg(business_id)::int IS NULL OR b.id = sqlc.narg(business_id))
+  AND (sqlc.narg(store_id)::int IS NULL OR ib.store_id = sqlc.narg(store_id))
+ORDER BY ib.expiry_date, ib.store_id, ib.id;
+
+-- name: GetStoreInBusiness :one
+SELECT s.id
+FROM store s
+JOIN branch br ON br.id = s.branch_id
+WHERE s.id = sqlc.arg(id)
+  AND (sqlc.narg(business_id)::int IS NULL OR br.business_id = sqlc.narg(business_id));
+
+-- name: GetVariationPriceForUpdate :one
+SELECT base_price FROM variation
+WHERE id = $1
+FOR UPDATE;
+
+-- name: UpdateVariationPrice :one
+UPDATE variation
+SET base_price = $2,
+    updated_at = NOW()
+WHERE id = $1
+RETURNING *;
+
+-- name: CreatePriceHistory :one
+INSERT INTO price_history (variation_id, old_price, new_price, changed_by)
+VALUES ($1, $2, $3, $4)
+RETURNING *;
+
+-- name: ListPriceHistory :many
+SELECT * FROM price_history
+WHERE variation_id = $1
+ORDER BY changed_at, id;
+
+-- name: GetVariationInBusiness :one
+SELECT v.id
+FROM variation v
+JOIN item i ON i.id = v.item_id
+WHERE v.id = sqlc.arg(id)
+  AND (sqlc.narg(business_id)::int IS NULL OR i.business_id = sqlc.narg(business_id));
+
+-- name: SetVariationCostPrice :exec
+UPDATE variation
+SET cost_price = $2,
+    updated_at = NOW()
+WHERE id = $1;
+
+-- name: SearchItems :many
+-- Items whose name or description, or one of whose variations' name, SKU or
+-- barcode, match query, best match first. The expressions match the search
+-- indexes, keep them in sync.
+WITH matches AS (
+    SELECT i.id, ts_rank(to_tsvector('simple', i.name || ' ' || COALESCE(i.description, '')), to_tsquery('simple', sqlc.arg(query))) AS rank
+    FROM item i
+    WHERE to_tsvector('simple', i.name || ' ' || COALESCE(i.description, '')) @@ to_tsquery('simple', sqlc.arg(query))
+      AND (sqlc.narg(business_id)::int IS NULL OR i.business_id = sqlc.narg(business_id))
+    UNION ALL
+    SELECT v.item_id, ts_rank(to_tsvector('simple', v.name || ' ' || v.sku || ' ' || COALESCE(v.barcode, '')), to_tsquery('simple', sqlc.arg(query)))
+    FROM variation v
+    JOIN item i ON i.id = v.item_id
+    WHERE to_tsvector('simple', v.name || ' ' || v.sku || ' ' || COALESCE(v.barcode, '')) @@ to_tsquery('simple', sqlc.arg(query))
+      AND (sqlc.narg(business_id)::int IS NULL OR i.business_id = sqlc.narg(business_id))
+)
+SELECT i.id, i.brand_id, i.category_id, i.name, i.description, i.item_type, i.is_active,
+       MAX(m.rank)::real AS rank
+FROM matches m
+JOIN item i ON i.id = m.id
+GROUP BY i.id
+ORDER BY rank DESC, i.name, i.id
+LIMIT sqlc.arg(page_limit) OFFSET sqlc.arg(page_offset);
+
+-- name: CountSearchItems :one
+SELECT COUNT(*) FROM item i
+WHERE (sqlc.narg(business_id)::int IS NULL OR i.business_id = sqlc.narg(business_id))
+  AND (to_tsvector('simple', i.name || ' ' || COALESCE(i.description, '')) @@ to_tsquery('simple', sqlc.arg(query))
+       OR EXISTS (
+           SELECT 1 FROM variation v
+           WHERE v.item_id = i.id
+             AND to_tsvector('simple', v.name || ' ' || v.sku || ' ' || COALESCE(v.barcode, '')) @@ to_tsquery('simple', sqlc.arg(query))
+       ));
+
+-- name: ListSearchVariations :many
+-- The variations of the items found by SearchItems, flagging the ones that
+-- match query themselves.
+SELECT v.*,
+       (to_tsvector('simple', v.name || ' ' || v.sku || ' ' || COALESCE(v.barcode, '')) @@ to_tsquery('simple', sqlc.arg(query)))::bool AS matched
+FROM variation v
+WHERE v.item_id = ANY(sqlc.arg(item_ids)::int[])
+ORDER BY v.item_id, matched DESC, v.id;
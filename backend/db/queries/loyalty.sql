@@ -0,0 +1,49 @@
+-- name: UpsertLoyaltyRule :one
+INSERT INTO loyalty_rule (business_id, earn_rate, rounding)
+VALUES ($1, $2, $3)
+ON CONFLICT (business_id)
+DO UPDATE SET
+    earn_rate = EXCLUDED.earn_rate,
+    rounding = EXCLUDED.rounding,
+    updated_at = NOW()
+RETURNING *;
+
+-- name: GetLoyaltyRuleForStore :one
+SELECT lr.* FROM loyalty_rule lr
+JOIN branch br ON br.business_id = lr.business_id
+JOIN store s ON s.branch_id = br.id
+WHERE s.id = $1;
+
+-- name: CreateLoyaltyPoints :one
+INSERT INTO loyalty_points (customer_id, sale_id, refund_id, points)
+VALUES ($1, $2, $3, $4)
+RETURNING *;
+
+-- name: GetSaleLoyaltyPoints :one
+SELECT
+    COALESCE(SUM(points) FILTER (WHERE refund_id IS NULL), 0)::int AS earned,
+    COALESCE(-SUM(points) FILTER (WHERE refund_id IS NOT NULL), 0)::int AS reversed
+FROM loyalty_points
+WHERE sale_id = $1;
+
+-- name: GetCustomerLoyaltyBalance :one
+SELECT COALESCE(SUM(points), 0)::int AS balance
+FROM loyalty_points
+WHERE customer_id = $1;
+
+-- name: GetCustomerLoyaltyBalanceInBusiness :one
+-- the points the customer earned on sales in the business, less the ones
+-- taken back. No row for a customer who never bought anything there.
+SELECT COALESCE(SUM(lp.points), 0)::int AS balance
+FROM sale s
+JOIN store st ON st.id = s.store_id
+JOIN branch br ON br.id = st.branch_id
+LEFT JOIN loyalty_points lp ON lp.sale_id = s.id AND lp.customer_id = s.customer_id
+WHERE s.customer_id = sqlc.arg(customer_id)
+  AND (sqlc.narg(business_id)::int IS NULL OR br.business_id = sqlc.narg(business_id))
+HAVING COUNT(s.id) > 0;
+
+-- name: ListSaleLoyaltyPoints :many
+SELECT * FROM loyalty_points
+WHERE sale_id = $1
+ORDER BY id;
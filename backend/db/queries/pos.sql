@@ -0,0 +1,258 @@
+-- name: CreateSale :one
+INSERT INTO sale (store_id, customer_id, cashier_id, subtotal, discount_amount, tax_amount, total_amount)
+VALUES ($1, $2, $3, $4, $5, $6, $7)
+RETURNING *;
+
+-- name: CreateSaleItem :one
+INSERT INTO sale_item (sale_id, variation_id, quantity, unit_price, unit_cost)
+VALUES ($1, $2, $3, $4, (SELECT cost_price FROM variation WHERE id = $2))
+RETURNING *;
+
+-- name: CreateSalePayment :one
+INSERT INTO sale_payment (sale_id, method, amount, reference)
+VALUES ($1, $2, $3, $4)
+RETURNING *;
+
+-- name: GetStorePaymentSettings :one
+SELECT COALESCE(b.payment_type, ARRAY['cash']::payment_type[])::payment_type[] AS payment_type,
+       COALESCE(b.rounding, 'nearest')::text AS rounding,
+       COALESCE(b.tax_rate, 0)::text AS tax_rate
+FROM store s
+JOIN branch br ON br.id = s.branch_id
+JOIN business b ON b.id = br.business_id
+WHERE s.id = $1;
+
+-- name: GetStoreBusinessID :one
+SELECT br.business_id
+FROM store s
+JOIN branch br ON br.id = s.branch_id
+WHERE s.id = $1;
+
+-- name: GetVariationSalePrice :one
+SELECT COALESCE(sp.price, v.base_price)::text AS price
+FROM variation v
+JOIN item i ON i.id = v.item_id
+JOIN store s ON s.id = sqlc.arg(store_id)
+JOIN branch br ON br.id = s.branch_id
+LEFT JOIN store_price sp ON sp.store_id = s.id AND sp.variation_id = v.id
+WHERE v.id = sqlc.arg(variation_id)
+  AND i.business_id = br.business_id;
+
+-- name: IsCustomerOfOtherBusiness :one
+SELECT COALESCE(bool_and(br.business_id <> sqlc.arg(business_id)), FALSE)::bool AS other
+FROM sale s
+JOIN store st ON st.id = s.store_id
+JOIN branch br ON br.id = st.branch_id
+WHERE s.customer_id = sqlc.arg(customer_id);
+
+-- name: GetFolioForSaleForUpdate :one
+SELECT f.* FROM folio f
+JOIN branch br ON br.business_id = f.business_id
+JOIN store s ON s.branch_id = br.id
+JOIN sale sa ON sa.store_id = s.id
+WHERE f.id = sqlc.arg(folio_id) AND sa.id = sqlc.arg(sale_id)
+FOR UPDATE OF f;
+
+-- name: CreateFolioCharge :one
+INSERT INTO folio_charge (folio_id, sale_id, amount)
+VALUES ($1, $2, $3)
+RETURNING *;
+
+-- name: GetSaleForUpdate :one
+SELECT s.* FROM sale s
+JOIN store st ON st.id = s.store_id
+JOIN branch br ON br.id = st.branch_id
+WHERE s.id = sqlc.arg(id)
+  AND (sqlc.narg(business_id)::int IS NULL OR br.business_id = sqlc.narg(business_id))
+FOR UPDATE OF s;
+
+-- name: ListSaleItemsForUpdate :many
+SELECT * FROM sale_item
+WHERE sale_id = $1
+ORDER BY id
+FOR UPDATE;
+
+-- name: AddSaleItemRefundedQuantity :one
+UPDATE sale_item
+SET refunded_quantity = refunded_quantity + $2
+WHERE id = $1
+RETURNING *;
+
+-- name: UpdateSaleStatus :one
+UPDATE sale
+SET status = $2,
+    updated_at = NOW()
+WHERE id = $1
+RETURNING *;
+
+-- name: CreateRefund :one
+INSERT INTO refund (sale_id, refunded_by, total_amount)
+VALUES ($1, $2, $3)
+RETURNING *;
+
+-- name: CreateRefundItem :one
+INSERT INTO refund_item (refund_id, sale_item_id, quantity, amount)
+VALUES ($1, $2, $3, $4)
+RETURNING *;
+
+-- name: GetSaleInBusiness :one
+SELECT s.* FROM sale s
+JOIN store st ON st.id = s.store_id
+JOIN branch br ON br.id = st.branch_id
+WHERE s.id = sqlc.arg(id)
+  AND (sqlc.narg(business_id)::int IS NULL OR br.business_id = sqlc.narg(business_id));
+
+-- name: ListSaleItems :many
+SELECT * FROM sale_item
+WHERE sale_id = $1
+ORDER BY id;
+
+-- name: ListSaleRefundItems :many
+SELECT
+    ri.refund_id,
+    r.refunded_by,
+    r.total_amount,
+    r.created_at,
+    si.variation_id,
+    ri.quantity,
+    ri.amount
+FROM refund_item ri
+JOIN refund r ON r.id = ri.refund_id
+JOIN sale_item si ON si.id = ri.sale_item_id
+WHERE r.sale_id = $1
+ORDER BY r.id, ri.id;
+
+-- name: ListSaleActivity :many
+SELECT * FROM activity_logs
+WHERE (entity_type = 'Sale' AND entity_id = sqlc.arg(sale_id))
+   OR (entity_type = 'Refund' AND entity_id IN (SELECT id FROM refund WHERE sale_id = sqlc.arg(sale_id)))
+ORDER BY created_at, id;
+
+-- name: GetSaleReceiptHeader :one
+SELECT b.name AS business_name, b.logo_url, b.motto, b.currency,
+       st.name AS store_name, st.address, st.phone,
+       COALESCE(
+           NULLIF(TRIM(u.first_name || ' ' || u.last_name), ''),
+           NULLIF(TRIM(a.first_name || ' ' || a.last_name), ''),
+           ''
+       )::text AS cashier_name
+FROM sale s
+JOIN store st ON st.id = s.store_id
+JOIN branch br ON br.id = st.branch_id
+JOIN business b ON b.id = br.business_id
+LEFT JOIN users u ON u.id = s.cashier_id
+LEFT JOIN admins a ON a.id = s.cashier_id AND u.id IS NULL
+WHERE s.id = $1;
+
+-- name: ListSaleReceiptItems :many
+SELECT si.variation_id, i.name AS item_name, v.name AS variation_name, si.quantity, si.unit_price
+FROM sale_item si
+JOIN variation v ON v.id = si.variation_id
+JOIN item i ON i.id = v.item_id
+WHERE si.sale_id = $1
+ORDER BY si.id;
+
+-- name: ListSalePayments :many
+SELECT * FROM sale_payment
+WHERE sale_id = $1
+ORDER BY id;
+
+-- name: GetStoreReportTimezone :one
+SELECT COALESCE(b.timezone, 'UTC')::text AS timezone
+FROM store st
+JOIN branch br ON br.id = st.branch_id
+JOIN business b ON b.id = br.business_id
+WHERE st.id = sqlc.arg(store_id)
+  AND (sqlc.narg(business_id)::int IS NULL OR b.id = sqlc.narg(business_id));
+
+-- name: GetBusinessTimezone :one
+SELECT COALESCE(timezone, 'UTC')::text AS timezone
+FROM business
+WHERE id = $1;
+
+-- name: GetSalesSummary :one
+SELECT COUNT(*)::int AS transactions,
+       COALESCE(SUM(s.subtotal), 0)::numeric(12,2) AS gross_sales,
+       COALESCE(SUM(s.discount_amount), 0)::numeric(12,2) AS discounts,
+       COALESCE(SUM(s.tax_amount), 0)::numeric(12,2) AS tax,
+       COALESCE(SUM(s.total_amount), 0)::numeric(12,2) AS total
+FROM sale s
+JOIN store st ON st.id = s.store_id
+JOIN branch br ON br.id = st.branch_id
+WHERE s.created_at >= sqlc.arg(start_time) AND s.created_at < sqlc.arg(end_time)
+  AND (sqlc.narg(store_id)::int IS NULL OR s.store_id = sqlc.narg(store_id))
+  AND (sqlc.narg(business_id)::int IS NULL OR br.business_id = sqlc.narg(business_id));
+
+-- name: ListSalesByPaymentMethod :many
+SELECT sp.method,
+       COUNT(DISTINCT sp.sale_id)::int AS transactions,
+       SUM(sp.amount)::numeric(12,2) AS amount
+FROM sale_payment sp
+JOIN sale s ON s.id = sp.sale_id
+JOIN store st ON st.id = s.store_id
+JOIN branch br ON br.id = st.branch_id
+WHERE s.created_at >= sqlc.arg(start_time) AND s.created_at < sqlc.arg(end_time)
+  AND (sqlc.narg(store_id)::int IS NULL OR s.store_id = sqlc.narg(store_id))
+  AND (sqlc.narg(business_id)::int IS NULL OR br.business_id = sqlc.narg(business_id))
+GROUP BY sp.method
+ORDER BY sp.method;
+
+-- name: ListSalesByCategory :many
+SELECT c.id AS category_id, c.name AS category_name,
+       SUM(si.quantity)::int AS quantity,
+       SUM(si.quantity * si.unit_price)::numeric(12,2) AS amount
+FROM sale_item si
+JOIN sale s ON s.id = si.sale_id
+JOIN store st ON st.id = s.store_id
+JOIN branch br ON br.id = st.branch_id
+JOIN variation v ON v.id = si.variation_id
+JOIN item i ON i.id = v.item_id
+JOIN category c ON c.id = i.category_id
+WHERE s.created_at >= sqlc.arg(start_time) AND s.created_at < sqlc.arg(end_time)
+  AND (sqlc.narg(store_id)::int IS NULL OR s.store_id = sqlc.narg(store_id))
+  AND (sqlc.narg(business_id)::int IS NULL OR br.business_id = sqlc.narg(business_id))
+GROUP BY c.id, c.name
+ORDER BY amount DESC, c.name;
+
+-- name: ListMarginByItem :many
+SELECT COALESCE(c.id, 0)::int AS category_id,
+       COALESCE(c.name, 'Uncategorized')::text AS category_name,
+       i.id AS item_id,
+       i.name AS item_name,
+       SUM(si.quantity - si.refunded_quantity)::int AS quantity,
+       SUM(CASE WHEN si.unit_cost IS NULL THEN si.quantity - si.refunded_quantity ELSE 0 END)::int AS uncosted_quantity,
+       COALESCE(SUM((si.quantity - si.refunded_quantity) * si.unit_price), 0)::numeric(12,2) AS revenue,
+       COALESCE(SUM((si.quantity - si.refunded_quantity) * si.unit_cost), 0)::numeric(12,2) AS cost
+FROM sale_item si
+JOIN sale s ON s.id = si.sale_id
+JOIN store st ON st.id = s.store_id
+JOIN branch br ON br.id = st.branch_id
+JOIN variation v ON v.id = si.variation_id
+JOIN item i ON i.id = v.item_id
+LEFT JOIN category c ON c.id = i.category_id
+WHERE s.created_at >= sqlc.arg(start_time) AND s.created_at < sqlc.arg(end_time)
+  AND (sqlc.narg(store_id)::int IS NULL OR s.store_id = sqlc.narg(store_id))
+  AND (sqlc.narg(business_id)::int IS NULL OR br.business_id = sqlc.narg(business_id))
+GROUP BY c.id, c.name, i.id, i.name
+HAVING SUM(si.quantity - si.refunded_quantity) > 0
+ORDER BY category_name, c.id, revenue DESC, i.name;
+
+-- name: ListSales :many
+SELECT s.* FROM sale s
+JOIN store st ON st.id = s.store_id
+JOIN branch br ON br.id = st.branch_id
+WHERE (sqlc.narg(start_time)::timestamp IS NULL OR s.created_at >= sqlc.narg(start_time))
+  AND (sqlc.narg(end_time)::timestamp IS NULL OR s.created_at < sqlc.narg(end_time))
+  AND (sqlc.narg(store_id)::int IS NULL OR s.store_id = sqlc.narg(store_id))
+  AND (sqlc.narg(business_id)::int IS NULL OR br.business_id = sqlc.narg(business_id))
+ORDER BY s.created_at DESC, s.id DESC
+LIMIT sqlc.arg(page_limit) OFFSET sqlc.arg(page_offset);
+
+-- name: CountSales :one
+SELECT COUNT(*) FROM sale s
+JOIN store st ON st.id = s.store_id
+JOIN branch br ON br.id = st.branch_id
+WHERE (sqlc.narg(start_time)::timestamp IS NULL OR s.created_at >= sqlc.narg(start_time))
+  AND (sqlc.narg(end_time)::timestamp IS NULL OR s.created_at < sqlc.narg(end_time))
+  AND (sqlc.narg(store_id)::int IS NULL OR s.store_id = sqlc.narg(store_id))
+  AND (sqlc.narg(business_id)::int IS NULL OR br.business_id = sqlc.narg(business_id));
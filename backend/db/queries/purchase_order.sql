@@ -0,0 +1,84 @@
+-- name: CreatePurchaseOrder :one
+INSERT INTO purchase_order (store_id, supplier, reference, notes, created_by)
+VALUES ($1, $2, $3, $4, $5)
+RETURNING *;
+
+-- name: CreatePurchaseOrderLine :one
+INSERT INTO purchase_order_line (purchase_order_id, variation_id, quantity, unit_cost)
+VALUES ($1, $2, $3, $4)
+RETURNING *;
+
+-- name: DeletePurchaseOrderLines :exec
+DELETE FROM purchase_order_line
+WHERE purchase_order_id = $1;
+
+-- name: GetPurchaseOrder :one
+SELECT po.*
+FROM purchase_order po
+JOIN store s ON s.id = po.store_id
+JOIN branch br ON br.id = s.branch_id
+WHERE po.id = sqlc.arg(id)
+  AND (sqlc.narg(business_id)::int IS NULL OR br.business_id = sqlc.narg(business_id));
+
+-- name: GetPurchaseOrderForUpdate :one
+SELECT po.*
+FROM purchase_order po
+JOIN store s ON s.id = po.store_id
+JOIN branch br ON br.id = s.branch_id
+WHERE po.id = sqlc.arg(id)
+  AND (sqlc.narg(business_id)::int IS NULL OR br.business_id = sqlc.narg(business_id))
+FOR UPDATE OF po;
+
+-- name: ListPurchaseOrders :many
+SELECT po.*
+FROM purchase_order po
+JOIN store s ON s.id = po.store_id
+JOIN branch br ON br.id = s.branch_id
+WHERE (sqlc.narg(business_id)::int IS NULL OR br.business_id = sqlc.narg(business_id))
+  AND (sqlc.narg(store_id)::int IS NULL OR po.store_id = sqlc.narg(store_id))
+  AND (sqlc.narg(status)::text IS NULL OR po.status = sqlc.narg(status))
+ORDER BY po.created_at DESC, po.id DESC;
+
+-- name: ListPurchaseOrderLines :many
+SELECT * FROM purchase_order_line
+WHERE purchase_order_id = $1
+ORDER BY variation_id;
+
+-- name: UpdatePurchaseOrder :one
+UPDATE purchase_order
+SET supplier = COALESCE(sqlc.narg(supplier), supplier),
+    reference = COALESCE(sqlc.narg(reference), reference),
+    notes = COALESCE(sqlc.narg(notes), notes),
+    updated_at = NOW()
+WHERE id = sqlc.arg(id)
+RETURNING *;
+
+-- name: SetPurchaseOrderStatus :one
+UPDATE purchase_order
+SET status = sqlc.arg(status),
+    ordered_at = CASE WHEN sqlc.arg(status) = 'ordered' THEN NOW() ELSE ordered_at END,
+    received_at = CASE WHEN sqlc.arg(status) = 'received' THEN NOW() ELSE received_at END,
+    updated_at = NOW()
+WHERE id = sqlc.arg(id)
+RETURNING *;
+
+-- name: ReceivePurchaseOrderLine :one
+UPDATE purchase_order_line
+SET received_quantity = received_quantity + sqlc.arg(quantity)
+WHERE id = sqlc.arg(id)
+RETURNING *;
+
+-- name: CreatePurchaseOrderReceipt :one
+INSERT INTO purchase_order_receipt (purchase_order_id, line_id, store_id, variation_id, quantity, unit_cost, received_by)
+VALUES ($1, $2, $3, $4, $5, $6, $7)
+RETURNING *;
+
+-- name: CancelPurchaseOrder :one
+UPDATE purchase_order
+SET status = 'cancelled',
+    cancelled_by = sqlc.arg(cancelled_by)::int,
+    cancelled_at = NOW(),
+    cancel_reason = sqlc.narg(cancel_reason),
+    updated_at = NOW()
+WHERE id = sqlc.arg(id)
+RETURNING *;
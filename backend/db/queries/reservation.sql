@@ -0,0 +1,40 @@
+-- name: CreateReservation :one
+INSERT INTO inventory_reservation (store_id, variation_id, quantity, reserved_by, expires_at)
+VALUES ($1, $2, $3, $4, $5)
+RETURNING *;
+
+-- name: GetReservationForUpdate :one
+SELECT * FROM inventory_reservation
+WHERE id = $1
+FOR UPDATE;
+
+-- name: GetInventoryItemForUpdate :one
+SELECT * FROM inventory
+WHERE store_id = $1 AND variation_id = $2
+FOR UPDATE;
+
+-- name: SumActiveReservations :one
+SELECT COALESCE(SUM(quantity), 0)::int AS reserved
+FROM inventory_reservation
+WHERE store_id = $1 AND variation_id = $2
+  AND status = 'active' AND expires_at > NOW();
+
+-- name: ConsumeReservation :one
+UPDATE inventory_reservation
+SET status = 'consumed',
+    updated_at = NOW()
+WHERE id = $1 AND status = 'active'
+RETURNING *;
+
+-- name: ReleaseExpiredReservations :execrows
+UPDATE inventory_reservation
+SET status = 'released',
+    updated_at = NOW()
+WHERE status = 'active' AND expires_at <= NOW();
+
+-- name: DecrementInventoryQuantity :one
+UPDATE inventory
+SET quantity = quantity - $3,
+    last_updated = NOW()
+WHERE store_id = $1 AND variation_id = $2
+RETURNING *;
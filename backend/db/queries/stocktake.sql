@@ -0,0 +1,44 @@
+-- name: CreateStocktake :one
+INSERT INTO stocktake (store_id, opened_by)
+VALUES ($1, $2)
+RETURNING *;
+
+-- name: SnapshotStocktakeLines :execrows
+INSERT INTO stocktake_line (stocktake_id, variation_id, system_quantity)
+SELECT sqlc.arg(stocktake_id), inv.variation_id, inv.quantity
+FROM inventory inv
+WHERE inv.store_id = sqlc.arg(store_id);
+
+-- name: GetStocktake :one
+SELECT * FROM stocktake
+WHERE id = $1 AND store_id = $2;
+
+-- name: GetStocktakeForUpdate :one
+SELECT * FROM stocktake
+WHERE id = $1 AND store_id = $2
+FOR UPDATE;
+
+-- name: ListStocktakeLines :many
+SELECT * FROM stocktake_line
+WHERE stocktake_id = $1
+ORDER BY variation_id;
+
+-- name: RecordStocktakeCount :one
+INSERT INTO stocktake_line (stocktake_id, variation_id, system_quantity, counted_quantity)
+VALUES ($1, $2, 0, $3)
+ON CONFLICT (stocktake_id, variation_id)
+DO UPDATE SET counted_quantity = EXCLUDED.counted_quantity
+RETURNING *;
+
+-- name: SetStocktakeLineAdjustment :exec
+UPDATE stocktake_line
+SET adjustment_id = $2
+WHERE id = $1;
+
+-- name: CommitStocktake :one
+UPDATE stocktake
+SET status = 'committed',
+    committed_by = $2,
+    committed_at = NOW()
+WHERE id = $1
+RETURNING *;
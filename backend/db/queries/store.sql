@@ -21,18 +21,17 @@ SELECT * FROM store ORDER BY created_at DESC;
 
 -- name: UpdateStore :one
 UPDATE store
-SET name = $2,
-    description = $3,
-    address = $4,
-    phone = $5,
-    email = $6,
-    is_active = $7,
-    store_code = $8,
-    store_type = $9,
-    assigned_user = $10,
-    manager_id = $11,
+SET name = COALESCE(sqlc.narg(name), name),
+    description = COALESCE(sqlc.narg(description), description),
+    address = COALESCE(sqlc.narg(address), address),
+    phone = COALESCE(sqlc.narg(phone), phone),
+    email = COALESCE(sqlc.narg(email), email),
+    is_active = COALESCE(sqlc.narg(is_active), is_active),
+    store_type = COALESCE(sqlc.narg(store_type), store_type),
+    assigned_user = COALESCE(sqlc.narg(assigned_user), assigned_user),
+    manager_id = COALESCE(sqlc.narg(manager_id), manager_id),
     updated_at = NOW()
-WHERE id = $1
+WHERE id = sqlc.arg(id)
 RETURNING *;
 
 -- name: DeleteStore :exec
@@ -49,3 +48,33 @@ RETURNING *;
 SELECT * FROM store
 WHERE name ILIKE '%' || $1 || '%'
 ORDER BY name;
+
+-- name: GetStoreAllowOverselling :one
+SELECT COALESCE(b.allow_overselling, FALSE)::boolean AS allow_overselling
+FROM store s
+JOIN branch br ON br.id = s.branch_id
+JOIN business b ON b.id = br.business_id
+WHERE s.id = $1;
+
+-- name: CreateStoreTransfer :one
+INSERT INTO store_transfer (from_store_id, to_store_id, transferred_by)
+VALUES ($1, $2, $3)
+RETURNING *;
+
+-- name: CreateStoreTransferItem :one
+INSERT INTO store_transfer_item (transfer_id, variation_id, quantity)
+VALUES ($1, $2, $3)
+RETURNING *;
+
+-- name: GetStoreForOwner :one
+SELECT s.*
+FROM store s
+JOIN branch br ON br.id = s.branch_id
+JOIN business b ON b.id = br.business_id
+WHERE s.id = sqlc.arg(id) AND b.owner_id = sqlc.arg(owner_id);
+
+-- name: CountSubStoresByBranch :one
+SELECT COUNT(*) FROM store WHERE branch_id = $1 AND store_type = 'sub-store';
+
+-- name: CountStoreStock :one
+SELECT COUNT(*) FROM inventory WHERE store_id = $1 AND quantity <> 0;
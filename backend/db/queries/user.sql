@@ -1,6 +1,6 @@
 -- name: CreateUser :one
-INSERT INTO users (username, first_name, last_name, email, password_hash, gender, role_id, is_active)
-VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+INSERT INTO users (username, first_name, last_name, email, password_hash, gender, role_id, is_active, branch_id, store_id)
+VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)
 RETURNING *;
 
 -- name: UpdateUser :one
@@ -11,22 +11,44 @@ SET username   = COALESCE(sqlc.narg(username), username),
     email      = COALESCE(sqlc.narg(email), email),
     gender     = COALESCE(sqlc.narg(gender), gender),
     role_id    = COALESCE(sqlc.narg(role_id), role_id),
-    is_active  = COALESCE(sqlc.narg(is_active), is_active)
-WHERE id = sqlc.arg(id)
+    is_active  = COALESCE(sqlc.narg(is_active), is_active),
+    -- the branch and store are replaced together, with NULL for none
+    branch_id  = CASE WHEN sqlc.arg(set_store)::bool THEN sqlc.narg(branch_id) ELSE branch_id END,
+    store_id   = CASE WHEN sqlc.arg(set_store)::bool THEN sqlc.narg(store_id) ELSE store_id END
+WHERE id = sqlc.arg(id) AND deleted_at IS NULL
 RETURNING *;
 
--- name: DeleteUser :exec
-DELETE FROM users WHERE id = $1;
+-- name: DeleteUser :execrows
+-- soft delete, a deleted user is deactivated too so it can't log in
+UPDATE users
+SET deleted_at = CURRENT_TIMESTAMP, is_active = FALSE, updated_at = CURRENT_TIMESTAMP
+WHERE id = $1 AND deleted_at IS NULL;
+
+-- name: RestoreUser :one
+UPDATE users
+SET deleted_at = NULL, is_active = TRUE, updated_at = CURRENT_TIMESTAMP
+WHERE id = $1 AND deleted_at IS NOT NULL
+RETURNING *;
 
 -- name: GetUserByID :one
 SELECT u.*, r.name as role_name FROM users u
 JOIN roles r ON u.role_id = r.id
-WHERE u.id = $1 LIMIT 1;
+WHERE u.id = $1 AND u.deleted_at IS NULL LIMIT 1;
 
 -- name: ListUsers :many
 SELECT u.*, r.name as role_name FROM users u
 JOIN roles r ON u.role_id = r.id
-ORDER BY u.created_at DESC;
+WHERE sqlc.arg(include_deleted)::bool OR u.deleted_at IS NULL
+ORDER BY u.created_at DESC, u.id DESC
+LIMIT sqlc.arg(page_limit) OFFSET sqlc.arg(page_offset);
+
+-- name: CountUsers :one
+SELECT COUNT(*) FROM users u
+JOIN roles r ON u.role_id = r.id
+WHERE sqlc.arg(include_deleted)::bool OR u.deleted_at IS NULL;
+
+-- name: ListUserIDsByRole :many
+SELECT id FROM users WHERE role_id = $1;
 
 -- name: UpdateUserPassword :exec
 UPDATE users
@@ -82,9 +104,29 @@ FROM admins a
 JOIN roles r ON a.role_id = r.id
 WHERE a.username = $1 LIMIT 1;
 
+-- name: GetAdminByID :one
+SELECT
+    a.id,
+    a.username,
+    a.first_name,
+    a.last_name,
+    a.email,
+    a.password_hash,
+    a.is_active,
+    a.email_verified,
+    a.verification_code,
+    a.verification_expires_at,
+    a.reset_code,
+    a.reset_code_expires_at,
+    r.name as role_name
+FROM admins a
+JOIN roles r ON a.role_id = r.id
+WHERE a.id = $1 LIMIT 1;
+
 -- name: SetAdminEmailVerification :exec
 UPDATE admins
-SET verification_code = $2,
+SET email_verified = FALSE,
+    verification_code = $2,
     verification_expires_at = $3,
     updated_at = NOW()
 WHERE id = $1;
@@ -97,11 +139,40 @@ SET email_verified = $2,
     updated_at = NOW()
 WHERE id = $1;
 
+-- name: SetUserEmailVerification :exec
+UPDATE users
+SET email_verified = FALSE,
+    verification_code = $2,
+    verification_expires_at = $3,
+    updated_at = NOW()
+WHERE id = $1;
+
+-- name: MarkUserEmailVerified :exec
+UPDATE users
+SET email_verified = TRUE,
+    verification_code = NULL,
+    verification_expires_at = NULL,
+    updated_at = NOW()
+WHERE id = $1;
+
 -- name: UpdateAdminPassword :exec
 UPDATE admins
 SET password_hash = $2, updated_at = CURRENT_TIMESTAMP
 WHERE id = $1;
 
+-- name: UpdateAdminProfile :one
+UPDATE admins
+SET first_name = COALESCE(sqlc.narg(first_name), first_name),
+    last_name  = COALESCE(sqlc.narg(last_name), last_name),
+    email      = COALESCE(sqlc.narg(email), email),
+    email_verified = CASE
+        WHEN sqlc.narg(email) IS NULL OR sqlc.narg(email) = email THEN email_verified
+        ELSE FALSE
+    END,
+    updated_at = NOW()
+WHERE id = sqlc.arg(id)
+RETURNING *;
+
 -- name: ClearAdminResetCode :exec
 UPDATE admins
 SET reset_code = NULL,
@@ -116,6 +187,32 @@ SET reset_code = $2,
     updated_at = NOW()
 WHERE id = $1;
 
+-- name: SetUserResetCode :exec
+INSERT INTO user_reset_code (user_id, code, expires_at)
+VALUES ($1, $2, $3)
+ON CONFLICT (user_id) DO UPDATE SET
+    code = EXCLUDED.code,
+    expires_at = EXCLUDED.expires_at;
+
+-- name: GetUserResetCode :one
+SELECT * FROM user_reset_code
+WHERE user_id = $1;
+
+-- name: ClearUserResetCode :exec
+DELETE FROM user_reset_code WHERE user_id = $1;
+
+-- name: CreateUserInvite :exec
+INSERT INTO user_invite (user_id, token, expires_at)
+VALUES ($1, $2, $3);
+
+-- name: GetUserInviteByToken :one
+SELECT i.user_id, i.token, i.expires_at FROM user_invite i
+JOIN users u ON u.id = i.user_id
+WHERE i.token = $1 AND u.deleted_at IS NULL;
+
+-- name: DeleteUserInvite :execrows
+DELETE FROM user_invite WHERE user_id = $1;
+
 -- name: DeleteAdmin :exec
 DELETE FROM users WHERE id = $1;
 
@@ -139,6 +236,25 @@ DELETE FROM roles WHERE id = $1;
 -- name: GetRoleByID :one
 SELECT * FROM roles WHERE id = $1 LIMIT 1;
 
+-- name: GetRoleByName :one
+SELECT * FROM roles WHERE name = $1 LIMIT 1;
+
+-- name: SeedRole :exec
+-- Creates a role unless one with the name exists, existing roles are left
+-- as they are.
+INSERT INTO roles (name, description) VALUES ($1, $2)
+ON CONFLICT (name) DO NOTHING;
+
+-- name: SeedPermission :exec
+INSERT INTO permissions (code, description) VALUES ($1, $2)
+ON CONFLICT (code) DO NOTHING;
+
+-- name: GrantAllPermissionsToRole :execrows
+INSERT INTO role_permissions (role_id, permission_id)
+SELECT r.id, p.id FROM roles r CROSS JOIN permissions p
+WHERE r.name = $1
+ON CONFLICT DO NOTHING;
+
 -- name: ListRoles :many
 SELECT * FROM roles ORDER BY name;
 
@@ -150,11 +266,38 @@ VALUES ($1, $2);
 DELETE FROM role_permissions
 WHERE role_id = $1 AND permission_id = $2;
 
+-- name: AddPermissionsToRole :execrows
+-- permissions the role already holds are skipped
+INSERT INTO role_permissions (role_id, permission_id)
+SELECT sqlc.arg(role_id), unnest(sqlc.arg(permission_ids)::int[])
+ON CONFLICT DO NOTHING;
+
+-- name: RemoveRolePermissionsExcept :execrows
+DELETE FROM role_permissions
+WHERE role_id = sqlc.arg(role_id) AND NOT (permission_id = ANY(sqlc.arg(keep_ids)::int[]));
+
+-- name: ListExistingPermissionIDs :many
+SELECT id FROM permissions
+WHERE id = ANY(sqlc.arg(ids)::int[]);
+
 -- name: GetRolePermissions :many
 SELECT p.* FROM permissions p
 JOIN role_permissions rp ON p.id = rp.permission_id
 WHERE rp.role_id = $1;
 
+-- name: GetPermissionsMatrix :many
+SELECT
+    r.id AS role_id,
+    r.name AS role_name,
+    p.id AS permission_id,
+    p.code AS permission_code,
+    (rp.role_id IS NOT NULL)::boolean AS granted
+FROM roles r
+CROSS JOIN permissions p
+LEFT JOIN role_permissions rp ON rp.role_id = r.id AND rp.permission_id = p.id
+WHERE (sqlc.narg('permission_group')::text IS NULL OR split_part(p.code, ':', 1) = sqlc.narg('permission_group'))
+ORDER BY r.id, p.code;
+
 -- name: LogActivity :one
 INSERT INTO activity_logs (user_id, action, details, entity_id, entity_type, ip_address, user_agent)
 VALUES ($1, $2, $3, $4, $5, $6, $7)
@@ -165,6 +308,67 @@ SELECT * FROM activity_logs
 ORDER BY created_at DESC
 LIMIT $1;
 
+-- name: ListActivityLogs :many
+SELECT * FROM activity_logs
+WHERE (sqlc.narg(user_id)::int IS NULL OR user_id = sqlc.narg(user_id))
+  AND (sqlc.narg(action)::text IS NULL OR action = sqlc.narg(action))
+  AND (sqlc.narg(entity_type)::text IS NULL OR entity_type = sqlc.narg(entity_type))
+  AND (sqlc.narg(start_time)::timestamp IS NULL OR created_at >= sqlc.narg(start_time))
+  AND (sqlc.narg(end_time)::timestamp IS NULL OR created_at < sqlc.narg(end_time))
+ORDER BY created_at DESC, id DESC
+LIMIT sqlc.arg(page_limit) OFFSET sqlc.arg(page_offset);
+
+-- name: CountActivityLogs :one
+SELECT COUNT(*) FROM activity_logs
+WHERE (sqlc.narg(user_id)::int IS NULL OR user_id = sqlc.narg(user_id))
+  AND (sqlc.narg(action)::text IS NULL OR action = sqlc.narg(action))
+  AND (sqlc.narg(entity_type)::text IS NULL OR entity_type = sqlc.narg(entity_type))
+  AND (sqlc.narg(start_time)::timestamp IS NULL OR created_at >= sqlc.narg(start_time))
+  AND (sqlc.narg(end_time)::timestamp IS NULL OR created_at < sqlc.narg(end_time));
+
+-- name: SearchActivityLogs :many
+-- the tsvector expression must match activity_logs_details_search_idx. Only
+-- the logs of the owner's businesses are searched: the actions of the owner
+-- and of the users of their branches, and the actions on their businesses,
+-- branches, stores and stock transfers like ListBusinessActivityLogs
+SELECT al.* FROM activity_logs al
+WHERE to_tsvector('simple', al.details) @@ websearch_to_tsquery('simple', sqlc.arg(query))
+  AND (sqlc.narg(user_id)::int IS NULL OR al.user_id = sqlc.narg(user_id))
+  AND (sqlc.narg(action)::text IS NULL OR al.action = sqlc.narg(action))
+  AND (sqlc.narg(entity_type)::text IS NULL OR al.entity_type = sqlc.narg(entity_type))
+  AND (sqlc.narg(start_time)::timestamp IS NULL OR al.created_at >= sqlc.narg(start_time))
+  AND (sqlc.narg(end_time)::timestamp IS NULL OR al.created_at < sqlc.narg(end_time))
+  AND (
+    al.user_id IN (
+        SELECT u.id FROM users u
+        JOIN branch br ON br.id = u.branch_id
+        JOIN business b ON b.id = br.business_id
+        WHERE b.owner_id = sqlc.arg(owner_id))
+    -- admins and users are numbered apart, an id that is also a user's may
+    -- be another business's user
+    OR (al.user_id = sqlc.arg(owner_id) AND NOT EXISTS (
+        SELECT 1 FROM users u WHERE u.id = al.user_id))
+    OR (al.entity_type = 'Business' AND al.entity_id IN (
+        SELECT b.id FROM business b WHERE b.owner_id = sqlc.arg(owner_id)))
+    OR (al.entity_type = 'Branch' AND al.entity_id IN (
+        SELECT br.id FROM branch br
+        JOIN business b ON b.id = br.business_id
+        WHERE b.owner_id = sqlc.arg(owner_id)))
+    OR (al.entity_type = 'Store' AND al.entity_id IN (
+        SELECT s.id FROM store s
+        JOIN branch br ON br.id = s.branch_id
+        JOIN business b ON b.id = br.business_id
+        WHERE b.owner_id = sqlc.arg(owner_id)))
+    OR (al.entity_type = 'StoreTransfer' AND al.entity_id IN (
+        SELECT st.id FROM store_transfer st
+        JOIN store s ON s.id = st.from_store_id
+        JOIN branch br ON br.id = s.branch_id
+        JOIN business b ON b.id = br.business_id
+        WHERE b.owner_id = sqlc.arg(owner_id)))
+  )
+ORDER BY al.created_at DESC, al.id DESC
+LIMIT sqlc.arg(page_limit) OFFSET sqlc.arg(page_offset);
+
 -- name: LogLoginAttempt :exec
 INSERT INTO login_history (username_or_email, ip_address, user_agent, success, error_reason)
 VALUES ($1, $2, $3, $4, $5)
@@ -175,6 +379,32 @@ SELECT * FROM login_history
 ORDER BY login_time DESC
 LIMIT $1;
 
+-- name: ExportLoginHistory :many
+-- keyset paginated by id so large ranges can be streamed batch by batch. An
+-- attempt can match a user's username and another user's email, it is listed
+-- once with the account Login tries first: users before admins, email before
+-- username.
+SELECT DISTINCT ON (lh.id)
+    lh.id,
+    COALESCE(u.username, a.username, lh.username_or_email)::text AS username,
+    COALESCE(u.email, a.email, '')::text AS email,
+    lh.ip_address,
+    lh.user_agent,
+    lh.success,
+    lh.error_reason,
+    lh.login_time
+FROM login_history lh
+LEFT JOIN users u ON u.username = lh.username_or_email OR u.email = lh.username_or_email
+LEFT JOIN admins a ON a.username = lh.username_or_email OR a.email = lh.username_or_email
+WHERE lh.id > sqlc.arg(after_id)
+  AND lh.login_time >= sqlc.arg(start_time)
+  AND lh.login_time < sqlc.arg(end_time)
+  AND (sqlc.narg(success)::boolean IS NULL OR lh.success = sqlc.narg(success))
+ORDER BY lh.id,
+    u.email = lh.username_or_email DESC NULLS LAST,
+    a.email = lh.username_or_email DESC NULLS LAST
+LIMIT sqlc.arg(batch_size);
+
 -- name: CreatePasswordResetToken :one
 INSERT INTO password_reset_tokens (user_id, token, expires_at)
 VALUES ($1, $2, $3)
@@ -200,6 +430,9 @@ SELECT
     u.password_hash,
     u.gender,
     u.is_active,
+    u.email_verified,
+    u.verification_code,
+    u.verification_expires_at,
     r.name as role_name
 FROM users u
 JOIN roles r ON u.role_id = r.id
@@ -231,14 +464,15 @@ SELECT
     u.password_hash,
     u.gender,
     u.is_active,
+    u.email_verified,
     r.name as role_name
 FROM users u
 JOIN roles r ON u.role_id = r.id
 WHERE u.username = $1 LIMIT 1;
 
 -- name: CreateRefreshToken :one
-INSERT INTO refresh_tokens (user_id, token, expires_at)
-VALUES ($1, $2, $3)
+INSERT INTO refresh_tokens (user_id, admin_id, token, expires_at, user_agent)
+VALUES (sqlc.narg(user_id), sqlc.narg(admin_id), sqlc.arg(token), sqlc.arg(expires_at), sqlc.arg(user_agent))
 RETURNING *;
 
 -- name: GetRefreshToken :one
@@ -251,11 +485,82 @@ UPDATE refresh_tokens
 SET revoked = TRUE, updated_at = CURRENT_TIMESTAMP
 WHERE token = $1;
 
+-- name: ListUserRefreshTokens :many
+SELECT * FROM refresh_tokens
+WHERE user_id IS NOT DISTINCT FROM sqlc.narg(user_id)
+  AND admin_id IS NOT DISTINCT FROM sqlc.narg(admin_id)
+  AND expires_at > NOW() AND revoked = FALSE
+ORDER BY created_at DESC;
+
+-- name: RevokeRefreshTokenByID :execrows
+UPDATE refresh_tokens
+SET revoked = TRUE, updated_at = CURRENT_TIMESTAMP
+WHERE id = sqlc.arg(id)
+  AND user_id IS NOT DISTINCT FROM sqlc.narg(user_id)
+  AND admin_id IS NOT DISTINCT FROM sqlc.narg(admin_id)
+  AND revoked = FALSE;
+
 -- name: RevokeAllUserRefreshTokens :exec
 UPDATE refresh_tokens
 SET revoked = TRUE, updated_at = CURRENT_TIMESTAMP
 WHERE user_id = $1;
 
+-- name: RevokeOtherUserRefreshTokens :execrows
+UPDATE refresh_tokens
+SET revoked = TRUE, updated_at = CURRENT_TIMESTAMP
+WHERE user_id IS NOT DISTINCT FROM sqlc.narg(user_id)
+  AND admin_id IS NOT DISTINCT FROM sqlc.narg(admin_id)
+  AND id <> sqlc.arg(keep_id) AND revoked = FALSE;
+
 -- name: CleanExpiredRefreshTokens :exec
 DELETE FROM refresh_tokens
-WHERE expires_at <= NOW() OR revoked = TRUE;
\ No newline at end of file
+WHERE expires_at <= NOW() OR revoked = TRUE;
+-- name: AddPasswordHistory :exec
+INSERT INTO password_history (user_id, admin_id, password_hash)
+VALUES (sqlc.narg(user_id), sqlc.narg(admin_id), sqlc.arg(password_hash));
+
+-- name: ListRecentPasswordHashes :many
+SELECT password_hash FROM password_history
+WHERE user_id IS NOT DISTINCT FROM sqlc.narg(user_id)
+  AND admin_id IS NOT DISTINCT FROM sqlc.narg(admin_id)
+ORDER BY created_at DESC, id DESC
+LIMIT sqlc.arg(keep);
+
+-- name: PrunePasswordHistory :exec
+DELETE FROM password_history
+WHERE user_id IS NOT DISTINCT FROM sqlc.narg(user_id)
+  AND admin_id IS NOT DISTINCT FROM sqlc.narg(admin_id)
+  AND id NOT IN (
+    SELECT id FROM password_history
+    WHERE user_id IS NOT DISTINCT FROM sqlc.narg(user_id)
+      AND admin_id IS NOT DISTINCT FROM sqlc.narg(admin_id)
+    ORDER BY created_at DESC, id DESC
+    LIMIT sqlc.arg(keep)
+  );
+
+-- name: ListNotificationPreferences :many
+SELECT * FROM notification_preference
+WHERE user_id = $1
+ORDER BY event;
+
+-- name: UpsertNotificationPreference :one
+INSERT INTO notification_preference (user_id, event, email, in_app)
+VALUES ($1, $2, $3, $4)
+ON CONFLICT (user_id, event) DO UPDATE
+SET email = EXCLUDED.email,
+    in_app = EXCLUDED.in_app,
+    updated_at = NOW()
+RETURNING *;
+
+-- name: GetUserStoreScope :one
+SELECT u.username, u.branch_id, u.store_id, s.store_type, br.business_id
+FROM users u
+LEFT JOIN store s ON s.id = u.store_id
+LEFT JOIN branch br ON br.id = COALESCE(u.branch_id, s.branch_id)
+WHERE u.id = $1 AND u.deleted_at IS NULL;
+
+-- name: ListBranchStoreIDs :many
+SELECT id FROM store WHERE branch_id = $1 ORDER BY id;
+
+-- name: GetStoreBranchID :one
+SELECT branch_id FROM store WHERE id = $1;
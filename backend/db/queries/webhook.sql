@@ -0,0 +1,42 @@
+-- name: CreateWebhookSubscription :one
+INSERT INTO webhook_subscriptions (business_id, url, events, secret, active)
+VALUES ($1, $2, $3, $4, $5)
+RETURNING *;
+
+-- name: GetWebhookSubscription :one
+SELECT * FROM webhook_subscriptions
+WHERE id = $1 AND business_id = $2;
+
+-- name: ListWebhookSubscriptions :many
+SELECT * FROM webhook_subscriptions
+WHERE business_id = $1
+ORDER BY id;
+
+-- name: ListActiveWebhookSubscriptions :many
+SELECT * FROM webhook_subscriptions
+WHERE business_id = $1 AND active AND $2::text = ANY(events)
+ORDER BY id;
+
+-- name: UpdateWebhookSubscription :one
+UPDATE webhook_subscriptions
+SET url = COALESCE(sqlc.narg(url), url),
+    events = COALESCE(sqlc.narg(events)::text[], events),
+    secret = COALESCE(sqlc.narg(secret), secret),
+    active = COALESCE(sqlc.narg(active), active),
+    updated_at = CURRENT_TIMESTAMP
+WHERE id = sqlc.arg(id) AND business_id = sqlc.arg(business_id)
+RETURNING *;
+
+-- name: DeleteWebhookSubscription :execrows
+DELETE FROM webhook_subscriptions
+WHERE id = $1 AND business_id = $2;
+
+-- name: CreateWebhookDelivery :exec
+INSERT INTO webhook_deliveries (subscription_id, event_id, event, attempt, status_code, error, delivered)
+VALUES ($1, $2, $3, $4, $5, $6, $7);
+
+-- name: ListWebhookDeliveries :many
+SELECT * FROM webhook_deliveries
+WHERE subscription_id = $1
+ORDER BY id DESC
+LIMIT $2;
@@ -12,6 +12,82 @@ import (
 	"github.com/lib/pq"
 )
 
+const countBranches = `-- name: CountBranches :one
+SELECT COUNT(*) FROM branch br
+JOIN business b ON b.id = br.business_id
+WHERE b.owner_id = $1 AND b.deleted_at IS NULL
+  AND ($2::int IS NULL OR br.business_id = $2)
+`
+
+type CountBranchesParams struct {
+	OwnerID    int32         `json:"owner_id"`
+	BusinessID sql.NullInt32 `json:"business_id"`
+}
+
+func (q *Queries) CountBranches(ctx context.Context, arg CountBranchesParams) (int64, error) {
+	row := q.db.QueryRowContext(ctx, countBranches, arg.OwnerID, arg.BusinessID)
+	var count int64
+	err := row.Scan(&count)
+	return count, err
+}
+
+const countBusinessActivityLogs = `-- name: CountBusinessActivityLogs :one
+SELECT COUNT(*) FROM activity_logs al
+WHERE (
+    (al.entity_type = 'Business' AND al.entity_id IN (
+        SELECT b.id FROM business b WHERE b.owner_id = $1))
+    OR (al.entity_type = 'Branch' AND al.entity_id IN (
+        SELECT br.id FROM branch br
+        JOIN business b ON b.id = br.business_id
+        WHERE b.owner_id = $1))
+    OR (al.entity_type = 'Store' AND al.entity_id IN (
+        SELECT s.id FROM store s
+        JOIN branch br ON br.id = s.branch_id
+        JOIN business b ON b.id = br.business_id
+        WHERE b.owner_id = $1))
+    OR (al.entity_type = 'StoreTransfer' AND al.entity_id IN (
+        SELECT st.id FROM store_transfer st
+        JOIN store s ON s.id = st.from_store_id
+        JOIN branch br ON br.id = s.branch_id
+        JOIN business b ON b.id = br.business_id
+        WHERE b.owner_id = $1))
+  )
+  AND ($2::text IS NULL OR al.entity_type = $2)
+  AND ($3::timestamp IS NULL OR al.created_at >= $3)
+  AND ($4::timestamp IS NULL OR al.created_at < $4)
+`
+
+type CountBusinessActivityLogsParams struct {
+	OwnerID    int32          `json:"owner_id"`
+	EntityType sql.NullString `json:"entity_type"`
+	StartTime  sql.NullTime   `json:"start_time"`
+	EndTime    sql.NullTime   `json:"end_time"`
+}
+
+func (q *Queries) CountBusinessActivityLogs(ctx context.Context, arg CountBusinessActivityLogsParams) (int64, error) {
+	row := q.db.QueryRowContext(ctx, countBusinessActivityLogs,
+		arg.OwnerID,
+		arg.EntityType,
+		arg.StartTime,
+		arg.EndTime,
+	)
+	var count int64
+	err := row.Scan(&count)
+	return count, err
+}
+
+const countBusinesses = `-- name: CountBusinesses :one
+SELECT COUNT(*) FROM business
+WHERE owner_id = $1 AND deleted_at IS NULL
+`
+
+func (q *Queries) CountBusinesses(ctx context.Context, ownerID int32) (int64, error) {
+	row := q.db.QueryRowContext(ctx, countBusinesses, ownerID)
+	var count int64
+	err := row.Scan(&count)
+	return count, err
+}
+
 const createBranch = `-- name: CreateBranch :one
 INSERT INTO branch (
     business_id, name, address_one, addres_two, country, phone, email, website, city, state, zip_code
@@ -73,12 +149,12 @@ INSERT INTO business (
     owner_id, name, motto, email, website, tax_id, tax_rate,
     country, logo_url, rounding, currency, timezone, language,
     low_stock_threshold, allow_overselling, payment_type,
-    font, primary_color
+    font, primary_color, logo_thumbnail_url
 ) VALUES (
     $1, $2, $3, $4, $5, $6, $7,
     $8, $9, $10, $11, $12, $13,
-    $14, $15, $16, $17, $18
-) RETURNING id, owner_id, name, motto, email, website, tax_id, tax_rate, country, logo_url, rounding, currency, timezone, language, low_stock_threshold, allow_overselling, payment_type, font, primary_color, created_at, updated_at
+    $14, $15, $16, $17, $18, $19
+) RETURNING id, owner_id, name, motto, email, website, tax_id, tax_rate, country, logo_url, rounding, currency, timezone, language, low_stock_threshold, allow_overselling, payment_type, font, primary_color, created_at, updated_at, depletion_strategy, deleted_at, logo_thumbnail_url, version
 `
 
 type CreateBusinessParams struct {
@@ -100,6 +176,7 @@ type CreateBusinessParams struct {
 	PaymentType       []PaymentType  `json:"payment_type"`
 	Font              sql.NullString `json:"font"`
 	PrimaryColor      sql.NullString `json:"primary_color"`
+	LogoThumbnailUrl  sql.NullString `json:"logo_thumbnail_url"`
 }
 
 func (q *Queries) CreateBusiness(ctx context.Context, arg CreateBusinessParams) (Business, error) {
@@ -122,6 +199,7 @@ func (q *Queries) CreateBusiness(ctx context.Context, arg CreateBusinessParams)
 		pq.Array(arg.PaymentType),
 		arg.Font,
 		arg.PrimaryColor,
+		arg.LogoThumbnailUrl,
 	)
 	var i Business
 	err := row.Scan(
@@ -146,6 +224,67 @@ func (q *Queries) CreateBusiness(ctx context.Context, arg CreateBusinessParams)
 		&i.PrimaryColor,
 		&i.CreatedAt,
 		&i.UpdatedAt,
+		&i.DepletionStrategy,
+		&i.DeletedAt,
+		&i.LogoThumbnailUrl,
+		&i.Version,
+	)
+	return i, err
+}
+
+const createBusinessSettingsHistory = `-- name: CreateBusinessSettingsHistory :one
+INSERT INTO business_settings_history (
+    business_id, tax_rate, rounding, currency, payment_type, motto,
+    logo_url, logo_thumbnail_url, font, primary_color, changed_by
+) VALUES (
+    $1, $2, $3, $4, $5, $6,
+    $7, $8, $9, $10, $11
+) RETURNING id, business_id, tax_rate, rounding, currency, payment_type, motto, logo_url, logo_thumbnail_url, font, primary_color, changed_by, changed_at
+`
+
+type CreateBusinessSettingsHistoryParams struct {
+	BusinessID       int32          `json:"business_id"`
+	TaxRate          sql.NullString `json:"tax_rate"`
+	Rounding         sql.NullString `json:"rounding"`
+	Currency         sql.NullString `json:"currency"`
+	PaymentType      []PaymentType  `json:"payment_type"`
+	Motto            sql.NullString `json:"motto"`
+	LogoUrl          sql.NullString `json:"logo_url"`
+	LogoThumbnailUrl sql.NullString `json:"logo_thumbnail_url"`
+	Font             sql.NullString `json:"font"`
+	PrimaryColor     sql.NullString `json:"primary_color"`
+	ChangedBy        int32          `json:"changed_by"`
+}
+
+func (q *Queries) CreateBusinessSettingsHistory(ctx context.Context, arg CreateBusinessSettingsHistoryParams) (BusinessSettingsHistory, error) {
+	row := q.db.QueryRowContext(ctx, createBusinessSettingsHistory,
+		arg.BusinessID,
+		arg.TaxRate,
+		arg.Rounding,
+		arg.Currency,
+		pq.Array(arg.PaymentType),
+		arg.Motto,
+		arg.LogoUrl,
+		arg.LogoThumbnailUrl,
+		arg.Font,
+		arg.PrimaryColor,
+		arg.ChangedBy,
+	)
+	var i BusinessSettingsHistory
+	err := row.Scan(
+		&i.ID,
+		&i.BusinessID,
+		&i.TaxRate,
+		&i.Rounding,
+		&i.Currency,
+		pq.Array(&i.PaymentType),
+		&i.Motto,
+		&i.LogoUrl,
+		&i.LogoThumbnailUrl,
+		&i.Font,
+		&i.PrimaryColor,
+		&i.ChangedBy,
+		&i.ChangedAt,
 	)
 	return i, err
 }
@@ -178,9 +317,9 @@ func (q *Queries) DeleteBranch(ctx context.Context, id int32) (Branch, error) {
 }
 
 const deleteBusiness = `-- name: DeleteBusiness :one
-DELETE FROM business
-WHERE id = $1 AND owner_id = $2
-RETURNING id, owner_id, name, motto, email, website, tax_id, tax_rate, country, logo_url, rounding, currency, timezone, language, low_stock_threshold, allow_overselling, payment_type, font, primary_color, created_at, updated_at
+UPDATE business SET deleted_at = CURRENT_TIMESTAMP
+WHERE id = $1 AND owner_id = $2 AND deleted_at IS NULL
+RETURNING id, owner_id, name, motto, email, website, tax_id, tax_rate, country, logo_url, rounding, currency, timezone, language, low_stock_threshold, allow_overselling, payment_type, font, primary_color, created_at, updated_at, depletion_strategy, deleted_at, logo_thumbnail_url, version
 `
 
 type DeleteBusinessParams struct {
@@ -213,6 +352,10 @@ func (q *Queries) DeleteBusiness(ctx context.Context, arg DeleteBusinessParams)
 		&i.PrimaryColor,
 		&i.CreatedAt,
 		&i.UpdatedAt,
+		&i.DepletionStrategy,
+		&i.DeletedAt,
+		&i.LogoThumbnailUrl,
+		&i.Version,
 	)
 	return i, err
 }
@@ -244,9 +387,9 @@ func (q *Queries) GetBranch(ctx context.Context, id int32) (Branch, error) {
 }
 
 const getBusiness = `-- name: GetBusiness :one
-SELECT id, owner_id, name, motto, email, website, tax_id, tax_rate, country, logo_url, rounding, currency, timezone, language, low_stock_threshold, allow_overselling, payment_type, font, primary_color, created_at, updated_at
+SELECT id, owner_id, name, motto, email, website, tax_id, tax_rate, country, logo_url, rounding, currency, timezone, language, low_stock_threshold, allow_overselling, payment_type, font, primary_color, created_at, updated_at, depletion_strategy, deleted_at, logo_thumbnail_url, version
 FROM business
-WHERE id = $1 AND owner_id = $2
+WHERE id = $1 AND owner_id = $2 AND deleted_at IS NULL
 `
 
 type GetBusinessParams struct {
@@ -279,16 +422,148 @@ func (q *Queries) GetBusiness(ctx context.Context, arg GetBusinessParams) (Busin
 		&i.PrimaryColor,
 		&i.CreatedAt,
 		&i.UpdatedAt,
+		&i.DepletionStrategy,
+		&i.DeletedAt,
+		&i.LogoThumbnailUrl,
+		&i.Version,
+	)
+	return i, err
+}
+
+const getBusinessForUpdate = `-- name: GetBusinessForUpdate :one
+SELECT id, owner_id, name, motto, email, website, tax_id, tax_rate, country, logo_url, rounding, currency, timezone, language, low_stock_threshold, allow_overselling, payment_type, font, primary_color, created_at, updated_at, depletion_strategy, deleted_at, logo_thumbnail_url, version
+FROM business
+WHERE id = $1 AND owner_id = $2 AND deleted_at IS NULL
+FOR UPDATE
+`
+
+type GetBusinessForUpdateParams struct {
+	ID      int32 `json:"id"`
+	OwnerID int32 `json:"owner_id"`
+}
+
+func (q *Queries) GetBusinessForUpdate(ctx context.Context, arg GetBusinessForUpdateParams) (Business, error) {
+	row := q.db.QueryRowContext(ctx, getBusinessForUpdate, arg.ID, arg.OwnerID)
+	var i Business
+	err := row.Scan(
+		&i.ID,
+		&i.OwnerID,
+		&i.Name,
+		&i.Motto,
+		&i.Email,
+		&i.Website,
+		&i.TaxID,
+		&i.TaxRate,
+		&i.Country,
+		&i.LogoUrl,
+		&i.Rounding,
+		&i.Currency,
+		&i.Timezone,
+		&i.Language,
+		&i.LowStockThreshold,
+		&i.AllowOverselling,
+		pq.Array(&i.PaymentType),
+		&i.Font,
+		&i.PrimaryColor,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.DepletionStrategy,
+		&i.DeletedAt,
+		&i.LogoThumbnailUrl,
+		&i.Version,
+	)
+	return i, err
+}
+
+const getOwnedBusinessID = `-- name: GetOwnedBusinessID :one
+SELECT id
+FROM business
+WHERE owner_id = $1
+  AND deleted_at IS NULL
+  AND ($2::int IS NULL OR id = $2)
+ORDER BY id
+LIMIT 1
+`
+
+type GetOwnedBusinessIDParams struct {
+	OwnerID    int32         `json:"owner_id"`
+	BusinessID sql.NullInt32 `json:"business_id"`
+}
+
+func (q *Queries) GetOwnedBusinessID(ctx context.Context, arg GetOwnedBusinessIDParams) (int32, error) {
+	row := q.db.QueryRowContext(ctx, getOwnedBusinessID, arg.OwnerID, arg.BusinessID)
+	var id int32
+	err := row.Scan(&id)
+	return id, err
+}
+
+const hardDeleteBusiness = `-- name: HardDeleteBusiness :one
+DELETE FROM business
+WHERE id = $1 AND owner_id = $2
+RETURNING id, owner_id, name, motto, email, website, tax_id, tax_rate, country, logo_url, rounding, currency, timezone, language, low_stock_threshold, allow_overselling, payment_type, font, primary_color, created_at, updated_at, depletion_strategy, deleted_at, logo_thumbnail_url, version
+`
+
+type HardDeleteBusinessParams struct {
+	ID      int32 `json:"id"`
+	OwnerID int32 `json:"owner_id"`
+}
+
+func (q *Queries) HardDeleteBusiness(ctx context.Context, arg HardDeleteBusinessParams) (Business, error) {
+	row := q.db.QueryRowContext(ctx, hardDeleteBusiness, arg.ID, arg.OwnerID)
+	var i Business
+	err := row.Scan(
+		&i.ID,
+		&i.OwnerID,
+		&i.Name,
+		&i.Motto,
+		&i.Email,
+		&i.Website,
+		&i.TaxID,
+		&i.TaxRate,
+		&i.Country,
+		&i.LogoUrl,
+		&i.Rounding,
+		&i.Currency,
+		&i.Timezone,
+		&i.Language,
+		&i.LowStockThreshold,
+		&i.AllowOverselling,
+		&i.PaymentType,
+		&i.Font,
+		&i.PrimaryColor,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.DepletionStrategy,
+		&i.DeletedAt,
+		&i.LogoThumbnailUrl,
+		&i.Version,
 	)
 	return i, err
 }
 
 const listBranches = `-- name: ListBranches :many
-SELECT id, business_id, name, address_one, addres_two, country, phone, email, website, city, state, zip_code, created_at, updated_at FROM branch ORDER BY created_at DESC
+SELECT br.id, br.business_id, br.name, br.address_one, br.addres_two, br.country, br.phone, br.email, br.website, br.city, br.state, br.zip_code, br.created_at, br.updated_at FROM branch br
+JOIN business b ON b.id = br.business_id
+WHERE b.owner_id = $1 AND b.deleted_at IS NULL
+  AND ($2::int IS NULL OR br.business_id = $2)
+ORDER BY br.created_at DESC, br.id DESC
+LIMIT $3 OFFSET $4
 `
 
-func (q *Queries) ListBranches(ctx context.Context) ([]Branch, error) {
-	rows, err := q.db.QueryContext(ctx, listBranches)
+type ListBranchesParams struct {
+	OwnerID    int32         `json:"owner_id"`
+	BusinessID sql.NullInt32 `json:"business_id"`
+	PageLimit  int32         `json:"page_limit"`
+	PageOffset int32         `json:"page_offset"`
+}
+
+func (q *Queries) ListBranches(ctx context.Context, arg ListBranchesParams) ([]Branch, error) {
+	rows, err := q.db.QueryContext(ctx, listBranches,
+		arg.OwnerID,
+		arg.BusinessID,
+		arg.PageLimit,
+		arg.PageOffset,
+	)
 	if err != nil {
 		return nil, err
 	}
@@ -325,15 +600,101 @@ func (q *Queries) ListBranches(ctx context.Context) ([]Branch, error) {
 	return items, nil
 }
 
+const listBusinessActivityLogs = `-- name: ListBusinessActivityLogs :many
+SELECT al.id, al.user_id, al.action, al.details, al.entity_id, al.entity_type, al.ip_address, al.user_agent, al.created_at FROM activity_logs al
+WHERE (
+    (al.entity_type = 'Business' AND al.entity_id IN (
+        SELECT b.id FROM business b WHERE b.owner_id = $1))
+    OR (al.entity_type = 'Branch' AND al.entity_id IN (
+        SELECT br.id FROM branch br
+        JOIN business b ON b.id = br.business_id
+        WHERE b.owner_id = $1))
+    OR (al.entity_type = 'Store' AND al.entity_id IN (
+        SELECT s.id FROM store s
+        JOIN branch br ON br.id = s.branch_id
+        JOIN business b ON b.id = br.business_id
+        WHERE b.owner_id = $1))
+    OR (al.entity_type = 'StoreTransfer' AND al.entity_id IN (
+        SELECT st.id FROM store_transfer st
+        JOIN store s ON s.id = st.from_store_id
+        JOIN branch br ON br.id = s.branch_id
+        JOIN business b ON b.id = br.business_id
+        WHERE b.owner_id = $1))
+  )
+  AND ($2::text IS NULL OR al.entity_type = $2)
+  AND ($3::timestamp IS NULL OR al.created_at >= $3)
+  AND ($4::timestamp IS NULL OR al.created_at < $4)
+ORDER BY al.created_at DESC, al.id DESC
+LIMIT $5 OFFSET $6
+`
+
+type ListBusinessActivityLogsParams struct {
+	OwnerID    int32          `json:"owner_id"`
+	EntityType sql.NullString `json:"entity_type"`
+	StartTime  sql.NullTime   `json:"start_time"`
+	EndTime    sql.NullTime   `json:"end_time"`
+	PageLimit  int32          `json:"page_limit"`
+	PageOffset int32          `json:"page_offset"`
+}
+
+// activity logs of the owner's businesses and of their branches, stores and
+// stock transfers, matched by the logged entity
+func (q *Queries) ListBusinessActivityLogs(ctx context.Context, arg ListBusinessActivityLogsParams) ([]ActivityLog, error) {
+	rows, err := q.db.QueryContext(ctx, listBusinessActivityLogs,
+		arg.OwnerID,
+		arg.EntityType,
+		arg.StartTime,
+		arg.EndTime,
+		arg.PageLimit,
+		arg.PageOffset,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []ActivityLog{}
+	for rows.Next() {
+		var i ActivityLog
+		if err := rows.Scan(
+			&i.ID,
+			&i.UserID,
+			&i.Action,
+			&i.Details,
+			&i.EntityID,
+			&i.EntityType,
+			&i.IpAddress,
+			&i.UserAgent,
+			&i.CreatedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
 const listBusinesses = `-- name: ListBusinesses :many
-SELECT id, owner_id, name, motto, email, website, tax_id, tax_rate, country, logo_url, rounding, currency, timezone, language, low_stock_threshold, allow_overselling, payment_type, font, primary_color, created_at, updated_at
+SELECT id, owner_id, name, motto, email, website, tax_id, tax_rate, country, logo_url, rounding, currency, timezone, language, low_stock_threshold, allow_overselling, payment_type, font, primary_color, created_at, updated_at, depletion_strategy, deleted_at, logo_thumbnail_url, version
 FROM business
-WHERE owner_id = $1
-ORDER BY created_at
+WHERE owner_id = $1 AND deleted_at IS NULL
+ORDER BY id
+LIMIT $2 OFFSET $3
 `
 
-func (q *Queries) ListBusinesses(ctx context.Context, ownerID int32) ([]Business, error) {
-	rows, err := q.db.QueryContext(ctx, listBusinesses, ownerID)
+type ListBusinessesParams struct {
+	OwnerID    int32 `json:"owner_id"`
+	PageLimit  int32 `json:"page_limit"`
+	PageOffset int32 `json:"page_offset"`
+}
+
+func (q *Queries) ListBusinesses(ctx context.Context, arg ListBusinessesParams) ([]Business, error) {
+	rows, err := q.db.QueryContext(ctx, listBusinesses, arg.OwnerID, arg.PageLimit, arg.PageOffset)
 	if err != nil {
 		return nil, err
 	}
@@ -363,6 +724,53 @@ func (q *Queries) ListBusinesses(ctx context.Context, ownerID int32) ([]Business
 			&i.PrimaryColor,
 			&i.CreatedAt,
 			&i.UpdatedAt,
+			&i.DepletionStrategy,
+			&i.DeletedAt,
+			&i.LogoThumbnailUrl,
+			&i.Version,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listBusinessSettingsHistory = `-- name: ListBusinessSettingsHistory :many
+SELECT id, business_id, tax_rate, rounding, currency, payment_type, motto, logo_url, logo_thumbnail_url, font, primary_color, changed_by, changed_at FROM business_settings_history
+WHERE business_id = $1
+ORDER BY changed_at, id
+`
+
+func (q *Queries) ListBusinessSettingsHistory(ctx context.Context, businessID int32) ([]BusinessSettingsHistory, error) {
+	rows, err := q.db.QueryContext(ctx, listBusinessSettingsHistory, businessID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []BusinessSettingsHistory{}
+	for rows.Next() {
+		var i BusinessSettingsHistory
+		if err := rows.Scan(
+			&i.ID,
+			&i.BusinessID,
+			&i.TaxRate,
+			&i.Rounding,
+			&i.Currency,
+			pq.Array(&i.PaymentType),
+			&i.Motto,
+			&i.LogoUrl,
+			&i.LogoThumbnailUrl,
+			&i.Font,
+			&i.PrimaryColor,
+			&i.ChangedBy,
+			&i.ChangedAt,
 		); err != nil {
 			return nil, err
 		}
@@ -377,6 +785,53 @@ func (q *Queries) ListBusinesses(ctx context.Context, ownerID int32) ([]Business
 	return items, nil
 }
 
+const restoreBusiness = `-- name: RestoreBusiness :one
+UPDATE business SET deleted_at = NULL, updated_at = CURRENT_TIMESTAMP
+WHERE id = $2 AND owner_id = $3
+  AND deleted_at IS NOT NULL AND deleted_at >= $1
+RETURNING id, owner_id, name, motto, email, website, tax_id, tax_rate, country, logo_url, rounding, currency, timezone, language, low_stock_threshold, allow_overselling, payment_type, font, primary_color, created_at, updated_at, depletion_strategy, deleted_at, logo_thumbnail_url, version
+`
+
+type RestoreBusinessParams struct {
+	ID           int32        `json:"id"`
+	OwnerID      int32        `json:"owner_id"`
+	DeletedAfter sql.NullTime `json:"deleted_after"`
+}
+
+// only businesses deleted after $1 can be restored
+func (q *Queries) RestoreBusiness(ctx context.Context, arg RestoreBusinessParams) (Business, error) {
+	row := q.db.QueryRowContext(ctx, restoreBusiness, arg.ID, arg.OwnerID, arg.DeletedAfter)
+	var i Business
+	err := row.Scan(
+		&i.ID,
+		&i.OwnerID,
+		&i.Name,
+		&i.Motto,
+		&i.Email,
+		&i.Website,
+		&i.TaxID,
+		&i.TaxRate,
+		&i.Country,
+		&i.LogoUrl,
+		&i.Rounding,
+		&i.Currency,
+		&i.Timezone,
+		&i.Language,
+		&i.LowStockThreshold,
+		&i.AllowOverselling,
+		&i.PaymentType,
+		&i.Font,
+		&i.PrimaryColor,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.DepletionStrategy,
+		&i.DeletedAt,
+		&i.LogoThumbnailUrl,
+		&i.Version,
+	)
+	return i, err
+}
+
 const updateBranch = `-- name: UpdateBranch :one
 UPDATE branch SET
     name = $2,
@@ -461,9 +916,13 @@ UPDATE business SET
     font = COALESCE($15, font),
     primary_color = COALESCE($16, primary_color),
     country = COALESCE($17, country),
+    depletion_strategy = COALESCE($18, depletion_strategy),
+    logo_thumbnail_url = COALESCE($19, logo_thumbnail_url),
+    version = version + 1,
     updated_at = CURRENT_TIMESTAMP
-WHERE id = $18 AND owner_id = $19
-RETURNING id, owner_id, name, motto, email, website, tax_id, tax_rate, country, logo_url, rounding, currency, timezone, language, low_stock_threshold, allow_overselling, payment_type, font, primary_color, created_at, updated_at
+WHERE id = $20 AND owner_id = $21 AND deleted_at IS NULL
+  AND ($22::int IS NULL OR version = $22)
+RETURNING id, owner_id, name, motto, email, website, tax_id, tax_rate, country, logo_url, rounding, currency, timezone, language, low_stock_threshold, allow_overselling, payment_type, font, primary_color, created_at, updated_at, depletion_strategy, deleted_at, logo_thumbnail_url, version
 `
 
 type UpdateBusinessParams struct {
@@ -484,8 +943,11 @@ type UpdateBusinessParams struct {
 	Font              sql.NullString `json:"font"`
 	PrimaryColor      sql.NullString `json:"primary_color"`
 	Country           sql.NullString `json:"country"`
+	DepletionStrategy sql.NullString `json:"depletion_strategy"`
+	LogoThumbnailUrl  sql.NullString `json:"logo_thumbnail_url"`
 	ID                int32          `json:"id"`
 	OwnerID           int32          `json:"owner_id"`
+	ExpectedVersion   sql.NullInt32  `json:"expected_version"`
 }
 
 func (q *Queries) UpdateBusiness(ctx context.Context, arg UpdateBusinessParams) (Business, error) {
@@ -507,8 +969,11 @@ func (q *Queries) UpdateBusiness(ctx context.Context, arg UpdateBusinessParams)
 		arg.Font,
 		arg.PrimaryColor,
 		arg.Country,
+		arg.DepletionStrategy,
+		arg.LogoThumbnailUrl,
 		arg.ID,
 		arg.OwnerID,
+		arg.ExpectedVersion,
 	)
 	var i Business
 	err := row.Scan(
@@ -533,6 +998,10 @@ func (q *Queries) UpdateBusiness(ctx context.Context, arg UpdateBusinessParams)
 		&i.PrimaryColor,
 		&i.CreatedAt,
 		&i.UpdatedAt,
+		&i.DepletionStrategy,
+		&i.DeletedAt,
+		&i.LogoThumbnailUrl,
+		&i.Version,
 	)
 	return i, err
 }
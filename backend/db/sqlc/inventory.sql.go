@@ -8,23 +8,181 @@ package db
 import (
 	"context"
 	"database/sql"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+const consumeInventoryBatches = `-- name: ConsumeInventoryBatches :many
+WITH strategy AS (
+    SELECT COALESCE(c.depletion_strategy, bu.depletion_strategy) AS depletion_strategy
+    FROM store s
+    JOIN branch br ON br.id = s.branch_id
+    JOIN business bu ON bu.id = br.business_id
+    JOIN variation v ON v.id = $1
+    JOIN item i ON i.id = v.item_id
+    LEFT JOIN category c ON c.id = i.category_id
+    WHERE s.id = $2
+), ordered AS (
+    SELECT ib.id, ib.quantity,
+        SUM(ib.quantity) OVER (
+            ORDER BY CASE WHEN st.depletion_strategy = 'fifo' THEN ib.created_at ELSE ib.expiry_date END, ib.id
+        ) - ib.quantity AS taken_before
+    FROM inventory_batch ib
+    LEFT JOIN strategy st ON TRUE
+    WHERE ib.store_id = $2 AND ib.variation_id = $1 AND ib.quantity > 0
+), consumed AS (
+    UPDATE inventory_batch b
+    SET quantity = b.quantity - LEAST(o.quantity, $3::int - o.taken_before),
+        updated_at = NOW()
+    FROM ordered o
+    WHERE b.id = o.id AND o.taken_before < $3::int
+    RETURNING b.id, LEAST(o.quantity, $3::int - o.taken_before) AS taken
 )
+INSERT INTO inventory_batch_movement (batch_id, quantity, reason, reference_id)
+SELECT id, taken, $4, $5
+FROM consumed
+RETURNING id, batch_id, quantity, reason, reference_id, created_at
+`
+
+type ConsumeInventoryBatchesParams struct {
+	VariationID int32         `json:"variation_id"`
+	StoreID     int32         `json:"store_id"`
+	Quantity    int32         `json:"quantity"`
+	Reason      string        `json:"reason"`
+	ReferenceID sql.NullInt32 `json:"reference_id"`
+}
+
+func (q *Queries) ConsumeInventoryBatches(ctx context.Context, arg ConsumeInventoryBatchesParams) ([]InventoryBatchMovement, error) {
+	rows, err := q.db.QueryContext(ctx, consumeInventoryBatches,
+		arg.VariationID,
+		arg.StoreID,
+		arg.Quantity,
+		arg.Reason,
+		arg.ReferenceID,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []InventoryBatchMovement{}
+	for rows.Next() {
+		var i InventoryBatchMovement
+		if err := rows.Scan(
+			&i.ID,
+			&i.BatchID,
+			&i.Quantity,
+			&i.Reason,
+			&i.ReferenceID,
+			&i.CreatedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const countActiveVariationsByItem = `-- name: CountActiveVariationsByItem :one
+SELECT COUNT(*) FROM variation WHERE item_id = $1 AND is_active = TRUE
+`
+
+func (q *Queries) CountActiveVariationsByItem(ctx context.Context, itemID int32) (int64, error) {
+	row := q.db.QueryRowContext(ctx, countActiveVariationsByItem, itemID)
+	var count int64
+	err := row.Scan(&count)
+	return count, err
+}
+
+const countItemsByCategory = `-- name: CountItemsByCategory :many
+SELECT category_id, COUNT(*)::bigint AS item_count FROM item
+WHERE ($1::int IS NULL OR business_id = $1)
+GROUP BY category_id
+`
+
+type CountItemsByCategoryRow struct {
+	CategoryID int32 `json:"category_id"`
+	ItemCount  int64 `json:"item_count"`
+}
+
+func (q *Queries) CountItemsByCategory(ctx context.Context, businessID sql.NullInt32) ([]CountItemsByCategoryRow, error) {
+	rows, err := q.db.QueryContext(ctx, countItemsByCategory, businessID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []CountItemsByCategoryRow{}
+	for rows.Next() {
+		var i CountItemsByCategoryRow
+		if err := rows.Scan(
+			&i.CategoryID,
+			&i.ItemCount,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const countSearchItems = `-- name: CountSearchItems :one
+SELECT COUNT(*) FROM item i
+WHERE ($1::int IS NULL OR i.business_id = $1)
+  AND (to_tsvector('simple', i.name || ' ' || COALESCE(i.description, '')) @@ to_tsquery('simple', $2)
+       OR EXISTS (
+           SELECT 1 FROM variation v
+           WHERE v.item_id = i.id
+             AND to_tsvector('simple', v.name || ' ' || v.sku || ' ' || COALESCE(v.barcode, '')) @@ to_tsquery('simple', $2)
+       ))
+`
+
+type CountSearchItemsParams struct {
+	BusinessID sql.NullInt32 `json:"business_id"`
+	Query      string        `json:"query"`
+}
+
+func (q *Queries) CountSearchItems(ctx context.Context, arg CountSearchItemsParams) (int64, error) {
+	row := q.db.QueryRowContext(ctx, countSearchItems, arg.BusinessID, arg.Query)
+	var count int64
+	err := row.Scan(&count)
+	return count, err
+}
 
 const createBrand = `-- name: CreateBrand :one
-INSERT INTO brand (name, description, logo)
-VALUES ($1, $2, $3)
-RETURNING id, name, description, logo, is_active, created_at, updated_at
+INSERT INTO brand (name, description, logo, business_id, logo_thumbnail)
+VALUES ($1, $2, $3, $4, $5)
+RETURNING id, name, description, logo, is_active, created_at, updated_at, business_id, logo_thumbnail
 `
 
 type CreateBrandParams struct {
-	Name        string         `json:"name"`
-	Description sql.NullString `json:"description"`
-	Logo        sql.NullString `json:"logo"`
+	Name          string         `json:"name"`
+	Description   sql.NullString `json:"description"`
+	Logo          sql.NullString `json:"logo"`
+	BusinessID    sql.NullInt32  `json:"business_id"`
+	LogoThumbnail sql.NullString `json:"logo_thumbnail"`
 }
 
 // Brand
 func (q *Queries) CreateBrand(ctx context.Context, arg CreateBrandParams) (Brand, error) {
-	row := q.db.QueryRowContext(ctx, createBrand, arg.Name, arg.Description, arg.Logo)
+	row := q.db.QueryRowContext(ctx, createBrand,
+		arg.Name,
+		arg.Description,
+		arg.Logo,
+		arg.BusinessID,
+		arg.LogoThumbnail,
+	)
 	var i Brand
 	err := row.Scan(
 		&i.ID,
@@ -34,25 +192,35 @@ func (q *Queries) CreateBrand(ctx context.Context, arg CreateBrandParams) (Brand
 		&i.IsActive,
 		&i.CreatedAt,
 		&i.UpdatedAt,
+		&i.BusinessID,
+		&i.LogoThumbnail,
 	)
 	return i, err
 }
 
 const createCategory = `-- name: CreateCategory :one
-INSERT INTO category (name, parent_id, description)
-VALUES ($1, $2, $3)
-RETURNING id, name, parent_id, description, is_active, created_at, updated_at
+INSERT INTO category (name, parent_id, description, business_id, depletion_strategy)
+VALUES ($1, $2, $3, $4, $5)
+RETURNING id, name, parent_id, description, is_active, created_at, updated_at, business_id, depletion_strategy
 `
 
 type CreateCategoryParams struct {
-	Name        string         `json:"name"`
-	ParentID    sql.NullInt32  `json:"parent_id"`
-	Description sql.NullString `json:"description"`
+	Name              string         `json:"name"`
+	ParentID          sql.NullInt32  `json:"parent_id"`
+	Description       sql.NullString `json:"description"`
+	BusinessID        sql.NullInt32  `json:"business_id"`
+	DepletionStrategy sql.NullString `json:"depletion_strategy"`
 }
 
 // Category
 func (q *Queries) CreateCategory(ctx context.Context, arg CreateCategoryParams) (Category, error) {
-	row := q.db.QueryRowContext(ctx, createCategory, arg.Name, arg.ParentID, arg.Description)
+	row := q.db.QueryRowContext(ctx, createCategory,
+		arg.Name,
+		arg.ParentID,
+		arg.Description,
+		arg.BusinessID,
+		arg.DepletionStrategy,
+	)
 	var i Category
 	err := row.Scan(
 		&i.ID,
@@ -62,6 +230,8 @@ func (q *Queries) CreateCategory(ctx context.Context, arg CreateCategoryParams)
 		&i.IsActive,
 		&i.CreatedAt,
 		&i.UpdatedAt,
+		&i.BusinessID,
+		&i.DepletionStrategy,
 	)
 	return i, err
 }
@@ -86,9 +256,9 @@ func (q *Queries) CreateColor(ctx context.Context, name string) (Color, error) {
 }
 
 const createItem = `-- name: CreateItem :one
-INSERT INTO item (brand_id, category_id, name, description, item_type, no_variants)
-VALUES ($1, $2, $3, $4, $5, $6)
-RETURNING id, brand_id, category_id, name, description, item_type, is_active, no_variants, created_at, updated_at
+INSERT INTO item (brand_id, category_id, name, description, item_type, no_variants, business_id)
+VALUES ($1, $2, $3, $4, $5, $6, $7)
+RETURNING id, brand_id, category_id, name, description, item_type, is_active, no_variants, created_at, updated_at, business_id, version
 `
 
 type CreateItemParams struct {
@@ -98,6 +268,7 @@ type CreateItemParams struct {
 	Description sql.NullString `json:"description"`
 	ItemType    string         `json:"item_type"`
 	NoVariants  sql.NullBool   `json:"no_variants"`
+	BusinessID  sql.NullInt32  `json:"business_id"`
 }
 
 // Item
@@ -109,6 +280,7 @@ func (q *Queries) CreateItem(ctx context.Context, arg CreateItemParams) (Item, e
 		arg.Description,
 		arg.ItemType,
 		arg.NoVariants,
+		arg.BusinessID,
 	)
 	var i Item
 	err := row.Scan(
@@ -122,6 +294,8 @@ func (q *Queries) CreateItem(ctx context.Context, arg CreateItemParams) (Item, e
 		&i.NoVariants,
 		&i.CreatedAt,
 		&i.UpdatedAt,
+		&i.BusinessID,
+		&i.Version,
 	)
 	return i, err
 }
@@ -159,6 +333,76 @@ func (q *Queries) CreateItemImage(ctx context.Context, arg CreateItemImageParams
 	return i, err
 }
 
+const createPriceHistory = `-- name: CreatePriceHistory :one
+INSERT INTO price_history (variation_id, old_price, new_price, changed_by)
+VALUES ($1, $2, $3, $4)
+RETURNING id, variation_id, old_price, new_price, changed_by, changed_at
+`
+
+type CreatePriceHistoryParams struct {
+	VariationID int32  `json:"variation_id"`
+	OldPrice    string `json:"old_price"`
+	NewPrice    string `json:"new_price"`
+	ChangedBy   int32  `json:"changed_by"`
+}
+
+func (q *Queries) CreatePriceHistory(ctx context.Context, arg CreatePriceHistoryParams) (PriceHistory, error) {
+	row := q.db.QueryRowContext(ctx, createPriceHistory,
+		arg.VariationID,
+		arg.OldPrice,
+		arg.NewPrice,
+		arg.ChangedBy,
+	)
+	var i PriceHistory
+	err := row.Scan(
+		&i.ID,
+		&i.VariationID,
+		&i.OldPrice,
+		&i.NewPrice,
+		&i.ChangedBy,
+		&i.ChangedAt,
+	)
+	return i, err
+}
+
+const createStockAdjustment = `-- name: CreateStockAdjustment :one
+INSERT INTO stock_adjustment (store_id, variation_id, previous_quantity, quantity, reason, adjusted_by)
+VALUES ($1, $2, $3, $4, $5, $6)
+RETURNING id, store_id, variation_id, previous_quantity, quantity, reason, adjusted_by, created_at
+`
+
+type CreateStockAdjustmentParams struct {
+	StoreID          int32  `json:"store_id"`
+	VariationID      int32  `json:"variation_id"`
+	PreviousQuantity int32  `json:"previous_quantity"`
+	Quantity         int32  `json:"quantity"`
+	Reason           string `json:"reason"`
+	AdjustedBy       int32  `json:"adjusted_by"`
+}
+
+func (q *Queries) CreateStockAdjustment(ctx context.Context, arg CreateStockAdjustmentParams) (StockAdjustment, error) {
+	row := q.db.QueryRowContext(ctx, createStockAdjustment,
+		arg.StoreID,
+		arg.VariationID,
+		arg.PreviousQuantity,
+		arg.Quantity,
+		arg.Reason,
+		arg.AdjustedBy,
+	)
+	var i StockAdjustment
+	err := row.Scan(
+		&i.ID,
+		&i.StoreID,
+		&i.VariationID,
+		&i.PreviousQuantity,
+		&i.Quantity,
+		&i.Reason,
+		&i.AdjustedBy,
+		&i.CreatedAt,
+	)
+	return i, err
+}
+
 const createUnit = `-- name: CreateUnit :one
 INSERT INTO unit (name, short_code)
 VALUES ($1, $2)
@@ -187,7 +431,7 @@ func (q *Queries) CreateUnit(ctx context.Context, arg CreateUnitParams) (Unit, e
 const createVariation = `-- name: CreateVariation :one
 INSERT INTO variation (item_id, sku, name, unit_id, size, color_id, barcode, base_price, reorder_level, is_default)
 VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)
-RETURNING id, item_id, sku, name, unit_id, size, color_id, barcode, base_price, reorder_level, is_default, is_active, created_at, updated_at
+RETURNING id, item_id, sku, name, unit_id, size, color_id, barcode, base_price, reorder_level, is_default, is_active, created_at, updated_at, cost_price
 `
 
 type CreateVariationParams struct {
@@ -233,25 +477,38 @@ func (q *Queries) CreateVariation(ctx context.Context, arg CreateVariationParams
 		&i.IsActive,
 		&i.CreatedAt,
 		&i.UpdatedAt,
+		&i.CostPrice,
 	)
 	return i, err
 }
 
 const deleteBrand = `-- name: DeleteBrand :exec
-DELETE FROM brand WHERE id = $1
+DELETE FROM brand
+WHERE id = $1 AND ($2::int IS NULL OR business_id = $2)
 `
 
-func (q *Queries) DeleteBrand(ctx context.Context, id int32) error {
-	_, err := q.db.ExecContext(ctx, deleteBrand, id)
+type DeleteBrandParams struct {
+	ID         int32         `json:"id"`
+	BusinessID sql.NullInt32 `json:"business_id"`
+}
+
+func (q *Queries) DeleteBrand(ctx context.Context, arg DeleteBrandParams) error {
+	_, err := q.db.ExecContext(ctx, deleteBrand, arg.ID, arg.BusinessID)
 	return err
 }
 
 const deleteCategory = `-- name: DeleteCategory :exec
-DELETE FROM category WHERE id = $1
+DELETE FROM category
+WHERE id = $1 AND ($2::int IS NULL OR business_id = $2)
 `
 
-func (q *Queries) DeleteCategory(ctx context.Context, id int32) error {
-	_, err := q.db.ExecContext(ctx, deleteCategory, id)
+type DeleteCategoryParams struct {
+	ID         int32         `json:"id"`
+	BusinessID sql.NullInt32 `json:"business_id"`
+}
+
+func (q *Queries) DeleteCategory(ctx context.Context, arg DeleteCategoryParams) error {
+	_, err := q.db.ExecContext(ctx, deleteCategory, arg.ID, arg.BusinessID)
 	return err
 }
 
@@ -276,11 +533,17 @@ func (q *Queries) DeleteInventory(ctx context.Context, id int32) error {
 }
 
 const deleteItem = `-- name: DeleteItem :exec
-DELETE FROM item WHERE id = $1
+DELETE FROM item
+WHERE id = $1 AND ($2::int IS NULL OR business_id = $2)
 `
 
-func (q *Queries) DeleteItem(ctx context.Context, id int32) error {
-	_, err := q.db.ExecContext(ctx, deleteItem, id)
+type DeleteItemParams struct {
+	ID         int32         `json:"id"`
+	BusinessID sql.NullInt32 `json:"business_id"`
+}
+
+func (q *Queries) DeleteItem(ctx context.Context, arg DeleteItemParams) error {
+	_, err := q.db.ExecContext(ctx, deleteItem, arg.ID, arg.BusinessID)
 	return err
 }
 
@@ -314,11 +577,18 @@ func (q *Queries) DeleteVariation(ctx context.Context, id int32) error {
 }
 
 const getBrand = `-- name: GetBrand :one
-SELECT id, name, description, logo, is_active, created_at, updated_at FROM brand WHERE id = $1 LIMIT 1
+SELECT id, name, description, logo, is_active, created_at, updated_at, business_id, logo_thumbnail FROM brand
+WHERE id = $1 AND ($2::int IS NULL OR business_id = $2)
+LIMIT 1
 `
 
-func (q *Queries) GetBrand(ctx context.Context, id int32) (Brand, error) {
-	row := q.db.QueryRowContext(ctx, getBrand, id)
+type GetBrandParams struct {
+	ID         int32         `json:"id"`
+	BusinessID sql.NullInt32 `json:"business_id"`
+}
+
+func (q *Queries) GetBrand(ctx context.Context, arg GetBrandParams) (Brand, error) {
+	row := q.db.QueryRowContext(ctx, getBrand, arg.ID, arg.BusinessID)
 	var i Brand
 	err := row.Scan(
 		&i.ID,
@@ -328,16 +598,25 @@ func (q *Queries) GetBrand(ctx context.Context, id int32) (Brand, error) {
 		&i.IsActive,
 		&i.CreatedAt,
 		&i.UpdatedAt,
+		&i.BusinessID,
+		&i.LogoThumbnail,
 	)
 	return i, err
 }
 
 const getCategory = `-- name: GetCategory :one
-SELECT id, name, parent_id, description, is_active, created_at, updated_at FROM category WHERE id = $1 LIMIT 1
+SELECT id, name, parent_id, description, is_active, created_at, updated_at, business_id, depletion_strategy FROM category
+WHERE id = $1 AND ($2::int IS NULL OR business_id = $2)
+LIMIT 1
 `
 
-func (q *Queries) GetCategory(ctx context.Context, id int32) (Category, error) {
-	row := q.db.QueryRowContext(ctx, getCategory, id)
+type GetCategoryParams struct {
+	ID         int32         `json:"id"`
+	BusinessID sql.NullInt32 `json:"business_id"`
+}
+
+func (q *Queries) GetCategory(ctx context.Context, arg GetCategoryParams) (Category, error) {
+	row := q.db.QueryRowContext(ctx, getCategory, arg.ID, arg.BusinessID)
 	var i Category
 	err := row.Scan(
 		&i.ID,
@@ -347,10 +626,47 @@ func (q *Queries) GetCategory(ctx context.Context, id int32) (Category, error) {
 		&i.IsActive,
 		&i.CreatedAt,
 		&i.UpdatedAt,
+		&i.BusinessID,
+		&i.DepletionStrategy,
 	)
 	return i, err
 }
 
+const getCategoryAncestors = `-- name: GetCategoryAncestors :many
+WITH RECURSIVE ancestors AS (
+    SELECT id, parent_id FROM category WHERE id = $1
+    UNION
+    SELECT c.id, c.parent_id FROM category c
+    JOIN ancestors a ON c.id = a.parent_id
+)
+SELECT id FROM ancestors
+`
+
+// Walks up the parent chain of a category, the category itself included.
+// UNION stops the walk if the chain already loops.
+func (q *Queries) GetCategoryAncestors(ctx context.Context, id int32) ([]int32, error) {
+	rows, err := q.db.QueryContext(ctx, getCategoryAncestors, id)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []int32{}
+	for rows.Next() {
+		var id int32
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		items = append(items, id)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
 const getColorByID = `-- name: GetColorByID :one
 SELECT id, name, created_at, updated_at FROM color
 WHERE id = $1
@@ -386,7 +702,7 @@ func (q *Queries) GetColorByName(ctx context.Context, name string) (Color, error
 }
 
 const getInventoryByStore = `-- name: GetInventoryByStore :many
-SELECT id, store_id, variation_id, quantity, last_updated FROM inventory WHERE store_id = $1
+SELECT id, store_id, variation_id, quantity, last_updated, min_keep FROM inventory WHERE store_id = $1
 `
 
 func (q *Queries) GetInventoryByStore(ctx context.Context, storeID int32) ([]Inventory, error) {
@@ -404,6 +720,7 @@ func (q *Queries) GetInventoryByStore(ctx context.Context, storeID int32) ([]Inv
 			&i.VariationID,
 			&i.Quantity,
 			&i.LastUpdated,
+			&i.MinKeep,
 		); err != nil {
 			return nil, err
 		}
@@ -419,7 +736,7 @@ func (q *Queries) GetInventoryByStore(ctx context.Context, storeID int32) ([]Inv
 }
 
 const getInventoryItem = `-- name: GetInventoryItem :one
-SELECT id, store_id, variation_id, quantity, last_updated FROM inventory
+SELECT id, store_id, variation_id, quantity, last_updated, min_keep FROM inventory
 WHERE store_id = $1 AND variation_id = $2
 LIMIT 1
 `
@@ -438,16 +755,24 @@ func (q *Queries) GetInventoryItem(ctx context.Context, arg GetInventoryItemPara
 		&i.VariationID,
 		&i.Quantity,
 		&i.LastUpdated,
+		&i.MinKeep,
 	)
 	return i, err
 }
 
 const getItem = `-- name: GetItem :one
-SELECT id, brand_id, category_id, name, description, item_type, is_active, no_variants, created_at, updated_at FROM item WHERE id = $1 LIMIT 1
+SELECT id, brand_id, category_id, name, description, item_type, is_active, no_variants, created_at, updated_at, business_id, version FROM item
+WHERE id = $1 AND ($2::int IS NULL OR business_id = $2)
+LIMIT 1
 `
 
-func (q *Queries) GetItem(ctx context.Context, id int32) (Item, error) {
-	row := q.db.QueryRowContext(ctx, getItem, id)
+type GetItemParams struct {
+	ID         int32         `json:"id"`
+	BusinessID sql.NullInt32 `json:"business_id"`
+}
+
+func (q *Queries) GetItem(ctx context.Context, arg GetItemParams) (Item, error) {
+	row := q.db.QueryRowContext(ctx, getItem, arg.ID, arg.BusinessID)
 	var i Item
 	err := row.Scan(
 		&i.ID,
@@ -460,6 +785,8 @@ func (q *Queries) GetItem(ctx context.Context, id int32) (Item, error) {
 		&i.NoVariants,
 		&i.CreatedAt,
 		&i.UpdatedAt,
+		&i.BusinessID,
+		&i.Version,
 	)
 	return i, err
 }
@@ -532,6 +859,26 @@ func (q *Queries) GetItemImagesByVariation(ctx context.Context, variationID sql.
 	return items, nil
 }
 
+const getStoreInBusiness = `-- name: GetStoreInBusiness :one
+SELECT s.id
+FROM store s
+JOIN branch br ON br.id = s.branch_id
+WHERE s.id = $1
+  AND ($2::int IS NULL OR br.business_id = $2)
+`
+
+type GetStoreInBusinessParams struct {
+	ID         int32         `json:"id"`
+	BusinessID sql.NullInt32 `json:"business_id"`
+}
+
+func (q *Queries) GetStoreInBusiness(ctx context.Context, arg GetStoreInBusinessParams) (int32, error) {
+	row := q.db.QueryRowContext(ctx, getStoreInBusiness, arg.ID, arg.BusinessID)
+	var id int32
+	err := row.Scan(&id)
+	return id, err
+}
+
 const getUnitByID = `-- name: GetUnitByID :one
 SELECT id, name, short_code, created_at, updated_at FROM unit
 WHERE id = $1
@@ -551,7 +898,7 @@ func (q *Queries) GetUnitByID(ctx context.Context, id int32) (Unit, error) {
 }
 
 const getVariation = `-- name: GetVariation :one
-SELECT id, item_id, sku, name, unit_id, size, color_id, barcode, base_price, reorder_level, is_default, is_active, created_at, updated_at FROM variation WHERE id = $1 LIMIT 1
+SELECT id, item_id, sku, name, unit_id, size, color_id, barcode, base_price, reorder_level, is_default, is_active, created_at, updated_at, cost_price FROM variation WHERE id = $1 LIMIT 1
 `
 
 func (q *Queries) GetVariation(ctx context.Context, id int32) (Variation, error) {
@@ -572,16 +919,82 @@ func (q *Queries) GetVariation(ctx context.Context, id int32) (Variation, error)
 		&i.IsActive,
 		&i.CreatedAt,
 		&i.UpdatedAt,
+		&i.CostPrice,
+	)
+	return i, err
+}
+
+const getVariationInBusiness = `-- name: GetVariationInBusiness :one
+SELECT v.id
+FROM variation v
+JOIN item i ON i.id = v.item_id
+WHERE v.id = $1
+  AND ($2::int IS NULL OR i.business_id = $2)
+`
+
+type GetVariationInBusinessParams struct {
+	ID         int32         `json:"id"`
+	BusinessID sql.NullInt32 `json:"business_id"`
+}
+
+func (q *Queries) GetVariationInBusiness(ctx context.Context, arg GetVariationInBusinessParams) (int32, error) {
+	row := q.db.QueryRowContext(ctx, getVariationInBusiness, arg.ID, arg.BusinessID)
+	var id int32
+	err := row.Scan(&id)
+	return id, err
+}
+
+const getVariationPriceForUpdate = `-- name: GetVariationPriceForUpdate :one
+SELECT base_price FROM variation
+WHERE id = $1
+FOR UPDATE
+`
+
+func (q *Queries) GetVariationPriceForUpdate(ctx context.Context, id int32) (string, error) {
+	row := q.db.QueryRowContext(ctx, getVariationPriceForUpdate, id)
+	var base_price string
+	err := row.Scan(&base_price)
+	return base_price, err
+}
+
+const incrementInventoryQuantity = `-- name: IncrementInventoryQuantity :one
+INSERT INTO inventory (store_id, variation_id, quantity)
+VALUES ($1, $2, $3)
+ON CONFLICT (store_id, variation_id)
+DO UPDATE SET
+    quantity = inventory.quantity + EXCLUDED.quantity,
+    last_updated = NOW()
+RETURNING id, store_id, variation_id, quantity, last_updated, min_keep
+`
+
+type IncrementInventoryQuantityParams struct {
+	StoreID     int32 `json:"store_id"`
+	VariationID int32 `json:"variation_id"`
+	Quantity    int32 `json:"quantity"`
+}
+
+func (q *Queries) IncrementInventoryQuantity(ctx context.Context, arg IncrementInventoryQuantityParams) (Inventory, error) {
+	row := q.db.QueryRowContext(ctx, incrementInventoryQuantity, arg.StoreID, arg.VariationID, arg.Quantity)
+	var i Inventory
+	err := row.Scan(
+		&i.ID,
+		&i.StoreID,
+		&i.VariationID,
+		&i.Quantity,
+		&i.LastUpdated,
+		&i.MinKeep,
 	)
 	return i, err
 }
 
 const listBrands = `-- name: ListBrands :many
-SELECT id, name, description, logo, is_active, created_at, updated_at FROM brand ORDER BY name
+SELECT id, name, description, logo, is_active, created_at, updated_at, business_id, logo_thumbnail FROM brand
+WHERE ($1::int IS NULL OR business_id = $1)
+ORDER BY name
 `
 
-func (q *Queries) ListBrands(ctx context.Context) ([]Brand, error) {
-	rows, err := q.db.QueryContext(ctx, listBrands)
+func (q *Queries) ListBrands(ctx context.Context, businessID sql.NullInt32) ([]Brand, error) {
+	rows, err := q.db.QueryContext(ctx, listBrands, businessID)
 	if err != nil {
 		return nil, err
 	}
@@ -597,6 +1010,8 @@ func (q *Queries) ListBrands(ctx context.Context) ([]Brand, error) {
 			&i.IsActive,
 			&i.CreatedAt,
 			&i.UpdatedAt,
+			&i.BusinessID,
+			&i.LogoThumbnail,
 		); err != nil {
 			return nil, err
 		}
@@ -612,11 +1027,13 @@ func (q *Queries) ListBrands(ctx context.Context) ([]Brand, error) {
 }
 
 const listCategories = `-- name: ListCategories :many
-SELECT id, name, parent_id, description, is_active, created_at, updated_at FROM category ORDER BY name
+SELECT id, name, parent_id, description, is_active, created_at, updated_at, business_id, depletion_strategy FROM category
+WHERE ($1::int IS NULL OR business_id = $1)
+ORDER BY name
 `
 
-func (q *Queries) ListCategories(ctx context.Context) ([]Category, error) {
-	rows, err := q.db.QueryContext(ctx, listCategories)
+func (q *Queries) ListCategories(ctx context.Context, businessID sql.NullInt32) ([]Category, error) {
+	rows, err := q.db.QueryContext(ctx, listCategories, businessID)
 	if err != nil {
 		return nil, err
 	}
@@ -632,6 +1049,8 @@ func (q *Queries) ListCategories(ctx context.Context) ([]Category, error) {
 			&i.IsActive,
 			&i.CreatedAt,
 			&i.UpdatedAt,
+			&i.BusinessID,
+			&i.DepletionStrategy,
 		); err != nil {
 			return nil, err
 		}
@@ -679,19 +1098,129 @@ func (q *Queries) ListColors(ctx context.Context) ([]Color, error) {
 	return items, nil
 }
 
+const listExpiringBatches = `-- name: ListExpiringBatches :many
+SELECT
+    ib.id,
+    ib.store_id,
+    s.name AS store_name,
+    ib.variation_id,
+    v.sku,
+    v.name AS variation_name,
+    i.name AS item_name,
+    ib.lot_number,
+    ib.expiry_date,
+    ib.quantity,
+    (ib.expiry_date - (NOW() AT TIME ZONE COALESCE(b.timezone, 'UTC'))::date)::int AS days_left
+FROM inventory_batch ib
+JOIN store s ON s.id = ib.store_id
+JOIN branch br ON br.id = s.branch_id
+JOIN business b ON b.id = br.business_id
+JOIN variation v ON v.id = ib.variation_id
+JOIN item i ON i.id = v.item_id
+WHERE ib.quantity > 0
+  AND ib.expiry_date <= (NOW() AT TIME ZONE COALESCE(b.timezone, 'UTC'))::date + $1::int
+  AND ($2::int IS NULL OR b.id = $2)
+  AND ($3::int IS NULL OR ib.store_id = $3)
+ORDER BY ib.expiry_date, ib.store_id, ib.id
+`
+
+type ListExpiringBatchesParams struct {
+	WithinDays int32         `json:"within_days"`
+	BusinessID sql.NullInt32 `json:"business_id"`
+	StoreID    sql.NullInt32 `json:"store_id"`
+}
+
+type ListExpiringBatchesRow struct {
+	ID            int32     `json:"id"`
+	StoreID       int32     `json:"store_id"`
+	StoreName     string    `json:"store_name"`
+	VariationID   int32     `json:"variation_id"`
+	Sku           string    `json:"sku"`
+	VariationName string    `json:"variation_name"`
+	ItemName      string    `json:"item_name"`
+	LotNumber     string    `json:"lot_number"`
+	ExpiryDate    time.Time `json:"expiry_date"`
+	Quantity      int32     `json:"quantity"`
+	DaysLeft      int32     `json:"days_left"`
+}
+
+func (q *Queries) ListExpiringBatches(ctx context.Context, arg ListExpiringBatchesParams) ([]ListExpiringBatchesRow, error) {
+	rows, err := q.db.QueryContext(ctx, listExpiringBatches, arg.WithinDays, arg.BusinessID, arg.StoreID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []ListExpiringBatchesRow{}
+	for rows.Next() {
+		var i ListExpiringBatchesRow
+		if err := rows.Scan(
+			&i.ID,
+			&i.StoreID,
+			&i.StoreName,
+			&i.VariationID,
+			&i.Sku,
+			&i.VariationName,
+			&i.ItemName,
+			&i.LotNumber,
+			&i.ExpiryDate,
+			&i.Quantity,
+			&i.DaysLeft,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
 const listItems = `-- name: ListItems :many
-SELECT id, brand_id, category_id, name, description, item_type, is_active, no_variants, created_at, updated_at FROM item ORDER BY name
+SELECT i.id, i.brand_id, i.category_id, i.name, i.description, i.item_type, i.is_active, i.no_variants, i.created_at, i.updated_at, i.business_id, i.version, COUNT(v.id) AS variation_count
+FROM item i
+LEFT JOIN variation v ON v.item_id = i.id
+WHERE ($1::int IS NULL OR i.business_id = $1)
+  AND ($2::int IS NULL OR i.category_id = $2)
+  AND ($3::int IS NULL OR i.brand_id = $3)
+GROUP BY i.id
+ORDER BY i.name
 `
 
-func (q *Queries) ListItems(ctx context.Context) ([]Item, error) {
-	rows, err := q.db.QueryContext(ctx, listItems)
+type ListItemsParams struct {
+	BusinessID sql.NullInt32 `json:"business_id"`
+	CategoryID sql.NullInt32 `json:"category_id"`
+	BrandID    sql.NullInt32 `json:"brand_id"`
+}
+
+type ListItemsRow struct {
+	ID             int32          `json:"id"`
+	BrandID        sql.NullInt32  `json:"brand_id"`
+	CategoryID     int32          `json:"category_id"`
+	Name           string         `json:"name"`
+	Description    sql.NullString `json:"description"`
+	ItemType       string         `json:"item_type"`
+	IsActive       sql.NullBool   `json:"is_active"`
+	NoVariants     sql.NullBool   `json:"no_variants"`
+	CreatedAt      sql.NullTime   `json:"created_at"`
+	UpdatedAt      sql.NullTime   `json:"updated_at"`
+	BusinessID     sql.NullInt32  `json:"business_id"`
+	Version        int32          `json:"version"`
+	VariationCount int64          `json:"variation_count"`
+}
+
+func (q *Queries) ListItems(ctx context.Context, arg ListItemsParams) ([]ListItemsRow, error) {
+	rows, err := q.db.QueryContext(ctx, listItems, arg.BusinessID, arg.CategoryID, arg.BrandID)
 	if err != nil {
 		return nil, err
 	}
 	defer rows.Close()
-	items := []Item{}
+	items := []ListItemsRow{}
 	for rows.Next() {
-		var i Item
+		var i ListItemsRow
 		if err := rows.Scan(
 			&i.ID,
 			&i.BrandID,
@@ -703,6 +1232,9 @@ func (q *Queries) ListItems(ctx context.Context) ([]Item, error) {
 			&i.NoVariants,
 			&i.CreatedAt,
 			&i.UpdatedAt,
+			&i.BusinessID,
+			&i.Version,
+			&i.VariationCount,
 		); err != nil {
 			return nil, err
 		}
@@ -718,11 +1250,18 @@ func (q *Queries) ListItems(ctx context.Context) ([]Item, error) {
 }
 
 const listItemsByCategory = `-- name: ListItemsByCategory :many
-SELECT id, brand_id, category_id, name, description, item_type, is_active, no_variants, created_at, updated_at FROM item WHERE category_id = $1 ORDER BY name
+SELECT id, brand_id, category_id, name, description, item_type, is_active, no_variants, created_at, updated_at, business_id, version FROM item
+WHERE category_id = $1 AND ($2::int IS NULL OR business_id = $2)
+ORDER BY name
 `
 
-func (q *Queries) ListItemsByCategory(ctx context.Context, categoryID int32) ([]Item, error) {
-	rows, err := q.db.QueryContext(ctx, listItemsByCategory, categoryID)
+type ListItemsByCategoryParams struct {
+	CategoryID int32         `json:"category_id"`
+	BusinessID sql.NullInt32 `json:"business_id"`
+}
+
+func (q *Queries) ListItemsByCategory(ctx context.Context, arg ListItemsByCategoryParams) ([]Item, error) {
+	rows, err := q.db.QueryContext(ctx, listItemsByCategory, arg.CategoryID, arg.BusinessID)
 	if err != nil {
 		return nil, err
 	}
@@ -741,6 +1280,192 @@ func (q *Queries) ListItemsByCategory(ctx context.Context, categoryID int32) ([]
 			&i.NoVariants,
 			&i.CreatedAt,
 			&i.UpdatedAt,
+			&i.BusinessID,
+			&i.Version,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listLowStockVariations = `-- name: ListLowStockVariations :many
+SELECT
+    inv.store_id,
+    s.name AS store_name,
+    s.address AS store_address,
+    inv.variation_id,
+    v.sku,
+    v.name AS variation_name,
+    i.name AS item_name,
+    inv.quantity,
+    COALESCE($1::int, b.low_stock_threshold, 0)::int AS threshold
+FROM inventory inv
+JOIN store s ON s.id = inv.store_id
+JOIN branch br ON br.id = s.branch_id
+JOIN business b ON b.id = br.business_id
+JOIN variation v ON v.id = inv.variation_id
+JOIN item i ON i.id = v.item_id
+WHERE ($2::int IS NULL OR b.id = $2)
+  AND ($3::int IS NULL OR inv.store_id = $3)
+  AND inv.quantity <= COALESCE($1::int, b.low_stock_threshold, 0)
+ORDER BY inv.quantity, inv.store_id, inv.variation_id
+`
+
+type ListLowStockVariationsParams struct {
+	Threshold  sql.NullInt32 `json:"threshold"`
+	BusinessID sql.NullInt32 `json:"business_id"`
+	StoreID    sql.NullInt32 `json:"store_id"`
+}
+
+type ListLowStockVariationsRow struct {
+	StoreID       int32  `json:"store_id"`
+	StoreName     string `json:"store_name"`
+	StoreAddress  string `json:"store_address"`
+	VariationID   int32  `json:"variation_id"`
+	Sku           string `json:"sku"`
+	VariationName string `json:"variation_name"`
+	ItemName      string `json:"item_name"`
+	Quantity      int32  `json:"quantity"`
+	Threshold     int32  `json:"threshold"`
+}
+
+func (q *Queries) ListLowStockVariations(ctx context.Context, arg ListLowStockVariationsParams) ([]ListLowStockVariationsRow, error) {
+	rows, err := q.db.QueryContext(ctx, listLowStockVariations, arg.Threshold, arg.BusinessID, arg.StoreID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []ListLowStockVariationsRow{}
+	for rows.Next() {
+		var i ListLowStockVariationsRow
+		if err := rows.Scan(
+			&i.StoreID,
+			&i.StoreName,
+			&i.StoreAddress,
+			&i.VariationID,
+			&i.Sku,
+			&i.VariationName,
+			&i.ItemName,
+			&i.Quantity,
+			&i.Threshold,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listPriceHistory = `-- name: ListPriceHistory :many
+SELECT id, variation_id, old_price, new_price, changed_by, changed_at FROM price_history
+WHERE variation_id = $1
+ORDER BY changed_at, id
+`
+
+func (q *Queries) ListPriceHistory(ctx context.Context, variationID int32) ([]PriceHistory, error) {
+	rows, err := q.db.QueryContext(ctx, listPriceHistory, variationID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []PriceHistory{}
+	for rows.Next() {
+		var i PriceHistory
+		if err := rows.Scan(
+			&i.ID,
+			&i.VariationID,
+			&i.OldPrice,
+			&i.NewPrice,
+			&i.ChangedBy,
+			&i.ChangedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listSearchVariations = `-- name: ListSearchVariations :many
+SELECT v.id, v.item_id, v.sku, v.name, v.unit_id, v.size, v.color_id, v.barcode, v.base_price, v.reorder_level, v.is_default, v.is_active, v.created_at, v.updated_at, v.cost_price,
+       (to_tsvector('simple', v.name || ' ' || v.sku || ' ' || COALESCE(v.barcode, '')) @@ to_tsquery('simple', $1))::bool AS matched
+FROM variation v
+WHERE v.item_id = ANY($2::int[])
+ORDER BY v.item_id, matched DESC, v.id
+`
+
+type ListSearchVariationsParams struct {
+	Query   string  `json:"query"`
+	ItemIds []int32 `json:"item_ids"`
+}
+
+type ListSearchVariationsRow struct {
+	ID           int32          `json:"id"`
+	ItemID       int32          `json:"item_id"`
+	Sku          string         `json:"sku"`
+	Name         string         `json:"name"`
+	UnitID       int32          `json:"unit_id"`
+	Size         sql.NullString `json:"size"`
+	ColorID      sql.NullInt32  `json:"color_id"`
+	Barcode      sql.NullString `json:"barcode"`
+	BasePrice    string         `json:"base_price"`
+	ReorderLevel sql.NullInt32  `json:"reorder_level"`
+	IsDefault    sql.NullBool   `json:"is_default"`
+	IsActive     sql.NullBool   `json:"is_active"`
+	CreatedAt    sql.NullTime   `json:"created_at"`
+	UpdatedAt    sql.NullTime   `json:"updated_at"`
+	CostPrice    sql.NullString `json:"cost_price"`
+	Matched      bool           `json:"matched"`
+}
+
+// The variations of the items found by SearchItems, flagging the ones that
+// match query themselves.
+func (q *Queries) ListSearchVariations(ctx context.Context, arg ListSearchVariationsParams) ([]ListSearchVariationsRow, error) {
+	rows, err := q.db.QueryContext(ctx, listSearchVariations, arg.Query, pq.Array(arg.ItemIds))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []ListSearchVariationsRow{}
+	for rows.Next() {
+		var i ListSearchVariationsRow
+		if err := rows.Scan(
+			&i.ID,
+			&i.ItemID,
+			&i.Sku,
+			&i.Name,
+			&i.UnitID,
+			&i.Size,
+			&i.ColorID,
+			&i.Barcode,
+			&i.BasePrice,
+			&i.ReorderLevel,
+			&i.IsDefault,
+			&i.IsActive,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.CostPrice,
+			&i.Matched,
 		); err != nil {
 			return nil, err
 		}
@@ -790,7 +1515,7 @@ func (q *Queries) ListUnits(ctx context.Context) ([]Unit, error) {
 }
 
 const listVariationsByItem = `-- name: ListVariationsByItem :many
-SELECT id, item_id, sku, name, unit_id, size, color_id, barcode, base_price, reorder_level, is_default, is_active, created_at, updated_at FROM variation WHERE item_id = $1 ORDER BY name
+SELECT id, item_id, sku, name, unit_id, size, color_id, barcode, base_price, reorder_level, is_default, is_active, created_at, updated_at, cost_price FROM variation WHERE item_id = $1 ORDER BY name
 `
 
 func (q *Queries) ListVariationsByItem(ctx context.Context, itemID int32) ([]Variation, error) {
@@ -817,6 +1542,7 @@ func (q *Queries) ListVariationsByItem(ctx context.Context, itemID int32) ([]Var
 			&i.IsActive,
 			&i.CreatedAt,
 			&i.UpdatedAt,
+			&i.CostPrice,
 		); err != nil {
 			return nil, err
 		}
@@ -831,6 +1557,174 @@ func (q *Queries) ListVariationsByItem(ctx context.Context, itemID int32) ([]Var
 	return items, nil
 }
 
+const receiveInventoryBatch = `-- name: ReceiveInventoryBatch :one
+INSERT INTO inventory_batch (store_id, variation_id, lot_number, expiry_date, quantity)
+VALUES ($1, $2, $3, $4, $5)
+ON CONFLICT (store_id, variation_id, lot_number)
+DO UPDATE SET
+    quantity = inventory_batch.quantity + EXCLUDED.quantity,
+    expiry_date = EXCLUDED.expiry_date,
+    updated_at = NOW()
+RETURNING id, store_id, variation_id, lot_number, expiry_date, quantity, created_at, updated_at
+`
+
+type ReceiveInventoryBatchParams struct {
+	StoreID     int32     `json:"store_id"`
+	VariationID int32     `json:"variation_id"`
+	LotNumber   string    `json:"lot_number"`
+	ExpiryDate  time.Time `json:"expiry_date"`
+	Quantity    int32     `json:"quantity"`
+}
+
+func (q *Queries) ReceiveInventoryBatch(ctx context.Context, arg ReceiveInventoryBatchParams) (InventoryBatch, error) {
+	row := q.db.QueryRowContext(ctx, receiveInventoryBatch,
+		arg.StoreID,
+		arg.VariationID,
+		arg.LotNumber,
+		arg.ExpiryDate,
+		arg.Quantity,
+	)
+	var i InventoryBatch
+	err := row.Scan(
+		&i.ID,
+		&i.StoreID,
+		&i.VariationID,
+		&i.LotNumber,
+		&i.ExpiryDate,
+		&i.Quantity,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+	)
+	return i, err
+}
+
+const searchItems = `-- name: SearchItems :many
+WITH matches AS (
+    SELECT i.id, ts_rank(to_tsvector('simple', i.name || ' ' || COALESCE(i.description, '')), to_tsquery('simple', $1)) AS rank
+    FROM item i
+    WHERE to_tsvector('simple', i.name || ' ' || COALESCE(i.description, '')) @@ to_tsquery('simple', $1)
+      AND ($2::int IS NULL OR i.business_id = $2)
+    UNION ALL
+    SELECT v.item_id, ts_rank(to_tsvector('simple', v.name || ' ' || v.sku || ' ' || COALESCE(v.barcode, '')), to_tsquery('simple', $1))
+    FROM variation v
+    JOIN item i ON i.id = v.item_id
+    WHERE to_tsvector('simple', v.name || ' ' || v.sku || ' ' || COALESCE(v.barcode, '')) @@ to_tsquery('simple', $1)
+      AND ($2::int IS NULL OR i.business_id = $2)
+)
+SELECT i.id, i.brand_id, i.category_id, i.name, i.description, i.item_type, i.is_active,
+       MAX(m.rank)::real AS rank
+FROM matches m
+JOIN item i ON i.id = m.id
+GROUP BY i.id
+ORDER BY rank DESC, i.name, i.id
+LIMIT $3 OFFSET $4
+`
+
+type SearchItemsParams struct {
+	Query      string        `json:"query"`
+	BusinessID sql.NullInt32 `json:"business_id"`
+	PageLimit  int32         `json:"page_limit"`
+	PageOffset int32         `json:"page_offset"`
+}
+
+type SearchItemsRow struct {
+	ID          int32          `json:"id"`
+	BrandID     sql.NullInt32  `json:"brand_id"`
+	CategoryID  int32          `json:"category_id"`
+	Name        string         `json:"name"`
+	Description sql.NullString `json:"description"`
+	ItemType    string         `json:"item_type"`
+	IsActive    sql.NullBool   `json:"is_active"`
+	Rank        float32        `json:"rank"`
+}
+
+// Items whose name or description, or one of whose variations' name, SKU or
+// barcode, match query, best match first. The expressions match the search
+// indexes, keep them in sync.
+func (q *Queries) SearchItems(ctx context.Context, arg SearchItemsParams) ([]SearchItemsRow, error) {
+	rows, err := q.db.QueryContext(ctx, searchItems,
+		arg.Query,
+		arg.BusinessID,
+		arg.PageLimit,
+		arg.PageOffset,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []SearchItemsRow{}
+	for rows.Next() {
+		var i SearchItemsRow
+		if err := rows.Scan(
+			&i.ID,
+			&i.BrandID,
+			&i.CategoryID,
+			&i.Name,
+			&i.Description,
+			&i.ItemType,
+			&i.IsActive,
+			&i.Rank,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const setInventoryMinKeep = `-- name: SetInventoryMinKeep :one
+INSERT INTO inventory (store_id, variation_id, min_keep)
+VALUES ($1, $2, $3)
+ON CONFLICT (store_id, variation_id)
+DO UPDATE SET
+    min_keep = EXCLUDED.min_keep,
+    last_updated = NOW()
+RETURNING id, store_id, variation_id, quantity, last_updated, min_keep
+`
+
+type SetInventoryMinKeepParams struct {
+	StoreID     int32 `json:"store_id"`
+	VariationID int32 `json:"variation_id"`
+	MinKeep     int32 `json:"min_keep"`
+}
+
+func (q *Queries) SetInventoryMinKeep(ctx context.Context, arg SetInventoryMinKeepParams) (Inventory, error) {
+	row := q.db.QueryRowContext(ctx, setInventoryMinKeep, arg.StoreID, arg.VariationID, arg.MinKeep)
+	var i Inventory
+	err := row.Scan(
+		&i.ID,
+		&i.StoreID,
+		&i.VariationID,
+		&i.Quantity,
+		&i.LastUpdated,
+		&i.MinKeep,
+	)
+	return i, err
+}
+
+const setVariationCostPrice = `-- name: SetVariationCostPrice :exec
+UPDATE variation
+SET cost_price = $2,
+    updated_at = NOW()
+WHERE id = $1
+`
+
+type SetVariationCostPriceParams struct {
+	ID        int32          `json:"id"`
+	CostPrice sql.NullString `json:"cost_price"`
+}
+
+func (q *Queries) SetVariationCostPrice(ctx context.Context, arg SetVariationCostPriceParams) error {
+	_, err := q.db.ExecContext(ctx, setVariationCostPrice, arg.ID, arg.CostPrice)
+	return err
+}
+
 const updateBrand = `-- name: UpdateBrand :one
 UPDATE brand
 SET name = $2,
@@ -838,8 +1732,8 @@ SET name = $2,
     logo = $4,
     is_active = $5,
     updated_at = NOW()
-WHERE id = $1
-RETURNING id, name, description, logo, is_active, created_at, updated_at
+WHERE id = $1 AND ($6::int IS NULL OR business_id = $6)
+RETURNING id, name, description, logo, is_active, created_at, updated_at, business_id, logo_thumbnail
 `
 
 type UpdateBrandParams struct {
@@ -848,6 +1742,7 @@ type UpdateBrandParams struct {
 	Description sql.NullString `json:"description"`
 	Logo        sql.NullString `json:"logo"`
 	IsActive    sql.NullBool   `json:"is_active"`
+	BusinessID  sql.NullInt32  `json:"business_id"`
 }
 
 func (q *Queries) UpdateBrand(ctx context.Context, arg UpdateBrandParams) (Brand, error) {
@@ -857,6 +1752,7 @@ func (q *Queries) UpdateBrand(ctx context.Context, arg UpdateBrandParams) (Brand
 		arg.Description,
 		arg.Logo,
 		arg.IsActive,
+		arg.BusinessID,
 	)
 	var i Brand
 	err := row.Scan(
@@ -867,6 +1763,8 @@ func (q *Queries) UpdateBrand(ctx context.Context, arg UpdateBrandParams) (Brand
 		&i.IsActive,
 		&i.CreatedAt,
 		&i.UpdatedAt,
+		&i.BusinessID,
+		&i.LogoThumbnail,
 	)
 	return i, err
 }
@@ -877,17 +1775,20 @@ SET name = $2,
     parent_id = $3,
     description = $4,
     is_active = $5,
+    depletion_strategy = $7,
     updated_at = NOW()
-WHERE id = $1
-RETURNING id, name, parent_id, description, is_active, created_at, updated_at
+WHERE id = $1 AND ($6::int IS NULL OR business_id = $6)
+RETURNING id, name, parent_id, description, is_active, created_at, updated_at, business_id, depletion_strategy
 `
 
 type UpdateCategoryParams struct {
-	ID          int32          `json:"id"`
-	Name        string         `json:"name"`
-	ParentID    sql.NullInt32  `json:"parent_id"`
-	Description sql.NullString `json:"description"`
-	IsActive    sql.NullBool   `json:"is_active"`
+	ID                int32          `json:"id"`
+	Name              string         `json:"name"`
+	ParentID          sql.NullInt32  `json:"parent_id"`
+	Description       sql.NullString `json:"description"`
+	IsActive          sql.NullBool   `json:"is_active"`
+	BusinessID        sql.NullInt32  `json:"business_id"`
+	DepletionStrategy sql.NullString `json:"depletion_strategy"`
 }
 
 func (q *Queries) UpdateCategory(ctx context.Context, arg UpdateCategoryParams) (Category, error) {
@@ -897,6 +1798,8 @@ func (q *Queries) UpdateCategory(ctx context.Context, arg UpdateCategoryParams)
 		arg.ParentID,
 		arg.Description,
 		arg.IsActive,
+		arg.BusinessID,
+		arg.DepletionStrategy,
 	)
 	var i Category
 	err := row.Scan(
@@ -907,6 +1810,8 @@ func (q *Queries) UpdateCategory(ctx context.Context, arg UpdateCategoryParams)
 		&i.IsActive,
 		&i.CreatedAt,
 		&i.UpdatedAt,
+		&i.BusinessID,
+		&i.DepletionStrategy,
 	)
 	return i, err
 }
@@ -941,7 +1846,7 @@ UPDATE inventory
 SET quantity = $3,
     last_updated = NOW()
 WHERE store_id = $1 AND variation_id = $2
-RETURNING id, store_id, variation_id, quantity, last_updated
+RETURNING id, store_id, variation_id, quantity, last_updated, min_keep
 `
 
 type UpdateInventoryQuantityParams struct {
@@ -959,38 +1864,42 @@ func (q *Queries) UpdateInventoryQuantity(ctx context.Context, arg UpdateInvento
 		&i.VariationID,
 		&i.Quantity,
 		&i.LastUpdated,
+		&i.MinKeep,
 	)
 	return i, err
 }
 
 const updateItem = `-- name: UpdateItem :one
 UPDATE item
-SET brand_id = $2,
-    category_id = $3,
-    name = $4,
-    description = $5,
-    item_type = $6,
-    no_variants = $7,
-    is_active = $8,
+SET brand_id = COALESCE($1, brand_id),
+    category_id = COALESCE($2, category_id),
+    name = COALESCE($3, name),
+    description = COALESCE($4, description),
+    item_type = COALESCE($5, item_type),
+    no_variants = COALESCE($6, no_variants),
+    is_active = COALESCE($7, is_active),
+    version = version + 1,
     updated_at = NOW()
-WHERE id = $1
-RETURNING id, brand_id, category_id, name, description, item_type, is_active, no_variants, created_at, updated_at
+WHERE id = $8 AND ($9::int IS NULL OR business_id = $9)
+  AND ($10::int IS NULL OR version = $10)
+RETURNING id, brand_id, category_id, name, description, item_type, is_active, no_variants, created_at, updated_at, business_id, version
 `
 
 type UpdateItemParams struct {
-	ID          int32          `json:"id"`
-	BrandID     sql.NullInt32  `json:"brand_id"`
-	CategoryID  int32          `json:"category_id"`
-	Name        string         `json:"name"`
-	Description sql.NullString `json:"description"`
-	ItemType    string         `json:"item_type"`
-	NoVariants  sql.NullBool   `json:"no_variants"`
-	IsActive    sql.NullBool   `json:"is_active"`
+	BrandID         sql.NullInt32  `json:"brand_id"`
+	CategoryID      sql.NullInt32  `json:"category_id"`
+	Name            sql.NullString `json:"name"`
+	Description     sql.NullString `json:"description"`
+	ItemType        sql.NullString `json:"item_type"`
+	NoVariants      sql.NullBool   `json:"no_variants"`
+	IsActive        sql.NullBool   `json:"is_active"`
+	ID              int32          `json:"id"`
+	BusinessID      sql.NullInt32  `json:"business_id"`
+	ExpectedVersion sql.NullInt32  `json:"expected_version"`
 }
 
 func (q *Queries) UpdateItem(ctx context.Context, arg UpdateItemParams) (Item, error) {
 	row := q.db.QueryRowContext(ctx, updateItem,
-		arg.ID,
 		arg.BrandID,
 		arg.CategoryID,
 		arg.Name,
@@ -998,6 +1907,9 @@ func (q *Queries) UpdateItem(ctx context.Context, arg UpdateItemParams) (Item, e
 		arg.ItemType,
 		arg.NoVariants,
 		arg.IsActive,
+		arg.ID,
+		arg.BusinessID,
+		arg.ExpectedVersion,
 	)
 	var i Item
 	err := row.Scan(
@@ -1011,6 +1923,8 @@ func (q *Queries) UpdateItem(ctx context.Context, arg UpdateItemParams) (Item, e
 		&i.NoVariants,
 		&i.CreatedAt,
 		&i.UpdatedAt,
+		&i.BusinessID,
+		&i.Version,
 	)
 	return i, err
 }
@@ -1057,7 +1971,7 @@ SET sku = $2,
     is_active = $11,
     updated_at = NOW()
 WHERE id = $1
-RETURNING id, item_id, sku, name, unit_id, size, color_id, barcode, base_price, reorder_level, is_default, is_active, created_at, updated_at
+RETURNING id, item_id, sku, name, unit_id, size, color_id, barcode, base_price, reorder_level, is_default, is_active, created_at, updated_at, cost_price
 `
 
 type UpdateVariationParams struct {
@@ -1104,6 +2018,43 @@ func (q *Queries) UpdateVariation(ctx context.Context, arg UpdateVariationParams
 		&i.IsActive,
 		&i.CreatedAt,
 		&i.UpdatedAt,
+		&i.CostPrice,
+	)
+	return i, err
+}
+
+const updateVariationPrice = `-- name: UpdateVariationPrice :one
+UPDATE variation
+SET base_price = $2,
+    updated_at = NOW()
+WHERE id = $1
+RETURNING id, item_id, sku, name, unit_id, size, color_id, barcode, base_price, reorder_level, is_default, is_active, created_at, updated_at, cost_price
+`
+
+type UpdateVariationPriceParams struct {
+	ID        int32  `json:"id"`
+	BasePrice string `json:"base_price"`
+}
+
+func (q *Queries) UpdateVariationPrice(ctx context.Context, arg UpdateVariationPriceParams) (Variation, error) {
+	row := q.db.QueryRowContext(ctx, updateVariationPrice, arg.ID, arg.BasePrice)
+	var i Variation
+	err := row.Scan(
+		&i.ID,
+		&i.ItemID,
+		&i.Sku,
+		&i.Name,
+		&i.UnitID,
+		&i.Size,
+		&i.ColorID,
+		&i.Barcode,
+		&i.BasePrice,
+		&i.ReorderLevel,
+		&i.IsDefault,
+		&i.IsActive,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.CostPrice,
 	)
 	return i, err
 }
@@ -1115,7 +2066,7 @@ ON CONFLICT (store_id, variation_id)
 DO UPDATE SET
     quantity = EXCLUDED.quantity,
     last_updated = NOW()
-RETURNING id, store_id, variation_id, quantity, last_updated
+RETURNING id, store_id, variation_id, quantity, last_updated, min_keep
 `
 
 type UpsertInventoryParams struct {
@@ -1134,6 +2085,7 @@ func (q *Queries) UpsertInventory(ctx context.Context, arg UpsertInventoryParams
 		&i.VariationID,
 		&i.Quantity,
 		&i.LastUpdated,
+		&i.MinKeep,
 	)
 	return i, err
 }
@@ -103,13 +103,15 @@ type Branch struct {
 }
 
 type Brand struct {
-	ID          int32          `json:"id"`
-	Name        string         `json:"name"`
-	Description sql.NullString `json:"description"`
-	Logo        sql.NullString `json:"logo"`
-	IsActive    sql.NullBool   `json:"is_active"`
-	CreatedAt   sql.NullTime   `json:"created_at"`
-	UpdatedAt   sql.NullTime   `json:"updated_at"`
+	ID            int32          `json:"id"`
+	Name          string         `json:"name"`
+	Description   sql.NullString `json:"description"`
+	Logo          sql.NullString `json:"logo"`
+	IsActive      sql.NullBool   `json:"is_active"`
+	CreatedAt     sql.NullTime   `json:"created_at"`
+	UpdatedAt     sql.NullTime   `json:"updated_at"`
+	BusinessID    sql.NullInt32  `json:"business_id"`
+	LogoThumbnail sql.NullString `json:"logo_thumbnail"`
 }
 
 type Business struct {
@@ -134,16 +136,38 @@ type Business struct {
 	PrimaryColor      sql.NullString `json:"primary_color"`
 	CreatedAt         sql.NullTime   `json:"created_at"`
 	UpdatedAt         sql.NullTime   `json:"updated_at"`
+	DepletionStrategy string         `json:"depletion_strategy"`
+	DeletedAt         sql.NullTime   `json:"deleted_at"`
+	LogoThumbnailUrl  sql.NullString `json:"logo_thumbnail_url"`
+	Version           int32          `json:"version"`
+}
+
+type BusinessSettingsHistory struct {
+	ID               int32          `json:"id"`
+	BusinessID       int32          `json:"business_id"`
+	TaxRate          sql.NullString `json:"tax_rate"`
+	Rounding         sql.NullString `json:"rounding"`
+	Currency         sql.NullString `json:"currency"`
+	PaymentType      []PaymentType  `json:"payment_type"`
+	Motto            sql.NullString `json:"motto"`
+	LogoUrl          sql.NullString `json:"logo_url"`
+	LogoThumbnailUrl sql.NullString `json:"logo_thumbnail_url"`
+	Font             sql.NullString `json:"font"`
+	PrimaryColor     sql.NullString `json:"primary_color"`
+	ChangedBy        int32          `json:"changed_by"`
+	ChangedAt        time.Time      `json:"changed_at"`
 }
 
 type Category struct {
-	ID          int32          `json:"id"`
-	Name        string         `json:"name"`
-	ParentID    sql.NullInt32  `json:"parent_id"`
-	Description sql.NullString `json:"description"`
-	IsActive    sql.NullBool   `json:"is_active"`
-	CreatedAt   sql.NullTime   `json:"created_at"`
-	UpdatedAt   sql.NullTime   `json:"updated_at"`
+	ID                int32          `json:"id"`
+	Name              string         `json:"name"`
+	ParentID          sql.NullInt32  `json:"parent_id"`
+	Description       sql.NullString `json:"description"`
+	IsActive          sql.NullBool   `json:"is_active"`
+	CreatedAt         sql.NullTime   `json:"created_at"`
+	UpdatedAt         sql.NullTime   `json:"updated_at"`
+	BusinessID        sql.NullInt32  `json:"business_id"`
+	DepletionStrategy sql.NullString `json:"depletion_strategy"`
 }
 
 type Color struct {
@@ -153,12 +177,95 @@ type Color struct {
 	UpdatedAt sql.NullTime `json:"updated_at"`
 }
 
+type Coupon struct {
+	ID         int32         `json:"id"`
+	BusinessID int32         `json:"business_id"`
+	DiscountID int32         `json:"discount_id"`
+	Code       string        `json:"code"`
+	UsageLimit sql.NullInt32 `json:"usage_limit"`
+	UsedCount  int32         `json:"used_count"`
+	ExpiresAt  sql.NullTime  `json:"expires_at"`
+	IsActive   bool          `json:"is_active"`
+	CreatedBy  int32         `json:"created_by"`
+	CreatedAt  sql.NullTime  `json:"created_at"`
+	UpdatedAt  sql.NullTime  `json:"updated_at"`
+}
+
+type Discount struct {
+	ID         int32          `json:"id"`
+	BusinessID int32          `json:"business_id"`
+	Name       string         `json:"name"`
+	Kind       string         `json:"kind"`
+	Value      string         `json:"value"`
+	Scope      string         `json:"scope"`
+	ItemID     sql.NullInt32  `json:"item_id"`
+	CategoryID sql.NullInt32  `json:"category_id"`
+	MinSpend   sql.NullString `json:"min_spend"`
+	StartsAt   sql.NullTime   `json:"starts_at"`
+	EndsAt     sql.NullTime   `json:"ends_at"`
+	IsActive   bool           `json:"is_active"`
+	CreatedBy  int32          `json:"created_by"`
+	CreatedAt  sql.NullTime   `json:"created_at"`
+	UpdatedAt  sql.NullTime   `json:"updated_at"`
+}
+
+type Folio struct {
+	ID         int32        `json:"id"`
+	BusinessID int32        `json:"business_id"`
+	RoomNumber string       `json:"room_number"`
+	GuestName  string       `json:"guest_name"`
+	Status     string       `json:"status"`
+	CreatedAt  sql.NullTime `json:"created_at"`
+	ClosedAt   sql.NullTime `json:"closed_at"`
+}
+
+type FolioCharge struct {
+	ID        int32        `json:"id"`
+	FolioID   int32        `json:"folio_id"`
+	SaleID    int32        `json:"sale_id"`
+	Amount    string       `json:"amount"`
+	CreatedAt sql.NullTime `json:"created_at"`
+}
+
 type Inventory struct {
 	ID          int32        `json:"id"`
 	StoreID     int32        `json:"store_id"`
 	VariationID int32        `json:"variation_id"`
 	Quantity    int32        `json:"quantity"`
 	LastUpdated sql.NullTime `json:"last_updated"`
+	MinKeep     int32        `json:"min_keep"`
+}
+
+type InventoryBatch struct {
+	ID          int32        `json:"id"`
+	StoreID     int32        `json:"store_id"`
+	VariationID int32        `json:"variation_id"`
+	LotNumber   string       `json:"lot_number"`
+	ExpiryDate  time.Time    `json:"expiry_date"`
+	Quantity    int32        `json:"quantity"`
+	CreatedAt   sql.NullTime `json:"created_at"`
+	UpdatedAt   sql.NullTime `json:"updated_at"`
+}
+
+type InventoryBatchMovement struct {
+	ID          int32         `json:"id"`
+	BatchID     int32         `json:"batch_id"`
+	Quantity    int32         `json:"quantity"`
+	Reason      string        `json:"reason"`
+	ReferenceID sql.NullInt32 `json:"reference_id"`
+	CreatedAt   sql.NullTime  `json:"created_at"`
+}
+
+type InventoryReservation struct {
+	ID          int32        `json:"id"`
+	StoreID     int32        `json:"store_id"`
+	VariationID int32        `json:"variation_id"`
+	Quantity    int32        `json:"quantity"`
+	Status      string       `json:"status"`
+	ReservedBy  int32        `json:"reserved_by"`
+	ExpiresAt   time.Time    `json:"expires_at"`
+	CreatedAt   sql.NullTime `json:"created_at"`
+	UpdatedAt   sql.NullTime `json:"updated_at"`
 }
 
 type Item struct {
@@ -172,6 +279,8 @@ type Item struct {
 	NoVariants  sql.NullBool   `json:"no_variants"`
 	CreatedAt   sql.NullTime   `json:"created_at"`
 	UpdatedAt   sql.NullTime   `json:"updated_at"`
+	BusinessID  sql.NullInt32  `json:"business_id"`
+	Version     int32          `json:"version"`
 }
 
 type ItemImage struct {
@@ -193,6 +302,39 @@ type LoginHistory struct {
 	ErrorReason     sql.NullString `json:"error_reason"`
 }
 
+type LoyaltyPoint struct {
+	ID         int32         `json:"id"`
+	CustomerID int32         `json:"customer_id"`
+	SaleID     int32         `json:"sale_id"`
+	RefundID   sql.NullInt32 `json:"refund_id"`
+	Points     int32         `json:"points"`
+	CreatedAt  sql.NullTime  `json:"created_at"`
+}
+
+type LoyaltyRule struct {
+	BusinessID int32        `json:"business_id"`
+	EarnRate   string       `json:"earn_rate"`
+	Rounding   string       `json:"rounding"`
+	CreatedAt  sql.NullTime `json:"created_at"`
+	UpdatedAt  sql.NullTime `json:"updated_at"`
+}
+
+type NotificationPreference struct {
+	UserID    int32        `json:"user_id"`
+	Event     string       `json:"event"`
+	Email     bool         `json:"email"`
+	InApp     bool         `json:"in_app"`
+	UpdatedAt sql.NullTime `json:"updated_at"`
+}
+
+type PasswordHistory struct {
+	ID           int32         `json:"id"`
+	UserID       sql.NullInt32 `json:"user_id"`
+	AdminID      sql.NullInt32 `json:"admin_id"`
+	PasswordHash string        `json:"password_hash"`
+	CreatedAt    sql.NullTime  `json:"created_at"`
+}
+
 type PasswordResetToken struct {
 	ID        int32        `json:"id"`
 	UserID    int32        `json:"user_id"`
@@ -207,14 +349,79 @@ type Permission struct {
 	Description sql.NullString `json:"description"`
 }
 
+type PriceHistory struct {
+	ID          int32     `json:"id"`
+	VariationID int32     `json:"variation_id"`
+	OldPrice    string    `json:"old_price"`
+	NewPrice    string    `json:"new_price"`
+	ChangedBy   int32     `json:"changed_by"`
+	ChangedAt   time.Time `json:"changed_at"`
+}
+
+type PurchaseOrder struct {
+	ID           int32          `json:"id"`
+	StoreID      int32          `json:"store_id"`
+	Supplier     string         `json:"supplier"`
+	Reference    sql.NullString `json:"reference"`
+	Notes        sql.NullString `json:"notes"`
+	Status       string         `json:"status"`
+	CreatedBy    int32          `json:"created_by"`
+	OrderedAt    sql.NullTime   `json:"ordered_at"`
+	ReceivedAt   sql.NullTime   `json:"received_at"`
+	CreatedAt    sql.NullTime   `json:"created_at"`
+	UpdatedAt    sql.NullTime   `json:"updated_at"`
+	CancelledBy  sql.NullInt32  `json:"cancelled_by"`
+	CancelledAt  sql.NullTime   `json:"cancelled_at"`
+	CancelReason sql.NullString `json:"cancel_reason"`
+}
+
+type PurchaseOrderLine struct {
+	ID               int32  `json:"id"`
+	PurchaseOrderID  int32  `json:"purchase_order_id"`
+	VariationID      int32  `json:"variation_id"`
+	Quantity         int32  `json:"quantity"`
+	ReceivedQuantity int32  `json:"received_quantity"`
+	UnitCost         string `json:"unit_cost"`
+}
+
+type PurchaseOrderReceipt struct {
+	ID              int32        `json:"id"`
+	PurchaseOrderID int32        `json:"purchase_order_id"`
+	LineID          int32        `json:"line_id"`
+	StoreID         int32        `json:"store_id"`
+	VariationID     int32        `json:"variation_id"`
+	Quantity        int32        `json:"quantity"`
+	UnitCost        string       `json:"unit_cost"`
+	ReceivedBy      int32        `json:"received_by"`
+	CreatedAt       sql.NullTime `json:"created_at"`
+}
+
 type RefreshToken struct {
-	ID        int32        `json:"id"`
-	UserID    int32        `json:"user_id"`
-	Token     string       `json:"token"`
-	ExpiresAt time.Time    `json:"expires_at"`
-	Revoked   sql.NullBool `json:"revoked"`
-	CreatedAt sql.NullTime `json:"created_at"`
-	UpdatedAt sql.NullTime `json:"updated_at"`
+	ID        int32          `json:"id"`
+	UserID    sql.NullInt32  `json:"user_id"`
+	Token     string         `json:"token"`
+	ExpiresAt time.Time      `json:"expires_at"`
+	Revoked   sql.NullBool   `json:"revoked"`
+	CreatedAt sql.NullTime   `json:"created_at"`
+	UpdatedAt sql.NullTime   `json:"updated_at"`
+	UserAgent sql.NullString `json:"user_agent"`
+	AdminID   sql.NullInt32  `json:"admin_id"`
+}
+
+type Refund struct {
+	ID          int32        `json:"id"`
+	SaleID      int32        `json:"sale_id"`
+	RefundedBy  int32        `json:"refunded_by"`
+	TotalAmount string       `json:"total_amount"`
+	CreatedAt   sql.NullTime `json:"created_at"`
+}
+
+type RefundItem struct {
+	ID         int32  `json:"id"`
+	RefundID   int32  `json:"refund_id"`
+	SaleItemID int32  `json:"sale_item_id"`
+	Quantity   int32  `json:"quantity"`
+	Amount     string `json:"amount"`
 }
 
 type Role struct {
@@ -228,6 +435,78 @@ type RolePermission struct {
 	PermissionID int32 `json:"permission_id"`
 }
 
+type Sale struct {
+	ID             int32        `json:"id"`
+	StoreID        int32        `json:"store_id"`
+	CustomerID     int32        `json:"customer_id"`
+	CashierID      int32        `json:"cashier_id"`
+	Subtotal       string       `json:"subtotal"`
+	DiscountAmount string       `json:"discount_amount"`
+	TaxAmount      string       `json:"tax_amount"`
+	TotalAmount    string       `json:"total_amount"`
+	Status         string       `json:"status"`
+	CreatedAt      sql.NullTime `json:"created_at"`
+	UpdatedAt      sql.NullTime `json:"updated_at"`
+}
+
+type SaleDiscount struct {
+	ID         int32         `json:"id"`
+	SaleID     int32         `json:"sale_id"`
+	DiscountID sql.NullInt32 `json:"discount_id"`
+	Name       string        `json:"name"`
+	Amount     string        `json:"amount"`
+	CouponID   sql.NullInt32 `json:"coupon_id"`
+}
+
+type SaleItem struct {
+	ID               int32          `json:"id"`
+	SaleID           int32          `json:"sale_id"`
+	VariationID      int32          `json:"variation_id"`
+	Quantity         int32          `json:"quantity"`
+	UnitPrice        string         `json:"unit_price"`
+	RefundedQuantity int32          `json:"refunded_quantity"`
+	UnitCost         sql.NullString `json:"unit_cost"`
+}
+
+type SalePayment struct {
+	ID        int32          `json:"id"`
+	SaleID    int32          `json:"sale_id"`
+	Method    PaymentType    `json:"method"`
+	Amount    string         `json:"amount"`
+	Reference sql.NullString `json:"reference"`
+	CreatedAt sql.NullTime   `json:"created_at"`
+}
+
+type StockAdjustment struct {
+	ID               int32        `json:"id"`
+	StoreID          int32        `json:"store_id"`
+	VariationID      int32        `json:"variation_id"`
+	PreviousQuantity int32        `json:"previous_quantity"`
+	Quantity         int32        `json:"quantity"`
+	Reason           string       `json:"reason"`
+	AdjustedBy       int32        `json:"adjusted_by"`
+	CreatedAt        sql.NullTime `json:"created_at"`
+}
+
+type Stocktake struct {
+	ID          int32         `json:"id"`
+	StoreID     int32         `json:"store_id"`
+	Status      string        `json:"status"`
+	OpenedBy    int32         `json:"opened_by"`
+	CommittedBy sql.NullInt32 `json:"committed_by"`
+	CreatedAt   sql.NullTime  `json:"created_at"`
+	CommittedAt sql.NullTime  `json:"committed_at"`
+}
+
+type StocktakeLine struct {
+	ID              int32         `json:"id"`
+	StocktakeID     int32         `json:"stocktake_id"`
+	VariationID     int32         `json:"variation_id"`
+	SystemQuantity  int32         `json:"system_quantity"`
+	CountedQuantity sql.NullInt32 `json:"counted_quantity"`
+	AdjustmentID    sql.NullInt32 `json:"adjustment_id"`
+}
+
 type Store struct {
 	ID           int32          `json:"id"`
 	Name         string         `json:"name"`
@@ -252,6 +531,21 @@ type StorePrice struct {
 	Price       string `json:"price"`
 }
 
+type StoreTransfer struct {
+	ID            int32        `json:"id"`
+	FromStoreID   int32        `json:"from_store_id"`
+	ToStoreID     int32        `json:"to_store_id"`
+	TransferredBy int32        `json:"transferred_by"`
+	CreatedAt     sql.NullTime `json:"created_at"`
+}
+
+type StoreTransferItem struct {
+	ID          int32 `json:"id"`
+	TransferID  int32 `json:"transfer_id"`
+	VariationID int32 `json:"variation_id"`
+	Quantity    int32 `json:"quantity"`
+}
+
 type Unit struct {
 	ID        int32          `json:"id"`
 	Name      string         `json:"name"`
@@ -261,17 +555,35 @@ type Unit struct {
 }
 
 type User struct {
-	ID           int32          `json:"id"`
-	Username     string         `json:"username"`
-	FirstName    string         `json:"first_name"`
-	LastName     string         `json:"last_name"`
-	Email        sql.NullString `json:"email"`
-	PasswordHash string         `json:"password_hash"`
-	Gender       sql.NullString `json:"gender"`
-	RoleID       sql.NullInt32  `json:"role_id"`
-	IsActive     sql.NullBool   `json:"is_active"`
-	CreatedAt    sql.NullTime   `json:"created_at"`
-	UpdatedAt    sql.NullTime   `json:"updated_at"`
+	ID                    int32          `json:"id"`
+	Username              string         `json:"username"`
+	FirstName             string         `json:"first_name"`
+	LastName              string         `json:"last_name"`
+	Email                 sql.NullString `json:"email"`
+	PasswordHash          string         `json:"password_hash"`
+	Gender                sql.NullString `json:"gender"`
+	RoleID                sql.NullInt32  `json:"role_id"`
+	IsActive              sql.NullBool   `json:"is_active"`
+	CreatedAt             sql.NullTime   `json:"created_at"`
+	UpdatedAt             sql.NullTime   `json:"updated_at"`
+	DeletedAt             sql.NullTime   `json:"deleted_at"`
+	BranchID              sql.NullInt32  `json:"branch_id"`
+	StoreID               sql.NullInt32  `json:"store_id"`
+	EmailVerified         bool           `json:"email_verified"`
+	VerificationCode      sql.NullString `json:"verification_code"`
+	VerificationExpiresAt sql.NullTime   `json:"verification_expires_at"`
+}
+
+type UserInvite struct {
+	UserID    int32     `json:"user_id"`
+	Token     string    `json:"token"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+type UserResetCode struct {
+	UserID    int32     `json:"user_id"`
+	Code      string    `json:"code"`
+	ExpiresAt time.Time `json:"expires_at"`
 }
 
 type Variation struct {
@@ -289,4 +601,28 @@ type Variation struct {
 	IsActive     sql.NullBool   `json:"is_active"`
 	CreatedAt    sql.NullTime   `json:"created_at"`
 	UpdatedAt    sql.NullTime   `json:"updated_at"`
+	CostPrice    sql.NullString `json:"cost_price"`
+}
+
+type WebhookDelivery struct {
+	ID             int32          `json:"id"`
+	SubscriptionID int32          `json:"subscription_id"`
+	EventID        string         `json:"event_id"`
+	Event          string         `json:"event"`
+	Attempt        int32          `json:"attempt"`
+	StatusCode     sql.NullInt32  `json:"status_code"`
+	Error          sql.NullString `json:"error"`
+	Delivered      bool           `json:"delivered"`
+	CreatedAt      sql.NullTime   `json:"created_at"`
+}
+
+type WebhookSubscription struct {
+	ID         int32        `json:"id"`
+	Url        string       `json:"url"`
+	Events     []string     `json:"events"`
+	Secret     string       `json:"secret"`
+	Active     bool         `json:"active"`
+	CreatedAt  sql.NullTime `json:"created_at"`
+	UpdatedAt  sql.NullTime `json:"updated_at"`
+	BusinessID int32        `json:"business_id"`
 }
@@ -10,6 +10,28 @@ import (
 	"database/sql"
 )
 
+const countStoreStock = `-- name: CountStoreStock :one
+SELECT COUNT(*) FROM inventory WHERE store_id = $1 AND quantity <> 0
+`
+
+func (q *Queries) CountStoreStock(ctx context.Context, storeID int32) (int64, error) {
+	row := q.db.QueryRowContext(ctx, countStoreStock, storeID)
+	var count int64
+	err := row.Scan(&count)
+	return count, err
+}
+
+const countSubStoresByBranch = `-- name: CountSubStoresByBranch :one
+SELECT COUNT(*) FROM store WHERE branch_id = $1 AND store_type = 'sub-store'
+`
+
+func (q *Queries) CountSubStoresByBranch(ctx context.Context, branchID int32) (int64, error) {
+	row := q.db.QueryRowContext(ctx, countSubStoresByBranch, branchID)
+	var count int64
+	err := row.Scan(&count)
+	return count, err
+}
+
 const createStore = `-- name: CreateStore :one
 INSERT INTO store (
     name, description, branch_id, address, phone, email, 
@@ -68,6 +90,55 @@ func (q *Queries) CreateStore(ctx context.Context, arg CreateStoreParams) (Store
 	return i, err
 }
 
+const createStoreTransfer = `-- name: CreateStoreTransfer :one
+INSERT INTO store_transfer (from_store_id, to_store_id, transferred_by)
+VALUES ($1, $2, $3)
+RETURNING id, from_store_id, to_store_id, transferred_by, created_at
+`
+
+type CreateStoreTransferParams struct {
+	FromStoreID   int32 `json:"from_store_id"`
+	ToStoreID     int32 `json:"to_store_id"`
+	TransferredBy int32 `json:"transferred_by"`
+}
+
+func (q *Queries) CreateStoreTransfer(ctx context.Context, arg CreateStoreTransferParams) (StoreTransfer, error) {
+	row := q.db.QueryRowContext(ctx, createStoreTransfer, arg.FromStoreID, arg.ToStoreID, arg.TransferredBy)
+	var i StoreTransfer
+	err := row.Scan(
+		&i.ID,
+		&i.FromStoreID,
+		&i.ToStoreID,
+		&i.TransferredBy,
+		&i.CreatedAt,
+	)
+	return i, err
+}
+
+const createStoreTransferItem = `-- name: CreateStoreTransferItem :one
+INSERT INTO store_transfer_item (transfer_id, variation_id, quantity)
+VALUES ($1, $2, $3)
+RETURNING id, transfer_id, variation_id, quantity
+`
+
+type CreateStoreTransferItemParams struct {
+	TransferID  int32 `json:"transfer_id"`
+	VariationID int32 `json:"variation_id"`
+	Quantity    int32 `json:"quantity"`
+}
+
+func (q *Queries) CreateStoreTransferItem(ctx context.Context, arg CreateStoreTransferItemParams) (StoreTransferItem, error) {
+	row := q.db.QueryRowContext(ctx, createStoreTransferItem, arg.TransferID, arg.VariationID, arg.Quantity)
+	var i StoreTransferItem
+	err := row.Scan(
+		&i.ID,
+		&i.TransferID,
+		&i.VariationID,
+		&i.Quantity,
+	)
+	return i, err
+}
+
 const deactivateStore = `-- name: DeactivateStore :one
 UPDATE store
 SET is_active = FALSE,
@@ -133,6 +204,21 @@ func (q *Queries) GetCentralStoreByBranch(ctx context.Context, branchID int32) (
 	return i, err
 }
 
+const getStoreAllowOverselling = `-- name: GetStoreAllowOverselling :one
+SELECT COALESCE(b.allow_overselling, FALSE)::boolean AS allow_overselling
+FROM store s
+JOIN branch br ON br.id = s.branch_id
+JOIN business b ON b.id = br.business_id
+WHERE s.id = $1
+`
+
+func (q *Queries) GetStoreAllowOverselling(ctx context.Context, id int32) (bool, error) {
+	row := q.db.QueryRowContext(ctx, getStoreAllowOverselling, id)
+	var allow_overselling bool
+	err := row.Scan(&allow_overselling)
+	return allow_overselling, err
+}
+
 const getStoreByID = `-- name: GetStoreByID :one
 SELECT id, name, description, branch_id, address, phone, email, is_active, store_type, store_code, created_at, updated_at, assigned_user, manager_id FROM store WHERE id = $1 LIMIT 1
 `
@@ -159,6 +245,41 @@ func (q *Queries) GetStoreByID(ctx context.Context, id int32) (Store, error) {
 	return i, err
 }
 
+const getStoreForOwner = `-- name: GetStoreForOwner :one
+SELECT s.id, s.name, s.description, s.branch_id, s.address, s.phone, s.email, s.is_active, s.store_type, s.store_code, s.created_at, s.updated_at, s.assigned_user, s.manager_id
+FROM store s
+JOIN branch br ON br.id = s.branch_id
+JOIN business b ON b.id = br.business_id
+WHERE s.id = $1 AND b.owner_id = $2
+`
+
+type GetStoreForOwnerParams struct {
+	ID      int32 `json:"id"`
+	OwnerID int32 `json:"owner_id"`
+}
+
+func (q *Queries) GetStoreForOwner(ctx context.Context, arg GetStoreForOwnerParams) (Store, error) {
+	row := q.db.QueryRowContext(ctx, getStoreForOwner, arg.ID, arg.OwnerID)
+	var i Store
+	err := row.Scan(
+		&i.ID,
+		&i.Name,
+		&i.Description,
+		&i.BranchID,
+		&i.Address,
+		&i.Phone,
+		&i.Email,
+		&i.IsActive,
+		&i.StoreType,
+		&i.StoreCode,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.AssignedUser,
+		&i.ManagerID,
+	)
+	return i, err
+}
+
 const getStoresByBranch = `-- name: GetStoresByBranch :many
 SELECT id, name, description, branch_id, address, phone, email, is_active, store_type, store_code, created_at, updated_at, assigned_user, manager_id FROM store WHERE branch_id = $1 ORDER BY name
 `
@@ -289,48 +410,45 @@ func (q *Queries) SearchStoresByName(ctx context.Context, dollar_1 sql.NullStrin
 
 const updateStore = `-- name: UpdateStore :one
 UPDATE store
-SET name = $2,
-    description = $3,
-    address = $4,
-    phone = $5,
-    email = $6,
-    is_active = $7,
-    store_code = $8,
-    store_type = $9,
-    assigned_user = $10,
-    manager_id = $11,
+SET name = COALESCE($1, name),
+    description = COALESCE($2, description),
+    address = COALESCE($3, address),
+    phone = COALESCE($4, phone),
+    email = COALESCE($5, email),
+    is_active = COALESCE($6, is_active),
+    store_type = COALESCE($7, store_type),
+    assigned_user = COALESCE($8, assigned_user),
+    manager_id = COALESCE($9, manager_id),
     updated_at = NOW()
-WHERE id = $1
+WHERE id = $10
 RETURNING id, name, description, branch_id, address, phone, email, is_active, store_type, store_code, created_at, updated_at, assigned_user, manager_id
 `
 
 type UpdateStoreParams struct {
-	ID           int32          `json:"id"`
-	Name         string         `json:"name"`
+	Name         sql.NullString `json:"name"`
 	Description  sql.NullString `json:"description"`
-	Address      string         `json:"address"`
-	Phone        string         `json:"phone"`
-	Email        string         `json:"email"`
+	Address      sql.NullString `json:"address"`
+	Phone        sql.NullString `json:"phone"`
+	Email        sql.NullString `json:"email"`
 	IsActive     sql.NullBool   `json:"is_active"`
-	StoreCode    string         `json:"store_code"`
-	StoreType    string         `json:"store_type"`
+	StoreType    sql.NullString `json:"store_type"`
 	AssignedUser sql.NullInt32  `json:"assigned_user"`
 	ManagerID    sql.NullInt32  `json:"manager_id"`
+	ID           int32          `json:"id"`
 }
 
 func (q *Queries) UpdateStore(ctx context.Context, arg UpdateStoreParams) (Store, error) {
 	row := q.db.QueryRowContext(ctx, updateStore,
-		arg.ID,
 		arg.Name,
 		arg.Description,
 		arg.Address,
 		arg.Phone,
 		arg.Email,
 		arg.IsActive,
-		arg.StoreCode,
 		arg.StoreType,
 		arg.AssignedUser,
 		arg.ManagerID,
+		arg.ID,
 	)
 	var i Store
 	err := row.Scan(
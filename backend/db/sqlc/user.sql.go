@@ -9,8 +9,46 @@ import (
 	"context"
 	"database/sql"
 	"time"
+
+	"github.com/lib/pq"
 )
 
+const addPasswordHistory = `-- name: AddPasswordHistory :exec
+INSERT INTO password_history (user_id, admin_id, password_hash)
+VALUES ($1, $2, $3)
+`
+
+type AddPasswordHistoryParams struct {
+	UserID       sql.NullInt32 `json:"user_id"`
+	AdminID      sql.NullInt32 `json:"admin_id"`
+	PasswordHash string        `json:"password_hash"`
+}
+
+func (q *Queries) AddPasswordHistory(ctx context.Context, arg AddPasswordHistoryParams) error {
+	_, err := q.db.ExecContext(ctx, addPasswordHistory, arg.UserID, arg.AdminID, arg.PasswordHash)
+	return err
+}
+
+const addPermissionsToRole = `-- name: AddPermissionsToRole :execrows
+INSERT INTO role_permissions (role_id, permission_id)
+SELECT $1, unnest($2::int[])
+ON CONFLICT DO NOTHING
+`
+
+type AddPermissionsToRoleParams struct {
+	RoleID        int32   `json:"role_id"`
+	PermissionIds []int32 `json:"permission_ids"`
+}
+
+// permissions the role already holds are skipped
+func (q *Queries) AddPermissionsToRole(ctx context.Context, arg AddPermissionsToRoleParams) (int64, error) {
+	result, err := q.db.ExecContext(ctx, addPermissionsToRole, arg.RoleID, pq.Array(arg.PermissionIds))
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
 const addPermissionToRole = `-- name: AddPermissionToRole :exec
 INSERT INTO role_permissions (role_id, permission_id)
 VALUES ($1, $2)
@@ -49,6 +87,58 @@ func (q *Queries) ClearAdminResetCode(ctx context.Context, id int32) error {
 	return err
 }
 
+const clearUserResetCode = `-- name: ClearUserResetCode :exec
+DELETE FROM user_reset_code WHERE user_id = $1
+`
+
+func (q *Queries) ClearUserResetCode(ctx context.Context, userID int32) error {
+	_, err := q.db.ExecContext(ctx, clearUserResetCode, userID)
+	return err
+}
+
+const countActivityLogs = `-- name: CountActivityLogs :one
+SELECT COUNT(*) FROM activity_logs
+WHERE ($1::int IS NULL OR user_id = $1)
+  AND ($2::text IS NULL OR action = $2)
+  AND ($3::text IS NULL OR entity_type = $3)
+  AND ($4::timestamp IS NULL OR created_at >= $4)
+  AND ($5::timestamp IS NULL OR created_at < $5)
+`
+
+type CountActivityLogsParams struct {
+	UserID     sql.NullInt32  `json:"user_id"`
+	Action     sql.NullString `json:"action"`
+	EntityType sql.NullString `json:"entity_type"`
+	StartTime  sql.NullTime   `json:"start_time"`
+	EndTime    sql.NullTime   `json:"end_time"`
+}
+
+func (q *Queries) CountActivityLogs(ctx context.Context, arg CountActivityLogsParams) (int64, error) {
+	row := q.db.QueryRowContext(ctx, countActivityLogs,
+		arg.UserID,
+		arg.Action,
+		arg.EntityType,
+		arg.StartTime,
+		arg.EndTime,
+	)
+	var count int64
+	err := row.Scan(&count)
+	return count, err
+}
+
+const countUsers = `-- name: CountUsers :one
+SELECT COUNT(*) FROM users u
+JOIN roles r ON u.role_id = r.id
+WHERE $1::bool OR u.deleted_at IS NULL
+`
+
+func (q *Queries) CountUsers(ctx context.Context, includeDeleted bool) (int64, error) {
+	row := q.db.QueryRowContext(ctx, countUsers, includeDeleted)
+	var count int64
+	err := row.Scan(&count)
+	return count, err
+}
+
 const createAdmin = `-- name: CreateAdmin :one
 INSERT INTO admins (username, email, first_name, last_name, password_hash, role_id, is_active)
 VALUES ($1, $2, $3, $4, $5, $6, $7)
@@ -122,19 +212,27 @@ func (q *Queries) CreatePasswordResetToken(ctx context.Context, arg CreatePasswo
 }
 
 const createRefreshToken = `-- name: CreateRefreshToken :one
-INSERT INTO refresh_tokens (user_id, token, expires_at)
-VALUES ($1, $2, $3)
-RETURNING id, user_id, token, expires_at, revoked, created_at, updated_at
+INSERT INTO refresh_tokens (user_id, admin_id, token, expires_at, user_agent)
+VALUES ($1, $2, $3, $4, $5)
+RETURNING id, user_id, token, expires_at, revoked, created_at, updated_at, user_agent, admin_id
 `
 
 type CreateRefreshTokenParams struct {
-	UserID    int32     `json:"user_id"`
-	Token     string    `json:"token"`
-	ExpiresAt time.Time `json:"expires_at"`
+	UserID    sql.NullInt32  `json:"user_id"`
+	AdminID   sql.NullInt32  `json:"admin_id"`
+	Token     string         `json:"token"`
+	ExpiresAt time.Time      `json:"expires_at"`
+	UserAgent sql.NullString `json:"user_agent"`
 }
 
 func (q *Queries) CreateRefreshToken(ctx context.Context, arg CreateRefreshTokenParams) (RefreshToken, error) {
-	row := q.db.QueryRowContext(ctx, createRefreshToken, arg.UserID, arg.Token, arg.ExpiresAt)
+	row := q.db.QueryRowContext(ctx, createRefreshToken,
+		arg.UserID,
+		arg.AdminID,
+		arg.Token,
+		arg.ExpiresAt,
+		arg.UserAgent,
+	)
 	var i RefreshToken
 	err := row.Scan(
 		&i.ID,
@@ -144,6 +242,8 @@ func (q *Queries) CreateRefreshToken(ctx context.Context, arg CreateRefreshToken
 		&i.Revoked,
 		&i.CreatedAt,
 		&i.UpdatedAt,
+		&i.UserAgent,
+		&i.AdminID,
 	)
 	return i, err
 }
@@ -167,9 +267,9 @@ func (q *Queries) CreateRole(ctx context.Context, arg CreateRoleParams) (Role, e
 }
 
 const createUser = `-- name: CreateUser :one
-INSERT INTO users (username, first_name, last_name, email, password_hash, gender, role_id, is_active)
-VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
-RETURNING id, username, first_name, last_name, email, password_hash, gender, role_id, is_active, created_at, updated_at
+INSERT INTO users (username, first_name, last_name, email, password_hash, gender, role_id, is_active, branch_id, store_id)
+VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)
+RETURNING id, username, first_name, last_name, email, password_hash, gender, role_id, is_active, created_at, updated_at, deleted_at, branch_id, store_id, email_verified, verification_code, verification_expires_at
 `
 
 type CreateUserParams struct {
@@ -181,6 +281,8 @@ type CreateUserParams struct {
 	Gender       sql.NullString `json:"gender"`
 	RoleID       sql.NullInt32  `json:"role_id"`
 	IsActive     sql.NullBool   `json:"is_active"`
+	BranchID     sql.NullInt32  `json:"branch_id"`
+	StoreID      sql.NullInt32  `json:"store_id"`
 }
 
 func (q *Queries) CreateUser(ctx context.Context, arg CreateUserParams) (User, error) {
@@ -193,6 +295,8 @@ func (q *Queries) CreateUser(ctx context.Context, arg CreateUserParams) (User, e
 		arg.Gender,
 		arg.RoleID,
 		arg.IsActive,
+		arg.BranchID,
+		arg.StoreID,
 	)
 	var i User
 	err := row.Scan(
@@ -207,10 +311,32 @@ func (q *Queries) CreateUser(ctx context.Context, arg CreateUserParams) (User, e
 		&i.IsActive,
 		&i.CreatedAt,
 		&i.UpdatedAt,
+		&i.DeletedAt,
+		&i.BranchID,
+		&i.StoreID,
+		&i.EmailVerified,
+		&i.VerificationCode,
+		&i.VerificationExpiresAt,
 	)
 	return i, err
 }
 
+const createUserInvite = `-- name: CreateUserInvite :exec
+INSERT INTO user_invite (user_id, token, expires_at)
+VALUES ($1, $2, $3)
+`
+
+type CreateUserInviteParams struct {
+	UserID    int32     `json:"user_id"`
+	Token     string    `json:"token"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+func (q *Queries) CreateUserInvite(ctx context.Context, arg CreateUserInviteParams) error {
+	_, err := q.db.ExecContext(ctx, createUserInvite, arg.UserID, arg.Token, arg.ExpiresAt)
+	return err
+}
+
 const deleteAdmin = `-- name: DeleteAdmin :exec
 DELETE FROM users WHERE id = $1
 `
@@ -229,13 +355,115 @@ func (q *Queries) DeleteRole(ctx context.Context, id int32) error {
 	return err
 }
 
-const deleteUser = `-- name: DeleteUser :exec
-DELETE FROM users WHERE id = $1
+const deleteUser = `-- name: DeleteUser :execrows
+UPDATE users
+SET deleted_at = CURRENT_TIMESTAMP, is_active = FALSE, updated_at = CURRENT_TIMESTAMP
+WHERE id = $1 AND deleted_at IS NULL
 `
 
-func (q *Queries) DeleteUser(ctx context.Context, id int32) error {
-	_, err := q.db.ExecContext(ctx, deleteUser, id)
-	return err
+// soft delete, a deleted user is deactivated too so it can't log in
+func (q *Queries) DeleteUser(ctx context.Context, id int32) (int64, error) {
+	result, err := q.db.ExecContext(ctx, deleteUser, id)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const deleteUserInvite = `-- name: DeleteUserInvite :execrows
+DELETE FROM user_invite WHERE user_id = $1
+`
+
+func (q *Queries) DeleteUserInvite(ctx context.Context, userID int32) (int64, error) {
+	result, err := q.db.ExecContext(ctx, deleteUserInvite, userID)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const exportLoginHistory = `-- name: ExportLoginHistory :many
+SELECT DISTINCT ON (lh.id)
+    lh.id,
+    COALESCE(u.username, a.username, lh.username_or_email)::text AS username,
+    COALESCE(u.email, a.email, '')::text AS email,
+    lh.ip_address,
+    lh.user_agent,
+    lh.success,
+    lh.error_reason,
+    lh.login_time
+FROM login_history lh
+LEFT JOIN users u ON u.username = lh.username_or_email OR u.email = lh.username_or_email
+LEFT JOIN admins a ON a.username = lh.username_or_email OR a.email = lh.username_or_email
+WHERE lh.id > $1
+  AND lh.login_time >= $2
+  AND lh.login_time < $3
+  AND ($4::boolean IS NULL OR lh.success = $4)
+ORDER BY lh.id,
+    u.email = lh.username_or_email DESC NULLS LAST,
+    a.email = lh.username_or_email DESC NULLS LAST
+LIMIT $5
+`
+
+type ExportLoginHistoryParams struct {
+	AfterID   int32        `json:"after_id"`
+	StartTime time.Time    `json:"start_time"`
+	EndTime   time.Time    `json:"end_time"`
+	Success   sql.NullBool `json:"success"`
+	BatchSize int32        `json:"batch_size"`
+}
+
+type ExportLoginHistoryRow struct {
+	ID          int32          `json:"id"`
+	Username    string         `json:"username"`
+	Email       string         `json:"email"`
+	IpAddress   sql.NullString `json:"ip_address"`
+	UserAgent   sql.NullString `json:"user_agent"`
+	Success     bool           `json:"success"`
+	ErrorReason sql.NullString `json:"error_reason"`
+	LoginTime   sql.NullTime   `json:"login_time"`
+}
+
+// keyset paginated by id so large ranges can be streamed batch by batch. An
+// attempt can match a user's username and another user's email, it is listed
+// once with the account Login tries first: users before admins, email before
+// username.
+func (q *Queries) ExportLoginHistory(ctx context.Context, arg ExportLoginHistoryParams) ([]ExportLoginHistoryRow, error) {
+	rows, err := q.db.QueryContext(ctx, exportLoginHistory,
+		arg.AfterID,
+		arg.StartTime,
+		arg.EndTime,
+		arg.Success,
+		arg.BatchSize,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []ExportLoginHistoryRow{}
+	for rows.Next() {
+		var i ExportLoginHistoryRow
+		if err := rows.Scan(
+			&i.ID,
+			&i.Username,
+			&i.Email,
+			&i.IpAddress,
+			&i.UserAgent,
+			&i.Success,
+			&i.ErrorReason,
+			&i.LoginTime,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
 }
 
 const getActivityLogs = `-- name: GetActivityLogs :many
@@ -334,6 +562,63 @@ func (q *Queries) GetAdminByEmail(ctx context.Context, email string) (GetAdminBy
 	return i, err
 }
 
+const getAdminByID = `-- name: GetAdminByID :one
+SELECT
+    a.id,
+    a.username,
+    a.first_name,
+    a.last_name,
+    a.email,
+    a.password_hash,
+    a.is_active,
+    a.email_verified,
+    a.verification_code,
+    a.verification_expires_at,
+    a.reset_code,
+    a.reset_code_expires_at,
+    r.name as role_name
+FROM admins a
+JOIN roles r ON a.role_id = r.id
+WHERE a.id = $1 LIMIT 1
+`
+
+type GetAdminByIDRow struct {
+	ID                    int32          `json:"id"`
+	Username              string         `json:"username"`
+	FirstName             string         `json:"first_name"`
+	LastName              string         `json:"last_name"`
+	Email                 string         `json:"email"`
+	PasswordHash          string         `json:"password_hash"`
+	IsActive              bool           `json:"is_active"`
+	EmailVerified         bool           `json:"email_verified"`
+	VerificationCode      sql.NullString `json:"verification_code"`
+	VerificationExpiresAt sql.NullTime   `json:"verification_expires_at"`
+	ResetCode             sql.NullString `json:"reset_code"`
+	ResetCodeExpiresAt    sql.NullTime   `json:"reset_code_expires_at"`
+	RoleName              string         `json:"role_name"`
+}
+
+func (q *Queries) GetAdminByID(ctx context.Context, id int32) (GetAdminByIDRow, error) {
+	row := q.db.QueryRowContext(ctx, getAdminByID, id)
+	var i GetAdminByIDRow
+	err := row.Scan(
+		&i.ID,
+		&i.Username,
+		&i.FirstName,
+		&i.LastName,
+		&i.Email,
+		&i.PasswordHash,
+		&i.IsActive,
+		&i.EmailVerified,
+		&i.VerificationCode,
+		&i.VerificationExpiresAt,
+		&i.ResetCode,
+		&i.ResetCodeExpiresAt,
+		&i.RoleName,
+	)
+	return i, err
+}
+
 const getAdminByUsername = `-- name: GetAdminByUsername :one
 SELECT
     a.id,
@@ -479,8 +764,59 @@ func (q *Queries) GetPasswordResetToken(ctx context.Context, token string) (Pass
 	return i, err
 }
 
+const getPermissionsMatrix = `-- name: GetPermissionsMatrix :many
+SELECT
+    r.id AS role_id,
+    r.name AS role_name,
+    p.id AS permission_id,
+    p.code AS permission_code,
+    (rp.role_id IS NOT NULL)::boolean AS granted
+FROM roles r
+CROSS JOIN permissions p
+LEFT JOIN role_permissions rp ON rp.role_id = r.id AND rp.permission_id = p.id
+WHERE ($1::text IS NULL OR split_part(p.code, ':', 1) = $1)
+ORDER BY r.id, p.code
+`
+
+type GetPermissionsMatrixRow struct {
+	RoleID         int32  `json:"role_id"`
+	RoleName       string `json:"role_name"`
+	PermissionID   int32  `json:"permission_id"`
+	PermissionCode string `json:"permission_code"`
+	Granted        bool   `json:"granted"`
+}
+
+func (q *Queries) GetPermissionsMatrix(ctx context.Context, permissionGroup sql.NullString) ([]GetPermissionsMatrixRow, error) {
+	rows, err := q.db.QueryContext(ctx, getPermissionsMatrix, permissionGroup)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []GetPermissionsMatrixRow{}
+	for rows.Next() {
+		var i GetPermissionsMatrixRow
+		if err := rows.Scan(
+			&i.RoleID,
+			&i.RoleName,
+			&i.PermissionID,
+			&i.PermissionCode,
+			&i.Granted,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
 const getRefreshToken = `-- name: GetRefreshToken :one
-SELECT id, user_id, token, expires_at, revoked, created_at, updated_at FROM refresh_tokens
+SELECT id, user_id, token, expires_at, revoked, created_at, updated_at, user_agent, admin_id FROM refresh_tokens
 WHERE token = $1 AND expires_at > NOW() AND revoked = FALSE
 LIMIT 1
 `
@@ -496,6 +832,8 @@ func (q *Queries) GetRefreshToken(ctx context.Context, token string) (RefreshTok
 		&i.Revoked,
 		&i.CreatedAt,
 		&i.UpdatedAt,
+		&i.UserAgent,
+		&i.AdminID,
 	)
 	return i, err
 }
@@ -511,6 +849,21 @@ func (q *Queries) GetRoleByID(ctx context.Context, id int32) (Role, error) {
 	return i, err
 }
 
+const getRoleByName = `-- name: GetRoleByName :one
+SELECT id, name, description FROM roles WHERE name = $1 LIMIT 1
+`
+
+func (q *Queries) GetRoleByName(ctx context.Context, name string) (Role, error) {
+	row := q.db.QueryRowContext(ctx, getRoleByName, name)
+	var i Role
+	err := row.Scan(
+		&i.ID,
+		&i.Name,
+		&i.Description,
+	)
+	return i, err
+}
+
 const getRolePermissions = `-- name: GetRolePermissions :many
 SELECT p.id, p.code, p.description FROM permissions p
 JOIN role_permissions rp ON p.id = rp.permission_id
@@ -540,6 +893,17 @@ func (q *Queries) GetRolePermissions(ctx context.Context, roleID int32) ([]Permi
 	return items, nil
 }
 
+const getStoreBranchID = `-- name: GetStoreBranchID :one
+SELECT branch_id FROM store WHERE id = $1
+`
+
+func (q *Queries) GetStoreBranchID(ctx context.Context, id int32) (int32, error) {
+	row := q.db.QueryRowContext(ctx, getStoreBranchID, id)
+	var branch_id int32
+	err := row.Scan(&branch_id)
+	return branch_id, err
+}
+
 const getUserByEmail = `-- name: GetUserByEmail :one
 SELECT
     u.id,
@@ -550,6 +914,9 @@ SELECT
     u.password_hash,
     u.gender,
     u.is_active,
+    u.email_verified,
+    u.verification_code,
+    u.verification_expires_at,
     r.name as role_name
 FROM users u
 JOIN roles r ON u.role_id = r.id
@@ -557,15 +924,18 @@ WHERE u.email = $1 LIMIT 1
 `
 
 type GetUserByEmailRow struct {
-	ID           int32          `json:"id"`
-	Username     string         `json:"username"`
-	FirstName    string         `json:"first_name"`
-	LastName     string         `json:"last_name"`
-	Email        sql.NullString `json:"email"`
-	PasswordHash string         `json:"password_hash"`
-	Gender       sql.NullString `json:"gender"`
-	IsActive     sql.NullBool   `json:"is_active"`
-	RoleName     string         `json:"role_name"`
+	ID                    int32          `json:"id"`
+	Username              string         `json:"username"`
+	FirstName             string         `json:"first_name"`
+	LastName              string         `json:"last_name"`
+	Email                 sql.NullString `json:"email"`
+	PasswordHash          string         `json:"password_hash"`
+	Gender                sql.NullString `json:"gender"`
+	IsActive              sql.NullBool   `json:"is_active"`
+	EmailVerified         bool           `json:"email_verified"`
+	VerificationCode      sql.NullString `json:"verification_code"`
+	VerificationExpiresAt sql.NullTime   `json:"verification_expires_at"`
+	RoleName              string         `json:"role_name"`
 }
 
 func (q *Queries) GetUserByEmail(ctx context.Context, email sql.NullString) (GetUserByEmailRow, error) {
@@ -580,30 +950,39 @@ func (q *Queries) GetUserByEmail(ctx context.Context, email sql.NullString) (Get
 		&i.PasswordHash,
 		&i.Gender,
 		&i.IsActive,
+		&i.EmailVerified,
+		&i.VerificationCode,
+		&i.VerificationExpiresAt,
 		&i.RoleName,
 	)
 	return i, err
 }
 
 const getUserByID = `-- name: GetUserByID :one
-SELECT u.id, u.username, u.first_name, u.last_name, u.email, u.password_hash, u.gender, u.role_id, u.is_active, u.created_at, u.updated_at, r.name as role_name FROM users u
+SELECT u.id, u.username, u.first_name, u.last_name, u.email, u.password_hash, u.gender, u.role_id, u.is_active, u.created_at, u.updated_at, u.deleted_at, u.branch_id, u.store_id, u.email_verified, u.verification_code, u.verification_expires_at, r.name as role_name FROM users u
 JOIN roles r ON u.role_id = r.id
-WHERE u.id = $1 LIMIT 1
+WHERE u.id = $1 AND u.deleted_at IS NULL LIMIT 1
 `
 
 type GetUserByIDRow struct {
-	ID           int32          `json:"id"`
-	Username     string         `json:"username"`
-	FirstName    string         `json:"first_name"`
-	LastName     string         `json:"last_name"`
-	Email        sql.NullString `json:"email"`
-	PasswordHash string         `json:"password_hash"`
-	Gender       sql.NullString `json:"gender"`
-	RoleID       sql.NullInt32  `json:"role_id"`
-	IsActive     sql.NullBool   `json:"is_active"`
-	CreatedAt    sql.NullTime   `json:"created_at"`
-	UpdatedAt    sql.NullTime   `json:"updated_at"`
-	RoleName     string         `json:"role_name"`
+	ID                    int32          `json:"id"`
+	Username              string         `json:"username"`
+	FirstName             string         `json:"first_name"`
+	LastName              string         `json:"last_name"`
+	Email                 sql.NullString `json:"email"`
+	PasswordHash          string         `json:"password_hash"`
+	Gender                sql.NullString `json:"gender"`
+	RoleID                sql.NullInt32  `json:"role_id"`
+	IsActive              sql.NullBool   `json:"is_active"`
+	CreatedAt             sql.NullTime   `json:"created_at"`
+	UpdatedAt             sql.NullTime   `json:"updated_at"`
+	DeletedAt             sql.NullTime   `json:"deleted_at"`
+	BranchID              sql.NullInt32  `json:"branch_id"`
+	StoreID               sql.NullInt32  `json:"store_id"`
+	EmailVerified         bool           `json:"email_verified"`
+	VerificationCode      sql.NullString `json:"verification_code"`
+	VerificationExpiresAt sql.NullTime   `json:"verification_expires_at"`
+	RoleName              string         `json:"role_name"`
 }
 
 func (q *Queries) GetUserByID(ctx context.Context, id int32) (GetUserByIDRow, error) {
@@ -621,6 +1000,12 @@ func (q *Queries) GetUserByID(ctx context.Context, id int32) (GetUserByIDRow, er
 		&i.IsActive,
 		&i.CreatedAt,
 		&i.UpdatedAt,
+		&i.DeletedAt,
+		&i.BranchID,
+		&i.StoreID,
+		&i.EmailVerified,
+		&i.VerificationCode,
+		&i.VerificationExpiresAt,
 		&i.RoleName,
 	)
 	return i, err
@@ -636,6 +1021,7 @@ SELECT
     u.password_hash,
     u.gender,
     u.is_active,
+    u.email_verified,
     r.name as role_name
 FROM users u
 JOIN roles r ON u.role_id = r.id
@@ -643,15 +1029,16 @@ WHERE u.username = $1 LIMIT 1
 `
 
 type GetUserByUsernameRow struct {
-	ID           int32          `json:"id"`
-	Username     string         `json:"username"`
-	FirstName    string         `json:"first_name"`
-	LastName     string         `json:"last_name"`
-	Email        sql.NullString `json:"email"`
-	PasswordHash string         `json:"password_hash"`
-	Gender       sql.NullString `json:"gender"`
-	IsActive     sql.NullBool   `json:"is_active"`
-	RoleName     string         `json:"role_name"`
+	ID            int32          `json:"id"`
+	Username      string         `json:"username"`
+	FirstName     string         `json:"first_name"`
+	LastName      string         `json:"last_name"`
+	Email         sql.NullString `json:"email"`
+	PasswordHash  string         `json:"password_hash"`
+	Gender        sql.NullString `json:"gender"`
+	IsActive      sql.NullBool   `json:"is_active"`
+	EmailVerified bool           `json:"email_verified"`
+	RoleName      string         `json:"role_name"`
 }
 
 func (q *Queries) GetUserByUsername(ctx context.Context, username string) (GetUserByUsernameRow, error) {
@@ -666,21 +1053,39 @@ func (q *Queries) GetUserByUsername(ctx context.Context, username string) (GetUs
 		&i.PasswordHash,
 		&i.Gender,
 		&i.IsActive,
+		&i.EmailVerified,
 		&i.RoleName,
 	)
 	return i, err
 }
 
-const getUserPermissions = `-- name: GetUserPermissions :many
-SELECT p.code
-FROM permissions p
-JOIN role_permissions rp ON p.id = rp.permission_id
-JOIN roles r ON rp.role_id = r.id
-JOIN users u ON u.role_id = r.id
-WHERE u.id = $1
+const getUserInviteByToken = `-- name: GetUserInviteByToken :one
+SELECT i.user_id, i.token, i.expires_at FROM user_invite i
+JOIN users u ON u.id = i.user_id
+WHERE i.token = $1 AND u.deleted_at IS NULL
 `
 
-func (q *Queries) GetUserPermissions(ctx context.Context, id int32) ([]string, error) {
+func (q *Queries) GetUserInviteByToken(ctx context.Context, token string) (UserInvite, error) {
+	row := q.db.QueryRowContext(ctx, getUserInviteByToken, token)
+	var i UserInvite
+	err := row.Scan(
+		&i.UserID,
+		&i.Token,
+		&i.ExpiresAt,
+	)
+	return i, err
+}
+
+const getUserPermissions = `-- name: GetUserPermissions :many
+SELECT p.code
+FROM permissions p
+JOIN role_permissions rp ON p.id = rp.permission_id
+JOIN roles r ON rp.role_id = r.id
+JOIN users u ON u.role_id = r.id
+WHERE u.id = $1
+`
+
+func (q *Queries) GetUserPermissions(ctx context.Context, id int32) ([]string, error) {
 	rows, err := q.db.QueryContext(ctx, getUserPermissions, id)
 	if err != nil {
 		return nil, err
@@ -703,6 +1108,255 @@ func (q *Queries) GetUserPermissions(ctx context.Context, id int32) ([]string, e
 	return items, nil
 }
 
+const getUserResetCode = `-- name: GetUserResetCode :one
+SELECT user_id, code, expires_at FROM user_reset_code
+WHERE user_id = $1
+`
+
+func (q *Queries) GetUserResetCode(ctx context.Context, userID int32) (UserResetCode, error) {
+	row := q.db.QueryRowContext(ctx, getUserResetCode, userID)
+	var i UserResetCode
+	err := row.Scan(
+		&i.UserID,
+		&i.Code,
+		&i.ExpiresAt,
+	)
+	return i, err
+}
+
+const getUserStoreScope = `-- name: GetUserStoreScope :one
+SELECT u.username, u.branch_id, u.store_id, s.store_type, br.business_id
+FROM users u
+LEFT JOIN store s ON s.id = u.store_id
+LEFT JOIN branch br ON br.id = COALESCE(u.branch_id, s.branch_id)
+WHERE u.id = $1 AND u.deleted_at IS NULL
+`
+
+type GetUserStoreScopeRow struct {
+	Username   string         `json:"username"`
+	BranchID   sql.NullInt32  `json:"branch_id"`
+	StoreID    sql.NullInt32  `json:"store_id"`
+	StoreType  sql.NullString `json:"store_type"`
+	BusinessID sql.NullInt32  `json:"business_id"`
+}
+
+func (q *Queries) GetUserStoreScope(ctx context.Context, id int32) (GetUserStoreScopeRow, error) {
+	row := q.db.QueryRowContext(ctx, getUserStoreScope, id)
+	var i GetUserStoreScopeRow
+	err := row.Scan(
+		&i.Username,
+		&i.BranchID,
+		&i.StoreID,
+		&i.StoreType,
+		&i.BusinessID,
+	)
+	return i, err
+}
+
+const grantAllPermissionsToRole = `-- name: GrantAllPermissionsToRole :execrows
+INSERT INTO role_permissions (role_id, permission_id)
+SELECT r.id, p.id FROM roles r CROSS JOIN permissions p
+WHERE r.name = $1
+ON CONFLICT DO NOTHING
+`
+
+func (q *Queries) GrantAllPermissionsToRole(ctx context.Context, name string) (int64, error) {
+	result, err := q.db.ExecContext(ctx, grantAllPermissionsToRole, name)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const listActivityLogs = `-- name: ListActivityLogs :many
+SELECT id, user_id, action, details, entity_id, entity_type, ip_address, user_agent, created_at FROM activity_logs
+WHERE ($1::int IS NULL OR user_id = $1)
+  AND ($2::text IS NULL OR action = $2)
+  AND ($3::text IS NULL OR entity_type = $3)
+  AND ($4::timestamp IS NULL OR created_at >= $4)
+  AND ($5::timestamp IS NULL OR created_at < $5)
+ORDER BY created_at DESC, id DESC
+LIMIT $6 OFFSET $7
+`
+
+type ListActivityLogsParams struct {
+	UserID     sql.NullInt32  `json:"user_id"`
+	Action     sql.NullString `json:"action"`
+	EntityType sql.NullString `json:"entity_type"`
+	StartTime  sql.NullTime   `json:"start_time"`
+	EndTime    sql.NullTime   `json:"end_time"`
+	PageLimit  int32          `json:"page_limit"`
+	PageOffset int32          `json:"page_offset"`
+}
+
+func (q *Queries) ListActivityLogs(ctx context.Context, arg ListActivityLogsParams) ([]ActivityLog, error) {
+	rows, err := q.db.QueryContext(ctx, listActivityLogs,
+		arg.UserID,
+		arg.Action,
+		arg.EntityType,
+		arg.StartTime,
+		arg.EndTime,
+		arg.PageLimit,
+		arg.PageOffset,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []ActivityLog{}
+	for rows.Next() {
+		var i ActivityLog
+		if err := rows.Scan(
+			&i.ID,
+			&i.UserID,
+			&i.Action,
+			&i.Details,
+			&i.EntityID,
+			&i.EntityType,
+			&i.IpAddress,
+			&i.UserAgent,
+			&i.CreatedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listBranchStoreIDs = `-- name: ListBranchStoreIDs :many
+SELECT id FROM store WHERE branch_id = $1 ORDER BY id
+`
+
+func (q *Queries) ListBranchStoreIDs(ctx context.Context, branchID int32) ([]int32, error) {
+	rows, err := q.db.QueryContext(ctx, listBranchStoreIDs, branchID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []int32{}
+	for rows.Next() {
+		var id int32
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		items = append(items, id)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listExistingPermissionIDs = `-- name: ListExistingPermissionIDs :many
+SELECT id FROM permissions
+WHERE id = ANY($1::int[])
+`
+
+func (q *Queries) ListExistingPermissionIDs(ctx context.Context, ids []int32) ([]int32, error) {
+	rows, err := q.db.QueryContext(ctx, listExistingPermissionIDs, pq.Array(ids))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []int32{}
+	for rows.Next() {
+		var id int32
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		items = append(items, id)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listNotificationPreferences = `-- name: ListNotificationPreferences :many
+SELECT user_id, event, email, in_app, updated_at FROM notification_preference
+WHERE user_id = $1
+ORDER BY event
+`
+
+func (q *Queries) ListNotificationPreferences(ctx context.Context, userID int32) ([]NotificationPreference, error) {
+	rows, err := q.db.QueryContext(ctx, listNotificationPreferences, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []NotificationPreference{}
+	for rows.Next() {
+		var i NotificationPreference
+		if err := rows.Scan(
+			&i.UserID,
+			&i.Event,
+			&i.Email,
+			&i.InApp,
+			&i.UpdatedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listRecentPasswordHashes = `-- name: ListRecentPasswordHashes :many
+SELECT password_hash FROM password_history
+WHERE user_id IS NOT DISTINCT FROM $1
+  AND admin_id IS NOT DISTINCT FROM $2
+ORDER BY created_at DESC, id DESC
+LIMIT $3
+`
+
+type ListRecentPasswordHashesParams struct {
+	UserID  sql.NullInt32 `json:"user_id"`
+	AdminID sql.NullInt32 `json:"admin_id"`
+	Keep    int32         `json:"keep"`
+}
+
+func (q *Queries) ListRecentPasswordHashes(ctx context.Context, arg ListRecentPasswordHashesParams) ([]string, error) {
+	rows, err := q.db.QueryContext(ctx, listRecentPasswordHashes, arg.UserID, arg.AdminID, arg.Keep)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []string{}
+	for rows.Next() {
+		var password_hash string
+		if err := rows.Scan(&password_hash); err != nil {
+			return nil, err
+		}
+		items = append(items, password_hash)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
 const listRoles = `-- name: ListRoles :many
 SELECT id, name, description FROM roles ORDER BY name
 `
@@ -730,29 +1384,116 @@ func (q *Queries) ListRoles(ctx context.Context) ([]Role, error) {
 	return items, nil
 }
 
+const listUserIDsByRole = `-- name: ListUserIDsByRole :many
+SELECT id FROM users WHERE role_id = $1
+`
+
+func (q *Queries) ListUserIDsByRole(ctx context.Context, roleID sql.NullInt32) ([]int32, error) {
+	rows, err := q.db.QueryContext(ctx, listUserIDsByRole, roleID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []int32{}
+	for rows.Next() {
+		var id int32
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		items = append(items, id)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listUserRefreshTokens = `-- name: ListUserRefreshTokens :many
+SELECT id, user_id, token, expires_at, revoked, created_at, updated_at, user_agent, admin_id FROM refresh_tokens
+WHERE user_id IS NOT DISTINCT FROM $1
+  AND admin_id IS NOT DISTINCT FROM $2
+  AND expires_at > NOW() AND revoked = FALSE
+ORDER BY created_at DESC
+`
+
+type ListUserRefreshTokensParams struct {
+	UserID  sql.NullInt32 `json:"user_id"`
+	AdminID sql.NullInt32 `json:"admin_id"`
+}
+
+func (q *Queries) ListUserRefreshTokens(ctx context.Context, arg ListUserRefreshTokensParams) ([]RefreshToken, error) {
+	rows, err := q.db.QueryContext(ctx, listUserRefreshTokens, arg.UserID, arg.AdminID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []RefreshToken{}
+	for rows.Next() {
+		var i RefreshToken
+		if err := rows.Scan(
+			&i.ID,
+			&i.UserID,
+			&i.Token,
+			&i.ExpiresAt,
+			&i.Revoked,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.UserAgent,
+			&i.AdminID,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
 const listUsers = `-- name: ListUsers :many
-SELECT u.id, u.username, u.first_name, u.last_name, u.email, u.password_hash, u.gender, u.role_id, u.is_active, u.created_at, u.updated_at, r.name as role_name FROM users u
+SELECT u.id, u.username, u.first_name, u.last_name, u.email, u.password_hash, u.gender, u.role_id, u.is_active, u.created_at, u.updated_at, u.deleted_at, u.branch_id, u.store_id, u.email_verified, u.verification_code, u.verification_expires_at, r.name as role_name FROM users u
 JOIN roles r ON u.role_id = r.id
-ORDER BY u.created_at DESC
+WHERE $1::bool OR u.deleted_at IS NULL
+ORDER BY u.created_at DESC, u.id DESC
+LIMIT $2 OFFSET $3
 `
 
+type ListUsersParams struct {
+	IncludeDeleted bool  `json:"include_deleted"`
+	PageLimit      int32 `json:"page_limit"`
+	PageOffset     int32 `json:"page_offset"`
+}
+
 type ListUsersRow struct {
-	ID           int32          `json:"id"`
-	Username     string         `json:"username"`
-	FirstName    string         `json:"first_name"`
-	LastName     string         `json:"last_name"`
-	Email        sql.NullString `json:"email"`
-	PasswordHash string         `json:"password_hash"`
-	Gender       sql.NullString `json:"gender"`
-	RoleID       sql.NullInt32  `json:"role_id"`
-	IsActive     sql.NullBool   `json:"is_active"`
-	CreatedAt    sql.NullTime   `json:"created_at"`
-	UpdatedAt    sql.NullTime   `json:"updated_at"`
-	RoleName     string         `json:"role_name"`
+	ID                    int32          `json:"id"`
+	Username              string         `json:"username"`
+	FirstName             string         `json:"first_name"`
+	LastName              string         `json:"last_name"`
+	Email                 sql.NullString `json:"email"`
+	PasswordHash          string         `json:"password_hash"`
+	Gender                sql.NullString `json:"gender"`
+	RoleID                sql.NullInt32  `json:"role_id"`
+	IsActive              sql.NullBool   `json:"is_active"`
+	CreatedAt             sql.NullTime   `json:"created_at"`
+	UpdatedAt             sql.NullTime   `json:"updated_at"`
+	DeletedAt             sql.NullTime   `json:"deleted_at"`
+	BranchID              sql.NullInt32  `json:"branch_id"`
+	StoreID               sql.NullInt32  `json:"store_id"`
+	EmailVerified         bool           `json:"email_verified"`
+	VerificationCode      sql.NullString `json:"verification_code"`
+	VerificationExpiresAt sql.NullTime   `json:"verification_expires_at"`
+	RoleName              string         `json:"role_name"`
 }
 
-func (q *Queries) ListUsers(ctx context.Context) ([]ListUsersRow, error) {
-	rows, err := q.db.QueryContext(ctx, listUsers)
+func (q *Queries) ListUsers(ctx context.Context, arg ListUsersParams) ([]ListUsersRow, error) {
+	rows, err := q.db.QueryContext(ctx, listUsers, arg.IncludeDeleted, arg.PageLimit, arg.PageOffset)
 	if err != nil {
 		return nil, err
 	}
@@ -772,6 +1513,12 @@ func (q *Queries) ListUsers(ctx context.Context) ([]ListUsersRow, error) {
 			&i.IsActive,
 			&i.CreatedAt,
 			&i.UpdatedAt,
+			&i.DeletedAt,
+			&i.BranchID,
+			&i.StoreID,
+			&i.EmailVerified,
+			&i.VerificationCode,
+			&i.VerificationExpiresAt,
 			&i.RoleName,
 		); err != nil {
 			return nil, err
@@ -883,6 +1630,44 @@ func (q *Queries) MarkTokenAsUsed(ctx context.Context, id int32) error {
 	return err
 }
 
+const markUserEmailVerified = `-- name: MarkUserEmailVerified :exec
+UPDATE users
+SET email_verified = TRUE,
+    verification_code = NULL,
+    verification_expires_at = NULL,
+    updated_at = NOW()
+WHERE id = $1
+`
+
+func (q *Queries) MarkUserEmailVerified(ctx context.Context, id int32) error {
+	_, err := q.db.ExecContext(ctx, markUserEmailVerified, id)
+	return err
+}
+
+const prunePasswordHistory = `-- name: PrunePasswordHistory :exec
+DELETE FROM password_history
+WHERE user_id IS NOT DISTINCT FROM $1
+  AND admin_id IS NOT DISTINCT FROM $2
+  AND id NOT IN (
+    SELECT id FROM password_history
+    WHERE user_id IS NOT DISTINCT FROM $1
+      AND admin_id IS NOT DISTINCT FROM $2
+    ORDER BY created_at DESC, id DESC
+    LIMIT $3
+  )
+`
+
+type PrunePasswordHistoryParams struct {
+	UserID  sql.NullInt32 `json:"user_id"`
+	AdminID sql.NullInt32 `json:"admin_id"`
+	Keep    int32         `json:"keep"`
+}
+
+func (q *Queries) PrunePasswordHistory(ctx context.Context, arg PrunePasswordHistoryParams) error {
+	_, err := q.db.ExecContext(ctx, prunePasswordHistory, arg.UserID, arg.AdminID, arg.Keep)
+	return err
+}
+
 const removePermissionFromRole = `-- name: RemovePermissionFromRole :exec
 DELETE FROM role_permissions
 WHERE role_id = $1 AND permission_id = $2
@@ -898,17 +1683,89 @@ func (q *Queries) RemovePermissionFromRole(ctx context.Context, arg RemovePermis
 	return err
 }
 
+const removeRolePermissionsExcept = `-- name: RemoveRolePermissionsExcept :execrows
+DELETE FROM role_permissions
+WHERE role_id = $1 AND NOT (permission_id = ANY($2::int[]))
+`
+
+type RemoveRolePermissionsExceptParams struct {
+	RoleID  int32   `json:"role_id"`
+	KeepIds []int32 `json:"keep_ids"`
+}
+
+func (q *Queries) RemoveRolePermissionsExcept(ctx context.Context, arg RemoveRolePermissionsExceptParams) (int64, error) {
+	result, err := q.db.ExecContext(ctx, removeRolePermissionsExcept, arg.RoleID, pq.Array(arg.KeepIds))
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const restoreUser = `-- name: RestoreUser :one
+UPDATE users
+SET deleted_at = NULL, is_active = TRUE, updated_at = CURRENT_TIMESTAMP
+WHERE id = $1 AND deleted_at IS NOT NULL
+RETURNING id, username, first_name, last_name, email, password_hash, gender, role_id, is_active, created_at, updated_at, deleted_at, branch_id, store_id, email_verified, verification_code, verification_expires_at
+`
+
+func (q *Queries) RestoreUser(ctx context.Context, id int32) (User, error) {
+	row := q.db.QueryRowContext(ctx, restoreUser, id)
+	var i User
+	err := row.Scan(
+		&i.ID,
+		&i.Username,
+		&i.FirstName,
+		&i.LastName,
+		&i.Email,
+		&i.PasswordHash,
+		&i.Gender,
+		&i.RoleID,
+		&i.IsActive,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.DeletedAt,
+		&i.BranchID,
+		&i.StoreID,
+		&i.EmailVerified,
+		&i.VerificationCode,
+		&i.VerificationExpiresAt,
+	)
+	return i, err
+}
+
 const revokeAllUserRefreshTokens = `-- name: RevokeAllUserRefreshTokens :exec
 UPDATE refresh_tokens
 SET revoked = TRUE, updated_at = CURRENT_TIMESTAMP
 WHERE user_id = $1
 `
 
-func (q *Queries) RevokeAllUserRefreshTokens(ctx context.Context, userID int32) error {
+func (q *Queries) RevokeAllUserRefreshTokens(ctx context.Context, userID sql.NullInt32) error {
 	_, err := q.db.ExecContext(ctx, revokeAllUserRefreshTokens, userID)
 	return err
 }
 
+const revokeOtherUserRefreshTokens = `-- name: RevokeOtherUserRefreshTokens :execrows
+UPDATE refresh_tokens
+SET revoked = TRUE, updated_at = CURRENT_TIMESTAMP
+WHERE user_id IS NOT DISTINCT FROM $1
+  AND admin_id IS NOT DISTINCT FROM $2
+  AND id <> $3 AND revoked = FALSE
+`
+
+type RevokeOtherUserRefreshTokensParams struct {
+	UserID  sql.NullInt32 `json:"user_id"`
+	AdminID sql.NullInt32 `json:"admin_id"`
+	KeepID  int32         `json:"keep_id"`
+}
+
+func (q *Queries) RevokeOtherUserRefreshTokens(ctx context.Context, arg RevokeOtherUserRefreshTokensParams) (int64, error) {
+	result, err := q.db.ExecContext(ctx, revokeOtherUserRefreshTokens, arg.UserID, arg.AdminID, arg.KeepID)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
 const revokeRefreshToken = `-- name: RevokeRefreshToken :exec
 UPDATE refresh_tokens
 SET revoked = TRUE, updated_at = CURRENT_TIMESTAMP
@@ -920,9 +1777,164 @@ func (q *Queries) RevokeRefreshToken(ctx context.Context, token string) error {
 	return err
 }
 
+const revokeRefreshTokenByID = `-- name: RevokeRefreshTokenByID :execrows
+UPDATE refresh_tokens
+SET revoked = TRUE, updated_at = CURRENT_TIMESTAMP
+WHERE id = $1
+  AND user_id IS NOT DISTINCT FROM $2
+  AND admin_id IS NOT DISTINCT FROM $3
+  AND revoked = FALSE
+`
+
+type RevokeRefreshTokenByIDParams struct {
+	ID      int32         `json:"id"`
+	UserID  sql.NullInt32 `json:"user_id"`
+	AdminID sql.NullInt32 `json:"admin_id"`
+}
+
+func (q *Queries) RevokeRefreshTokenByID(ctx context.Context, arg RevokeRefreshTokenByIDParams) (int64, error) {
+	result, err := q.db.ExecContext(ctx, revokeRefreshTokenByID, arg.ID, arg.UserID, arg.AdminID)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const searchActivityLogs = `-- name: SearchActivityLogs :many
+SELECT al.id, al.user_id, al.action, al.details, al.entity_id, al.entity_type, al.ip_address, al.user_agent, al.created_at FROM activity_logs al
+WHERE to_tsvector('simple', al.details) @@ websearch_to_tsquery('simple', $1)
+  AND ($2::int IS NULL OR al.user_id = $2)
+  AND ($3::text IS NULL OR al.action = $3)
+  AND ($4::text IS NULL OR al.entity_type = $4)
+  AND ($5::timestamp IS NULL OR al.created_at >= $5)
+  AND ($6::timestamp IS NULL OR al.created_at < $6)
+  AND (
+    al.user_id IN (
+        SELECT u.id FROM users u
+        JOIN branch br ON br.id = u.branch_id
+        JOIN business b ON b.id = br.business_id
+        WHERE b.owner_id = $7)
+    -- admins and users are numbered apart, an id that is also a user's may
+    -- be another business's user
+    OR (al.user_id = $7 AND NOT EXISTS (
+        SELECT 1 FROM users u WHERE u.id = al.user_id))
+    OR (al.entity_type = 'Business' AND al.entity_id IN (
+        SELECT b.id FROM business b WHERE b.owner_id = $7))
+    OR (al.entity_type = 'Branch' AND al.entity_id IN (
+        SELECT br.id FROM branch br
+        JOIN business b ON b.id = br.business_id
+        WHERE b.owner_id = $7))
+    OR (al.entity_type = 'Store' AND al.entity_id IN (
+        SELECT s.id FROM store s
+        JOIN branch br ON br.id = s.branch_id
+        JOIN business b ON b.id = br.business_id
+        WHERE b.owner_id = $7))
+    OR (al.entity_type = 'StoreTransfer' AND al.entity_id IN (
+        SELECT st.id FROM store_transfer st
+        JOIN store s ON s.id = st.from_store_id
+        JOIN branch br ON br.id = s.branch_id
+        JOIN business b ON b.id = br.business_id
+        WHERE b.owner_id = $7))
+  )
+ORDER BY al.created_at DESC, al.id DESC
+LIMIT $8 OFFSET $9
+`
+
+type SearchActivityLogsParams struct {
+	Query      string         `json:"query"`
+	UserID     sql.NullInt32  `json:"user_id"`
+	Action     sql.NullString `json:"action"`
+	EntityType sql.NullString `json:"entity_type"`
+	StartTime  sql.NullTime   `json:"start_time"`
+	EndTime    sql.NullTime   `json:"end_time"`
+	OwnerID    int32          `json:"owner_id"`
+	PageLimit  int32          `json:"page_limit"`
+	PageOffset int32          `json:"page_offset"`
+}
+
+// the tsvector expression must match activity_logs_details_search_idx. Only
+// the logs of the owner's businesses are searched: the actions of the owner
+// and of the users of their branches, and the actions on their businesses,
+// branches, stores and stock transfers like ListBusinessActivityLogs
+func (q *Queries) SearchActivityLogs(ctx context.Context, arg SearchActivityLogsParams) ([]ActivityLog, error) {
+	rows, err := q.db.QueryContext(ctx, searchActivityLogs,
+		arg.Query,
+		arg.UserID,
+		arg.Action,
+		arg.EntityType,
+		arg.StartTime,
+		arg.EndTime,
+		arg.OwnerID,
+		arg.PageLimit,
+		arg.PageOffset,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []ActivityLog{}
+	for rows.Next() {
+		var i ActivityLog
+		if err := rows.Scan(
+			&i.ID,
+			&i.UserID,
+			&i.Action,
+			&i.Details,
+			&i.EntityID,
+			&i.EntityType,
+			&i.IpAddress,
+			&i.UserAgent,
+			&i.CreatedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const seedPermission = `-- name: SeedPermission :exec
+INSERT INTO permissions (code, description) VALUES ($1, $2)
+ON CONFLICT (code) DO NOTHING
+`
+
+type SeedPermissionParams struct {
+	Code        string         `json:"code"`
+	Description sql.NullString `json:"description"`
+}
+
+func (q *Queries) SeedPermission(ctx context.Context, arg SeedPermissionParams) error {
+	_, err := q.db.ExecContext(ctx, seedPermission, arg.Code, arg.Description)
+	return err
+}
+
+const seedRole = `-- name: SeedRole :exec
+INSERT INTO roles (name, description) VALUES ($1, $2)
+ON CONFLICT (name) DO NOTHING
+`
+
+type SeedRoleParams struct {
+	Name        string         `json:"name"`
+	Description sql.NullString `json:"description"`
+}
+
+// Creates a role unless one with the name exists, existing roles are left
+// as they are.
+func (q *Queries) SeedRole(ctx context.Context, arg SeedRoleParams) error {
+	_, err := q.db.ExecContext(ctx, seedRole, arg.Name, arg.Description)
+	return err
+}
+
 const setAdminEmailVerification = `-- name: SetAdminEmailVerification :exec
 UPDATE admins
-SET verification_code = $2,
+SET email_verified = FALSE,
+    verification_code = $2,
     verification_expires_at = $3,
     updated_at = NOW()
 WHERE id = $1
@@ -958,6 +1970,45 @@ func (q *Queries) SetAdminResetCode(ctx context.Context, arg SetAdminResetCodePa
 	return err
 }
 
+const setUserEmailVerification = `-- name: SetUserEmailVerification :exec
+UPDATE users
+SET email_verified = FALSE,
+    verification_code = $2,
+    verification_expires_at = $3,
+    updated_at = NOW()
+WHERE id = $1
+`
+
+type SetUserEmailVerificationParams struct {
+	ID                    int32          `json:"id"`
+	VerificationCode      sql.NullString `json:"verification_code"`
+	VerificationExpiresAt sql.NullTime   `json:"verification_expires_at"`
+}
+
+func (q *Queries) SetUserEmailVerification(ctx context.Context, arg SetUserEmailVerificationParams) error {
+	_, err := q.db.ExecContext(ctx, setUserEmailVerification, arg.ID, arg.VerificationCode, arg.VerificationExpiresAt)
+	return err
+}
+
+const setUserResetCode = `-- name: SetUserResetCode :exec
+INSERT INTO user_reset_code (user_id, code, expires_at)
+VALUES ($1, $2, $3)
+ON CONFLICT (user_id) DO UPDATE SET
+    code = EXCLUDED.code,
+    expires_at = EXCLUDED.expires_at
+`
+
+type SetUserResetCodeParams struct {
+	UserID    int32     `json:"user_id"`
+	Code      string    `json:"code"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+func (q *Queries) SetUserResetCode(ctx context.Context, arg SetUserResetCodeParams) error {
+	_, err := q.db.ExecContext(ctx, setUserResetCode, arg.UserID, arg.Code, arg.ExpiresAt)
+	return err
+}
+
 const updateAdminPassword = `-- name: UpdateAdminPassword :exec
 UPDATE admins
 SET password_hash = $2, updated_at = CURRENT_TIMESTAMP
@@ -974,6 +2025,55 @@ func (q *Queries) UpdateAdminPassword(ctx context.Context, arg UpdateAdminPasswo
 	return err
 }
 
+const updateAdminProfile = `-- name: UpdateAdminProfile :one
+UPDATE admins
+SET first_name = COALESCE($1, first_name),
+    last_name  = COALESCE($2, last_name),
+    email      = COALESCE($3, email),
+    email_verified = CASE
+        WHEN $3 IS NULL OR $3 = email THEN email_verified
+        ELSE FALSE
+    END,
+    updated_at = NOW()
+WHERE id = $4
+RETURNING id, first_name, last_name, username, email, password_hash, role_id, is_active, email_verified, verification_code, verification_expires_at, reset_code, reset_code_expires_at, created_at, updated_at
+`
+
+type UpdateAdminProfileParams struct {
+	FirstName sql.NullString `json:"first_name"`
+	LastName  sql.NullString `json:"last_name"`
+	Email     sql.NullString `json:"email"`
+	ID        int32          `json:"id"`
+}
+
+func (q *Queries) UpdateAdminProfile(ctx context.Context, arg UpdateAdminProfileParams) (Admin, error) {
+	row := q.db.QueryRowContext(ctx, updateAdminProfile,
+		arg.FirstName,
+		arg.LastName,
+		arg.Email,
+		arg.ID,
+	)
+	var i Admin
+	err := row.Scan(
+		&i.ID,
+		&i.FirstName,
+		&i.LastName,
+		&i.Username,
+		&i.Email,
+		&i.PasswordHash,
+		&i.RoleID,
+		&i.IsActive,
+		&i.EmailVerified,
+		&i.VerificationCode,
+		&i.VerificationExpiresAt,
+		&i.ResetCode,
+		&i.ResetCodeExpiresAt,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+	)
+	return i, err
+}
+
 const updateRole = `-- name: UpdateRole :one
 UPDATE roles
 SET
@@ -1004,9 +2104,12 @@ SET username   = COALESCE($1, username),
     email      = COALESCE($4, email),
     gender     = COALESCE($5, gender),
     role_id    = COALESCE($6, role_id),
-    is_active  = COALESCE($7, is_active)
-WHERE id = $8
-RETURNING id, username, first_name, last_name, email, password_hash, gender, role_id, is_active, created_at, updated_at
+    is_active  = COALESCE($7, is_active),
+    -- the branch and store are replaced together, with NULL for none
+    branch_id  = CASE WHEN $8::bool THEN $9 ELSE branch_id END,
+    store_id   = CASE WHEN $8::bool THEN $10 ELSE store_id END
+WHERE id = $11 AND deleted_at IS NULL
+RETURNING id, username, first_name, last_name, email, password_hash, gender, role_id, is_active, created_at, updated_at, deleted_at, branch_id, store_id, email_verified, verification_code, verification_expires_at
 `
 
 type UpdateUserParams struct {
@@ -1017,6 +2120,9 @@ type UpdateUserParams struct {
 	Gender    sql.NullString `json:"gender"`
 	RoleID    sql.NullInt32  `json:"role_id"`
 	IsActive  sql.NullBool   `json:"is_active"`
+	SetStore  bool           `json:"set_store"`
+	BranchID  sql.NullInt32  `json:"branch_id"`
+	StoreID   sql.NullInt32  `json:"store_id"`
 	ID        int32          `json:"id"`
 }
 
@@ -1029,6 +2135,9 @@ func (q *Queries) UpdateUser(ctx context.Context, arg UpdateUserParams) (User, e
 		arg.Gender,
 		arg.RoleID,
 		arg.IsActive,
+		arg.SetStore,
+		arg.BranchID,
+		arg.StoreID,
 		arg.ID,
 	)
 	var i User
@@ -1044,6 +2153,12 @@ func (q *Queries) UpdateUser(ctx context.Context, arg UpdateUserParams) (User, e
 		&i.IsActive,
 		&i.CreatedAt,
 		&i.UpdatedAt,
+		&i.DeletedAt,
+		&i.BranchID,
+		&i.StoreID,
+		&i.EmailVerified,
+		&i.VerificationCode,
+		&i.VerificationExpiresAt,
 	)
 	return i, err
 }
@@ -1079,3 +2194,38 @@ func (q *Queries) UpdateUserStatus(ctx context.Context, arg UpdateUserStatusPara
 	_, err := q.db.ExecContext(ctx, updateUserStatus, arg.ID, arg.IsActive)
 	return err
 }
+
+const upsertNotificationPreference = `-- name: UpsertNotificationPreference :one
+INSERT INTO notification_preference (user_id, event, email, in_app)
+VALUES ($1, $2, $3, $4)
+ON CONFLICT (user_id, event) DO UPDATE
+SET email = EXCLUDED.email,
+    in_app = EXCLUDED.in_app,
+    updated_at = NOW()
+RETURNING user_id, event, email, in_app, updated_at
+`
+
+type UpsertNotificationPreferenceParams struct {
+	UserID int32  `json:"user_id"`
+	Event  string `json:"event"`
+	Email  bool   `json:"email"`
+	InApp  bool   `json:"in_app"`
+}
+
+func (q *Queries) UpsertNotificationPreference(ctx context.Context, arg UpsertNotificationPreferenceParams) (NotificationPreference, error) {
+	row := q.db.QueryRowContext(ctx, upsertNotificationPreference,
+		arg.UserID,
+		arg.Event,
+		arg.Email,
+		arg.InApp,
+	)
+	var i NotificationPreference
+	err := row.Scan(
+		&i.UserID,
+		&i.Event,
+		&i.Email,
+		&i.InApp,
+		&i.UpdatedAt,
+	)
+	return i, err
+}
@@ -1,23 +1,33 @@
 package auth
 
 import (
+	"context"
 	"database/sql"
+	"encoding/csv"
 	"errors"
 	"fmt"
 	db "herp/db/sqlc"
+	"herp/internal/config"
 	"herp/internal/utils"
+	"herp/pkg/monitoring/logging"
+	"herp/pkg/password"
 	"net/http"
+	"net/url"
 	"strconv"
+	"time"
 
 	"github.com/gin-gonic/gin"
 )
 
 type AdminHandler struct {
 	service *Service
+	config  *config.Config
+	logger  *logging.Logger
+	emailer utils.Emailer
 }
 
-func NewAdminHandler(s *Service) *AdminHandler {
-	return &AdminHandler{s}
+func NewAdminHandler(s *Service, c *config.Config, l *logging.Logger, emailer utils.Emailer) *AdminHandler {
+	return &AdminHandler{s, c, l, emailer}
 }
 
 func (h *AdminHandler) RegisterAdminRoutes(router *gin.RouterGroup, authSvc *Service) {
@@ -27,11 +37,18 @@ func (h *AdminHandler) RegisterAdminRoutes(router *gin.RouterGroup, authSvc *Ser
 	// User management
 	admin.GET("/users", h.ListUsers)
 	admin.POST("/user", h.CreateUser)
+	admin.POST("/users/bulk", h.BulkCreateUsers)
+	admin.POST("/users/invite", h.InviteUser)
 	admin.GET("/user/:id", h.GetUser)
 	admin.PUT("/user/:id", h.UpdateUser)
 	admin.DELETE("/user/:id", h.DeleteUser)
 	admin.POST("/user/:id/reset-password", h.ResetPassword)
+	admin.GET("/users/:id/activity", h.GetUserActivityLogs)
+	admin.POST("/users/:id/restore", h.RestoreUser)
+	admin.GET("/users/:id/permissions", h.GetUserPermissions)
+	admin.GET("/activity", h.GetActivityLogs)
 	admin.GET("/login-history", h.GetLoginHistory)
+	admin.GET("/login-history/export", PermissionMiddlewareStrict(authSvc, "admin:audit"), h.ExportLoginHistory)
 	admin.POST("/reset-password", h.ResetAdminPassword)
 
 	// Role management
@@ -43,6 +60,9 @@ func (h *AdminHandler) RegisterAdminRoutes(router *gin.RouterGroup, authSvc *Ser
 	admin.POST("/role/:id/permission", h.AddPermissionToRole)
 	admin.DELETE("/role/:id/permission/:permission_id", h.RemovePermissionFromRole)
 	admin.GET("/role/:id/permission", h.GetRolePermissions) 
+	admin.PUT("/roles/:id/permissions", h.SetRolePermissions)
+	admin.POST("/roles/:id/permissions/bulk", h.AddPermissionsToRole)
+	admin.GET("/permissions/matrix", h.GetPermissionsMatrix)
 }
 
 // User Management
@@ -51,10 +71,13 @@ type CreateUserRequest struct {
 	FirstName string `json:"first_name" binding:"required,min=2"`
 	LastName  string `json:"last_name" binding:"required,min=2"`
 	Email     string `json:"email" binding:"required,email"`
-	Password  string `json:"password" binding:"required,min=4"`
+	Password  string `json:"password" binding:"required"`
 	Gender    string `json:"gender" binding:"required,oneof=male female"`
 	RoleID    int    `json:"role_id" binding:"required"`
 	IsActive  bool   `json:"is_active" binding:"required"`
+	// the store the user works at, the branch is taken from it when not given
+	BranchID *int32 `json:"branch_id" binding:"omitempty,gt=0" example:"1"`
+	StoreID  *int32 `json:"store_id" binding:"omitempty,gt=0" example:"1"`
 }
 
 // type ResetPasswordRequest struct {
@@ -78,10 +101,25 @@ func (h *AdminHandler) ResetAdminPassword(c *gin.Context) {}
 func (h *AdminHandler) CreateUser(c *gin.Context) {
 	var req CreateUserRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
-		utils.ErrorResponse(c, http.StatusBadRequest, err.Error())
+		utils.BindingErrorResponse(c, err)
+		return
+	}
+	user, err := h.service.CreateUser(c, req.params())
+	if passwordPolicyError(c, err) || storeAssignmentError(c, err) {
 		return
 	}
-	user, err := h.service.CreateUser(c, db.CreateUserParams{
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	utils.SuccessResponse(c, http.StatusCreated, "user created successfully", user)
+}
+
+// params carries the request over to the user insert, the password is
+// hashed by the service.
+func (req CreateUserRequest) params() db.CreateUserParams {
+	return db.CreateUserParams{
 		Username:     req.Username,
 		FirstName:    req.FirstName,
 		LastName:     req.LastName,
@@ -90,13 +128,161 @@ func (h *AdminHandler) CreateUser(c *gin.Context) {
 		Gender:       sql.NullString{Valid: true, String: req.Gender},
 		RoleID:       sql.NullInt32{Valid: true, Int32: int32(req.RoleID)},
 		IsActive:     sql.NullBool{Valid: true, Bool: req.IsActive},
+		BranchID:     nullInt32(req.BranchID),
+		StoreID:      nullInt32(req.StoreID),
+	}
+}
+
+type InviteUserRequest struct {
+	Username  string `json:"username" binding:"required,min=3" example:"johndoe"`
+	FirstName string `json:"first_name" binding:"required,min=2" example:"John"`
+	LastName  string `json:"last_name" binding:"required,min=2" example:"Doe"`
+	Email     string `json:"email" binding:"required,email" example:"johndoe@email.com"`
+	Gender    string `json:"gender" binding:"required,oneof=male female" example:"male"`
+	RoleID    int    `json:"role_id" binding:"required" example:"2"`
+	// the store the user works at, the branch is taken from it when not given
+	BranchID *int32 `json:"branch_id" binding:"omitempty,gt=0" example:"1"`
+	StoreID  *int32 `json:"store_id" binding:"omitempty,gt=0" example:"1"`
+}
+
+// InviteUser godoc
+// @Summary Invite a user
+// @Description Create an inactive user without a password and email them a link to set one. The account is activated once the password is set, the link expires after 72 hours and works once.
+// @Tags admin
+// @Accept json
+// @Produce json
+// @Param user body InviteUserRequest true "User to invite"
+// @Success 201 {object} map[string]interface{} "User invited"
+// @Failure 400 {object} map[string]string "Bad request or unknown role"
+// @Failure 409 {object} map[string]string "Username or email already taken"
+// @Failure 500 {object} map[string]string "Internal server error"
+// @Security BearerAuth
+// @Router /api/v1/admin/users/invite [post]
+func (h *AdminHandler) InviteUser(c *gin.Context) {
+	var req InviteUserRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.BindingErrorResponse(c, err)
+		return
+	}
+
+	user, token, err := h.service.InviteUser(c.Request.Context(), db.CreateUserParams{
+		Username:  req.Username,
+		FirstName: req.FirstName,
+		LastName:  req.LastName,
+		Email:     sql.NullString{Valid: true, String: req.Email},
+		Gender:    sql.NullString{Valid: true, String: req.Gender},
+		RoleID:    sql.NullInt32{Valid: true, Int32: int32(req.RoleID)},
+		BranchID:  nullInt32(req.BranchID),
+		StoreID:   nullInt32(req.StoreID),
+	})
+	if storeAssignmentError(c, err) {
+		return
+	}
+	if err != nil {
+		switch {
+		case errors.Is(err, ErrUsernameTaken), errors.Is(err, ErrEmailTaken):
+			utils.ErrorResponse(c, http.StatusConflict, err.Error())
+		case errors.Is(err, ErrUnknownRole):
+			utils.ErrorResponse(c, http.StatusBadRequest, err.Error())
+		default:
+			utils.ErrorResponse(c, http.StatusInternalServerError, err.Error())
+		}
+		return
+	}
+
+	link, err := url.Parse(h.config.InviteURL)
+	if err != nil {
+		h.logger.Errorf("invalid invite url %q: %v", h.config.InviteURL, err)
+		utils.ErrorResponse(c, http.StatusInternalServerError, "user created but the invite link could not be made")
+		return
+	}
+	query := link.Query()
+	query.Set("token", token)
+	link.RawQuery = query.Encode()
+
+	emailBody, _ := utils.RenderEmailTemplate("templates/auth/invite.html", map[string]any{
+		"Username": user.Username,
+		"Link":     link.String(),
+		"Hours":    int(inviteTTL.Hours()),
 	})
+	if err := h.emailer.SendEmail(req.Email, "You have been invited to Herp", emailBody); err != nil {
+		h.logger.Errorf("error sending invite email to %s: %v", req.Email, err)
+		utils.ErrorResponse(c, http.StatusInternalServerError, "user created but the invite email could not be sent")
+		return
+	}
+
+	utils.SuccessResponse(c, http.StatusCreated, "user invited successfully", user)
+}
+
+type BulkCreateUsersRequest struct {
+	Users []CreateUserRequest `json:"users" binding:"required,min=1,max=100,dive"`
+	// create the valid users even when some entries fail, by default nothing
+	// is created unless every entry is valid
+	Partial bool `json:"partial" example:"false"`
+}
+
+type BulkCreateUserResult struct {
+	Index   int    `json:"index" example:"0"` // Position of the entry in the request
+	Created bool   `json:"created" example:"true"`
+	ID      int32  `json:"id,omitempty" example:"12"` // ID of the created user
+	Error   string `json:"error,omitempty" example:"username is already taken"`
+	// the password policy requirements the password of the entry misses
+	UnmetRequirements []string `json:"unmet_requirements,omitempty"`
+}
+
+// BulkCreateUsers godoc
+// @Summary Create many users
+// @Description Create up to 100 users at once. Every entry is checked before any user is created, by default nothing is created unless every entry is valid. With partial the valid entries are created and the rest are reported.
+// @Tags admin
+// @Accept json
+// @Produce json
+// @Param users body BulkCreateUsersRequest true "Users to create"
+// @Success 201 {object} []BulkCreateUserResult "Users created"
+// @Failure 400 {object} []BulkCreateUserResult "No users were created"
+// @Failure 422 {object} map[string]string "Validation failed"
+// @Failure 500 {object} map[string]string "Internal server error"
+// @Security BearerAuth
+// @Router /api/v1/admin/users/bulk [post]
+func (h *AdminHandler) BulkCreateUsers(c *gin.Context) {
+	var req BulkCreateUsersRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.BindingErrorResponse(c, err)
+		return
+	}
+
+	params := make([]db.CreateUserParams, 0, len(req.Users))
+	for _, user := range req.Users {
+		params = append(params, user.params())
+	}
+	results, err := h.service.CreateUsers(c.Request.Context(), params, req.Partial)
 	if err != nil {
 		utils.ErrorResponse(c, http.StatusInternalServerError, err.Error())
 		return
 	}
 
-	utils.SuccessResponse(c, http.StatusCreated, "user created successfully", user)
+	created := 0
+	response := make([]BulkCreateUserResult, 0, len(results))
+	for _, result := range results {
+		entry := BulkCreateUserResult{Index: result.Index, Created: result.Created}
+		if result.Created {
+			created++
+			entry.ID = result.User.ID
+		}
+		if result.Err != nil {
+			entry.Error = result.Err.Error()
+			var policyErr *password.PolicyError
+			if errors.As(result.Err, &policyErr) {
+				entry.UnmetRequirements = policyErr.Unmet
+			}
+		}
+		response = append(response, entry)
+	}
+
+	if created == 0 {
+		utils.ErrorResponseWithData(c, http.StatusBadRequest, "no users were created", response)
+		return
+	}
+	utils.SuccessResponse(c, http.StatusCreated, fmt.Sprintf("%d of %d users created", created, len(results)), response)
 }
 
 type UpdateUserRequest struct {
@@ -107,6 +293,9 @@ type UpdateUserRequest struct {
 	Gender    *string `json:"gender" binding:"omitempty,oneof=male female" example:"male"`
 	RoleID    *int    `json:"role_id" binding:"omitempty" example:"2"`
 	IsActive  *bool   `json:"is_active" binding:"omitempty" example:"true"`
+	// giving either replaces the branch and store assignment, 0 clears it
+	BranchID *int32 `json:"branch_id" binding:"omitempty,gte=0" example:"1"`
+	StoreID  *int32 `json:"store_id" binding:"omitempty,gte=0" example:"1"`
 }
 
 // UpdateUser updates an existing user
@@ -131,7 +320,7 @@ func (h *AdminHandler) UpdateUser(c *gin.Context) {
 
 	var req UpdateUserRequest
 	if err = c.ShouldBindJSON(&req); err != nil {
-		utils.ErrorResponse(c, http.StatusBadRequest, err.Error())
+		utils.BindingErrorResponse(c, err)
 		return
 	}
 
@@ -186,7 +375,16 @@ func (h *AdminHandler) UpdateUser(c *gin.Context) {
 		}
 	}
 
+	if req.BranchID != nil || req.StoreID != nil {
+		updateParams.SetStore = true
+		updateParams.BranchID = nullInt32(req.BranchID)
+		updateParams.StoreID = nullInt32(req.StoreID)
+	}
+
 	user, err := h.service.UpdateUser(c.Request.Context(), updateParams)
+	if storeAssignmentError(c, err) {
+		return
+	}
 	if err != nil {
 		utils.ErrorResponse(c, http.StatusInternalServerError, err.Error())
 		return
@@ -197,11 +395,12 @@ func (h *AdminHandler) UpdateUser(c *gin.Context) {
 
 // DeleteUser deletes a user account
 // @Summary Delete user
-// @Description Delete a user account from the system
+// @Description Delete a user account. The account is deactivated and hidden, its logs stay attributable and it can be restored.
 // @Tags admin
 // @Param id path int true "User ID"
 // @Success 204 "User deleted successfully"
 // @Failure 400 {object} map[string]string "Bad request"
+// @Failure 404 {object} map[string]string "User not found"
 // @Failure 500 {object} map[string]string "Internal server error"
 // @Security BearerAuth
 // @Router /api/v1/admin/users/{id} [delete]
@@ -213,6 +412,10 @@ func (h *AdminHandler) DeleteUser(c *gin.Context) {
 	}
 
 	if err := h.service.DeleteUser(c.Request.Context(), int32(userID)); err != nil {
+		if errors.Is(err, ErrUserNotFound) {
+			utils.ErrorResponse(c, http.StatusNotFound, err.Error())
+			return
+		}
 		utils.ErrorResponse(c, http.StatusInternalServerError, err.Error())
 		return
 	}
@@ -220,8 +423,40 @@ func (h *AdminHandler) DeleteUser(c *gin.Context) {
 	utils.SuccessResponse(c, http.StatusOK, "user is deleted", nil)
 }
 
+// RestoreUser restores a deleted user account
+// @Summary Restore user
+// @Description Restore a deleted user account, it is active again
+// @Tags admin
+// @Produce json
+// @Param id path int true "User ID"
+// @Success 200 {object} map[string]interface{} "User restored"
+// @Failure 400 {object} map[string]string "Bad request"
+// @Failure 404 {object} map[string]string "No deleted user with this ID"
+// @Failure 500 {object} map[string]string "Internal server error"
+// @Security BearerAuth
+// @Router /api/v1/admin/users/{id}/restore [post]
+func (h *AdminHandler) RestoreUser(c *gin.Context) {
+	userID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "invalid user id")
+		return
+	}
+
+	user, err := h.service.RestoreUser(c.Request.Context(), int32(userID))
+	if err != nil {
+		if errors.Is(err, ErrUserNotFound) {
+			utils.ErrorResponse(c, http.StatusNotFound, "no deleted user with this id")
+			return
+		}
+		utils.ErrorResponse(c, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	utils.SuccessResponse(c, http.StatusOK, "user is restored", user)
+}
+
 type ResetPasswordRequest struct {
-	NewPassword string `json:"new_password" binding:"required,min=8"`
+	NewPassword string `json:"new_password" binding:"required"`
 }
 
 // ResetPassword resets a user's password
@@ -245,7 +480,7 @@ func (h *AdminHandler) ResetPassword(c *gin.Context) {
 
 	var req ResetPasswordRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
-		utils.ErrorResponse(c, http.StatusBadRequest, err.Error())
+		utils.BindingErrorResponse(c, err)
 		return
 	}
 
@@ -253,7 +488,15 @@ func (h *AdminHandler) ResetPassword(c *gin.Context) {
 		ID:           int32(userID),
 		PasswordHash: req.NewPassword,
 	}
-	if err := h.service.ResetPassword(c.Request.Context(), params); err != nil {
+	err = h.service.ResetPassword(c.Request.Context(), params)
+	if passwordPolicyError(c, err) {
+		return
+	}
+	if errors.Is(err, ErrPasswordReused) {
+		utils.ErrorResponse(c, http.StatusBadRequest, err.Error())
+		return
+	}
+	if err != nil {
 		utils.ErrorResponse(c, http.StatusInternalServerError, err.Error())
 		return
 	}
@@ -263,22 +506,44 @@ func (h *AdminHandler) ResetPassword(c *gin.Context) {
 
 // ListUsers retrieves all users
 // @Summary List all users
-// @Description Get a list of all users in the system
+// @Description Get a list of all users in the system, deleted users are left out unless include_deleted is set
 // @Tags admin
 // @Produce json
-// @Success 200 {array} map[string]interface{} "List of users"
+// @Param include_deleted query bool false "Include deleted users"
+// @Param page query int false "Page number, defaults to 1"
+// @Param limit query int false "Users per page (default 20, max 100)"
+// @Success 200 {object} utils.PaginatedResponse "A page of users"
+// @Failure 400 {object} map[string]string "Bad request"
 // @Failure 500 {object} map[string]string "Internal server error"
 // @Security BearerAuth
 // @Router /api/v1/admin/users [get]
 func (h *AdminHandler) ListUsers(c *gin.Context) {
-	users, err := h.service.queries.ListUsers(c.Request.Context())
+	includeDeleted := false
+	if v := c.Query("include_deleted"); v != "" {
+		b, err := strconv.ParseBool(v)
+		if err != nil {
+			utils.ErrorResponse(c, http.StatusBadRequest, "invalid include_deleted")
+			return
+		}
+		includeDeleted = b
+	}
+
+	pagination, err := utils.ParsePagination(c)
 	if err != nil {
-		utils.ErrorResponse(c, 500, err.Error())
+		utils.ErrorResponse(c, http.StatusBadRequest, err.Error())
 		return
 	}
-	utils.SuccessResponse(c, 200, "", gin.H{
-		"data": users,
+
+	users, total, err := h.service.ListUsers(c.Request.Context(), db.ListUsersParams{
+		IncludeDeleted: includeDeleted,
+		PageLimit:      int32(pagination.Limit),
+		PageOffset:     int32(pagination.Offset),
 	})
+	if err != nil {
+		utils.ErrorResponse(c, 500, err.Error())
+		return
+	}
+	utils.SuccessResponse(c, 200, "", pagination.Response(users, total))
 }
 
 // GetUser retrieves a specific user
@@ -315,6 +580,38 @@ func (h *AdminHandler) GetUser(c *gin.Context) {
 	})
 }
 
+// GetUserPermissions godoc
+// @Summary Get a user's effective permissions
+// @Description Resolve the permissions a user holds through their role, straight from the database
+// @Tags admin
+// @Produce json
+// @Param id path int true "User ID"
+// @Success 200 {object} EffectivePermissions "Effective permissions"
+// @Failure 400 {object} map[string]string "Bad request"
+// @Failure 404 {object} map[string]string "User not found"
+// @Failure 500 {object} map[string]string "Internal server error"
+// @Security BearerAuth
+// @Router /api/v1/admin/users/{id}/permissions [get]
+func (h *AdminHandler) GetUserPermissions(c *gin.Context) {
+	userID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "invalid user id")
+		return
+	}
+
+	effective, err := h.service.UserEffectivePermissions(c.Request.Context(), int32(userID))
+	if err != nil {
+		if errors.Is(err, ErrUserNotFound) {
+			utils.ErrorResponse(c, http.StatusNotFound, "user not found")
+			return
+		}
+		utils.ErrorResponse(c, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	utils.SuccessResponse(c, http.StatusOK, "", effective)
+}
+
 // Role Management
 
 type CreateRoleRequest struct {
@@ -337,7 +634,7 @@ type CreateRoleRequest struct {
 func (h *AdminHandler) CreateRole(c *gin.Context) {
 	var req CreateRoleRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
-		utils.ErrorResponse(c, http.StatusBadRequest, err.Error())
+		utils.BindingErrorResponse(c, err)
 		return
 	}
 
@@ -384,7 +681,7 @@ func (h *AdminHandler) UpdateRole(c *gin.Context) {
 
 	var req UpdateRoleRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
-		utils.ErrorResponse(c, 400, err.Error())
+		utils.BindingErrorResponse(c, err)
 		return
 	}
 
@@ -507,7 +804,7 @@ func (h *AdminHandler) AddPermissionToRole(c *gin.Context) {
 
 	var req ManageRolePermissionRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
-		utils.ErrorResponse(c, http.StatusBadRequest, err.Error())
+		utils.BindingErrorResponse(c, err)
 		return
 	}
 
@@ -524,6 +821,75 @@ func (h *AdminHandler) AddPermissionToRole(c *gin.Context) {
 	utils.SuccessResponse(c, http.StatusNoContent, fmt.Sprintf("permission %d added to role %d", roleID, req.PermissionID), nil)
 }
 
+type RolePermissionsRequest struct {
+	PermissionIDs []int32 `json:"permission_ids" binding:"required" example:"1,2,3"`
+}
+
+// SetRolePermissions godoc
+// @Summary Replace role permissions
+// @Description Make the given permissions the exact permission set of a role, in one transaction. An empty list takes every permission away.
+// @Tags admin
+// @Accept json
+// @Produce json
+// @Param id path int true "Role ID"
+// @Param permissions body RolePermissionsRequest true "Permission IDs"
+// @Success 200 {object} RolePermissionsChange "Permissions added and removed"
+// @Failure 400 {object} map[string]string "Bad request or unknown permission IDs"
+// @Failure 404 {object} map[string]string "Role not found"
+// @Failure 500 {object} map[string]string "Internal server error"
+// @Security BearerAuth
+// @Router /api/v1/admin/roles/{id}/permissions [put]
+func (h *AdminHandler) SetRolePermissions(c *gin.Context) {
+	h.changeRolePermissions(c, h.service.ReplaceRolePermissions)
+}
+
+// AddPermissionsToRole godoc
+// @Summary Add permissions to role
+// @Description Grant many permissions to a role at once, the ones it already holds are skipped
+// @Tags admin
+// @Accept json
+// @Produce json
+// @Param id path int true "Role ID"
+// @Param permissions body RolePermissionsRequest true "Permission IDs"
+// @Success 200 {object} RolePermissionsChange "Permissions added"
+// @Failure 400 {object} map[string]string "Bad request or unknown permission IDs"
+// @Failure 404 {object} map[string]string "Role not found"
+// @Failure 500 {object} map[string]string "Internal server error"
+// @Security BearerAuth
+// @Router /api/v1/admin/roles/{id}/permissions/bulk [post]
+func (h *AdminHandler) AddPermissionsToRole(c *gin.Context) {
+	h.changeRolePermissions(c, h.service.AddPermissionsToRole)
+}
+
+func (h *AdminHandler) changeRolePermissions(c *gin.Context, change func(ctx context.Context, roleID int32, permissionIDs []int32) (RolePermissionsChange, error)) {
+	roleID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "invalid role ID")
+		return
+	}
+
+	var req RolePermissionsRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.BindingErrorResponse(c, err)
+		return
+	}
+
+	result, err := change(c.Request.Context(), int32(roleID), req.PermissionIDs)
+	if err != nil {
+		switch {
+		case errors.Is(err, ErrRoleNotFound):
+			utils.ErrorResponse(c, http.StatusNotFound, err.Error())
+		case errors.Is(err, ErrUnknownPermissions):
+			utils.ErrorResponse(c, http.StatusBadRequest, err.Error())
+		default:
+			utils.ErrorResponse(c, http.StatusInternalServerError, err.Error())
+		}
+		return
+	}
+
+	utils.SuccessResponse(c, http.StatusOK, fmt.Sprintf("%d permissions added, %d removed", result.Added, result.Removed), result)
+}
+
 // RemovePermissionFromRole godoc
 // @Summary Remove permission from role
 // @Description Remove a permission from a specific role
@@ -590,30 +956,188 @@ func (h *AdminHandler) GetRolePermissions(c *gin.Context) {
 	utils.SuccessResponse(c, http.StatusOK, "", permissions)
 }
 
+// GetPermissionsMatrix godoc
+// @Summary Get permissions matrix
+// @Description Retrieve which permissions every role holds, as a roles x permissions grid
+// @Tags admin
+// @Produce json
+// @Param group query string false "Permission group, e.g. inventory"
+// @Success 200 {object} PermissionsMatrix "Permissions matrix"
+// @Failure 500 {object} map[string]string "Internal server error"
+// @Security BearerAuth
+// @Router /api/v1/admin/permissions/matrix [get]
+func (h *AdminHandler) GetPermissionsMatrix(c *gin.Context) {
+	matrix, err := h.service.GetPermissionsMatrix(c.Request.Context(), c.Query("group"))
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, err.Error())
+		return
+	}
 
-// func (h *AdminHandler) GetUserActivityLogs(c *gin.Context) {
-// 	userID, err := strconv.Atoi(c.Param("id"))
-// 	if err != nil {
-// 		utils.ErrorResponse(c, http.StatusBadRequest, "invalid user ID")
-// 		return
-// 	}
+	utils.SuccessResponse(c, http.StatusOK, "", matrix)
+}
 
-// 	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "100"))
-// 	if limit > 1000 {
-// 		limit = 1000
-// 	}
 
-// 	logs, err := h.service.queries.GetActivityLogs(c.Request.Context(), db.GetActivityLogsParams{
-// 		UserID: int32(userID),
-// 		Limit:  int32(limit),
-// 	})
-// 	if err != nil {
-// 		utils.ErrorResponse(c, http.StatusInternalServerError, err.Error())
-// 		return
-// 	}
+type ActivityLogResponse struct {
+	ID         int32     `json:"id"`
+	UserID     int32     `json:"user_id"`
+	Action     string    `json:"action"`
+	Details    string    `json:"details"`
+	EntityID   int32     `json:"entity_id"`
+	EntityType string    `json:"entity_type"`
+	IpAddress  string    `json:"ip_address"`
+	UserAgent  string    `json:"user_agent"`
+	CreatedAt  time.Time `json:"created_at"`
+}
 
-// 	utils.SuccessResponse(c, http.StatusOK, "", logs)
-// }
+type ActivityLogsResponse struct {
+	Logs  []ActivityLogResponse `json:"logs"`
+	Page  int                   `json:"page" example:"1"`
+	Limit int                   `json:"limit" example:"100"`
+	Total int64                 `json:"total" example:"1"`
+}
+
+// GetUserActivityLogs godoc
+// @Summary Get a user's activity logs
+// @Description Page through the actions of one user, newest first
+// @Tags admin
+// @Produce json
+// @Param id path int true "User ID"
+// @Param action query string false "Only this action"
+// @Param entity_type query string false "Only actions on this entity type"
+// @Param start query string false "Start date (YYYY-MM-DD or RFC3339)"
+// @Param end query string false "End date, inclusive (YYYY-MM-DD or RFC3339)"
+// @Param page query int false "Page number, defaults to 1"
+// @Param limit query int false "Entries per page (default 100, max 1000)"
+// @Success 200 {object} ActivityLogsResponse "Activity logs"
+// @Failure 400 {object} map[string]string "Bad request"
+// @Failure 500 {object} map[string]string "Internal server error"
+// @Security BearerAuth
+// @Router /api/v1/admin/users/{id}/activity [get]
+func (h *AdminHandler) GetUserActivityLogs(c *gin.Context) {
+	userID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "invalid user ID")
+		return
+	}
+
+	params, ok := activityLogFilters(c)
+	if !ok {
+		return
+	}
+	params.UserID = sql.NullInt32{Int32: int32(userID), Valid: true}
+
+	h.listActivityLogs(c, params)
+}
+
+// GetActivityLogs godoc
+// @Summary Get activity logs
+// @Description Page through the actions of every user, newest first
+// @Tags admin
+// @Produce json
+// @Param user_id query int false "Only actions of this user"
+// @Param action query string false "Only this action"
+// @Param entity_type query string false "Only actions on this entity type"
+// @Param start query string false "Start date (YYYY-MM-DD or RFC3339)"
+// @Param end query string false "End date, inclusive (YYYY-MM-DD or RFC3339)"
+// @Param page query int false "Page number, defaults to 1"
+// @Param limit query int false "Entries per page (default 100, max 1000)"
+// @Success 200 {object} ActivityLogsResponse "Activity logs"
+// @Failure 400 {object} map[string]string "Bad request"
+// @Failure 500 {object} map[string]string "Internal server error"
+// @Security BearerAuth
+// @Router /api/v1/admin/activity [get]
+func (h *AdminHandler) GetActivityLogs(c *gin.Context) {
+	params, ok := activityLogFilters(c)
+	if !ok {
+		return
+	}
+
+	if v := c.Query("user_id"); v != "" {
+		userID, err := strconv.Atoi(v)
+		if err != nil {
+			utils.ErrorResponse(c, http.StatusBadRequest, "invalid user_id")
+			return
+		}
+		params.UserID = sql.NullInt32{Int32: int32(userID), Valid: true}
+	}
+
+	h.listActivityLogs(c, params)
+}
+
+// activityLogFilters reads the filters and page shared by the activity log
+// endpoints. It responds with 400 and returns false when one is invalid.
+func activityLogFilters(c *gin.Context) (db.ListActivityLogsParams, bool) {
+	page, err := strconv.Atoi(c.DefaultQuery("page", "1"))
+	if err != nil || page < 1 {
+		utils.ErrorResponse(c, http.StatusBadRequest, "invalid page")
+		return db.ListActivityLogsParams{}, false
+	}
+
+	limit, err := strconv.Atoi(c.DefaultQuery("limit", "100"))
+	if err != nil || limit < 1 {
+		utils.ErrorResponse(c, http.StatusBadRequest, "invalid limit")
+		return db.ListActivityLogsParams{}, false
+	}
+	if limit > 1000 {
+		limit = 1000
+	}
+
+	params := db.ListActivityLogsParams{
+		Action:     sql.NullString{String: c.Query("action"), Valid: c.Query("action") != ""},
+		EntityType: sql.NullString{String: c.Query("entity_type"), Valid: c.Query("entity_type") != ""},
+		PageLimit:  int32(limit),
+		PageOffset: int32((page - 1) * limit),
+	}
+
+	if v := c.Query("start"); v != "" {
+		t, err := parseExportTime(v, false)
+		if err != nil {
+			utils.ErrorResponse(c, http.StatusBadRequest, "invalid start date")
+			return db.ListActivityLogsParams{}, false
+		}
+		params.StartTime = sql.NullTime{Time: t, Valid: true}
+	}
+	if v := c.Query("end"); v != "" {
+		t, err := parseExportTime(v, true)
+		if err != nil {
+			utils.ErrorResponse(c, http.StatusBadRequest, "invalid end date")
+			return db.ListActivityLogsParams{}, false
+		}
+		params.EndTime = sql.NullTime{Time: t, Valid: true}
+	}
+
+	return params, true
+}
+
+func (h *AdminHandler) listActivityLogs(c *gin.Context, params db.ListActivityLogsParams) {
+	logs, total, err := h.service.ListActivityLogs(c.Request.Context(), params)
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	response := ActivityLogsResponse{
+		Logs:  make([]ActivityLogResponse, 0, len(logs)),
+		Page:  int(params.PageOffset/params.PageLimit) + 1,
+		Limit: int(params.PageLimit),
+		Total: total,
+	}
+	for _, log := range logs {
+		response.Logs = append(response.Logs, ActivityLogResponse{
+			ID:         log.ID,
+			UserID:     log.UserID,
+			Action:     log.Action,
+			Details:    log.Details,
+			EntityID:   log.EntityID,
+			EntityType: log.EntityType,
+			IpAddress:  log.IpAddress.String,
+			UserAgent:  log.UserAgent.String,
+			CreatedAt:  log.CreatedAt.Time,
+		})
+	}
+
+	utils.SuccessResponse(c, http.StatusOK, "", response)
+}
 
 // GetLoginHistory godoc
 // @Summary Get login history
@@ -640,3 +1164,118 @@ func (h *AdminHandler) GetLoginHistory(c *gin.Context) {
 
 	utils.SuccessResponse(c, http.StatusOK, "", history)
 }
+
+var loginHistoryCSVHeader = []string{"username", "email", "ip_address", "user_agent", "success", "error_reason", "timestamp"}
+
+// parseExportTime parses a YYYY-MM-DD date or an RFC3339 timestamp. A date
+// given as the end of a range includes that whole day.
+func parseExportTime(value string, end bool) (time.Time, error) {
+	if t, err := time.Parse("2006-01-02", value); err == nil {
+		if end {
+			t = t.AddDate(0, 0, 1)
+		}
+		return t, nil
+	}
+	return time.Parse(time.RFC3339, value)
+}
+
+// ExportLoginHistory godoc
+// @Summary Export login history
+// @Description Download login attempts of a date range as CSV. Defaults to the last 30 days.
+// @Tags admin
+// @Produce text/csv
+// @Param start query string false "Start date (YYYY-MM-DD or RFC3339)"
+// @Param end query string false "End date, inclusive (YYYY-MM-DD or RFC3339)"
+// @Param success query bool false "Only export successful or failed attempts"
+// @Success 200 {file} file "CSV file"
+// @Failure 400 {object} map[string]string "Bad request"
+// @Failure 500 {object} map[string]string "Internal server error"
+// @Security BearerAuth
+// @Router /api/v1/admin/login-history/export [get]
+func (h *AdminHandler) ExportLoginHistory(c *gin.Context) {
+	end := time.Now()
+	start := end.AddDate(0, 0, -30)
+
+	if v := c.Query("start"); v != "" {
+		t, err := parseExportTime(v, false)
+		if err != nil {
+			utils.ErrorResponse(c, http.StatusBadRequest, "invalid start date")
+			return
+		}
+		start = t
+	}
+	if v := c.Query("end"); v != "" {
+		t, err := parseExportTime(v, true)
+		if err != nil {
+			utils.ErrorResponse(c, http.StatusBadRequest, "invalid end date")
+			return
+		}
+		end = t
+	}
+	if !start.Before(end) {
+		utils.ErrorResponse(c, http.StatusBadRequest, "start must be before end")
+		return
+	}
+
+	var success sql.NullBool
+	if v := c.Query("success"); v != "" {
+		b, err := strconv.ParseBool(v)
+		if err != nil {
+			utils.ErrorResponse(c, http.StatusBadRequest, "invalid success filter")
+			return
+		}
+		success = sql.NullBool{Bool: b, Valid: true}
+	}
+
+	w := csv.NewWriter(c.Writer)
+	started := false
+	// headers are only sent once the first batch is read, so a failing query
+	// can still be reported as a normal error response
+	startCSV := func() {
+		c.Header("Content-Type", "text/csv")
+		c.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="login-history-%s-%s.csv"`, start.Format("20060102"), end.Format("20060102")))
+		c.Status(http.StatusOK)
+		w.Write(loginHistoryCSVHeader)
+		started = true
+	}
+
+	err := h.service.ExportLoginHistory(c.Request.Context(), start, end, success, func(rows []db.ExportLoginHistoryRow) error {
+		if !started {
+			startCSV()
+		}
+		for _, row := range rows {
+			var timestamp string
+			if row.LoginTime.Valid {
+				timestamp = row.LoginTime.Time.Format(time.RFC3339)
+			}
+			if err := w.Write([]string{
+				utils.CSVSafe(row.Username),
+				utils.CSVSafe(row.Email),
+				utils.CSVSafe(row.IpAddress.String),
+				utils.CSVSafe(row.UserAgent.String),
+				strconv.FormatBool(row.Success),
+				utils.CSVSafe(row.ErrorReason.String),
+				timestamp,
+			}); err != nil {
+				return err
+			}
+		}
+		w.Flush()
+		c.Writer.Flush()
+		return w.Error()
+	})
+	if err != nil {
+		if !started {
+			utils.ErrorResponse(c, http.StatusInternalServerError, utils.SERVERERROR)
+			return
+		}
+		// part of the file has already been sent, all we can do is stop
+		c.Error(err)
+		return
+	}
+
+	if !started {
+		startCSV()
+	}
+	w.Flush()
+}
@@ -0,0 +1,142 @@
+package auth
+
+import (
+	"context"
+	"database/sql"
+	"herp/db/dbtest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// storedAdmin creates an active admin with the password and role.
+func storedAdmin(t *testing.T, conn *sql.DB, username, pass string, role int32) int32 {
+	t.Helper()
+	id := dbtest.Admin(t, conn, username)
+	hash, err := bcrypt.GenerateFromPassword([]byte(pass), bcrypt.MinCost)
+	require.NoError(t, err)
+	dbtest.Exec(t, conn, `UPDATE admins SET password_hash = $1, role_id = $2 WHERE id = $3`, string(hash), role, id)
+	return id
+}
+
+func TestAdminLoginRole(t *testing.T) {
+	s, conn := newStoredService(t)
+	ctx := context.Background()
+	storedAdmin(t, conn, "boss", "Password1", storedRole(t, conn, "owner"))
+	storedAdmin(t, conn, "auditor", "Password1", storedRole(t, conn, "auditor"))
+
+	for _, tt := range []struct{ login, role string }{
+		{"boss", "owner"},
+		{"boss@example.com", "owner"},
+		{"auditor", "auditor"},
+		{"auditor@example.com", "auditor"},
+	} {
+		token, _, err := s.Login(ctx, tt.login, "Password1", "10.0.0.1", "test")
+		require.NoError(t, err, tt.login)
+		claims, err := s.ParseToken(token)
+		require.NoError(t, err)
+		assert.Equal(t, tt.role, claims.Role, "%s logged in with the wrong role", tt.login)
+	}
+}
+
+func TestAdminRefreshToken(t *testing.T) {
+	s, conn := newStoredService(t)
+	ctx := context.Background()
+	id := storedAdmin(t, conn, "boss", "Password1", storedRole(t, conn, "owner"))
+
+	_, refresh, err := s.Login(ctx, "boss", "Password1", "10.0.0.1", "test")
+	require.NoError(t, err)
+	var userID, adminID sql.NullInt32
+	require.NoError(t, conn.QueryRow(`SELECT user_id, admin_id FROM refresh_tokens WHERE token = $1`, refresh).Scan(&userID, &adminID))
+	assert.False(t, userID.Valid)
+	assert.Equal(t, id, adminID.Int32)
+
+	token, rotated, err := s.RefreshToken(ctx, refresh)
+	require.NoError(t, err)
+	assert.NotEqual(t, refresh, rotated)
+	claims, err := s.ParseToken(token)
+	require.NoError(t, err)
+	assert.Equal(t, int(id), claims.UserID)
+	assert.Equal(t, "boss", claims.Username)
+	assert.Equal(t, "owner", claims.Role)
+
+	_, _, err = s.RefreshToken(ctx, refresh)
+	assert.ErrorIs(t, err, ErrInvalidCredentials)
+}
+
+func TestSessionsOfAdminAndUserWithSameID(t *testing.T) {
+	s, conn := newStoredService(t)
+	ctx := context.Background()
+	adminID := storedAdmin(t, conn, "boss", "Password1", storedRole(t, conn, "owner"))
+	userID := storedUser(t, conn, "cashier", "Password1")
+	// user and admin ids come from different sequences and can be the same
+	dbtest.Exec(t, conn, `UPDATE users SET id = $1 WHERE id = $2`, adminID, userID)
+
+	adminToken, _, err := s.Login(ctx, "boss", "Password1", "10.0.0.1", "test")
+	require.NoError(t, err)
+	adminClaims, err := s.ParseToken(adminToken)
+	require.NoError(t, err)
+	userToken, _, err := s.Login(ctx, "cashier", "Password1", "10.0.0.2", "test")
+	require.NoError(t, err)
+	userClaims, err := s.ParseToken(userToken)
+	require.NoError(t, err)
+	require.Equal(t, adminClaims.UserID, userClaims.UserID)
+
+	sessions, err := s.ListSessions(ctx, adminClaims)
+	require.NoError(t, err)
+	require.Len(t, sessions, 1)
+	assert.Equal(t, int32(adminClaims.SessionID), sessions[0].ID)
+
+	err = s.RevokeSession(ctx, adminClaims, int32(userClaims.SessionID))
+	assert.ErrorIs(t, err, ErrSessionNotFound)
+	sessions, err = s.ListSessions(ctx, userClaims)
+	require.NoError(t, err)
+	require.Len(t, sessions, 1)
+	assert.Equal(t, int32(userClaims.SessionID), sessions[0].ID)
+}
+
+func TestUserLoginEmailVerified(t *testing.T) {
+	s, conn := newStoredService(t)
+	s.requireVerifiedEmail = true
+	ctx := context.Background()
+	id := storedUser(t, conn, "cashier", "Password1")
+
+	_, _, err := s.Login(ctx, "cashier", "Password1", "10.0.0.1", "test")
+	require.NoError(t, err, "users created by admins start verified")
+
+	dbtest.Exec(t, conn, `UPDATE users SET email_verified = FALSE WHERE id = $1`, id)
+	for _, login := range []string{"cashier", "cashier@example.com"} {
+		_, _, err = s.Login(ctx, login, "Password1", "10.0.0.1", "test")
+		assert.ErrorIs(t, err, ErrEmailNotVerified, login)
+	}
+}
+
+func TestUserEmailChangeVerification(t *testing.T) {
+	s, conn := newStoredService(t)
+	s.requireVerifiedEmail = true
+	ctx := context.Background()
+	storedUser(t, conn, "cashier", "Password1")
+	token, _, err := s.Login(ctx, "cashier", "Password1", "10.0.0.1", "test")
+	require.NoError(t, err)
+	claims, err := s.ParseToken(token)
+	require.NoError(t, err)
+
+	email := "new@example.com"
+	profile, code, err := s.UpdateProfile(ctx, claims, UpdateProfileParams{Email: &email})
+	require.NoError(t, err)
+	assert.NotEmpty(t, code, "the new address is sent a code")
+	assert.False(t, profile.EmailVerified)
+	_, _, err = s.Login(ctx, "cashier", "Password1", "10.0.0.1", "test")
+	assert.ErrorIs(t, err, ErrEmailNotVerified)
+
+	ok, err := s.VerifyEmailCode(ctx, email, code)
+	require.NoError(t, err)
+	assert.True(t, ok)
+	profile, err = s.Me(ctx, claims)
+	require.NoError(t, err)
+	assert.True(t, profile.EmailVerified)
+	_, _, err = s.Login(ctx, "cashier", "Password1", "10.0.0.1", "test")
+	assert.NoError(t, err)
+}
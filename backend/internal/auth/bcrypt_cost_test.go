@@ -0,0 +1,83 @@
+package auth
+
+import (
+	"context"
+	"database/sql"
+	"herp/db/dbtest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/crypto/bcrypt"
+)
+
+func storedHashCost(t *testing.T, conn *sql.DB, table string, id int32) int {
+	t.Helper()
+	var hash string
+	require.NoError(t, conn.QueryRow(`SELECT password_hash FROM `+table+` WHERE id = $1`, id).Scan(&hash))
+	cost, err := bcrypt.Cost([]byte(hash))
+	require.NoError(t, err)
+	return cost
+}
+
+func TestLoginUpgradesHashCost(t *testing.T) {
+	s, conn := newStoredService(t)
+	s.bcryptCost = bcrypt.MinCost + 1
+	ctx := context.Background()
+
+	// hashed at MinCost before the cost was raised
+	id := storedUser(t, conn, "cashier", "Password1")
+	require.Equal(t, bcrypt.MinCost, storedHashCost(t, conn, "users", id))
+
+	// a failed login leaves it alone
+	_, _, err := s.Login(ctx, "cashier", "Wrong1234", "10.0.0.1", "test")
+	require.Error(t, err)
+	assert.Equal(t, bcrypt.MinCost, storedHashCost(t, conn, "users", id))
+
+	_, _, err = s.Login(ctx, "cashier", "Password1", "10.0.0.1", "test")
+	require.NoError(t, err)
+	assert.Equal(t, bcrypt.MinCost+1, storedHashCost(t, conn, "users", id))
+
+	// the upgraded hash still takes the password
+	_, _, err = s.Login(ctx, "cashier", "Password1", "10.0.0.1", "test")
+	require.NoError(t, err)
+}
+
+func TestLoginUpgradesAdminHashCost(t *testing.T) {
+	s, conn := newStoredService(t)
+	s.bcryptCost = bcrypt.MinCost + 1
+	ctx := context.Background()
+
+	id := dbtest.Admin(t, conn, "boss")
+	hash, err := bcrypt.GenerateFromPassword([]byte("Password1"), bcrypt.MinCost)
+	require.NoError(t, err)
+	dbtest.Exec(t, conn, `UPDATE admins SET password_hash = $1 WHERE id = $2`, string(hash), id)
+
+	_, _, err = s.Login(ctx, "boss", "Password1", "10.0.0.1", "test")
+	require.NoError(t, err)
+	assert.Equal(t, bcrypt.MinCost+1, storedHashCost(t, conn, "admins", id))
+}
+
+func TestLoginKeepsHigherHashCost(t *testing.T) {
+	s, conn := newStoredService(t)
+	ctx := context.Background()
+
+	// lowering the cost doesn't weaken existing hashes
+	id := storedUser(t, conn, "cashier", "Password1")
+	hash, err := bcrypt.GenerateFromPassword([]byte("Password1"), bcrypt.MinCost+1)
+	require.NoError(t, err)
+	dbtest.Exec(t, conn, `UPDATE users SET password_hash = $1 WHERE id = $2`, string(hash), id)
+
+	_, _, err = s.Login(ctx, "cashier", "Password1", "10.0.0.1", "test")
+	require.NoError(t, err)
+	assert.Equal(t, bcrypt.MinCost+1, storedHashCost(t, conn, "users", id))
+}
+
+func TestHashPasswordCost(t *testing.T) {
+	s := &Service{bcryptCost: bcrypt.MinCost + 2}
+	hash, err := s.hashPassword("Password1")
+	require.NoError(t, err)
+	cost, err := bcrypt.Cost(hash)
+	require.NoError(t, err)
+	assert.Equal(t, bcrypt.MinCost+2, cost)
+}
@@ -0,0 +1,192 @@
+package auth
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	db "herp/db/sqlc"
+	"herp/pkg/password"
+	"strings"
+
+	"github.com/lib/pq"
+)
+
+var (
+	ErrUsernameTaken = errors.New("username is already taken")
+	ErrUnknownRole   = errors.New("role does not exist")
+)
+
+// BulkUserResult is what happened to one entry of a bulk user creation. Err
+// is set on the entries that failed, Created on the ones that were created.
+type BulkUserResult struct {
+	Index   int
+	Created bool
+	User    db.User
+	Err     error
+}
+
+// CreateUsers creates many users at once. Every entry is checked first the
+// way CreateUser checks it, and against the other entries for a repeated
+// username or email. Unless partial is set nothing is created when one entry
+// fails and the valid entries are created together in one transaction. With
+// partial the valid entries are created on their own and the invalid ones are
+// reported next to them.
+func (s *Service) CreateUsers(ctx context.Context, params []db.CreateUserParams, partial bool) ([]BulkUserResult, error) {
+	results := make([]BulkUserResult, len(params))
+	valid := make([]int, 0, len(params))
+	for n := range params {
+		results[n].Index = n
+		err := s.checkNewUser(ctx, &params[n], params[:n])
+		if err != nil {
+			if !isEntryError(err) {
+				return nil, err
+			}
+			results[n].Err = err
+			continue
+		}
+		valid = append(valid, n)
+	}
+	if len(valid) == 0 || (!partial && len(valid) != len(params)) {
+		return results, nil
+	}
+
+	for _, n := range valid {
+		hashedPassword, err := s.hashPassword(params[n].PasswordHash)
+		if err != nil {
+			return nil, err
+		}
+		params[n].PasswordHash = string(hashedPassword)
+	}
+
+	if partial {
+		for _, n := range valid {
+			user, err := s.queries.CreateUser(ctx, params[n])
+			if err != nil {
+				if err = createUserError(err); !isEntryError(err) {
+					return nil, err
+				}
+				results[n].Err = err
+				continue
+			}
+			results[n].Created = true
+			results[n].User = user
+		}
+		return results, nil
+	}
+
+	q, ok := s.queries.(*db.Queries)
+	if !ok {
+		return nil, fmt.Errorf("invalid queries implementation")
+	}
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	txQueries := q.WithTx(tx)
+
+	for _, n := range valid {
+		user, err := txQueries.CreateUser(ctx, params[n])
+		if err != nil {
+			// a user created since the entries were checked, nothing is kept
+			if err = createUserError(err); !isEntryError(err) {
+				return nil, err
+			}
+			results[n].Err = err
+			for _, m := range valid {
+				results[m].Created = false
+				results[m].User = db.User{}
+			}
+			return results, nil
+		}
+		results[n].Created = true
+		results[n].User = user
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	return results, nil
+}
+
+// checkNewUser checks one entry of a bulk creation against the ones before
+// it and the existing users, and resolves its branch.
+func (s *Service) checkNewUser(ctx context.Context, params *db.CreateUserParams, before []db.CreateUserParams) error {
+	if err := s.passwordPolicy.Validate(params.PasswordHash); err != nil {
+		return err
+	}
+
+	for _, other := range before {
+		if other.Username == params.Username {
+			return ErrUsernameTaken
+		}
+		if other.Email.Valid && params.Email.Valid && strings.EqualFold(other.Email.String, params.Email.String) {
+			return ErrEmailTaken
+		}
+	}
+
+	if _, err := s.queries.GetUserByUsername(ctx, params.Username); err == nil {
+		return ErrUsernameTaken
+	} else if !errors.Is(err, sql.ErrNoRows) {
+		return err
+	}
+	if params.Email.Valid {
+		if _, err := s.queries.GetUserByEmail(ctx, params.Email); err == nil {
+			return ErrEmailTaken
+		} else if !errors.Is(err, sql.ErrNoRows) {
+			return err
+		}
+	}
+
+	if params.RoleID.Valid {
+		if _, err := s.queries.GetRoleByID(ctx, params.RoleID.Int32); err != nil {
+			if errors.Is(err, sql.ErrNoRows) {
+				return ErrUnknownRole
+			}
+			return err
+		}
+	}
+
+	branchID, err := s.resolveStoreAssignment(ctx, params.BranchID, params.StoreID)
+	if err != nil {
+		return err
+	}
+	params.BranchID = branchID
+	return nil
+}
+
+// isEntryError reports whether err is about the entry itself rather than a
+// failure to check or create it.
+func isEntryError(err error) bool {
+	var policyErr *password.PolicyError
+	return errors.As(err, &policyErr) ||
+		errors.Is(err, ErrUsernameTaken) ||
+		errors.Is(err, ErrEmailTaken) ||
+		errors.Is(err, ErrUnknownRole) ||
+		errors.Is(err, ErrStoreNotFound) ||
+		errors.Is(err, ErrStoreNotInBranch)
+}
+
+// createUserError maps the constraint a user insert ran into to the error
+// reported for its entry, any other error is returned as is.
+func createUserError(err error) error {
+	var pgErr *pq.Error
+	if !errors.As(err, &pgErr) {
+		return err
+	}
+	switch pgErr.Code {
+	case "23505": // unique_violation
+		if pgErr.Constraint == "users_email_key" {
+			return ErrEmailTaken
+		}
+		return ErrUsernameTaken
+	case "23503": // foreign_key_violation
+		if pgErr.Constraint == "users_role_id_fkey" {
+			return ErrUnknownRole
+		}
+	}
+	return err
+}
@@ -0,0 +1,97 @@
+package auth
+
+import (
+	"context"
+	"database/sql"
+	db "herp/db/sqlc"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/crypto/bcrypt"
+)
+
+func newUser(username string) db.CreateUserParams {
+	return db.CreateUserParams{
+		Username:     username,
+		FirstName:    "Test",
+		LastName:     "User",
+		Email:        sql.NullString{String: username + "@example.com", Valid: true},
+		PasswordHash: "Password1",
+	}
+}
+
+func countUsers(t *testing.T, conn *sql.DB) int {
+	t.Helper()
+	var count int
+	require.NoError(t, conn.QueryRow(`SELECT count(*) FROM users`).Scan(&count))
+	return count
+}
+
+func TestCreateUsers(t *testing.T) {
+	s, conn := newStoredService(t)
+
+	results, err := s.CreateUsers(context.Background(), []db.CreateUserParams{newUser("ada"), newUser("bola")}, false)
+	require.NoError(t, err)
+	require.Len(t, results, 2)
+	for n, result := range results {
+		assert.Equal(t, n, result.Index)
+		assert.True(t, result.Created)
+		assert.NoError(t, result.Err)
+		assert.NotZero(t, result.User.ID)
+	}
+	assert.Equal(t, "bola", results[1].User.Username)
+	assert.Equal(t, 2, countUsers(t, conn))
+
+	// stored hashed
+	assert.NoError(t, bcrypt.CompareHashAndPassword([]byte(results[0].User.PasswordHash), []byte("Password1")))
+}
+
+func TestCreateUsersDuplicate(t *testing.T) {
+	s, conn := newStoredService(t)
+	storedUser(t, conn, "taken", "Password1")
+	ctx := context.Background()
+
+	// the same username twice in one request
+	results, err := s.CreateUsers(ctx, []db.CreateUserParams{newUser("ada"), newUser("bola"), newUser("ada")}, false)
+	require.NoError(t, err)
+	assert.False(t, results[0].Created)
+	assert.False(t, results[1].Created)
+	assert.ErrorIs(t, results[2].Err, ErrUsernameTaken)
+	assert.Equal(t, 1, countUsers(t, conn), "nothing created")
+
+	// an existing username, and an email used twice
+	other := newUser("chidi")
+	other.Email.String = "ADA@example.com"
+	results, err = s.CreateUsers(ctx, []db.CreateUserParams{newUser("ada"), newUser("taken"), other}, false)
+	require.NoError(t, err)
+	assert.NoError(t, results[0].Err)
+	assert.ErrorIs(t, results[1].Err, ErrUsernameTaken)
+	assert.ErrorIs(t, results[2].Err, ErrEmailTaken)
+	assert.Equal(t, 1, countUsers(t, conn), "nothing created")
+}
+
+func TestCreateUsersPartial(t *testing.T) {
+	s, conn := newStoredService(t)
+	storedUser(t, conn, "taken", "Password1")
+	unknownRole := newUser("bola")
+	unknownRole.RoleID = sql.NullInt32{Int32: 9999, Valid: true}
+
+	results, err := s.CreateUsers(context.Background(), []db.CreateUserParams{
+		newUser("ada"), unknownRole, newUser("taken"), newUser("chidi"),
+	}, true)
+	require.NoError(t, err)
+	require.Len(t, results, 4)
+	assert.True(t, results[0].Created)
+	assert.ErrorIs(t, results[1].Err, ErrUnknownRole)
+	assert.False(t, results[1].Created)
+	assert.ErrorIs(t, results[2].Err, ErrUsernameTaken)
+	assert.True(t, results[3].Created)
+	assert.Equal(t, 3, countUsers(t, conn))
+
+	// nothing valid, nothing created
+	results, err = s.CreateUsers(context.Background(), []db.CreateUserParams{newUser("taken")}, true)
+	require.NoError(t, err)
+	assert.False(t, results[0].Created)
+	assert.Equal(t, 3, countUsers(t, conn))
+}
@@ -0,0 +1,136 @@
+package auth
+
+import (
+	"context"
+	"database/sql"
+	db "herp/db/sqlc"
+	"herp/pkg/jwt"
+	"herp/pkg/password"
+	"net/http"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// sessionQuerier has one admin with three sessions, the one the claims come
+// from and two others, and a user with the same id and sessions of their own.
+type sessionQuerier struct {
+	Querier
+	admin        db.GetAdminByUsernameRow
+	sessions     []int32
+	userSessions []int32
+	updates      int
+}
+
+func newSessionQuerier(t *testing.T, current string) *sessionQuerier {
+	hash, err := bcrypt.GenerateFromPassword([]byte(current), bcrypt.MinCost)
+	require.NoError(t, err)
+	return &sessionQuerier{
+		admin:        db.GetAdminByUsernameRow{ID: 1, Username: "owner", PasswordHash: string(hash), IsActive: true},
+		sessions:     []int32{11, 12, 13},
+		userSessions: []int32{21, 22},
+	}
+}
+
+func (q *sessionQuerier) GetUserByID(ctx context.Context, id int32) (db.GetUserByIDRow, error) {
+	return db.GetUserByIDRow{}, sql.ErrNoRows
+}
+
+func (q *sessionQuerier) GetAdminByUsername(ctx context.Context, username string) (db.GetAdminByUsernameRow, error) {
+	if username != q.admin.Username {
+		return db.GetAdminByUsernameRow{}, sql.ErrNoRows
+	}
+	return q.admin, nil
+}
+
+func (q *sessionQuerier) UpdateAdminPassword(ctx context.Context, params db.UpdateAdminPasswordParams) error {
+	q.admin.PasswordHash = params.PasswordHash
+	q.updates++
+	return nil
+}
+
+func (q *sessionQuerier) RevokeOtherUserRefreshTokens(ctx context.Context, params db.RevokeOtherUserRefreshTokensParams) (int64, error) {
+	sessions := &q.userSessions
+	if params.AdminID.Valid {
+		if params.UserID.Valid || params.AdminID.Int32 != q.admin.ID {
+			return 0, nil
+		}
+		sessions = &q.sessions
+	}
+	var kept []int32
+	for _, id := range *sessions {
+		if id == params.KeepID {
+			kept = append(kept, id)
+		}
+	}
+	revoked := len(*sessions) - len(kept)
+	*sessions = kept
+	return int64(revoked), nil
+}
+
+func changePasswordService(q Querier) *Service {
+	return &Service{
+		queries:        q,
+		bcryptCost:     bcrypt.MinCost,
+		passwordPolicy: password.Policy{MinLength: 8, RequireDigit: true},
+	}
+}
+
+var ownerClaims = &jwt.Claims{UserID: 1, Username: "owner", SessionID: 11}
+
+func TestChangePasswordWrongCurrent(t *testing.T) {
+	q := newSessionQuerier(t, "OldPassword1")
+	s := changePasswordService(q)
+
+	_, err := s.ChangePassword(context.Background(), ownerClaims, "NotIt1234", "NewPassword2")
+	assert.ErrorIs(t, err, ErrInvalidCurrentPassword)
+	assert.Zero(t, q.updates)
+	assert.Equal(t, []int32{11, 12, 13}, q.sessions, "no session is revoked")
+}
+
+func TestChangePasswordRevokesOtherSessions(t *testing.T) {
+	q := newSessionQuerier(t, "OldPassword1")
+	s := changePasswordService(q)
+
+	revoked, err := s.ChangePassword(context.Background(), ownerClaims, "OldPassword1", "NewPassword2")
+	require.NoError(t, err)
+	assert.Equal(t, int64(2), revoked)
+	assert.Equal(t, []int32{11}, q.sessions, "the current session is kept")
+	assert.Equal(t, []int32{21, 22}, q.userSessions, "the user with the same id keeps their sessions")
+	assert.NoError(t, bcrypt.CompareHashAndPassword([]byte(q.admin.PasswordHash), []byte("NewPassword2")))
+
+	// the old password is gone
+	_, err = s.ChangePassword(context.Background(), ownerClaims, "OldPassword1", "OtherPassword3")
+	assert.ErrorIs(t, err, ErrInvalidCurrentPassword)
+}
+
+func TestChangePasswordPolicy(t *testing.T) {
+	q := newSessionQuerier(t, "OldPassword1")
+	s := changePasswordService(q)
+
+	_, err := s.ChangePassword(context.Background(), ownerClaims, "OldPassword1", "short")
+	var policyErr *password.PolicyError
+	assert.ErrorAs(t, err, &policyErr)
+	assert.Zero(t, q.updates)
+}
+
+func TestHandler_ChangePassword(t *testing.T) {
+	q := newSessionQuerier(t, "OldPassword1")
+	h, r := setupHandler(&mockService{}, &fakeEmailer{})
+	h.service = changePasswordService(q)
+	r.POST("/auth/change-password", func(c *gin.Context) {
+		c.Set("claims", ownerClaims)
+	}, h.ChangePassword)
+
+	w := postJSON(r, "/auth/change-password", `{"current_password":"NotIt1234","new_password":"NewPassword2"}`)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Len(t, q.sessions, 3)
+
+	w = postJSON(r, "/auth/change-password", `{"current_password":"OldPassword1","new_password":"NewPassword2"}`)
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	assert.Contains(t, w.Body.String(), `"revoked_sessions":2`)
+	assert.Equal(t, []int32{11}, q.sessions)
+}
@@ -0,0 +1,105 @@
+package auth
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"herp/db/dbtest"
+	db "herp/db/sqlc"
+	"herp/internal/config"
+	"herp/pkg/monitoring/logging"
+	"herp/pkg/password"
+	"herp/pkg/redis"
+	"herp/pkg/redis/redistest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// newStoredService returns a service on the test database and Redis.
+func newStoredService(t *testing.T) (*Service, *sql.DB) {
+	t.Helper()
+	conn := dbtest.Open(t)
+	client := redistest.Client(t)
+	cfg := &config.Config{}
+	return NewService(db.New(conn), testKeys(), "secret", time.Hour, time.Hour,
+		redis.NewRedisFromClient(client), client, 5, 15, 15, 100, conn,
+		logging.NewLogger(cfg), password.Policy{}, false, 6, bcrypt.MinCost), conn
+}
+
+// storedUser creates an active cashier with the password.
+func storedUser(t *testing.T, conn *sql.DB, username, pass string) int32 {
+	t.Helper()
+	hash, err := bcrypt.GenerateFromPassword([]byte(pass), bcrypt.MinCost)
+	require.NoError(t, err)
+	roleID := dbtest.Insert(t, conn, `
+		INSERT INTO roles (name) VALUES ('cashier')
+		ON CONFLICT (name) DO UPDATE SET name = EXCLUDED.name
+		RETURNING id`)
+	return dbtest.Insert(t, conn, `
+		INSERT INTO users (username, first_name, last_name, email, password_hash, role_id)
+		VALUES ($1, 'Test', 'User', $2, $3, $4)
+		RETURNING id`, username, username+"@example.com", string(hash), roleID)
+}
+
+func listedUsernames(t *testing.T, s *Service, includeDeleted bool) []string {
+	t.Helper()
+	users, _, err := s.ListUsers(context.Background(), db.ListUsersParams{IncludeDeleted: includeDeleted, PageLimit: 10})
+	require.NoError(t, err)
+	names := []string{}
+	for _, user := range users {
+		names = append(names, user.Username)
+	}
+	return names
+}
+
+func TestDeletedUserCannotLogIn(t *testing.T) {
+	s, conn := newStoredService(t)
+	id := storedUser(t, conn, "cashier", "Password1")
+	ctx := context.Background()
+
+	_, _, err := s.Login(ctx, "cashier", "Password1", "10.0.0.1", "test")
+	require.NoError(t, err)
+
+	require.NoError(t, s.DeleteUser(ctx, id))
+
+	// by username and by email, with the right password
+	_, _, err = s.Login(ctx, "cashier", "Password1", "10.0.0.1", "test")
+	assert.ErrorIs(t, err, ErrUserInactive)
+	_, _, err = s.Login(ctx, "cashier@example.com", "Password1", "10.0.0.1", "test")
+	assert.ErrorIs(t, err, ErrUserInactive)
+
+	// the row is kept for the logs
+	var deleted bool
+	require.NoError(t, conn.QueryRow(`SELECT deleted_at IS NOT NULL FROM users WHERE id = $1`, id).Scan(&deleted))
+	assert.True(t, deleted)
+
+	_, err = s.RestoreUser(ctx, id)
+	require.NoError(t, err)
+	_, _, err = s.Login(ctx, "cashier", "Password1", "10.0.0.1", "test")
+	assert.NoError(t, err)
+}
+
+func TestDeletedUserHidden(t *testing.T) {
+	s, conn := newStoredService(t)
+	id := storedUser(t, conn, "cashier", "Password1")
+	storedUser(t, conn, "waiter", "Password1")
+	ctx := context.Background()
+
+	// cached before the delete
+	_, err := s.GetUserByID(ctx, id)
+	require.NoError(t, err)
+
+	require.NoError(t, s.DeleteUser(ctx, id))
+
+	_, err = s.GetUserByID(ctx, id)
+	assert.True(t, errors.Is(err, sql.ErrNoRows), "got %v", err)
+	assert.Equal(t, []string{"waiter"}, listedUsernames(t, s, false))
+	assert.ElementsMatch(t, []string{"cashier", "waiter"}, listedUsernames(t, s, true))
+
+	// deleting twice finds nothing
+	assert.ErrorIs(t, s.DeleteUser(ctx, id), ErrUserNotFound)
+}
@@ -0,0 +1,73 @@
+package auth
+
+import (
+	"encoding/json"
+	"herp/internal/config"
+	"herp/pkg/jwt"
+	"herp/pkg/monitoring/logging"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// getEffectivePermissions asks the admin routes for the permissions of user
+// as the caller holding permissions.
+func getEffectivePermissions(s *Service, user string, permissions ...string) *httptest.ResponseRecorder {
+	gin.SetMode(gin.TestMode)
+	cfg := &config.Config{}
+	h := NewAdminHandler(s, cfg, logging.NewLogger(cfg), nil)
+
+	r := gin.New()
+	api := r.Group("/api/v1")
+	api.Use(func(c *gin.Context) {
+		c.Set("claims", &jwt.Claims{UserID: 1, Username: "admin", Permissions: permissions})
+	})
+	h.RegisterAdminRoutes(api, s)
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/v1/admin/users/"+user+"/permissions", nil))
+	return w
+}
+
+func TestGetUserPermissions(t *testing.T) {
+	s, conn := newStoredService(t)
+	view := storedPermission(t, conn, "sales:view")
+	refund := storedPermission(t, conn, "sales:refund")
+	storedPermission(t, conn, "inventory:manage")
+	role := storedRole(t, conn, "supervisor", view, refund)
+	user := storedCashier(t, conn, role)
+
+	w := getEffectivePermissions(s, strconv.Itoa(int(user)), "admin:manage")
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	var body struct {
+		Data EffectivePermissions `json:"data"`
+	}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &body))
+	assert.Equal(t, user, body.Data.UserID)
+	assert.Equal(t, "cashier", body.Data.Username)
+	assert.Equal(t, "supervisor", body.Data.RoleName)
+	require.NotNil(t, body.Data.RoleID)
+	assert.Equal(t, role, *body.Data.RoleID)
+	assert.ElementsMatch(t, []string{"sales:view", "sales:refund"}, body.Data.Permissions)
+}
+
+func TestGetUserPermissionsErrors(t *testing.T) {
+	s, conn := newStoredService(t)
+	user := strconv.Itoa(int(storedUser(t, conn, "cashier", "Password1")))
+
+	// admins only
+	assert.Equal(t, http.StatusForbidden, getEffectivePermissions(s, user, "sales:view").Code)
+
+	assert.Equal(t, http.StatusNotFound, getEffectivePermissions(s, "9999", "admin:manage").Code)
+	assert.Equal(t, http.StatusBadRequest, getEffectivePermissions(s, "me", "admin:manage").Code)
+
+	// a role without permissions is an empty list, not null
+	w := getEffectivePermissions(s, user, "admin:manage")
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), `"permissions":[]`)
+}
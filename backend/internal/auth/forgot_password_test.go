@@ -0,0 +1,255 @@
+package auth
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	db "herp/db/sqlc"
+	"herp/pkg/ratelimit"
+	"herp/pkg/redis/redistest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// resetQuerier keeps admins and users by email with their reset codes and
+// passwords.
+type resetQuerier struct {
+	Querier
+	admins    map[string]*db.GetAdminByEmailRow
+	users     map[string]*db.GetUserByEmailRow
+	userCodes map[int32]db.UserResetCode
+}
+
+func newResetQuerier() *resetQuerier {
+	return &resetQuerier{
+		admins:    map[string]*db.GetAdminByEmailRow{},
+		users:     map[string]*db.GetUserByEmailRow{},
+		userCodes: map[int32]db.UserResetCode{},
+	}
+}
+
+func (q *resetQuerier) addAdmin(email string) {
+	q.admins[email] = &db.GetAdminByEmailRow{ID: int32(len(q.admins) + 1), Email: email, IsActive: true}
+}
+
+func (q *resetQuerier) addUser(email string) {
+	q.users[email] = &db.GetUserByEmailRow{
+		ID:       int32(len(q.users) + 1),
+		Email:    sql.NullString{String: email, Valid: true},
+		IsActive: sql.NullBool{Bool: true, Valid: true},
+	}
+}
+
+func (q *resetQuerier) adminByID(id int32) *db.GetAdminByEmailRow {
+	for _, admin := range q.admins {
+		if admin.ID == id {
+			return admin
+		}
+	}
+	panic("no admin")
+}
+
+func (q *resetQuerier) userByID(id int32) *db.GetUserByEmailRow {
+	for _, user := range q.users {
+		if user.ID == id {
+			return user
+		}
+	}
+	panic("no user")
+}
+
+func (q *resetQuerier) GetAdminByEmail(ctx context.Context, email string) (db.GetAdminByEmailRow, error) {
+	admin, ok := q.admins[email]
+	if !ok {
+		return db.GetAdminByEmailRow{}, sql.ErrNoRows
+	}
+	return *admin, nil
+}
+
+func (q *resetQuerier) SetAdminResetCode(ctx context.Context, params db.SetAdminResetCodeParams) error {
+	admin := q.adminByID(params.ID)
+	admin.ResetCode = params.ResetCode
+	admin.ResetCodeExpiresAt = params.ResetCodeExpiresAt
+	return nil
+}
+
+func (q *resetQuerier) ClearAdminResetCode(ctx context.Context, id int32) error {
+	admin := q.adminByID(id)
+	admin.ResetCode = sql.NullString{}
+	admin.ResetCodeExpiresAt = sql.NullTime{}
+	return nil
+}
+
+func (q *resetQuerier) UpdateAdminPassword(ctx context.Context, params db.UpdateAdminPasswordParams) error {
+	q.adminByID(params.ID).PasswordHash = params.PasswordHash
+	return nil
+}
+
+func (q *resetQuerier) GetUserByEmail(ctx context.Context, email sql.NullString) (db.GetUserByEmailRow, error) {
+	user, ok := q.users[email.String]
+	if !ok {
+		return db.GetUserByEmailRow{}, sql.ErrNoRows
+	}
+	return *user, nil
+}
+
+func (q *resetQuerier) SetUserResetCode(ctx context.Context, params db.SetUserResetCodeParams) error {
+	q.userCodes[params.UserID] = db.UserResetCode(params)
+	return nil
+}
+
+func (q *resetQuerier) GetUserResetCode(ctx context.Context, userID int32) (db.UserResetCode, error) {
+	code, ok := q.userCodes[userID]
+	if !ok {
+		return db.UserResetCode{}, sql.ErrNoRows
+	}
+	return code, nil
+}
+
+func (q *resetQuerier) ClearUserResetCode(ctx context.Context, userID int32) error {
+	delete(q.userCodes, userID)
+	return nil
+}
+
+func (q *resetQuerier) UpdateUserPassword(ctx context.Context, params db.UpdateUserPasswordParams) error {
+	q.userByID(params.ID).PasswordHash = params.PasswordHash
+	return nil
+}
+
+func TestForgotPasswordThrottle(t *testing.T) {
+	q := newResetQuerier()
+	q.addAdmin("owner@example.com")
+	s := &Service{
+		queries:          q,
+		rateLimiter:      ratelimit.NewRateLimit(redistest.Client(t)),
+		otpLength:        6,
+		jwtRefreshSecret: "secret",
+	}
+	ctx := context.Background()
+
+	code, err := s.ForgotPassword(ctx, "owner@example.com")
+	require.NoError(t, err)
+	assert.Len(t, code, 6)
+	assert.True(t, s.codeMatches(q.admins["owner@example.com"].ResetCode.String, code))
+
+	// a second code within the minute isn't made, whatever the case
+	_, err = s.ForgotPassword(ctx, "Owner@Example.com")
+	assert.ErrorIs(t, err, ErrResendTooSoon)
+	assert.True(t, s.codeMatches(q.admins["owner@example.com"].ResetCode.String, code), "the first code still holds")
+
+	// unknown addresses are throttled the same way
+	_, err = s.ForgotPassword(ctx, "nobody@example.com")
+	assert.ErrorIs(t, err, ErrUserNotFound)
+	_, err = s.ForgotPassword(ctx, "nobody@example.com")
+	assert.ErrorIs(t, err, ErrResendTooSoon)
+}
+
+func TestHandler_ForgotPassword_SameResponse(t *testing.T) {
+	var bodies []string
+	for _, err := range []error{nil, ErrUserNotFound, ErrUserInactive, ErrResendTooSoon, errors.New("connection refused")} {
+		svc := &mockService{
+			forgotPasswordFunc: func(ctx context.Context, email string) (string, error) {
+				if err != nil {
+					return "", err
+				}
+				return "123456", nil
+			},
+		}
+		emailer := &fakeEmailer{}
+		h, r := setupHandler(svc, emailer)
+		r.POST("/forgot-password", h.ForgotPassword)
+
+		w := postJSON(r, "/forgot-password", `{"email":"user@example.com"}`)
+		assert.Equal(t, 200, w.Code, "%v", err)
+		bodies = append(bodies, w.Body.String())
+
+		// mail only goes to real accounts
+		if err == nil {
+			assert.Len(t, emailer.sent, 1)
+		} else {
+			assert.Empty(t, emailer.sent, "%v", err)
+		}
+	}
+	for _, body := range bodies[1:] {
+		assert.Equal(t, bodies[0], body)
+	}
+}
+
+func TestResetPassword(t *testing.T) {
+	tests := []struct {
+		name  string
+		email string
+		// hash returns the stored password hash of the account
+		hash func(q *resetQuerier) string
+		// pending reports whether the account still has a reset code
+		pending func(q *resetQuerier) bool
+	}{
+		{
+			name:    "admin",
+			email:   "owner@example.com",
+			hash:    func(q *resetQuerier) string { return q.admins["owner@example.com"].PasswordHash },
+			pending: func(q *resetQuerier) bool { return q.admins["owner@example.com"].ResetCode.Valid },
+		},
+		{
+			name:  "user",
+			email: "cashier@example.com",
+			hash:  func(q *resetQuerier) string { return q.users["cashier@example.com"].PasswordHash },
+			pending: func(q *resetQuerier) bool {
+				_, ok := q.userCodes[q.users["cashier@example.com"].ID]
+				return ok
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			q := newResetQuerier()
+			q.addAdmin("owner@example.com")
+			q.addUser("cashier@example.com")
+			s := &Service{
+				queries:          q,
+				rateLimiter:      ratelimit.NewRateLimit(redistest.Client(t)),
+				otpLength:        6,
+				jwtRefreshSecret: "secret",
+				bcryptCost:       bcrypt.MinCost,
+			}
+			ctx := context.Background()
+
+			code, err := s.ForgotPassword(ctx, tt.email)
+			require.NoError(t, err)
+			require.True(t, tt.pending(q))
+
+			err = s.ResetAdminPassword(ctx, tt.email, "000000"+code, "NewPassword123")
+			assert.ErrorIs(t, err, errInvalidResetCode)
+
+			require.NoError(t, s.ResetAdminPassword(ctx, tt.email, code, "NewPassword123"))
+			assert.NoError(t, bcrypt.CompareHashAndPassword([]byte(tt.hash(q)), []byte("NewPassword123")))
+			assert.False(t, tt.pending(q), "the code is cleared once used")
+
+			// a used code can't be used again
+			err = s.ResetAdminPassword(ctx, tt.email, code, "OtherPassword123")
+			assert.ErrorIs(t, err, errInvalidResetCode)
+		})
+	}
+}
+
+func TestResetPasswordExpiredCode(t *testing.T) {
+	q := newResetQuerier()
+	q.addAdmin("owner@example.com")
+	q.addUser("cashier@example.com")
+	s := &Service{queries: q, jwtRefreshSecret: "secret", bcryptCost: bcrypt.MinCost}
+	ctx := context.Background()
+	expired := time.Now().Add(-time.Minute)
+
+	q.admins["owner@example.com"].ResetCode = sql.NullString{String: s.hashCode("123456"), Valid: true}
+	q.admins["owner@example.com"].ResetCodeExpiresAt = sql.NullTime{Time: expired, Valid: true}
+	q.userCodes[q.users["cashier@example.com"].ID] = db.UserResetCode{Code: s.hashCode("123456"), ExpiresAt: expired}
+
+	for _, email := range []string{"owner@example.com", "cashier@example.com"} {
+		err := s.ResetAdminPassword(ctx, email, "123456", "NewPassword123")
+		assert.ErrorIs(t, err, errInvalidResetCode, email)
+	}
+}
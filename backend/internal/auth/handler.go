@@ -1,14 +1,16 @@
 package auth
 
 import (
+	"database/sql"
 	"errors"
-	"fmt"
 	"herp/internal/config"
 	"herp/internal/utils"
 	"herp/pkg/jwt"
 	"herp/pkg/monitoring/logging"
+	"herp/pkg/password"
 	"log"
 	"net/http"
+	"strconv"
 	"strings"
 	"time"
 
@@ -20,10 +22,11 @@ type Handler struct {
 	config  *config.Config
 	logger  *logging.Logger
 	env     string // remove
+	emailer utils.Emailer
 }
 
-func NewHandler(service ServiceInterface, c *config.Config, l *logging.Logger, e string) *Handler {
-	return &Handler{service, c, l, e}
+func NewHandler(service ServiceInterface, c *config.Config, l *logging.Logger, e string, emailer utils.Emailer) *Handler {
+	return &Handler{service, c, l, e, emailer}
 }
 
 // LoginRequest represents the login request payload
@@ -59,7 +62,12 @@ type ForgotPasswordRequest struct {
 type ResetAdminPasswordRequest struct {
 	Email       string `json:"email" binding:"required,email" example:"admin@example.com"` // Admin email address
 	Code        string `json:"code" binding:"required" example:"1234567"`                  // Password reset code
-	NewPassword string `json:"new_password" binding:"required,min=8" example:"NewPassword123"`
+	NewPassword string `json:"new_password" binding:"required" example:"NewPassword123"`
+}
+
+type AcceptInviteRequest struct {
+	Token       string `json:"token" binding:"required" example:"3f2a9c..."` // Token from the invite link
+	NewPassword string `json:"new_password" binding:"required" example:"NewPassword123"`
 }
 
 // ErrorResponse represents an error response
@@ -109,7 +117,7 @@ func (h *Handler) Login(c *gin.Context) {
 	var req LoginRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
 		log.Println("Error binding JSON:", err)
-		utils.ErrorResponse(c, 400, err.Error())
+		utils.BindingErrorResponse(c, err)
 		return
 	}
 
@@ -133,7 +141,10 @@ func (h *Handler) Login(c *gin.Context) {
 		h.logger.Printf("login error: %v", err)
 		status := http.StatusUnauthorized
 		errorMsg := err.Error()
-		if !errors.Is(err, ErrInvalidCredentials) && !errors.Is(err, ErrUserInactive) {
+		if errors.Is(err, ErrEmailNotVerified) {
+			// a distinct status so clients can offer to resend the code
+			status = http.StatusForbidden
+		} else if !errors.Is(err, ErrInvalidCredentials) && !errors.Is(err, ErrUserInactive) {
 			status = http.StatusBadRequest
 		} else if strings.Contains(errorMsg, "temporarily blocked") ||
 			strings.Contains(errorMsg, "Account temporarily locked") ||
@@ -145,7 +156,7 @@ func (h *Handler) Login(c *gin.Context) {
 	}
 
 	// Parse token to get expiry
-	claims, _ := jwt.ParseToken(token, h.config.JWTSecret)
+	claims, _ := h.service.ParseToken(token)
 	expiry := time.Time{}
 	if claims != nil {
 		expiry = claims.ExpiresAt.Time
@@ -188,7 +199,7 @@ func (h *Handler) Refresh(c *gin.Context) {
 		return
 	}
 
-	claims, _ := jwt.ParseToken(accessToken, h.config.JWTSecret)
+	claims, _ := h.service.ParseToken(accessToken)
 	expiry := time.Time{}
 	if claims != nil {
 		expiry = claims.ExpiresAt.Time
@@ -244,6 +255,446 @@ func (h *Handler) Logout(c *gin.Context) {
 	utils.SuccessResponse(c, 200, "Logged out successfully", nil)
 }
 
+// MeResponse represents the profile of the current user
+// @Description Current user profile payload
+type MeResponse struct {
+	ID            int32    `json:"id" example:"1"`                                        // User ID
+	Username      string   `json:"username" example:"johndoe"`                            // Username
+	Email         string   `json:"email" example:"john@example.com"`                      // Email address
+	FirstName     string   `json:"first_name" example:"John"`                             // First name
+	LastName      string   `json:"last_name" example:"Doe"`                               // Last name
+	Role          string   `json:"role" example:"manager"`                                // Role name
+	Permissions   []string `json:"permissions" example:"inventory:read,inventory:update"` // Permission codes of the role
+	EmailVerified bool     `json:"email_verified" example:"true"`                         // Whether the email is verified
+	IsAdmin       bool     `json:"is_admin" example:"false"`                              // Whether the account is an admin
+}
+
+// Me godoc
+// @Summary Current user
+// @Description Get the profile of the current user with the role and permissions it has now, which may differ from the ones in the token
+// @Tags auth
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} MeResponse "Profile retrieved successfully"
+// @Failure 401 {object} UnauthorizedResponse "Unauthorized"
+// @Failure 403 {object} ErrorrResponse "User is inactive"
+// @Failure 500 {object} InternalServerErrorResponse "Internal server error"
+// @Router /api/v1/auth/me [get]
+func (h *Handler) Me(c *gin.Context) {
+	claims, ok := jwt.GetUserFromContext(c)
+	if !ok {
+		utils.ErrorResponse(c, 401, "unauthorized")
+		return
+	}
+
+	profile, err := h.service.Me(c.Request.Context(), claims)
+	if err != nil {
+		switch {
+		case errors.Is(err, ErrUserNotFound):
+			utils.ErrorResponse(c, 401, "unauthorized")
+		case errors.Is(err, ErrUserInactive):
+			utils.ErrorResponse(c, 403, err.Error())
+		default:
+			h.logger.Errorf("error loading profile of user %d: %v", claims.UserID, err)
+			utils.ErrorResponse(c, 500, utils.SERVERERROR)
+		}
+		return
+	}
+
+	utils.SuccessResponse(c, 200, "profile", meResponse(profile))
+}
+
+func meResponse(profile Profile) MeResponse {
+	permissions := profile.Permissions
+	if permissions == nil {
+		permissions = []string{}
+	}
+	return MeResponse{
+		ID:            profile.ID,
+		Username:      profile.Username,
+		Email:         profile.Email,
+		FirstName:     profile.FirstName,
+		LastName:      profile.LastName,
+		Role:          profile.RoleName,
+		Permissions:   permissions,
+		EmailVerified: profile.EmailVerified,
+		IsAdmin:       profile.IsAdmin,
+	}
+}
+
+// UpdateMeRequest represents the profile self-update payload, fields left out
+// are not changed
+// @Description Update current user profile request payload
+type UpdateMeRequest struct {
+	FirstName *string `json:"first_name" binding:"omitempty,min=1,max=50" example:"John"`
+	LastName  *string `json:"last_name" binding:"omitempty,min=1,max=50" example:"Doe"`
+	Email     *string `json:"email" binding:"omitempty,email,max=100" example:"john@example.com"`
+}
+
+// UpdateMe godoc
+// @Summary Update current user
+// @Description Change the name or email of the current user. The role and active status can't be changed here. A user or admin who changes their email is sent a code to verify the new address and can't log in until it is verified.
+// @Tags auth
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param body body UpdateMeRequest true "Profile changes"
+// @Success 200 {object} MeResponse "Profile updated successfully"
+// @Failure 400 {object} BadRequestResponse "Bad request"
+// @Failure 401 {object} UnauthorizedResponse "Unauthorized"
+// @Failure 403 {object} ErrorrResponse "User is inactive"
+// @Failure 409 {object} ErrorrResponse "Email already used"
+// @Failure 500 {object} InternalServerErrorResponse "Internal server error"
+// @Router /api/v1/auth/me [patch]
+func (h *Handler) UpdateMe(c *gin.Context) {
+	claims, ok := jwt.GetUserFromContext(c)
+	if !ok {
+		utils.ErrorResponse(c, 401, "unauthorized")
+		return
+	}
+
+	var req UpdateMeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.BindingErrorResponse(c, err)
+		return
+	}
+
+	profile, code, err := h.service.UpdateProfile(c.Request.Context(), claims, UpdateProfileParams{
+		FirstName: req.FirstName,
+		LastName:  req.LastName,
+		Email:     req.Email,
+	})
+	if err != nil {
+		switch {
+		case errors.Is(err, ErrUserNotFound):
+			utils.ErrorResponse(c, 401, "unauthorized")
+		case errors.Is(err, ErrUserInactive):
+			utils.ErrorResponse(c, 403, err.Error())
+		case errors.Is(err, ErrEmailTaken):
+			utils.ErrorResponse(c, 409, err.Error())
+		default:
+			h.logger.Errorf("error updating profile of user %d: %v", claims.UserID, err)
+			utils.ErrorResponse(c, 500, utils.SERVERERROR)
+		}
+		return
+	}
+
+	if code != "" {
+		emailBody, _ := utils.RenderEmailTemplate("templates/auth/verify_email.html", map[string]any{
+			"Username": profile.Username,
+			"Code":     code,
+		})
+		if err := h.emailer.SendEmail(profile.Email, "Verify your Herp account", emailBody); err != nil {
+			// the email is changed either way, a new code can be requested
+			h.logger.Errorf("error sending verification email to %s: %v", profile.Email, err)
+		}
+	}
+
+	utils.SuccessResponse(c, 200, "profile updated", meResponse(profile))
+}
+
+// ChangePasswordRequest represents the change password payload
+// @Description Change password request payload
+type ChangePasswordRequest struct {
+	CurrentPassword string `json:"current_password" binding:"required" example:"OldP@ssw0rd"`
+	NewPassword     string `json:"new_password" binding:"required" example:"NewP@ssw0rd"`
+}
+
+// ChangePassword godoc
+// @Summary Change password
+// @Description Change the password of the current user, confirming the current one first. Every other session of the user is revoked, the one making the request stays logged in.
+// @Tags auth
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param body body ChangePasswordRequest true "Current and new password"
+// @Success 200 "Password changed"
+// @Failure 400 {object} BadRequestResponse "Wrong current password or new password rejected"
+// @Failure 401 {object} UnauthorizedResponse "Unauthorized"
+// @Failure 403 {object} ErrorrResponse "User is inactive"
+// @Failure 500 {object} InternalServerErrorResponse "Internal server error"
+// @Router /api/v1/auth/change-password [post]
+func (h *Handler) ChangePassword(c *gin.Context) {
+	claims, ok := jwt.GetUserFromContext(c)
+	if !ok {
+		utils.ErrorResponse(c, 401, "unauthorized")
+		return
+	}
+
+	var req ChangePasswordRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.BindingErrorResponse(c, err)
+		return
+	}
+
+	revoked, err := h.service.ChangePassword(c.Request.Context(), claims, req.CurrentPassword, req.NewPassword)
+	if passwordPolicyError(c, err) {
+		return
+	}
+	if err != nil {
+		switch {
+		case errors.Is(err, ErrInvalidCurrentPassword), errors.Is(err, ErrPasswordReused):
+			utils.ErrorResponse(c, 400, err.Error())
+		case errors.Is(err, ErrUserNotFound):
+			utils.ErrorResponse(c, 401, "unauthorized")
+		case errors.Is(err, ErrUserInactive):
+			utils.ErrorResponse(c, 403, err.Error())
+		default:
+			h.logger.Errorf("error changing password of user %d: %v", claims.UserID, err)
+			utils.ErrorResponse(c, 500, utils.SERVERERROR)
+		}
+		return
+	}
+
+	utils.SuccessResponse(c, 200, "password changed", gin.H{"revoked_sessions": revoked})
+}
+
+// SessionResponse represents an active session of the current user
+// @Description Session response payload
+type SessionResponse struct {
+	ID        int32     `json:"id" example:"12"`                                         // Session ID
+	Device    string    `json:"device" example:"Chrome on Windows"`                      // Label derived from the user agent
+	UserAgent string    `json:"user_agent" example:"Mozilla/5.0 (Windows NT 10.0; ...)"` // User agent the session was started from
+	Current   bool      `json:"current" example:"true"`                                  // Whether this is the session making the request
+	CreatedAt time.Time `json:"created_at" example:"2024-01-15T10:30:00Z"`               // When the session was started or last refreshed
+	ExpiresAt time.Time `json:"expires_at" example:"2024-02-14T10:30:00Z"`               // When the session expires
+}
+
+// ListSessions godoc
+// @Summary List sessions
+// @Description List the active sessions of the current user
+// @Tags auth
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {array} SessionResponse "Sessions retrieved successfully"
+// @Failure 401 {object} UnauthorizedResponse "Unauthorized"
+// @Failure 500 {object} InternalServerErrorResponse "Internal server error"
+// @Router /api/v1/auth/sessions [get]
+func (h *Handler) ListSessions(c *gin.Context) {
+	claims, ok := jwt.GetUserFromContext(c)
+	if !ok {
+		utils.ErrorResponse(c, 401, "unauthorized")
+		return
+	}
+
+	sessions, err := h.service.ListSessions(c.Request.Context(), claims)
+	if err != nil {
+		switch {
+		case errors.Is(err, ErrUserNotFound):
+			utils.ErrorResponse(c, 401, "unauthorized")
+		case errors.Is(err, ErrUserInactive):
+			utils.ErrorResponse(c, 403, err.Error())
+		default:
+			h.logger.Errorf("error listing sessions of user %d: %v", claims.UserID, err)
+			utils.ErrorResponse(c, 500, utils.SERVERERROR)
+		}
+		return
+	}
+
+	response := make([]SessionResponse, 0, len(sessions))
+	for _, session := range sessions {
+		response = append(response, SessionResponse{
+			ID:        session.ID,
+			Device:    deviceLabel(session.UserAgent.String),
+			UserAgent: session.UserAgent.String,
+			Current:   int(session.ID) == claims.SessionID,
+			CreatedAt: session.CreatedAt.Time,
+			ExpiresAt: session.ExpiresAt,
+		})
+	}
+
+	utils.SuccessResponse(c, 200, "sessions", response)
+}
+
+// RevokeSession godoc
+// @Summary Revoke session
+// @Description Revoke one of the current user's sessions. Revoking the current session also logs out the access token used for the request.
+// @Tags auth
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "Session ID"
+// @Success 200 "Session revoked"
+// @Failure 400 {object} BadRequestResponse "Bad request"
+// @Failure 401 {object} UnauthorizedResponse "Unauthorized"
+// @Failure 404 {object} BadRequestResponse "Session not found"
+// @Failure 500 {object} InternalServerErrorResponse "Internal server error"
+// @Router /api/v1/auth/sessions/{id} [delete]
+func (h *Handler) RevokeSession(c *gin.Context) {
+	claims, ok := jwt.GetUserFromContext(c)
+	if !ok {
+		utils.ErrorResponse(c, 401, "unauthorized")
+		return
+	}
+
+	sessionID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		utils.ErrorResponse(c, 400, utils.INVALID_REQUEST_DATA)
+		return
+	}
+
+	if err := h.service.RevokeSession(c.Request.Context(), claims, int32(sessionID)); err != nil {
+		switch {
+		case errors.Is(err, ErrSessionNotFound):
+			utils.ErrorResponse(c, 404, err.Error())
+		case errors.Is(err, ErrUserNotFound):
+			utils.ErrorResponse(c, 401, "unauthorized")
+		case errors.Is(err, ErrUserInactive):
+			utils.ErrorResponse(c, 403, err.Error())
+		default:
+			h.logger.Errorf("error revoking session %d of user %d: %v", sessionID, claims.UserID, err)
+			utils.ErrorResponse(c, 500, utils.SERVERERROR)
+		}
+		return
+	}
+
+	if sessionID == claims.SessionID {
+		token := strings.TrimPrefix(c.GetHeader(AuthorizationHeader), BearerPrefix)
+		if err := h.service.Logout(c.Request.Context(), token, time.Until(claims.ExpiresAt.Time)); err != nil {
+			h.logger.Errorf("error blacklisting token of revoked session %d: %v", sessionID, err)
+			utils.ErrorResponse(c, 500, utils.SERVERERROR)
+			return
+		}
+	}
+
+	utils.SuccessResponse(c, 200, "session revoked", nil)
+}
+
+// NotificationPreferenceItem represents one notification preference
+// @Description Notification preference payload
+type NotificationPreferenceItem struct {
+	Event   string `json:"event" binding:"required,oneof=low_stock large_sale security" example:"low_stock"` // Event the preference is for
+	Email   *bool  `json:"email" binding:"required" example:"true"`                                          // Receive the event by email
+	InApp   *bool  `json:"in_app" binding:"required" example:"true"`                                         // Receive the event in the app
+	Default bool   `json:"default" example:"false"`                                                          // Whether the value comes from the user's role
+}
+
+// UpdateNotificationPreferencesRequest represents the notification preferences update payload
+// @Description Update notification preferences request payload
+type UpdateNotificationPreferencesRequest struct {
+	Preferences []NotificationPreferenceItem `json:"preferences" binding:"required,dive"`
+}
+
+// notificationPreferencesUser parses the user id of a notification
+// preferences route. Users can only see and change their own preferences,
+// admins can see and change anyone's.
+func (h *Handler) notificationPreferencesUser(c *gin.Context) (int32, bool) {
+	claims, ok := jwt.GetUserFromContext(c)
+	if !ok {
+		utils.ErrorResponse(c, 401, "unauthorized")
+		return 0, false
+	}
+
+	userID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		utils.ErrorResponse(c, 400, utils.INVALID_REQUEST_DATA)
+		return 0, false
+	}
+
+	if userID != claims.UserID && !h.service.HasPermission(claims, "admin:manage") {
+		utils.ErrorResponse(c, 403, "you can only manage your own notification preferences")
+		return 0, false
+	}
+
+	return int32(userID), true
+}
+
+func notificationPreferencesResponse(prefs []NotificationPreference) []NotificationPreferenceItem {
+	response := make([]NotificationPreferenceItem, 0, len(prefs))
+	for _, pref := range prefs {
+		response = append(response, NotificationPreferenceItem{
+			Event:   pref.Event,
+			Email:   &pref.Email,
+			InApp:   &pref.InApp,
+			Default: pref.Default,
+		})
+	}
+	return response
+}
+
+// GetNotificationPreferences godoc
+// @Summary Get notification preferences
+// @Description Get which events a user is notified about and over which channels. Events the user never set use the defaults of their role.
+// @Tags auth
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "User ID"
+// @Success 200 {array} NotificationPreferenceItem "Notification preferences retrieved successfully"
+// @Failure 400 {object} BadRequestResponse "Bad request"
+// @Failure 401 {object} UnauthorizedResponse "Unauthorized"
+// @Failure 403 {object} ErrorrResponse "Not your preferences"
+// @Failure 404 {object} ErrorrResponse "User not found"
+// @Failure 500 {object} InternalServerErrorResponse "Internal server error"
+// @Router /api/v1/users/{id}/notification-preferences [get]
+func (h *Handler) GetNotificationPreferences(c *gin.Context) {
+	userID, ok := h.notificationPreferencesUser(c)
+	if !ok {
+		return
+	}
+
+	prefs, err := h.service.GetNotificationPreferences(c.Request.Context(), userID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			utils.ErrorResponse(c, 404, "user not found")
+			return
+		}
+		h.logger.Errorf("error getting notification preferences of user %d: %v", userID, err)
+		utils.ErrorResponse(c, 500, utils.SERVERERROR)
+		return
+	}
+
+	utils.SuccessResponse(c, 200, "notification preferences", notificationPreferencesResponse(prefs))
+}
+
+// UpdateNotificationPreferences godoc
+// @Summary Update notification preferences
+// @Description Set which events a user is notified about and over which channels. Events left out keep their current value.
+// @Tags auth
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "User ID"
+// @Param body body UpdateNotificationPreferencesRequest true "Preferences to set"
+// @Success 200 {array} NotificationPreferenceItem "Notification preferences updated successfully"
+// @Failure 400 {object} BadRequestResponse "Bad request"
+// @Failure 401 {object} UnauthorizedResponse "Unauthorized"
+// @Failure 403 {object} ErrorrResponse "Not your preferences"
+// @Failure 404 {object} ErrorrResponse "User not found"
+// @Failure 500 {object} InternalServerErrorResponse "Internal server error"
+// @Router /api/v1/users/{id}/notification-preferences [put]
+func (h *Handler) UpdateNotificationPreferences(c *gin.Context) {
+	userID, ok := h.notificationPreferencesUser(c)
+	if !ok {
+		return
+	}
+
+	var req UpdateNotificationPreferencesRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.BindingErrorResponse(c, err)
+		return
+	}
+
+	prefs := make([]NotificationPreference, 0, len(req.Preferences))
+	for _, item := range req.Preferences {
+		prefs = append(prefs, NotificationPreference{Event: item.Event, Email: *item.Email, InApp: *item.InApp})
+	}
+
+	prefs, err := h.service.UpdateNotificationPreferences(c.Request.Context(), userID, prefs)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			utils.ErrorResponse(c, 404, "user not found")
+		case errors.Is(err, ErrUnknownNotificationEvent):
+			utils.ErrorResponse(c, 400, err.Error())
+		default:
+			h.logger.Errorf("error updating notification preferences of user %d: %v", userID, err)
+			utils.ErrorResponse(c, 500, utils.SERVERERROR)
+		}
+		return
+	}
+
+	utils.SuccessResponse(c, 200, "notification preferences updated", notificationPreferencesResponse(prefs))
+}
+
 // RegisterAdminRequest represents the login request payload
 // @Description Register admin request payload
 type RegisterAdminRequest struct {
@@ -251,7 +702,7 @@ type RegisterAdminRequest struct {
 	LastName  string `json:"last_name" binding:"required,min=2"`
 	Username  string `json:"username" binding:"required,min=3"`
 	Email     string `json:"email" binding:"required,email"`
-	Password  string `json:"password" binding:"required,min=8"`
+	Password  string `json:"password" binding:"required"`
 }
 
 // Admin Register godoc
@@ -265,19 +716,21 @@ type RegisterAdminRequest struct {
 // @Failure 400 {object} BadRequestResponse "Bad request"
 // @Failure 401 {object} UnauthorizedResponse "Unauthorized"
 // @Failure 500 {object} InternalServerErrorResponse "Internal server error"
-// @Failure 500 {string} string "Unable to send email at this time, request a new verification code for example@email.com"
 // @Router /api/v1/auth/register [post]
 func (h *Handler) RegisterAdmin(c *gin.Context) {
 	var req RegisterAdminRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
-		utils.ErrorResponse(c, 400, err.Error())
+		utils.BindingErrorResponse(c, err)
 		return
 	}
 	// Generate verification code and expiry
-	code := utils.GenerateOTP()
+	code := utils.GenerateOTP(h.config.OTPLength)
 	expiry := time.Now().Add(10 * time.Minute)
 
 	admin, err := h.service.RegisterAdmin(c, req.Username, req.Email, req.Password, req.FirstName, req.LastName)
+	if passwordPolicyError(c, err) {
+		return
+	}
 	if err != nil {
 		log.Printf("error registering admin: %v", err)
 		utils.ErrorResponse(c, 500, err.Error())
@@ -296,12 +749,9 @@ func (h *Handler) RegisterAdmin(c *gin.Context) {
 		"Username": admin.Username,
 		"Code":     code,
 	})
-	plunk := utils.Plunk{HttpClient: http.DefaultClient, Config: h.config}
-	err = plunk.SendEmail(admin.Email, "Verify your Herp account", emailBody)
-	if err != nil {
-		log.Printf("error sending verification email: %v", err)
-		utils.ErrorResponse(c, 500, fmt.Sprintf("Unable to send email at this time, request a new verification code for %s", admin.Email))
-		return
+	// the admin exists either way, a lost email can be resent
+	if err := h.emailer.SendEmail(admin.Email, "Verify your Herp account", emailBody); err != nil {
+		h.logger.Errorf("error queueing verification email to %s: %v", admin.Email, err)
 	}
 	utils.SuccessResponse(c, 200, "Registration successful", RegisterResponse{
 		ID:              admin.ID,
@@ -339,7 +789,7 @@ type VerifyEmailRequest struct {
 func (h *Handler) VerifyEmail(c *gin.Context) {
 	var req VerifyEmailRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
-		utils.ErrorResponse(c, 400, err.Error())
+		utils.BindingErrorResponse(c, err)
 		return
 	}
 
@@ -355,41 +805,115 @@ func (h *Handler) VerifyEmail(c *gin.Context) {
 	utils.SuccessResponse(c, 200, "Email verified successfully", nil)
 }
 
+// ResendVerificationRequest represents the resend verification payload
+// @Description resend verification request payload
+type ResendVerificationRequest struct {
+	Email string `json:"email" binding:"required,email"`
+}
+
+// Resend Verification godoc
+// @Summary Resend verification email
+// @Description Send a new email verification code to a user or admin who hasn't verified their email. An address can get a new code once a minute.
+// @Tags auth
+// @Accept json
+// @Produce json
+// @Param body body ResendVerificationRequest true "Resend Verification Request"
+// @Success 200 "Verification code sent"
+// @Failure 400 {object} BadRequestResponse "Bad request or email already verified"
+// @Failure 404 {object} ErrorrResponse "User not found"
+// @Failure 429 {object} ErrorrResponse "Code sent less than a minute ago"
+// @Failure 500 {object} InternalServerErrorResponse "Internal server error"
+// @Router /api/v1/auth/resend-verification [post]
+func (h *Handler) ResendVerification(c *gin.Context) {
+	var req ResendVerificationRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.BindingErrorResponse(c, err)
+		return
+	}
+
+	account, code, err := h.service.ResendVerification(c.Request.Context(), req.Email)
+	if err != nil {
+		switch {
+		case errors.Is(err, ErrUserNotFound):
+			utils.ErrorResponse(c, 404, err.Error())
+		case errors.Is(err, ErrEmailVerified):
+			utils.ErrorResponse(c, 400, err.Error())
+		case errors.Is(err, ErrResendTooSoon):
+			utils.ErrorResponse(c, 429, err.Error())
+		default:
+			h.logger.Errorf("error resending verification to %s: %v", req.Email, err)
+			utils.ErrorResponse(c, 500, utils.SERVERERROR)
+		}
+		return
+	}
+
+	emailBody, _ := utils.RenderEmailTemplate("templates/auth/verify_email.html", map[string]any{
+		"Username": account.Username,
+		"Code":     code,
+	})
+	if err := h.emailer.SendEmail(account.Email, "Verify your Herp account", emailBody); err != nil {
+		log.Printf("error sending verification email: %v", err)
+		utils.ErrorResponse(c, 500, "Unable to send email at this time, try again later")
+		return
+	}
+	utils.SuccessResponse(c, 200, "Verification code sent", nil)
+}
+
+// Get Config godoc
+// @Summary Get effective configuration
+// @Description Get the configuration the server is running with, keyed by environment variable. Secrets that are set are shown as [REDACTED].
+// @Tags admin
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} map[string]any "Effective configuration"
+// @Failure 401 {object} UnauthorizedResponse "Unauthorized"
+// @Failure 403 {object} ErrorrResponse "Forbidden"
+// @Router /api/v1/admin/config [get]
+func (h *Handler) GetConfig(c *gin.Context) {
+	utils.SuccessResponse(c, 200, "effective configuration", h.config.Effective())
+}
+
 // Forgot Password godoc
 // @Summary Forgot Password
-// @Description Initiate password reset by sending a reset code to the user's email
+// @Description Initiate password reset by sending a reset code to the user's email. The response is the same whether or not the email belongs to an account, and an email gets at most one code a minute.
 // @Tags auth
 // @Accept json
 // @Produce json
 // @Param body body ForgotPasswordRequest true "Forgot Password Request"
-// @Success 200 "Reset code sent to email"
+// @Success 200 "Reset code sent if the account exists"
 // @Failure 400 {object} BadRequestResponse "Bad request"
-// @Failure 404 {object} UnauthorizedResponse "User not found"
-// @Failure 500 {object} InternalServerErrorResponse "Internal server error"
 // @Router /api/v1/auth/forgot-password [post]
 func (h *Handler) ForgotPassword(c *gin.Context) {
 	var req ForgotPasswordRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
-		utils.ErrorResponse(c, 400, err.Error())
+		utils.BindingErrorResponse(c, err)
 		return
 	}
+
+	// whatever happens the answer is the same, so it can't be used to find
+	// out which emails have an account
+	const message = "If an account exists for this email, a reset code has been sent"
+
 	code, err := h.service.ForgotPassword(c.Request.Context(), req.Email)
 	if err != nil {
-		utils.ErrorResponse(c, 404, err.Error())
+		switch {
+		case errors.Is(err, ErrUserNotFound), errors.Is(err, ErrUserInactive), errors.Is(err, ErrResendTooSoon):
+			h.logger.Printf("no reset code sent to %s: %v", req.Email, err)
+		default:
+			h.logger.Errorf("error creating reset code for %s: %v", req.Email, err)
+		}
+		utils.SuccessResponse(c, 200, message, nil)
 		return
 	}
 	// Send verification email
 	emailBody, _ := utils.RenderEmailTemplate("templates/auth/forgot_password.html", map[string]any{
 		"Code": code,
 	})
-	plunk := utils.Plunk{HttpClient: http.DefaultClient, Config: h.config}
-	err = plunk.SendEmail(req.Email, "Reset your password", emailBody)
+	err = h.emailer.SendEmail(req.Email, "Reset your password", emailBody)
 	if err != nil {
-		log.Printf("error sending verification email: %v", err)
-		utils.ErrorResponse(c, 500, err.Error())
-		return
+		h.logger.Errorf("error sending reset email to %s: %v", req.Email, err)
 	}
-	utils.SuccessResponse(c, 200, "Reset code sent to email", nil)
+	utils.SuccessResponse(c, 200, message, nil)
 }
 
 // Reset Password godoc
@@ -407,13 +931,59 @@ func (h *Handler) ForgotPassword(c *gin.Context) {
 func (h *Handler) ResetPassword(c *gin.Context) {
 	var req ResetAdminPasswordRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
-		utils.ErrorResponse(c, 400, err.Error())
+		utils.BindingErrorResponse(c, err)
 		return
 	}
 	err := h.service.ResetAdminPassword(c.Request.Context(), req.Email, req.Code, req.NewPassword)
+	if passwordPolicyError(c, err) {
+		return
+	}
 	if err != nil {
 		utils.ErrorResponse(c, 400, err.Error())
 		return
 	}
 	utils.SuccessResponse(c, 200, "Password reset successful", nil)
 }
+
+// Accept Invite godoc
+// @Summary Accept Invite
+// @Description Set the password of an invited user with the token from their invite link and activate the account. A token works once.
+// @Tags auth
+// @Accept json
+// @Produce json
+// @Param body body AcceptInviteRequest true "Accept Invite Request"
+// @Success 200 "Invite accepted"
+// @Failure 400 {object} BadRequestResponse "Bad request, or invalid, used or expired invite"
+// @Failure 500 {object} InternalServerErrorResponse "Internal server error"
+// @Router /api/v1/auth/accept-invite [post]
+func (h *Handler) AcceptInvite(c *gin.Context) {
+	var req AcceptInviteRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.BindingErrorResponse(c, err)
+		return
+	}
+	err := h.service.AcceptInvite(c.Request.Context(), req.Token, req.NewPassword)
+	if passwordPolicyError(c, err) {
+		return
+	}
+	if err != nil {
+		if errors.Is(err, ErrInvalidInvite) {
+			utils.ErrorResponse(c, 400, err.Error())
+			return
+		}
+		utils.ErrorResponse(c, 500, err.Error())
+		return
+	}
+	utils.SuccessResponse(c, 200, "Invite accepted, you can now log in", nil)
+}
+
+// passwordPolicyError responds with the password rules that were not met when
+// err is a *password.PolicyError and reports whether it did.
+func passwordPolicyError(c *gin.Context, err error) bool {
+	var policyErr *password.PolicyError
+	if !errors.As(err, &policyErr) {
+		return false
+	}
+	utils.ErrorResponseWithData(c, 400, policyErr.Error(), gin.H{"unmet_requirements": policyErr.Unmet})
+	return true
+}
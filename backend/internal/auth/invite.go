@@ -0,0 +1,131 @@
+package auth
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	db "herp/db/sqlc"
+	"time"
+)
+
+// inviteTTL is how long an invited user has to set their password.
+const inviteTTL = 72 * time.Hour
+
+var ErrInvalidInvite = errors.New("invalid or expired invite")
+
+// InviteUser creates an inactive user without a password, and an invite the
+// user accepts by setting one. It returns the user and the invite token to
+// send them, only a hash of the token is stored.
+func (s *Service) InviteUser(ctx context.Context, params db.CreateUserParams) (db.User, string, error) {
+	var err error
+	params.BranchID, err = s.resolveStoreAssignment(ctx, params.BranchID, params.StoreID)
+	if err != nil {
+		return db.User{}, "", err
+	}
+	// no bcrypt hash is empty, so no password matches it
+	params.PasswordHash = ""
+	params.IsActive = sql.NullBool{Bool: false, Valid: true}
+
+	token, err := generateRefreshToken()
+	if err != nil {
+		return db.User{}, "", err
+	}
+
+	q, ok := s.queries.(*db.Queries)
+	if !ok {
+		return db.User{}, "", fmt.Errorf("invalid queries implementation")
+	}
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return db.User{}, "", err
+	}
+	defer tx.Rollback()
+
+	txQueries := q.WithTx(tx)
+
+	user, err := txQueries.CreateUser(ctx, params)
+	if err != nil {
+		return db.User{}, "", createUserError(err)
+	}
+	err = txQueries.CreateUserInvite(ctx, db.CreateUserInviteParams{
+		UserID:    user.ID,
+		Token:     s.hashCode(token),
+		ExpiresAt: time.Now().Add(inviteTTL),
+	})
+	if err != nil {
+		return db.User{}, "", err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return db.User{}, "", err
+	}
+	return user, token, nil
+}
+
+// AcceptInvite sets the password of an invited user and activates the
+// account. The invite is deleted with it, so a token works once.
+func (s *Service) AcceptInvite(ctx context.Context, token, newPassword string) error {
+	if err := s.passwordPolicy.Validate(newPassword); err != nil {
+		return err
+	}
+
+	invite, err := s.queries.GetUserInviteByToken(ctx, s.hashCode(token))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return ErrInvalidInvite
+		}
+		return err
+	}
+	if invite.ExpiresAt.Before(time.Now()) {
+		return ErrInvalidInvite
+	}
+
+	hashed, err := s.hashPassword(newPassword)
+	if err != nil {
+		return err
+	}
+
+	q, ok := s.queries.(*db.Queries)
+	if !ok {
+		return fmt.Errorf("invalid queries implementation")
+	}
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	txQueries := q.WithTx(tx)
+
+	// deleting the invite first settles two requests accepting it at once
+	deleted, err := txQueries.DeleteUserInvite(ctx, invite.UserID)
+	if err != nil {
+		return err
+	}
+	if deleted == 0 {
+		return ErrInvalidInvite
+	}
+	err = txQueries.UpdateUserPassword(ctx, db.UpdateUserPasswordParams{
+		ID:           invite.UserID,
+		PasswordHash: string(hashed),
+	})
+	if err != nil {
+		return err
+	}
+	err = txQueries.UpdateUserStatus(ctx, db.UpdateUserStatusParams{
+		ID:       invite.UserID,
+		IsActive: sql.NullBool{Bool: true, Valid: true},
+	})
+	if err != nil {
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+	s.recordPasswordHistory(ctx, sql.NullInt32{Int32: invite.UserID, Valid: true}, sql.NullInt32{}, string(hashed))
+	return nil
+}
@@ -0,0 +1,56 @@
+package auth
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAcceptInvite(t *testing.T) {
+	s, _ := newStoredService(t)
+	ctx := context.Background()
+
+	user, token, err := s.InviteUser(ctx, newUser("ada"))
+	require.NoError(t, err)
+	assert.False(t, user.IsActive.Bool)
+	assert.NotEmpty(t, token)
+
+	// no password yet, nothing logs in
+	_, _, err = s.Login(ctx, "ada", "", "10.0.0.1", "test")
+	assert.Error(t, err)
+
+	require.NoError(t, s.AcceptInvite(ctx, token, "Password1"))
+	_, _, err = s.Login(ctx, "ada", "Password1", "10.0.0.1", "test")
+	assert.NoError(t, err)
+}
+
+func TestAcceptInviteReused(t *testing.T) {
+	s, _ := newStoredService(t)
+	ctx := context.Background()
+	_, token, err := s.InviteUser(ctx, newUser("ada"))
+	require.NoError(t, err)
+
+	require.NoError(t, s.AcceptInvite(ctx, token, "Password1"))
+	assert.ErrorIs(t, s.AcceptInvite(ctx, token, "Password2"), ErrInvalidInvite)
+
+	// the first password stays
+	_, _, err = s.Login(ctx, "ada", "Password1", "10.0.0.1", "test")
+	assert.NoError(t, err)
+}
+
+func TestAcceptInviteExpired(t *testing.T) {
+	s, conn := newStoredService(t)
+	ctx := context.Background()
+	user, token, err := s.InviteUser(ctx, newUser("ada"))
+	require.NoError(t, err)
+	_, err = conn.Exec(`UPDATE user_invite SET expires_at = '2000-01-01' WHERE user_id = $1`, user.ID)
+	require.NoError(t, err)
+
+	assert.ErrorIs(t, s.AcceptInvite(ctx, token, "Password1"), ErrInvalidInvite)
+	_, _, err = s.Login(ctx, "ada", "Password1", "10.0.0.1", "test")
+	assert.Error(t, err, "still inactive")
+
+	assert.ErrorIs(t, s.AcceptInvite(ctx, "not-a-token", "Password1"), ErrInvalidInvite)
+}
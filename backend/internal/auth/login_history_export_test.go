@@ -0,0 +1,90 @@
+package auth
+
+import (
+	"database/sql"
+	"encoding/csv"
+	"herp/db/dbtest"
+	"herp/internal/config"
+	"herp/pkg/monitoring/logging"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func loginAttempt(t *testing.T, conn *sql.DB, login string, success bool, userAgent string) {
+	t.Helper()
+	dbtest.Exec(t, conn, `
+		INSERT INTO login_history (username_or_email, login_time, ip_address, user_agent, success, error_reason)
+		VALUES ($1, '2026-03-01 10:00', '10.0.0.1', $2, $3, CASE WHEN $3 THEN 'success' ELSE 'ErrInvalidCredentials' END)`,
+		login, userAgent, success)
+}
+
+// exportLoginHistory downloads the login history of 2026-03-01 and returns
+// its rows, header first.
+func exportLoginHistory(t *testing.T, s *Service, query string) [][]string {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+	cfg := &config.Config{}
+	h := NewAdminHandler(s, cfg, logging.NewLogger(cfg), nil)
+	r := gin.New()
+	r.GET("/login-history/export", h.ExportLoginHistory)
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/login-history/export?start=2026-03-01&end=2026-03-01"+query, nil))
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	assert.Equal(t, "text/csv", w.Header().Get("Content-Type"))
+	assert.Equal(t, `attachment; filename="login-history-20260301-20260302.csv"`, w.Header().Get("Content-Disposition"))
+	rows, err := csv.NewReader(strings.NewReader(w.Body.String())).ReadAll()
+	require.NoError(t, err)
+	return rows
+}
+
+func TestExportLoginHistory(t *testing.T) {
+	s, conn := newStoredService(t)
+	storedUser(t, conn, "ada", "Password1")
+	loginAttempt(t, conn, "ada", true, "Mozilla/5.0")
+	loginAttempt(t, conn, "ada@example.com", false, "=HYPERLINK(\"http://evil\")")
+	loginAttempt(t, conn, "nobody", false, "curl/8.0")
+
+	rows := exportLoginHistory(t, s, "")
+	require.Len(t, rows, 4)
+	assert.Equal(t, []string{"username", "email", "ip_address", "user_agent", "success", "error_reason", "timestamp"}, rows[0])
+	assert.Equal(t, []string{"ada", "ada@example.com", "10.0.0.1", "Mozilla/5.0", "true", "success", "2026-03-01T10:00:00Z"}, rows[1])
+	// resolved by email, and the user agent can't run as a formula
+	assert.Equal(t, "ada", rows[2][0])
+	assert.Equal(t, "'=HYPERLINK(\"http://evil\")", rows[2][3])
+	assert.Equal(t, []string{"nobody", ""}, rows[3][:2])
+
+	rows = exportLoginHistory(t, s, "&success=true")
+	require.Len(t, rows, 2)
+	assert.Equal(t, "true", rows[1][4])
+
+	rows = exportLoginHistory(t, s, "&success=false")
+	require.Len(t, rows, 3)
+	assert.Equal(t, "false", rows[1][4])
+	assert.Equal(t, "false", rows[2][4])
+}
+
+func TestExportLoginHistoryMatchedTwice(t *testing.T) {
+	s, conn := newStoredService(t)
+	// one user's username is another user's email
+	storedUser(t, conn, "ada", "Password1")
+	storedUser(t, conn, "ada@example.com", "Password1")
+	dbtest.Exec(t, conn, `
+		INSERT INTO login_history (username_or_email, login_time, success)
+		SELECT 'ada@example.com', '2026-03-01 10:00', true FROM generate_series(1, $1::int)`,
+		loginHistoryExportBatch+10)
+
+	rows := exportLoginHistory(t, s, "")
+	// every attempt once, across batches
+	require.Len(t, rows, loginHistoryExportBatch+11)
+	for _, row := range rows[1:] {
+		// Login tries the email first
+		require.Equal(t, "ada", row[0])
+	}
+}
@@ -2,8 +2,10 @@ package auth
 
 import (
 	"errors"
+	"herp/internal/utils"
 	"herp/pkg/jwt"
 	"net/http"
+	"slices"
 	"strings"
 
 	"github.com/gin-gonic/gin"
@@ -14,40 +16,58 @@ const (
 	BearerPrefix        = "Bearer "
 )
 
+// Codes sent with a 401 so clients can tell an expired token, which can be
+// refreshed, from one that needs a new login.
+const (
+	TokenExpiredCode = "TOKEN_EXPIRED"
+	TokenInvalidCode = "TOKEN_INVALID"
+)
+
 var (
 	ErrNoAuthHeader      = errors.New("authorization header is missing")
 	ErrInvalidAuthHeader = errors.New("invalid authorization header format")
 	ErrInvalidToken      = errors.New("invalid token")
+	ErrExpiredToken      = errors.New("token has expired")
 )
 
 func AuthMiiddleware(authSvc *Service) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		authHeader := c.GetHeader(AuthorizationHeader)
 		if authHeader == "" {
-			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": ErrInvalidAuthHeader.Error()})
+			utils.ErrorResponse(c, http.StatusUnauthorized, ErrInvalidAuthHeader.Error())
+			c.Abort()
 			return
 		} 
 
 		if !strings.HasPrefix(authHeader, BearerPrefix) {
-			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": ErrInvalidAuthHeader.Error()})
+			utils.ErrorResponse(c, http.StatusUnauthorized, ErrInvalidAuthHeader.Error())
+			c.Abort()
 			return
 		}
 
 		token := strings.TrimPrefix(authHeader, BearerPrefix)
-		claims, err := jwt.ParseToken(token, authSvc.jwtSecret)
+		claims, err := authSvc.ParseToken(token)
 		if err != nil {
-			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": ErrInvalidToken.Error()})
+			if errors.Is(err, jwt.ErrTokenExpired) {
+				utils.ErrorResponseWithCode(c, http.StatusUnauthorized, ErrExpiredToken.Error(), TokenExpiredCode)
+				c.Abort()
+				return
+			}
+			utils.ErrorResponseWithCode(c, http.StatusUnauthorized, ErrInvalidToken.Error(), TokenInvalidCode)
+			c.Abort()
 			return
 		}
 
 		// check blacklist
 		blacklisted, err := authSvc.IsTokenBlacklisted(c.Request.Context(), token)
 		if err != nil {
-			c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			utils.ErrorResponse(c, http.StatusInternalServerError, utils.SERVERERROR)
+			c.Abort()
 			return
 		}
 		if blacklisted {
-			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": ErrInvalidToken.Error()})
+			utils.ErrorResponseWithCode(c, http.StatusUnauthorized, ErrInvalidToken.Error(), TokenInvalidCode)
+			c.Abort()
 			return
 		}
 
@@ -60,18 +80,62 @@ func PermissionMiddleware(authSvc *Service, permission string) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		claims, exists := c.Get("claims")
 		if !exists {
-			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "unauthorized to make this request"})
+			utils.ErrorResponse(c, http.StatusUnauthorized, "unauthorized to make this request")
+			c.Abort()
 			return
 		}
 
 		jwtClaims, ok := claims.(*jwt.Claims)
 		if !ok {
-			c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": "invalid claim type"})
+			utils.ErrorResponse(c, http.StatusInternalServerError, "invalid claim type")
+			c.Abort()
 			return
 		}
 
 		if !authSvc.HasPermission(jwtClaims, permission) {
-			c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "insufficient permissions"})
+			utils.ErrorResponse(c, http.StatusForbidden, "insufficient permissions")
+			c.Abort()
+			return
+		}
+		c.Next()
+	}
+}
+
+// PermissionMiddlewareStrict is PermissionMiddleware for sensitive routes, it
+// checks the permissions the caller holds now instead of trusting the token,
+// so a revoked permission takes effect before the token expires. It costs a
+// cache or database lookup per request.
+func PermissionMiddlewareStrict(authSvc *Service, permission string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		claims, exists := c.Get("claims")
+		if !exists {
+			utils.ErrorResponse(c, http.StatusUnauthorized, "unauthorized to make this request")
+			c.Abort()
+			return
+		}
+
+		jwtClaims, ok := claims.(*jwt.Claims)
+		if !ok {
+			utils.ErrorResponse(c, http.StatusInternalServerError, "invalid claim type")
+			c.Abort()
+			return
+		}
+
+		permissions, err := authSvc.CurrentPermissions(c.Request.Context(), jwtClaims)
+		if err != nil {
+			if errors.Is(err, ErrUserNotFound) || errors.Is(err, ErrUserInactive) {
+				utils.ErrorResponseWithCode(c, http.StatusUnauthorized, ErrInvalidToken.Error(), TokenInvalidCode)
+				c.Abort()
+				return
+			}
+			utils.ErrorResponse(c, http.StatusInternalServerError, utils.SERVERERROR)
+			c.Abort()
+			return
+		}
+
+		if !slices.Contains(permissions, permission) {
+			utils.ErrorResponse(c, http.StatusForbidden, "insufficient permissions")
+			c.Abort()
 			return
 		}
 		c.Next()
@@ -0,0 +1,94 @@
+package auth
+
+import (
+	"encoding/json"
+	"herp/pkg/jwt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func testKeys() *jwt.KeySet {
+	return jwt.NewKeySet("k1", "0123456789abcdef0123456789abcdef", nil)
+}
+
+// authenticate runs a request with the given Authorization header through
+// AuthMiiddleware and returns the answer.
+func authenticate(t *testing.T, svc *Service, header string) (int, map[string]string) {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.GET("/me", AuthMiiddleware(svc), func(c *gin.Context) {
+		c.Status(http.StatusNoContent)
+	})
+
+	w := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodGet, "/me", nil)
+	if header != "" {
+		req.Header.Set(AuthorizationHeader, header)
+	}
+	r.ServeHTTP(w, req)
+
+	body := map[string]string{}
+	if w.Body.Len() > 0 {
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &body))
+	}
+	return w.Code, body
+}
+
+func TestAuthMiddlewareTokenErrors(t *testing.T) {
+	svc := &Service{jwtKeys: testKeys()}
+
+	expired, err := jwt.GenerateToken(1, "admin", "admin@example.com", "admin", svc.jwtKeys, nil, jwt.AccessToken, -time.Minute, 0)
+	require.NoError(t, err)
+
+	valid, err := jwt.GenerateToken(1, "admin", "admin@example.com", "admin", svc.jwtKeys, nil, jwt.AccessToken, time.Hour, 0)
+	require.NoError(t, err)
+	parts := strings.Split(valid, ".")
+	require.Len(t, parts, 3)
+	// the same signature over claims naming another user
+	forged, err := jwt.GenerateToken(2, "other", "other@example.com", "admin", svc.jwtKeys, nil, jwt.AccessToken, time.Hour, 0)
+	require.NoError(t, err)
+	tampered := strings.Join([]string{parts[0], strings.Split(forged, ".")[1], parts[2]}, ".")
+
+	otherKey, err := jwt.GenerateToken(1, "admin", "admin@example.com", "admin", jwt.NewKeySet("k1", "another secret of 32 characters!", nil), nil, jwt.AccessToken, time.Hour, 0)
+	require.NoError(t, err)
+
+	tests := []struct {
+		name     string
+		token    string
+		wantCode string
+		wantErr  string
+	}{
+		{"expired", expired, TokenExpiredCode, ErrExpiredToken.Error()},
+		{"tampered claims", tampered, TokenInvalidCode, ErrInvalidToken.Error()},
+		{"signed with another key", otherKey, TokenInvalidCode, ErrInvalidToken.Error()},
+		{"malformed", "not.a.token", TokenInvalidCode, ErrInvalidToken.Error()},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			status, body := authenticate(t, svc, BearerPrefix+tt.token)
+			assert.Equal(t, http.StatusUnauthorized, status)
+			assert.Equal(t, "error", body["status"])
+			assert.Equal(t, tt.wantCode, body["code"])
+			assert.Equal(t, tt.wantErr, body["error"])
+		})
+	}
+}
+
+func TestAuthMiddlewareHeader(t *testing.T) {
+	svc := &Service{jwtKeys: testKeys()}
+
+	for _, header := range []string{"", "Token abc"} {
+		status, body := authenticate(t, svc, header)
+		assert.Equal(t, http.StatusUnauthorized, status)
+		assert.Equal(t, "error", body["status"])
+		assert.Equal(t, ErrInvalidAuthHeader.Error(), body["error"])
+	}
+}
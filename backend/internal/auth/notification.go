@@ -0,0 +1,163 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	db "herp/db/sqlc"
+	"slices"
+)
+
+// Events a user can be notified about.
+const (
+	NotifyLowStock  = "low_stock"
+	NotifyLargeSale = "large_sale"
+	NotifySecurity  = "security"
+)
+
+// Channels notifications are delivered over.
+const (
+	ChannelEmail = "email"
+	ChannelInApp = "in_app"
+)
+
+var NotificationEvents = []string{NotifyLowStock, NotifyLargeSale, NotifySecurity}
+
+var (
+	ErrUnknownNotificationEvent   = errors.New("unknown notification event")
+	ErrUnknownNotificationChannel = errors.New("unknown notification channel")
+)
+
+// NotificationPreference is whether a user receives an event by email and in
+// the app. Default is set when the user never changed it and it comes from
+// their role.
+type NotificationPreference struct {
+	Event   string
+	Email   bool
+	InApp   bool
+	Default bool
+}
+
+// defaultNotificationPreference is what a role gets until the user picks
+// otherwise. Security notices go to everyone, managers and admins also hear
+// about stock and large sales, other staff only see low stock in the app.
+func defaultNotificationPreference(role, event string) NotificationPreference {
+	pref := NotificationPreference{Event: event, Default: true}
+	switch {
+	case event == NotifySecurity, role == "admin", role == "manager":
+		pref.Email, pref.InApp = true, true
+	case event == NotifyLowStock:
+		pref.InApp = true
+	}
+	return pref
+}
+
+// GetNotificationPreferences returns the preferences of a user for every
+// event, falling back to their role's defaults for events they never set.
+func (s *Service) GetNotificationPreferences(ctx context.Context, userID int32) ([]NotificationPreference, error) {
+	user, err := s.queries.GetUserByID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	stored, err := s.queries.ListNotificationPreferences(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	return mergeNotificationPreferences(user.RoleName, stored), nil
+}
+
+// UpdateNotificationPreferences saves the given preferences of a user, events
+// left out keep what they had, and returns the preferences for every event.
+func (s *Service) UpdateNotificationPreferences(ctx context.Context, userID int32, prefs []NotificationPreference) ([]NotificationPreference, error) {
+	for _, pref := range prefs {
+		if !slices.Contains(NotificationEvents, pref.Event) {
+			return nil, fmt.Errorf("%w: %s", ErrUnknownNotificationEvent, pref.Event)
+		}
+	}
+
+	user, err := s.queries.GetUserByID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	q, ok := s.queries.(*db.Queries)
+	if !ok {
+		return nil, fmt.Errorf("invalid queries implementation")
+	}
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	txQueries := q.WithTx(tx)
+
+	for _, pref := range prefs {
+		if _, err := txQueries.UpsertNotificationPreference(ctx, db.UpsertNotificationPreferenceParams{
+			UserID: userID,
+			Event:  pref.Event,
+			Email:  pref.Email,
+			InApp:  pref.InApp,
+		}); err != nil {
+			return nil, err
+		}
+	}
+
+	stored, err := txQueries.ListNotificationPreferences(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+
+	return mergeNotificationPreferences(user.RoleName, stored), nil
+}
+
+// NotificationEnabled reports whether a user wants to receive an event over a
+// channel. Notification producers call it for each recipient before sending.
+func (s *Service) NotificationEnabled(ctx context.Context, userID int32, event, channel string) (bool, error) {
+	if !slices.Contains(NotificationEvents, event) {
+		return false, fmt.Errorf("%w: %s", ErrUnknownNotificationEvent, event)
+	}
+
+	prefs, err := s.GetNotificationPreferences(ctx, userID)
+	if err != nil {
+		return false, err
+	}
+
+	for _, pref := range prefs {
+		if pref.Event != event {
+			continue
+		}
+		switch channel {
+		case ChannelEmail:
+			return pref.Email, nil
+		case ChannelInApp:
+			return pref.InApp, nil
+		}
+	}
+
+	return false, fmt.Errorf("%w: %s", ErrUnknownNotificationChannel, channel)
+}
+
+func mergeNotificationPreferences(role string, stored []db.NotificationPreference) []NotificationPreference {
+	byEvent := make(map[string]db.NotificationPreference, len(stored))
+	for _, pref := range stored {
+		byEvent[pref.Event] = pref
+	}
+
+	prefs := make([]NotificationPreference, 0, len(NotificationEvents))
+	for _, event := range NotificationEvents {
+		if pref, ok := byEvent[event]; ok {
+			prefs = append(prefs, NotificationPreference{Event: event, Email: pref.Email, InApp: pref.InApp})
+			continue
+		}
+		prefs = append(prefs, defaultNotificationPreference(role, event))
+	}
+	return prefs
+}
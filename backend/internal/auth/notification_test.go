@@ -0,0 +1,81 @@
+package auth
+
+import (
+	"context"
+	db "herp/db/sqlc"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNotificationPreferenceDefaults(t *testing.T) {
+	q := newSeededQuerier()
+	manager := q.addUser("manager", "manager")
+	cashier := q.addUser("cashier", "cashier")
+	s := &Service{queries: q}
+
+	prefs, err := s.GetNotificationPreferences(context.Background(), manager.ID)
+	require.NoError(t, err)
+	assert.Equal(t, []NotificationPreference{
+		{Event: NotifyLowStock, Email: true, InApp: true, Default: true},
+		{Event: NotifyLargeSale, Email: true, InApp: true, Default: true},
+		{Event: NotifySecurity, Email: true, InApp: true, Default: true},
+	}, prefs)
+
+	prefs, err = s.GetNotificationPreferences(context.Background(), cashier.ID)
+	require.NoError(t, err)
+	assert.Equal(t, []NotificationPreference{
+		{Event: NotifyLowStock, Email: false, InApp: true, Default: true},
+		{Event: NotifyLargeSale, Email: false, InApp: false, Default: true},
+		{Event: NotifySecurity, Email: true, InApp: true, Default: true},
+	}, prefs)
+}
+
+func TestNotificationEnabled(t *testing.T) {
+	q := newSeededQuerier()
+	manager := q.addUser("manager", "manager")
+	s := &Service{queries: q}
+	ctx := context.Background()
+
+	enabled, err := s.NotificationEnabled(ctx, manager.ID, NotifyLargeSale, ChannelEmail)
+	require.NoError(t, err)
+	assert.True(t, enabled)
+
+	// the manager turned large sale emails off, they still see them in the app
+	q.notificationPreferences[manager.ID] = []db.NotificationPreference{
+		{UserID: manager.ID, Event: NotifyLargeSale, Email: false, InApp: true},
+	}
+
+	enabled, err = s.NotificationEnabled(ctx, manager.ID, NotifyLargeSale, ChannelEmail)
+	require.NoError(t, err)
+	assert.False(t, enabled, "a disabled channel is not sent to")
+
+	enabled, err = s.NotificationEnabled(ctx, manager.ID, NotifyLargeSale, ChannelInApp)
+	require.NoError(t, err)
+	assert.True(t, enabled)
+
+	// other events keep the role default
+	enabled, err = s.NotificationEnabled(ctx, manager.ID, NotifyLowStock, ChannelEmail)
+	require.NoError(t, err)
+	assert.True(t, enabled)
+
+	prefs, err := s.GetNotificationPreferences(ctx, manager.ID)
+	require.NoError(t, err)
+	assert.Equal(t, NotificationPreference{Event: NotifyLargeSale, Email: false, InApp: true}, prefs[1])
+}
+
+func TestNotificationEnabledUnknown(t *testing.T) {
+	q := newSeededQuerier()
+	manager := q.addUser("manager", "manager")
+	s := &Service{queries: q}
+
+	_, err := s.NotificationEnabled(context.Background(), manager.ID, "birthday", ChannelEmail)
+	assert.ErrorIs(t, err, ErrUnknownNotificationEvent)
+
+	_, err = s.NotificationEnabled(context.Background(), manager.ID, NotifySecurity, "sms")
+	assert.ErrorIs(t, err, ErrUnknownNotificationChannel)
+
+	_, err = s.UpdateNotificationPreferences(context.Background(), manager.ID, []NotificationPreference{{Event: "birthday"}})
+	assert.ErrorIs(t, err, ErrUnknownNotificationEvent)
+}
@@ -0,0 +1,21 @@
+package auth
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCodeMatches(t *testing.T) {
+	s := &Service{jwtRefreshSecret: "secret"}
+	stored := s.hashCode("123456")
+
+	assert.NotContains(t, stored, "123456")
+	assert.True(t, s.codeMatches(stored, "123456"))
+	assert.False(t, s.codeMatches(stored, "123457"))
+	assert.False(t, s.codeMatches(stored, ""))
+
+	// a code hashed under another secret doesn't match
+	other := &Service{jwtRefreshSecret: "other"}
+	assert.False(t, other.codeMatches(stored, "123456"))
+}
@@ -0,0 +1,136 @@
+package auth
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"herp/pkg/jwt"
+	"time"
+)
+
+// userPermissionsTTL bounds how long a permission set is served from cache
+// when an invalidation was missed, e.g. redis was briefly unreachable.
+const userPermissionsTTL = 30 * time.Minute
+
+func userPermissionsKey(userID int32) string {
+	return fmt.Sprintf("user_permissions:%d", userID)
+}
+
+// getUserPermissions returns the permission names of a user, cached per user.
+// The cache is dropped whenever the user's role or the role's permissions
+// change, see invalidateRoleUsers.
+func (s *Service) getUserPermissions(ctx context.Context, userID int32) ([]string, error) {
+	cacheKey := userPermissionsKey(userID)
+	if cached, err := s.redis.Get(ctx, cacheKey); err == nil {
+		var permissions []string
+		if err := json.Unmarshal([]byte(cached), &permissions); err == nil {
+			return permissions, nil
+		}
+	}
+
+	permissions, err := s.queries.GetUserPermissions(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	jsonPermissions, _ := json.Marshal(permissions)
+	s.redis.Set(ctx, cacheKey, jsonPermissions, userPermissionsTTL)
+	return permissions, nil
+}
+
+// invalidateRoleUsers drops the cached permissions and profile of every user
+// holding a role. Tokens already issued keep their permissions until they
+// expire, the next refresh picks up the new set.
+func (s *Service) invalidateRoleUsers(ctx context.Context, roleID int32) {
+	userIDs, err := s.queries.ListUserIDsByRole(ctx, sql.NullInt32{Int32: roleID, Valid: true})
+	if err != nil {
+		s.logger.Warnf("failed to list users of role %d for cache invalidation: %v", roleID, err)
+		return
+	}
+	if len(userIDs) == 0 {
+		return
+	}
+	keys := make([]string, 0, 2*len(userIDs))
+	for _, id := range userIDs {
+		keys = append(keys, fmt.Sprintf("user:%d", id), userPermissionsKey(id))
+	}
+	if err := s.redis.Delete(ctx, keys...); err != nil {
+		s.logger.Warnf("failed to invalidate permission cache of role %d: %v", roleID, err)
+	}
+}
+
+// CurrentPermissions returns the permissions the owner of claims holds right
+// now rather than the ones baked into the token. Users and admins are told
+// apart the way Me does it, by id and username. A deleted or deactivated
+// account is reported as ErrUserNotFound or ErrUserInactive.
+func (s *Service) CurrentPermissions(ctx context.Context, claims *jwt.Claims) ([]string, error) {
+	user, err := s.GetUserByID(ctx, int32(claims.UserID))
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return nil, err
+	}
+	if err == nil && user.Username == claims.Username {
+		if !user.IsActive.Bool {
+			return nil, ErrUserInactive
+		}
+		return s.getUserPermissions(ctx, user.ID)
+	}
+
+	admin, err := s.queries.GetAdminByUsername(ctx, claims.Username)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrUserNotFound
+		}
+		return nil, err
+	}
+	if admin.ID != int32(claims.UserID) {
+		return nil, ErrUserNotFound
+	}
+	if !admin.IsActive {
+		return nil, ErrUserInactive
+	}
+	return s.queries.GetAdminPermissions(ctx, admin.ID)
+}
+
+// EffectivePermissions are the permissions a user holds through their role.
+type EffectivePermissions struct {
+	UserID      int32    `json:"user_id"`
+	Username    string   `json:"username"`
+	IsActive    bool     `json:"is_active"`
+	RoleID      *int32   `json:"role_id"`
+	RoleName    string   `json:"role_name"`
+	Permissions []string `json:"permissions"`
+}
+
+// UserEffectivePermissions resolves the permissions of a user from the
+// database, bypassing the cache, so they show what the next token or cache
+// refresh will carry.
+func (s *Service) UserEffectivePermissions(ctx context.Context, userID int32) (EffectivePermissions, error) {
+	user, err := s.queries.GetUserByID(ctx, userID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return EffectivePermissions{}, ErrUserNotFound
+		}
+		return EffectivePermissions{}, err
+	}
+	permissions, err := s.queries.GetUserPermissions(ctx, user.ID)
+	if err != nil {
+		return EffectivePermissions{}, err
+	}
+	if permissions == nil {
+		permissions = []string{}
+	}
+
+	effective := EffectivePermissions{
+		UserID:      user.ID,
+		Username:    user.Username,
+		IsActive:    user.IsActive.Bool,
+		RoleName:    user.RoleName,
+		Permissions: permissions,
+	}
+	if user.RoleID.Valid {
+		effective.RoleID = &user.RoleID.Int32
+	}
+	return effective, nil
+}
@@ -0,0 +1,107 @@
+package auth
+
+import (
+	"context"
+	"database/sql"
+	db "herp/db/sqlc"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// storedCashier creates a user holding role.
+func storedCashier(t *testing.T, conn *sql.DB, role int32) int32 {
+	t.Helper()
+	id := storedUser(t, conn, "cashier", "Password1")
+	_, err := conn.Exec(`UPDATE users SET role_id = $1 WHERE id = $2`, role, id)
+	require.NoError(t, err)
+	return id
+}
+
+func TestPermissionCacheInvalidation(t *testing.T) {
+	s, conn := newStoredService(t)
+	ctx := context.Background()
+	view := storedPermission(t, conn, "sales:view")
+	refund := storedPermission(t, conn, "sales:refund")
+	role := storedRole(t, conn, "till", view, refund)
+	user := storedCashier(t, conn, role)
+
+	permissions, err := s.getUserPermissions(ctx, user)
+	require.NoError(t, err)
+	assert.ElementsMatch(t, []string{"sales:view", "sales:refund"}, permissions)
+
+	require.NoError(t, s.RemovePermissionFromRole(ctx, db.RemovePermissionFromRoleParams{RoleID: role, PermissionID: refund}))
+	permissions, err = s.getUserPermissions(ctx, user)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"sales:view"}, permissions)
+}
+
+func TestPermissionCacheRoleChange(t *testing.T) {
+	s, conn := newStoredService(t)
+	ctx := context.Background()
+	view := storedPermission(t, conn, "sales:view")
+	refund := storedPermission(t, conn, "sales:refund")
+	manager := storedRole(t, conn, "manager", view, refund)
+	cashier := storedRole(t, conn, "till", view)
+	user := storedCashier(t, conn, manager)
+
+	permissions, err := s.getUserPermissions(ctx, user)
+	require.NoError(t, err)
+	assert.ElementsMatch(t, []string{"sales:view", "sales:refund"}, permissions)
+
+	// demoted
+	_, err = s.UpdateUser(ctx, db.UpdateUserParams{ID: user, RoleID: sql.NullInt32{Int32: cashier, Valid: true}})
+	require.NoError(t, err)
+	permissions, err = s.getUserPermissions(ctx, user)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"sales:view"}, permissions)
+}
+
+// guarded serves a route behind the token check and the permission check,
+// for the Authorization header given.
+func guarded(s *Service, check gin.HandlerFunc, token string) int {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.GET("/refunds", AuthMiiddleware(s), check, func(c *gin.Context) {
+		c.Status(http.StatusNoContent)
+	})
+	w := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodGet, "/refunds", nil)
+	req.Header.Set(AuthorizationHeader, "Bearer "+token)
+	r.ServeHTTP(w, req)
+	return w.Code
+}
+
+func TestPermissionMiddlewareStrictRevoked(t *testing.T) {
+	s, conn := newStoredService(t)
+	ctx := context.Background()
+	view := storedPermission(t, conn, "pos:view")
+	refund := storedPermission(t, conn, "pos:refund")
+	role := storedRole(t, conn, "till", view, refund)
+	user := storedCashier(t, conn, role)
+
+	token, _, err := s.Login(ctx, "cashier", "Password1", "10.0.0.1", "test")
+	require.NoError(t, err)
+
+	fast := PermissionMiddleware(s, "pos:refund")
+	strict := PermissionMiddlewareStrict(s, "pos:refund")
+	assert.Equal(t, http.StatusNoContent, guarded(s, fast, token))
+	assert.Equal(t, http.StatusNoContent, guarded(s, strict, token))
+
+	require.NoError(t, s.RemovePermissionFromRole(ctx, db.RemovePermissionFromRoleParams{RoleID: role, PermissionID: refund}))
+
+	// the token still carries the permission, only the strict check sees
+	// it is gone
+	assert.Equal(t, http.StatusNoContent, guarded(s, fast, token))
+	assert.Equal(t, http.StatusForbidden, guarded(s, strict, token))
+	assert.Equal(t, http.StatusNoContent, guarded(s, PermissionMiddlewareStrict(s, "pos:view"), token))
+
+	// a deactivated account is turned away altogether
+	_, err = s.UpdateUser(ctx, db.UpdateUserParams{ID: user, IsActive: sql.NullBool{Bool: false, Valid: true}})
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusUnauthorized, guarded(s, PermissionMiddlewareStrict(s, "pos:view"), token))
+}
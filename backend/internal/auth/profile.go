@@ -0,0 +1,244 @@
+package auth
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	db "herp/db/sqlc"
+	"herp/internal/utils"
+	"herp/pkg/jwt"
+	"time"
+
+	"github.com/lib/pq"
+	"golang.org/x/crypto/bcrypt"
+)
+
+var (
+	ErrEmailTaken             = errors.New("email is already used by another account")
+	ErrInvalidCurrentPassword = errors.New("current password is incorrect")
+)
+
+// Profile is the account a token belongs to, as it is stored now rather than
+// when the token was issued.
+type Profile struct {
+	ID            int32
+	Username      string
+	Email         string
+	FirstName     string
+	LastName      string
+	RoleName      string
+	Permissions   []string
+	EmailVerified bool
+	IsAdmin       bool
+}
+
+// Me loads the account of the claims with its current role and permissions.
+// Users and admins are kept apart and their ids overlap, so the username in
+// the claims tells which one the token was issued for, users first like
+// Login does. Users are read through the GetUserByID cache.
+func (s *Service) Me(ctx context.Context, claims *jwt.Claims) (Profile, error) {
+	user, err := s.GetUserByID(ctx, int32(claims.UserID))
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return Profile{}, err
+	}
+	if err == nil && user.Username == claims.Username {
+		if !user.IsActive.Bool {
+			return Profile{}, ErrUserInactive
+		}
+		permissions, err := s.getUserPermissions(ctx, user.ID)
+		if err != nil {
+			return Profile{}, err
+		}
+		return Profile{
+			ID:            user.ID,
+			Username:      user.Username,
+			Email:         user.Email.String,
+			FirstName:     user.FirstName,
+			LastName:      user.LastName,
+			RoleName:      user.RoleName,
+			Permissions:   permissions,
+			EmailVerified: user.EmailVerified,
+		}, nil
+	}
+
+	admin, err := s.queries.GetAdminByUsername(ctx, claims.Username)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return Profile{}, ErrUserNotFound
+		}
+		return Profile{}, err
+	}
+	if admin.ID != int32(claims.UserID) {
+		return Profile{}, ErrUserNotFound
+	}
+	if !admin.IsActive {
+		return Profile{}, ErrUserInactive
+	}
+	permissions, err := s.queries.GetAdminPermissions(ctx, admin.ID)
+	if err != nil {
+		return Profile{}, err
+	}
+	return Profile{
+		ID:            admin.ID,
+		Username:      admin.Username,
+		Email:         admin.Email,
+		FirstName:     admin.FirstName,
+		LastName:      admin.LastName,
+		RoleName:      admin.RoleName,
+		Permissions:   permissions,
+		EmailVerified: admin.EmailVerified,
+		IsAdmin:       true,
+	}, nil
+}
+
+// UpdateProfileParams are the fields an account can change about itself, nil
+// leaves a field as it is. Role and active status are only changed by admins.
+type UpdateProfileParams struct {
+	FirstName *string
+	LastName  *string
+	Email     *string
+}
+
+// UpdateProfile changes the account of the claims and returns it as Me does.
+// An account that changes its email has to verify it again, the returned
+// code is to be sent to the new address.
+func (s *Service) UpdateProfile(ctx context.Context, claims *jwt.Claims, args UpdateProfileParams) (Profile, string, error) {
+	current, err := s.Me(ctx, claims)
+	if err != nil {
+		return Profile{}, "", err
+	}
+
+	var code string
+	if current.IsAdmin {
+		admin, err := s.queries.UpdateAdminProfile(ctx, db.UpdateAdminProfileParams{
+			FirstName: nullString(args.FirstName),
+			LastName:  nullString(args.LastName),
+			Email:     nullString(args.Email),
+			ID:        current.ID,
+		})
+		if err != nil {
+			return Profile{}, "", profileUpdateError(err)
+		}
+		if admin.Email != current.Email {
+			code = utils.GenerateOTP(s.otpLength)
+			if err := s.SetEmailVerification(ctx, admin.ID, code, time.Now().Add(10*time.Minute)); err != nil {
+				return Profile{}, "", err
+			}
+		}
+	} else {
+		user, err := s.UpdateUser(ctx, db.UpdateUserParams{
+			FirstName: nullString(args.FirstName),
+			LastName:  nullString(args.LastName),
+			Email:     nullString(args.Email),
+			ID:        current.ID,
+		})
+		if err != nil {
+			return Profile{}, "", profileUpdateError(err)
+		}
+		if user.Email.String != current.Email {
+			code = utils.GenerateOTP(s.otpLength)
+			if err := s.setUserEmailVerification(ctx, user.ID, code, time.Now().Add(10*time.Minute)); err != nil {
+				return Profile{}, "", err
+			}
+		}
+		// the lookups by email and username are cached with the old profile
+		s.redis.Delete(ctx, fmt.Sprintf("user:email:%s", current.Email))
+		s.redis.Delete(ctx, fmt.Sprintf("user_by_username:%s", current.Username))
+	}
+
+	updated, err := s.Me(ctx, claims)
+	if err != nil {
+		return Profile{}, "", err
+	}
+	return updated, code, nil
+}
+
+// ChangePassword sets a new password for the account of the claims once the
+// current one is confirmed. Every other session of the account is revoked,
+// the session the claims were issued with stays logged in. It returns how
+// many sessions were revoked.
+func (s *Service) ChangePassword(ctx context.Context, claims *jwt.Claims, currentPassword, newPassword string) (int64, error) {
+	var userID, adminID sql.NullInt32
+	var currentHash string
+	user, err := s.queries.GetUserByID(ctx, int32(claims.UserID))
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return 0, err
+	}
+	if err == nil && user.Username == claims.Username {
+		if !user.IsActive.Bool {
+			return 0, ErrUserInactive
+		}
+		userID = sql.NullInt32{Int32: user.ID, Valid: true}
+		currentHash = user.PasswordHash
+	} else {
+		admin, err := s.queries.GetAdminByUsername(ctx, claims.Username)
+		if err != nil {
+			if errors.Is(err, sql.ErrNoRows) {
+				return 0, ErrUserNotFound
+			}
+			return 0, err
+		}
+		if admin.ID != int32(claims.UserID) {
+			return 0, ErrUserNotFound
+		}
+		if !admin.IsActive {
+			return 0, ErrUserInactive
+		}
+		adminID = sql.NullInt32{Int32: admin.ID, Valid: true}
+		currentHash = admin.PasswordHash
+	}
+
+	if err := bcrypt.CompareHashAndPassword([]byte(currentHash), []byte(currentPassword)); err != nil {
+		return 0, ErrInvalidCurrentPassword
+	}
+	if err := s.passwordPolicy.Validate(newPassword); err != nil {
+		return 0, err
+	}
+	if err := s.checkPasswordReuse(ctx, userID, adminID, currentHash, newPassword); err != nil {
+		return 0, err
+	}
+
+	hashed, err := s.hashPassword(newPassword)
+	if err != nil {
+		return 0, err
+	}
+	if adminID.Valid {
+		err = s.queries.UpdateAdminPassword(ctx, db.UpdateAdminPasswordParams{
+			ID:           adminID.Int32,
+			PasswordHash: string(hashed),
+		})
+	} else {
+		err = s.queries.UpdateUserPassword(ctx, db.UpdateUserPasswordParams{
+			ID:           userID.Int32,
+			PasswordHash: string(hashed),
+		})
+		// the cached user still has the old hash
+		s.redis.Delete(ctx, fmt.Sprintf("user:%d", userID.Int32))
+	}
+	if err != nil {
+		return 0, err
+	}
+	s.recordPasswordHistory(ctx, userID, adminID, string(hashed))
+
+	// user and admin ids overlap, only the sessions of this account go
+	return s.queries.RevokeOtherUserRefreshTokens(ctx, db.RevokeOtherUserRefreshTokensParams{
+		UserID:  userID,
+		AdminID: adminID,
+		KeepID:  int32(claims.SessionID),
+	})
+}
+
+func nullString(s *string) sql.NullString {
+	if s == nil {
+		return sql.NullString{}
+	}
+	return sql.NullString{String: *s, Valid: true}
+}
+
+func profileUpdateError(err error) error {
+	if pgErr, ok := err.(*pq.Error); ok && pgErr.Code == "23505" { // unique_violation
+		return ErrEmailTaken
+	}
+	return err
+}
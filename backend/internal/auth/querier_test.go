@@ -0,0 +1,125 @@
+package auth
+
+import (
+	"context"
+	"database/sql"
+	db "herp/db/sqlc"
+	"sort"
+	"strings"
+)
+
+// fakeQuerier keeps the tables the Service reads in memory. Only the
+// queries a test runs are implemented, the others panic through the nil
+// Querier.
+type fakeQuerier struct {
+	Querier
+	roles       []db.Role
+	permissions []db.Permission
+	// grants[role][permission] is set when the role holds the permission
+	grants map[int32]map[int32]bool
+	users  map[int32]db.GetUserByIDRow
+	// notificationPreferences are the preferences users saved, by user
+	notificationPreferences map[int32][]db.NotificationPreference
+}
+
+// newSeededQuerier returns a fakeQuerier holding what SeedDefaults creates,
+// the default roles and permissions with every permission granted to the
+// admin role.
+func newSeededQuerier() *fakeQuerier {
+	q := &fakeQuerier{
+		grants:                  map[int32]map[int32]bool{},
+		users:                   map[int32]db.GetUserByIDRow{},
+		notificationPreferences: map[int32][]db.NotificationPreference{},
+	}
+	for _, role := range defaultRoles {
+		q.addRole(role.name)
+	}
+	for _, permission := range defaultPermissions {
+		q.addPermission(permission.name)
+	}
+	for _, permission := range q.permissions {
+		q.grant(q.roles[0].ID, permission.ID)
+	}
+	return q
+}
+
+func (q *fakeQuerier) addRole(name string) db.Role {
+	role := db.Role{ID: int32(len(q.roles) + 1), Name: name}
+	q.roles = append(q.roles, role)
+	return role
+}
+
+func (q *fakeQuerier) addPermission(code string) db.Permission {
+	permission := db.Permission{ID: int32(len(q.permissions) + 1), Code: code}
+	q.permissions = append(q.permissions, permission)
+	return permission
+}
+
+func (q *fakeQuerier) grant(roleID, permissionID int32) {
+	if q.grants[roleID] == nil {
+		q.grants[roleID] = map[int32]bool{}
+	}
+	q.grants[roleID][permissionID] = true
+}
+
+// addUser adds a user with the named role.
+func (q *fakeQuerier) addUser(username, role string) db.GetUserByIDRow {
+	user := db.GetUserByIDRow{
+		ID:       int32(len(q.users) + 1),
+		Username: username,
+		Email:    sql.NullString{String: username + "@example.com", Valid: true},
+		IsActive: sql.NullBool{Bool: true, Valid: true},
+		RoleName: role,
+	}
+	for _, r := range q.roles {
+		if r.Name == role {
+			user.RoleID = sql.NullInt32{Int32: r.ID, Valid: true}
+		}
+	}
+	q.users[user.ID] = user
+	return user
+}
+
+func (q *fakeQuerier) permissionID(code string) int32 {
+	for _, permission := range q.permissions {
+		if permission.Code == code {
+			return permission.ID
+		}
+	}
+	return 0
+}
+
+func (q *fakeQuerier) GetPermissionsMatrix(ctx context.Context, permissionGroup sql.NullString) ([]db.GetPermissionsMatrixRow, error) {
+	permissions := append([]db.Permission(nil), q.permissions...)
+	sort.Slice(permissions, func(i, j int) bool { return permissions[i].Code < permissions[j].Code })
+
+	var rows []db.GetPermissionsMatrixRow
+	for _, role := range q.roles {
+		for _, permission := range permissions {
+			group, _, _ := strings.Cut(permission.Code, ":")
+			if permissionGroup.Valid && group != permissionGroup.String {
+				continue
+			}
+			rows = append(rows, db.GetPermissionsMatrixRow{
+				RoleID:         role.ID,
+				RoleName:       role.Name,
+				PermissionID:   permission.ID,
+				PermissionCode: permission.Code,
+				Granted:        q.grants[role.ID][permission.ID],
+			})
+		}
+	}
+	return rows, nil
+}
+
+func (q *fakeQuerier) GetUserByID(ctx context.Context, id int32) (db.GetUserByIDRow, error) {
+	user, ok := q.users[id]
+	if !ok {
+		return db.GetUserByIDRow{}, sql.ErrNoRows
+	}
+	return user, nil
+}
+
+func (q *fakeQuerier) ListNotificationPreferences(ctx context.Context, userID int32) ([]db.NotificationPreference, error) {
+	return q.notificationPreferences[userID], nil
+}
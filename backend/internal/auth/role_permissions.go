@@ -0,0 +1,108 @@
+package auth
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	db "herp/db/sqlc"
+	"slices"
+)
+
+var (
+	ErrRoleNotFound       = errors.New("role not found")
+	ErrUnknownPermissions = errors.New("unknown permission ids")
+)
+
+// RolePermissionsChange is how many permissions a bulk change granted to and
+// took from a role, with the permissions the role holds afterwards.
+type RolePermissionsChange struct {
+	Added       int64           `json:"added"`
+	Removed     int64           `json:"removed"`
+	Permissions []db.Permission `json:"permissions"`
+}
+
+// ReplaceRolePermissions makes permissionIDs the exact permission set of a
+// role in one transaction. Only the difference is written, permissions the
+// role keeps are left alone. Permissions are read into tokens on login and
+// refresh, users of the role get the new set with their next token.
+func (s *Service) ReplaceRolePermissions(ctx context.Context, roleID int32, permissionIDs []int32) (RolePermissionsChange, error) {
+	return s.changeRolePermissions(ctx, roleID, permissionIDs, true)
+}
+
+// AddPermissionsToRole grants many permissions to a role at once, the ones
+// it already holds are skipped.
+func (s *Service) AddPermissionsToRole(ctx context.Context, roleID int32, permissionIDs []int32) (RolePermissionsChange, error) {
+	return s.changeRolePermissions(ctx, roleID, permissionIDs, false)
+}
+
+func (s *Service) changeRolePermissions(ctx context.Context, roleID int32, permissionIDs []int32, replace bool) (RolePermissionsChange, error) {
+	ids := slices.Clone(permissionIDs)
+	slices.Sort(ids)
+	ids = slices.Compact(ids)
+
+	if _, err := s.queries.GetRoleByID(ctx, roleID); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return RolePermissionsChange{}, ErrRoleNotFound
+		}
+		return RolePermissionsChange{}, err
+	}
+
+	existing, err := s.queries.ListExistingPermissionIDs(ctx, ids)
+	if err != nil {
+		return RolePermissionsChange{}, err
+	}
+	if len(existing) != len(ids) {
+		var unknown []int32
+		for _, id := range ids {
+			if !slices.Contains(existing, id) {
+				unknown = append(unknown, id)
+			}
+		}
+		return RolePermissionsChange{}, fmt.Errorf("%w: %v", ErrUnknownPermissions, unknown)
+	}
+
+	q, ok := s.queries.(*db.Queries)
+	if !ok {
+		return RolePermissionsChange{}, fmt.Errorf("invalid queries implementation")
+	}
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return RolePermissionsChange{}, err
+	}
+	defer tx.Rollback()
+
+	txQueries := q.WithTx(tx)
+
+	var change RolePermissionsChange
+	if replace {
+		change.Removed, err = txQueries.RemoveRolePermissionsExcept(ctx, db.RemoveRolePermissionsExceptParams{
+			RoleID:  roleID,
+			KeepIds: ids,
+		})
+		if err != nil {
+			return RolePermissionsChange{}, err
+		}
+	}
+	change.Added, err = txQueries.AddPermissionsToRole(ctx, db.AddPermissionsToRoleParams{
+		RoleID:        roleID,
+		PermissionIds: ids,
+	})
+	if err != nil {
+		return RolePermissionsChange{}, err
+	}
+
+	change.Permissions, err = txQueries.GetRolePermissions(ctx, roleID)
+	if err != nil {
+		return RolePermissionsChange{}, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return RolePermissionsChange{}, err
+	}
+	if change.Added > 0 || change.Removed > 0 {
+		s.invalidateRoleUsers(ctx, roleID)
+	}
+	return change, nil
+}
@@ -0,0 +1,99 @@
+package auth
+
+import (
+	"context"
+	"database/sql"
+	"herp/db/dbtest"
+	db "herp/db/sqlc"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func storedPermission(t *testing.T, conn *sql.DB, code string) int32 {
+	t.Helper()
+	return dbtest.Insert(t, conn, `INSERT INTO permissions (code) VALUES ($1) RETURNING id`, code)
+}
+
+func storedRole(t *testing.T, conn *sql.DB, name string, permissionIDs ...int32) int32 {
+	t.Helper()
+	roleID := dbtest.Insert(t, conn, `INSERT INTO roles (name) VALUES ($1) RETURNING id`, name)
+	for _, id := range permissionIDs {
+		dbtest.Exec(t, conn, `INSERT INTO role_permissions (role_id, permission_id) VALUES ($1, $2)`, roleID, id)
+	}
+	return roleID
+}
+
+func codes(permissions []db.Permission) []string {
+	got := []string{}
+	for _, p := range permissions {
+		got = append(got, p.Code)
+	}
+	return got
+}
+
+func TestReplaceRolePermissions(t *testing.T) {
+	s, conn := newStoredService(t)
+	view := storedPermission(t, conn, "sales:view")
+	create := storedPermission(t, conn, "sales:create")
+	refund := storedPermission(t, conn, "sales:refund")
+	void := storedPermission(t, conn, "sales:void")
+	role := storedRole(t, conn, "cashier", view, create, refund)
+	other := storedRole(t, conn, "manager", view, refund)
+
+	// view is kept, refund is taken away and void granted, given twice
+	change, err := s.ReplaceRolePermissions(context.Background(), role, []int32{void, view, create, void})
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), change.Added)
+	assert.Equal(t, int64(1), change.Removed)
+	assert.ElementsMatch(t, []string{"sales:view", "sales:create", "sales:void"}, codes(change.Permissions))
+
+	// the same set again changes nothing
+	change, err = s.ReplaceRolePermissions(context.Background(), role, []int32{view, create, void})
+	require.NoError(t, err)
+	assert.Zero(t, change.Added)
+	assert.Zero(t, change.Removed)
+
+	// an empty set takes everything away
+	change, err = s.ReplaceRolePermissions(context.Background(), role, nil)
+	require.NoError(t, err)
+	assert.Equal(t, int64(3), change.Removed)
+	assert.Empty(t, change.Permissions)
+
+	// other roles are left alone
+	var held int
+	require.NoError(t, conn.QueryRow(`SELECT count(*) FROM role_permissions WHERE role_id = $1`, other).Scan(&held))
+	assert.Equal(t, 2, held)
+}
+
+func TestAddPermissionsToRole(t *testing.T) {
+	s, conn := newStoredService(t)
+	view := storedPermission(t, conn, "sales:view")
+	create := storedPermission(t, conn, "sales:create")
+	role := storedRole(t, conn, "cashier", view)
+
+	change, err := s.AddPermissionsToRole(context.Background(), role, []int32{view, create})
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), change.Added)
+	assert.Zero(t, change.Removed)
+	assert.ElementsMatch(t, []string{"sales:view", "sales:create"}, codes(change.Permissions))
+}
+
+func TestReplaceRolePermissionsUnknown(t *testing.T) {
+	s, conn := newStoredService(t)
+	view := storedPermission(t, conn, "sales:view")
+	role := storedRole(t, conn, "cashier", view)
+
+	_, err := s.ReplaceRolePermissions(context.Background(), role, []int32{view, 998, 999})
+	require.ErrorIs(t, err, ErrUnknownPermissions)
+	assert.Contains(t, err.Error(), "[998 999]")
+
+	// nothing changed
+	var held int
+	require.NoError(t, conn.QueryRow(`SELECT count(*) FROM role_permissions WHERE role_id = $1`, role).Scan(&held))
+	assert.Equal(t, 1, held)
+
+	_, err = s.ReplaceRolePermissions(context.Background(), role+100, []int32{view})
+	assert.ErrorIs(t, err, ErrRoleNotFound)
+}
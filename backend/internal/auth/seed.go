@@ -0,0 +1,93 @@
+package auth
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	db "herp/db/sqlc"
+)
+
+// AdminRole is the role given to registered admins, it holds every
+// permission.
+const AdminRole = "admin"
+
+type seedEntry struct {
+	name        string
+	description string
+}
+
+// defaultRoles are the roles the application expects to exist.
+var defaultRoles = []seedEntry{
+	{AdminRole, "System administrator with full access"},
+	{"manager", "Hotel manager with broad access"},
+	{"cashier", "Cashier with limited POS access"},
+	{"pos_staff", "POS system user"},
+}
+
+// defaultPermissions are the permissions checked by PermissionMiddleware
+// across the routes, plus the user management ones.
+var defaultPermissions = []seedEntry{
+	{"admin:manage", "Manage admin settings"},
+	{"admin:audit", "Export audit data such as login history"},
+
+	{"business:create", "Create business"},
+	{"business:view", "View business"},
+	{"business:update", "Update business"},
+	{"business:delete", "Delete business"},
+	{"logs:view", "View activity logs"},
+	{"logs:activity_logs", "View the activity logs of a business"},
+
+	{"inventory:create", "Create inventory items"},
+	{"inventory:view", "View inventory items"},
+	{"inventory:update", "Update inventory items"},
+	{"inventory:delete", "Delete inventory items"},
+	{"inventory:adjust", "Adjust stock by hand"},
+
+	{"pos:sell", "Create new sales in POS"},
+	{"pos:view", "View sales history in POS"},
+	{"pos:manage_items", "Manage POS items"},
+	{"pos:refund", "Refund sales in POS"},
+	{"pos:manage_discounts", "Manage POS discount rules"},
+
+	{"user:create", "Create new users"},
+	{"user:view", "View user information"},
+	{"user:update", "Update user information"},
+	{"user:delete", "Delete users"},
+}
+
+// SeedDefaults makes sure the default roles and permissions exist and that
+// the admin role holds every permission. Rows that already exist are left
+// untouched, so it is safe to run on every boot after the migrations.
+func SeedDefaults(ctx context.Context, conn *sql.DB) error {
+	tx, err := conn.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	q := db.New(tx)
+
+	for _, role := range defaultRoles {
+		if err := q.SeedRole(ctx, db.SeedRoleParams{
+			Name:        role.name,
+			Description: sql.NullString{String: role.description, Valid: true},
+		}); err != nil {
+			return fmt.Errorf("seed role %s: %w", role.name, err)
+		}
+	}
+
+	for _, permission := range defaultPermissions {
+		if err := q.SeedPermission(ctx, db.SeedPermissionParams{
+			Code:        permission.name,
+			Description: sql.NullString{String: permission.description, Valid: true},
+		}); err != nil {
+			return fmt.Errorf("seed permission %s: %w", permission.name, err)
+		}
+	}
+
+	if _, err := q.GrantAllPermissionsToRole(ctx, AdminRole); err != nil {
+		return fmt.Errorf("grant permissions to %s: %w", AdminRole, err)
+	}
+
+	return tx.Commit()
+}
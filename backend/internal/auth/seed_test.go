@@ -0,0 +1,87 @@
+package auth
+
+import (
+	"context"
+	"herp/db/dbtest"
+	db "herp/db/sqlc"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSeedDefaults(t *testing.T) {
+	conn := dbtest.Open(t)
+	ctx := context.Background()
+
+	// a role that is already there keeps its description
+	dbtest.Exec(t, conn, `INSERT INTO roles (name, description) VALUES ('cashier', 'Till operator')`)
+
+	// safe to run on every boot
+	for range 2 {
+		require.NoError(t, SeedDefaults(ctx, conn))
+	}
+
+	var roles, permissions, granted int
+	require.NoError(t, conn.QueryRow(`SELECT count(*) FROM roles`).Scan(&roles))
+	assert.Equal(t, len(defaultRoles), roles)
+	require.NoError(t, conn.QueryRow(`SELECT count(*) FROM permissions`).Scan(&permissions))
+	assert.Equal(t, len(defaultPermissions), permissions)
+
+	// the admin holds every permission, the other roles none
+	require.NoError(t, conn.QueryRow(`
+		SELECT count(*) FROM role_permissions rp
+		JOIN roles r ON r.id = rp.role_id
+		WHERE r.name = $1`, AdminRole).Scan(&granted))
+	assert.Equal(t, len(defaultPermissions), granted)
+	require.NoError(t, conn.QueryRow(`
+		SELECT count(*) FROM role_permissions rp
+		JOIN roles r ON r.id = rp.role_id
+		WHERE r.name <> $1`, AdminRole).Scan(&granted))
+	assert.Zero(t, granted)
+
+	var description string
+	require.NoError(t, conn.QueryRow(`SELECT description FROM roles WHERE name = 'cashier'`).Scan(&description))
+	assert.Equal(t, "Till operator", description)
+
+	// admins registered against the seeded role get every permission
+	admin := dbtest.Admin(t, conn, "owner")
+	held, err := db.New(conn).GetAdminPermissions(ctx, admin)
+	require.NoError(t, err)
+	assert.Len(t, held, len(defaultPermissions))
+}
+
+var permissionCheck = regexp.MustCompile(`PermissionMiddleware(?:Strict)?\([\w.]+, "([^"]+)"\)`)
+
+// TestDefaultPermissionsCoverRoutes makes sure every permission a route
+// checks is seeded, so a fresh install doesn't lock the admin out of it.
+func TestDefaultPermissionsCoverRoutes(t *testing.T) {
+	seeded := map[string]bool{}
+	for _, permission := range defaultPermissions {
+		seeded[permission.name] = true
+	}
+
+	checked := 0
+	root := filepath.Join("..", "..")
+	err := filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() || !strings.HasSuffix(path, ".go") || strings.HasSuffix(path, "_test.go") {
+			return err
+		}
+		source, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		for _, match := range permissionCheck.FindAllStringSubmatch(string(source), -1) {
+			checked++
+			assert.True(t, seeded[match[1]], "%s checks %s, which isn't seeded", path, match[1])
+		}
+		return nil
+	})
+	require.NoError(t, err)
+	assert.NotZero(t, checked, "no permission checks found under %s", root)
+}
@@ -4,8 +4,8 @@
 //
 // Fields:
 //   - queries: Database queries interface for user, role, and token operations.
-//   - jwtSecret: Secret key for signing JWT access tokens.
-//   - jwtRefreshSecret: Secret key for signing JWT refresh tokens (can fallback to jwtSecret).
+//   - jwtKeys: Keys for signing and verifying JWT access tokens, the current one and those being rotated out.
+//   - jwtRefreshSecret: Secret key for signing JWT refresh tokens.
 //   - accessExpiry: Duration for which access tokens are valid.
 //   - refreshExpiry: Duration for which refresh tokens are valid.
 //   - redis: Redis client for caching and token blacklisting.
@@ -23,12 +23,14 @@
 //   - User Management: CreateUser, UpdateUser, DeleteUser, ResetPassword.
 //   - Role Management: CreateRole, UpdateRole, DeleteRole, AddPermissionToRole, RemovePermissionFromRole.
 //   - GetUserByID, GetUserByEmail, GetUserByUsername: Fetches user details, with Redis caching.
-//   - ListUsers, ListRoles, GetRolePermissions: Lists users, roles, and permissions.
+//   - ListUsers, ListRoles, GetRolePermissions, GetPermissionsMatrix: Lists users, roles, and permissions.
 //   - Logging: LogUserActivity, LogLogin for auditing user actions and login attempts.
+//   - ExportLoginHistory: Streams login attempts of a date range in batches.
+//   - ListActivityLogs: Pages through activity logs with filters and a total count.
 //
 // Internal Utilities:
 //   - generateRefreshToken: Generates a secure random refresh token.
-//   - cleanExpiredTokens: Periodically cleans up expired refresh tokens from the database.
+//   - StartTokenCleanup: Periodically cleans up expired refresh tokens from the database.
 //
 // Error Handling:
 //   - ErrInvalidCredentials: Returned when authentication fails.
@@ -50,10 +52,13 @@ import (
 	"herp/internal/utils"
 	"herp/pkg/jwt"
 	"herp/pkg/monitoring/logging"
+	"herp/pkg/password"
 	"herp/pkg/ratelimit"
 	"herp/pkg/redis"
 	"log"
+	"math"
 	"slices"
+	"strings"
 	"time"
 
 	r "github.com/redis/go-redis/v9"
@@ -64,11 +69,15 @@ var (
 	ErrInvalidCredentials = errors.New("invalid credentials")
 	ErrUserInactive       = errors.New("user is inactive")
 	ErrUserNotFound       = errors.New("user not found")
+	ErrPasswordReused     = errors.New("password was used recently")
+	ErrEmailNotVerified   = errors.New("email not verified")
+	ErrEmailVerified      = errors.New("email is already verified")
+	ErrResendTooSoon      = errors.New("a code was sent to this email recently")
 )
 
 type Service struct {
 	queries            Querier
-	jwtSecret          string
+	jwtKeys            *jwt.KeySet
 	jwtRefreshSecret   string
 	accessExpiry       time.Duration
 	refreshExpiry      time.Duration
@@ -81,28 +90,64 @@ type Service struct {
 	ipRateLimit        int
 	db                 *sql.DB
 	logger             *logging.Logger
+	passwordPolicy     password.Policy
+	// refuse logins of admins who haven't verified their email
+	requireVerifiedEmail bool
+	// digits of verification and reset codes
+	otpLength int
+	// bcrypt cost of new password hashes, older ones are upgraded on login
+	bcryptCost int
 }
 
-func NewService(queries Querier, jwtSecret, jwtRefreshSecret string, accessExpiry, refreshExpiry time.Duration, redis *redis.Redis, redisClient *r.Client, loginRateLimit, loginRateWindow, loginBlockDuration, ipRateLimit int, db *sql.DB, logger *logging.Logger) *Service {
-	if jwtRefreshSecret == "" {
-		jwtRefreshSecret = jwtSecret // Fallback to same secret if not provided
-	}
+func NewService(queries Querier, jwtKeys *jwt.KeySet, jwtRefreshSecret string, accessExpiry, refreshExpiry time.Duration, redis *redis.Redis, redisClient *r.Client, loginRateLimit, loginRateWindow, loginBlockDuration, ipRateLimit int, db *sql.DB, logger *logging.Logger, passwordPolicy password.Policy, requireVerifiedEmail bool, otpLength, bcryptCost int) *Service {
 	rateLimiter := ratelimit.NewRateLimit(redisClient)
 	return &Service{
-		queries:            queries,
-		jwtSecret:          jwtSecret,
-		accessExpiry:       accessExpiry,
-		refreshExpiry:      refreshExpiry,
-		jwtRefreshSecret:   jwtRefreshSecret,
-		redis:              redis,
-		rClient:            redisClient,
-		rateLimiter:        rateLimiter,
-		loginRateLimit:     loginRateLimit,
-		loginRateWindow:    time.Duration(loginRateWindow) * time.Minute,
-		loginBlockDuration: time.Duration(loginBlockDuration) * time.Minute,
-		ipRateLimit:        ipRateLimit,
-		db:                 db,
-		logger:             logger,
+		queries:              queries,
+		jwtKeys:              jwtKeys,
+		accessExpiry:         accessExpiry,
+		refreshExpiry:        refreshExpiry,
+		jwtRefreshSecret:     jwtRefreshSecret,
+		redis:                redis,
+		rClient:              redisClient,
+		rateLimiter:          rateLimiter,
+		loginRateLimit:       loginRateLimit,
+		loginRateWindow:      time.Duration(loginRateWindow) * time.Minute,
+		loginBlockDuration:   time.Duration(loginBlockDuration) * time.Minute,
+		ipRateLimit:          ipRateLimit,
+		db:                   db,
+		logger:               logger,
+		passwordPolicy:       passwordPolicy,
+		requireVerifiedEmail: requireVerifiedEmail,
+		otpLength:            otpLength,
+		bcryptCost:           bcryptCost,
+	}
+}
+
+// hashPassword hashes a password at the configured bcrypt cost.
+func (s *Service) hashPassword(password string) ([]byte, error) {
+	return bcrypt.GenerateFromPassword([]byte(password), s.bcryptCost)
+}
+
+// upgradePasswordHash re-hashes the password of an account that just logged
+// in when its hash was made at a lower cost than the configured one. Failing
+// to do so doesn't fail the login, it is tried again on the next one.
+func (s *Service) upgradePasswordHash(ctx context.Context, id int32, isAdmin bool, passwordHash, password string) {
+	cost, err := bcrypt.Cost([]byte(passwordHash))
+	if err != nil || cost >= s.bcryptCost {
+		return
+	}
+	hashed, err := s.hashPassword(password)
+	if err != nil {
+		s.logger.Warnf("failed to re-hash password of %d: %v", id, err)
+		return
+	}
+	if isAdmin {
+		err = s.queries.UpdateAdminPassword(ctx, db.UpdateAdminPasswordParams{ID: id, PasswordHash: string(hashed)})
+	} else {
+		err = s.queries.UpdateUserPassword(ctx, db.UpdateUserPasswordParams{ID: id, PasswordHash: string(hashed)})
+	}
+	if err != nil {
+		s.logger.Warnf("failed to upgrade password hash of %d: %v", id, err)
 	}
 }
 
@@ -137,8 +182,8 @@ func (s *Service) checkRateLimits(ctx context.Context, username, ipAddress strin
 		return fmt.Errorf("account temporarily locked. Try again in %v", ttl)
 	}
 
-	// Check IP rate limit for general requests
-	exceeded, _, timeLeft, err := s.rateLimiter.Check(
+	// Check and count the IP rate limit for general requests
+	allowed, _, timeLeft, err := s.rateLimiter.Allow(
 		ctx,
 		fmt.Sprintf("ip_requests:%s", ipAddress),
 		s.ipRateLimit,
@@ -147,7 +192,7 @@ func (s *Service) checkRateLimits(ctx context.Context, username, ipAddress strin
 	if err != nil {
 		return err
 	}
-	if exceeded {
+	if !allowed {
 		return fmt.Errorf("too many requests. Try again in %v", timeLeft)
 	}
 
@@ -156,7 +201,10 @@ func (s *Service) checkRateLimits(ctx context.Context, username, ipAddress strin
 
 func (s *Service) RegisterAdmin(ctx context.Context, username, email, password, first_name, last_name string) (db.Admin, error) {
 	log.Println("Registering new admin user:", username, email)
-	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(password), 12)
+	if err := s.passwordPolicy.Validate(password); err != nil {
+		return db.Admin{}, err
+	}
+	hashedPassword, err := s.hashPassword(password)
 	if err != nil {
 		s.logger.Error("error hashing password: ", err)
 		return db.Admin{}, err
@@ -185,6 +233,12 @@ func (s *Service) RegisterAdmin(ctx context.Context, username, email, password,
 	log.Println("Creating admin user in database")
 	txQueries := q.WithTx(tx)
 
+	adminRole, err := txQueries.GetRoleByName(ctx, AdminRole)
+	if err != nil {
+		s.logger.Error("error looking up admin role: ", err)
+		return db.Admin{}, err
+	}
+
 	log.Println("Admin user details:", username, email, first_name, last_name)
 	user, err := txQueries.CreateAdmin(ctx, db.CreateAdminParams{
 		Username:     username,
@@ -192,7 +246,7 @@ func (s *Service) RegisterAdmin(ctx context.Context, username, email, password,
 		FirstName:    first_name,
 		LastName:     last_name,
 		PasswordHash: string(hashedPassword),
-		RoleID:       1,
+		RoleID:       adminRole.ID,
 		IsActive:     true,
 	})
 	if err != nil {
@@ -217,29 +271,56 @@ func (s *Service) RegisterAdmin(ctx context.Context, username, email, password,
 	return user, nil
 }
 
-// SetEmailVerification sets the verification code and expiry for a user.
+// SetEmailVerification sets the verification code and expiry for an admin and
+// marks their email as not verified until it is used, the code is stored
+// hashed.
 func (a *Service) SetEmailVerification(ctx context.Context, userID int32, code string, expiry time.Time) error {
 	return a.queries.SetAdminEmailVerification(ctx, db.SetAdminEmailVerificationParams{
 		ID:                    userID,
-		VerificationCode:      sql.NullString{Valid: code != "", String: code},
+		VerificationCode:      sql.NullString{Valid: code != "", String: a.hashCode(code)},
 		VerificationExpiresAt: sql.NullTime{Valid: true, Time: expiry},
 	})
 }
 
+// setUserEmailVerification is SetEmailVerification for users. The cached user
+// would still show the email as verified, so it is dropped.
+func (s *Service) setUserEmailVerification(ctx context.Context, userID int32, code string, expiry time.Time) error {
+	err := s.queries.SetUserEmailVerification(ctx, db.SetUserEmailVerificationParams{
+		ID:                    userID,
+		VerificationCode:      sql.NullString{Valid: code != "", String: s.hashCode(code)},
+		VerificationExpiresAt: sql.NullTime{Valid: true, Time: expiry},
+	})
+	if err != nil {
+		return err
+	}
+	s.redis.Delete(ctx, fmt.Sprintf("user:%d", userID))
+	return nil
+}
+
 // VerifyEmailCode checks the code and marks the email as verified if valid and not expired.
+// Users are looked up first like Login does.
 func (a *Service) VerifyEmailCode(ctx context.Context, email, code string) (bool, error) {
+	user, err := a.queries.GetUserByEmail(ctx, sql.NullString{String: email, Valid: true})
+	if err == nil {
+		if !a.verificationCodeValid(user.EmailVerified, user.VerificationCode, user.VerificationExpiresAt, code) {
+			return false, nil
+		}
+		if err := a.queries.MarkUserEmailVerified(ctx, user.ID); err != nil {
+			return false, err
+		}
+		a.redis.Delete(ctx, fmt.Sprintf("user:%d", user.ID))
+		return true, nil
+	}
+	if !errors.Is(err, sql.ErrNoRows) {
+		return false, err
+	}
+
 	admin, err := a.queries.GetAdminByEmail(ctx, email)
 	if err != nil {
 		return false, err
 	}
-	if admin.EmailVerified {
-		return false, nil // Already verified
-	}
-	if admin.VerificationCode.String != code {
-		return false, nil // Invalid code
-	}
-	if !admin.VerificationExpiresAt.Valid || admin.VerificationExpiresAt.Time.Before(time.Now()) {
-		return false, nil // Expired
+	if !a.verificationCodeValid(admin.EmailVerified, admin.VerificationCode, admin.VerificationExpiresAt, code) {
+		return false, nil
 	}
 	// Mark as verified and clear code
 	err = a.queries.MarkAdminEmailVerified(ctx, db.MarkAdminEmailVerifiedParams{
@@ -252,6 +333,71 @@ func (a *Service) VerifyEmailCode(ctx context.Context, email, code string) (bool
 	return true, nil
 }
 
+// verificationCodeValid tells whether code verifies an email that isn't
+// verified yet with the stored code and expiry.
+func (a *Service) verificationCodeValid(verified bool, stored sql.NullString, expiresAt sql.NullTime, code string) bool {
+	if verified {
+		return false // Already verified
+	}
+	if !stored.Valid || !a.codeMatches(stored.String, code) {
+		return false // Invalid code
+	}
+	if !expiresAt.Valid || expiresAt.Time.Before(time.Now()) {
+		return false // Expired
+	}
+	return true
+}
+
+// ResendVerification sets a new verification code for a user or admin who
+// hasn't verified their email yet and returns the account with the code to
+// be sent. Users are looked up first like Login does. An address can get a
+// new code once a minute.
+func (s *Service) ResendVerification(ctx context.Context, email string) (Profile, string, error) {
+	var account Profile
+	var verified bool
+	user, err := s.queries.GetUserByEmail(ctx, sql.NullString{String: email, Valid: true})
+	switch {
+	case err == nil:
+		account = Profile{ID: user.ID, Username: user.Username, Email: user.Email.String}
+		verified = user.EmailVerified
+	case errors.Is(err, sql.ErrNoRows):
+		admin, err := s.queries.GetAdminByEmail(ctx, email)
+		if err != nil {
+			if errors.Is(err, sql.ErrNoRows) {
+				return Profile{}, "", ErrUserNotFound
+			}
+			return Profile{}, "", err
+		}
+		account = Profile{ID: admin.ID, Username: admin.Username, Email: admin.Email, IsAdmin: true}
+		verified = admin.EmailVerified
+	default:
+		return Profile{}, "", err
+	}
+	if verified {
+		return Profile{}, "", ErrEmailVerified
+	}
+
+	allowed, _, retryAfter, err := s.rateLimiter.Allow(ctx, fmt.Sprintf("resend_verification:%s", account.Email), 1, time.Minute)
+	if err != nil {
+		return Profile{}, "", err
+	}
+	if !allowed {
+		return Profile{}, "", fmt.Errorf("%w, try again in %.0f seconds", ErrResendTooSoon, math.Ceil(retryAfter.Seconds()))
+	}
+
+	code := utils.GenerateOTP(s.otpLength)
+	expiry := time.Now().Add(10 * time.Minute)
+	if account.IsAdmin {
+		err = s.SetEmailVerification(ctx, account.ID, code, expiry)
+	} else {
+		err = s.setUserEmailVerification(ctx, account.ID, code, expiry)
+	}
+	if err != nil {
+		return Profile{}, "", err
+	}
+	return account, code, nil
+}
+
 func (s *Service) recordFailedAttempt(ctx context.Context, username, ipAddress, reason string) {
 	// Increment user attempt counter
 	userAttemptsKey := fmt.Sprintf("login_attempts:user:%s", username)
@@ -314,43 +460,52 @@ func (s *Service) Login(ctx context.Context, emailOrUsername, password, ipAddres
 		return "", "", err
 	}
 
-	// Increment IP request counter
-	ipRequestKey := fmt.Sprintf("ip_requests:%s", ipAddress)
-	s.rateLimiter.Increment(ctx, ipRequestKey, time.Minute)
-
 	// Helper to handle successful login
-	handleSuccess := func(userID int32, username, email, roleName, passwordHash string, isAdmin bool) (string, string, error) {
+	// emailVerified is only checked after the password, so the verification
+	// state of an account isn't given away to anyone who knows its email
+	handleSuccess := func(userID int32, username, email, roleName, passwordHash string, isAdmin, emailVerified bool) (string, string, error) {
 		if err := bcrypt.CompareHashAndPassword([]byte(passwordHash), []byte(password)); err != nil {
 			s.recordFailedAttempt(ctx, emailOrUsername, ipAddress, "ErrInvalidCredentials")
 			remaining, _ := s.rateLimiter.GetRemainingAttempts(ctx, fmt.Sprintf("login_attempts:user:%s", emailOrUsername), s.loginRateLimit, s.loginRateWindow)
 			return "", "", fmt.Errorf("invalid credentials. %d attempts remaining", remaining)
 		}
 		s.resetLoginAttempts(ctx, emailOrUsername)
+		s.upgradePasswordHash(ctx, userID, isAdmin, passwordHash, password)
+		if s.requireVerifiedEmail && !emailVerified {
+			return "", "", ErrEmailNotVerified
+		}
 		var permissions []string
 		var err error
 		if isAdmin {
 			permissions, err = s.queries.GetAdminPermissions(ctx, userID)
 		} else {
-			permissions, err = s.queries.GetUserPermissions(ctx, userID)
+			permissions, err = s.getUserPermissions(ctx, userID)
 		}
 		if err != nil {
 			return "", "", err
 		}
-		token, err := jwt.GenerateToken(
-			int(userID), username, email, roleName,
-			s.jwtSecret, permissions, jwt.AccessToken, s.accessExpiry,
-		)
+		refreshToken, err := generateRefreshToken()
 		if err != nil {
 			return "", "", err
 		}
-		refreshToken, err := generateRefreshToken()
+		params := db.CreateRefreshTokenParams{
+			Token: refreshToken, ExpiresAt: time.Now().Add(s.refreshExpiry),
+			UserAgent: sql.NullString{String: userAgent, Valid: userAgent != ""},
+		}
+		// admin ids are not user ids, the token is kept apart for them
+		if isAdmin {
+			params.AdminID = sql.NullInt32{Int32: userID, Valid: true}
+		} else {
+			params.UserID = sql.NullInt32{Int32: userID, Valid: true}
+		}
+		session, err := s.queries.CreateRefreshToken(ctx, params)
 		if err != nil {
 			return "", "", err
 		}
-		expiresAt := time.Now().Add(s.refreshExpiry)
-		_, err = s.queries.CreateRefreshToken(ctx, db.CreateRefreshTokenParams{
-			UserID: userID, Token: refreshToken, ExpiresAt: expiresAt,
-		})
+		token, err := jwt.GenerateToken(
+			int(userID), username, email, roleName,
+			s.jwtKeys, permissions, jwt.AccessToken, s.accessExpiry, int(session.ID),
+		)
 		if err != nil {
 			return "", "", err
 		}
@@ -358,13 +513,14 @@ func (s *Service) Login(ctx context.Context, emailOrUsername, password, ipAddres
 		return token, refreshToken, nil
 	}
 
-	// Try user by email
+	// Try user by email, users created by admins start verified and only
+	// have to verify an email they change themselves
 	if userByEmail, err := s.queries.GetUserByEmail(ctx, sql.NullString{String: emailOrUsername, Valid: true}); err == nil {
 		if !userByEmail.IsActive.Bool {
 			s.recordFailedAttempt(ctx, emailOrUsername, ipAddress, "ErrUserInactive")
 			return "", "", ErrUserInactive
 		}
-		return handleSuccess(userByEmail.ID, userByEmail.Username, userByEmail.Email.String, userByEmail.RoleName, userByEmail.PasswordHash, false)
+		return handleSuccess(userByEmail.ID, userByEmail.Username, userByEmail.Email.String, userByEmail.RoleName, userByEmail.PasswordHash, false, userByEmail.EmailVerified)
 	}
 
 	// Try user by username
@@ -373,7 +529,7 @@ func (s *Service) Login(ctx context.Context, emailOrUsername, password, ipAddres
 			s.recordFailedAttempt(ctx, emailOrUsername, ipAddress, "ErrUserInactive")
 			return "", "", ErrUserInactive
 		}
-		return handleSuccess(userByUsername.ID, userByUsername.Username, userByUsername.Email.String, userByUsername.RoleName, userByUsername.PasswordHash, false)
+		return handleSuccess(userByUsername.ID, userByUsername.Username, userByUsername.Email.String, userByUsername.RoleName, userByUsername.PasswordHash, false, userByUsername.EmailVerified)
 	}
 
 	// Try admin by email
@@ -382,7 +538,7 @@ func (s *Service) Login(ctx context.Context, emailOrUsername, password, ipAddres
 			s.recordFailedAttempt(ctx, emailOrUsername, ipAddress, "ErrUserInactive")
 			return "", "", ErrUserInactive
 		}
-		return handleSuccess(adminByEmail.ID, adminByEmail.Username, adminByEmail.Email, adminByEmail.RoleName, adminByEmail.PasswordHash, true)
+		return handleSuccess(adminByEmail.ID, adminByEmail.Username, adminByEmail.Email, adminByEmail.RoleName, adminByEmail.PasswordHash, true, adminByEmail.EmailVerified)
 	}
 
 	// Try admin by username
@@ -391,7 +547,7 @@ func (s *Service) Login(ctx context.Context, emailOrUsername, password, ipAddres
 			s.recordFailedAttempt(ctx, emailOrUsername, ipAddress, "ErrUserInactive")
 			return "", "", ErrUserInactive
 		}
-		return handleSuccess(adminByUsername.ID, adminByUsername.Username, adminByUsername.Email, adminByUsername.RoleName, adminByUsername.PasswordHash, true)
+		return handleSuccess(adminByUsername.ID, adminByUsername.Username, adminByUsername.Email, adminByUsername.RoleName, adminByUsername.PasswordHash, true, adminByUsername.EmailVerified)
 	}
 
 	s.recordFailedAttempt(ctx, emailOrUsername, ipAddress, "ErrUserNotFound")
@@ -410,52 +566,72 @@ func (s *Service) RefreshToken(ctx context.Context, refreshToken string) (string
 		return "", "", ErrInvalidCredentials
 	}
 
-	// Get user information
-	user, err := s.queries.GetUserByID(ctx, tokenRecord.UserID)
-	if err != nil {
-		return "", "", err
+	// Get the account the token was issued to, a user or an admin
+	var id int32
+	var username, email, roleName string
+	var permissions []string
+	if tokenRecord.AdminID.Valid {
+		admin, err := s.queries.GetAdminByID(ctx, tokenRecord.AdminID.Int32)
+		if err != nil {
+			return "", "", err
+		}
+		if !admin.IsActive {
+			return "", "", ErrUserInactive
+		}
+		permissions, err = s.queries.GetAdminPermissions(ctx, admin.ID)
+		if err != nil {
+			return "", "", err
+		}
+		id, username, email, roleName = admin.ID, admin.Username, admin.Email, admin.RoleName
+	} else {
+		user, err := s.queries.GetUserByID(ctx, tokenRecord.UserID.Int32)
+		if err != nil {
+			return "", "", err
+		}
+		if !user.IsActive.Bool {
+			return "", "", ErrUserInactive
+		}
+		permissions, err = s.getUserPermissions(ctx, user.ID)
+		if err != nil {
+			return "", "", err
+		}
+		id, username, email, roleName = user.ID, user.Username, user.Email.String, user.RoleName
 	}
 
-	if !user.IsActive.Bool {
-		return "", "", ErrUserInactive
+	// Generate new refresh token (rotate refresh token)
+	newRefreshToken, err := generateRefreshToken()
+	if err != nil {
+		return "", "", err
 	}
 
-	permissions, err := s.queries.GetUserPermissions(ctx, user.ID)
+	expiresAt := time.Now().Add(s.refreshExpiry)
+	session, err := s.queries.CreateRefreshToken(ctx, db.CreateRefreshTokenParams{
+		UserID:    tokenRecord.UserID,
+		AdminID:   tokenRecord.AdminID,
+		Token:     newRefreshToken,
+		ExpiresAt: expiresAt,
+		UserAgent: tokenRecord.UserAgent,
+	})
 	if err != nil {
 		return "", "", err
 	}
 
 	// Generate new access token
 	newAccessToken, err := jwt.GenerateToken(
-		int(user.ID),
-		user.Username,
-		user.Email.String,
-		user.RoleName,
-		s.jwtSecret,
+		int(id),
+		username,
+		email,
+		roleName,
+		s.jwtKeys,
 		permissions,
 		jwt.AccessToken,
 		s.accessExpiry,
+		int(session.ID),
 	)
 	if err != nil {
 		return "", "", err
 	}
 
-	// Generate new refresh token (rotate refresh token)
-	newRefreshToken, err := generateRefreshToken()
-	if err != nil {
-		return "", "", err
-	}
-
-	expiresAt := time.Now().Add(s.refreshExpiry)
-	_, err = s.queries.CreateRefreshToken(ctx, db.CreateRefreshTokenParams{
-		UserID:    int32(user.ID),
-		Token:     newRefreshToken,
-		ExpiresAt: expiresAt,
-	})
-	if err != nil {
-		return "", "", err
-	}
-
 	// Revoke the old refresh token
 	if err := s.queries.RevokeRefreshToken(ctx, refreshToken); err != nil {
 		// Log error but continue
@@ -465,26 +641,32 @@ func (s *Service) RefreshToken(ctx context.Context, refreshToken string) (string
 	return newAccessToken, newRefreshToken, nil
 }
 
-func (s *Service) cleanExpiredTokens(ctx context.Context) {
-	// Run cleanup every hour
-	ticker := time.NewTicker(time.Hour)
-	defer ticker.Stop()
-
-	for {
-		select {
-		case <-ticker.C:
-			if err := s.queries.CleanExpiredRefreshTokens(ctx); err != nil {
-				fmt.Printf("Error cleaning expired tokens: %v\n", err)
+// StartTokenCleanup deletes expired refresh tokens every interval until ctx
+// is cancelled. It is started once for the whole process, the returned
+// channel is closed when the cleanup has stopped.
+func (s *Service) StartTokenCleanup(ctx context.Context, interval time.Duration, onError func(error)) <-chan struct{} {
+	done := make(chan struct{})
+	ticker := time.NewTicker(interval)
+	go func() {
+		defer close(done)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				if err := s.queries.CleanExpiredRefreshTokens(ctx); err != nil && ctx.Err() == nil && onError != nil {
+					onError(err)
+				}
 			}
-		case <-ctx.Done():
-			return
 		}
-	}
+	}()
+	return done
 }
 
 func (s *Service) RevokeAllUserSessions(ctx context.Context, userID int) error {
 	// Revoke all refresh tokens for user
-	if err := s.queries.RevokeAllUserRefreshTokens(ctx, int32(userID)); err != nil {
+	if err := s.queries.RevokeAllUserRefreshTokens(ctx, sql.NullInt32{Int32: int32(userID), Valid: true}); err != nil {
 		return err
 	}
 
@@ -493,8 +675,14 @@ func (s *Service) RevokeAllUserSessions(ctx context.Context, userID int) error {
 	return s.redis.Delete(ctx, cacheKey)
 }
 
+// ParseToken verifies an access token against the current and previous
+// signing keys and returns its claims.
+func (s *Service) ParseToken(token string) (*jwt.Claims, error) {
+	return jwt.ParseToken(token, s.jwtKeys)
+}
+
 func (s *Service) Logout(ctx context.Context, token string, expiry time.Duration) error {
-	claims, err := jwt.ParseToken(token, s.jwtSecret)
+	claims, err := s.ParseToken(token)
 	if err != nil {
 		return err
 	}
@@ -522,7 +710,15 @@ func (s *Service) HasPermission(claims *jwt.Claims, requiredPermission string) b
 
 // Admin user management functions
 func (s *Service) CreateUser(ctx context.Context, params db.CreateUserParams) (db.User, error) {
-	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(params.PasswordHash), bcrypt.DefaultCost)
+	if err := s.passwordPolicy.Validate(params.PasswordHash); err != nil {
+		return db.User{}, err
+	}
+	hashedPassword, err := s.hashPassword(params.PasswordHash)
+	if err != nil {
+		return db.User{}, err
+	}
+
+	params.BranchID, err = s.resolveStoreAssignment(ctx, params.BranchID, params.StoreID)
 	if err != nil {
 		return db.User{}, err
 	}
@@ -532,6 +728,13 @@ func (s *Service) CreateUser(ctx context.Context, params db.CreateUserParams) (d
 }
 
 func (s *Service) UpdateUser(ctx context.Context, params db.UpdateUserParams) (db.User, error) {
+	if params.SetStore {
+		branchID, err := s.resolveStoreAssignment(ctx, params.BranchID, params.StoreID)
+		if err != nil {
+			return db.User{}, err
+		}
+		params.BranchID = branchID
+	}
 	updatedUser, err := s.queries.UpdateUser(ctx, params)
 	if err != nil {
 		return db.User{}, err
@@ -539,20 +742,132 @@ func (s *Service) UpdateUser(ctx context.Context, params db.UpdateUserParams) (d
 	// Invalidate cache
 	cacheKey := fmt.Sprintf("user:%d", params.ID)
 	s.redis.Delete(ctx, cacheKey)
+	if params.RoleID.Valid {
+		s.redis.Delete(ctx, userPermissionsKey(params.ID))
+	}
 	return updatedUser, nil
 }
 
+// DeleteUser soft deletes a user, the account is deactivated and hidden but
+// its activity logs and login history stay attributable. Refreshing its
+// sessions fails from now on.
 func (s *Service) DeleteUser(ctx context.Context, id int32) error {
-	return s.queries.DeleteUser(ctx, id)
+	user, err := s.queries.GetUserByID(ctx, id)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return ErrUserNotFound
+		}
+		return err
+	}
+	deleted, err := s.queries.DeleteUser(ctx, id)
+	if err != nil {
+		return err
+	}
+	if deleted == 0 {
+		return ErrUserNotFound
+	}
+	s.invalidateUserCache(ctx, user.ID, user.Username, user.Email.String)
+	return nil
+}
+
+// RestoreUser undeletes a user, it is active again.
+func (s *Service) RestoreUser(ctx context.Context, id int32) (db.User, error) {
+	user, err := s.queries.RestoreUser(ctx, id)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return db.User{}, ErrUserNotFound
+		}
+		return db.User{}, err
+	}
+	s.invalidateUserCache(ctx, user.ID, user.Username, user.Email.String)
+	return user, nil
+}
+
+// invalidateUserCache drops the cached lookups of a user by id, username and
+// email.
+func (s *Service) invalidateUserCache(ctx context.Context, id int32, username, email string) {
+	s.redis.Delete(ctx,
+		fmt.Sprintf("user:%d", id),
+		fmt.Sprintf("user_by_username:%s", username),
+		fmt.Sprintf("user:email:%s", email),
+		userPermissionsKey(id),
+	)
 }
 
 func (s *Service) ResetPassword(ctx context.Context, params db.UpdateUserPasswordParams) error {
-	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(params.PasswordHash), bcrypt.DefaultCost)
+	if err := s.passwordPolicy.Validate(params.PasswordHash); err != nil {
+		return err
+	}
+	hashedPassword, err := s.hashPassword(params.PasswordHash)
 	if err != nil {
 		return err
 	}
+	user, err := s.queries.GetUserByID(ctx, params.ID)
+	if err != nil {
+		return err
+	}
+	userID := sql.NullInt32{Int32: params.ID, Valid: true}
+	if err := s.checkPasswordReuse(ctx, userID, sql.NullInt32{}, user.PasswordHash, params.PasswordHash); err != nil {
+		return err
+	}
+
 	params.PasswordHash = string(hashedPassword)
-	return s.queries.UpdateUserPassword(ctx, params)
+	if err := s.queries.UpdateUserPassword(ctx, params); err != nil {
+		return err
+	}
+	s.recordPasswordHistory(ctx, userID, sql.NullInt32{}, params.PasswordHash)
+	return nil
+}
+
+// checkPasswordReuse returns ErrPasswordReused when password matches the
+// account's current hash or one of its last passwordPolicy.History hashes.
+// Exactly one of userID and adminID is set.
+func (s *Service) checkPasswordReuse(ctx context.Context, userID, adminID sql.NullInt32, currentHash, password string) error {
+	if s.passwordPolicy.History <= 0 {
+		return nil
+	}
+
+	recent, err := s.queries.ListRecentPasswordHashes(ctx, db.ListRecentPasswordHashesParams{
+		UserID:  userID,
+		AdminID: adminID,
+		Keep:    int32(s.passwordPolicy.History),
+	})
+	if err != nil {
+		return err
+	}
+
+	for _, hash := range append([]string{currentHash}, recent...) {
+		if bcrypt.CompareHashAndPassword([]byte(hash), []byte(password)) == nil {
+			return ErrPasswordReused
+		}
+	}
+	return nil
+}
+
+// recordPasswordHistory stores a newly set password hash and forgets the ones
+// older than the last passwordPolicy.History. The password has already been
+// changed at this point so failures are only logged.
+func (s *Service) recordPasswordHistory(ctx context.Context, userID, adminID sql.NullInt32, hash string) {
+	if s.passwordPolicy.History <= 0 {
+		return
+	}
+
+	if err := s.queries.AddPasswordHistory(ctx, db.AddPasswordHistoryParams{
+		UserID:       userID,
+		AdminID:      adminID,
+		PasswordHash: hash,
+	}); err != nil {
+		s.logger.Error("error saving password history: ", err)
+		return
+	}
+
+	if err := s.queries.PrunePasswordHistory(ctx, db.PrunePasswordHistoryParams{
+		UserID:  userID,
+		AdminID: adminID,
+		Keep:    int32(s.passwordPolicy.History),
+	}); err != nil {
+		s.logger.Error("error pruning password history: ", err)
+	}
 }
 
 // Role management functions
@@ -561,7 +876,12 @@ func (s *Service) CreateRole(ctx context.Context, params db.CreateRoleParams) (d
 }
 
 func (s *Service) UpdateRole(ctx context.Context, params db.UpdateRoleParams) (db.Role, error) {
-	return s.queries.UpdateRole(ctx, params)
+	role, err := s.queries.UpdateRole(ctx, params)
+	if err != nil {
+		return db.Role{}, err
+	}
+	s.invalidateRoleUsers(ctx, role.ID)
+	return role, nil
 }
 
 func (s *Service) DeleteRole(ctx context.Context, id int32) error {
@@ -569,11 +889,19 @@ func (s *Service) DeleteRole(ctx context.Context, id int32) error {
 }
 
 func (s *Service) AddPermissionToRole(ctx context.Context, params db.AddPermissionToRoleParams) error {
-	return s.queries.AddPermissionToRole(ctx, params)
+	if err := s.queries.AddPermissionToRole(ctx, params); err != nil {
+		return err
+	}
+	s.invalidateRoleUsers(ctx, params.RoleID)
+	return nil
 }
 
 func (s *Service) RemovePermissionFromRole(ctx context.Context, params db.RemovePermissionFromRoleParams) error {
-	return s.queries.RemovePermissionFromRole(ctx, params)
+	if err := s.queries.RemovePermissionFromRole(ctx, params); err != nil {
+		return err
+	}
+	s.invalidateRoleUsers(ctx, params.RoleID)
+	return nil
 }
 
 func (s *Service) GetUserByID(ctx context.Context, id int32) (db.GetUserByIDRow, error) {
@@ -649,8 +977,18 @@ func (s *Service) GetUserByUsername(ctx context.Context, username string) (db.Ge
 	return user, nil
 }
 
-func (s *Service) ListUsers(ctx context.Context) ([]db.ListUsersRow, error) {
-	return s.queries.ListUsers(ctx)
+// ListUsers returns a page of the users, newest first, deleted ones only with
+// params.IncludeDeleted, and how many there are in total.
+func (s *Service) ListUsers(ctx context.Context, params db.ListUsersParams) ([]db.ListUsersRow, int64, error) {
+	users, err := s.queries.ListUsers(ctx, params)
+	if err != nil {
+		return nil, 0, err
+	}
+	total, err := s.queries.CountUsers(ctx, params.IncludeDeleted)
+	if err != nil {
+		return nil, 0, err
+	}
+	return users, total, nil
 }
 
 func (s *Service) ListRoles(ctx context.Context) ([]db.Role, error) {
@@ -661,6 +999,51 @@ func (s *Service) GetRolePermissions(ctx context.Context, roleID int32) ([]db.Pe
 	return s.queries.GetRolePermissions(ctx, roleID)
 }
 
+type MatrixPermission struct {
+	ID   int32  `json:"id"`
+	Code string `json:"code"`
+}
+
+type MatrixRole struct {
+	ID   int32  `json:"id"`
+	Name string `json:"name"`
+	// Granted[i] tells whether the role holds Permissions[i]
+	Granted []bool `json:"granted"`
+}
+
+type PermissionsMatrix struct {
+	Permissions []MatrixPermission `json:"permissions"`
+	Roles       []MatrixRole       `json:"roles"`
+}
+
+// GetPermissionsMatrix returns every role against every permission, optionally
+// limited to one permission group (the part of the code before ':').
+func (s *Service) GetPermissionsMatrix(ctx context.Context, group string) (PermissionsMatrix, error) {
+	rows, err := s.queries.GetPermissionsMatrix(ctx, sql.NullString{String: group, Valid: group != ""})
+	if err != nil {
+		return PermissionsMatrix{}, err
+	}
+
+	matrix := PermissionsMatrix{
+		Permissions: []MatrixPermission{},
+		Roles:       []MatrixRole{},
+	}
+	// rows are ordered by role then permission code, so the first role's rows
+	// give the permission columns
+	for _, row := range rows {
+		if len(matrix.Roles) == 0 || matrix.Roles[len(matrix.Roles)-1].ID != row.RoleID {
+			matrix.Roles = append(matrix.Roles, MatrixRole{ID: row.RoleID, Name: row.RoleName, Granted: []bool{}})
+		}
+		if len(matrix.Roles) == 1 {
+			matrix.Permissions = append(matrix.Permissions, MatrixPermission{ID: row.PermissionID, Code: row.PermissionCode})
+		}
+		role := &matrix.Roles[len(matrix.Roles)-1]
+		role.Granted = append(role.Granted, row.Granted)
+	}
+
+	return matrix, nil
+}
+
 func (s *Service) LogActivity(ctx context.Context, params db.LogActivityParams) error {
 	_, err := s.queries.LogActivity(ctx, params)
 	return err
@@ -677,16 +1060,32 @@ func (s *Service) LogActivity(ctx context.Context, params db.LogActivityParams)
 // 	return err
 // }
 
-// ForgotPassword: generates a reset code and expiry, stores it for user/admin
+// resetCodeTTL is how long a password reset code can be used.
+const resetCodeTTL = 15 * time.Minute
+
+var errInvalidResetCode = errors.New("invalid or expired code")
+
+// ForgotPassword: generates a reset code and expiry, stores it for user/admin.
+// An address can request a code once a minute, ErrResendTooSoon otherwise.
+// Unknown addresses return ErrUserNotFound, which callers must not reveal.
 func (s *Service) ForgotPassword(ctx context.Context, email string) (string, error) {
+	// throttled before the lookup so unknown addresses are treated the same
+	allowed, _, _, err := s.rateLimiter.Allow(ctx, fmt.Sprintf("forgot_password:%s", strings.ToLower(email)), 1, time.Minute)
+	if err != nil {
+		return "", err
+	}
+	if !allowed {
+		return "", ErrResendTooSoon
+	}
+
+	code := utils.GenerateOTP(s.otpLength)
+	expiry := time.Now().Add(resetCodeTTL)
+
 	admin, err := s.queries.GetAdminByEmail(ctx, email)
 	if err == nil {
-		code := utils.GenerateOTP()
-		fmt.Println("Generated code:", code)
-		expiry := time.Now().Add(15 * time.Minute)
 		err := s.queries.SetAdminResetCode(ctx, db.SetAdminResetCodeParams{
 			ID:                 admin.ID,
-			ResetCode:          sql.NullString{String: code, Valid: true},
+			ResetCode:          sql.NullString{String: s.hashCode(code), Valid: true},
 			ResetCodeExpiresAt: sql.NullTime{Time: expiry, Valid: true},
 		})
 		if err != nil {
@@ -694,18 +1093,48 @@ func (s *Service) ForgotPassword(ctx context.Context, email string) (string, err
 		}
 		return code, nil
 	}
+	if !errors.Is(err, sql.ErrNoRows) {
+		return "", err
+	}
 
-	return "", errors.New("email not found")
+	user, err := s.queries.GetUserByEmail(ctx, sql.NullString{String: email, Valid: true})
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return "", ErrUserNotFound
+		}
+		return "", err
+	}
+	if !user.IsActive.Bool {
+		return "", ErrUserInactive
+	}
+	err = s.queries.SetUserResetCode(ctx, db.SetUserResetCodeParams{
+		UserID:    user.ID,
+		Code:      s.hashCode(code),
+		ExpiresAt: expiry,
+	})
+	if err != nil {
+		return "", err
+	}
+	return code, nil
 }
 
-// ResetPassword: verifies code and sets new password for user/admin
+// ResetPassword: verifies code and sets new password for user/admin. Admins
+// are looked up first, the same as in ForgotPassword.
 func (s *Service) ResetAdminPassword(ctx context.Context, email, code, newPassword string) error {
+	if err := s.passwordPolicy.Validate(newPassword); err != nil {
+		return err
+	}
+
 	admin, err := s.queries.GetAdminByEmail(ctx, email)
 	if err == nil {
-		if !admin.ResetCode.Valid || admin.ResetCode.String != code || !admin.ResetCodeExpiresAt.Valid || admin.ResetCodeExpiresAt.Time.Before(time.Now()) {
-			return errors.New("invalid or expired code")
+		if !admin.ResetCode.Valid || !s.codeMatches(admin.ResetCode.String, code) || !admin.ResetCodeExpiresAt.Valid || admin.ResetCodeExpiresAt.Time.Before(time.Now()) {
+			return errInvalidResetCode
 		}
-		hashed, _ := bcrypt.GenerateFromPassword([]byte(newPassword), bcrypt.DefaultCost)
+		adminID := sql.NullInt32{Int32: admin.ID, Valid: true}
+		if err := s.checkPasswordReuse(ctx, sql.NullInt32{}, adminID, admin.PasswordHash, newPassword); err != nil {
+			return err
+		}
+		hashed, _ := s.hashPassword(newPassword)
 		err := s.queries.UpdateAdminPassword(ctx, db.UpdateAdminPasswordParams{
 			ID:           admin.ID,
 			PasswordHash: string(hashed),
@@ -713,10 +1142,100 @@ func (s *Service) ResetAdminPassword(ctx context.Context, email, code, newPasswo
 		if err != nil {
 			return err
 		}
+		s.recordPasswordHistory(ctx, sql.NullInt32{}, adminID, string(hashed))
 		// Clear reset code
 		_ = s.queries.ClearAdminResetCode(ctx, admin.ID)
 		return nil
 	}
+	if !errors.Is(err, sql.ErrNoRows) {
+		return err
+	}
+
+	user, err := s.queries.GetUserByEmail(ctx, sql.NullString{String: email, Valid: true})
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return errors.New("email not found")
+		}
+		return err
+	}
+	resetCode, err := s.queries.GetUserResetCode(ctx, user.ID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return errInvalidResetCode
+		}
+		return err
+	}
+	if !s.codeMatches(resetCode.Code, code) || resetCode.ExpiresAt.Before(time.Now()) {
+		return errInvalidResetCode
+	}
+	userID := sql.NullInt32{Int32: user.ID, Valid: true}
+	if err := s.checkPasswordReuse(ctx, userID, sql.NullInt32{}, user.PasswordHash, newPassword); err != nil {
+		return err
+	}
+	hashed, _ := s.hashPassword(newPassword)
+	err = s.queries.UpdateUserPassword(ctx, db.UpdateUserPasswordParams{
+		ID:           user.ID,
+		PasswordHash: string(hashed),
+	})
+	if err != nil {
+		return err
+	}
+	s.recordPasswordHistory(ctx, userID, sql.NullInt32{}, string(hashed))
+	_ = s.queries.ClearUserResetCode(ctx, user.ID)
+	return nil
+}
+
+// loginHistoryExportBatch is how many login attempts are read per query when
+// exporting login history.
+const loginHistoryExportBatch = 500
+
+// ExportLoginHistory passes the login attempts made in [start, end) to fn in
+// batches, oldest first. Batches are read with keyset pagination so exporting
+// a large range never holds more than one batch in memory.
+func (s *Service) ExportLoginHistory(ctx context.Context, start, end time.Time, success sql.NullBool, fn func([]db.ExportLoginHistoryRow) error) error {
+	var afterID int32
+	for {
+		rows, err := s.queries.ExportLoginHistory(ctx, db.ExportLoginHistoryParams{
+			AfterID:   afterID,
+			StartTime: start,
+			EndTime:   end,
+			Success:   success,
+			BatchSize: loginHistoryExportBatch,
+		})
+		if err != nil {
+			return err
+		}
+		if len(rows) == 0 {
+			return nil
+		}
+
+		if err := fn(rows); err != nil {
+			return err
+		}
+
+		if len(rows) < loginHistoryExportBatch {
+			return nil
+		}
+		afterID = rows[len(rows)-1].ID
+	}
+}
 
-	return errors.New("email not found")
+// ListActivityLogs returns a page of the activity logs matching params, newest
+// first, and how many match in total.
+func (s *Service) ListActivityLogs(ctx context.Context, params db.ListActivityLogsParams) ([]db.ActivityLog, int64, error) {
+	logs, err := s.queries.ListActivityLogs(ctx, params)
+	if err != nil {
+		return nil, 0, err
+	}
+	total, err := s.queries.CountActivityLogs(ctx, db.CountActivityLogsParams{
+		UserID:     params.UserID,
+		Action:     params.Action,
+		EntityType: params.EntityType,
+		StartTime:  params.StartTime,
+		EndTime:    params.EndTime,
+	})
+	if err != nil {
+		return nil, 0, err
+	}
+	return logs, total, nil
 }
@@ -4,6 +4,7 @@ import (
 	"context"
 	"database/sql"
 	db "herp/db/sqlc"
+	"herp/pkg/jwt"
 	"time"
 )
 
@@ -12,10 +13,21 @@ type ServiceInterface interface {
 	RegisterAdmin(ctx context.Context, username, email, password, first, last string) (db.Admin, error)
 	SetEmailVerification(ctx context.Context, id int32, code string, expiry time.Time) error
 	VerifyEmailCode(ctx context.Context, email, code string) (bool, error)
+	ResendVerification(ctx context.Context, email string) (Profile, string, error)
 	ForgotPassword(ctx context.Context, email string) (string, error)
 	ResetAdminPassword(ctx context.Context, email, code, newPassword string) error
+	AcceptInvite(ctx context.Context, token, newPassword string) error
 	RefreshToken(ctx context.Context, refreshToken string) (string, string, error)
+	ParseToken(token string) (*jwt.Claims, error)
 	Logout(ctx context.Context, token string, expiry time.Duration) error
+	Me(ctx context.Context, claims *jwt.Claims) (Profile, error)
+	UpdateProfile(ctx context.Context, claims *jwt.Claims, args UpdateProfileParams) (Profile, string, error)
+	ChangePassword(ctx context.Context, claims *jwt.Claims, currentPassword, newPassword string) (int64, error)
+	ListSessions(ctx context.Context, claims *jwt.Claims) ([]db.RefreshToken, error)
+	RevokeSession(ctx context.Context, claims *jwt.Claims, sessionID int32) error
+	GetNotificationPreferences(ctx context.Context, userID int32) ([]NotificationPreference, error)
+	UpdateNotificationPreferences(ctx context.Context, userID int32, prefs []NotificationPreference) ([]NotificationPreference, error)
+	HasPermission(claims *jwt.Claims, requiredPermission string) bool
 }
 
 // Querier defines the database methods the Service depends on.
@@ -24,7 +36,10 @@ type Querier interface {
 	CreateAdmin(ctx context.Context, params db.CreateAdminParams) (db.Admin, error)
 	SetAdminEmailVerification(ctx context.Context, params db.SetAdminEmailVerificationParams) error
 	GetAdminByEmail(ctx context.Context, email string) (db.GetAdminByEmailRow, error)
+	GetAdminByID(ctx context.Context, id int32) (db.GetAdminByIDRow, error)
 	MarkAdminEmailVerified(ctx context.Context, params db.MarkAdminEmailVerifiedParams) error
+	SetUserEmailVerification(ctx context.Context, params db.SetUserEmailVerificationParams) error
+	MarkUserEmailVerified(ctx context.Context, id int32) error
 	LogLoginAttempt(ctx context.Context, params db.LogLoginAttemptParams) error
 	GetUserPermissions(ctx context.Context, userID int32) ([]string, error)
 	GetAdminPermissions(ctx context.Context, adminID int32) ([]string, error)
@@ -35,24 +50,50 @@ type Querier interface {
 	GetRefreshToken(ctx context.Context, token string) (db.RefreshToken, error)
 	RevokeRefreshToken(ctx context.Context, token string) error
 	CleanExpiredRefreshTokens(ctx context.Context) error
-	RevokeAllUserRefreshTokens(ctx context.Context, userID int32) error
+	RevokeOtherUserRefreshTokens(ctx context.Context, arg db.RevokeOtherUserRefreshTokensParams) (int64, error)
+	RevokeAllUserRefreshTokens(ctx context.Context, userID sql.NullInt32) error
+	ListUserRefreshTokens(ctx context.Context, params db.ListUserRefreshTokensParams) ([]db.RefreshToken, error)
+	RevokeRefreshTokenByID(ctx context.Context, params db.RevokeRefreshTokenByIDParams) (int64, error)
+	ListNotificationPreferences(ctx context.Context, userID int32) ([]db.NotificationPreference, error)
+	UpsertNotificationPreference(ctx context.Context, params db.UpsertNotificationPreferenceParams) (db.NotificationPreference, error)
 	CreateUser(ctx context.Context, params db.CreateUserParams) (db.User, error)
 	UpdateUser(ctx context.Context, params db.UpdateUserParams) (db.User, error)
-	DeleteUser(ctx context.Context, id int32) error
+	DeleteUser(ctx context.Context, id int32) (int64, error)
+	RestoreUser(ctx context.Context, id int32) (db.User, error)
 	UpdateUserPassword(ctx context.Context, params db.UpdateUserPasswordParams) error
 	CreateRole(ctx context.Context, params db.CreateRoleParams) (db.Role, error)
 	UpdateRole(ctx context.Context, params db.UpdateRoleParams) (db.Role, error)
 	DeleteRole(ctx context.Context, id int32) error
 	AddPermissionToRole(ctx context.Context, params db.AddPermissionToRoleParams) error
 	RemovePermissionFromRole(ctx context.Context, params db.RemovePermissionFromRoleParams) error
-	ListUsers(ctx context.Context) ([]db.ListUsersRow, error)
+	AddPermissionsToRole(ctx context.Context, params db.AddPermissionsToRoleParams) (int64, error)
+	RemoveRolePermissionsExcept(ctx context.Context, params db.RemoveRolePermissionsExceptParams) (int64, error)
+	ListExistingPermissionIDs(ctx context.Context, ids []int32) ([]int32, error)
+	ListUserIDsByRole(ctx context.Context, roleID sql.NullInt32) ([]int32, error)
+	ListUsers(ctx context.Context, params db.ListUsersParams) ([]db.ListUsersRow, error)
+	CountUsers(ctx context.Context, includeDeleted bool) (int64, error)
 	ListRoles(ctx context.Context) ([]db.Role, error)
 	GetRolePermissions(ctx context.Context, roleID int32) ([]db.Permission, error)
+	GetPermissionsMatrix(ctx context.Context, permissionGroup sql.NullString) ([]db.GetPermissionsMatrixRow, error)
 	SetAdminResetCode(ctx context.Context, params db.SetAdminResetCodeParams) error
 	UpdateAdminPassword(ctx context.Context, params db.UpdateAdminPasswordParams) error
+	UpdateAdminProfile(ctx context.Context, params db.UpdateAdminProfileParams) (db.Admin, error)
+	AddPasswordHistory(ctx context.Context, params db.AddPasswordHistoryParams) error
+	ListRecentPasswordHashes(ctx context.Context, params db.ListRecentPasswordHashesParams) ([]string, error)
+	PrunePasswordHistory(ctx context.Context, params db.PrunePasswordHistoryParams) error
 	ClearAdminResetCode(ctx context.Context, adminID int32) error
+	SetUserResetCode(ctx context.Context, params db.SetUserResetCodeParams) error
+	GetUserResetCode(ctx context.Context, userID int32) (db.UserResetCode, error)
+	ClearUserResetCode(ctx context.Context, userID int32) error
+	GetUserInviteByToken(ctx context.Context, token string) (db.UserInvite, error)
 	GetUserByID(ctx context.Context, ID int32) (db.GetUserByIDRow, error)
 	GetRoleByID(ctx context.Context, id int32) (db.Role, error)
 	GetLoginHistory(ctx context.Context, limit int32) ([]db.LoginHistory, error)
+	ExportLoginHistory(ctx context.Context, params db.ExportLoginHistoryParams) ([]db.ExportLoginHistoryRow, error)
 	LogActivity(ctx context.Context, params db.LogActivityParams) (db.ActivityLog, error)
+	ListActivityLogs(ctx context.Context, params db.ListActivityLogsParams) ([]db.ActivityLog, error)
+	CountActivityLogs(ctx context.Context, params db.CountActivityLogsParams) (int64, error)
+	GetUserStoreScope(ctx context.Context, id int32) (db.GetUserStoreScopeRow, error)
+	ListBranchStoreIDs(ctx context.Context, branchID int32) ([]int32, error)
+	GetStoreBranchID(ctx context.Context, id int32) (int32, error)
 }
@@ -0,0 +1,61 @@
+package auth
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGetPermissionsMatrix(t *testing.T) {
+	q := newSeededQuerier()
+	cashier := q.roles[2]
+	q.grant(cashier.ID, q.permissionID("pos:sell"))
+	q.grant(cashier.ID, q.permissionID("pos:view"))
+	s := &Service{queries: q}
+
+	matrix, err := s.GetPermissionsMatrix(context.Background(), "")
+	require.NoError(t, err)
+
+	require.Len(t, matrix.Permissions, len(defaultPermissions))
+	require.Len(t, matrix.Roles, len(defaultRoles))
+	for _, role := range matrix.Roles {
+		require.Len(t, role.Granted, len(matrix.Permissions), role.Name)
+		for i, permission := range matrix.Permissions {
+			assert.Equal(t, q.grants[role.ID][permission.ID], role.Granted[i], "%s holds %s", role.Name, permission.Code)
+		}
+	}
+
+	// the admin role holds everything, the cashier only what was granted
+	assert.NotContains(t, matrix.Roles[0].Granted, false)
+	var held []string
+	for i, granted := range matrix.Roles[2].Granted {
+		if granted {
+			held = append(held, matrix.Permissions[i].Code)
+		}
+	}
+	assert.Equal(t, []string{"pos:sell", "pos:view"}, held)
+	assert.NotContains(t, matrix.Roles[1].Granted, true)
+}
+
+func TestGetPermissionsMatrixGroup(t *testing.T) {
+	s := &Service{queries: newSeededQuerier()}
+
+	matrix, err := s.GetPermissionsMatrix(context.Background(), "pos")
+	require.NoError(t, err)
+	require.NotEmpty(t, matrix.Permissions)
+	for _, permission := range matrix.Permissions {
+		assert.True(t, strings.HasPrefix(permission.Code, "pos:"), permission.Code)
+	}
+	require.Len(t, matrix.Roles, len(defaultRoles))
+	assert.Len(t, matrix.Roles[0].Granted, len(matrix.Permissions))
+
+	// an unknown group is an empty matrix, not a null one
+	matrix, err = s.GetPermissionsMatrix(context.Background(), "nothing")
+	require.NoError(t, err)
+	assert.NotNil(t, matrix.Permissions)
+	assert.NotNil(t, matrix.Roles)
+	assert.Empty(t, matrix.Roles)
+}
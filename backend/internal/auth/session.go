@@ -0,0 +1,112 @@
+package auth
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	db "herp/db/sqlc"
+	"herp/pkg/jwt"
+	"strings"
+)
+
+var ErrSessionNotFound = errors.New("session not found")
+
+// ListSessions returns the refresh tokens of the account of the claims that
+// can still be used, newest first.
+func (s *Service) ListSessions(ctx context.Context, claims *jwt.Claims) ([]db.RefreshToken, error) {
+	userID, adminID, err := s.sessionAccount(ctx, claims)
+	if err != nil {
+		return nil, err
+	}
+	return s.queries.ListUserRefreshTokens(ctx, db.ListUserRefreshTokensParams{
+		UserID:  userID,
+		AdminID: adminID,
+	})
+}
+
+// RevokeSession revokes one refresh token of the account of the claims.
+// Tokens of other accounts, and tokens that are already revoked, are reported
+// as ErrSessionNotFound.
+func (s *Service) RevokeSession(ctx context.Context, claims *jwt.Claims, sessionID int32) error {
+	userID, adminID, err := s.sessionAccount(ctx, claims)
+	if err != nil {
+		return err
+	}
+	revoked, err := s.queries.RevokeRefreshTokenByID(ctx, db.RevokeRefreshTokenByIDParams{
+		ID:      sessionID,
+		UserID:  userID,
+		AdminID: adminID,
+	})
+	if err != nil {
+		return err
+	}
+	if revoked == 0 {
+		return ErrSessionNotFound
+	}
+	return nil
+}
+
+// sessionAccount tells whether the claims belong to a user or an admin, as Me
+// does, and returns the id as the user_id or admin_id of their refresh tokens.
+// User and admin ids overlap, the other one is left null.
+func (s *Service) sessionAccount(ctx context.Context, claims *jwt.Claims) (sql.NullInt32, sql.NullInt32, error) {
+	profile, err := s.Me(ctx, claims)
+	if err != nil {
+		return sql.NullInt32{}, sql.NullInt32{}, err
+	}
+	id := sql.NullInt32{Int32: profile.ID, Valid: true}
+	if profile.IsAdmin {
+		return sql.NullInt32{}, id, nil
+	}
+	return id, sql.NullInt32{}, nil
+}
+
+// deviceLabel turns a user agent into a short "Browser on OS" label. It only
+// knows the common clients, anything else is labelled by its product token.
+func deviceLabel(userAgent string) string {
+	if userAgent == "" {
+		return "Unknown device"
+	}
+
+	browser := ""
+	switch {
+	case strings.Contains(userAgent, "Edg/"):
+		browser = "Edge"
+	case strings.Contains(userAgent, "OPR/"):
+		browser = "Opera"
+	case strings.Contains(userAgent, "Chrome/"):
+		browser = "Chrome"
+	case strings.Contains(userAgent, "Firefox/"):
+		browser = "Firefox"
+	case strings.Contains(userAgent, "Safari/"):
+		browser = "Safari"
+	}
+
+	os := ""
+	switch {
+	case strings.Contains(userAgent, "Android"):
+		os = "Android"
+	case strings.Contains(userAgent, "iPhone"), strings.Contains(userAgent, "iPad"):
+		os = "iOS"
+	case strings.Contains(userAgent, "Windows"):
+		os = "Windows"
+	case strings.Contains(userAgent, "Mac OS X"):
+		os = "macOS"
+	case strings.Contains(userAgent, "Linux"):
+		os = "Linux"
+	}
+
+	switch {
+	case browser != "" && os != "":
+		return browser + " on " + os
+	case browser != "":
+		return browser
+	case os != "":
+		return os
+	}
+
+	// e.g. "PostmanRuntime/7.36.0" or "curl/8.4.0"
+	product, _, _ := strings.Cut(userAgent, " ")
+	product, _, _ = strings.Cut(product, "/")
+	return product
+}
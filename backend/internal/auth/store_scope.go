@@ -0,0 +1,157 @@
+package auth
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"herp/internal/utils"
+	"herp/pkg/jwt"
+	"net/http"
+	"slices"
+
+	"github.com/gin-gonic/gin"
+)
+
+var (
+	ErrStoreNotFound    = errors.New("store not found")
+	ErrStoreNotInBranch = errors.New("store does not belong to the branch")
+)
+
+const storeScopeKey = "store_scope"
+
+// StoreScope is the set of stores a request may work with. An unrestricted
+// scope, the one of admins and of users without an assignment, allows every
+// store. User is set for staff users, whose business is the one of the
+// branch or store they are assigned to, as they don't own one.
+type StoreScope struct {
+	Restricted bool
+	BranchID   sql.NullInt32
+	StoreIDs   []int32
+	User       bool
+	BusinessID sql.NullInt32
+}
+
+// Allows reports whether the scope covers a store.
+func (s StoreScope) Allows(storeID int32) bool {
+	return !s.Restricted || slices.Contains(s.StoreIDs, storeID)
+}
+
+// nullInt32 maps an optional id to a nullable one, 0 being none.
+func nullInt32(id *int32) sql.NullInt32 {
+	if id == nil || *id == 0 {
+		return sql.NullInt32{}
+	}
+	return sql.NullInt32{Int32: *id, Valid: true}
+}
+
+// storeAssignmentError answers 400 for a branch or store assignment that
+// doesn't hold up and reports whether it did.
+func storeAssignmentError(c *gin.Context, err error) bool {
+	if errors.Is(err, ErrStoreNotFound) || errors.Is(err, ErrStoreNotInBranch) {
+		utils.ErrorResponse(c, http.StatusBadRequest, err.Error())
+		return true
+	}
+	return false
+}
+
+// resolveStoreAssignment checks the branch and store a user is assigned to
+// and returns the branch, which is taken from the store when only the store
+// is given.
+func (s *Service) resolveStoreAssignment(ctx context.Context, branchID, storeID sql.NullInt32) (sql.NullInt32, error) {
+	if !storeID.Valid {
+		return branchID, nil
+	}
+	storeBranch, err := s.queries.GetStoreBranchID(ctx, storeID.Int32)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return sql.NullInt32{}, ErrStoreNotFound
+		}
+		return sql.NullInt32{}, err
+	}
+	if branchID.Valid && branchID.Int32 != storeBranch {
+		return sql.NullInt32{}, ErrStoreNotInBranch
+	}
+	return sql.NullInt32{Int32: storeBranch, Valid: true}, nil
+}
+
+// UserStoreScope resolves the stores the owner of claims may work with. A
+// user assigned to a sub-store gets that store, one assigned to the central
+// store or only to a branch gets every store of the branch. Admins are told
+// apart from users by username, the way CurrentPermissions does it, and are
+// not restricted.
+func (s *Service) UserStoreScope(ctx context.Context, claims *jwt.Claims) (StoreScope, error) {
+	user, err := s.queries.GetUserStoreScope(ctx, int32(claims.UserID))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return StoreScope{}, nil
+		}
+		return StoreScope{}, err
+	}
+	if user.Username != claims.Username {
+		return StoreScope{}, nil
+	}
+	if !user.BranchID.Valid && !user.StoreID.Valid {
+		return StoreScope{User: true}, nil
+	}
+
+	scope := StoreScope{Restricted: true, BranchID: user.BranchID, User: true, BusinessID: user.BusinessID}
+	if user.StoreID.Valid && user.StoreType.String != "central" {
+		scope.StoreIDs = []int32{user.StoreID.Int32}
+		return scope, nil
+	}
+	if user.BranchID.Valid {
+		scope.StoreIDs, err = s.queries.ListBranchStoreIDs(ctx, user.BranchID.Int32)
+		if err != nil {
+			return StoreScope{}, err
+		}
+	}
+	return scope, nil
+}
+
+// StoreScopeMiddleware puts the StoreScope of the caller in the context for
+// handlers to check with GetStoreScope or RequireStore. It must run after
+// AuthMiiddleware.
+func StoreScopeMiddleware(authSvc *Service) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		claims, ok := jwt.GetUserFromContext(c)
+		if !ok {
+			utils.ErrorResponse(c, http.StatusUnauthorized, "unauthorized to make this request")
+			c.Abort()
+			return
+		}
+		scope, err := authSvc.UserStoreScope(c.Request.Context(), claims)
+		if err != nil {
+			authSvc.logger.Errorf("error resolving store scope of %s: %v", claims.Username, err)
+			utils.ErrorResponse(c, http.StatusInternalServerError, utils.SERVERERROR)
+			c.Abort()
+			return
+		}
+		SetStoreScope(c, scope)
+		c.Next()
+	}
+}
+
+// SetStoreScope puts a scope in the context, in place of the caller's.
+func SetStoreScope(c *gin.Context, scope StoreScope) {
+	c.Set(storeScopeKey, scope)
+}
+
+// GetStoreScope returns the scope StoreScopeMiddleware resolved, a request
+// that didn't go through it is not restricted.
+func GetStoreScope(c *gin.Context) StoreScope {
+	scope, _ := c.Get(storeScopeKey)
+	s, _ := scope.(StoreScope)
+	return s
+}
+
+// RequireStore answers 403 and returns false when the caller may not work
+// with a store.
+func RequireStore(c *gin.Context, storeID int32) bool {
+	if GetStoreScope(c).Allows(storeID) {
+		return true
+	}
+	utils.ErrorResponse(c, http.StatusForbidden, fmt.Sprintf("not assigned to store %d", storeID))
+	c.Abort()
+	return false
+}
@@ -0,0 +1,62 @@
+package auth
+
+import (
+	"context"
+	"runtime"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// cleanupQuerier counts the cleanups of expired refresh tokens.
+type cleanupQuerier struct {
+	Querier
+	cleaned atomic.Int32
+}
+
+func (q *cleanupQuerier) CleanExpiredRefreshTokens(ctx context.Context) error {
+	q.cleaned.Add(1)
+	return nil
+}
+
+func TestStartTokenCleanup(t *testing.T) {
+	q := &cleanupQuerier{}
+	s := &Service{queries: q}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := s.StartTokenCleanup(ctx, 5*time.Millisecond, func(err error) { t.Errorf("unexpected error: %v", err) })
+	assert.Eventually(t, func() bool { return q.cleaned.Load() >= 2 }, 2*time.Second, 5*time.Millisecond)
+
+	cancel()
+	select {
+	case <-done:
+	case <-time.After(2 * time.Second):
+		t.Fatal("token cleanup did not stop")
+	}
+	cleaned := q.cleaned.Load()
+	time.Sleep(20 * time.Millisecond)
+	assert.Equal(t, cleaned, q.cleaned.Load(), "no cleanup after stopping")
+}
+
+func TestLoginStartsNoCleanup(t *testing.T) {
+	s, conn := newStoredService(t)
+	storedUser(t, conn, "ada", "Secret123!")
+	ctx := context.Background()
+
+	// the first login opens the connections the rest reuse
+	_, _, err := s.Login(ctx, "ada", "Secret123!", "127.0.0.1", "test")
+	require.NoError(t, err)
+	before := runtime.NumGoroutine()
+
+	for range 20 {
+		_, _, err := s.Login(ctx, "ada", "Secret123!", "127.0.0.1", "test")
+		require.NoError(t, err)
+	}
+
+	// a goroutine per login would be 20 more
+	assert.Eventually(t, func() bool { return runtime.NumGoroutine() <= before+2 }, 2*time.Second, 10*time.Millisecond,
+		"%d goroutines before the logins, %d after", before, runtime.NumGoroutine())
+}
@@ -1,29 +1,151 @@
 package config
 
 import (
+	"reflect"
+
 	"github.com/kelseyhightower/envconfig"
 )
 
 type Config struct {
 	Port               string `envconfig:"PORT" default:"9000"`
-	DatabaseURL        string `envconfig:"DATABASE_URL" required:"true"`
-	JWTSecret          string `envconfig:"JWT_SECRET" required:"true" default:"your_very_strong_encypted_secret"`
+	DatabaseURL        string `envconfig:"DATABASE_URL" required:"true" secret:"true"`
+	JWTSecret          string `envconfig:"JWT_SECRET" required:"true" default:"your_very_strong_encypted_secret" secret:"true"`
 	JWTExpiry          int    `envconfig:"JWT_EXPIRY" default:"15"` // in minutes
-	JWTRefreshSecret   string `envconfig:"JWT_REFRESH_SECRET" default:"your_very_strong_encypted_secret"`
+	JWTRefreshSecret   string `envconfig:"JWT_REFRESH_SECRET" default:"your_very_strong_encypted_secret" secret:"true"`
 	JWTRefreshExpiry   int    `envconfig:"JWT_REFRESH_EXPIRY" default:"720"` // in hours
-	ApiVersion         string `envconfig:"API_VERSION" default:"v1.0.0"`
+	ApiVersion         string `envconfig:"API_VERSION" default:"v1.1.0"`
 	PlunkBaseUrl       string `envconfig:"PLUNK_BASE_URL"`
-	PlunkSecretKey     string `envconfig:"PLUNK_SECRET_KEY"`
+	PlunkSecretKey     string `envconfig:"PLUNK_SECRET_KEY" secret:"true"`
 	RedisHost          string `envconfig:"REDIS_HOST" default:"localhost"`
-	RedisPassword      string `envconfig:"REDIS_PASSWORD"`
+	RedisPassword      string `envconfig:"REDIS_PASSWORD" secret:"true"`
 	RedisPort          string `envconfig:"REDIS_PORT" default:"6379"`
 	LoginRateLimit     int    `envconfig:"LOGIN_RATE_LIMIT" default:"5"`
 	LoginRateWindow    int    `envconfig:"LOGIN_RATE_WINDOW" default:"15"`
 	LoginBlockDuration int    `envconfig:"LOGIN_BLOCK_DURATION" default:"30"`
 	IPRateLimit        int    `envconfig:"IP_RATE_LIMIT" default:"50"`
+	UserRateLimit      int    `envconfig:"USER_RATE_LIMIT" default:"120"` // per minute
 	GinMode            string `envconfig:"GIN_MODE" default:"release"`
 	PapertrailAddr     string `envconfig:"PAPERTRAIL_ADDR"`
-	PapertrailAppName string `envconfig:"PAPERTRAIL_APPNAME"`
+	PapertrailAppName  string `envconfig:"PAPERTRAIL_APPNAME"`
+	ReservationTTL     int    `envconfig:"RESERVATION_TTL" default:"15"`   // in minutes
+	ReservationSweep   int    `envconfig:"RESERVATION_SWEEP" default:"60"` // in seconds
+	BusinessScope      bool   `envconfig:"ENFORCE_BUSINESS_SCOPE" default:"true"`
+	PriceHistory       bool   `envconfig:"PRICE_HISTORY" default:"true"` // record variation price changes
+	SaleMaxLines       int    `envconfig:"SALE_MAX_LINES" default:"100"`
+	SaleMaxQuantity    int    `envconfig:"SALE_MAX_QUANTITY" default:"1000"`       // per line
+	SaleTaxOverride    bool   `envconfig:"SALE_TAX_RATE_OVERRIDE" default:"false"` // let sales set their own tax rate
+	DiscountStackCap   int    `envconfig:"DISCOUNT_STACK_CAP" default:"100"`       // percent of a sale's subtotal its discounts may take off
+	PasswordMinLength  int    `envconfig:"PASSWORD_MIN_LENGTH" default:"8"`
+	PasswordUpper      bool   `envconfig:"PASSWORD_REQUIRE_UPPER" default:"true"`
+	PasswordLower      bool   `envconfig:"PASSWORD_REQUIRE_LOWER" default:"true"`
+	PasswordDigit      bool   `envconfig:"PASSWORD_REQUIRE_DIGIT" default:"true"`
+	PasswordSymbol     bool   `envconfig:"PASSWORD_REQUIRE_SYMBOL" default:"false"`
+	PasswordHistory    int    `envconfig:"PASSWORD_HISTORY" default:"5"` // previous passwords that can't be reused
+	BackupInterval     int    `envconfig:"BACKUP_INTERVAL" default:"0"`  // in hours, 0 disables scheduled backups
+	BackupRetain       int    `envconfig:"BACKUP_RETAIN" default:"7"`
+	BackupTransactions bool   `envconfig:"BACKUP_INCLUDE_TRANSACTIONS" default:"false"`
+	BackupPrefix       string `envconfig:"BACKUP_PREFIX" default:"backups/"`
+	BackupDir          string `envconfig:"BACKUP_DIR" default:"tmp/backups"` // used when no S3 bucket is set
+	BackupS3Bucket     string `envconfig:"BACKUP_S3_BUCKET"`
+	BackupS3Region     string `envconfig:"BACKUP_S3_REGION" default:"us-east-1"`
+	BackupS3Endpoint   string `envconfig:"BACKUP_S3_ENDPOINT"`
+	BackupS3AccessKey  string `envconfig:"BACKUP_S3_ACCESS_KEY" secret:"true"`
+	BackupS3SecretKey  string `envconfig:"BACKUP_S3_SECRET_KEY" secret:"true"`
+
+	// refuse logins of admins who haven't verified their email
+	RequireVerifiedEmail bool `envconfig:"REQUIRE_VERIFIED_EMAIL" default:"true"`
+	// digits of the email verification and password reset codes
+	OTPLength int `envconfig:"OTP_LENGTH" default:"6"`
+	// bcrypt cost of password hashes, lower cost hashes are upgraded on login
+	BcryptCost int `envconfig:"BCRYPT_COST" default:"12"`
+	// page invite emails link to, with the invite token in the token query
+	// parameter, which it posts to /auth/accept-invite with the new password
+	InviteURL string `envconfig:"INVITE_URL" default:"http://localhost:3000/accept-invite"`
+
+	// units as name:short_code, created on startup with the colors if missing
+	SeedUnits  []string `envconfig:"SEED_UNITS" default:"Kilogram:kg,Gram:g,Litre:l,Millilitre:ml,Piece:pcs,Pack:pack"`
+	SeedColors []string `envconfig:"SEED_COLORS" default:"Black,White,Grey,Red,Blue,Green,Yellow,Brown"`
+
+	// log JSON request and response bodies, truncated to LogBodyMaxSize bytes
+	LogBodies      bool `envconfig:"LOG_BODIES" default:"false"`
+	LogBodyMaxSize int  `envconfig:"LOG_BODY_MAX_SIZE" default:"4096"`
+
+	// days a deleted business can still be restored
+	BusinessRestoreDays int `envconfig:"BUSINESS_RESTORE_DAYS" default:"30"`
+
+	// uploaded logos go to the S3 bucket when one is set, otherwise under
+	// UploadDir which the API serves itself
+	UploadDir         string `envconfig:"UPLOAD_DIR" default:"."`
+	UploadS3Bucket    string `envconfig:"UPLOAD_S3_BUCKET"`
+	UploadS3Region    string `envconfig:"UPLOAD_S3_REGION" default:"us-east-1"`
+	UploadS3Endpoint  string `envconfig:"UPLOAD_S3_ENDPOINT"`
+	UploadS3AccessKey string `envconfig:"UPLOAD_S3_ACCESS_KEY" secret:"true"`
+	UploadS3SecretKey string `envconfig:"UPLOAD_S3_SECRET_KEY" secret:"true"`
+	UploadPublicURL   string `envconfig:"UPLOAD_PUBLIC_URL"` // defaults to the bucket URL
+
+	// largest image upload in bytes and the content types accepted
+	UploadMaxSize    int64    `envconfig:"UPLOAD_MAX_SIZE" default:"2097152"`
+	UploadImageTypes []string `envconfig:"UPLOAD_IMAGE_TYPES" default:"image/jpeg,image/png,image/webp"`
+
+	// longest edge in pixels of the thumbnails made of uploaded images, 0
+	// makes none
+	UploadThumbnailSize int `envconfig:"UPLOAD_THUMBNAIL_SIZE" default:"256"`
+
+	// exchange rates are fetched from CurrencyRatesURL when set, otherwise
+	// CurrencyRates against CurrencyBase are used, cached for
+	// CurrencyRatesTTL minutes
+	CurrencyBase     string   `envconfig:"CURRENCY_BASE" default:"USD"`
+	CurrencyRates    []string `envconfig:"CURRENCY_RATES" default:"NGN:1500,EUR:0.92,GBP:0.79"`
+	CurrencyRatesURL string   `envconfig:"CURRENCY_RATES_URL"`
+	CurrencyRatesTTL int      `envconfig:"CURRENCY_RATES_TTL" default:"60"`
+
+	// minutes an Idempotency-Key of a sale is remembered
+	SaleIdempotencyTTL int `envconfig:"SALE_IDEMPOTENCY_TTL" default:"10"`
+
+	// access tokens are signed with JWTSecret under JWTKeyID, tokens of the
+	// previous keys, as kid:secret, keep verifying while they are listed
+	JWTKeyID        string   `envconfig:"JWT_KEY_ID" default:"default"`
+	JWTPreviousKeys []string `envconfig:"JWT_PREVIOUS_KEYS" secret:"true"`
+
+	// iss and aud claims of access tokens, tokens without the same are
+	// refused, empty ones aren't checked
+	JWTIssuer   string `envconfig:"JWT_ISSUER"`
+	JWTAudience string `envconfig:"JWT_AUDIENCE"`
+
+	// webhook deliveries are tried up to WebhookMaxAttempts times, waiting
+	// WebhookBackoff seconds before the first retry and doubling after
+	WebhookMaxAttempts int `envconfig:"WEBHOOK_MAX_ATTEMPTS" default:"5"`
+	WebhookBackoff     int `envconfig:"WEBHOOK_BACKOFF" default:"30"`
+	WebhookTimeout     int `envconfig:"WEBHOOK_TIMEOUT" default:"10"` // in seconds
+	WebhookWorkers     int `envconfig:"WEBHOOK_WORKERS" default:"4"`
+	WebhookQueueSize   int `envconfig:"WEBHOOK_QUEUE_SIZE" default:"1000"`
+
+	// emails go through Plunk, an SMTP server or nowhere with "none"
+	EmailProvider string `envconfig:"EMAIL_PROVIDER" default:"plunk"`
+	EmailFrom     string `envconfig:"EMAIL_FROM"`
+	SMTPHost      string `envconfig:"SMTP_HOST"`
+	SMTPPort      int    `envconfig:"SMTP_PORT" default:"587"`
+	SMTPUsername  string `envconfig:"SMTP_USERNAME"`
+	SMTPPassword  string `envconfig:"SMTP_PASSWORD" secret:"true"`
+
+	// emails are queued in redis and retried before being dead lettered
+	EmailMaxAttempts  int `envconfig:"EMAIL_MAX_ATTEMPTS" default:"5"`
+	EmailRetryBackoff int `envconfig:"EMAIL_RETRY_BACKOFF" default:"30"` // in seconds, doubled per attempt
+
+	// cross origin access to /api/v1, off while no origin is allowed
+	CORSAllowedOrigins   []string `envconfig:"CORS_ALLOWED_ORIGINS"`
+	CORSAllowedMethods   []string `envconfig:"CORS_ALLOWED_METHODS" default:"GET,POST,PUT,PATCH,DELETE"`
+	CORSAllowedHeaders   []string `envconfig:"CORS_ALLOWED_HEADERS" default:"Authorization,Content-Type,X-Request-ID,Idempotency-Key,X-Business-ID,Accept-Currency"`
+	CORSExposedHeaders   []string `envconfig:"CORS_EXPOSED_HEADERS" default:"X-Request-ID,X-RateLimit-Limit,X-RateLimit-Remaining,X-RateLimit-Reset,Retry-After,Idempotent-Replayed,Content-Disposition"`
+	CORSAllowCredentials bool     `envconfig:"CORS_ALLOW_CREDENTIALS" default:"false"`
+	CORSMaxAge           int      `envconfig:"CORS_MAX_AGE" default:"600"` // in seconds
+
+	// host and schemes the API docs send requests to, the host defaults to
+	// localhost on PORT
+	DocsHost    string   `envconfig:"DOCS_HOST"`
+	DocsSchemes []string `envconfig:"DOCS_SCHEMES" default:"http,https"`
+	// serve the API docs, unset means only outside of release mode
+	DocsEnabled *bool `envconfig:"DOCS_ENABLED"`
 }
 
 func Load() (*Config, error) {
@@ -34,3 +156,33 @@ func Load() (*Config, error) {
 	}
 	return &cfg, nil
 }
+
+// Redacted is shown in place of secrets that are set.
+const Redacted = "[REDACTED]"
+
+// Effective returns the configuration keyed by environment variable, with
+// the fields tagged secret replaced by Redacted, so it can be shown to
+// operators.
+func (c *Config) Effective() map[string]any {
+	effective := map[string]any{}
+	v := reflect.ValueOf(*c)
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		name := field.Tag.Get("envconfig")
+		if name == "" {
+			name = field.Name
+		}
+
+		value := v.Field(i).Interface()
+		if field.Tag.Get("secret") == "true" {
+			if v.Field(i).IsZero() {
+				value = ""
+			} else {
+				value = Redacted
+			}
+		}
+		effective[name] = value
+	}
+	return effective
+}
@@ -0,0 +1,130 @@
+package backup
+
+import (
+	"database/sql"
+	"fmt"
+	db "herp/db/sqlc"
+	"herp/internal/auth"
+	"herp/internal/utils"
+	"herp/pkg/jwt"
+	"herp/pkg/monitoring/logging"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+type Handler struct {
+	service BackupInterface
+	logger  *logging.Logger
+}
+
+func NewHandler(service BackupInterface, logger *logging.Logger) *Handler {
+	return &Handler{
+		service: service,
+		logger:  logger,
+	}
+}
+
+func (h *Handler) RegisterRoutes(r *gin.RouterGroup, authSvc *auth.Service) {
+	backups := r.Group("/admin/backups")
+	backups.Use(auth.AdminMiddleware(authSvc))
+	{
+		backups.GET("", h.ListBackups)
+		backups.POST("", h.CreateBackup)
+	}
+}
+
+type BackupResponse struct {
+	Key       string    `json:"key" example:"backups/herp-20240115T103000Z.json.gz"`
+	Size      int64     `json:"size" example:"20480"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+type FailureResponse struct {
+	At    time.Time `json:"at"`
+	Error string    `json:"error"`
+}
+
+type ListBackupsResponse struct {
+	Backups []BackupResponse `json:"backups"`
+	// set when the last backup failed
+	LastFailure *FailureResponse `json:"last_failure"`
+}
+
+// ListBackups godoc
+// @Summary List backups
+// @Description List the stored backups, newest first, and the last failure if the last backup failed.
+// @Tags backup
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} ListBackupsResponse
+// @Failure 401
+// @Failure 403
+// @Failure 500
+// @Router /api/v1/admin/backups [get]
+func (h *Handler) ListBackups(c *gin.Context) {
+	objects, err := h.service.List(c)
+	if err != nil {
+		h.logger.Errorf("error listing backups: %v", err)
+		utils.ErrorResponse(c, 500, utils.SERVERERROR)
+		return
+	}
+
+	response := ListBackupsResponse{Backups: make([]BackupResponse, 0, len(objects))}
+	for _, o := range objects {
+		response.Backups = append(response.Backups, BackupResponse{
+			Key:       o.Key,
+			Size:      o.Size,
+			CreatedAt: o.LastModified,
+		})
+	}
+	if failure := h.service.LastFailure(); failure != nil {
+		response.LastFailure = &FailureResponse{At: failure.At, Error: failure.Error}
+	}
+
+	utils.SuccessResponse(c, 200, "backups", response)
+}
+
+// CreateBackup godoc
+// @Summary Back up now
+// @Description Run a backup immediately instead of waiting for the schedule.
+// @Tags backup
+// @Produce json
+// @Security BearerAuth
+// @Success 201 {object} BackupResponse
+// @Failure 401
+// @Failure 403
+// @Failure 500
+// @Router /api/v1/admin/backups [post]
+func (h *Handler) CreateBackup(c *gin.Context) {
+	claims, ok := jwt.GetUserFromContext(c)
+	if !ok {
+		h.logger.Errorf("could not get user from context")
+		utils.ErrorResponse(c, 500, utils.SERVERERROR)
+		return
+	}
+
+	object, err := h.service.Run(c)
+	if err != nil {
+		h.logger.Errorf("error running backup: %v", err)
+		utils.ErrorResponse(c, 500, "backup failed")
+		return
+	}
+
+	if _, err := h.service.LogActivity(c, db.LogActivityParams{
+		UserID:     int32(claims.UserID),
+		Action:     "create_backup",
+		EntityType: "Backup",
+		Details:    utils.WriteActivityDetails(claims.Username, claims.Email, fmt.Sprintf("Created backup %s", object.Key), object.LastModified),
+		IpAddress:  sql.NullString{Valid: true, String: utils.GetClientIP(c)},
+		UserAgent:  sql.NullString{Valid: true, String: c.Request.UserAgent()},
+	}); err != nil {
+		h.logger.Warnf("error logging backup activity: %v", err)
+	}
+
+	utils.SuccessResponse(c, 201, "backup created", BackupResponse{
+		Key:       object.Key,
+		Size:      object.Size,
+		CreatedAt: object.LastModified,
+	})
+}
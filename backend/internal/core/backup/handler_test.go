@@ -0,0 +1,131 @@
+package backup
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	db "herp/db/sqlc"
+	"herp/internal/config"
+	"herp/pkg/jwt"
+	"herp/pkg/monitoring/logging"
+	"herp/pkg/storage"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// backupService runs backups into objects, failing them while err is set.
+type backupService struct {
+	objects    []storage.Object
+	failure    *Failure
+	err        error
+	runs       int
+	activities []db.LogActivityParams
+}
+
+func (s *backupService) Run(ctx context.Context) (storage.Object, error) {
+	s.runs++
+	if s.err != nil {
+		s.failure = &Failure{At: time.Now(), Error: s.err.Error()}
+		return storage.Object{}, s.err
+	}
+	s.failure = nil
+	object := storage.Object{Key: "backups/herp-20260301T090000Z.json.gz", Size: 2048, LastModified: time.Now()}
+	s.objects = append([]storage.Object{object}, s.objects...)
+	return object, nil
+}
+
+func (s *backupService) List(ctx context.Context) ([]storage.Object, error) {
+	return s.objects, nil
+}
+
+func (s *backupService) LastFailure() *Failure {
+	return s.failure
+}
+
+func (s *backupService) LogActivity(ctx context.Context, params db.LogActivityParams) (db.ActivityLog, error) {
+	s.activities = append(s.activities, params)
+	return db.ActivityLog{}, nil
+}
+
+func newBackupRouter(service BackupInterface) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	cfg := &config.Config{}
+	h := NewHandler(service, logging.NewLogger(cfg))
+
+	r := gin.New()
+	r.Use(func(c *gin.Context) {
+		c.Set("claims", &jwt.Claims{UserID: 1, Username: "admin", Email: "admin@example.com"})
+	})
+	r.GET("/admin/backups", h.ListBackups)
+	r.POST("/admin/backups", h.CreateBackup)
+	return r
+}
+
+func serve(r *gin.Engine, method string) *httptest.ResponseRecorder {
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(method, "/admin/backups", nil))
+	return w
+}
+
+func TestCreateBackup(t *testing.T) {
+	service := &backupService{}
+	r := newBackupRouter(service)
+
+	w := serve(r, http.MethodPost)
+	require.Equal(t, http.StatusCreated, w.Code, w.Body.String())
+	assert.Equal(t, 1, service.runs)
+
+	var body struct {
+		Data BackupResponse `json:"data"`
+	}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &body))
+	assert.Equal(t, "backups/herp-20260301T090000Z.json.gz", body.Data.Key)
+	assert.Equal(t, int64(2048), body.Data.Size)
+
+	require.Len(t, service.activities, 1)
+	assert.Equal(t, "create_backup", service.activities[0].Action)
+	assert.Equal(t, int32(1), service.activities[0].UserID)
+
+	// the backup made is listed
+	w = serve(r, http.MethodGet)
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	var list struct {
+		Data ListBackupsResponse `json:"data"`
+	}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &list))
+	require.Len(t, list.Data.Backups, 1)
+	assert.Equal(t, body.Data.Key, list.Data.Backups[0].Key)
+	assert.Nil(t, list.Data.LastFailure)
+}
+
+func TestCreateBackupFailure(t *testing.T) {
+	service := &backupService{err: errors.New("upload: bucket is read only")}
+	r := newBackupRouter(service)
+
+	w := serve(r, http.MethodPost)
+	assert.Equal(t, http.StatusInternalServerError, w.Code)
+	assert.Empty(t, service.activities)
+
+	// the failure is shown with the backups until one succeeds
+	w = serve(r, http.MethodGet)
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	var list struct {
+		Data ListBackupsResponse `json:"data"`
+	}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &list))
+	assert.Empty(t, list.Data.Backups)
+	require.NotNil(t, list.Data.LastFailure)
+	assert.Equal(t, "upload: bucket is read only", list.Data.LastFailure.Error)
+
+	service.err = nil
+	require.Equal(t, http.StatusCreated, serve(r, http.MethodPost).Code)
+	w = serve(r, http.MethodGet)
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &list))
+	assert.Nil(t, list.Data.LastFailure)
+}
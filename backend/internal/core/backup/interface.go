@@ -0,0 +1,29 @@
+package backup
+
+import (
+	"context"
+	db "herp/db/sqlc"
+	"herp/pkg/storage"
+)
+
+type Querier interface {
+	ExportBusinesses(ctx context.Context) ([]db.Business, error)
+	ExportBranches(ctx context.Context) ([]db.Branch, error)
+	ExportStores(ctx context.Context) ([]db.Store, error)
+	ExportCategories(ctx context.Context) ([]db.Category, error)
+	ExportBrands(ctx context.Context) ([]db.Brand, error)
+	ExportUnits(ctx context.Context) ([]db.Unit, error)
+	ExportItems(ctx context.Context) ([]db.Item, error)
+	ExportVariations(ctx context.Context) ([]db.Variation, error)
+	ExportInventory(ctx context.Context) ([]db.Inventory, error)
+	ExportSales(ctx context.Context) ([]db.Sale, error)
+	ExportSaleItems(ctx context.Context) ([]db.SaleItem, error)
+	LogActivity(ctx context.Context, params db.LogActivityParams) (db.ActivityLog, error)
+}
+
+type BackupInterface interface {
+	Run(ctx context.Context) (storage.Object, error)
+	List(ctx context.Context) ([]storage.Object, error)
+	LastFailure() *Failure
+	LogActivity(ctx context.Context, params db.LogActivityParams) (db.ActivityLog, error)
+}
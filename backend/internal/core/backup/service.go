@@ -0,0 +1,249 @@
+package backup
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	db "herp/db/sqlc"
+	"herp/pkg/storage"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Export is the content of a backup. Sales and stock levels are only filled
+// in when transactional data is included.
+type Export struct {
+	CreatedAt  time.Time      `json:"created_at"`
+	Businesses []db.Business  `json:"businesses"`
+	Branches   []db.Branch    `json:"branches"`
+	Stores     []db.Store     `json:"stores"`
+	Categories []db.Category  `json:"categories"`
+	Brands     []db.Brand     `json:"brands"`
+	Units      []db.Unit      `json:"units"`
+	Items      []db.Item      `json:"items"`
+	Variations []db.Variation `json:"variations"`
+	Inventory  []db.Inventory `json:"inventory,omitempty"`
+	Sales      []db.Sale      `json:"sales,omitempty"`
+	SaleItems  []db.SaleItem  `json:"sale_items,omitempty"`
+}
+
+// Failure is the last backup that failed.
+type Failure struct {
+	At    time.Time
+	Error string
+}
+
+type Options struct {
+	Prefix              string // key prefix backups are stored under
+	Retain              int    // backups kept, older ones are deleted
+	IncludeTransactions bool
+}
+
+type Backup struct {
+	db      *sql.DB
+	queries Querier
+	storage storage.Storage
+	opts    Options
+
+	running     sync.Mutex // one backup runs at a time
+	mu          sync.Mutex // guards lastFailure
+	lastFailure *Failure
+}
+
+func NewBackup(db *sql.DB, queries Querier, store storage.Storage, opts Options) *Backup {
+	return &Backup{
+		db:      db,
+		queries: queries,
+		storage: store,
+		opts:    opts,
+	}
+}
+
+func (b *Backup) LogActivity(ctx context.Context, params db.LogActivityParams) (db.ActivityLog, error) {
+	return b.queries.LogActivity(ctx, params)
+}
+
+// Run exports the business configuration, and transactional data when
+// enabled, uploads it as gzipped JSON and prunes backups beyond the retention
+// count. A failed run is recorded in the activity log and remembered until
+// the next successful one.
+func (b *Backup) Run(ctx context.Context) (storage.Object, error) {
+	b.running.Lock()
+	defer b.running.Unlock()
+
+	object, err := b.run(ctx)
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if err != nil {
+		b.lastFailure = &Failure{At: time.Now(), Error: err.Error()}
+		// user 0 is the system, scheduled backups have nobody behind them
+		b.queries.LogActivity(ctx, db.LogActivityParams{
+			Action:     "backup_failed",
+			EntityType: "Backup",
+			Details:    err.Error(),
+		})
+		return storage.Object{}, err
+	}
+	b.lastFailure = nil
+	return object, nil
+}
+
+func (b *Backup) run(ctx context.Context) (storage.Object, error) {
+	export, err := b.export(ctx)
+	if err != nil {
+		return storage.Object{}, fmt.Errorf("export: %w", err)
+	}
+
+	var buf bytes.Buffer
+	zw := gzip.NewWriter(&buf)
+	if err := json.NewEncoder(zw).Encode(export); err != nil {
+		return storage.Object{}, err
+	}
+	if err := zw.Close(); err != nil {
+		return storage.Object{}, err
+	}
+
+	key := b.opts.Prefix + "herp-" + export.CreatedAt.UTC().Format("20060102T150405Z") + ".json.gz"
+	if err := b.storage.Put(ctx, key, buf.Bytes(), "application/gzip"); err != nil {
+		return storage.Object{}, fmt.Errorf("upload: %w", err)
+	}
+
+	if err := b.prune(ctx); err != nil {
+		return storage.Object{}, fmt.Errorf("prune: %w", err)
+	}
+
+	return storage.Object{Key: key, Size: int64(buf.Len()), LastModified: export.CreatedAt}, nil
+}
+
+// export reads everything in one read only snapshot so the backup is
+// consistent.
+func (b *Backup) export(ctx context.Context) (Export, error) {
+	q, ok := b.queries.(*db.Queries)
+	if !ok {
+		return Export{}, fmt.Errorf("invalid query type in backup")
+	}
+
+	tx, err := b.db.BeginTx(ctx, &sql.TxOptions{Isolation: sql.LevelRepeatableRead, ReadOnly: true})
+	if err != nil {
+		return Export{}, err
+	}
+	defer tx.Rollback()
+
+	txQueries := q.WithTx(tx)
+	export := Export{CreatedAt: time.Now()}
+
+	if export.Businesses, err = txQueries.ExportBusinesses(ctx); err != nil {
+		return Export{}, err
+	}
+	if export.Branches, err = txQueries.ExportBranches(ctx); err != nil {
+		return Export{}, err
+	}
+	if export.Stores, err = txQueries.ExportStores(ctx); err != nil {
+		return Export{}, err
+	}
+	if export.Categories, err = txQueries.ExportCategories(ctx); err != nil {
+		return Export{}, err
+	}
+	if export.Brands, err = txQueries.ExportBrands(ctx); err != nil {
+		return Export{}, err
+	}
+	if export.Units, err = txQueries.ExportUnits(ctx); err != nil {
+		return Export{}, err
+	}
+	if export.Items, err = txQueries.ExportItems(ctx); err != nil {
+		return Export{}, err
+	}
+	if export.Variations, err = txQueries.ExportVariations(ctx); err != nil {
+		return Export{}, err
+	}
+
+	if b.opts.IncludeTransactions {
+		if export.Inventory, err = txQueries.ExportInventory(ctx); err != nil {
+			return Export{}, err
+		}
+		if export.Sales, err = txQueries.ExportSales(ctx); err != nil {
+			return Export{}, err
+		}
+		if export.SaleItems, err = txQueries.ExportSaleItems(ctx); err != nil {
+			return Export{}, err
+		}
+	}
+
+	return export, nil
+}
+
+// List returns the stored backups, newest first.
+func (b *Backup) List(ctx context.Context) ([]storage.Object, error) {
+	objects, err := b.storage.List(ctx, b.opts.Prefix)
+	if err != nil {
+		return nil, err
+	}
+
+	backups := objects[:0]
+	for _, o := range objects {
+		if strings.HasSuffix(o.Key, ".json.gz") {
+			backups = append(backups, o)
+		}
+	}
+	// keys carry the time they were taken, so they sort chronologically
+	sort.Slice(backups, func(i, j int) bool { return backups[i].Key > backups[j].Key })
+	return backups, nil
+}
+
+// prune deletes the backups beyond the newest opts.Retain.
+func (b *Backup) prune(ctx context.Context) error {
+	if b.opts.Retain <= 0 {
+		return nil
+	}
+
+	backups, err := b.List(ctx)
+	if err != nil {
+		return err
+	}
+	if len(backups) <= b.opts.Retain {
+		return nil
+	}
+	for _, o := range backups[b.opts.Retain:] {
+		if err := b.storage.Delete(ctx, o.Key); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// LastFailure returns the last failed backup, or nil if the last one
+// succeeded.
+func (b *Backup) LastFailure() *Failure {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.lastFailure
+}
+
+// StartScheduler runs a backup every interval until ctx is cancelled. The
+// returned channel is closed once the scheduler has stopped, after a backup
+// under way has returned.
+func (b *Backup) StartScheduler(ctx context.Context, interval time.Duration, onError func(error)) <-chan struct{} {
+	done := make(chan struct{})
+	ticker := time.NewTicker(interval)
+	go func() {
+		defer close(done)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				if _, err := b.Run(ctx); err != nil && onError != nil {
+					onError(err)
+				}
+			}
+		}
+	}()
+	return done
+}
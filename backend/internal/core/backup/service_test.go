@@ -0,0 +1,155 @@
+package backup
+
+import (
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"herp/db/dbtest"
+	db "herp/db/sqlc"
+	"herp/pkg/storage"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// putBackups stores a backup for each of the last n days, named like Run
+// names them.
+func putBackups(t *testing.T, store storage.Storage, prefix string, n int) []string {
+	t.Helper()
+	keys := make([]string, 0, n)
+	day := time.Date(2026, 1, 1, 3, 0, 0, 0, time.UTC)
+	for i := range n {
+		key := prefix + "herp-" + day.AddDate(0, 0, i).Format("20060102T150405Z") + ".json.gz"
+		require.NoError(t, store.Put(context.Background(), key, []byte("{}"), "application/gzip"))
+		keys = append(keys, key)
+	}
+	return keys
+}
+
+func listKeys(t *testing.T, b *Backup) []string {
+	t.Helper()
+	objects, err := b.List(context.Background())
+	require.NoError(t, err)
+	keys := []string{}
+	for _, o := range objects {
+		keys = append(keys, o.Key)
+	}
+	return keys
+}
+
+func TestListNewestFirst(t *testing.T) {
+	store := storage.NewLocal(t.TempDir())
+	keys := putBackups(t, store, "backups/", 3)
+	// files that aren't backups are left out
+	require.NoError(t, store.Put(context.Background(), "backups/README.txt", []byte("notes"), "text/plain"))
+
+	b := NewBackup(nil, nil, store, Options{Prefix: "backups/"})
+	assert.Equal(t, []string{keys[2], keys[1], keys[0]}, listKeys(t, b))
+}
+
+func TestPrune(t *testing.T) {
+	tests := []struct {
+		name   string
+		retain int
+		stored int
+		want   int
+	}{
+		{"over the retention", 3, 5, 3},
+		{"at the retention", 3, 3, 3},
+		{"under the retention", 3, 2, 2},
+		{"retention off", 0, 5, 5},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store := storage.NewLocal(t.TempDir())
+			keys := putBackups(t, store, "backups/", tt.stored)
+			require.NoError(t, store.Put(context.Background(), "backups/README.txt", []byte("notes"), "text/plain"))
+
+			b := NewBackup(nil, nil, store, Options{Prefix: "backups/", Retain: tt.retain})
+			require.NoError(t, b.prune(context.Background()))
+
+			// the newest are kept
+			var want []string
+			for i := len(keys) - 1; i >= len(keys)-tt.want; i-- {
+				want = append(want, keys[i])
+			}
+			assert.Equal(t, want, listKeys(t, b))
+
+			_, err := store.Get(context.Background(), "backups/README.txt")
+			assert.NoError(t, err, "a file that isn't a backup was pruned")
+		})
+	}
+}
+
+// failingStorage refuses every upload.
+type failingStorage struct {
+	storage.Storage
+}
+
+func (failingStorage) Put(ctx context.Context, key string, body []byte, contentType string) error {
+	return errors.New("bucket is read only")
+}
+
+func TestRun(t *testing.T) {
+	conn := dbtest.Open(t)
+	owner := dbtest.Admin(t, conn, "owner")
+	dbtest.Business(t, conn, owner, "Palmwine Express")
+
+	store := storage.NewLocal(t.TempDir())
+	old := putBackups(t, store, "backups/", 3)
+	b := NewBackup(conn, db.New(conn), store, Options{Prefix: "backups/", Retain: 2})
+
+	object, err := b.Run(context.Background())
+	require.NoError(t, err)
+	assert.Nil(t, b.LastFailure())
+
+	// the new backup and the newest of the old ones are kept
+	assert.Equal(t, []string{object.Key, old[2]}, listKeys(t, b))
+
+	r, err := store.Get(context.Background(), object.Key)
+	require.NoError(t, err)
+	defer r.Close()
+	zr, err := gzip.NewReader(r)
+	require.NoError(t, err)
+	var export Export
+	require.NoError(t, json.NewDecoder(zr).Decode(&export))
+	require.Len(t, export.Businesses, 1)
+	assert.Equal(t, "Palmwine Express", export.Businesses[0].Name)
+	assert.Len(t, export.Branches, 1)
+	// transactional data is left out unless asked for
+	assert.Nil(t, export.Sales)
+}
+
+func TestRunFailure(t *testing.T) {
+	conn := dbtest.Open(t)
+	b := NewBackup(conn, db.New(conn), failingStorage{storage.NewLocal(t.TempDir())}, Options{Prefix: "backups/"})
+
+	_, err := b.Run(context.Background())
+	require.Error(t, err)
+
+	failure := b.LastFailure()
+	require.NotNil(t, failure)
+	assert.Equal(t, "upload: bucket is read only", failure.Error)
+
+	var action, details string
+	require.NoError(t, conn.QueryRow(`SELECT action, details FROM activity_logs WHERE entity_type = 'Backup'`).Scan(&action, &details))
+	assert.Equal(t, "backup_failed", action)
+	assert.Equal(t, fmt.Sprint(err), details)
+}
+
+func TestStartSchedulerStops(t *testing.T) {
+	b := NewBackup(nil, nil, storage.NewLocal(t.TempDir()), Options{Prefix: "backups/"})
+	ctx, cancel := context.WithCancel(context.Background())
+	done := b.StartScheduler(ctx, time.Hour, func(err error) { t.Errorf("unexpected error: %v", err) })
+
+	cancel()
+	select {
+	case <-done:
+	case <-time.After(2 * time.Second):
+		t.Fatal("backup scheduler did not stop")
+	}
+}
@@ -0,0 +1,102 @@
+package business
+
+import (
+	"database/sql"
+	"encoding/json"
+	"herp/db/dbtest"
+	db "herp/db/sqlc"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// logActivity logs an action on an entity at a fixed time.
+func logActivity(t *testing.T, conn *sql.DB, userID int32, action, entityType string, entityID int32, at string) {
+	t.Helper()
+	dbtest.Exec(t, conn, `
+		INSERT INTO activity_logs (user_id, action, details, entity_id, entity_type, created_at)
+		VALUES ($1, $2, $2, $3, $4, $5)`, userID, action, entityID, entityType, at)
+}
+
+func listActivity(t *testing.T, conn *sql.DB, ownerID int32, query string) ListActivityLogsResponse {
+	t.Helper()
+	h, r := newBusinessRouter(NewBusiness(db.New(conn), conn), int(ownerID))
+	r.GET("/business/activity", h.GetActivityLogs)
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/business/activity"+query, nil))
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	var body struct {
+		Data ListActivityLogsResponse `json:"data"`
+	}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &body))
+	return body.Data
+}
+
+func actions(response ListActivityLogsResponse) []string {
+	got := []string{}
+	for _, log := range response.Logs {
+		got = append(got, log.Action)
+	}
+	return got
+}
+
+func TestGetActivityLogsIsolation(t *testing.T) {
+	conn := dbtest.Open(t)
+	ada := dbtest.Admin(t, conn, "ada")
+	bayo := dbtest.Admin(t, conn, "bayo")
+	adaBusiness, adaBranch := dbtest.Business(t, conn, ada, "Palmwine Express")
+	adaStore := dbtest.Store(t, conn, adaBranch, "Bar")
+	bayoBusiness, bayoBranch := dbtest.Business(t, conn, bayo, "Suya Spot")
+	bayoStore := dbtest.Store(t, conn, bayoBranch, "Grill")
+
+	logActivity(t, conn, ada, "create_business", "Business", adaBusiness, "2026-03-01 09:00:00")
+	logActivity(t, conn, ada, "create_branch", "Branch", adaBranch, "2026-03-02 09:00:00")
+	logActivity(t, conn, ada, "create_store", "Store", adaStore, "2026-03-03 09:00:00")
+	logActivity(t, conn, bayo, "create_business", "Business", bayoBusiness, "2026-03-01 10:00:00")
+	logActivity(t, conn, bayo, "create_branch", "Branch", bayoBranch, "2026-03-02 10:00:00")
+	logActivity(t, conn, bayo, "create_store", "Store", bayoStore, "2026-03-03 10:00:00")
+	// activity on bayo's business by someone else is still bayo's to see
+	logActivity(t, conn, ada, "update_business", "Business", bayoBusiness, "2026-03-04 10:00:00")
+
+	got := listActivity(t, conn, ada, "")
+	assert.Equal(t, []string{"create_store", "create_branch", "create_business"}, actions(got))
+	assert.Equal(t, int64(3), got.Total)
+	for _, log := range got.Logs {
+		assert.Contains(t, []int32{adaBusiness, adaBranch, adaStore}, log.EntityID)
+	}
+
+	got = listActivity(t, conn, bayo, "")
+	assert.Equal(t, []string{"update_business", "create_store", "create_branch", "create_business"}, actions(got))
+	assert.Equal(t, int64(4), got.Total)
+
+	// an owner with no businesses sees nothing
+	nobody := dbtest.Admin(t, conn, "nobody")
+	got = listActivity(t, conn, nobody, "")
+	assert.Empty(t, got.Logs)
+	assert.Zero(t, got.Total)
+}
+
+func TestGetActivityLogsFilters(t *testing.T) {
+	conn := dbtest.Open(t)
+	ada := dbtest.Admin(t, conn, "ada")
+	business, branch := dbtest.Business(t, conn, ada, "Palmwine Express")
+	logActivity(t, conn, ada, "create_business", "Business", business, "2026-03-01 09:00:00")
+	logActivity(t, conn, ada, "create_branch", "Branch", branch, "2026-03-02 09:00:00")
+	logActivity(t, conn, ada, "update_branch", "Branch", branch, "2026-03-03 09:00:00")
+
+	got := listActivity(t, conn, ada, "?entity_type=Branch")
+	assert.Equal(t, []string{"update_branch", "create_branch"}, actions(got))
+
+	// the end date takes in the whole day
+	got = listActivity(t, conn, ada, "?start=2026-03-01&end=2026-03-02")
+	assert.Equal(t, []string{"create_branch", "create_business"}, actions(got))
+
+	got = listActivity(t, conn, ada, "?page=2&limit=2")
+	assert.Equal(t, []string{"create_business"}, actions(got))
+	assert.Equal(t, int64(3), got.Total)
+	assert.Equal(t, 2, got.Page)
+}
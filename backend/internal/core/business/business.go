@@ -2,14 +2,19 @@ package business
 
 import (
 	"database/sql"
+	"errors"
 	"fmt"
 	db "herp/db/sqlc"
 	"herp/internal/auth"
 	"herp/internal/config"
 	"herp/internal/utils"
+	"herp/pkg/currency"
 	"herp/pkg/jwt"
 	"herp/pkg/monitoring/logging"
+	"herp/pkg/storage"
+	"math"
 	"strconv"
+	"strings"
 	"time"
 
 	"github.com/gin-gonic/gin"
@@ -21,13 +26,17 @@ type Handler struct {
 	service BusinessInterface
 	config  *config.Config
 	logger  *logging.Logger
+	files   storage.FileStore
+	rates   *currency.Converter
 }
 
-func NewBusinessHandler(service BusinessInterface, c *config.Config, l *logging.Logger) *Handler {
+func NewBusinessHandler(service BusinessInterface, c *config.Config, l *logging.Logger, files storage.FileStore, rates *currency.Converter) *Handler {
 	return &Handler{
 		service: service,
 		config:  c,
 		logger:  l,
+		files:   files,
+		rates:   rates,
 	}
 }
 
@@ -38,10 +47,14 @@ func (h *Handler) RegisterRoutes(r *gin.RouterGroup, authSvc *auth.Service) {
 	{
 		business.POST("", auth.PermissionMiddleware(authSvc, "business:create"), h.createBusinessWithBranch)
 		business.GET("/:id", auth.PermissionMiddleware(authSvc, "business:view"), h.getBusiness)
+		business.GET("/:id/convert", auth.PermissionMiddleware(authSvc, "business:view"), h.convertAmount)
 		business.PATCH("/:id", auth.PermissionMiddleware(authSvc, "business:update"), h.updateBusiness)
 		business.DELETE("/:id", auth.PermissionMiddleware(authSvc, "business:delete"), h.deleteBusiness)
+		business.GET("/:id/settings/history", auth.PermissionMiddleware(authSvc, "business:view"), h.listSettingsHistory)
+		business.POST("/:id/restore", auth.PermissionMiddleware(authSvc, "business:delete"), h.restoreBusiness)
 		business.GET("/all", auth.PermissionMiddleware(authSvc, "business:view"), h.listBusinesses)
 		business.POST("/create", auth.PermissionMiddleware(authSvc, "business:create"), h.createBusiness)
+		business.GET("/activity", auth.PermissionMiddleware(authSvc, "business:view"), h.GetActivityLogs)
 	}
 
 	branch := business.Group("/branch")
@@ -63,7 +76,7 @@ type CreateBusinessParams struct {
 	LogoUrl           *string  `form:"logo_url" binding:"omitempty" example:"https://imgur.com/234343"`
 	Rounding          *string  `form:"rounding" binding:"omitempty" example:"nearest"`
 	Currency          *string  `form:"currency" binding:"omitempty" example:"NGN"`
-	Timezone          *string  `form:"timezone" binding:"omitempty" example:"UTC +1"`
+	Timezone          *string  `form:"timezone" binding:"omitempty" example:"Africa/Lagos"`
 	Language          *string  `form:"language" binding:"omitempty" example:"en"`
 	LowStockThreshold *int     `form:"low_stock_threshold" binding:"omitempty" example:"5"`
 	AllowOverselling  *bool    `form:"allow_overselling" binding:"omitempty" example:"false"`
@@ -71,7 +84,7 @@ type CreateBusinessParams struct {
 	Font              *string  `form:"font" binding:"omitempty"`
 	PrimaryColor      *string  `form:"primary_color" binding:"omitempty"`
 	Motto             *string  `form:"motto" binding:"omitempty"`
-	Country           *string  `form:"country" binding:"omitempty" example:"Nigeria"`
+	Country           *string  `form:"country" binding:"omitempty" example:"NG"`
 }
 
 type CreateBusinesswithBranchResponse struct {
@@ -82,9 +95,10 @@ type CreateBusinesswithBranchResponse struct {
 	TaxID             string   `json:"tax_id" binding:"omitempty" example:"123456789"`
 	TaxRate           string   `json:"tax_rate" binding:"omitempty" example:"12"`
 	LogoUrl           string   `json:"logo_url" binding:"omitempty" example:"https://imgur.com/234343"`
+	LogoThumbnailUrl  string   `json:"logo_thumbnail_url"`
 	Rounding          string   `json:"rounding" binding:"omitempty" example:"nearest"`
 	Currency          string   `json:"currency" binding:"omitempty" example:"NGN"`
-	Timezone          string   `json:"timezone" binding:"omitempty" example:"UTC +1"`
+	Timezone          string   `json:"timezone" binding:"omitempty" example:"Africa/Lagos"`
 	Language          string   `json:"language" binding:"omitempty" example:"en"`
 	LowStockThreshold int32    `json:"low_stock_threshold" binding:"omitempty" example:"5"`
 	AllowOverselling  bool     `json:"allow_overselling" binding:"omitempty" example:"false"`
@@ -92,7 +106,7 @@ type CreateBusinesswithBranchResponse struct {
 	Font              string   `json:"font" binding:"omitempty"`
 	PrimaryColor      string   `json:"primary_color" binding:"omitempty"`
 	Motto             string   `json:"motto" binding:"omitempty"`
-	Country           string   `json:"country" binding:"omitempty" example:"Nigeria"`
+	Country           string   `json:"country" binding:"omitempty" example:"NG"`
 	Branch            Branch   `json:"branch"`
 }
 
@@ -104,9 +118,10 @@ type BusinessResponse struct {
 	TaxID             string    `json:"tax_id" binding:"omitempty" example:"123456789"`
 	TaxRate           string    `json:"tax_rate" binding:"omitempty" example:"12"`
 	LogoUrl           string    `json:"logo_url" binding:"omitempty" example:"https://imgur.com/234343"`
+	LogoThumbnailUrl  string    `json:"logo_thumbnail_url"`
 	Rounding          string    `json:"rounding" binding:"omitempty" example:"nearest"`
 	Currency          string    `json:"currency" binding:"omitempty" example:"NGN"`
-	Timezone          string    `json:"timezone" binding:"omitempty" example:"UTC +1"`
+	Timezone          string    `json:"timezone" binding:"omitempty" example:"Africa/Lagos"`
 	Language          string    `json:"language" binding:"omitempty" example:"en"`
 	LowStockThreshold int32     `json:"low_stock_threshold" binding:"omitempty" example:"5"`
 	AllowOverselling  bool      `json:"allow_overselling" binding:"omitempty" example:"false"`
@@ -114,9 +129,11 @@ type BusinessResponse struct {
 	Font              string    `json:"font" binding:"omitempty"`
 	PrimaryColor      string    `json:"primary_color" binding:"omitempty"`
 	Motto             string    `json:"motto" binding:"omitempty"`
-	Country           string    `json:"country" binding:"omitempty" example:"Nigeria"`
+	Country           string    `json:"country" binding:"omitempty" example:"NG"`
 	CreateAt          time.Time `json:"created_at"`
 	UpdateAt          time.Time `json:"updated_at"`
+	// counts the updates, send it back with an update to reject stale ones
+	Version int32 `json:"version" example:"1"`
 }
 
 type Branch struct {
@@ -137,9 +154,9 @@ type Branch struct {
 // @Param website formData string false "Business website"
 // @Param tax_id formData string false "Tax ID"
 // @Param tax_rate formData string false "Tax Rate"
-// @Param currency formData string false "Currency code (e.g. NGN)"
-// @Param timezone formData string false "Timezone (e.g. UTC+1)"
-// @Param country formData string false "Country"
+// @Param currency formData string false "ISO 4217 currency code (e.g. NGN)"
+// @Param timezone formData string false "IANA timezone (e.g. Africa/Lagos)"
+// @Param country formData string false "ISO 3166 country code (e.g. NG)"
 // @Param payment_type formData []string false "Accepted payment types (e.g. cash,pos,room_charge,transfer)"
 // @Param low_stock_threshold formData int false "Low stock threshold"
 // @Param allow_overselling formData bool false "Allow overselling"
@@ -173,14 +190,27 @@ func (h *Handler) createBusiness(c *gin.Context) {
 	var req CreateBusinessParams
 	if err := c.ShouldBind(&req); err != nil {
 		h.logger.Errorf("error binding creating business request data: %v", err)
-		utils.ErrorResponse(c, 400, utils.INVALID_REQUEST_DATA)
+		utils.BindingErrorResponse(c, err)
+		return
+	}
+	if fields := checkLocale(req.Currency, req.Timezone, req.Country); fields != nil {
+		utils.ErrorResponseWithData(c, 400, utils.VALIDATION_FAILED, fields)
 		return
 	}
 
 	// Handle file upload if present
-	logoUrl, err := utils.UploadFile(c, "logo", "images", 2<<20) // 2MB max
-	if err == nil && logoUrl != "" {
-		req.LogoUrl = &logoUrl
+	logo, err := utils.UploadFile(c, h.files, "logo", "images", utils.ImageUploadOptions(h.config))
+	if err != nil {
+		if errors.Is(err, utils.ErrFileTooLarge) || errors.Is(err, utils.ErrFileType) {
+			utils.ErrorResponse(c, 400, err.Error())
+			return
+		}
+		h.logger.Errorf("error uploading business logo: %v", err)
+		utils.ErrorResponse(c, 500, utils.SERVERERROR)
+		return
+	}
+	if logo.URL != "" {
+		req.LogoUrl = &logo.URL
 	}
 
 	var params db.CreateBusinessParams
@@ -190,6 +220,9 @@ func (h *Handler) createBusiness(c *gin.Context) {
 		utils.ErrorResponse(c, 500, utils.SERVERERROR)
 		return
 	}
+	if logo.ThumbnailURL != "" {
+		params.LogoThumbnailUrl = sql.NullString{String: logo.ThumbnailURL, Valid: true}
+	}
 
 	business, err := h.service.CreateBusiness(c, params)
 	if err != nil {
@@ -235,6 +268,7 @@ func (h *Handler) createBusiness(c *gin.Context) {
 		TaxID:             business.TaxID.String,
 		TaxRate:           business.TaxRate.String,
 		LogoUrl:           business.LogoUrl.String,
+		LogoThumbnailUrl:  business.LogoThumbnailUrl.String,
 		Rounding:          business.Rounding.String,
 		Currency:          business.Currency.String,
 		Timezone:          business.Timezone.String,
@@ -246,6 +280,7 @@ func (h *Handler) createBusiness(c *gin.Context) {
 		PrimaryColor:      business.PrimaryColor.String,
 		Motto:             business.Motto.String,
 		Country:           business.Country,
+		Version:           business.Version,
 	})
 
 }
@@ -262,9 +297,9 @@ func (h *Handler) createBusiness(c *gin.Context) {
 // @Param website formData string false "Business website"
 // @Param tax_id formData string false "Tax ID"
 // @Param tax_rate formData string false "Tax Rate"
-// @Param currency formData string false "Currency code (e.g. NGN)"
-// @Param timezone formData string false "Timezone (e.g. UTC+1)"
-// @Param country formData string false "Country"
+// @Param currency formData string false "ISO 4217 currency code (e.g. NGN)"
+// @Param timezone formData string false "IANA timezone (e.g. Africa/Lagos)"
+// @Param country formData string false "ISO 3166 country code (e.g. NG)"
 // @Param payment_type formData []string false "Accepted payment types (e.g. cash,pos,room_charge,transfer)"
 // @Param low_stock_threshold formData int false "Low stock threshold"
 // @Param allow_overselling formData bool false "Allow overselling"
@@ -298,13 +333,27 @@ func (h *Handler) createBusinessWithBranch(c *gin.Context) {
 	var req CreateBusinessParams
 	if err := c.ShouldBind(&req); err != nil {
 		h.logger.Errorf("error binding creating business request data: %v", err)
-		utils.ErrorResponse(c, 400, utils.INVALID_REQUEST_DATA)
+		utils.BindingErrorResponse(c, err)
+		return
+	}
+	if fields := checkLocale(req.Currency, req.Timezone, req.Country); fields != nil {
+		utils.ErrorResponseWithData(c, 400, utils.VALIDATION_FAILED, fields)
 		return
 	}
 
-	logoUrl, err := utils.UploadFile(c, "logo", "images", 2<<20) // 2MB max
-	if err == nil && logoUrl != "" {
-		req.LogoUrl = &logoUrl
+	// Handle file upload if present
+	logo, err := utils.UploadFile(c, h.files, "logo", "images", utils.ImageUploadOptions(h.config))
+	if err != nil {
+		if errors.Is(err, utils.ErrFileTooLarge) || errors.Is(err, utils.ErrFileType) {
+			utils.ErrorResponse(c, 400, err.Error())
+			return
+		}
+		h.logger.Errorf("error uploading business logo: %v", err)
+		utils.ErrorResponse(c, 500, utils.SERVERERROR)
+		return
+	}
+	if logo.URL != "" {
+		req.LogoUrl = &logo.URL
 	}
 
 	var params db.CreateBusinessParams
@@ -314,6 +363,9 @@ func (h *Handler) createBusinessWithBranch(c *gin.Context) {
 		utils.ErrorResponse(c, 500, utils.SERVERERROR)
 		return
 	}
+	if logo.ThumbnailURL != "" {
+		params.LogoThumbnailUrl = sql.NullString{String: logo.ThumbnailURL, Valid: true}
+	}
 
 	params.OwnerID = int32(claims.UserID)
 
@@ -361,6 +413,7 @@ func (h *Handler) createBusinessWithBranch(c *gin.Context) {
 		TaxID:             business.TaxID.String,
 		TaxRate:           business.TaxRate.String,
 		LogoUrl:           business.LogoUrl.String,
+		LogoThumbnailUrl:  business.LogoThumbnailUrl.String,
 		Rounding:          business.Rounding.String,
 		Currency:          business.Currency.String,
 		Timezone:          business.Timezone.String,
@@ -391,6 +444,7 @@ func (h *Handler) createBusinessWithBranch(c *gin.Context) {
 // @Failure 400
 // @Failure 401
 // @Failure 403
+// @Failure 404
 // @Failure 500
 // @Router /api/v1/business/:id [get]
 func (h *Handler) getBusiness(c *gin.Context) {
@@ -413,29 +467,120 @@ func (h *Handler) getBusiness(c *gin.Context) {
 		OwnerID: int32(claims.UserID),
 	}
 
-	fmt.Printf("business id: %d and owner id: %d", bid, claims.UserID)
 	business, err := h.service.GetBusiness(c, params)
 	if err != nil {
+		// businesses of other owners aren't found either
+		if errors.Is(err, sql.ErrNoRows) {
+			utils.ErrorResponse(c, 404, "business not found")
+			return
+		}
 		h.logger.Errorf("error getting business with is %d: %v", bid, err)
 		utils.ErrorResponse(c, 500, utils.SERVERERROR)
 		return
 	}
 
 	utils.SuccessResponse(c, 200, "get business successful", BusinessResponse{
-		ID:       business.ID,
-		Name:     business.Name,
-		Motto:    business.Motto.String,
-		Email:    business.Email.String,
-		Website:  business.Website.String,
-		TaxID:    business.TaxID.String,
-		TaxRate:  business.TaxRate.String,
-		LogoUrl:  business.LogoUrl.String,
-		Rounding: business.Rounding.String,
-		Currency: business.Currency.String,
-		Timezone: business.Timezone.String,
-		Language: business.Language.String,
-		CreateAt: business.CreatedAt.Time,
-		UpdateAt: business.UpdatedAt.Time,
+		ID:               business.ID,
+		Name:             business.Name,
+		Motto:            business.Motto.String,
+		Email:            business.Email.String,
+		Website:          business.Website.String,
+		TaxID:            business.TaxID.String,
+		TaxRate:          business.TaxRate.String,
+		LogoUrl:          business.LogoUrl.String,
+		LogoThumbnailUrl: business.LogoThumbnailUrl.String,
+		Rounding:         business.Rounding.String,
+		Currency:         business.Currency.String,
+		Timezone:         business.Timezone.String,
+		Language:         business.Language.String,
+		CreateAt:         business.CreatedAt.Time,
+		UpdateAt:         business.UpdatedAt.Time,
+		Version:          business.Version,
+	})
+}
+
+type ConvertResponse struct {
+	Amount    float64 `json:"amount" example:"15000"`
+	Currency  string  `json:"currency" example:"NGN"`
+	Converted float64 `json:"converted" example:"10"`
+	To        string  `json:"to" example:"USD"`
+	Rate      float64 `json:"rate" example:"0.000667"`
+}
+
+// ConvertAmount godoc
+// @Summary Convert an amount
+// @Description Convert an amount in the currency of a business to another currency
+// @Tags business
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "Business ID"
+// @Param amount query number true "Amount in the currency of the business"
+// @Param to query string true "Currency code to convert to"
+// @Success 200 {object} ConvertResponse
+// @Failure 400
+// @Failure 401
+// @Failure 403
+// @Failure 404
+// @Failure 500
+// @Router /business/{id}/convert [get]
+func (h *Handler) convertAmount(c *gin.Context) {
+	claims, ok := jwt.GetUserFromContext(c)
+	if !ok {
+		h.logger.Errorf("could not get user from context")
+		utils.ErrorResponse(c, 500, utils.SERVERERROR)
+		return
+	}
+	bid, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		utils.ErrorResponse(c, 400, utils.INVALID_REQUEST_DATA)
+		return
+	}
+	amount, err := strconv.ParseFloat(c.Query("amount"), 64)
+	if err != nil || math.IsNaN(amount) || math.IsInf(amount, 0) {
+		utils.ErrorResponse(c, 400, "amount must be a number")
+		return
+	}
+	to := strings.ToUpper(strings.TrimSpace(c.Query("to")))
+	if to == "" {
+		utils.ErrorResponse(c, 400, "to is required")
+		return
+	}
+
+	business, err := h.service.GetBusiness(c, db.GetBusinessParams{
+		ID:      int32(bid),
+		OwnerID: int32(claims.UserID),
+	})
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			utils.ErrorResponse(c, 404, "business not found")
+			return
+		}
+		h.logger.Errorf("error getting business with id %d: %v", bid, err)
+		utils.ErrorResponse(c, 500, utils.SERVERERROR)
+		return
+	}
+	if business.Currency.String == "" {
+		utils.ErrorResponse(c, 400, "business has no currency")
+		return
+	}
+
+	converted, rate, err := h.rates.Convert(c, amount, business.Currency.String, to)
+	if err != nil {
+		if errors.Is(err, currency.ErrUnknownCurrency) {
+			utils.ErrorResponse(c, 400, err.Error())
+			return
+		}
+		h.logger.Errorf("error converting %s to %s: %v", business.Currency.String, to, err)
+		utils.ErrorResponse(c, 500, utils.SERVERERROR)
+		return
+	}
+
+	utils.SuccessResponse(c, 200, "", ConvertResponse{
+		Amount:    amount,
+		Currency:  strings.ToUpper(business.Currency.String),
+		Converted: converted,
+		To:        to,
+		Rate:      rate,
 	})
 }
 
@@ -458,6 +603,11 @@ type UpdateBusinessRequest struct {
 	Font         *string `json:"font"`
 	PrimaryColor *string `json:"primary_color"`
 	Country      *string `json:"country"`
+	// order batches are sold in, "fefo" (soonest expiry first) or "fifo"
+	DepletionStrategy *string `json:"depletion_strategy" binding:"omitempty,oneof=fefo fifo"`
+	// version of the business the update was made from, the update is
+	// rejected when the business has changed since
+	Version *int32 `json:"version" binding:"omitempty,min=1" example:"3"`
 }
 
 type UpdateBusinessResponse struct {
@@ -469,6 +619,7 @@ type UpdateBusinessResponse struct {
 	TaxID             string `json:"tax_id"`
 	TaxRate           string `json:"tax_rate"`
 	LogoUrl           string `json:"logo_url"`
+	LogoThumbnailUrl  string `json:"logo_thumbnail_url"`
 	Rounding          string `json:"rounding"`
 	Currency          string `json:"currency"`
 	Timezone          string `json:"timezone"`
@@ -476,14 +627,16 @@ type UpdateBusinessResponse struct {
 	LowStockThreshold int32  `json:"low_stock_threshold"`
 	AllowOverselling  bool   `json:"allow_overselling"`
 	// PaymentType       []PaymentType  `json:"payment_type"`
-	Font         string `json:"font"`
-	PrimaryColor string `json:"primary_color"`
-	Country      string `json:"country"`
+	Font              string `json:"font"`
+	PrimaryColor      string `json:"primary_color"`
+	Country           string `json:"country"`
+	DepletionStrategy string `json:"depletion_strategy"`
+	Version           int32  `json:"version"`
 }
 
 // UpdateBusiness godoc
 // @Summary Update a business
-// @Description Update a business
+// @Description Update a business. When version is given the update is only made if the business is still at that version, otherwise it fails with 409 and the business has to be fetched again.
 // @Tags business
 // @Accept json
 // @Produce json
@@ -493,6 +646,7 @@ type UpdateBusinessResponse struct {
 // @Failure 400
 // @Failure 403
 // @Failure 404
+// @Failure 409
 // @Failure 500
 // @Router /business/{id} [patch]
 func (h *Handler) updateBusiness(c *gin.Context) {
@@ -516,7 +670,11 @@ func (h *Handler) updateBusiness(c *gin.Context) {
 	var req UpdateBusinessRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
 		h.logger.Errorf("error binding business update: %v", err)
-		utils.ErrorResponse(c, 400, err.Error())
+		utils.BindingErrorResponse(c, err)
+		return
+	}
+	if fields := checkLocale(req.Currency, req.Timezone, req.Country); fields != nil {
+		utils.ErrorResponseWithData(c, 400, utils.VALIDATION_FAILED, fields)
 		return
 	}
 
@@ -553,9 +711,16 @@ func (h *Handler) updateBusiness(c *gin.Context) {
 	utils.PatchNullString(&updateParams.PrimaryColor, req.PrimaryColor)
 	utils.PatchNullInt32(&updateParams.LowStockThreshold, req.LowStockThreshold)
 	utils.PatchNullBool(&updateParams.AllowOverselling, req.AllowOverselling)
+	utils.PatchNullString(&updateParams.DepletionStrategy, req.DepletionStrategy)
+	utils.PatchNullString(&updateParams.Country, req.Country)
+	utils.PatchNullInt32(&updateParams.ExpectedVersion, req.Version)
 	// Update the business
-	updatedBusiness, err := h.service.UpdateBusiness(c, updateParams)
+	updatedBusiness, err := h.service.UpdateBusiness(c, updateParams, int32(claims.UserID))
 	if err != nil {
+		if errors.Is(err, ErrRecordModified) {
+			utils.ErrorResponse(c, 409, err.Error())
+			return
+		}
 		h.logger.Errorf("could not update business: %v", err)
 		utils.ErrorResponse(c, 500, err.Error())
 		return
@@ -586,24 +751,120 @@ func (h *Handler) updateBusiness(c *gin.Context) {
 		TaxID:             updatedBusiness.TaxID.String,
 		TaxRate:           updatedBusiness.TaxRate.String,
 		LogoUrl:           updatedBusiness.LogoUrl.String,
+		LogoThumbnailUrl:  updatedBusiness.LogoThumbnailUrl.String,
 		Rounding:          updatedBusiness.Rounding.String,
 		Currency:          updatedBusiness.Currency.String,
+		DepletionStrategy: updatedBusiness.DepletionStrategy,
+		Version:           updatedBusiness.Version,
 	})
 }
 
+// SettingsHistoryResponse is what the settings of a business were before a
+// change.
+type SettingsHistoryResponse struct {
+	ID               int32     `json:"id"`
+	TaxRate          string    `json:"tax_rate"`
+	Rounding         string    `json:"rounding"`
+	Currency         string    `json:"currency"`
+	PaymentType      []string  `json:"payment_type"`
+	Motto            string    `json:"motto"`
+	LogoUrl          string    `json:"logo_url"`
+	LogoThumbnailUrl string    `json:"logo_thumbnail_url"`
+	Font             string    `json:"font"`
+	PrimaryColor     string    `json:"primary_color"`
+	ChangedBy        int32     `json:"changed_by"`
+	ChangedAt        time.Time `json:"changed_at"`
+}
+
+// ListSettingsHistory godoc
+// @Summary List business settings history
+// @Description List the tax rate, rounding, currency, payment types and branding a business had before each change of them, oldest first.
+// @Tags business
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "Business ID"
+// @Success 200 {object} []SettingsHistoryResponse
+// @Failure 400
+// @Failure 401
+// @Failure 404
+// @Failure 500
+// @Router /business/{id}/settings/history [get]
+func (h *Handler) listSettingsHistory(c *gin.Context) {
+	claims, ok := jwt.GetUserFromContext(c)
+	if !ok {
+		h.logger.Errorf("could not get user from context")
+		utils.ErrorResponse(c, 500, utils.SERVERERROR)
+		return
+	}
+
+	bid, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		utils.ErrorResponse(c, 400, utils.INVALID_REQUEST_DATA)
+		return
+	}
+
+	if _, err := h.service.GetBusiness(c, db.GetBusinessParams{
+		ID:      int32(bid),
+		OwnerID: int32(claims.UserID),
+	}); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			utils.ErrorResponse(c, 404, "business not found")
+			return
+		}
+		h.logger.Errorf("error getting business with id %d: %v", bid, err)
+		utils.ErrorResponse(c, 500, utils.SERVERERROR)
+		return
+	}
+
+	history, err := h.service.ListSettingsHistory(c, int32(bid))
+	if err != nil {
+		h.logger.Errorf("error listing settings history of business %d: %v", bid, err)
+		utils.ErrorResponse(c, 500, utils.SERVERERROR)
+		return
+	}
+
+	response := make([]SettingsHistoryResponse, 0, len(history))
+	for _, entry := range history {
+		paymentTypes := make([]string, 0, len(entry.PaymentType))
+		for _, paymentType := range entry.PaymentType {
+			paymentTypes = append(paymentTypes, string(paymentType))
+		}
+		response = append(response, SettingsHistoryResponse{
+			ID:               entry.ID,
+			TaxRate:          entry.TaxRate.String,
+			Rounding:         entry.Rounding.String,
+			Currency:         entry.Currency.String,
+			PaymentType:      paymentTypes,
+			Motto:            entry.Motto.String,
+			LogoUrl:          entry.LogoUrl.String,
+			LogoThumbnailUrl: entry.LogoThumbnailUrl.String,
+			Font:             entry.Font.String,
+			PrimaryColor:     entry.PrimaryColor.String,
+			ChangedBy:        entry.ChangedBy,
+			ChangedAt:        entry.ChangedAt,
+		})
+	}
+
+	utils.SuccessResponse(c, 200, "settings history", response)
+}
+
 // DeleteBusiness godoc
 // @Summary Delete business
-// @Description Delete a new business
+// @Description Delete a business. It is hidden from every read and can be restored for BUSINESS_RESTORE_DAYS days. With hard=true it is removed for good, which fails while sales or other records still reference it.
 // @Tags business
 // @Accept json
 // @Produce json
 // @Security BearerAuth
+// @Param id path int true "Business ID"
+// @Param hard query bool false "Permanently remove the business"
 // @Success 200 {string} string "business deleted"
 // @Failure 400
 // @Failure 401
 // @Failure 403
+// @Failure 404
+// @Failure 409
 // @Failure 500
-// @Router /api/v1/business/:id [delete]
+// @Router /api/v1/business/{id} [delete]
 func (h *Handler) deleteBusiness(c *gin.Context) {
 	claims, ok := jwt.GetUserFromContext(c)
 	if !ok {
@@ -620,13 +881,38 @@ func (h *Handler) deleteBusiness(c *gin.Context) {
 		return
 	}
 
-	params := db.DeleteBusinessParams{
-		ID:      int32(bid),
-		OwnerID: int32(claims.UserID),
+	hard := false
+	if v := c.Query("hard"); v != "" {
+		hard, err = strconv.ParseBool(v)
+		if err != nil {
+			utils.ErrorResponse(c, 400, "invalid hard flag")
+			return
+		}
 	}
 
-	business, err := h.service.DeleteBusiness(c, params)
+	var business db.Business
+	action := "Deleted business"
+	if hard {
+		action = "Permanently deleted business"
+		business, err = h.service.HardDeleteBusiness(c, db.HardDeleteBusinessParams{
+			ID:      int32(bid),
+			OwnerID: int32(claims.UserID),
+		})
+	} else {
+		business, err = h.service.DeleteBusiness(c, db.DeleteBusinessParams{
+			ID:      int32(bid),
+			OwnerID: int32(claims.UserID),
+		})
+	}
 	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			utils.ErrorResponse(c, 404, "business not found")
+			return
+		}
+		if pgErr, ok := err.(*pq.Error); ok && pgErr.Code == "23503" { // foreign_key_violation
+			utils.ErrorResponse(c, 409, "business is still referenced by other records")
+			return
+		}
 		h.logger.Errorf("error deleteing business with is %d: %v", bid, err)
 		utils.ErrorResponse(c, 500, utils.SERVERERROR)
 		return
@@ -635,10 +921,10 @@ func (h *Handler) deleteBusiness(c *gin.Context) {
 	// Log activity
 	_, err = h.service.LogActivity(c, db.LogActivityParams{
 		UserID:     int32(claims.UserID),
-		Action:     "Deleted business",
+		Action:     action,
 		EntityType: "Business",
 		EntityID:   business.ID,
-		Details:    utils.WriteActivityDetails(claims.Username, claims.Email, fmt.Sprintf("Deleted business %s", business.Name), business.CreatedAt.Time),
+		Details:    utils.WriteActivityDetails(claims.Username, claims.Email, fmt.Sprintf("%s %s", action, business.Name), time.Now()),
 		IpAddress:  sql.NullString{Valid: true, String: utils.GetClientIP(c)},
 		UserAgent:  sql.NullString{Valid: true, String: c.Request.UserAgent()},
 	})
@@ -651,6 +937,84 @@ func (h *Handler) deleteBusiness(c *gin.Context) {
 	utils.SuccessResponse(c, 200, "business deleted", nil)
 }
 
+// RestoreBusiness godoc
+// @Summary Restore business
+// @Description Restore a business deleted less than BUSINESS_RESTORE_DAYS days ago.
+// @Tags business
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "Business ID"
+// @Success 200 {object} BusinessResponse
+// @Failure 400
+// @Failure 401
+// @Failure 403
+// @Failure 404
+// @Failure 500
+// @Router /api/v1/business/{id}/restore [post]
+func (h *Handler) restoreBusiness(c *gin.Context) {
+	claims, ok := jwt.GetUserFromContext(c)
+	if !ok {
+		h.logger.Errorf("could not get user from context")
+		utils.ErrorResponse(c, 500, utils.SERVERERROR)
+		return
+	}
+
+	bid, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		utils.ErrorResponse(c, 400, utils.INVALID_REQUEST_DATA)
+		return
+	}
+
+	business, err := h.service.RestoreBusiness(c, db.RestoreBusinessParams{
+		ID:           int32(bid),
+		OwnerID:      int32(claims.UserID),
+		DeletedAfter: sql.NullTime{Time: time.Now().AddDate(0, 0, -h.config.BusinessRestoreDays), Valid: true},
+	})
+	if err != nil {
+		// businesses that aren't deleted or were deleted too long ago
+		// can't be restored either
+		if errors.Is(err, sql.ErrNoRows) {
+			utils.ErrorResponse(c, 404, "no restorable business found")
+			return
+		}
+		h.logger.Errorf("error restoring business with id %d: %v", bid, err)
+		utils.ErrorResponse(c, 500, utils.SERVERERROR)
+		return
+	}
+
+	_, err = h.service.LogActivity(c, db.LogActivityParams{
+		UserID:     int32(claims.UserID),
+		Action:     "Restored business",
+		EntityType: "Business",
+		EntityID:   business.ID,
+		Details:    utils.WriteActivityDetails(claims.Username, claims.Email, fmt.Sprintf("Restored business %s", business.Name), business.UpdatedAt.Time),
+		IpAddress:  sql.NullString{Valid: true, String: utils.GetClientIP(c)},
+		UserAgent:  sql.NullString{Valid: true, String: c.Request.UserAgent()},
+	})
+	if err != nil {
+		h.logger.Warnf("error logging activity: %v", err)
+	}
+
+	utils.SuccessResponse(c, 200, "business restored", BusinessResponse{
+		ID:               business.ID,
+		Name:             business.Name,
+		Motto:            business.Motto.String,
+		Email:            business.Email.String,
+		Website:          business.Website.String,
+		TaxID:            business.TaxID.String,
+		TaxRate:          business.TaxRate.String,
+		LogoUrl:          business.LogoUrl.String,
+		LogoThumbnailUrl: business.LogoThumbnailUrl.String,
+		Rounding:         business.Rounding.String,
+		Currency:         business.Currency.String,
+		Timezone:         business.Timezone.String,
+		Language:         business.Language.String,
+		CreateAt:         business.CreatedAt.Time,
+		UpdateAt:         business.UpdatedAt.Time,
+		Version:          business.Version,
+	})
+}
+
 type ListBusinessResponse struct {
 	ID                int32  `json:"id"`
 	Name              string `json:"name"`
@@ -661,6 +1025,7 @@ type ListBusinessResponse struct {
 	TaxRate           string `json:"tax_rate"`
 	Country           string `json:"country"`
 	LogoUrl           string `json:"logo_url"`
+	LogoThumbnailUrl  string `json:"logo_thumbnail_url"`
 	Rounding          string `json:"rounding"`
 	Currency          string `json:"currency"`
 	Timezone          string `json:"timezone"`
@@ -672,16 +1037,19 @@ type ListBusinessResponse struct {
 	PrimaryColor string    `json:"primary_color"`
 	CreatedAt    time.Time `json:"created_at"`
 	UpdatedAt    time.Time `json:"updated_at"`
+	Version      int32     `json:"version"`
 }
 
 // ListBusinesses godoc
 // @Summary Get a list of businesses
-// @Description Get a list of businesses
+// @Description Get a list of businesses you own, ordered by id.
 // @Tags business
 // @Accept json
 // @Produce json
 // @Security BearerAuth
-// @Success 200 {object} []BusinessResponse
+// @Param page query int false "Page number" default(1)
+// @Param limit query int false "Number of businesses per page, at most 100" default(20)
+// @Success 200 {object} utils.PaginatedResponse{items=[]ListBusinessResponse}
 // @Failure 400
 // @Failure 401
 // @Failure 403
@@ -694,15 +1062,34 @@ func (h *Handler) listBusinesses(c *gin.Context) {
 		utils.ErrorResponse(c, 500, utils.SERVERERROR)
 		return
 	}
-	businesses, err := h.service.ListBusinesses(c, int32(claims.UserID))
+
+	pagination, err := utils.ParsePagination(c)
+	if err != nil {
+		utils.ErrorResponse(c, 400, err.Error())
+		return
+	}
+
+	businesses, err := h.service.ListBusinesses(c, db.ListBusinessesParams{
+		OwnerID:    int32(claims.UserID),
+		PageLimit:  int32(pagination.Limit),
+		PageOffset: int32(pagination.Offset),
+	})
 	if err != nil {
 		h.logger.Errorf("error listing businesses: %v", err)
 		utils.ErrorResponse(c, 500, utils.SERVERERROR)
 		return
 	}
 
+	total, err := h.service.CountBusinesses(c, int32(claims.UserID))
+	if err != nil {
+		h.logger.Errorf("error counting businesses: %v", err)
+		utils.ErrorResponse(c, 500, utils.SERVERERROR)
+		return
+	}
+
+	response := make([]ListBusinessResponse, 0, len(businesses))
 	for _, business := range businesses {
-		utils.SuccessResponse(c, 200, "A list of your businesses", ListBusinessResponse{
+		response = append(response, ListBusinessResponse{
 			ID:                business.ID,
 			Name:              business.Name,
 			Email:             business.Email.String,
@@ -711,6 +1098,7 @@ func (h *Handler) listBusinesses(c *gin.Context) {
 			TaxID:             business.TaxID.String,
 			TaxRate:           business.TaxRate.String,
 			LogoUrl:           business.LogoUrl.String,
+			LogoThumbnailUrl:  business.LogoThumbnailUrl.String,
 			Font:              business.Font.String,
 			Language:          business.Language.String,
 			Currency:          business.Currency.String,
@@ -722,9 +1110,11 @@ func (h *Handler) listBusinesses(c *gin.Context) {
 			Country:           business.Country,
 			CreatedAt:         business.CreatedAt.Time,
 			UpdatedAt:         business.UpdatedAt.Time,
+			Version:           business.Version,
 		})
 	}
 
+	utils.SuccessResponse(c, 200, "A list of your businesses", pagination.Response(response, total))
 }
 
 type CreateBranchRequest struct {
@@ -732,7 +1122,7 @@ type CreateBranchRequest struct {
 	Name       string `json:"name" example:"Main branch" binding:"required"`
 	AddressOne string `json:"address_one" binding:"required" example:"..."`
 	AddresTwo  string `json:"addres_two" binding:"omitempty" example:"1 Plamwine express"`
-	Country    string `json:"country" binding:"required" example:"Nigeria"`
+	Country    string `json:"country" binding:"required" example:"NG"`
 	Phone      string `json:"phone" binding:"omitempty" example:"+2349028378964"`
 	Email      string `json:"email" binding:"omitempty" example:"admin.mainbranch@gmail.com"`
 	Website    string `json:"website" binding:"omitempty" example:"https://"`
@@ -747,7 +1137,7 @@ type CreateBranchResponse struct {
 	Name       string `json:"name" example:"Main branch" binding:"required"`
 	AddressOne string `json:"address_one" binding:"required" example:"..."`
 	AddresTwo  string `json:"addres_two" binding:"omitempty" example:"1 Plamwine express"`
-	Country    string `json:"country" binding:"required" example:"Nigeria"`
+	Country    string `json:"country" binding:"required" example:"NG"`
 	Phone      string `json:"phone" binding:"omitempty" example:"+2349028378964"`
 	Email      string `json:"email" binding:"omitempty" example:""`
 	Website    string `json:"website" binding:"omitempty" example:"https://"`
@@ -781,7 +1171,7 @@ func (h *Handler) createBranch(c *gin.Context) {
 	var req CreateBranchRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
 		h.logger.Errorf("create branch request binding error: %v", err)
-		utils.ErrorResponse(c, 400, utils.INVALID_REQUEST_DATA)
+		utils.BindingErrorResponse(c, err)
 		return
 	}
 
@@ -884,7 +1274,7 @@ type UpdateBranchRequest struct {
 	Name       string `json:"name" example:"Main branch" binding:"required"`
 	AddressOne string `json:"address_one" binding:"required" example:"..."`
 	AddresTwo  string `json:"addres_two" binding:"omitempty" example:"1 Plamwine express"`
-	Country    string `json:"country" binding:"required" example:"Nigeria"`
+	Country    string `json:"country" binding:"required" example:"NG"`
 	Phone      string `json:"phone" binding:"omitempty" example:"+2349028378964"`
 	Email      string `json:"email" binding:"omitempty" example:""`
 	Website    string `json:"website" binding:"omitempty" example:"https://"`
@@ -927,7 +1317,7 @@ func (h *Handler) updateBranch(c *gin.Context) {
 	var req UpdateBranchRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
 		h.logger.Errorf("update branch request binding error: %v", err)
-		utils.ErrorResponse(c, 400, utils.INVALID_REQUEST_DATA)
+		utils.BindingErrorResponse(c, err)
 		return
 	}
 
@@ -1030,10 +1420,258 @@ func (h *Handler) deleteBranch(c *gin.Context) {
 
 }
 
+type BranchResponse struct {
+	CreateBranchResponse
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+type ListBranchesResponse struct {
+	Branches []BranchResponse `json:"branches"`
+	Page     int              `json:"page" example:"1"`
+	Limit    int              `json:"limit" example:"20"`
+	Total    int64            `json:"total" example:"1"`
+}
+
+// ListBranches godoc
+// @Summary List branches
+// @Description List branches of the businesses you own.
+// @Tags business
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param business_id query int false "Only list branches of this business"
+// @Param page query int false "Page number" default(1)
+// @Param limit query int false "Number of branches per page" default(20)
+// @Success 200 {object} ListBranchesResponse
+// @Failure 400
+// @Failure 401
+// @Failure 403
+// @Failure 500
+// @Router /api/v1/business/branch [get]
 func (h *Handler) listBranches(c *gin.Context) {
-	// Implementation goes here
+	claims, ok := jwt.GetUserFromContext(c)
+	if !ok {
+		h.logger.Errorf("could not get user from context")
+		utils.ErrorResponse(c, 500, utils.SERVERERROR)
+		return
+	}
+
+	page, err := strconv.Atoi(c.DefaultQuery("page", "1"))
+	if err != nil || page < 1 {
+		utils.ErrorResponse(c, 400, utils.INVALID_REQUEST_DATA)
+		return
+	}
+
+	limit, err := strconv.Atoi(c.DefaultQuery("limit", "20"))
+	if err != nil || limit < 1 {
+		utils.ErrorResponse(c, 400, utils.INVALID_REQUEST_DATA)
+		return
+	}
+	if limit > 100 {
+		limit = 100
+	}
+
+	var businessID sql.NullInt32
+	if bid := c.Query("business_id"); bid != "" {
+		id, err := strconv.Atoi(bid)
+		if err != nil {
+			utils.ErrorResponse(c, 400, utils.INVALID_REQUEST_DATA)
+			return
+		}
+		businessID = sql.NullInt32{Int32: int32(id), Valid: true}
+	}
+
+	// branches are always scoped to the caller's businesses, a business_id
+	// the caller doesn't own simply yields an empty list
+	branches, err := h.service.ListBranches(c, db.ListBranchesParams{
+		OwnerID:    int32(claims.UserID),
+		BusinessID: businessID,
+		PageLimit:  int32(limit),
+		PageOffset: int32((page - 1) * limit),
+	})
+	if err != nil {
+		h.logger.Errorf("error listing branches: %v", err)
+		utils.ErrorResponse(c, 500, utils.SERVERERROR)
+		return
+	}
+
+	total, err := h.service.CountBranches(c, db.CountBranchesParams{
+		OwnerID:    int32(claims.UserID),
+		BusinessID: businessID,
+	})
+	if err != nil {
+		h.logger.Errorf("error counting branches: %v", err)
+		utils.ErrorResponse(c, 500, utils.SERVERERROR)
+		return
+	}
+
+	response := ListBranchesResponse{
+		Branches: make([]BranchResponse, 0, len(branches)),
+		Page:     page,
+		Limit:    limit,
+		Total:    total,
+	}
+	for _, branch := range branches {
+		response.Branches = append(response.Branches, BranchResponse{
+			CreateBranchResponse: CreateBranchResponse{
+				ID:         branch.ID,
+				BusinessID: branch.BusinessID,
+				Name:       branch.Name,
+				AddressOne: branch.AddressOne.String,
+				AddresTwo:  branch.AddresTwo.String,
+				Country:    branch.Country.String,
+				Phone:      branch.Phone.String,
+				Email:      branch.Email.String,
+				Website:    branch.Website.String,
+				City:       branch.City.String,
+				State:      branch.State.String,
+				ZipCode:    branch.ZipCode.String,
+			},
+			CreatedAt: branch.CreatedAt.Time,
+			UpdatedAt: branch.UpdatedAt.Time,
+		})
+	}
+
+	utils.SuccessResponse(c, 200, "A list of your branches", response)
 }
 
-func (h *Handler) GetAcitivityLogs(c *gin.Context) {
-	// Implementation goes here
+type ActivityLogResponse struct {
+	ID         int32     `json:"id"`
+	UserID     int32     `json:"user_id"`
+	Action     string    `json:"action"`
+	Details    string    `json:"details"`
+	EntityID   int32     `json:"entity_id"`
+	EntityType string    `json:"entity_type"`
+	IpAddress  string    `json:"ip_address"`
+	UserAgent  string    `json:"user_agent"`
+	CreatedAt  time.Time `json:"created_at"`
+}
+
+type ListActivityLogsResponse struct {
+	Logs  []ActivityLogResponse `json:"logs"`
+	Page  int                   `json:"page" example:"1"`
+	Limit int                   `json:"limit" example:"20"`
+	Total int64                 `json:"total" example:"1"`
+}
+
+// parseLogTime reads a date or an RFC3339 time. A date given as the end of a
+// range includes the whole day.
+func parseLogTime(value string, end bool) (time.Time, error) {
+	if t, err := time.Parse("2006-01-02", value); err == nil {
+		if end {
+			t = t.AddDate(0, 0, 1)
+		}
+		return t, nil
+	}
+	return time.Parse(time.RFC3339, value)
+}
+
+// GetActivityLogs godoc
+// @Summary List business activity logs
+// @Description List the activity on the businesses you own, their branches, stores and stock transfers, newest first.
+// @Tags business
+// @Produce json
+// @Security BearerAuth
+// @Param entity_type query string false "Only actions on this entity type, e.g. Branch"
+// @Param start query string false "Start date (YYYY-MM-DD or RFC3339)"
+// @Param end query string false "End date, inclusive (YYYY-MM-DD or RFC3339)"
+// @Param page query int false "Page number" default(1)
+// @Param limit query int false "Number of logs per page" default(20)
+// @Success 200 {object} ListActivityLogsResponse
+// @Failure 400
+// @Failure 401
+// @Failure 403
+// @Failure 500
+// @Router /api/v1/business/activity [get]
+func (h *Handler) GetActivityLogs(c *gin.Context) {
+	claims, ok := jwt.GetUserFromContext(c)
+	if !ok {
+		h.logger.Errorf("could not get user from context")
+		utils.ErrorResponse(c, 500, utils.SERVERERROR)
+		return
+	}
+
+	page, err := strconv.Atoi(c.DefaultQuery("page", "1"))
+	if err != nil || page < 1 {
+		utils.ErrorResponse(c, 400, utils.INVALID_REQUEST_DATA)
+		return
+	}
+
+	limit, err := strconv.Atoi(c.DefaultQuery("limit", "20"))
+	if err != nil || limit < 1 {
+		utils.ErrorResponse(c, 400, utils.INVALID_REQUEST_DATA)
+		return
+	}
+	if limit > 100 {
+		limit = 100
+	}
+
+	entityType := sql.NullString{String: c.Query("entity_type"), Valid: c.Query("entity_type") != ""}
+
+	var startTime, endTime sql.NullTime
+	if v := c.Query("start"); v != "" {
+		t, err := parseLogTime(v, false)
+		if err != nil {
+			utils.ErrorResponse(c, 400, "invalid start date")
+			return
+		}
+		startTime = sql.NullTime{Time: t, Valid: true}
+	}
+	if v := c.Query("end"); v != "" {
+		t, err := parseLogTime(v, true)
+		if err != nil {
+			utils.ErrorResponse(c, 400, "invalid end date")
+			return
+		}
+		endTime = sql.NullTime{Time: t, Valid: true}
+	}
+
+	logs, err := h.service.ListActivityLogs(c, db.ListBusinessActivityLogsParams{
+		OwnerID:    int32(claims.UserID),
+		EntityType: entityType,
+		StartTime:  startTime,
+		EndTime:    endTime,
+		PageLimit:  int32(limit),
+		PageOffset: int32((page - 1) * limit),
+	})
+	if err != nil {
+		h.logger.Errorf("error listing business activity logs: %v", err)
+		utils.ErrorResponse(c, 500, utils.SERVERERROR)
+		return
+	}
+
+	total, err := h.service.CountActivityLogs(c, db.CountBusinessActivityLogsParams{
+		OwnerID:    int32(claims.UserID),
+		EntityType: entityType,
+		StartTime:  startTime,
+		EndTime:    endTime,
+	})
+	if err != nil {
+		h.logger.Errorf("error counting business activity logs: %v", err)
+		utils.ErrorResponse(c, 500, utils.SERVERERROR)
+		return
+	}
+
+	response := ListActivityLogsResponse{
+		Logs:  make([]ActivityLogResponse, 0, len(logs)),
+		Page:  page,
+		Limit: limit,
+		Total: total,
+	}
+	for _, log := range logs {
+		response.Logs = append(response.Logs, ActivityLogResponse{
+			ID:         log.ID,
+			UserID:     log.UserID,
+			Action:     log.Action,
+			Details:    log.Details,
+			EntityID:   log.EntityID,
+			EntityType: log.EntityType,
+			IpAddress:  log.IpAddress.String,
+			UserAgent:  log.UserAgent.String,
+			CreatedAt:  log.CreatedAt.Time,
+		})
+	}
+
+	utils.SuccessResponse(c, 200, "Activity on your businesses", response)
 }
@@ -10,28 +10,42 @@ type Querier interface {
 	GetBusiness(ctx context.Context, params db.GetBusinessParams) (db.Business, error)
 	UpdateBusiness(ctx context.Context, params db.UpdateBusinessParams) (db.Business, error)
 	DeleteBusiness(ctx context.Context, params db.DeleteBusinessParams) (db.Business, error)
-	ListBusinesses(ctx context.Context, id int32) ([]db.Business, error)
+	RestoreBusiness(ctx context.Context, params db.RestoreBusinessParams) (db.Business, error)
+	HardDeleteBusiness(ctx context.Context, params db.HardDeleteBusinessParams) (db.Business, error)
+	ListBusinesses(ctx context.Context, params db.ListBusinessesParams) ([]db.Business, error)
+	CountBusinesses(ctx context.Context, ownerID int32) (int64, error)
 	CreateBranch(ctx context.Context, params db.CreateBranchParams) (db.Branch, error)
 	GetBranch(ctx context.Context, id int32) (db.Branch, error)
 	UpdateBranch(ctx context.Context, params db.UpdateBranchParams) (db.Branch, error)
 	DeleteBranch(ctx context.Context, id int32) (db.Branch, error)
-	ListBranches(ctx context.Context) ([]db.Branch, error)
+	ListBranches(ctx context.Context, params db.ListBranchesParams) ([]db.Branch, error)
+	CountBranches(ctx context.Context, params db.CountBranchesParams) (int64, error)
 	CreateStore(ctx context.Context, params db.CreateStoreParams) (db.Store, error)
 	LogActivity(ctx context.Context, params db.LogActivityParams) (db.ActivityLog, error)
 	GetActivityLogs(ctx context.Context, limit int32) ([]db.ActivityLog, error)
+	ListBusinessActivityLogs(ctx context.Context, params db.ListBusinessActivityLogsParams) ([]db.ActivityLog, error)
+	ListBusinessSettingsHistory(ctx context.Context, businessID int32) ([]db.BusinessSettingsHistory, error)
+	CountBusinessActivityLogs(ctx context.Context, params db.CountBusinessActivityLogsParams) (int64, error)
 }
 
 type BusinessInterface interface {
 	CreateBusinessWithBranch(ctx context.Context, params db.CreateBusinessParams) (db.Business, db.Branch, error)
 	CreateBusiness(ctx context.Context, params db.CreateBusinessParams) (db.Business, error)
 	GetBusiness(ctx context.Context, params db.GetBusinessParams) (db.Business, error)
-	UpdateBusiness(ctx context.Context, params db.UpdateBusinessParams) (db.Business, error)
+	UpdateBusiness(ctx context.Context, params db.UpdateBusinessParams, changedBy int32) (db.Business, error)
+	ListSettingsHistory(ctx context.Context, businessID int32) ([]db.BusinessSettingsHistory, error)
 	DeleteBusiness(ctx context.Context, params db.DeleteBusinessParams) (db.Business, error)
-	ListBusinesses(ctx context.Context, ownerID int32) ([]db.Business, error)
+	RestoreBusiness(ctx context.Context, params db.RestoreBusinessParams) (db.Business, error)
+	HardDeleteBusiness(ctx context.Context, params db.HardDeleteBusinessParams) (db.Business, error)
+	ListBusinesses(ctx context.Context, params db.ListBusinessesParams) ([]db.Business, error)
+	CountBusinesses(ctx context.Context, ownerID int32) (int64, error)
 	CreateBranch(ctx context.Context, params db.CreateBranchParams) (db.Branch, error)
 	GetBranch(ctx context.Context, id int32) (db.Branch, error)
 	UpdateBranch(ctx context.Context, params db.UpdateBranchParams) (db.Branch, error)
 	DeleteBranch(ctx context.Context, id int32) (db.Branch, error)
-	ListBranches(ctx context.Context) ([]db.Branch, error)
+	ListBranches(ctx context.Context, params db.ListBranchesParams) ([]db.Branch, error)
+	CountBranches(ctx context.Context, params db.CountBranchesParams) (int64, error)
+	ListActivityLogs(ctx context.Context, params db.ListBusinessActivityLogsParams) ([]db.ActivityLog, error)
+	CountActivityLogs(ctx context.Context, params db.CountBusinessActivityLogsParams) (int64, error)
 	LogActivity(ctx context.Context, params db.LogActivityParams) (db.ActivityLog, error)
 }
@@ -0,0 +1,206 @@
+package business
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	db "herp/db/sqlc"
+	"herp/internal/config"
+	"herp/pkg/jwt"
+	"herp/pkg/monitoring/logging"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// businessService keeps businesses in memory and filters them the way the
+// queries do, by owner and leaving out deleted ones.
+type businessService struct {
+	BusinessInterface
+	businesses []db.Business
+	// err fails the lookups when set
+	err error
+}
+
+func (s *businessService) add(ownerID int32, name string) db.Business {
+	business := db.Business{ID: int32(len(s.businesses) + 1), OwnerID: ownerID, Name: name, Version: 1}
+	s.businesses = append(s.businesses, business)
+	return business
+}
+
+func (s *businessService) owned(ownerID int32) []db.Business {
+	var owned []db.Business
+	for _, business := range s.businesses {
+		if business.OwnerID == ownerID && !business.DeletedAt.Valid {
+			owned = append(owned, business)
+		}
+	}
+	sort.Slice(owned, func(i, j int) bool { return owned[i].ID < owned[j].ID })
+	return owned
+}
+
+func (s *businessService) ListBusinesses(ctx context.Context, params db.ListBusinessesParams) ([]db.Business, error) {
+	owned := s.owned(params.OwnerID)
+	start := min(int(params.PageOffset), len(owned))
+	end := min(start+int(params.PageLimit), len(owned))
+	return owned[start:end], nil
+}
+
+func (s *businessService) GetBusiness(ctx context.Context, params db.GetBusinessParams) (db.Business, error) {
+	if s.err != nil {
+		return db.Business{}, s.err
+	}
+	for _, business := range s.owned(params.OwnerID) {
+		if business.ID == params.ID {
+			return business, nil
+		}
+	}
+	return db.Business{}, sql.ErrNoRows
+}
+
+func (s *businessService) CountBusinesses(ctx context.Context, ownerID int32) (int64, error) {
+	return int64(len(s.owned(ownerID))), nil
+}
+
+func newBusinessRouter(service BusinessInterface, userID int) (*Handler, *gin.Engine) {
+	gin.SetMode(gin.TestMode)
+	cfg := &config.Config{}
+	h := NewBusinessHandler(service, cfg, logging.NewLogger(cfg), nil, nil)
+
+	r := gin.New()
+	r.Use(func(c *gin.Context) {
+		c.Set("claims", &jwt.Claims{UserID: userID, Username: "owner"})
+	})
+	return h, r
+}
+
+type listBusinessesBody struct {
+	Data struct {
+		Items []ListBusinessResponse `json:"items"`
+		Page  int                    `json:"page"`
+		Total int64                  `json:"total"`
+		Pages int                    `json:"pages"`
+	} `json:"data"`
+}
+
+func TestListBusinesses(t *testing.T) {
+	service := &businessService{}
+	service.add(1, "Palmwine Express")
+	service.add(2, "Someone else's")
+	service.add(1, "Palmwine Express Annex")
+	service.add(1, "Palmwine Express Lekki")
+	h, r := newBusinessRouter(service, 1)
+	r.GET("/business/all", h.listBusinesses)
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/business/all", nil))
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+
+	// a single JSON document, not one per business
+	var body listBusinessesBody
+	decoder := json.NewDecoder(w.Body)
+	require.NoError(t, decoder.Decode(&body))
+	assert.False(t, decoder.More(), "more than one response written")
+
+	var names []string
+	for _, business := range body.Data.Items {
+		names = append(names, business.Name)
+	}
+	assert.Equal(t, []string{"Palmwine Express", "Palmwine Express Annex", "Palmwine Express Lekki"}, names)
+	assert.Equal(t, int64(3), body.Data.Total)
+	assert.Equal(t, 1, body.Data.Pages)
+}
+
+func TestListBusinessesPages(t *testing.T) {
+	service := &businessService{}
+	for range 5 {
+		service.add(1, "Palmwine Express")
+	}
+	h, r := newBusinessRouter(service, 1)
+	r.GET("/business/all", h.listBusinesses)
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/business/all?page=2&limit=2", nil))
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+
+	var body listBusinessesBody
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &body))
+	require.Len(t, body.Data.Items, 2)
+	assert.Equal(t, int32(3), body.Data.Items[0].ID)
+	assert.Equal(t, int32(4), body.Data.Items[1].ID)
+	assert.Equal(t, 2, body.Data.Page)
+	assert.Equal(t, int64(5), body.Data.Total)
+	assert.Equal(t, 3, body.Data.Pages)
+}
+
+func TestListBusinessesEmpty(t *testing.T) {
+	h, r := newBusinessRouter(&businessService{}, 1)
+	r.GET("/business/all", h.listBusinesses)
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/business/all", nil))
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), `"items":[]`)
+}
+
+func TestGetBusiness(t *testing.T) {
+	service := &businessService{}
+	ours := service.add(1, "Palmwine Express")
+	theirs := service.add(2, "Someone else's")
+	deleted := service.add(1, "Closed down")
+	service.businesses[deleted.ID-1].DeletedAt = sql.NullTime{Valid: true}
+
+	tests := []struct {
+		name string
+		path string
+		want int
+	}{
+		{"owned", fmt.Sprintf("/business/%d", ours.ID), http.StatusOK},
+		{"not found", "/business/99", http.StatusNotFound},
+		{"not owned", fmt.Sprintf("/business/%d", theirs.ID), http.StatusNotFound},
+		{"deleted", fmt.Sprintf("/business/%d", deleted.ID), http.StatusNotFound},
+		{"invalid id", "/business/abc", http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h, r := newBusinessRouter(service, 1)
+			r.GET("/business/:id", h.getBusiness)
+
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, tt.path, nil))
+			require.Equal(t, tt.want, w.Code, w.Body.String())
+			if tt.want == http.StatusNotFound {
+				// the same answer whether or not the business exists
+				assert.Contains(t, w.Body.String(), "business not found")
+			}
+		})
+	}
+
+	h, r := newBusinessRouter(service, 1)
+	r.GET("/business/:id", h.getBusiness)
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, fmt.Sprintf("/business/%d", ours.ID), nil))
+	var body struct {
+		Data BusinessResponse `json:"data"`
+	}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &body))
+	assert.Equal(t, "Palmwine Express", body.Data.Name)
+}
+
+func TestGetBusinessServerError(t *testing.T) {
+	service := &businessService{err: errors.New("connection refused")}
+	h, r := newBusinessRouter(service, 1)
+	r.GET("/business/:id", h.getBusiness)
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/business/1", nil))
+	assert.Equal(t, http.StatusInternalServerError, w.Code)
+	assert.NotContains(t, w.Body.String(), "connection refused")
+}
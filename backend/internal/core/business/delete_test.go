@@ -0,0 +1,127 @@
+package business
+
+import (
+	"database/sql"
+	"fmt"
+	"herp/db/dbtest"
+	db "herp/db/sqlc"
+	"herp/internal/config"
+	"herp/pkg/jwt"
+	"herp/pkg/monitoring/logging"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newDeleteRouter serves the business reads, delete and restore for
+// ownerID against conn.
+func newDeleteRouter(conn *sql.DB, ownerID int32) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	cfg := &config.Config{BusinessRestoreDays: 30}
+	h := NewBusinessHandler(NewBusiness(db.New(conn), conn), cfg, logging.NewLogger(cfg), nil, nil)
+
+	r := gin.New()
+	r.Use(func(c *gin.Context) {
+		c.Set("claims", &jwt.Claims{UserID: int(ownerID), Username: "owner"})
+	})
+	r.GET("/business/all", h.listBusinesses)
+	r.GET("/business/:id", h.getBusiness)
+	r.DELETE("/business/:id", h.deleteBusiness)
+	r.POST("/business/:id/restore", h.restoreBusiness)
+	return r
+}
+
+func call(r *gin.Engine, method, path string) *httptest.ResponseRecorder {
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(method, path, nil))
+	return w
+}
+
+func TestDeleteBusinessHides(t *testing.T) {
+	conn := dbtest.Open(t)
+	owner := dbtest.Admin(t, conn, "owner")
+	businessID, branchID := dbtest.Business(t, conn, owner, "Palmwine Express")
+	r := newDeleteRouter(conn, owner)
+	path := fmt.Sprintf("/business/%d", businessID)
+
+	require.Equal(t, http.StatusOK, call(r, http.MethodDelete, path).Code)
+
+	assert.Equal(t, http.StatusNotFound, call(r, http.MethodGet, path).Code)
+	w := call(r, http.MethodGet, "/business/all")
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), `"items":[]`)
+	// deleting it again finds nothing
+	assert.Equal(t, http.StatusNotFound, call(r, http.MethodDelete, path).Code)
+
+	// the row and what hangs off it are kept
+	var deleted bool
+	require.NoError(t, conn.QueryRow(`SELECT deleted_at IS NOT NULL FROM business WHERE id = $1`, businessID).Scan(&deleted))
+	assert.True(t, deleted)
+	var branches int
+	require.NoError(t, conn.QueryRow(`SELECT count(*) FROM branch WHERE id = $1`, branchID).Scan(&branches))
+	assert.Equal(t, 1, branches)
+}
+
+func TestRestoreBusinessReveals(t *testing.T) {
+	conn := dbtest.Open(t)
+	owner := dbtest.Admin(t, conn, "owner")
+	businessID, _ := dbtest.Business(t, conn, owner, "Palmwine Express")
+	r := newDeleteRouter(conn, owner)
+	path := fmt.Sprintf("/business/%d", businessID)
+
+	// only deleted businesses are restored
+	assert.Equal(t, http.StatusNotFound, call(r, http.MethodPost, path+"/restore").Code)
+
+	require.Equal(t, http.StatusOK, call(r, http.MethodDelete, path).Code)
+	w := call(r, http.MethodPost, path+"/restore")
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	assert.Contains(t, w.Body.String(), `"name":"Palmwine Express"`)
+
+	assert.Equal(t, http.StatusOK, call(r, http.MethodGet, path).Code)
+	w = call(r, http.MethodGet, "/business/all")
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), "Palmwine Express")
+
+	// someone else can't restore it
+	other := dbtest.Admin(t, conn, "other")
+	require.Equal(t, http.StatusOK, call(r, http.MethodDelete, path).Code)
+	assert.Equal(t, http.StatusNotFound, call(newDeleteRouter(conn, other), http.MethodPost, path+"/restore").Code)
+}
+
+func TestRestoreBusinessAfterRetention(t *testing.T) {
+	conn := dbtest.Open(t)
+	owner := dbtest.Admin(t, conn, "owner")
+	businessID, _ := dbtest.Business(t, conn, owner, "Palmwine Express")
+	dbtest.Exec(t, conn, `UPDATE business SET deleted_at = CURRENT_TIMESTAMP - INTERVAL '31 days' WHERE id = $1`, businessID)
+	r := newDeleteRouter(conn, owner)
+
+	assert.Equal(t, http.StatusNotFound, call(r, http.MethodPost, fmt.Sprintf("/business/%d/restore", businessID)).Code)
+}
+
+func TestHardDeleteBusinessRemoves(t *testing.T) {
+	conn := dbtest.Open(t)
+	owner := dbtest.Admin(t, conn, "owner")
+	businessID, _ := dbtest.Business(t, conn, owner, "Palmwine Express")
+	kept, _ := dbtest.Business(t, conn, owner, "Palmwine Express Annex")
+	r := newDeleteRouter(conn, owner)
+	path := fmt.Sprintf("/business/%d", businessID)
+
+	assert.Equal(t, http.StatusBadRequest, call(r, http.MethodDelete, path+"?hard=maybe").Code)
+
+	// soft deleted businesses can still be removed for good
+	require.Equal(t, http.StatusOK, call(r, http.MethodDelete, path).Code)
+	require.Equal(t, http.StatusOK, call(r, http.MethodDelete, path+"?hard=true").Code)
+
+	var rows int
+	require.NoError(t, conn.QueryRow(`SELECT count(*) FROM business WHERE id = $1`, businessID).Scan(&rows))
+	assert.Zero(t, rows)
+	require.NoError(t, conn.QueryRow(`SELECT count(*) FROM branch WHERE business_id = $1`, businessID).Scan(&rows))
+	assert.Zero(t, rows)
+	assert.Equal(t, http.StatusNotFound, call(r, http.MethodPost, path+"/restore").Code)
+
+	assert.Equal(t, http.StatusOK, call(r, http.MethodGet, fmt.Sprintf("/business/%d", kept)).Code)
+}
@@ -0,0 +1,104 @@
+package business
+
+import (
+	"fmt"
+	"herp/pkg/currency"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-playground/validator/v10"
+)
+
+// localeValidator checks currency and country codes against the ISO lists
+// the validator ships with.
+var localeValidator = validator.New()
+
+var utcOffsetPattern = regexp.MustCompile(`^(?:UTC|GMT)\s*([+-])\s*(\d{1,2})(?::?(\d{2}))?$`)
+
+// offsetZones names the zone of the places keeping an offset with minutes as
+// their standard time. Etc/GMT zones only come in whole hours.
+var offsetZones = map[string]string{
+	"+03:30": "Asia/Tehran",
+	"+04:30": "Asia/Kabul",
+	"+05:30": "Asia/Kolkata",
+	"+05:45": "Asia/Kathmandu",
+	"+06:30": "Asia/Yangon",
+	"+08:45": "Australia/Eucla",
+	"+09:30": "Australia/Darwin",
+	"+10:30": "Australia/Lord_Howe",
+	"+12:45": "Pacific/Chatham",
+	"-03:30": "America/St_Johns",
+	"-09:30": "Pacific/Marquesas",
+}
+
+// normalizeTimezone returns the IANA name of a timezone. Whole hour offsets
+// like "UTC +1", which businesses used to be created with, are turned into
+// the matching Etc/GMT zone, whose sign is inverted in the tz database.
+// Offsets with minutes like "UTC +5:30" are turned into the zone in
+// offsetZones, other offsets are invalid.
+func normalizeTimezone(timezone string) (string, bool) {
+	timezone = strings.TrimSpace(timezone)
+	if m := utcOffsetPattern.FindStringSubmatch(strings.ToUpper(timezone)); m != nil {
+		hours, _ := strconv.Atoi(m[2])
+		minutes, _ := strconv.Atoi(m[3])
+		if minutes != 0 {
+			name, ok := offsetZones[fmt.Sprintf("%s%02d:%02d", m[1], hours, minutes)]
+			return name, ok
+		}
+		if hours == 0 {
+			return "UTC", true
+		}
+		if (m[1] == "+" && hours > 14) || (m[1] == "-" && hours > 12) {
+			return "", false
+		}
+		sign := "-"
+		if m[1] == "-" {
+			sign = "+"
+		}
+		return fmt.Sprintf("Etc/GMT%s%d", sign, hours), true
+	}
+	// LoadLocation takes "" as UTC and "Local" as the server's zone
+	if _, err := time.LoadLocation(timezone); err != nil || timezone == "" || timezone == "Local" {
+		return "", false
+	}
+	return timezone, true
+}
+
+// checkLocale upper cases the currency and country of a business request and
+// returns why each of currency, timezone and country is invalid, keyed by
+// field, or nil when all that are given are valid. Currencies are ISO 4217
+// codes, countries ISO 3166-1 alpha-2 or alpha-3 codes and timezones names in
+// the IANA tz database, which the reports load them from. A timezone given as
+// a UTC offset is replaced by its name.
+func checkLocale(currencyCode, timezone, country *string) map[string]string {
+	fields := map[string]string{}
+	if currencyCode != nil {
+		code, err := currency.Normalize(*currencyCode)
+		if err != nil || localeValidator.Var(code, "iso4217") != nil {
+			fields["currency"] = "must be an ISO 4217 currency code"
+		} else {
+			*currencyCode = code
+		}
+	}
+	if timezone != nil {
+		if name, ok := normalizeTimezone(*timezone); ok {
+			*timezone = name
+		} else {
+			fields["timezone"] = "must be an IANA time zone, e.g. Africa/Lagos"
+		}
+	}
+	if country != nil {
+		code := strings.ToUpper(strings.TrimSpace(*country))
+		if localeValidator.Var(code, "iso3166_1_alpha2|iso3166_1_alpha3") != nil {
+			fields["country"] = "must be an ISO 3166 country code"
+		} else {
+			*country = code
+		}
+	}
+	if len(fields) == 0 {
+		return nil
+	}
+	return fields
+}
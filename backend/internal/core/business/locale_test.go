@@ -0,0 +1,243 @@
+package business
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"herp/db/dbtest"
+	db "herp/db/sqlc"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNormalizeTimezone(t *testing.T) {
+	tests := []struct {
+		timezone string
+		want     string
+		ok       bool
+	}{
+		{"Africa/Lagos", "Africa/Lagos", true},
+		{" America/Argentina/Buenos_Aires ", "America/Argentina/Buenos_Aires", true},
+		{"UTC +1", "Etc/GMT-1", true},
+		{"utc+01:00", "Etc/GMT-1", true},
+		{"GMT -5", "Etc/GMT+5", true},
+		{"UTC +0", "UTC", true},
+		{"UTC +14", "Etc/GMT-14", true},
+		{"UTC +5:30", "Asia/Kolkata", true},
+		{"UTC+0545", "Asia/Kathmandu", true},
+		{"GMT -3:30", "America/St_Johns", true},
+		{"UTC +9:30", "Australia/Darwin", true},
+		// no place keeps these offsets
+		{"UTC +2:30", "", false},
+		{"UTC +15", "", false},
+		{"UTC -13", "", false},
+		{"UTC +5:75", "", false},
+		{"Mars/Olympus_Mons", "", false},
+		{"", "", false},
+		{"Local", "", false},
+	}
+	for _, tt := range tests {
+		got, ok := normalizeTimezone(tt.timezone)
+		assert.Equal(t, tt.ok, ok, tt.timezone)
+		assert.Equal(t, tt.want, got, tt.timezone)
+	}
+}
+
+func TestNormalizeTimezoneMigration(t *testing.T) {
+	conn := dbtest.Open(t)
+	owner := dbtest.Admin(t, conn, "owner")
+	timezones := map[string]string{
+		"UTC +1":       "Etc/GMT-1",
+		"UTC +5:30":    "Asia/Kolkata",
+		"GMT-03:30":    "America/St_Johns",
+		"Africa/Lagos": "Africa/Lagos",
+		"UTC +2:30":    "UTC",
+		"Lagos":        "UTC",
+	}
+	ids := map[string]int32{}
+	for timezone := range timezones {
+		id, _ := dbtest.Business(t, conn, owner, "Business "+timezone)
+		dbtest.Exec(t, conn, `UPDATE business SET timezone = $1 WHERE id = $2`, timezone, id)
+		ids[timezone] = id
+	}
+
+	migration, err := os.ReadFile(filepath.Join("..", "..", "..", "db", "migrations", "000036_normalize_timezone.up.sql"))
+	require.NoError(t, err)
+	dbtest.Exec(t, conn, string(migration))
+
+	for timezone, want := range timezones {
+		var got string
+		require.NoError(t, conn.QueryRow(`SELECT timezone FROM business WHERE id = $1`, ids[timezone]).Scan(&got))
+		assert.Equal(t, want, got, timezone)
+	}
+
+	// the values that named no zone are left in the activity log
+	rows, err := conn.Query(`SELECT entity_id, details FROM activity_logs WHERE action = 'timezone_reset' ORDER BY entity_id`)
+	require.NoError(t, err)
+	defer rows.Close()
+	reset := map[int32]string{}
+	for rows.Next() {
+		var id int32
+		var details string
+		require.NoError(t, rows.Scan(&id, &details))
+		reset[id] = details
+	}
+	require.NoError(t, rows.Err())
+	assert.Len(t, reset, 2)
+	assert.Contains(t, reset[ids["UTC +2:30"]], "'UTC +2:30'")
+	assert.Contains(t, reset[ids["Lagos"]], "'Lagos'")
+}
+
+func TestCheckLocale(t *testing.T) {
+	text := func(s string) *string { return &s }
+	tests := []struct {
+		name                        string
+		currency, timezone, country *string
+		// normalized values, or the fields reported invalid
+		wantCurrency, wantTimezone, wantCountry string
+		invalid                                 []string
+	}{
+		{name: "nothing given"},
+		{
+			name:     "valid",
+			currency: text("ngn"), timezone: text("Africa/Lagos"), country: text(" ng "),
+			wantCurrency: "NGN", wantTimezone: "Africa/Lagos", wantCountry: "NG",
+		},
+		{
+			name:     "alpha-3 country and offset timezone",
+			currency: text("USD"), timezone: text("UTC +5:30"), country: text("ind"),
+			wantCurrency: "USD", wantTimezone: "Asia/Kolkata", wantCountry: "IND",
+		},
+		{name: "unknown currency", currency: text("XYZ"), invalid: []string{"currency"}},
+		{name: "currency name", currency: text("naira"), invalid: []string{"currency"}},
+		{name: "unknown timezone", timezone: text("Lagos"), invalid: []string{"timezone"}},
+		{name: "empty timezone", timezone: text(""), invalid: []string{"timezone"}},
+		{name: "unknown country", country: text("XX"), invalid: []string{"country"}},
+		{name: "country name", country: text("Nigeria"), invalid: []string{"country"}},
+		{
+			name:     "all invalid",
+			currency: text("ABCD"), timezone: text("UTC +2:30"), country: text("ZZZ"),
+			invalid: []string{"country", "currency", "timezone"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fields := checkLocale(tt.currency, tt.timezone, tt.country)
+			if tt.invalid != nil {
+				var invalid []string
+				for field := range fields {
+					invalid = append(invalid, field)
+				}
+				assert.ElementsMatch(t, tt.invalid, invalid)
+				return
+			}
+			assert.Nil(t, fields)
+			if tt.currency != nil {
+				assert.Equal(t, tt.wantCurrency, *tt.currency)
+			}
+			if tt.timezone != nil {
+				assert.Equal(t, tt.wantTimezone, *tt.timezone)
+			}
+			if tt.country != nil {
+				assert.Equal(t, tt.wantCountry, *tt.country)
+			}
+		})
+	}
+}
+
+// localeService keeps the updates it is asked to make.
+type localeService struct {
+	businessService
+	updates []db.UpdateBusinessParams
+}
+
+func (s *localeService) UpdateBusiness(ctx context.Context, params db.UpdateBusinessParams, changedBy int32) (db.Business, error) {
+	s.updates = append(s.updates, params)
+	return db.Business{ID: params.ID, OwnerID: params.OwnerID, Timezone: params.Timezone, Currency: params.Currency}, nil
+}
+
+func (s *localeService) LogActivity(ctx context.Context, params db.LogActivityParams) (db.ActivityLog, error) {
+	return db.ActivityLog{}, nil
+}
+
+func TestUpdateBusinessLocale(t *testing.T) {
+	service := &localeService{}
+	business := service.add(1, "Palmwine Express")
+	h, r := newBusinessRouter(service, 1)
+	r.PATCH("/business/:id", h.updateBusiness)
+	patch := func(body string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodPatch, fmt.Sprintf("/business/%d", business.ID), strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		r.ServeHTTP(w, req)
+		return w
+	}
+
+	w := patch(`{"currency":"NAIRA","timezone":"Lagos","country":"Nigeria"}`)
+	require.Equal(t, http.StatusBadRequest, w.Code)
+	var body struct {
+		Data map[string]string `json:"data"`
+	}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &body))
+	for _, field := range []string{"currency", "timezone", "country"} {
+		assert.Contains(t, body.Data, field)
+	}
+	assert.Empty(t, service.updates)
+
+	// only the offending field is reported
+	w = patch(`{"currency":"NGN","timezone":"Africa/Lagos","country":"XX"}`)
+	require.Equal(t, http.StatusBadRequest, w.Code)
+	body.Data = nil
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &body))
+	assert.Len(t, body.Data, 1)
+	assert.Contains(t, body.Data, "country")
+	assert.Empty(t, service.updates)
+
+	w = patch(`{"currency":"ngn","timezone":"UTC +1","country":"ng"}`)
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	require.Len(t, service.updates, 1)
+	assert.Equal(t, sql.NullString{String: "NGN", Valid: true}, service.updates[0].Currency)
+	assert.Equal(t, sql.NullString{String: "Etc/GMT-1", Valid: true}, service.updates[0].Timezone)
+	assert.Equal(t, sql.NullString{String: "NG", Valid: true}, service.updates[0].Country)
+}
+
+func TestBusinessTimezoneDownMigration(t *testing.T) {
+	conn := dbtest.Open(t)
+	owner := dbtest.Admin(t, conn, "owner")
+	timezones := map[string]string{
+		"Etc/GMT-1":                      "Etc/GMT-1",
+		"UTC":                            "UTC",
+		"Asia/Kolkata":                   "UTC +5:30",
+		"America/Argentina/Buenos_Aires": "UTC -3",
+		"Pacific/Marquesas":              "UTC -9:30",
+	}
+	ids := map[string]int32{}
+	for timezone := range timezones {
+		id, _ := dbtest.Business(t, conn, owner, "Business "+timezone)
+		dbtest.Exec(t, conn, `UPDATE business SET timezone = $1 WHERE id = $2`, timezone, id)
+		ids[timezone] = id
+	}
+
+	migration, err := os.ReadFile(filepath.Join("..", "..", "..", "db", "migrations", "000035_business_timezone.down.sql"))
+	require.NoError(t, err)
+	// rolled back, the other tests need the wider column
+	tx, err := conn.Begin()
+	require.NoError(t, err)
+	defer tx.Rollback()
+	_, err = tx.Exec(string(migration))
+	require.NoError(t, err)
+
+	for timezone, want := range timezones {
+		var got string
+		require.NoError(t, tx.QueryRow(`SELECT timezone FROM business WHERE id = $1`, ids[timezone]).Scan(&got))
+		assert.Equal(t, want, got, timezone)
+	}
+}
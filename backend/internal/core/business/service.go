@@ -16,10 +16,16 @@ package business
 import (
 	"context"
 	"database/sql"
+	"errors"
 	"fmt"
 	db "herp/db/sqlc"
+	"slices"
 )
 
+// ErrRecordModified is returned by an update made from a version of a
+// business that is no longer the current one.
+var ErrRecordModified = errors.New("record modified")
+
 type Business struct {
 	db      *sql.DB
 	queries Querier
@@ -86,19 +92,110 @@ func (c *Business) GetBusiness(ctx context.Context, args db.GetBusinessParams) (
 	return c.queries.GetBusiness(ctx, args)
 }
 
-// UpdateBusiness updates an existing business.
-func (c *Business) UpdateBusiness(ctx context.Context, params db.UpdateBusinessParams) (db.Business, error) {
-	return c.queries.UpdateBusiness(ctx, params)
+// UpdateBusiness updates an existing business. When the update changes its
+// tax rate, rounding, currency, payment types or branding, the settings it
+// had before are written to the settings history in the same transaction.
+// When params.ExpectedVersion is set and the business has moved past it,
+// nothing is changed and ErrRecordModified is returned.
+func (c *Business) UpdateBusiness(ctx context.Context, params db.UpdateBusinessParams, changedBy int32) (db.Business, error) {
+	q, ok := c.queries.(*db.Queries)
+	if !ok {
+		return db.Business{}, fmt.Errorf("invalid query type in business")
+	}
+
+	tx, err := c.db.BeginTx(ctx, nil)
+	if err != nil {
+		return db.Business{}, err
+	}
+	defer tx.Rollback()
+
+	txQueries := q.WithTx(tx)
+
+	previous, err := txQueries.GetBusinessForUpdate(ctx, db.GetBusinessForUpdateParams{
+		ID:      params.ID,
+		OwnerID: params.OwnerID,
+	})
+	if err != nil {
+		return db.Business{}, err
+	}
+	// the row is locked, nobody can change the version before the update
+	if params.ExpectedVersion.Valid && previous.Version != params.ExpectedVersion.Int32 {
+		return db.Business{}, ErrRecordModified
+	}
+
+	business, err := txQueries.UpdateBusiness(ctx, params)
+	if err != nil {
+		return db.Business{}, err
+	}
+
+	if settingsChanged(previous, business) {
+		if _, err := txQueries.CreateBusinessSettingsHistory(ctx, db.CreateBusinessSettingsHistoryParams{
+			BusinessID:       previous.ID,
+			TaxRate:          previous.TaxRate,
+			Rounding:         previous.Rounding,
+			Currency:         previous.Currency,
+			PaymentType:      previous.PaymentType,
+			Motto:            previous.Motto,
+			LogoUrl:          previous.LogoUrl,
+			LogoThumbnailUrl: previous.LogoThumbnailUrl,
+			Font:             previous.Font,
+			PrimaryColor:     previous.PrimaryColor,
+			ChangedBy:        changedBy,
+		}); err != nil {
+			return db.Business{}, err
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return db.Business{}, err
+	}
+	return business, nil
+}
+
+// settingsChanged reports whether any setting kept in the settings history
+// differs between two versions of a business.
+func settingsChanged(previous, current db.Business) bool {
+	return previous.TaxRate != current.TaxRate ||
+		previous.Rounding != current.Rounding ||
+		previous.Currency != current.Currency ||
+		!slices.Equal(previous.PaymentType, current.PaymentType) ||
+		previous.Motto != current.Motto ||
+		previous.LogoUrl != current.LogoUrl ||
+		previous.LogoThumbnailUrl != current.LogoThumbnailUrl ||
+		previous.Font != current.Font ||
+		previous.PrimaryColor != current.PrimaryColor
 }
 
-// DeleteBusiness deletes a business by its ID.
+// ListSettingsHistory returns the settings a business had before each of
+// their changes, oldest first.
+func (c *Business) ListSettingsHistory(ctx context.Context, businessID int32) ([]db.BusinessSettingsHistory, error) {
+	return c.queries.ListBusinessSettingsHistory(ctx, businessID)
+}
+
+// DeleteBusiness soft deletes a business by its ID, it is hidden from reads
+// until it is restored.
 func (c *Business) DeleteBusiness(ctx context.Context, args db.DeleteBusinessParams) (db.Business, error) {
 	return c.queries.DeleteBusiness(ctx, args)
 }
 
-// ListBusinesses lists all businesses.
-func (c *Business) ListBusinesses(ctx context.Context, ownerID int32) ([]db.Business, error) {
-	return c.queries.ListBusinesses(ctx, ownerID)
+// RestoreBusiness undeletes a business deleted after args.DeletedAfter.
+func (c *Business) RestoreBusiness(ctx context.Context, args db.RestoreBusinessParams) (db.Business, error) {
+	return c.queries.RestoreBusiness(ctx, args)
+}
+
+// HardDeleteBusiness permanently removes a business, deleted or not.
+func (c *Business) HardDeleteBusiness(ctx context.Context, args db.HardDeleteBusinessParams) (db.Business, error) {
+	return c.queries.HardDeleteBusiness(ctx, args)
+}
+
+// ListBusinesses lists a page of the businesses owned by params.OwnerID.
+func (c *Business) ListBusinesses(ctx context.Context, params db.ListBusinessesParams) ([]db.Business, error) {
+	return c.queries.ListBusinesses(ctx, params)
+}
+
+// CountBusinesses counts the businesses owned by ownerID.
+func (c *Business) CountBusinesses(ctx context.Context, ownerID int32) (int64, error) {
+	return c.queries.CountBusinesses(ctx, ownerID)
 }
 
 // --------Branch Methods-------- //
@@ -123,9 +220,26 @@ func (c *Business) DeleteBranch(ctx context.Context, id int32) (db.Branch, error
 	return c.queries.DeleteBranch(ctx, id)
 }
 
-// ListBranch lists branches
-func (c *Business) ListBranches(ctx context.Context) ([]db.Branch, error) {
-	return c.queries.ListBranches(ctx)
+// ListBranch lists branches of businesses owned by params.OwnerID
+func (c *Business) ListBranches(ctx context.Context, params db.ListBranchesParams) ([]db.Branch, error) {
+	return c.queries.ListBranches(ctx, params)
+}
+
+// CountBranches counts branches of businesses owned by params.OwnerID
+func (c *Business) CountBranches(ctx context.Context, params db.CountBranchesParams) (int64, error) {
+	return c.queries.CountBranches(ctx, params)
+}
+
+// ListActivityLogs lists activity logs of the businesses owned by
+// params.OwnerID, their branches and stores
+func (c *Business) ListActivityLogs(ctx context.Context, params db.ListBusinessActivityLogsParams) ([]db.ActivityLog, error) {
+	return c.queries.ListBusinessActivityLogs(ctx, params)
+}
+
+// CountActivityLogs counts activity logs of the businesses owned by
+// params.OwnerID, their branches and stores
+func (c *Business) CountActivityLogs(ctx context.Context, params db.CountBusinessActivityLogsParams) (int64, error) {
+	return c.queries.CountBusinessActivityLogs(ctx, params)
 }
 
 func (c *Business) LogActivity(ctx context.Context, params db.LogActivityParams) (db.ActivityLog, error) {
@@ -0,0 +1,75 @@
+package business
+
+import (
+	"context"
+	"database/sql"
+	"herp/db/dbtest"
+	db "herp/db/sqlc"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func text(s string) sql.NullString {
+	return sql.NullString{String: s, Valid: true}
+}
+
+func TestUpdateBusinessSettingsHistory(t *testing.T) {
+	conn := dbtest.Open(t)
+	owner := dbtest.Admin(t, conn, "owner")
+	businessID, _ := dbtest.Business(t, conn, owner, "Palmwine Express")
+	service := NewBusiness(db.New(conn), conn)
+	ctx := context.Background()
+
+	_, err := service.UpdateBusiness(ctx, db.UpdateBusinessParams{
+		ID: businessID, OwnerID: owner, TaxRate: text("7.50"),
+	}, owner)
+	require.NoError(t, err)
+	// a name isn't a setting, no history for it
+	_, err = service.UpdateBusiness(ctx, db.UpdateBusinessParams{
+		ID: businessID, OwnerID: owner, Name: text("Palmwine Express Ltd"),
+	}, owner)
+	require.NoError(t, err)
+	updated, err := service.UpdateBusiness(ctx, db.UpdateBusinessParams{
+		ID: businessID, OwnerID: owner, TaxRate: text("10.00"), Rounding: text("up"),
+		PaymentType: []db.PaymentType{db.PaymentTypeCash, db.PaymentTypePos},
+	}, owner+1)
+	require.NoError(t, err)
+	assert.Equal(t, "10.00", updated.TaxRate.String)
+
+	history, err := service.ListSettingsHistory(ctx, businessID)
+	require.NoError(t, err)
+	require.Len(t, history, 2)
+
+	// each row holds what the settings were before the change
+	first, second := history[0], history[1]
+	assert.Equal(t, text("0.00"), first.TaxRate)
+	assert.Equal(t, text("nearest"), first.Rounding)
+	assert.Equal(t, []db.PaymentType{db.PaymentTypeCash}, first.PaymentType)
+	assert.Equal(t, owner, first.ChangedBy)
+
+	assert.Equal(t, text("7.50"), second.TaxRate)
+	assert.Equal(t, text("nearest"), second.Rounding)
+	assert.Equal(t, []db.PaymentType{db.PaymentTypeCash}, second.PaymentType)
+	assert.Equal(t, owner+1, second.ChangedBy)
+	assert.False(t, second.ChangedAt.Before(first.ChangedAt))
+}
+
+func TestUpdateBusinessSettingsHistoryNotOwner(t *testing.T) {
+	conn := dbtest.Open(t)
+	owner := dbtest.Admin(t, conn, "owner")
+	other := dbtest.Admin(t, conn, "other")
+	businessID, _ := dbtest.Business(t, conn, owner, "Palmwine Express")
+	service := NewBusiness(db.New(conn), conn)
+	ctx := context.Background()
+
+	_, err := service.UpdateBusiness(ctx, db.UpdateBusinessParams{
+		ID: businessID, OwnerID: other, TaxRate: text("7.50"),
+	}, other)
+	assert.ErrorIs(t, err, sql.ErrNoRows)
+
+	history, err := service.ListSettingsHistory(ctx, businessID)
+	require.NoError(t, err)
+	assert.Empty(t, history)
+}
@@ -0,0 +1,51 @@
+package business
+
+import (
+	"context"
+	"database/sql"
+	"herp/db/dbtest"
+	db "herp/db/sqlc"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func version(v int32) sql.NullInt32 {
+	return sql.NullInt32{Int32: v, Valid: true}
+}
+
+func TestUpdateBusinessVersion(t *testing.T) {
+	conn := dbtest.Open(t)
+	owner := dbtest.Admin(t, conn, "owner")
+	businessID, _ := dbtest.Business(t, conn, owner, "Palmwine Express")
+	service := NewBusiness(db.New(conn), conn)
+	ctx := context.Background()
+
+	updated, err := service.UpdateBusiness(ctx, db.UpdateBusinessParams{
+		ID: businessID, OwnerID: owner, TaxRate: text("7.50"), ExpectedVersion: version(1),
+	}, owner)
+	require.NoError(t, err)
+	assert.Equal(t, int32(2), updated.Version)
+
+	// made from the copy read before the first update
+	_, err = service.UpdateBusiness(ctx, db.UpdateBusinessParams{
+		ID: businessID, OwnerID: owner, TaxRate: text("5.00"), ExpectedVersion: version(1),
+	}, owner)
+	assert.ErrorIs(t, err, ErrRecordModified)
+
+	got, err := service.GetBusiness(ctx, db.GetBusinessParams{ID: businessID, OwnerID: owner})
+	require.NoError(t, err)
+	assert.Equal(t, "7.50", got.TaxRate.String)
+	assert.Equal(t, int32(2), got.Version)
+	history, err := service.ListSettingsHistory(ctx, businessID)
+	require.NoError(t, err)
+	assert.Len(t, history, 1, "nothing written for the rejected update")
+
+	// without a version the update always goes through
+	updated, err = service.UpdateBusiness(ctx, db.UpdateBusinessParams{
+		ID: businessID, OwnerID: owner, Name: text("Palmwine Express Ltd"),
+	}, owner)
+	require.NoError(t, err)
+	assert.Equal(t, int32(3), updated.Version)
+}
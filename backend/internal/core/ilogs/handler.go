@@ -1,9 +1,14 @@
 package logs
 
 import (
+	"database/sql"
+	db "herp/db/sqlc"
 	"herp/internal/auth"
 	"herp/internal/utils"
+	"herp/pkg/jwt"
 	"herp/pkg/monitoring/logging"
+	"strconv"
+	"strings"
 	"time"
 
 	"github.com/gin-gonic/gin"
@@ -28,6 +33,12 @@ func (h *LogsHandler) RegisterRoutes(rg *gin.RouterGroup, authSvc *auth.Service)
 	{
 		logs.GET("/", auth.PermissionMiddleware(authSvc, "logs:activity_logs"), h.GetActivityLogs)
 	}
+
+	audit := rg.Group("/admin/audit")
+	audit.Use(auth.AdminMiddleware(authSvc))
+	{
+		audit.GET("/search", auth.PermissionMiddleware(authSvc, "admin:audit"), h.SearchActivityLogs)
+	}
 }
 
 type LogsResponse struct {
@@ -79,3 +90,123 @@ func (h *LogsHandler) GetActivityLogs(c *gin.Context) {
 
 	utils.SuccessResponse(c, 200, "Logs fetched successfully", logsResponse)
 }
+
+// parseSearchTime reads a date or an RFC3339 time. A date given as the end of
+// a range includes the whole day.
+func parseSearchTime(value string, end bool) (time.Time, error) {
+	if t, err := time.Parse("2006-01-02", value); err == nil {
+		if end {
+			t = t.AddDate(0, 0, 1)
+		}
+		return t, nil
+	}
+	return time.Parse(time.RFC3339, value)
+}
+
+// SearchActivityLogs godoc
+// @Summary Search activity logs
+// @Description Full text search the details of activity logs of the caller's businesses, e.g. every action mentioning a business name, newest first. Only the caller's own actions, those of the users of their branches and those on their businesses, branches, stores and stock transfers are searched. Words are all required, quoted phrases and -word are supported. The structured filters narrow the results further.
+// @Tags Logs
+// @Produce json
+// @Security BearerAuth
+// @Param q query string true "Search terms"
+// @Param user_id query int false "Only actions of this user"
+// @Param action query string false "Only this action"
+// @Param entity_type query string false "Only actions on this entity type"
+// @Param start query string false "Start date (YYYY-MM-DD or RFC3339)"
+// @Param end query string false "End date, inclusive (YYYY-MM-DD or RFC3339)"
+// @Param page query int false "Page number, defaults to 1"
+// @Param limit query int false "Entries per page, defaults to 20, at most 100"
+// @Success 200 {object} []LogsResponse
+// @Failure 400
+// @Failure 401
+// @Failure 403
+// @Failure 500
+// @Router /api/v1/admin/audit/search [get]
+func (h *LogsHandler) SearchActivityLogs(c *gin.Context) {
+	claims, ok := jwt.GetUserFromContext(c)
+	if !ok {
+		h.logger.Errorf("could not get user from context")
+		utils.ErrorResponse(c, 500, utils.SERVERERROR)
+		return
+	}
+
+	query := strings.TrimSpace(c.Query("q"))
+	if query == "" {
+		utils.ErrorResponse(c, 400, "q is required")
+		return
+	}
+
+	page, err := strconv.Atoi(c.DefaultQuery("page", "1"))
+	if err != nil || page < 1 {
+		utils.ErrorResponse(c, 400, utils.INVALID_REQUEST_DATA)
+		return
+	}
+
+	limit, err := strconv.Atoi(c.DefaultQuery("limit", "20"))
+	if err != nil || limit < 1 {
+		utils.ErrorResponse(c, 400, utils.INVALID_REQUEST_DATA)
+		return
+	}
+	if limit > 100 {
+		limit = 100
+	}
+
+	params := db.SearchActivityLogsParams{
+		Query:      query,
+		Action:     sql.NullString{String: c.Query("action"), Valid: c.Query("action") != ""},
+		EntityType: sql.NullString{String: c.Query("entity_type"), Valid: c.Query("entity_type") != ""},
+		OwnerID:    int32(claims.UserID),
+		PageLimit:  int32(limit),
+		PageOffset: int32((page - 1) * limit),
+	}
+
+	if v := c.Query("user_id"); v != "" {
+		userID, err := strconv.Atoi(v)
+		if err != nil {
+			utils.ErrorResponse(c, 400, "invalid user_id")
+			return
+		}
+		params.UserID = sql.NullInt32{Int32: int32(userID), Valid: true}
+	}
+	if v := c.Query("start"); v != "" {
+		t, err := parseSearchTime(v, false)
+		if err != nil {
+			utils.ErrorResponse(c, 400, "invalid start date")
+			return
+		}
+		params.StartTime = sql.NullTime{Time: t, Valid: true}
+	}
+	if v := c.Query("end"); v != "" {
+		t, err := parseSearchTime(v, true)
+		if err != nil {
+			utils.ErrorResponse(c, 400, "invalid end date")
+			return
+		}
+		params.EndTime = sql.NullTime{Time: t, Valid: true}
+	}
+
+	logs, err := h.service.SearchActivityLogs(c, params)
+	if err != nil {
+		h.logger.Errorf("error searching activity logs: %v", err)
+		utils.ErrorResponse(c, 500, utils.SERVERERROR)
+		return
+	}
+
+	response := make([]LogsResponse, 0, len(logs))
+	for _, log := range logs {
+		response = append(response, LogsResponse{
+			ID:         log.ID,
+			UserID:     log.UserID,
+			Action:     log.Action,
+			Details:    log.Details,
+			EntityID:   log.EntityID,
+			EntityType: log.EntityType,
+			IpAddress:  log.IpAddress.String,
+			UserAgent:  log.UserAgent.String,
+			CreatedAt:  log.CreatedAt.Time,
+		})
+	}
+
+	utils.SuccessResponse(c, 200, "activity logs", response)
+}
@@ -0,0 +1,108 @@
+package logs
+
+import (
+	"database/sql"
+	"encoding/json"
+	"herp/db/dbtest"
+	db "herp/db/sqlc"
+	"herp/internal/config"
+	"herp/pkg/jwt"
+	"herp/pkg/monitoring/logging"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// branchUser creates a user of the branch.
+func branchUser(t *testing.T, conn *sql.DB, username string, branchID int32) int32 {
+	t.Helper()
+	return dbtest.Insert(t, conn, `
+		INSERT INTO users (username, first_name, last_name, password_hash, branch_id)
+		VALUES ($1, 'Test', 'User', 'x', $2)
+		RETURNING id`, username, branchID)
+}
+
+func activity(t *testing.T, conn *sql.DB, userID int32, entityType string, entityID int32, details string) int32 {
+	t.Helper()
+	return dbtest.Insert(t, conn, `
+		INSERT INTO activity_logs (user_id, action, details, entity_id, entity_type)
+		VALUES ($1, 'Updated', $2, $3, $4)
+		RETURNING id`, userID, details, entityID, entityType)
+}
+
+// searchLogs searches the activity logs as the admin ownerID.
+func searchLogs(t *testing.T, conn *sql.DB, ownerID int32, query string) []int32 {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+	h := NewLogsHandler(NewLogs(conn, db.New(conn)), logging.NewLogger(&config.Config{}))
+	r := gin.New()
+	r.Use(func(c *gin.Context) {
+		c.Set("claims", &jwt.Claims{UserID: int(ownerID), Username: "owner"})
+	})
+	r.GET("/admin/audit/search", h.SearchActivityLogs)
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/admin/audit/search?"+query, nil))
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	var body struct {
+		Data []LogsResponse `json:"data"`
+	}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &body))
+	ids := []int32{}
+	for _, log := range body.Data {
+		ids = append(ids, log.ID)
+	}
+	return ids
+}
+
+func TestSearchActivityLogsScope(t *testing.T) {
+	conn := dbtest.Open(t)
+	ours := dbtest.Admin(t, conn, "ours")
+	theirs := dbtest.Admin(t, conn, "theirs")
+	ourBusiness, ourBranch := dbtest.Business(t, conn, ours, "Palmwine Express")
+	theirBusiness, theirBranch := dbtest.Business(t, conn, theirs, "Zobo Palace")
+	ourStore := dbtest.Store(t, conn, ourBranch, "Bar")
+	ourCashier := branchUser(t, conn, "ada", ourBranch)
+	theirCashier := branchUser(t, conn, "bola", theirBranch)
+
+	byOwner := activity(t, conn, ours, "Item", 1, "Renamed palm wine")
+	byCashier := activity(t, conn, ourCashier, "Sale", 1, "Sold palm wine")
+	onStore := activity(t, conn, theirs, "Store", ourStore, "Moved palm wine to the bar")
+	onBusiness := activity(t, conn, ours, "Business", ourBusiness, "Palm wine tax rate changed")
+	activity(t, conn, theirs, "Item", 2, "Renamed palm wine")
+	activity(t, conn, theirCashier, "Sale", 2, "Sold palm wine")
+	activity(t, conn, theirs, "Business", theirBusiness, "Palm wine tax rate changed")
+
+	assert.ElementsMatch(t, []int32{byOwner, byCashier, onStore, onBusiness}, searchLogs(t, conn, ours, "q=palm+wine"))
+	assert.Len(t, searchLogs(t, conn, theirs, "q=palm+wine"), 4)
+
+	// the filters don't reach past the scope
+	assert.Empty(t, searchLogs(t, conn, ours, "q=palm+wine&user_id="+itoa(theirCashier)))
+	assert.Equal(t, []int32{byCashier}, searchLogs(t, conn, ours, "q=sold&user_id="+itoa(ourCashier)))
+}
+
+func TestSearchActivityLogsUserSharingOwnerID(t *testing.T) {
+	conn := dbtest.Open(t)
+	owner := dbtest.Admin(t, conn, "owner")
+	dbtest.Business(t, conn, owner, "Palmwine Express")
+	other := dbtest.Admin(t, conn, "other")
+	_, otherBranch := dbtest.Business(t, conn, other, "Zobo Palace")
+
+	// a user of another business numbered like the owner
+	dbtest.Exec(t, conn, `
+		INSERT INTO users (id, username, first_name, last_name, password_hash, branch_id)
+		VALUES ($1, 'namesake', 'Test', 'User', 'x', $2)`, owner, otherBranch)
+	activity(t, conn, owner, "Sale", 1, "Sold zobo")
+
+	assert.Empty(t, searchLogs(t, conn, owner, "q=zobo"))
+	assert.Len(t, searchLogs(t, conn, other, "q=zobo"), 1)
+}
+
+func itoa(id int32) string {
+	return strconv.Itoa(int(id))
+}
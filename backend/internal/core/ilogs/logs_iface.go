@@ -7,8 +7,10 @@ import (
 
 type Querier interface {
 	GetActivityLogs(ctx context.Context, limit int32) ([]db.ActivityLog, error)
+	SearchActivityLogs(ctx context.Context, params db.SearchActivityLogsParams) ([]db.ActivityLog, error)
 }
 
 type LogsInterface interface {
 	GetActivityLogs(ctx context.Context, limit int32) ([]db.ActivityLog, error)
+	SearchActivityLogs(ctx context.Context, params db.SearchActivityLogsParams) ([]db.ActivityLog, error)
 }
@@ -17,4 +17,10 @@ func NewLogs(db *sql.DB, queries Querier) *Logs {
 
 func(l *Logs) GetActivityLogs(ctx context.Context, limit int32) ([]db.ActivityLog, error) {
 	return l.queries.GetActivityLogs(ctx, limit)
-}
\ No newline at end of file
+}
+
+// SearchActivityLogs full text searches the details of activity logs,
+// newest first.
+func (l *Logs) SearchActivityLogs(ctx context.Context, params db.SearchActivityLogsParams) ([]db.ActivityLog, error) {
+	return l.queries.SearchActivityLogs(ctx, params)
+}
@@ -0,0 +1,135 @@
+package inventory
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	db "herp/db/sqlc"
+)
+
+// Reasons a manual stock adjustment can be recorded under.
+const (
+	AdjustmentDamage  = "damage"
+	AdjustmentTheft   = "theft"
+	AdjustmentRecount = "recount"
+	AdjustmentRestock = "restock"
+)
+
+var (
+	ErrInvalidAdjustmentReason = errors.New("invalid adjustment reason")
+	ErrAdjustmentDirection     = errors.New("adjustment does not match its reason")
+	ErrNegativeStock           = errors.New("adjustment would leave negative stock")
+)
+
+type AdjustStockParams struct {
+	StoreID     int32
+	VariationID int32
+	// change applied to the stock on hand, negative to write stock off
+	Delta      int32
+	Reason     string
+	AdjustedBy int32
+}
+
+// checkAdjustmentReason makes sure delta goes the way the reason implies:
+// damaged and stolen stock is written off, a restock adds to it and a recount
+// can go either way.
+func checkAdjustmentReason(reason string, delta int32) error {
+	switch reason {
+	case AdjustmentDamage, AdjustmentTheft:
+		if delta > 0 {
+			return ErrAdjustmentDirection
+		}
+	case AdjustmentRestock:
+		if delta < 0 {
+			return ErrAdjustmentDirection
+		}
+	case AdjustmentRecount:
+	default:
+		return ErrInvalidAdjustmentReason
+	}
+	return nil
+}
+
+// AdjustStock changes the stock of a variation in a store by hand and records
+// the change as a stock adjustment, in one transaction. Stock cannot go below
+// zero unless the store's business allows overselling. Stock written off is
+// also taken out of the variation's batches, like a cycle count does.
+func (i *Inventory) AdjustStock(ctx context.Context, args AdjustStockParams) (db.StockAdjustment, db.Inventory, error) {
+	if err := checkAdjustmentReason(args.Reason, args.Delta); err != nil {
+		return db.StockAdjustment{}, db.Inventory{}, err
+	}
+
+	q, ok := i.queries.(*db.Queries)
+	if !ok {
+		return db.StockAdjustment{}, db.Inventory{}, fmt.Errorf("invalid query type in inventory")
+	}
+
+	tx, err := i.db.BeginTx(ctx, nil)
+	if err != nil {
+		return db.StockAdjustment{}, db.Inventory{}, err
+	}
+	defer tx.Rollback()
+
+	txQueries := q.WithTx(tx)
+
+	var previous int32
+	stock, err := txQueries.GetInventoryItemForUpdate(ctx, db.GetInventoryItemForUpdateParams{
+		StoreID:     args.StoreID,
+		VariationID: args.VariationID,
+	})
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return db.StockAdjustment{}, db.Inventory{}, err
+	}
+	if err == nil {
+		previous = stock.Quantity
+	}
+
+	if previous+args.Delta < 0 {
+		allowOverselling, err := txQueries.GetStoreAllowOverselling(ctx, args.StoreID)
+		if err != nil {
+			return db.StockAdjustment{}, db.Inventory{}, err
+		}
+		if !allowOverselling {
+			return db.StockAdjustment{}, db.Inventory{}, ErrNegativeStock
+		}
+	}
+
+	updated, err := txQueries.IncrementInventoryQuantity(ctx, db.IncrementInventoryQuantityParams{
+		StoreID:     args.StoreID,
+		VariationID: args.VariationID,
+		Quantity:    args.Delta,
+	})
+	if err != nil {
+		return db.StockAdjustment{}, db.Inventory{}, err
+	}
+
+	adjustment, err := txQueries.CreateStockAdjustment(ctx, db.CreateStockAdjustmentParams{
+		StoreID:          args.StoreID,
+		VariationID:      args.VariationID,
+		PreviousQuantity: previous,
+		Quantity:         args.Delta,
+		Reason:           args.Reason,
+		AdjustedBy:       args.AdjustedBy,
+	})
+	if err != nil {
+		return db.StockAdjustment{}, db.Inventory{}, err
+	}
+
+	if args.Delta < 0 {
+		if _, err := ConsumeBatchesTx(ctx, txQueries, db.ConsumeInventoryBatchesParams{
+			StoreID:     args.StoreID,
+			VariationID: args.VariationID,
+			Quantity:    -args.Delta,
+			Reason:      args.Reason,
+			ReferenceID: sql.NullInt32{Int32: adjustment.ID, Valid: true},
+		}); err != nil {
+			return db.StockAdjustment{}, db.Inventory{}, err
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return db.StockAdjustment{}, db.Inventory{}, err
+	}
+	return adjustment, updated, nil
+}
@@ -0,0 +1,130 @@
+package inventory
+
+import (
+	"context"
+	"database/sql"
+	"herp/db/dbtest"
+	db "herp/db/sqlc"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type adjustmentFixture struct {
+	conn            *sql.DB
+	service         *Inventory
+	business, store int32
+	palmWine        int32
+}
+
+func newAdjustmentFixture(t *testing.T) adjustmentFixture {
+	t.Helper()
+	conn := dbtest.Open(t)
+	owner := dbtest.Admin(t, conn, "owner")
+	businessID, branchID := dbtest.Business(t, conn, owner, "Palmwine Express")
+	f := adjustmentFixture{
+		conn:     conn,
+		service:  NewInventory(db.New(conn), conn),
+		business: businessID,
+		store:    dbtest.Store(t, conn, branchID, "Bar"),
+		palmWine: dbtest.Variation(t, conn, businessID, "PW-1L", "1500.00"),
+	}
+	dbtest.Stock(t, conn, f.store, f.palmWine, 10)
+	return f
+}
+
+func (f adjustmentFixture) adjust(delta int32, reason string) (db.StockAdjustment, db.Inventory, error) {
+	return f.service.AdjustStock(context.Background(), AdjustStockParams{
+		StoreID:     f.store,
+		VariationID: f.palmWine,
+		Delta:       delta,
+		Reason:      reason,
+		AdjustedBy:  1,
+	})
+}
+
+func (f adjustmentFixture) stock(t *testing.T) int32 {
+	t.Helper()
+	var quantity int32
+	require.NoError(t, f.conn.QueryRow(`SELECT quantity FROM inventory WHERE store_id = $1 AND variation_id = $2`, f.store, f.palmWine).Scan(&quantity))
+	return quantity
+}
+
+func (f adjustmentFixture) adjustments(t *testing.T) int {
+	t.Helper()
+	var n int
+	require.NoError(t, f.conn.QueryRow(`SELECT COUNT(*) FROM stock_adjustment WHERE store_id = $1`, f.store).Scan(&n))
+	return n
+}
+
+func TestAdjustStockReasons(t *testing.T) {
+	tests := []struct {
+		reason string
+		delta  int32
+		want   int32
+	}{
+		{AdjustmentDamage, -2, 8},
+		{AdjustmentTheft, -10, 0},
+		{AdjustmentRecount, 3, 13},
+		{AdjustmentRecount, -4, 6},
+		{AdjustmentRestock, 24, 34},
+	}
+	for _, tt := range tests {
+		t.Run(tt.reason, func(t *testing.T) {
+			f := newAdjustmentFixture(t)
+
+			adjustment, stock, err := f.adjust(tt.delta, tt.reason)
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, stock.Quantity)
+			assert.Equal(t, tt.want, f.stock(t))
+
+			assert.Equal(t, tt.reason, adjustment.Reason)
+			assert.Equal(t, int32(10), adjustment.PreviousQuantity)
+			assert.Equal(t, tt.delta, adjustment.Quantity)
+			assert.Equal(t, int32(1), adjustment.AdjustedBy)
+			assert.Equal(t, 1, f.adjustments(t))
+		})
+	}
+}
+
+func TestAdjustStockReasonChecks(t *testing.T) {
+	tests := []struct {
+		reason string
+		delta  int32
+		err    error
+	}{
+		{AdjustmentDamage, 1, ErrAdjustmentDirection},
+		{AdjustmentTheft, 2, ErrAdjustmentDirection},
+		{AdjustmentRestock, -1, ErrAdjustmentDirection},
+		{"spillage", -1, ErrInvalidAdjustmentReason},
+		{"", 1, ErrInvalidAdjustmentReason},
+	}
+	for _, tt := range tests {
+		assert.ErrorIs(t, checkAdjustmentReason(tt.reason, tt.delta), tt.err, "%s %d", tt.reason, tt.delta)
+	}
+}
+
+func TestAdjustStockNegative(t *testing.T) {
+	f := newAdjustmentFixture(t)
+
+	_, _, err := f.adjust(-11, AdjustmentDamage)
+	assert.ErrorIs(t, err, ErrNegativeStock)
+	assert.Equal(t, int32(10), f.stock(t), "stock left alone")
+	assert.Zero(t, f.adjustments(t), "nothing recorded")
+
+	// down to zero is fine
+	_, stock, err := f.adjust(-10, AdjustmentRecount)
+	require.NoError(t, err)
+	assert.Zero(t, stock.Quantity)
+}
+
+func TestAdjustStockNegativeOverselling(t *testing.T) {
+	f := newAdjustmentFixture(t)
+	dbtest.Exec(t, f.conn, `UPDATE business SET allow_overselling = TRUE WHERE id = $1`, f.business)
+
+	_, stock, err := f.adjust(-12, AdjustmentTheft)
+	require.NoError(t, err)
+	assert.Equal(t, int32(-2), stock.Quantity)
+	assert.Equal(t, 1, f.adjustments(t))
+}
@@ -0,0 +1,79 @@
+package inventory
+
+import (
+	"context"
+	"fmt"
+	db "herp/db/sqlc"
+)
+
+// ReceiveBatch records a batch of a variation received into a store and adds
+// its quantity to the store's stock. Receiving more of a lot that is already
+// known tops it up and moves its expiry date to the one given.
+func (i *Inventory) ReceiveBatch(ctx context.Context, params db.ReceiveInventoryBatchParams) (db.InventoryBatch, db.Inventory, error) {
+	q, ok := i.queries.(*db.Queries)
+	if !ok {
+		return db.InventoryBatch{}, db.Inventory{}, fmt.Errorf("invalid query type in inventory")
+	}
+
+	tx, err := i.db.BeginTx(ctx, nil)
+	if err != nil {
+		return db.InventoryBatch{}, db.Inventory{}, err
+	}
+	defer tx.Rollback()
+
+	txQueries := q.WithTx(tx)
+
+	batch, err := txQueries.ReceiveInventoryBatch(ctx, params)
+	if err != nil {
+		return db.InventoryBatch{}, db.Inventory{}, err
+	}
+
+	stock, err := txQueries.IncrementInventoryQuantity(ctx, db.IncrementInventoryQuantityParams{
+		StoreID:     params.StoreID,
+		VariationID: params.VariationID,
+		Quantity:    params.Quantity,
+	})
+	if err != nil {
+		return db.InventoryBatch{}, db.Inventory{}, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return db.InventoryBatch{}, db.Inventory{}, err
+	}
+
+	return batch, stock, nil
+}
+
+// ListExpiringBatches lists batches with stock left that expire within
+// params.WithinDays days, counted in each business's timezone, soonest
+// first. Batches that have already expired are included.
+func (i *Inventory) ListExpiringBatches(ctx context.Context, params db.ListExpiringBatchesParams) ([]db.ListExpiringBatchesRow, error) {
+	return i.queries.ListExpiringBatches(ctx, params)
+}
+
+func (i *Inventory) GetStoreInBusiness(ctx context.Context, params db.GetStoreInBusinessParams) (int32, error) {
+	return i.queries.GetStoreInBusiness(ctx, params)
+}
+
+func (i *Inventory) GetVariationInBusiness(ctx context.Context, params db.GetVariationInBusinessParams) (int32, error) {
+	return i.queries.GetVariationInBusiness(ctx, params)
+}
+
+// Reasons recorded on batch movements, the reference id of a movement points
+// at the sale, transfer or reservation the stock went to.
+const (
+	MovementSale        = "sale"
+	MovementTransfer    = "transfer"
+	MovementReservation = "reservation"
+)
+
+// ConsumeBatchesTx takes quantity out of the batches of a variation in a
+// store, using queries bound to the caller's transaction, and records how
+// much was taken from each batch. Batches are depleted in the order set by
+// the depletion strategy of the item's category, or of the business when the
+// category has none: soonest expiry first for "fefo", first received first
+// for "fifo". Stock that was never received as a batch is not tracked, so
+// taking more than the batches hold simply empties them.
+func ConsumeBatchesTx(ctx context.Context, q *db.Queries, params db.ConsumeInventoryBatchesParams) ([]db.InventoryBatchMovement, error) {
+	return q.ConsumeInventoryBatches(ctx, params)
+}
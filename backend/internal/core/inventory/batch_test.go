@@ -0,0 +1,124 @@
+package inventory
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"herp/db/dbtest"
+	db "herp/db/sqlc"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// batch creates a batch of a variation in a store expiring in days days,
+// counted from today in timezone.
+func batch(t *testing.T, conn *sql.DB, storeID, variationID int32, lot, timezone string, days, quantity int32) int32 {
+	t.Helper()
+	return dbtest.Insert(t, conn, `
+		INSERT INTO inventory_batch (store_id, variation_id, lot_number, expiry_date, quantity)
+		VALUES ($1, $2, $3, (NOW() AT TIME ZONE $4::text)::date + $5::int, $6)
+		RETURNING id`, storeID, variationID, lot, timezone, days, quantity)
+}
+
+func TestListExpiringBatches(t *testing.T) {
+	conn := dbtest.Open(t)
+	q := db.New(conn)
+	ctx := context.Background()
+
+	// a day ahead of UTC for most of the day, so the window has to be
+	// counted in the business timezone
+	const timezone = "Pacific/Kiritimati"
+	owner := dbtest.Admin(t, conn, "owner")
+	businessID, branchID := dbtest.Business(t, conn, owner, "Palmwine Express")
+	dbtest.Exec(t, conn, `UPDATE business SET timezone = $1 WHERE id = $2`, timezone, businessID)
+	bar := dbtest.Store(t, conn, branchID, "Bar")
+	kitchen := dbtest.Store(t, conn, branchID, "Kitchen")
+	milk := dbtest.Variation(t, conn, businessID, "MLK-1L", "900.00")
+
+	expired := batch(t, conn, bar, milk, "L-0", timezone, -1, 2)
+	today := batch(t, conn, kitchen, milk, "L-1", timezone, 0, 3)
+	lastDay := batch(t, conn, bar, milk, "L-2", timezone, 7, 4)
+	batch(t, conn, bar, milk, "L-3", timezone, 8, 5)
+	// used up batches are not listed
+	batch(t, conn, bar, milk, "L-4", timezone, 1, 0)
+
+	otherID, otherBranchID := dbtest.Business(t, conn, dbtest.Admin(t, conn, "other"), "Other Hotel")
+	otherStore := dbtest.Store(t, conn, otherBranchID, "Other Bar")
+	batch(t, conn, otherStore, dbtest.Variation(t, conn, otherID, "OT-1", "100.00"), "X-1", "UTC", 1, 9)
+
+	list := func(params db.ListExpiringBatchesParams) ([]int32, []int32) {
+		t.Helper()
+		params.BusinessID = nullInt(businessID)
+		rows, err := q.ListExpiringBatches(ctx, params)
+		require.NoError(t, err)
+		ids, days := []int32{}, []int32{}
+		for _, row := range rows {
+			ids = append(ids, row.ID)
+			days = append(days, row.DaysLeft)
+		}
+		return ids, days
+	}
+
+	// inside the window, soonest first, the day after it is left out
+	ids, days := list(db.ListExpiringBatchesParams{WithinDays: 7})
+	assert.Equal(t, []int32{expired, today, lastDay}, ids)
+	assert.Equal(t, []int32{-1, 0, 7}, days)
+
+	ids, _ = list(db.ListExpiringBatchesParams{WithinDays: 0})
+	assert.Equal(t, []int32{expired, today}, ids)
+
+	ids, _ = list(db.ListExpiringBatchesParams{WithinDays: 7, StoreID: nullInt(bar)})
+	assert.Equal(t, []int32{expired, lastDay}, ids)
+
+	ids, _ = list(db.ListExpiringBatchesParams{WithinDays: 30, StoreID: nullInt(otherStore)})
+	assert.Empty(t, ids)
+}
+
+// expiringQuerier returns rows, and the params it was asked with.
+type expiringQuerier struct {
+	catalogQuerier
+	rows   []db.ListExpiringBatchesRow
+	params db.ListExpiringBatchesParams
+}
+
+func (q *expiringQuerier) ListExpiringBatches(ctx context.Context, params db.ListExpiringBatchesParams) ([]db.ListExpiringBatchesRow, error) {
+	q.params = params
+	return q.rows, nil
+}
+
+func TestListExpiringHandler(t *testing.T) {
+	q := &expiringQuerier{
+		catalogQuerier: *newCatalogQuerier(),
+		rows: []db.ListExpiringBatchesRow{
+			{ID: 4, StoreID: 3, StoreName: "Bar", VariationID: 7, Sku: "MLK-1L", LotNumber: "L-2", ExpiryDate: time.Date(2026, 3, 8, 0, 0, 0, 0, time.UTC), Quantity: 4, DaysLeft: 7},
+		},
+	}
+	h, r := newCatalogRouter(q, 1)
+	r.GET("/inventory/expiring", h.listExpiring)
+
+	w := serve(r, http.MethodGet, "/inventory/expiring?store_id=3", "")
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	// the window defaults to a week
+	assert.Equal(t, db.ListExpiringBatchesParams{WithinDays: 7, BusinessID: nullInt(10), StoreID: nullInt(3)}, q.params)
+
+	var body struct {
+		Data []ExpiringBatchResponse `json:"data"`
+	}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &body))
+	require.Len(t, body.Data, 1)
+	assert.Equal(t, "2026-03-08", body.Data[0].ExpiryDate)
+	assert.Equal(t, int32(7), body.Data[0].DaysLeft)
+
+	w = serve(r, http.MethodGet, "/inventory/expiring?within_days=30", "")
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	assert.Equal(t, int32(30), q.params.WithinDays)
+
+	for _, query := range []string{"?within_days=-1", "?within_days=366", "?within_days=soon", "?store_id=bar"} {
+		w := serve(r, http.MethodGet, "/inventory/expiring"+query, "")
+		assert.Equal(t, http.StatusBadRequest, w.Code, query)
+	}
+}
@@ -0,0 +1,301 @@
+package inventory
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	db "herp/db/sqlc"
+	"herp/internal/auth"
+	"herp/internal/config"
+	"herp/pkg/jwt"
+	"herp/pkg/monitoring/logging"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// catalogQuerier keeps a catalog in memory and scopes it by business the way
+// the queries do, a null business matches every row. It sits under a real
+// Inventory so the service rules run as well.
+type catalogQuerier struct {
+	Querier
+	owned      map[int32][]int32
+	brands     []db.Brand
+	categories []db.Category
+	items      []db.Item
+	variations []db.Variation
+	activities []db.LogActivityParams
+}
+
+func newCatalogQuerier() *catalogQuerier {
+	// owner 1 has business 10, owner 2 has business 20
+	return &catalogQuerier{owned: map[int32][]int32{1: {10}, 2: {20}}}
+}
+
+func inScope(businessID, scope sql.NullInt32) bool {
+	return !scope.Valid || businessID == scope
+}
+
+func nullInt(id int32) sql.NullInt32 {
+	return sql.NullInt32{Int32: id, Valid: true}
+}
+
+func (q *catalogQuerier) addBrand(businessID int32, name string) db.Brand {
+	brand := db.Brand{ID: int32(len(q.brands) + 1), Name: name, BusinessID: nullInt(businessID)}
+	q.brands = append(q.brands, brand)
+	return brand
+}
+
+func (q *catalogQuerier) addCategory(businessID int32, name string, parentID int32) db.Category {
+	category := db.Category{ID: int32(len(q.categories) + 1), Name: name, BusinessID: nullInt(businessID)}
+	if parentID != 0 {
+		category.ParentID = sql.NullInt32{Int32: parentID, Valid: true}
+	}
+	q.categories = append(q.categories, category)
+	return category
+}
+
+func (q *catalogQuerier) addItem(businessID int32, name string, categoryID int32) db.Item {
+	item := db.Item{ID: int32(len(q.items) + 1), Name: name, CategoryID: categoryID, ItemType: "for_sale", BusinessID: nullInt(businessID), Version: 1}
+	q.items = append(q.items, item)
+	return item
+}
+
+func (q *catalogQuerier) GetOwnedBusinessID(ctx context.Context, params db.GetOwnedBusinessIDParams) (int32, error) {
+	for _, id := range q.owned[params.OwnerID] {
+		if !params.BusinessID.Valid || params.BusinessID.Int32 == id {
+			return id, nil
+		}
+	}
+	return 0, sql.ErrNoRows
+}
+
+func (q *catalogQuerier) ListBrands(ctx context.Context, businessID sql.NullInt32) ([]db.Brand, error) {
+	var brands []db.Brand
+	for _, brand := range q.brands {
+		if inScope(brand.BusinessID, businessID) {
+			brands = append(brands, brand)
+		}
+	}
+	return brands, nil
+}
+
+func (q *catalogQuerier) GetBrand(ctx context.Context, params db.GetBrandParams) (db.Brand, error) {
+	for _, brand := range q.brands {
+		if brand.ID == params.ID && inScope(brand.BusinessID, params.BusinessID) {
+			return brand, nil
+		}
+	}
+	return db.Brand{}, sql.ErrNoRows
+}
+
+func (q *catalogQuerier) ListCategories(ctx context.Context, businessID sql.NullInt32) ([]db.Category, error) {
+	var categories []db.Category
+	for _, category := range q.categories {
+		if inScope(category.BusinessID, businessID) {
+			categories = append(categories, category)
+		}
+	}
+	return categories, nil
+}
+
+func (q *catalogQuerier) GetCategory(ctx context.Context, params db.GetCategoryParams) (db.Category, error) {
+	for _, category := range q.categories {
+		if category.ID == params.ID && inScope(category.BusinessID, params.BusinessID) {
+			return category, nil
+		}
+	}
+	return db.Category{}, sql.ErrNoRows
+}
+
+func (q *catalogQuerier) CreateCategory(ctx context.Context, params db.CreateCategoryParams) (db.Category, error) {
+	category := q.addCategory(params.BusinessID.Int32, params.Name, params.ParentID.Int32)
+	return category, nil
+}
+
+func (q *catalogQuerier) GetItem(ctx context.Context, params db.GetItemParams) (db.Item, error) {
+	for _, item := range q.items {
+		if item.ID == params.ID && inScope(item.BusinessID, params.BusinessID) {
+			return item, nil
+		}
+	}
+	return db.Item{}, sql.ErrNoRows
+}
+
+func (q *catalogQuerier) UpdateItem(ctx context.Context, params db.UpdateItemParams) (db.Item, error) {
+	for n := range q.items {
+		item := &q.items[n]
+		if item.ID != params.ID || !inScope(item.BusinessID, params.BusinessID) {
+			continue
+		}
+		if params.ExpectedVersion.Valid && item.Version != params.ExpectedVersion.Int32 {
+			break
+		}
+		if params.Name.Valid {
+			item.Name = params.Name.String
+		}
+		if params.CategoryID.Valid {
+			item.CategoryID = params.CategoryID.Int32
+		}
+		if params.BrandID.Valid {
+			item.BrandID = params.BrandID
+		}
+		item.Version++
+		return *item, nil
+	}
+	return db.Item{}, sql.ErrNoRows
+}
+
+func (q *catalogQuerier) CountActiveVariationsByItem(ctx context.Context, itemID int32) (int64, error) {
+	var count int64
+	for _, variation := range q.variations {
+		if variation.ItemID == itemID && variation.IsActive.Bool {
+			count++
+		}
+	}
+	return count, nil
+}
+
+func (q *catalogQuerier) DeleteItem(ctx context.Context, params db.DeleteItemParams) error {
+	for n, item := range q.items {
+		if item.ID == params.ID && inScope(item.BusinessID, params.BusinessID) {
+			q.items = append(q.items[:n], q.items[n+1:]...)
+			return nil
+		}
+	}
+	return nil
+}
+
+func (q *catalogQuerier) LogActivity(ctx context.Context, params db.LogActivityParams) (db.ActivityLog, error) {
+	q.activities = append(q.activities, params)
+	return db.ActivityLog{}, nil
+}
+
+// newCatalogRouter serves the inventory routes a test registers to the admin
+// userID, with business scoping on.
+func newCatalogRouter(q Querier, userID int) (*Handler, *gin.Engine) {
+	gin.SetMode(gin.TestMode)
+	cfg := &config.Config{BusinessScope: true}
+	h := NewInventoryHandler(NewInventory(q, nil), cfg, logging.NewLogger(cfg), nil)
+
+	r := gin.New()
+	r.Use(func(c *gin.Context) {
+		c.Set("claims", &jwt.Claims{UserID: userID, Username: "owner", Email: "owner@example.com"})
+	})
+	return h, r
+}
+
+func serve(r *gin.Engine, method, path, body string) *httptest.ResponseRecorder {
+	var req *http.Request
+	if body == "" {
+		req = httptest.NewRequest(method, path, nil)
+	} else {
+		req = httptest.NewRequest(method, path, strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+	}
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	return w
+}
+
+func TestCatalogIsScopedToTheBusiness(t *testing.T) {
+	q := newCatalogQuerier()
+	q.addBrand(10, "Ours")
+	q.addBrand(20, "Theirs")
+	drinks := q.addCategory(10, "Drinks", 0)
+	q.addCategory(20, "Their drinks", 0)
+	h, r := newCatalogRouter(q, 1)
+	r.GET("/inventory/brand", h.listBrands)
+	r.GET("/inventory/category", h.listCategories)
+
+	w := serve(r, http.MethodGet, "/inventory/brand", "")
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	var brands struct {
+		Data []CreateBrandResponse `json:"data"`
+	}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &brands))
+	require.Len(t, brands.Data, 1)
+	assert.Equal(t, "Ours", brands.Data[0].Name)
+
+	w = serve(r, http.MethodGet, "/inventory/category", "")
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	var categories struct {
+		Data []CategoryResponse `json:"data"`
+	}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &categories))
+	require.Len(t, categories.Data, 1)
+	assert.Equal(t, drinks.ID, categories.Data[0].ID)
+}
+
+func TestCatalogCreatesInTheBusiness(t *testing.T) {
+	q := newCatalogQuerier()
+	theirs := q.addCategory(20, "Their drinks", 0)
+	h, r := newCatalogRouter(q, 1)
+	r.POST("/inventory/category", h.createCategory)
+
+	w := serve(r, http.MethodPost, "/inventory/category", `{"name":"Drinks"}`)
+	require.Equal(t, http.StatusCreated, w.Code, w.Body.String())
+	created := q.categories[len(q.categories)-1]
+	assert.Equal(t, nullInt(10), created.BusinessID)
+
+	// another business's category can't be a parent
+	w = serve(r, http.MethodPost, "/inventory/category", `{"name":"Wine","parent_id":`+itoa(theirs.ID)+`}`)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+}
+
+func TestCatalogOnlyMutatesTheBusiness(t *testing.T) {
+	q := newCatalogQuerier()
+	ours := q.addItem(10, "Palm wine", q.addCategory(10, "Drinks", 0).ID)
+	theirs := q.addItem(20, "Zobo", q.addCategory(20, "Their drinks", 0).ID)
+	h, r := newCatalogRouter(q, 1)
+	r.PATCH("/inventory/item/:id", h.updateItem)
+	r.DELETE("/inventory/item/:id", h.deleteItem)
+
+	w := serve(r, http.MethodPatch, "/inventory/item/"+itoa(theirs.ID), `{"name":"Mine now"}`)
+	assert.Equal(t, http.StatusNotFound, w.Code)
+	w = serve(r, http.MethodDelete, "/inventory/item/"+itoa(theirs.ID), "")
+	assert.Equal(t, http.StatusNotFound, w.Code)
+
+	got, err := q.GetItem(context.Background(), db.GetItemParams{ID: theirs.ID})
+	require.NoError(t, err)
+	assert.Equal(t, "Zobo", got.Name)
+
+	w = serve(r, http.MethodPatch, "/inventory/item/"+itoa(ours.ID), `{"name":"Fresh palm wine"}`)
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	w = serve(r, http.MethodDelete, "/inventory/item/"+itoa(ours.ID), "")
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	_, err = q.GetItem(context.Background(), db.GetItemParams{ID: ours.ID})
+	assert.ErrorIs(t, err, sql.ErrNoRows)
+}
+
+func TestCatalogStaffUseTheirBusiness(t *testing.T) {
+	q := newCatalogQuerier()
+	q.addBrand(10, "Ours")
+	q.addBrand(20, "Theirs")
+	h, r := newCatalogRouter(q, 1)
+	// user 1 is a cashier of business 20, not the owner of business 10
+	r.GET("/inventory/brand", withStoreScope(20), h.listBrands)
+
+	w := serve(r, http.MethodGet, "/inventory/brand", "")
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	assert.Contains(t, w.Body.String(), "Theirs")
+	assert.NotContains(t, w.Body.String(), "Ours")
+}
+
+// withStoreScope marks the request as made by a staff user assigned to a
+// store of businessID.
+func withStoreScope(businessID int32) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		auth.SetStoreScope(c, auth.StoreScope{Restricted: true, StoreIDs: []int32{5}, User: true, BusinessID: nullInt(businessID)})
+	}
+}
+
+func itoa(id int32) string {
+	return strconv.Itoa(int(id))
+}
@@ -0,0 +1,159 @@
+package inventory
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	db "herp/db/sqlc"
+	"slices"
+)
+
+var (
+	ErrCategoryCycle          = errors.New("category hierarchy has a cycle")
+	ErrParentCategoryNotFound = errors.New("parent category not found")
+	ErrCategoryParentCycle    = errors.New("parent would create a cycle")
+)
+
+// CategoryNode is a category with its subcategories. ItemCount counts the
+// items filed directly under the category, TotalItemCount includes the items
+// of every subcategory.
+type CategoryNode struct {
+	Category       db.Category
+	ItemCount      int64
+	TotalItemCount int64
+	Children       []CategoryNode
+}
+
+// CategoryTree returns the categories of a business nested under their
+// parents. Categories whose parent is not visible in the scope are roots.
+func (i *Inventory) CategoryTree(ctx context.Context, businessID sql.NullInt32) ([]CategoryNode, error) {
+	categories, counts, err := i.categoriesWithCounts(ctx, businessID)
+	if err != nil {
+		return nil, err
+	}
+	return BuildCategoryTree(categories, counts)
+}
+
+// CategoryChildren returns the subcategories of a category, each with its own
+// subcategories.
+func (i *Inventory) CategoryChildren(ctx context.Context, params db.GetCategoryParams) ([]CategoryNode, error) {
+	if _, err := i.queries.GetCategory(ctx, params); err != nil {
+		return nil, err
+	}
+	tree, err := i.CategoryTree(ctx, params.BusinessID)
+	if err != nil {
+		return nil, err
+	}
+	if node := findCategoryNode(tree, params.ID); node != nil {
+		return node.Children, nil
+	}
+	return []CategoryNode{}, nil
+}
+
+// UpdateCategory updates a category. A new parent has to exist in the same
+// scope and must not be the category itself or one of its descendants,
+// otherwise the category would become its own ancestor.
+func (i *Inventory) UpdateCategory(ctx context.Context, params db.UpdateCategoryParams) (db.Category, error) {
+	if params.ParentID.Valid {
+		if _, err := i.queries.GetCategory(ctx, db.GetCategoryParams{ID: params.ParentID.Int32, BusinessID: params.BusinessID}); err != nil {
+			if errors.Is(err, sql.ErrNoRows) {
+				return db.Category{}, ErrParentCategoryNotFound
+			}
+			return db.Category{}, err
+		}
+		// the category is an ancestor of the new parent when the parent
+		// sits somewhere below it
+		ancestors, err := i.queries.GetCategoryAncestors(ctx, params.ParentID.Int32)
+		if err != nil {
+			return db.Category{}, err
+		}
+		if slices.Contains(ancestors, params.ID) {
+			return db.Category{}, ErrCategoryParentCycle
+		}
+	}
+	return i.queries.UpdateCategory(ctx, params)
+}
+
+func (i *Inventory) categoriesWithCounts(ctx context.Context, businessID sql.NullInt32) ([]db.Category, map[int32]int64, error) {
+	categories, err := i.queries.ListCategories(ctx, businessID)
+	if err != nil {
+		return nil, nil, err
+	}
+	rows, err := i.queries.CountItemsByCategory(ctx, businessID)
+	if err != nil {
+		return nil, nil, err
+	}
+	counts := make(map[int32]int64, len(rows))
+	for _, row := range rows {
+		counts[row.CategoryID] = row.ItemCount
+	}
+	return categories, counts, nil
+}
+
+// BuildCategoryTree nests a flat list of categories under their parents,
+// keeping the order of the list among siblings. A category whose ancestor
+// chain loops back on itself can never be placed and fails the build with
+// ErrCategoryCycle.
+func BuildCategoryTree(categories []db.Category, itemCounts map[int32]int64) ([]CategoryNode, error) {
+	byID := make(map[int32]db.Category, len(categories))
+	for _, category := range categories {
+		byID[category.ID] = category
+	}
+
+	children := make(map[int32][]db.Category)
+	var roots []db.Category
+	for _, category := range categories {
+		if category.ParentID.Valid {
+			if _, ok := byID[category.ParentID.Int32]; ok {
+				children[category.ParentID.Int32] = append(children[category.ParentID.Int32], category)
+				continue
+			}
+		}
+		roots = append(roots, category)
+	}
+
+	placed := make(map[int32]bool, len(categories))
+	var build func(category db.Category) CategoryNode
+	build = func(category db.Category) CategoryNode {
+		placed[category.ID] = true
+		node := CategoryNode{
+			Category:       category,
+			ItemCount:      itemCounts[category.ID],
+			TotalItemCount: itemCounts[category.ID],
+			Children:       []CategoryNode{},
+		}
+		for _, child := range children[category.ID] {
+			childNode := build(child)
+			node.TotalItemCount += childNode.TotalItemCount
+			node.Children = append(node.Children, childNode)
+		}
+		return node
+	}
+
+	tree := make([]CategoryNode, 0, len(roots))
+	for _, root := range roots {
+		tree = append(tree, build(root))
+	}
+
+	// every category reachable from a root has been placed, the rest hang
+	// off a loop
+	for _, category := range categories {
+		if !placed[category.ID] {
+			return nil, fmt.Errorf("%w: category %d", ErrCategoryCycle, category.ID)
+		}
+	}
+	return tree, nil
+}
+
+func findCategoryNode(nodes []CategoryNode, id int32) *CategoryNode {
+	for i := range nodes {
+		if nodes[i].Category.ID == id {
+			return &nodes[i]
+		}
+		if node := findCategoryNode(nodes[i].Children, id); node != nil {
+			return node
+		}
+	}
+	return nil
+}
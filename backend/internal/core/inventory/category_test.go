@@ -0,0 +1,279 @@
+package inventory
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	db "herp/db/sqlc"
+	"net/http"
+	"slices"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func (q *catalogQuerier) CountItemsByCategory(ctx context.Context, businessID sql.NullInt32) ([]db.CountItemsByCategoryRow, error) {
+	counts := map[int32]int64{}
+	var order []int32
+	for _, item := range q.items {
+		if !inScope(item.BusinessID, businessID) {
+			continue
+		}
+		if counts[item.CategoryID] == 0 {
+			order = append(order, item.CategoryID)
+		}
+		counts[item.CategoryID]++
+	}
+	rows := make([]db.CountItemsByCategoryRow, 0, len(order))
+	for _, id := range order {
+		rows = append(rows, db.CountItemsByCategoryRow{CategoryID: id, ItemCount: counts[id]})
+	}
+	return rows, nil
+}
+
+// setParent points a category at a new parent without any checks, the way a
+// bad write straight to the database would.
+func (q *catalogQuerier) setParent(id, parentID int32) {
+	for i := range q.categories {
+		if q.categories[i].ID == id {
+			q.categories[i].ParentID = nullInt(parentID)
+		}
+	}
+}
+
+// categoryCatalog is business 10 with
+//
+//	Drinks (1 item)
+//	  Alcoholic
+//	    Palm wine (2 items)
+//	  Soft drinks (1 item)
+//	Food (1 item)
+//
+// and a category of business 20.
+type categoryCatalog struct {
+	q                                             *catalogQuerier
+	drinks, alcoholic, palmWine, softDrinks, food db.Category
+	other                                         db.Category
+}
+
+func newCategoryCatalog() categoryCatalog {
+	q := newCatalogQuerier()
+	c := categoryCatalog{q: q}
+	c.drinks = q.addCategory(10, "Drinks", 0)
+	c.alcoholic = q.addCategory(10, "Alcoholic", c.drinks.ID)
+	c.palmWine = q.addCategory(10, "Palm wine", c.alcoholic.ID)
+	c.softDrinks = q.addCategory(10, "Soft drinks", c.drinks.ID)
+	c.food = q.addCategory(10, "Food", 0)
+	c.other = q.addCategory(20, "Someone else's", 0)
+	q.addItem(10, "Water", c.drinks.ID)
+	q.addItem(10, "Palm wine 1L", c.palmWine.ID)
+	q.addItem(10, "Palm wine 50cl", c.palmWine.ID)
+	q.addItem(10, "Zobo", c.softDrinks.ID)
+	q.addItem(10, "Suya", c.food.ID)
+	q.addItem(20, "Elsewhere", c.other.ID)
+	return c
+}
+
+// treeNode is a category node reduced to what the tests compare.
+type treeNode struct {
+	Name     string
+	Items    int64
+	Total    int64
+	Children []treeNode
+}
+
+func summarize(nodes []CategoryNode) []treeNode {
+	summary := []treeNode{}
+	for _, node := range nodes {
+		summary = append(summary, treeNode{node.Category.Name, node.ItemCount, node.TotalItemCount, summarize(node.Children)})
+	}
+	return summary
+}
+
+func summarizeResponse(nodes []CategoryTreeResponse) []treeNode {
+	summary := []treeNode{}
+	for _, node := range nodes {
+		summary = append(summary, treeNode{node.Name, node.ItemCount, node.TotalItemCount, summarizeResponse(node.Children)})
+	}
+	return summary
+}
+
+var wantCategoryTree = []treeNode{
+	{"Drinks", 1, 4, []treeNode{
+		{"Alcoholic", 0, 2, []treeNode{
+			{"Palm wine", 2, 2, []treeNode{}},
+		}},
+		{"Soft drinks", 1, 1, []treeNode{}},
+	}},
+	{"Food", 1, 1, []treeNode{}},
+}
+
+func TestBuildCategoryTree(t *testing.T) {
+	c := newCategoryCatalog()
+
+	tree, err := NewInventory(c.q, nil).CategoryTree(context.Background(), nullInt(10))
+	require.NoError(t, err)
+	assert.Equal(t, wantCategoryTree, summarize(tree))
+}
+
+func TestBuildCategoryTreeCycle(t *testing.T) {
+	c := newCategoryCatalog()
+	// Drinks now sits under Palm wine, its own grandchild
+	c.q.setParent(c.drinks.ID, c.palmWine.ID)
+
+	_, err := NewInventory(c.q, nil).CategoryTree(context.Background(), nullInt(10))
+	assert.ErrorIs(t, err, ErrCategoryCycle)
+
+	// a category that is its own parent
+	_, err = BuildCategoryTree([]db.Category{
+		{ID: 1, Name: "Loop", ParentID: nullInt(1)},
+		{ID: 2, Name: "Fine"},
+	}, nil)
+	assert.ErrorIs(t, err, ErrCategoryCycle)
+}
+
+func TestGetCategoryTree(t *testing.T) {
+	c := newCategoryCatalog()
+	h, r := newCatalogRouter(c.q, 1)
+	r.GET("/inventory/category/tree", h.getCategoryTree)
+	r.GET("/inventory/category/:id/children", h.listCategoryChildren)
+
+	w := serve(r, http.MethodGet, "/inventory/category/tree", "")
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	var body struct {
+		Data []CategoryTreeResponse `json:"data"`
+	}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &body))
+	assert.Equal(t, wantCategoryTree, summarizeResponse(body.Data))
+
+	w = serve(r, http.MethodGet, "/inventory/category/"+itoa(c.drinks.ID)+"/children", "")
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &body))
+	assert.Equal(t, wantCategoryTree[0].Children, summarizeResponse(body.Data))
+
+	w = serve(r, http.MethodGet, "/inventory/category/"+itoa(c.palmWine.ID)+"/children", "")
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	assert.Contains(t, w.Body.String(), `"data":[]`)
+
+	// another business's category isn't found
+	w = serve(r, http.MethodGet, "/inventory/category/"+itoa(c.other.ID)+"/children", "")
+	assert.Equal(t, http.StatusNotFound, w.Code)
+
+	c.q.setParent(c.drinks.ID, c.palmWine.ID)
+	w = serve(r, http.MethodGet, "/inventory/category/tree", "")
+	assert.Equal(t, http.StatusConflict, w.Code)
+	w = serve(r, http.MethodGet, "/inventory/category/"+itoa(c.food.ID)+"/children", "")
+	assert.Equal(t, http.StatusConflict, w.Code)
+}
+
+// GetCategoryAncestors walks up the parent chain like the recursive query,
+// stopping once it comes back to a category it has seen.
+func (q *catalogQuerier) GetCategoryAncestors(ctx context.Context, id int32) ([]int32, error) {
+	var ancestors []int32
+	for id != 0 && !slices.Contains(ancestors, id) {
+		ancestors = append(ancestors, id)
+		next := int32(0)
+		for _, category := range q.categories {
+			if category.ID == id {
+				next = category.ParentID.Int32
+			}
+		}
+		id = next
+	}
+	return ancestors, nil
+}
+
+func (q *catalogQuerier) UpdateCategory(ctx context.Context, params db.UpdateCategoryParams) (db.Category, error) {
+	for i, category := range q.categories {
+		if category.ID == params.ID && inScope(category.BusinessID, params.BusinessID) {
+			q.categories[i].Name = params.Name
+			q.categories[i].ParentID = params.ParentID
+			q.categories[i].Description = params.Description
+			q.categories[i].IsActive = params.IsActive
+			return q.categories[i], nil
+		}
+	}
+	return db.Category{}, sql.ErrNoRows
+}
+
+func TestUpdateCategoryParent(t *testing.T) {
+	tests := []struct {
+		name     string
+		category func(c categoryCatalog) db.Category
+		parent   func(c categoryCatalog) db.Category
+		code     int
+	}{
+		{
+			name:     "self parent",
+			category: func(c categoryCatalog) db.Category { return c.alcoholic },
+			parent:   func(c categoryCatalog) db.Category { return c.alcoholic },
+			code:     http.StatusBadRequest,
+		},
+		{
+			name:     "child as parent",
+			category: func(c categoryCatalog) db.Category { return c.drinks },
+			parent:   func(c categoryCatalog) db.Category { return c.softDrinks },
+			code:     http.StatusBadRequest,
+		},
+		{
+			name:     "grandchild as parent",
+			category: func(c categoryCatalog) db.Category { return c.drinks },
+			parent:   func(c categoryCatalog) db.Category { return c.palmWine },
+			code:     http.StatusBadRequest,
+		},
+		{
+			name:     "another business's parent",
+			category: func(c categoryCatalog) db.Category { return c.food },
+			parent:   func(c categoryCatalog) db.Category { return c.other },
+			code:     http.StatusBadRequest,
+		},
+		{
+			name:     "sibling branch",
+			category: func(c categoryCatalog) db.Category { return c.alcoholic },
+			parent:   func(c categoryCatalog) db.Category { return c.food },
+			code:     http.StatusOK,
+		},
+		{
+			name:     "deeper in another branch",
+			category: func(c categoryCatalog) db.Category { return c.food },
+			parent:   func(c categoryCatalog) db.Category { return c.palmWine },
+			code:     http.StatusOK,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := newCategoryCatalog()
+			h, r := newCatalogRouter(c.q, 1)
+			r.PUT("/inventory/category/:id", h.updateCategory)
+			category, parent := tt.category(c), tt.parent(c)
+
+			w := serve(r, http.MethodPut, "/inventory/category/"+itoa(category.ID),
+				`{"name":"`+category.Name+`","parent_id":`+itoa(parent.ID)+`}`)
+			require.Equal(t, tt.code, w.Code, w.Body.String())
+
+			tree, err := NewInventory(c.q, nil).CategoryTree(context.Background(), nullInt(10))
+			require.NoError(t, err, "the tree is still whole")
+			if tt.code != http.StatusOK {
+				assert.Equal(t, wantCategoryTree, summarize(tree), "nothing moved")
+				return
+			}
+			assert.Equal(t, parent.ID, findCategoryNode(tree, category.ID).Category.ParentID.Int32)
+		})
+	}
+}
+
+func TestUpdateCategoryCycleMessage(t *testing.T) {
+	c := newCategoryCatalog()
+	h, r := newCatalogRouter(c.q, 1)
+	r.PUT("/inventory/category/:id", h.updateCategory)
+
+	w := serve(r, http.MethodPut, "/inventory/category/"+itoa(c.drinks.ID), `{"name":"Drinks","parent_id":`+itoa(c.palmWine.ID)+`}`)
+	require.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Contains(t, w.Body.String(), "would create a cycle")
+
+	_, err := NewInventory(c.q, nil).UpdateCategory(context.Background(), db.UpdateCategoryParams{
+		ID: c.palmWine.ID, Name: "Palm wine", ParentID: nullInt(c.palmWine.ID), BusinessID: nullInt(10),
+	})
+	assert.ErrorIs(t, err, ErrCategoryParentCycle)
+}
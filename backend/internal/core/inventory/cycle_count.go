@@ -0,0 +1,135 @@
+package inventory
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	db "herp/db/sqlc"
+	"sort"
+)
+
+// MovementCycleCount is the reason recorded on adjustments and batch
+// movements that reconcile stock with a cycle count.
+const MovementCycleCount = "cycle_count"
+
+var ErrDuplicateCountItem = errors.New("item appears more than once in count")
+
+type CycleCountLine struct {
+	VariationID     int32
+	CountedQuantity int32
+}
+
+type CycleCountParams struct {
+	StoreID   int32
+	CountedBy int32
+	Lines     []CycleCountLine
+	// Confirm writes adjustments for the variances, otherwise the count is
+	// only compared
+	Confirm bool
+}
+
+type CycleCountResult struct {
+	VariationID     int32
+	SystemQuantity  int32
+	CountedQuantity int32
+	Variance        int32 // counted minus system
+	// set when a confirmed count adjusted the stock
+	Adjustment *db.StockAdjustment
+}
+
+// CycleCount compares counted quantities with the stock on record in a store.
+// When confirmed, stock with a variance is set to what was counted and each
+// correction is recorded as a cycle_count adjustment, all in one transaction.
+// Stock written off is also taken out of the variation's batches.
+func (i *Inventory) CycleCount(ctx context.Context, args CycleCountParams) ([]CycleCountResult, error) {
+	q, ok := i.queries.(*db.Queries)
+	if !ok {
+		return nil, fmt.Errorf("invalid query type in inventory")
+	}
+
+	lines := make([]CycleCountLine, len(args.Lines))
+	copy(lines, args.Lines)
+	// lock inventory rows in a stable order, like transfers do
+	sort.Slice(lines, func(i, j int) bool { return lines[i].VariationID < lines[j].VariationID })
+	for i := 1; i < len(lines); i++ {
+		if lines[i].VariationID == lines[i-1].VariationID {
+			return nil, ErrDuplicateCountItem
+		}
+	}
+
+	tx, err := i.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	txQueries := q.WithTx(tx)
+
+	results := make([]CycleCountResult, 0, len(lines))
+	for _, line := range lines {
+		var system int32
+		stock, err := txQueries.GetInventoryItemForUpdate(ctx, db.GetInventoryItemForUpdateParams{
+			StoreID:     args.StoreID,
+			VariationID: line.VariationID,
+		})
+		if err != nil && !errors.Is(err, sql.ErrNoRows) {
+			return nil, err
+		}
+		if err == nil {
+			system = stock.Quantity
+		}
+
+		result := CycleCountResult{
+			VariationID:     line.VariationID,
+			SystemQuantity:  system,
+			CountedQuantity: line.CountedQuantity,
+			Variance:        line.CountedQuantity - system,
+		}
+
+		if args.Confirm && result.Variance != 0 {
+			if _, err := txQueries.IncrementInventoryQuantity(ctx, db.IncrementInventoryQuantityParams{
+				StoreID:     args.StoreID,
+				VariationID: line.VariationID,
+				Quantity:    result.Variance,
+			}); err != nil {
+				return nil, err
+			}
+
+			adjustment, err := txQueries.CreateStockAdjustment(ctx, db.CreateStockAdjustmentParams{
+				StoreID:          args.StoreID,
+				VariationID:      line.VariationID,
+				PreviousQuantity: system,
+				Quantity:         result.Variance,
+				Reason:           MovementCycleCount,
+				AdjustedBy:       args.CountedBy,
+			})
+			if err != nil {
+				return nil, err
+			}
+			result.Adjustment = &adjustment
+
+			if result.Variance < 0 {
+				if _, err := ConsumeBatchesTx(ctx, txQueries, db.ConsumeInventoryBatchesParams{
+					StoreID:     args.StoreID,
+					VariationID: line.VariationID,
+					Quantity:    -result.Variance,
+					Reason:      MovementCycleCount,
+					ReferenceID: sql.NullInt32{Int32: adjustment.ID, Valid: true},
+				}); err != nil {
+					return nil, err
+				}
+			}
+		}
+
+		results = append(results, result)
+	}
+
+	if args.Confirm {
+		if err := tx.Commit(); err != nil {
+			return nil, err
+		}
+	}
+
+	return results, nil
+}
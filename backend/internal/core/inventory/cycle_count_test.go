@@ -0,0 +1,166 @@
+package inventory
+
+import (
+	"context"
+	"database/sql"
+	"herp/db/dbtest"
+	db "herp/db/sqlc"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type cycleCountFixture struct {
+	conn                               *sql.DB
+	service                            *Inventory
+	store                              int32
+	palmWine, zobo, chapman, unstocked int32
+}
+
+func newCycleCountFixture(t *testing.T) cycleCountFixture {
+	t.Helper()
+	conn := dbtest.Open(t)
+	owner := dbtest.Admin(t, conn, "owner")
+	businessID, branchID := dbtest.Business(t, conn, owner, "Palmwine Express")
+	f := cycleCountFixture{
+		conn:     conn,
+		service:  NewInventory(db.New(conn), conn),
+		store:    dbtest.Store(t, conn, branchID, "Bar"),
+		palmWine: dbtest.Variation(t, conn, businessID, "PW-1L", "1500.00"),
+		zobo:     dbtest.Variation(t, conn, businessID, "ZB-50CL", "500.00"),
+		chapman:  dbtest.Variation(t, conn, businessID, "CH-50CL", "800.00"),
+		// never stocked in the store
+		unstocked: dbtest.Variation(t, conn, businessID, "NW-1", "100.00"),
+	}
+	dbtest.Stock(t, conn, f.store, f.palmWine, 10)
+	dbtest.Stock(t, conn, f.store, f.zobo, 5)
+	dbtest.Stock(t, conn, f.store, f.chapman, 7)
+	return f
+}
+
+func (f cycleCountFixture) count(t *testing.T, confirm bool) []CycleCountResult {
+	t.Helper()
+	results, err := f.service.CycleCount(context.Background(), CycleCountParams{
+		StoreID:   f.store,
+		CountedBy: 1,
+		Confirm:   confirm,
+		Lines: []CycleCountLine{
+			{VariationID: f.chapman, CountedQuantity: 7},
+			{VariationID: f.palmWine, CountedQuantity: 8},
+			{VariationID: f.zobo, CountedQuantity: 6},
+			{VariationID: f.unstocked, CountedQuantity: 2},
+		},
+	})
+	require.NoError(t, err)
+	return results
+}
+
+func (f cycleCountFixture) stock(t *testing.T, variationID int32) int32 {
+	t.Helper()
+	var quantity int32
+	err := f.conn.QueryRow(`SELECT quantity FROM inventory WHERE store_id = $1 AND variation_id = $2`, f.store, variationID).Scan(&quantity)
+	if err == sql.ErrNoRows {
+		return 0
+	}
+	require.NoError(t, err)
+	return quantity
+}
+
+type variance struct {
+	variation, system, counted, variance int32
+}
+
+func variances(results []CycleCountResult) []variance {
+	got := make([]variance, 0, len(results))
+	for _, r := range results {
+		got = append(got, variance{r.VariationID, r.SystemQuantity, r.CountedQuantity, r.Variance})
+	}
+	return got
+}
+
+func TestCycleCountDryRun(t *testing.T) {
+	f := newCycleCountFixture(t)
+
+	results := f.count(t, false)
+	assert.Equal(t, []variance{
+		{f.palmWine, 10, 8, -2},
+		{f.zobo, 5, 6, 1},
+		{f.chapman, 7, 7, 0},
+		{f.unstocked, 0, 2, 2},
+	}, variances(results))
+	for _, r := range results {
+		assert.Nil(t, r.Adjustment)
+	}
+
+	// nothing is written
+	assert.Equal(t, int32(10), f.stock(t, f.palmWine))
+	assert.Equal(t, int32(5), f.stock(t, f.zobo))
+	assert.Equal(t, int32(0), f.stock(t, f.unstocked))
+	var adjustments int
+	require.NoError(t, f.conn.QueryRow(`SELECT count(*) FROM stock_adjustment`).Scan(&adjustments))
+	assert.Zero(t, adjustments)
+}
+
+func TestCycleCountConfirmed(t *testing.T) {
+	f := newCycleCountFixture(t)
+	lot := batch(t, f.conn, f.store, f.palmWine, "L-1", "UTC", 30, 10)
+
+	results := f.count(t, true)
+	assert.Equal(t, []variance{
+		{f.palmWine, 10, 8, -2},
+		{f.zobo, 5, 6, 1},
+		{f.chapman, 7, 7, 0},
+		{f.unstocked, 0, 2, 2},
+	}, variances(results))
+
+	// stock is what was counted
+	assert.Equal(t, int32(8), f.stock(t, f.palmWine))
+	assert.Equal(t, int32(6), f.stock(t, f.zobo))
+	assert.Equal(t, int32(7), f.stock(t, f.chapman))
+	assert.Equal(t, int32(2), f.stock(t, f.unstocked))
+
+	// one adjustment per variance, none for the matching count
+	for _, r := range results {
+		if r.Variance == 0 {
+			assert.Nil(t, r.Adjustment)
+			continue
+		}
+		require.NotNil(t, r.Adjustment, "variation %d", r.VariationID)
+		assert.Equal(t, MovementCycleCount, r.Adjustment.Reason)
+		assert.Equal(t, r.Variance, r.Adjustment.Quantity)
+		assert.Equal(t, r.SystemQuantity, r.Adjustment.PreviousQuantity)
+	}
+	var adjustments int
+	require.NoError(t, f.conn.QueryRow(`SELECT count(*) FROM stock_adjustment WHERE reason = 'cycle_count'`).Scan(&adjustments))
+	assert.Equal(t, 3, adjustments)
+
+	// what was written off is taken out of the batches
+	var left int32
+	require.NoError(t, f.conn.QueryRow(`SELECT quantity FROM inventory_batch WHERE id = $1`, lot).Scan(&left))
+	assert.Equal(t, int32(8), left)
+	var moved int32
+	require.NoError(t, f.conn.QueryRow(`
+		SELECT quantity FROM inventory_batch_movement WHERE batch_id = $1 AND reason = 'cycle_count'`, lot).Scan(&moved))
+	assert.Equal(t, int32(2), moved)
+
+	// counting again finds nothing to reconcile
+	for _, r := range f.count(t, true) {
+		assert.Zero(t, r.Variance, "variation %d", r.VariationID)
+	}
+}
+
+func TestCycleCountDuplicateLine(t *testing.T) {
+	f := newCycleCountFixture(t)
+
+	_, err := f.service.CycleCount(context.Background(), CycleCountParams{
+		StoreID: f.store,
+		Confirm: true,
+		Lines: []CycleCountLine{
+			{VariationID: f.zobo, CountedQuantity: 1},
+			{VariationID: f.zobo, CountedQuantity: 2},
+		},
+	})
+	assert.ErrorIs(t, err, ErrDuplicateCountItem)
+	assert.Equal(t, int32(5), f.stock(t, f.zobo))
+}
@@ -0,0 +1,86 @@
+package inventory
+
+import (
+	"context"
+	"database/sql"
+	"herp/db/dbtest"
+	db "herp/db/sqlc"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDuplicateSku(t *testing.T) {
+	assert.Equal(t, "TS-RED-M-42", duplicateSku("TS-RED-M", 42))
+
+	long := strings.Repeat("A", 50)
+	got := duplicateSku(long, 1234)
+	assert.Len(t, got, 50)
+	assert.Equal(t, strings.Repeat("A", 45)+"-1234", got)
+}
+
+func TestDuplicateItem(t *testing.T) {
+	conn := dbtest.Open(t)
+	q := db.New(conn)
+	ctx := context.Background()
+	service := NewInventory(q, conn)
+
+	owner := dbtest.Admin(t, conn, "owner")
+	businessID, branchID := dbtest.Business(t, conn, owner, "Palmwine Express")
+	bar := dbtest.Store(t, conn, branchID, "Bar")
+
+	small := dbtest.Variation(t, conn, businessID, "PW-50CL", "800.00")
+	itemID := dbtest.Insert(t, conn, `SELECT item_id FROM variation WHERE id = $1`, small)
+	unitID := dbtest.Insert(t, conn, `SELECT unit_id FROM variation WHERE id = $1`, small)
+	large := dbtest.Insert(t, conn, `
+		INSERT INTO variation (item_id, sku, name, unit_id, base_price) VALUES ($1, 'PW-1L', 'Palm wine 1L', $2, '1500.00')
+		RETURNING id`, itemID, unitID)
+	dbtest.Insert(t, conn, `
+		INSERT INTO variation (item_id, sku, name, unit_id, base_price, is_active) VALUES ($1, 'PW-2L', 'Palm wine 2L', $2, '2800.00', false)
+		RETURNING id`, itemID, unitID)
+	dbtest.Stock(t, conn, bar, small, 12)
+	dbtest.Stock(t, conn, bar, large, 4)
+
+	item, variations, err := service.DuplicateItem(ctx, db.GetItemParams{ID: itemID, BusinessID: nullInt(businessID)}, "Palm wine (chilled)")
+	require.NoError(t, err)
+	assert.NotEqual(t, itemID, item.ID)
+	assert.Equal(t, "Palm wine (chilled)", item.Name)
+	assert.Equal(t, nullInt(businessID), item.BusinessID)
+
+	// the inactive variation is left behind
+	require.Len(t, variations, 2)
+	prices := map[string]string{}
+	for _, v := range variations {
+		assert.Equal(t, item.ID, v.ItemID)
+		prices[v.Sku] = v.BasePrice
+
+		var stock int
+		require.NoError(t, conn.QueryRow(`SELECT count(*) FROM inventory WHERE variation_id = $1`, v.ID).Scan(&stock))
+		assert.Zero(t, stock, "variation %s has stock", v.Sku)
+	}
+	assert.Equal(t, map[string]string{
+		duplicateSku("PW-50CL", item.ID): "800.00",
+		duplicateSku("PW-1L", item.ID):   "1500.00",
+	}, prices)
+
+	// a second copy gets SKUs of its own
+	again, againVariations, err := service.DuplicateItem(ctx, db.GetItemParams{ID: itemID, BusinessID: nullInt(businessID)}, "Palm wine (large)")
+	require.NoError(t, err)
+	for _, v := range againVariations {
+		assert.True(t, strings.HasSuffix(v.Sku, "-"+itoa(again.ID)), v.Sku)
+		assert.NotContains(t, prices, v.Sku)
+	}
+
+	// the source keeps its stock
+	var quantity int32
+	require.NoError(t, conn.QueryRow(`SELECT quantity FROM inventory WHERE store_id = $1 AND variation_id = $2`, bar, small).Scan(&quantity))
+	assert.Equal(t, int32(12), quantity)
+
+	// items of another business can't be copied
+	other := dbtest.Admin(t, conn, "other")
+	otherID, _ := dbtest.Business(t, conn, other, "Other Hotel")
+	_, _, err = service.DuplicateItem(ctx, db.GetItemParams{ID: itemID, BusinessID: nullInt(otherID)}, "Stolen")
+	assert.ErrorIs(t, err, sql.ErrNoRows)
+}
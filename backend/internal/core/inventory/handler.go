@@ -2,12 +2,17 @@ package inventory
 
 import (
 	"database/sql"
+	"errors"
 	"fmt"
 	db "herp/db/sqlc"
 	"herp/internal/auth"
+	"herp/internal/config"
 	"herp/internal/utils"
 	"herp/pkg/jwt"
 	"herp/pkg/monitoring/logging"
+	"herp/pkg/storage"
+	"strconv"
+	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/jinzhu/copier"
@@ -16,44 +21,132 @@ import (
 
 type Handler struct {
 	service InventoryInterface
+	config  *config.Config
 	logger  *logging.Logger
+	files   storage.FileStore
 }
 
-func NewInventoryHandler(service InventoryInterface, l *logging.Logger) *Handler {
+func NewInventoryHandler(service InventoryInterface, c *config.Config, l *logging.Logger, files storage.FileStore) *Handler {
 	return &Handler{
 		service: service,
+		config:  c,
 		logger:  l,
+		files:   files,
 	}
 }
 
+// businessScope resolves the business that catalog reads and writes are
+// limited to. Staff users work in the business of the branch or store they
+// are assigned to. Admins work in the businesses they own, the X-Business-ID
+// header picks one of them, otherwise their first business is used. When
+// scoping is disabled in config an empty scope is returned and no filtering
+// is applied.
+func (h *Handler) businessScope(c *gin.Context, claims *jwt.Claims) (sql.NullInt32, bool) {
+	if !h.config.BusinessScope {
+		return sql.NullInt32{}, true
+	}
+
+	var requested sql.NullInt32
+	if header := c.GetHeader("X-Business-ID"); header != "" {
+		id, err := strconv.Atoi(header)
+		if err != nil || id <= 0 {
+			utils.ErrorResponse(c, 400, "invalid X-Business-ID header")
+			return sql.NullInt32{}, false
+		}
+		requested = sql.NullInt32{Int32: int32(id), Valid: true}
+	}
+
+	if scope := auth.GetStoreScope(c); scope.User {
+		if !scope.BusinessID.Valid || (requested.Valid && requested.Int32 != scope.BusinessID.Int32) {
+			utils.ErrorResponse(c, 403, "no business found for this account")
+			return sql.NullInt32{}, false
+		}
+		return scope.BusinessID, true
+	}
+
+	businessID, err := h.service.GetOwnedBusinessID(c, db.GetOwnedBusinessIDParams{
+		OwnerID:    int32(claims.UserID),
+		BusinessID: requested,
+	})
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			utils.ErrorResponse(c, 403, "no business found for this account")
+			return sql.NullInt32{}, false
+		}
+		h.logger.Errorf("error resolving business scope: %v", err)
+		utils.ErrorResponse(c, 500, utils.SERVERERROR)
+		return sql.NullInt32{}, false
+	}
+
+	return sql.NullInt32{Int32: businessID, Valid: true}, true
+}
+
 func (h *Handler) RegisterRoutes(r *gin.RouterGroup, authSvc *auth.Service) {
 	inventory := r.Group("/inventory")
 	inventory.Use(auth.AuthMiiddleware(authSvc))
+	inventory.Use(auth.StoreScopeMiddleware(authSvc))
 
 	brand := inventory.Group("/brand")
 	{
 		brand.POST("", auth.PermissionMiddleware(authSvc, "inventory:create"), h.createBrand)
+		brand.GET("", auth.PermissionMiddleware(authSvc, "inventory:view"), h.listBrands)
 	}
 
 	category := inventory.Group("/category")
 	{
 		category.POST("", auth.PermissionMiddleware(authSvc, "inventory:create"), h.createCategory)
+		category.GET("", auth.PermissionMiddleware(authSvc, "inventory:view"), h.listCategories)
+		category.GET("/tree", auth.PermissionMiddleware(authSvc, "inventory:view"), h.getCategoryTree)
+		category.GET("/:id/children", auth.PermissionMiddleware(authSvc, "inventory:view"), h.listCategoryChildren)
+		category.PUT("/:id", auth.PermissionMiddleware(authSvc, "inventory:update"), h.updateCategory)
 	}
 
-	// item := inventory.Group("/item")
-	// {
-	// 	// item.POST("", auth.PermissionMiddleware(authSvc, "inventory:create"), h.createItem)
-	// }
+	item := inventory.Group("/item")
+	{
+		item.POST("", auth.PermissionMiddleware(authSvc, "inventory:create"), h.createItem)
+		item.GET("", auth.PermissionMiddleware(authSvc, "inventory:view"), h.listItems)
+		item.GET("/:id", auth.PermissionMiddleware(authSvc, "inventory:view"), h.getItem)
+		item.PATCH("/:id", auth.PermissionMiddleware(authSvc, "inventory:update"), h.updateItem)
+		item.DELETE("/:id", auth.PermissionMiddleware(authSvc, "inventory:delete"), h.deleteItem)
+		item.POST("/:id/duplicate", auth.PermissionMiddleware(authSvc, "inventory:create"), h.duplicateItem)
+	}
 
 	variation := inventory.Group("/variation")
 	{
 		variation.POST("", auth.PermissionMiddleware(authSvc, "inventory:create"), h.CreateVariation)
+		variation.PUT("/:id/price", auth.PermissionMiddleware(authSvc, "inventory:update"), h.setVariationPrice)
+		variation.GET("/:id/price-history", auth.PermissionMiddleware(authSvc, "inventory:view"), h.listPriceHistory)
 	}
 
 	unit := inventory.Group("/unit")
 	{
 		unit.POST("", auth.PermissionMiddleware(authSvc, "inventory:create"), h.createUnit)
 	}
+
+	inventory.POST("/reserve", auth.PermissionMiddleware(authSvc, "inventory:update"), h.reserveStock)
+	inventory.POST("/batch", auth.PermissionMiddleware(authSvc, "inventory:create"), h.receiveBatch)
+	inventory.GET("/search", auth.PermissionMiddleware(authSvc, "inventory:view"), h.searchItems)
+	inventory.GET("/expiring", auth.PermissionMiddleware(authSvc, "inventory:view"), h.listExpiring)
+	inventory.GET("/low-stock", auth.PermissionMiddleware(authSvc, "inventory:view"), h.listLowStock)
+	inventory.POST("/cycle-count", auth.PermissionMiddleware(authSvc, "inventory:update"), h.cycleCount)
+	inventory.POST("/adjust", auth.PermissionMiddleware(authSvc, "inventory:adjust"), h.adjustStock)
+
+	purchaseOrder := inventory.Group("/purchase-order")
+	{
+		purchaseOrder.POST("", auth.PermissionMiddleware(authSvc, "inventory:create"), h.createPurchaseOrder)
+		purchaseOrder.GET("", auth.PermissionMiddleware(authSvc, "inventory:view"), h.listPurchaseOrders)
+		purchaseOrder.GET("/:id", auth.PermissionMiddleware(authSvc, "inventory:view"), h.getPurchaseOrder)
+		purchaseOrder.PATCH("/:id", auth.PermissionMiddleware(authSvc, "inventory:update"), h.updatePurchaseOrder)
+		purchaseOrder.POST("/:id/receive", auth.PermissionMiddleware(authSvc, "inventory:create"), h.receivePurchaseOrder)
+		purchaseOrder.POST("/:id/cancel", auth.PermissionMiddleware(authSvc, "inventory:update"), h.cancelPurchaseOrder)
+	}
+	inventory.PUT("/min-keep", auth.PermissionMiddleware(authSvc, "inventory:update"), h.setMinKeep)
+
+	// purchasing clients cancel orders under their own path too
+	purchasing := r.Group("/purchasing/orders")
+	purchasing.Use(auth.AuthMiiddleware(authSvc))
+	purchasing.Use(auth.StoreScopeMiddleware(authSvc))
+	purchasing.POST("/:id/cancel", auth.PermissionMiddleware(authSvc, "inventory:update"), h.cancelPurchaseOrder)
 }
 
 type CreateBrandRequest struct {
@@ -63,11 +156,12 @@ type CreateBrandRequest struct {
 }
 
 type CreateBrandResponse struct {
-	ID          int32  `json:"id"`
-	Name        string `json:"name" binding:"omitempty" example:"Coca-Cola"`
-	Description string `json:"description" binding:"omitempty" example:"..."`
-	IsActive    bool   `json:"is_active" binding:"omitempty" example:"true"`
-	Logo        string `json:"logo" binding:"omitempty"`
+	ID            int32  `json:"id"`
+	Name          string `json:"name" binding:"omitempty" example:"Coca-Cola"`
+	Description   string `json:"description" binding:"omitempty" example:"..."`
+	IsActive      bool   `json:"is_active" binding:"omitempty" example:"true"`
+	Logo          string `json:"logo" binding:"omitempty"`
+	LogoThumbnail string `json:"logo_thumbnail" binding:"omitempty"`
 }
 
 // CreateBrand godoc
@@ -101,27 +195,40 @@ func (h *Handler) createBrand(c *gin.Context) {
 	var req CreateBrandRequest
 	if err := c.ShouldBind(&req); err != nil {
 		h.logger.Errorf("error binding creating brand request data: %v", err)
-		utils.ErrorResponse(c, 400, utils.INVALID_REQUEST_DATA)
+		utils.BindingErrorResponse(c, err)
+		return
+	}
+
+	scope, ok := h.businessScope(c, claims)
+	if !ok {
 		return
 	}
 
 	// Handle logo file separately
-	var logoUrl string
-	if url, err := utils.UploadFile(c, "logo", "images", 2<<20); err == nil && url != "" {
-		logoUrl = url
+	logo, err := utils.UploadFile(c, h.files, "logo", "images", utils.ImageUploadOptions(h.config))
+	if err != nil {
+		if errors.Is(err, utils.ErrFileTooLarge) || errors.Is(err, utils.ErrFileType) {
+			utils.ErrorResponse(c, 400, err.Error())
+			return
+		}
+		h.logger.Errorf("error uploading brand logo: %v", err)
+		utils.ErrorResponse(c, 500, utils.SERVERERROR)
+		return
 	}
 
 	var params db.CreateBrandParams
-	err := copier.Copy(&params, &req)
+	err = copier.Copy(&params, &req)
 	if err != nil {
 		h.logger.Errorf("error copying create brand request data: %v", err)
 		utils.ErrorResponse(c, 500, utils.SERVERERROR)
 		return
 	}
 
-	if logoUrl != "" {
-		params.Logo = sql.NullString{String: logoUrl, Valid: true}
+	if logo.URL != "" {
+		params.Logo = sql.NullString{String: logo.URL, Valid: true}
+		params.LogoThumbnail = sql.NullString{String: logo.ThumbnailURL, Valid: true}
 	}
+	params.BusinessID = scope
 
 	brand, err := h.service.CreateBrand(c, params)
 	if err != nil {
@@ -149,19 +256,68 @@ func (h *Handler) createBrand(c *gin.Context) {
 	})
 
 	utils.SuccessResponse(c, 201, "brand created", CreateBrandResponse{
-		ID:          brand.ID,
-		Name:        brand.Name,
-		Description: brand.Description.String,
-		IsActive:    brand.IsActive.Bool,
-		Logo:        brand.Logo.String,
+		ID:            brand.ID,
+		Name:          brand.Name,
+		Description:   brand.Description.String,
+		IsActive:      brand.IsActive.Bool,
+		Logo:          brand.Logo.String,
+		LogoThumbnail: brand.LogoThumbnail.String,
 	})
 }
 
+// ListBrands godoc
+// @Summary List brands
+// @Description List the brands of your business.
+// @Tags inventory
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} []CreateBrandResponse
+// @Failure 401
+// @Failure 403
+// @Failure 500
+// @Router /api/v1/inventory/brand [get]
+func (h *Handler) listBrands(c *gin.Context) {
+	claims, ok := jwt.GetUserFromContext(c)
+	if !ok {
+		h.logger.Errorf("could not get user from context")
+		utils.ErrorResponse(c, 500, utils.SERVERERROR)
+		return
+	}
+
+	scope, ok := h.businessScope(c, claims)
+	if !ok {
+		return
+	}
+
+	brands, err := h.service.ListBrands(c, scope)
+	if err != nil {
+		h.logger.Errorf("error listing brands: %v", err)
+		utils.ErrorResponse(c, 500, utils.SERVERERROR)
+		return
+	}
+
+	response := make([]CreateBrandResponse, 0, len(brands))
+	for _, brand := range brands {
+		response = append(response, CreateBrandResponse{
+			ID:            brand.ID,
+			Name:          brand.Name,
+			Description:   brand.Description.String,
+			IsActive:      brand.IsActive.Bool,
+			Logo:          brand.Logo.String,
+			LogoThumbnail: brand.LogoThumbnail.String,
+		})
+	}
+
+	utils.SuccessResponse(c, 200, "brands fetched", response)
+}
+
 type Category struct {
 	Name        string `json:"name" binding:"required"`
 	ParentID    *int32 `json:"parent_id"`
 	Description string `json:"description"`
 	IsActive    bool   `json:"is_active" example:"true" default:"true"`
+	// overrides the business depletion strategy for items in this category
+	DepletionStrategy *string `json:"depletion_strategy" binding:"omitempty,oneof=fefo fifo"`
 }
 
 type CategoryResponse struct {
@@ -170,6 +326,8 @@ type CategoryResponse struct {
 	ParentID    *int32 `json:"parent_id"`
 	Description string `json:"description"`
 	IsActive    bool   `json:"is_active"`
+	// empty when the category uses the business depletion strategy
+	DepletionStrategy string `json:"depletion_strategy,omitempty"`
 }
 
 // CreateCategory godoc
@@ -197,13 +355,18 @@ func (h *Handler) createCategory(c *gin.Context) {
 	var req Category
 	if err := c.ShouldBind(&req); err != nil {
 		h.logger.Errorf("error binding create category request data: %v", err)
-		utils.ErrorResponse(c, 400, utils.INVALID_REQUEST_DATA)
+		utils.BindingErrorResponse(c, err)
+		return
+	}
+
+	scope, ok := h.businessScope(c, claims)
+	if !ok {
 		return
 	}
 
 	// Validate parent if provided
 	if req.ParentID != nil {
-		_, err := h.service.GetCategory(c, *req.ParentID)
+		_, err := h.service.GetCategory(c, db.GetCategoryParams{ID: *req.ParentID, BusinessID: scope})
 		if err == sql.ErrNoRows {
 			utils.ErrorResponse(c, 400, fmt.Sprintf("parent category with id %d does not exist", *req.ParentID))
 			return
@@ -221,6 +384,7 @@ func (h *Handler) createCategory(c *gin.Context) {
 		utils.ErrorResponse(c, 500, utils.SERVERERROR)
 		return
 	}
+	params.BusinessID = scope
 
 	category, err := h.service.CreateCategory(c, params)
 	if err != nil {
@@ -248,232 +412,197 @@ func (h *Handler) createCategory(c *gin.Context) {
 	})
 
 	utils.SuccessResponse(c, 201, "created category", CategoryResponse{
-		ID:          category.ID,
-		Name:        category.Name,
-		ParentID:    &category.ParentID.Int32,
-		Description: category.Description.String,
-		IsActive:    category.IsActive.Bool,
+		ID:                category.ID,
+		Name:              category.Name,
+		ParentID:          &category.ParentID.Int32,
+		Description:       category.Description.String,
+		IsActive:          category.IsActive.Bool,
+		DepletionStrategy: category.DepletionStrategy.String,
 	})
 }
 
-type ItemRequest struct {
-	BrandID      *int32 `json:"brand_id" binding:"omitempty" example:"3"`
-	CategoryID   int32  `json:"category_id" binding:"required" example:"1"`
-	Name         string `json:"name" binding:"required" example:"Shoes"`
-	Description  string `json:"description"`
-	IsActive     bool   `json:"is_active" default:"true" example:"true"`
-	UnitID       int32  `json:"unit_id"`
-	DefaultPrice string `json:"default_price"`
-}
-
-type ItemResponse struct {
-	ID            int32        `json:"id"`
-	BrandID       int32        `json:"brand_id" binding:"required" example:"3"`
-	CategoryID    int32        `json:"category_id" binding:"required" example:"1"`
-	Name          string       `json:"name" binding:"required" example:"Shoes"`
-	Description   string       `json:"description"`
-	Has_variation bool         `json:"has_variation"`
-	IsActive      bool         `json:"is_active"`
-	Variation     db.Variation `json:"variation"`
-}
-
-// CreateItem godoc
-// @Summary Create Item
-// @Description An ite
+// ListCategories godoc
+// @Summary List categories
+// @Description List the categories of your business.
 // @Tags inventory
-// @Accept json
 // @Produce json
 // @Security BearerAuth
-// @Success 201 {object} ItemResponse
-// @Param body body ItemRequest true "item details"
-// @Failure 400
+// @Success 200 {object} []CategoryResponse
 // @Failure 401
 // @Failure 403
 // @Failure 500
-// @Router /api/v1/inventory/item [post]
-// func (h *Handler) createItem(c *gin.Context) {
-// 	claims, ok := jwt.GetUserFromContext(c)
-// 	if !ok {
-// 		h.logger.Errorf("could not get user from context")
-// 		utils.ErrorResponse(c, 500, utils.SERVERERROR)
-// 		return
-// 	}
-
-// 	var req ItemRequest
-// 	if err := c.ShouldBindJSON(&req); err != nil {
-// 		h.logger.Errorf("create item binding error: %v", err)
-// 		utils.ErrorResponse(c, 400, utils.INVALID_REQUEST_DATA)
-// 		return
-// 	}
-
-// 	var params db.CreateItemParams
-// 	err := copier.Copy(&params, &req)
-// 	if err != nil {
-// 		h.logger.Errorf("error copying create item request data: %v", err)
-// 		utils.ErrorResponse(c, 500, utils.SERVERERROR)
-// 		return
-// 	}
-
-// 	_, err = h.service.GetBrand(c, params.BrandID.Int32)
-// 	if err != nil {
-// 		if err == sql.ErrNoRows {
-// 			utils.ErrorResponse(c, 400, fmt.Sprintf("brand with id %d does not exist", req.BrandID))
-// 			return
-// 		}
-
-// 		h.logger.Errorf("error getting brand with id %d: %v", req.BrandID, err)
-// 		utils.ErrorResponse(c, 500, utils.SERVERERROR)
-// 		return
-// 	}
-
-// 	_, err = h.service.GetCategory(c, params.BrandID.Int32)
-// 	if err != nil {
-// 		if err == sql.ErrNoRows {
-// 			utils.ErrorResponse(c, 400, fmt.Sprintf("category with id %d does not exist", req.CategoryID))
-// 			return
-// 		}
-
-// 		h.logger.Errorf("error getting category with id %d: %v", req.CategoryID, err)
-// 		utils.ErrorResponse(c, 500, utils.SERVERERROR)
-// 		return
-// 	}
-
-// 	item, variations, err := h.service.CreateItemWithVariations(c, params)
-// 	if err != nil {
-// 		h.logger.Errorf("error creating item: %v", err)
-// 		utils.ErrorResponse(c, 500, utils.SERVERERROR)
-// 		return
-// 	}
-
-// 	// add activity log here
-// 	_, err = h.service.LogActivity(c, db.LogActivityParams{
-// 		UserID:     int32(claims.UserID),
-// 		Action:     "Created Item",
-// 		EntityType: "Item",
-// 		EntityID:   item.ID,
-// 		Details:    utils.WriteActivityDetails("system", "system", fmt.Sprintf("Created item %s", item.Name), item.CreatedAt.Time),
-// 		IpAddress:  sql.NullString{Valid: true, String: utils.GetClientIP(c)},
-// 		UserAgent:  sql.NullString{Valid: true, String: c.Request.UserAgent()},
-// 	})
-
-// 	if err != nil {
-// 		h.logger.Warnf("error logging create item activity: %v", err)
-// 		// not returning error to user as branch has been created successfully
-// 	}
-
-// 	utils.SuccessResponse(c, 201, "item created", ItemResponse{
-// 		ID:          item.ID,
-// 		Name:        item.Name,
-// 		BrandID:     item.BrandID.Int32,
-// 		CategoryID:  item.CategoryID,
-// 		Description: item.Description.String,
-// 		IsActive:    item.IsActive.Bool,
-// 		Variation:   variations, // TODO: serialize
-// 	})
-// }
+// @Router /api/v1/inventory/category [get]
+func (h *Handler) listCategories(c *gin.Context) {
+	claims, ok := jwt.GetUserFromContext(c)
+	if !ok {
+		h.logger.Errorf("could not get user from context")
+		utils.ErrorResponse(c, 500, utils.SERVERERROR)
+		return
+	}
 
-type UnitRequest struct {
-	Name      string `json:"name" binding:"required" example:"kg"`
-	ShortCode string `json:"short_code"`
-}
+	scope, ok := h.businessScope(c, claims)
+	if !ok {
+		return
+	}
 
-type UnitResponse struct {
-	ID        int32  `json:"id"`
-	Name      string `json:"name"`
-	ShortCode string `json:"short_code"`
+	categories, err := h.service.ListCategories(c, scope)
+	if err != nil {
+		h.logger.Errorf("error listing categories: %v", err)
+		utils.ErrorResponse(c, 500, utils.SERVERERROR)
+		return
+	}
+
+	response := make([]CategoryResponse, 0, len(categories))
+	for _, category := range categories {
+		response = append(response, categoryResponse(category))
+	}
+
+	utils.SuccessResponse(c, 200, "categories fetched", response)
 }
 
-// CreateUnit godoc
-// @Summary Create a unit
-// @Description Create an inventory unit.
+// UpdateCategory godoc
+// @Summary Update a category
+// @Description Update a category. Leaving out parent_id makes it a top level category, a parent cannot be the category itself or one of its subcategories.
 // @Tags inventory
 // @Accept json
 // @Produce json
 // @Security BearerAuth
-// @Success 201 {object} UnitResponse
-// @Param body body UnitRequest true "unit details"
+// @Param id path int true "Category ID"
+// @Param body body Category true "category details"
+// @Success 200 {object} CategoryResponse
 // @Failure 400
 // @Failure 401
 // @Failure 403
+// @Failure 404
 // @Failure 500
-// @Router /api/v1/inventory/unit [post]
-func (h *Handler) createUnit(c *gin.Context) {
-	var req UnitRequest
-	if err := c.ShouldBind(&req); err != nil {
-		h.logger.Errorf("error binding creating unit request data: %v", err)
+// @Router /api/v1/inventory/category/{id} [put]
+func (h *Handler) updateCategory(c *gin.Context) {
+	claims, ok := jwt.GetUserFromContext(c)
+	if !ok {
+		h.logger.Errorf("could not get user from context")
+		utils.ErrorResponse(c, 500, utils.SERVERERROR)
+		return
+	}
+
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		h.logger.Errorf("update category id str conv err: %v", err)
 		utils.ErrorResponse(c, 400, utils.INVALID_REQUEST_DATA)
 		return
 	}
 
-	params := db.CreateUnitParams{
-		Name:      req.Name,
-		ShortCode: sql.NullString{String: req.ShortCode, Valid: req.ShortCode != ""},
+	var req Category
+	if err := c.ShouldBindJSON(&req); err != nil {
+		h.logger.Errorf("error binding update category request data: %v", err)
+		utils.BindingErrorResponse(c, err)
+		return
 	}
 
-	unit, err := h.service.CreateUnit(c, params)
+	scope, ok := h.businessScope(c, claims)
+	if !ok {
+		return
+	}
+
+	params := db.UpdateCategoryParams{
+		ID:          int32(id),
+		Name:        req.Name,
+		Description: sql.NullString{String: req.Description, Valid: req.Description != ""},
+		IsActive:    sql.NullBool{Bool: req.IsActive, Valid: true},
+		BusinessID:  scope,
+	}
+	if req.ParentID != nil {
+		params.ParentID = sql.NullInt32{Int32: *req.ParentID, Valid: true}
+	}
+	if req.DepletionStrategy != nil {
+		params.DepletionStrategy = sql.NullString{String: *req.DepletionStrategy, Valid: true}
+	}
+
+	category, err := h.service.UpdateCategory(c, params)
 	if err != nil {
-		h.logger.Errorf("error creating a unit: %v", err)
-		utils.ErrorResponse(c, 500, utils.SERVERERROR)
+		switch {
+		case errors.Is(err, ErrParentCategoryNotFound):
+			utils.ErrorResponse(c, 400, fmt.Sprintf("parent category with id %d does not exist", *req.ParentID))
+		case errors.Is(err, ErrCategoryParentCycle):
+			utils.ErrorResponse(c, 400, fmt.Sprintf("parent category %d would create a cycle", *req.ParentID))
+		case errors.Is(err, sql.ErrNoRows):
+			utils.ErrorResponse(c, 404, fmt.Sprintf("category with id %d does not exist", id))
+		default:
+			if pgErr, ok := err.(*pq.Error); ok && pgErr.Code == "23505" {
+				utils.ErrorResponse(c, 400, fmt.Sprintf("category with name %s already exists", req.Name))
+				return
+			}
+			h.logger.Errorf("error updating category with id %d: %v", id, err)
+			utils.ErrorResponse(c, 500, utils.SERVERERROR)
+		}
 		return
 	}
 
-	utils.SuccessResponse(c, 201, "unit created", UnitResponse{
-		Name:      unit.Name,
-		ShortCode: unit.ShortCode.String,
-		ID:        unit.ID,
+	_, err = h.service.LogActivity(c, db.LogActivityParams{
+		UserID:     int32(claims.UserID),
+		Action:     "Updated Category",
+		EntityType: "Category",
+		EntityID:   category.ID,
+		Details:    utils.WriteActivityDetails(claims.Username, claims.Email, fmt.Sprintf("Updated category %s", category.Name), category.UpdatedAt.Time),
+		IpAddress:  sql.NullString{Valid: true, String: utils.GetClientIP(c)},
+		UserAgent:  sql.NullString{Valid: true, String: c.Request.UserAgent()},
 	})
+	if err != nil {
+		h.logger.Warnf("error logging update category activity: %v", err)
+	}
+
+	utils.SuccessResponse(c, 200, "category updated", categoryResponse(category))
 }
 
-type VariationRequest struct {
-	ItemID       int32  `json:"item_id" binding:"required" example:"1"`
-	Sku          string `json:"sku" binding:"omitempty" example:"GTR30l"`
-	Name         string `json:"name" binding:"required" example:"...."`
-	UnitID       int32  `json:"unit_id" binding:"required" example:"1"`
-	Size         string `json:"size" binding:"omitempty" example:"xl"`
-	ColorID      int32  `json:"color" binding:"omitempty" example:"1"`
-	Barcode      string `json:"barcode" binding:"omitempty" example:"..."`
-	ReorderLevel int32  `json:"reorder_level" binding:"omitempty" example:"5" default:"5"`
-	BasePrice    string `json:"base_price" binding:"required" example:"10.99"`
-	IsActive     bool   `json:"is_active" binding:"omitempty" default:"true"`
-	IsDefault    bool   `json:"is_default" binding:"omitempty" default:"false" example:"false"`
+func categoryResponse(category db.Category) CategoryResponse {
+	var parentID *int32
+	if category.ParentID.Valid {
+		parentID = &category.ParentID.Int32
+	}
+	return CategoryResponse{
+		ID:                category.ID,
+		Name:              category.Name,
+		ParentID:          parentID,
+		Description:       category.Description.String,
+		IsActive:          category.IsActive.Bool,
+		DepletionStrategy: category.DepletionStrategy.String,
+	}
 }
 
-type VariationResponse struct {
-	ID           int32  `json:"id"`
-	ItemID       int32  `json:"item_id"`
-	Sku          string `json:"sku"`
-	Name         string `json:"name"`
-	UnitID       int32  `json:"unit_id"`
-	Size         string `json:"size"`
-	ColorID      int32  `json:"color_id"`
-	Barcode      string `json:"barcode"`
-	IsActive     bool   `json:"is_active"`
-	ReorderLevel int32  `json:"reorder_level"`
-	BasePrice    string `json:"base_price"`
+type CategoryTreeResponse struct {
+	CategoryResponse
+	// items filed directly under the category
+	ItemCount int64 `json:"item_count"`
+	// items of the category and all its subcategories
+	TotalItemCount int64                  `json:"total_item_count"`
+	Children       []CategoryTreeResponse `json:"children"`
 }
 
-func safePrefix(s string, length int) string {
-	if len(s) < length {
-		return s
+func categoryTreeResponse(nodes []CategoryNode) []CategoryTreeResponse {
+	response := make([]CategoryTreeResponse, 0, len(nodes))
+	for _, node := range nodes {
+		response = append(response, CategoryTreeResponse{
+			CategoryResponse: categoryResponse(node.Category),
+			ItemCount:        node.ItemCount,
+			TotalItemCount:   node.TotalItemCount,
+			Children:         categoryTreeResponse(node.Children),
+		})
 	}
-	return s[:length]
+	return response
 }
 
-// CreateVariant godoc
-// @Summary Create a variant
-// @Description Create an item variation. If sku is empty, system autogenerates it.
+// GetCategoryTree godoc
+// @Summary Get the category tree
+// @Description Get the categories of your business nested under their parents, with item counts per category.
 // @Tags inventory
-// @Accept json
 // @Produce json
 // @Security BearerAuth
-// @Success 201 {object} VariationResponse
-// @Param body body VariationRequest true "variation details"
-// @Failure 400
+// @Success 200 {object} []CategoryTreeResponse
 // @Failure 401
 // @Failure 403
+// @Failure 409 "the category hierarchy has a cycle"
 // @Failure 500
-// @Router /api/v1/inventory/variation [post]
-func (h *Handler) CreateVariation(c *gin.Context) {
+// @Router /api/v1/inventory/category/tree [get]
+func (h *Handler) getCategoryTree(c *gin.Context) {
 	claims, ok := jwt.GetUserFromContext(c)
 	if !ok {
 		h.logger.Errorf("could not get user from context")
@@ -481,108 +610,2575 @@ func (h *Handler) CreateVariation(c *gin.Context) {
 		return
 	}
 
-	var req VariationRequest
-	if err := c.ShouldBind(&req); err != nil {
-		h.logger.Errorf("error binding creating business request data: %v", err)
-		utils.ErrorResponse(c, 400, utils.INVALID_REQUEST_DATA)
+	scope, ok := h.businessScope(c, claims)
+	if !ok {
 		return
 	}
 
-	_, err := h.service.GetItem(c, req.ItemID)
-	if err == sql.ErrNoRows {
-		utils.ErrorResponse(c, 400, fmt.Sprintf("item with id %d does not exist", req.ItemID))
+	tree, err := h.service.CategoryTree(c, scope)
+	if err != nil {
+		h.logger.Errorf("error building category tree: %v", err)
+		if errors.Is(err, ErrCategoryCycle) {
+			utils.ErrorResponse(c, 409, err.Error())
+			return
+		}
+		utils.ErrorResponse(c, 500, utils.SERVERERROR)
 		return
-	} else if err != nil {
-		h.logger.Errorf("error fetching item in create variation: %v", err)
+	}
+
+	utils.SuccessResponse(c, 200, "category tree fetched", categoryTreeResponse(tree))
+}
+
+// ListCategoryChildren godoc
+// @Summary List subcategories
+// @Description List the subcategories of a category, each nested with its own subcategories and item counts.
+// @Tags inventory
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "Category ID"
+// @Success 200 {object} []CategoryTreeResponse
+// @Failure 400
+// @Failure 401
+// @Failure 403
+// @Failure 404
+// @Failure 409 "the category hierarchy has a cycle"
+// @Failure 500
+// @Router /api/v1/inventory/category/{id}/children [get]
+func (h *Handler) listCategoryChildren(c *gin.Context) {
+	claims, ok := jwt.GetUserFromContext(c)
+	if !ok {
+		h.logger.Errorf("could not get user from context")
 		utils.ErrorResponse(c, 500, utils.SERVERERROR)
 		return
 	}
 
-	// INFO: sku will be auto generated if empty
-	if req.Sku == "" {
-		var brand db.Brand
-		item, err := h.service.GetItem(c, req.ItemID)
-		if err != nil {
-			utils.ErrorResponse(c, 500, err.Error())
-			return
-		}
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		h.logger.Errorf("list category children id str conv err: %v", err)
+		utils.ErrorResponse(c, 400, utils.INVALID_REQUEST_DATA)
+		return
+	}
 
-		category, err := h.service.GetCategory(c, item.CategoryID)
-		if err != nil {
-			utils.ErrorResponse(c, 500, err.Error())
-			return
-		}
+	scope, ok := h.businessScope(c, claims)
+	if !ok {
+		return
+	}
 
-		if item.BrandID.Valid && item.BrandID.Int32 != 0 {
-			brand, err = h.service.GetBrand(c, item.BrandID.Int32)
-			if err != nil {
-				h.logger.Errorf("error fetching brand in create variation: %v", err)
-				utils.ErrorResponse(c, 500, err.Error())
+	children, err := h.service.CategoryChildren(c, db.GetCategoryParams{ID: int32(id), BusinessID: scope})
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			utils.ErrorResponse(c, 404, fmt.Sprintf("category with id %d does not exist", id))
+		case errors.Is(err, ErrCategoryCycle):
+			h.logger.Errorf("error building category tree: %v", err)
+			utils.ErrorResponse(c, 409, err.Error())
+		default:
+			h.logger.Errorf("error listing children of category %d: %v", id, err)
+			utils.ErrorResponse(c, 500, utils.SERVERERROR)
+		}
+		return
+	}
+
+	utils.SuccessResponse(c, 200, "subcategories fetched", categoryTreeResponse(children))
+}
+
+type ItemRequest struct {
+	BrandID      *int32 `json:"brand_id" binding:"omitempty" example:"3"`
+	CategoryID   int32  `json:"category_id" binding:"required" example:"1"`
+	Name         string `json:"name" binding:"required" example:"Shoes"`
+	Description  string `json:"description"`
+	ItemType     string `json:"item_type" binding:"omitempty,oneof=fixed consumable raw_material for_sale" example:"for_sale"`
+	IsActive     bool   `json:"is_active" default:"true" example:"true"`
+	UnitID       int32  `json:"unit_id"`
+	DefaultPrice string `json:"default_price"`
+}
+
+type ItemResponse struct {
+	ID            int32        `json:"id"`
+	BrandID       int32        `json:"brand_id" binding:"required" example:"3"`
+	CategoryID    int32        `json:"category_id" binding:"required" example:"1"`
+	Name          string       `json:"name" binding:"required" example:"Shoes"`
+	Description   string       `json:"description"`
+	Has_variation bool         `json:"has_variation"`
+	IsActive      bool         `json:"is_active"`
+	Variation     db.Variation `json:"variation"`
+}
+
+// CreateItem godoc
+// @Summary Create Item
+// @Description Create an item. When a unit is given the item gets a default variation with the default price.
+// @Tags inventory
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Success 201 {object} ItemDetailResponse
+// @Param body body ItemRequest true "item details"
+// @Failure 400
+// @Failure 401
+// @Failure 403
+// @Failure 500
+// @Router /api/v1/inventory/item [post]
+func (h *Handler) createItem(c *gin.Context) {
+	claims, ok := jwt.GetUserFromContext(c)
+	if !ok {
+		h.logger.Errorf("could not get user from context")
+		utils.ErrorResponse(c, 500, utils.SERVERERROR)
+		return
+	}
+
+	var req ItemRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		h.logger.Errorf("create item binding error: %v", err)
+		utils.BindingErrorResponse(c, err)
+		return
+	}
+
+	scope, ok := h.businessScope(c, claims)
+	if !ok {
+		return
+	}
+
+	// brand is optional
+	if req.BrandID != nil && *req.BrandID != 0 {
+		if _, err := h.service.GetBrand(c, db.GetBrandParams{ID: *req.BrandID, BusinessID: scope}); err != nil {
+			if errors.Is(err, sql.ErrNoRows) {
+				utils.ErrorResponse(c, 400, fmt.Sprintf("brand with id %d does not exist", *req.BrandID))
 				return
 			}
+			h.logger.Errorf("error getting brand with id %d: %v", *req.BrandID, err)
+			utils.ErrorResponse(c, 500, utils.SERVERERROR)
+			return
+		}
+	}
+
+	if _, err := h.service.GetCategory(c, db.GetCategoryParams{ID: req.CategoryID, BusinessID: scope}); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			utils.ErrorResponse(c, 400, fmt.Sprintf("category with id %d does not exist", req.CategoryID))
+			return
+		}
+		h.logger.Errorf("error getting category with id %d: %v", req.CategoryID, err)
+		utils.ErrorResponse(c, 500, utils.SERVERERROR)
+		return
+	}
+
+	itemType := req.ItemType
+	if itemType == "" {
+		itemType = "for_sale"
+	}
+	params := db.CreateItemParams{
+		CategoryID:  req.CategoryID,
+		Name:        req.Name,
+		Description: sql.NullString{String: req.Description, Valid: req.Description != ""},
+		ItemType:    itemType,
+		// the default variation needs a unit, items created without one get
+		// their variations added later
+		NoVariants: sql.NullBool{Bool: req.UnitID != 0, Valid: true},
+		BusinessID: scope,
+	}
+	if req.BrandID != nil && *req.BrandID != 0 {
+		params.BrandID = sql.NullInt32{Int32: *req.BrandID, Valid: true}
+	}
+
+	defaultPrice := req.DefaultPrice
+	if defaultPrice == "" {
+		defaultPrice = "0"
+	}
+
+	item, variation, err := h.service.CreateItemWithVariations(c, params, req.UnitID, defaultPrice)
+	if err != nil {
+		h.logger.Errorf("error creating item: %v", err)
+		utils.ErrorResponse(c, 500, utils.SERVERERROR)
+		return
+	}
+
+	_, err = h.service.LogActivity(c, db.LogActivityParams{
+		UserID:     int32(claims.UserID),
+		Action:     "Created Item",
+		EntityType: "Item",
+		EntityID:   item.ID,
+		Details:    utils.WriteActivityDetails(claims.Username, claims.Email, fmt.Sprintf("Created item %s", item.Name), item.CreatedAt.Time),
+		IpAddress:  sql.NullString{Valid: true, String: utils.GetClientIP(c)},
+		UserAgent:  sql.NullString{Valid: true, String: c.Request.UserAgent()},
+	})
+	if err != nil {
+		h.logger.Warnf("error logging create item activity: %v", err)
+	}
+
+	var variations []db.Variation
+	if variation.ID != 0 {
+		variations = append(variations, variation)
+	}
+	utils.SuccessResponse(c, 201, "item created", itemDetailResponse(item, variations))
+}
+
+type ItemListResponse struct {
+	ID             int32  `json:"id"`
+	BrandID        int32  `json:"brand_id"`
+	CategoryID     int32  `json:"category_id"`
+	Name           string `json:"name"`
+	Description    string `json:"description"`
+	ItemType       string `json:"item_type"`
+	IsActive       bool   `json:"is_active"`
+	Version        int32  `json:"version"`
+	VariationCount int64  `json:"variation_count"`
+}
+
+// ListItems godoc
+// @Summary List items
+// @Description List the items of your business with the number of variations each has.
+// @Tags inventory
+// @Produce json
+// @Security BearerAuth
+// @Param category_id query int false "Only list items in this category"
+// @Param brand_id query int false "Only list items of this brand"
+// @Success 200 {object} []ItemListResponse
+// @Failure 400
+// @Failure 401
+// @Failure 403
+// @Failure 500
+// @Router /api/v1/inventory/item [get]
+func (h *Handler) listItems(c *gin.Context) {
+	claims, ok := jwt.GetUserFromContext(c)
+	if !ok {
+		h.logger.Errorf("could not get user from context")
+		utils.ErrorResponse(c, 500, utils.SERVERERROR)
+		return
+	}
+
+	scope, ok := h.businessScope(c, claims)
+	if !ok {
+		return
+	}
+
+	params := db.ListItemsParams{BusinessID: scope}
+	if cid := c.Query("category_id"); cid != "" {
+		id, err := strconv.Atoi(cid)
+		if err != nil {
+			utils.ErrorResponse(c, 400, utils.INVALID_REQUEST_DATA)
+			return
+		}
+		params.CategoryID = sql.NullInt32{Int32: int32(id), Valid: true}
+	}
+	if bid := c.Query("brand_id"); bid != "" {
+		id, err := strconv.Atoi(bid)
+		if err != nil {
+			utils.ErrorResponse(c, 400, utils.INVALID_REQUEST_DATA)
+			return
+		}
+		params.BrandID = sql.NullInt32{Int32: int32(id), Valid: true}
+	}
+
+	items, err := h.service.ListItems(c, params)
+	if err != nil {
+		h.logger.Errorf("error listing items: %v", err)
+		utils.ErrorResponse(c, 500, utils.SERVERERROR)
+		return
+	}
+
+	response := make([]ItemListResponse, 0, len(items))
+	for _, item := range items {
+		response = append(response, ItemListResponse{
+			ID:             item.ID,
+			BrandID:        item.BrandID.Int32,
+			CategoryID:     item.CategoryID,
+			Name:           item.Name,
+			Description:    item.Description.String,
+			ItemType:       item.ItemType,
+			IsActive:       item.IsActive.Bool,
+			Version:        item.Version,
+			VariationCount: item.VariationCount,
+		})
+	}
+
+	utils.SuccessResponse(c, 200, "items fetched", response)
+}
+
+type SearchVariationResponse struct {
+	VariationResponse
+	// whether the variation's name, SKU or barcode matched the search
+	Matched bool `json:"matched"`
+}
+
+type ItemSearchResponse struct {
+	ID          int32                     `json:"id"`
+	BrandID     int32                     `json:"brand_id"`
+	CategoryID  int32                     `json:"category_id"`
+	Name        string                    `json:"name"`
+	Description string                    `json:"description"`
+	ItemType    string                    `json:"item_type"`
+	IsActive    bool                      `json:"is_active"`
+	Rank        float32                   `json:"rank"`
+	Variations  []SearchVariationResponse `json:"variations"`
+}
+
+// SearchItems godoc
+// @Summary Search items
+// @Description Search the items of your business by name and description, and by the name, SKU and barcode of their variations. Every word has to match the start of a word, case-insensitively. Items come best match first with all their variations, the ones that matched are flagged.
+// @Tags inventory
+// @Produce json
+// @Security BearerAuth
+// @Param q query string true "What to search for"
+// @Param page query int false "Page number" default(1)
+// @Param limit query int false "Number of items per page, at most 100" default(20)
+// @Success 200 {object} utils.PaginatedResponse{items=[]ItemSearchResponse}
+// @Failure 400
+// @Failure 401
+// @Failure 403
+// @Failure 500
+// @Router /api/v1/inventory/search [get]
+func (h *Handler) searchItems(c *gin.Context) {
+	claims, ok := jwt.GetUserFromContext(c)
+	if !ok {
+		h.logger.Errorf("could not get user from context")
+		utils.ErrorResponse(c, 500, utils.SERVERERROR)
+		return
+	}
+
+	pagination, err := utils.ParsePagination(c)
+	if err != nil {
+		utils.ErrorResponse(c, 400, err.Error())
+		return
+	}
+
+	scope, ok := h.businessScope(c, claims)
+	if !ok {
+		return
+	}
+
+	results, total, err := h.service.SearchItems(c, SearchItemsParams{
+		Query:      c.Query("q"),
+		BusinessID: scope,
+		Limit:      int32(pagination.Limit),
+		Offset:     int32(pagination.Offset),
+	})
+	if err != nil {
+		if errors.Is(err, ErrEmptySearch) {
+			utils.ErrorResponse(c, 400, err.Error())
+			return
+		}
+		h.logger.Errorf("error searching items: %v", err)
+		utils.ErrorResponse(c, 500, utils.SERVERERROR)
+		return
+	}
+
+	response := make([]ItemSearchResponse, 0, len(results))
+	for _, result := range results {
+		item := ItemSearchResponse{
+			ID:          result.Item.ID,
+			BrandID:     result.Item.BrandID.Int32,
+			CategoryID:  result.Item.CategoryID,
+			Name:        result.Item.Name,
+			Description: result.Item.Description.String,
+			ItemType:    result.Item.ItemType,
+			IsActive:    result.Item.IsActive.Bool,
+			Rank:        result.Item.Rank,
+			Variations:  make([]SearchVariationResponse, 0, len(result.Variations)),
+		}
+		for _, variation := range result.Variations {
+			item.Variations = append(item.Variations, SearchVariationResponse{
+				VariationResponse: VariationResponse{
+					ID:           variation.ID,
+					ItemID:       variation.ItemID,
+					Sku:          variation.Sku,
+					Name:         variation.Name,
+					UnitID:       variation.UnitID,
+					Size:         variation.Size.String,
+					ColorID:      variation.ColorID.Int32,
+					Barcode:      variation.Barcode.String,
+					IsActive:     variation.IsActive.Bool,
+					ReorderLevel: variation.ReorderLevel.Int32,
+					BasePrice:    variation.BasePrice,
+					CostPrice:    costPrice(variation.CostPrice),
+				},
+				Matched: variation.Matched,
+			})
+		}
+		response = append(response, item)
+	}
+
+	utils.SuccessResponse(c, 200, "items found", pagination.Response(response, total))
+}
+
+type ItemDetailResponse struct {
+	ID          int32               `json:"id"`
+	BrandID     int32               `json:"brand_id"`
+	CategoryID  int32               `json:"category_id"`
+	Name        string              `json:"name"`
+	Description string              `json:"description"`
+	ItemType    string              `json:"item_type"`
+	IsActive    bool                `json:"is_active"`
+	Version     int32               `json:"version"`
+	Variations  []VariationResponse `json:"variations"`
+}
+
+// GetItem godoc
+// @Summary Get an item
+// @Description Get an item with its variations.
+// @Tags inventory
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "Item ID"
+// @Success 200 {object} ItemDetailResponse
+// @Failure 400
+// @Failure 401
+// @Failure 403
+// @Failure 404
+// @Failure 500
+// @Router /api/v1/inventory/item/{id} [get]
+func (h *Handler) getItem(c *gin.Context) {
+	claims, ok := jwt.GetUserFromContext(c)
+	if !ok {
+		h.logger.Errorf("could not get user from context")
+		utils.ErrorResponse(c, 500, utils.SERVERERROR)
+		return
+	}
+
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		h.logger.Errorf("get item id str conv err: %v", err)
+		utils.ErrorResponse(c, 400, utils.INVALID_REQUEST_DATA)
+		return
+	}
+
+	scope, ok := h.businessScope(c, claims)
+	if !ok {
+		return
+	}
+
+	item, err := h.service.GetItem(c, db.GetItemParams{ID: int32(id), BusinessID: scope})
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			utils.ErrorResponse(c, 404, fmt.Sprintf("item with id %d does not exist", id))
+			return
+		}
+		h.logger.Errorf("error getting item with id %d: %v", id, err)
+		utils.ErrorResponse(c, 500, utils.SERVERERROR)
+		return
+	}
+
+	variations, err := h.service.ListVariationsByItem(c, item.ID)
+	if err != nil {
+		h.logger.Errorf("error listing variations of item %d: %v", item.ID, err)
+		utils.ErrorResponse(c, 500, utils.SERVERERROR)
+		return
+	}
+
+	utils.SuccessResponse(c, 200, "item fetched", itemDetailResponse(item, variations))
+}
+
+func itemDetailResponse(item db.Item, variations []db.Variation) ItemDetailResponse {
+	response := ItemDetailResponse{
+		ID:          item.ID,
+		BrandID:     item.BrandID.Int32,
+		CategoryID:  item.CategoryID,
+		Name:        item.Name,
+		Description: item.Description.String,
+		ItemType:    item.ItemType,
+		IsActive:    item.IsActive.Bool,
+		Version:     item.Version,
+		Variations:  make([]VariationResponse, 0, len(variations)),
+	}
+	for _, variation := range variations {
+		response.Variations = append(response.Variations, VariationResponse{
+			ID:           variation.ID,
+			ItemID:       variation.ItemID,
+			Sku:          variation.Sku,
+			Name:         variation.Name,
+			UnitID:       variation.UnitID,
+			Size:         variation.Size.String,
+			ColorID:      variation.ColorID.Int32,
+			Barcode:      variation.Barcode.String,
+			IsActive:     variation.IsActive.Bool,
+			ReorderLevel: variation.ReorderLevel.Int32,
+			BasePrice:    variation.BasePrice,
+			CostPrice:    costPrice(variation.CostPrice),
+		})
+	}
+	return response
+}
+
+type DuplicateItemRequest struct {
+	Name string `json:"name" binding:"required,max=150" example:"Shoes (copy)"`
+}
+
+// DuplicateItem godoc
+// @Summary Duplicate an item
+// @Description Copy an item and its active variations under a new name. Variations get new SKUs, keep their prices and start without stock.
+// @Tags inventory
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "Item ID"
+// @Param body body DuplicateItemRequest true "name of the copy"
+// @Success 201 {object} ItemDetailResponse
+// @Failure 400
+// @Failure 401
+// @Failure 403
+// @Failure 404
+// @Failure 500
+// @Router /api/v1/inventory/item/{id}/duplicate [post]
+func (h *Handler) duplicateItem(c *gin.Context) {
+	claims, ok := jwt.GetUserFromContext(c)
+	if !ok {
+		h.logger.Errorf("could not get user from context")
+		utils.ErrorResponse(c, 500, utils.SERVERERROR)
+		return
+	}
+
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		h.logger.Errorf("duplicate item id str conv err: %v", err)
+		utils.ErrorResponse(c, 400, utils.INVALID_REQUEST_DATA)
+		return
+	}
+
+	var req DuplicateItemRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		h.logger.Errorf("error binding duplicate item request data: %v", err)
+		utils.BindingErrorResponse(c, err)
+		return
+	}
+
+	scope, ok := h.businessScope(c, claims)
+	if !ok {
+		return
+	}
+
+	item, variations, err := h.service.DuplicateItem(c, db.GetItemParams{ID: int32(id), BusinessID: scope}, req.Name)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			utils.ErrorResponse(c, 404, fmt.Sprintf("item with id %d does not exist", id))
+			return
+		}
+		h.logger.Errorf("error duplicating item with id %d: %v", id, err)
+		utils.ErrorResponse(c, 500, utils.SERVERERROR)
+		return
+	}
+
+	_, err = h.service.LogActivity(c, db.LogActivityParams{
+		UserID:     int32(claims.UserID),
+		Action:     "Duplicated Item",
+		EntityType: "Item",
+		EntityID:   item.ID,
+		Details:    utils.WriteActivityDetails(claims.Username, claims.Email, fmt.Sprintf("Duplicated item %d as %s with %d variations", id, item.Name, len(variations)), item.CreatedAt.Time),
+		IpAddress:  sql.NullString{Valid: true, String: utils.GetClientIP(c)},
+		UserAgent:  sql.NullString{Valid: true, String: c.Request.UserAgent()},
+	})
+	if err != nil {
+		h.logger.Warnf("error logging duplicate item activity: %v", err)
+	}
+
+	utils.SuccessResponse(c, 201, "item duplicated", itemDetailResponse(item, variations))
+}
+
+type UpdateItemRequest struct {
+	BrandID     *int32  `json:"brand_id" example:"3"`
+	CategoryID  *int32  `json:"category_id" example:"1"`
+	Name        *string `json:"name" example:"Shoes"`
+	Description *string `json:"description"`
+	ItemType    *string `json:"item_type" binding:"omitempty,oneof=fixed consumable raw_material for_sale" example:"for_sale"`
+	NoVariants  *bool   `json:"no_variants"`
+	IsActive    *bool   `json:"is_active"`
+	// version of the item the update was made from, the update is rejected
+	// when the item has changed since
+	Version *int32 `json:"version" binding:"omitempty,min=1" example:"3"`
+}
+
+type UpdateItemResponse struct {
+	ID          int32  `json:"id"`
+	BrandID     int32  `json:"brand_id"`
+	CategoryID  int32  `json:"category_id"`
+	Name        string `json:"name"`
+	Description string `json:"description"`
+	ItemType    string `json:"item_type"`
+	NoVariants  bool   `json:"no_variants"`
+	IsActive    bool   `json:"is_active"`
+	Version     int32  `json:"version"`
+}
+
+// UpdateItem godoc
+// @Summary Update an item
+// @Description Update an item, only the fields present in the body are changed. When version is given the update is only made if the item is still at that version, otherwise it fails with 409 and the item has to be fetched again.
+// @Tags inventory
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "Item ID"
+// @Param body body UpdateItemRequest true "item details"
+// @Success 200 {object} UpdateItemResponse
+// @Failure 400
+// @Failure 401
+// @Failure 403
+// @Failure 404
+// @Failure 409
+// @Failure 500
+// @Router /api/v1/inventory/item/{id} [patch]
+func (h *Handler) updateItem(c *gin.Context) {
+	claims, ok := jwt.GetUserFromContext(c)
+	if !ok {
+		h.logger.Errorf("could not get user from context")
+		utils.ErrorResponse(c, 500, utils.SERVERERROR)
+		return
+	}
+
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		h.logger.Errorf("update item id str conv err: %v", err)
+		utils.ErrorResponse(c, 400, utils.INVALID_REQUEST_DATA)
+		return
+	}
+
+	var req UpdateItemRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		h.logger.Errorf("error binding update item request data: %v", err)
+		utils.BindingErrorResponse(c, err)
+		return
+	}
+
+	scope, ok := h.businessScope(c, claims)
+	if !ok {
+		return
+	}
+
+	if req.BrandID != nil {
+		if _, err := h.service.GetBrand(c, db.GetBrandParams{ID: *req.BrandID, BusinessID: scope}); err != nil {
+			if errors.Is(err, sql.ErrNoRows) {
+				utils.ErrorResponse(c, 400, fmt.Sprintf("brand with id %d does not exist", *req.BrandID))
+				return
+			}
+			h.logger.Errorf("error getting brand with id %d: %v", *req.BrandID, err)
+			utils.ErrorResponse(c, 500, utils.SERVERERROR)
+			return
+		}
+	}
+
+	if req.CategoryID != nil {
+		if _, err := h.service.GetCategory(c, db.GetCategoryParams{ID: *req.CategoryID, BusinessID: scope}); err != nil {
+			if errors.Is(err, sql.ErrNoRows) {
+				utils.ErrorResponse(c, 400, fmt.Sprintf("category with id %d does not exist", *req.CategoryID))
+				return
+			}
+			h.logger.Errorf("error getting category with id %d: %v", *req.CategoryID, err)
+			utils.ErrorResponse(c, 500, utils.SERVERERROR)
+			return
+		}
+	}
+
+	params := db.UpdateItemParams{
+		ID:         int32(id),
+		BusinessID: scope,
+	}
+	utils.PatchNullInt32(&params.BrandID, req.BrandID)
+	utils.PatchNullInt32(&params.CategoryID, req.CategoryID)
+	utils.PatchNullString(&params.Name, req.Name)
+	utils.PatchNullString(&params.Description, req.Description)
+	utils.PatchNullString(&params.ItemType, req.ItemType)
+	utils.PatchNullBool(&params.NoVariants, req.NoVariants)
+	utils.PatchNullBool(&params.IsActive, req.IsActive)
+	utils.PatchNullInt32(&params.ExpectedVersion, req.Version)
+
+	item, err := h.service.UpdateItem(c, params)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			utils.ErrorResponse(c, 404, fmt.Sprintf("item with id %d does not exist", id))
+			return
+		}
+		if errors.Is(err, ErrRecordModified) {
+			utils.ErrorResponse(c, 409, err.Error())
+			return
+		}
+		h.logger.Errorf("error updating item with id %d: %v", id, err)
+		utils.ErrorResponse(c, 500, utils.SERVERERROR)
+		return
+	}
+
+	_, err = h.service.LogActivity(c, db.LogActivityParams{
+		UserID:     int32(claims.UserID),
+		Action:     "Updated Item",
+		EntityType: "Item",
+		EntityID:   item.ID,
+		Details:    utils.WriteActivityDetails(claims.Username, claims.Email, fmt.Sprintf("Updated item %s", item.Name), item.UpdatedAt.Time),
+		IpAddress:  sql.NullString{Valid: true, String: utils.GetClientIP(c)},
+		UserAgent:  sql.NullString{Valid: true, String: c.Request.UserAgent()},
+	})
+	if err != nil {
+		h.logger.Warnf("error logging update item activity: %v", err)
+	}
+
+	utils.SuccessResponse(c, 200, "item updated", UpdateItemResponse{
+		ID:          item.ID,
+		BrandID:     item.BrandID.Int32,
+		CategoryID:  item.CategoryID,
+		Name:        item.Name,
+		Description: item.Description.String,
+		ItemType:    item.ItemType,
+		NoVariants:  item.NoVariants.Bool,
+		IsActive:    item.IsActive.Bool,
+		Version:     item.Version,
+	})
+}
+
+// DeleteItem godoc
+// @Summary Delete an item
+// @Description Delete an item. Items with active variations can't be deleted, deactivate the variations first.
+// @Tags inventory
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "Item ID"
+// @Success 200
+// @Failure 400
+// @Failure 401
+// @Failure 403
+// @Failure 404
+// @Failure 409
+// @Failure 500
+// @Router /api/v1/inventory/item/{id} [delete]
+func (h *Handler) deleteItem(c *gin.Context) {
+	claims, ok := jwt.GetUserFromContext(c)
+	if !ok {
+		h.logger.Errorf("could not get user from context")
+		utils.ErrorResponse(c, 500, utils.SERVERERROR)
+		return
+	}
+
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		h.logger.Errorf("delete item id str conv err: %v", err)
+		utils.ErrorResponse(c, 400, utils.INVALID_REQUEST_DATA)
+		return
+	}
+
+	scope, ok := h.businessScope(c, claims)
+	if !ok {
+		return
+	}
+
+	item, err := h.service.GetItem(c, db.GetItemParams{ID: int32(id), BusinessID: scope})
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			utils.ErrorResponse(c, 404, fmt.Sprintf("item with id %d does not exist", id))
+			return
+		}
+		h.logger.Errorf("error getting item with id %d: %v", id, err)
+		utils.ErrorResponse(c, 500, utils.SERVERERROR)
+		return
+	}
+
+	err = h.service.DeleteItem(c, db.DeleteItemParams{ID: item.ID, BusinessID: scope})
+	if err != nil {
+		if errors.Is(err, ErrItemHasVariations) {
+			utils.ErrorResponse(c, 409, "item still has active variations, deactivate them before deleting the item")
+			return
+		}
+		if pgErr, ok := err.(*pq.Error); ok && pgErr.Code == "23503" { // foreign_key_violation
+			utils.ErrorResponse(c, 409, "item has been sold or stocked and can't be deleted, deactivate it instead")
+			return
+		}
+		h.logger.Errorf("error deleting item with id %d: %v", id, err)
+		utils.ErrorResponse(c, 500, utils.SERVERERROR)
+		return
+	}
+
+	_, err = h.service.LogActivity(c, db.LogActivityParams{
+		UserID:     int32(claims.UserID),
+		Action:     "Deleted Item",
+		EntityType: "Item",
+		EntityID:   item.ID,
+		Details:    utils.WriteActivityDetails(claims.Username, claims.Email, fmt.Sprintf("Deleted item %s", item.Name), time.Now()),
+		IpAddress:  sql.NullString{Valid: true, String: utils.GetClientIP(c)},
+		UserAgent:  sql.NullString{Valid: true, String: c.Request.UserAgent()},
+	})
+	if err != nil {
+		h.logger.Warnf("error logging delete item activity: %v", err)
+	}
+
+	utils.SuccessResponse(c, 200, "item deleted", nil)
+}
+
+type UnitRequest struct {
+	Name      string `json:"name" binding:"required" example:"kg"`
+	ShortCode string `json:"short_code"`
+}
+
+type UnitResponse struct {
+	ID        int32  `json:"id"`
+	Name      string `json:"name"`
+	ShortCode string `json:"short_code"`
+}
+
+// CreateUnit godoc
+// @Summary Create a unit
+// @Description Create an inventory unit.
+// @Tags inventory
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Success 201 {object} UnitResponse
+// @Param body body UnitRequest true "unit details"
+// @Failure 400
+// @Failure 401
+// @Failure 403
+// @Failure 500
+// @Router /api/v1/inventory/unit [post]
+func (h *Handler) createUnit(c *gin.Context) {
+	var req UnitRequest
+	if err := c.ShouldBind(&req); err != nil {
+		h.logger.Errorf("error binding creating unit request data: %v", err)
+		utils.BindingErrorResponse(c, err)
+		return
+	}
+
+	params := db.CreateUnitParams{
+		Name:      req.Name,
+		ShortCode: sql.NullString{String: req.ShortCode, Valid: req.ShortCode != ""},
+	}
+
+	unit, err := h.service.CreateUnit(c, params)
+	if err != nil {
+		h.logger.Errorf("error creating a unit: %v", err)
+		utils.ErrorResponse(c, 500, utils.SERVERERROR)
+		return
+	}
+
+	utils.SuccessResponse(c, 201, "unit created", UnitResponse{
+		Name:      unit.Name,
+		ShortCode: unit.ShortCode.String,
+		ID:        unit.ID,
+	})
+}
+
+type VariationRequest struct {
+	ItemID       int32  `json:"item_id" binding:"required" example:"1"`
+	Sku          string `json:"sku" binding:"omitempty" example:"GTR30l"`
+	Name         string `json:"name" binding:"required" example:"...."`
+	UnitID       int32  `json:"unit_id" binding:"required" example:"1"`
+	Size         string `json:"size" binding:"omitempty" example:"xl"`
+	ColorID      int32  `json:"color" binding:"omitempty" example:"1"`
+	Barcode      string `json:"barcode" binding:"omitempty" example:"..."`
+	ReorderLevel int32  `json:"reorder_level" binding:"omitempty" example:"5" default:"5"`
+	BasePrice    string `json:"base_price" binding:"required" example:"10.99"`
+	IsActive     bool   `json:"is_active" binding:"omitempty" default:"true"`
+	IsDefault    bool   `json:"is_default" binding:"omitempty" default:"false" example:"false"`
+}
+
+type VariationResponse struct {
+	ID           int32  `json:"id"`
+	ItemID       int32  `json:"item_id"`
+	Sku          string `json:"sku"`
+	Name         string `json:"name"`
+	UnitID       int32  `json:"unit_id"`
+	Size         string `json:"size"`
+	ColorID      int32  `json:"color_id"`
+	Barcode      string `json:"barcode"`
+	IsActive     bool   `json:"is_active"`
+	ReorderLevel int32  `json:"reorder_level"`
+	BasePrice    string `json:"base_price"`
+	// what the variation was last bought at, null while unknown
+	CostPrice *string `json:"cost_price"`
+}
+
+func costPrice(cost sql.NullString) *string {
+	if !cost.Valid {
+		return nil
+	}
+	return &cost.String
+}
+
+func safePrefix(s string, length int) string {
+	if len(s) < length {
+		return s
+	}
+	return s[:length]
+}
+
+// CreateVariant godoc
+// @Summary Create a variant
+// @Description Create an item variation. If sku is empty, system autogenerates it.
+// @Tags inventory
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Success 201 {object} VariationResponse
+// @Param body body VariationRequest true "variation details"
+// @Failure 400
+// @Failure 401
+// @Failure 403
+// @Failure 500
+// @Router /api/v1/inventory/variation [post]
+func (h *Handler) CreateVariation(c *gin.Context) {
+	claims, ok := jwt.GetUserFromContext(c)
+	if !ok {
+		h.logger.Errorf("could not get user from context")
+		utils.ErrorResponse(c, 500, utils.SERVERERROR)
+		return
+	}
+
+	var req VariationRequest
+	if err := c.ShouldBind(&req); err != nil {
+		h.logger.Errorf("error binding creating business request data: %v", err)
+		utils.BindingErrorResponse(c, err)
+		return
+	}
+
+	scope, ok := h.businessScope(c, claims)
+	if !ok {
+		return
+	}
+
+	_, err := h.service.GetItem(c, db.GetItemParams{ID: req.ItemID, BusinessID: scope})
+	if err == sql.ErrNoRows {
+		utils.ErrorResponse(c, 400, fmt.Sprintf("item with id %d does not exist", req.ItemID))
+		return
+	} else if err != nil {
+		h.logger.Errorf("error fetching item in create variation: %v", err)
+		utils.ErrorResponse(c, 500, utils.SERVERERROR)
+		return
+	}
+
+	// INFO: sku will be auto generated if empty
+	if req.Sku == "" {
+		var brand db.Brand
+		item, err := h.service.GetItem(c, db.GetItemParams{ID: req.ItemID, BusinessID: scope})
+		if err != nil {
+			utils.ErrorResponse(c, 500, err.Error())
+			return
+		}
+
+		category, err := h.service.GetCategory(c, db.GetCategoryParams{ID: item.CategoryID, BusinessID: scope})
+		if err != nil {
+			utils.ErrorResponse(c, 500, err.Error())
+			return
+		}
+
+		if item.BrandID.Valid && item.BrandID.Int32 != 0 {
+			brand, err = h.service.GetBrand(c, db.GetBrandParams{ID: item.BrandID.Int32, BusinessID: scope})
+			if err != nil {
+				h.logger.Errorf("error fetching brand in create variation: %v", err)
+				utils.ErrorResponse(c, 500, err.Error())
+				return
+			}
+
+			req.Sku = fmt.Sprintf("%s-%s-%s-%s",
+				safePrefix(category.Name, 3),
+				safePrefix(brand.Name, 2),
+				safePrefix(item.Name, 2),
+				safePrefix(req.Name, 2),
+			)
+		} else {
+			req.Sku = fmt.Sprintf("%s-%s-%s",
+				safePrefix(category.Name, 3),
+				safePrefix(item.Name, 2),
+				safePrefix(req.Name, 2),
+			)
+		}
+	}
+
+	params := db.CreateVariationParams{
+		Name:         req.Name,
+		ItemID:       req.ItemID,
+		Sku:          req.Sku,
+		UnitID:       req.UnitID,
+		Size:         sql.NullString{String: req.Size, Valid: req.Size != ""},
+		ColorID:      sql.NullInt32{Int32: req.ColorID, Valid: req.ColorID != 0},
+		Barcode:      sql.NullString{String: req.Barcode, Valid: req.Barcode != ""},
+		ReorderLevel: sql.NullInt32{Int32: req.ReorderLevel, Valid: req.ReorderLevel != 0},
+		BasePrice:    req.BasePrice,
+	}
+
+	variant, err := h.service.CreateVariation(c, params)
+	if err != nil {
+		if pgErr, ok := err.(*pq.Error); ok {
+			switch pgErr.Code {
+			case "23505": // unique_violation
+				utils.ErrorResponse(c, 400, fmt.Sprintf("variant with name %s already exists", req.Name))
+				return
+			}
+		}
+
+		h.logger.Errorf("error creating a variant: %v", err)
+		utils.ErrorResponse(c, 500, utils.SERVERERROR)
+		return
+	}
+
+	h.service.LogActivity(c, db.LogActivityParams{
+		UserID:     int32(claims.UserID),
+		EntityID:   variant.ID,
+		Action:     "Created Variant",
+		EntityType: "Variation",
+		Details:    utils.WriteActivityDetails(claims.Username, claims.Email, fmt.Sprintf("Created variant %s", variant.Name), variant.CreatedAt.Time),
+		IpAddress:  sql.NullString{Valid: true, String: utils.GetClientIP(c)},
+		UserAgent:  sql.NullString{Valid: true, String: c.Request.UserAgent()},
+	})
+
+	utils.SuccessResponse(c, 201, "variant created", VariationResponse{
+		ID:        variant.ID,
+		ItemID:    variant.ItemID,
+		Sku:       variant.Sku,
+		Name:      variant.Name,
+		UnitID:    variant.UnitID,
+		Size:      variant.Size.String,
+		ColorID:   variant.ColorID.Int32,
+		Barcode:   variant.Barcode.String,
+		BasePrice: variant.BasePrice,
+		IsActive:  variant.IsActive.Bool,
+	})
+}
+
+type ReserveStockRequest struct {
+	StoreID     int32 `json:"store_id" binding:"required" example:"1"`
+	VariationID int32 `json:"variation_id" binding:"required" example:"1"`
+	Quantity    int32 `json:"quantity" binding:"required,gt=0" example:"2"`
+}
+
+type ReservationResponse struct {
+	ID          int32     `json:"id"`
+	StoreID     int32     `json:"store_id"`
+	VariationID int32     `json:"variation_id"`
+	Quantity    int32     `json:"quantity"`
+	Status      string    `json:"status"`
+	ExpiresAt   time.Time `json:"expires_at"`
+}
+
+// ReserveStock godoc
+// @Summary Reserve stock
+// @Description Hold stock of a variation in a store of the business for a pending order. The reservation expires after the configured TTL unless it is consumed by a sale.
+// @Tags inventory
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Success 201 {object} ReservationResponse
+// @Param body body ReserveStockRequest true "reservation details"
+// @Failure 400
+// @Failure 401
+// @Failure 403
+// @Failure 409
+// @Failure 500
+// @Router /api/v1/inventory/reserve [post]
+func (h *Handler) reserveStock(c *gin.Context) {
+	claims, ok := jwt.GetUserFromContext(c)
+	if !ok {
+		h.logger.Errorf("could not get user from context")
+		utils.ErrorResponse(c, 500, utils.SERVERERROR)
+		return
+	}
+
+	var req ReserveStockRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		h.logger.Errorf("error binding reserve stock request data: %v", err)
+		utils.BindingErrorResponse(c, err)
+		return
+	}
+
+	scope, ok := h.businessScope(c, claims)
+	if !ok {
+		return
+	}
+
+	if !auth.RequireStore(c, req.StoreID) {
+		return
+	}
+	if _, err := h.service.GetStoreInBusiness(c, db.GetStoreInBusinessParams{ID: req.StoreID, BusinessID: scope}); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			utils.ErrorResponse(c, 400, fmt.Sprintf("store with id %d does not exist", req.StoreID))
+			return
+		}
+		h.logger.Errorf("error getting store with id %d: %v", req.StoreID, err)
+		utils.ErrorResponse(c, 500, utils.SERVERERROR)
+		return
+	}
+	if _, err := h.service.GetVariationInBusiness(c, db.GetVariationInBusinessParams{ID: req.VariationID, BusinessID: scope}); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			utils.ErrorResponse(c, 400, fmt.Sprintf("variation with id %d does not exist", req.VariationID))
+			return
+		}
+		h.logger.Errorf("error getting variation with id %d: %v", req.VariationID, err)
+		utils.ErrorResponse(c, 500, utils.SERVERERROR)
+		return
+	}
+
+	reservation, err := h.service.ReserveStock(c, ReserveStockParams{
+		StoreID:     req.StoreID,
+		VariationID: req.VariationID,
+		Quantity:    req.Quantity,
+		ReservedBy:  int32(claims.UserID),
+		ExpiresAt:   time.Now().Add(time.Duration(h.config.ReservationTTL) * time.Minute),
+	})
+	if err != nil {
+		if errors.Is(err, ErrInsufficientStock) {
+			utils.ErrorResponse(c, 409, "not enough stock available to reserve")
+			return
+		}
+
+		h.logger.Errorf("error reserving stock: %v", err)
+		utils.ErrorResponse(c, 500, utils.SERVERERROR)
+		return
+	}
+
+	h.service.LogActivity(c, db.LogActivityParams{
+		UserID:     int32(claims.UserID),
+		EntityID:   reservation.ID,
+		Action:     "Reserved Stock",
+		EntityType: "Reservation",
+		Details:    utils.WriteActivityDetails(claims.Username, claims.Email, fmt.Sprintf("Reserved %d of variation %d in store %d", reservation.Quantity, reservation.VariationID, reservation.StoreID), reservation.CreatedAt.Time),
+		IpAddress:  sql.NullString{Valid: true, String: utils.GetClientIP(c)},
+		UserAgent:  sql.NullString{Valid: true, String: c.Request.UserAgent()},
+	})
+
+	utils.SuccessResponse(c, 201, "stock reserved", ReservationResponse{
+		ID:          reservation.ID,
+		StoreID:     reservation.StoreID,
+		VariationID: reservation.VariationID,
+		Quantity:    reservation.Quantity,
+		Status:      reservation.Status,
+		ExpiresAt:   reservation.ExpiresAt,
+	})
+}
+
+type LowStockResponse struct {
+	StoreID       int32  `json:"store_id"`
+	StoreName     string `json:"store_name"`
+	StoreAddress  string `json:"store_address"`
+	VariationID   int32  `json:"variation_id"`
+	Sku           string `json:"sku"`
+	VariationName string `json:"variation_name"`
+	ItemName      string `json:"item_name"`
+	Quantity      int32  `json:"quantity"`
+	Threshold     int32  `json:"threshold"`
+	Deficit       int32  `json:"deficit"`
+}
+
+// ListLowStock godoc
+// @Summary List low stock
+// @Description List variations whose quantity in a store is at or below the business low stock threshold.
+// @Tags inventory
+// @Produce json
+// @Security BearerAuth
+// @Param store_id query int false "Only list stock of this store"
+// @Param threshold query int false "Use this threshold instead of the business one"
+// @Success 200 {object} []LowStockResponse
+// @Failure 400
+// @Failure 401
+// @Failure 403
+// @Failure 500
+// @Router /api/v1/inventory/low-stock [get]
+func (h *Handler) listLowStock(c *gin.Context) {
+	claims, ok := jwt.GetUserFromContext(c)
+	if !ok {
+		h.logger.Errorf("could not get user from context")
+		utils.ErrorResponse(c, 500, utils.SERVERERROR)
+		return
+	}
+
+	scope, ok := h.businessScope(c, claims)
+	if !ok {
+		return
+	}
+
+	params := db.ListLowStockVariationsParams{BusinessID: scope}
+	if sid := c.Query("store_id"); sid != "" {
+		id, err := strconv.Atoi(sid)
+		if err != nil {
+			utils.ErrorResponse(c, 400, utils.INVALID_REQUEST_DATA)
+			return
+		}
+		params.StoreID = sql.NullInt32{Int32: int32(id), Valid: true}
+	}
+	if t := c.Query("threshold"); t != "" {
+		threshold, err := strconv.Atoi(t)
+		if err != nil || threshold < 0 {
+			utils.ErrorResponse(c, 400, utils.INVALID_REQUEST_DATA)
+			return
+		}
+		params.Threshold = sql.NullInt32{Int32: int32(threshold), Valid: true}
+	}
+
+	rows, err := h.service.ListLowStockVariations(c, params)
+	if err != nil {
+		h.logger.Errorf("error listing low stock: %v", err)
+		utils.ErrorResponse(c, 500, utils.SERVERERROR)
+		return
+	}
+
+	response := make([]LowStockResponse, 0, len(rows))
+	for _, row := range rows {
+		response = append(response, LowStockResponse{
+			StoreID:       row.StoreID,
+			StoreName:     row.StoreName,
+			StoreAddress:  row.StoreAddress,
+			VariationID:   row.VariationID,
+			Sku:           row.Sku,
+			VariationName: row.VariationName,
+			ItemName:      row.ItemName,
+			Quantity:      row.Quantity,
+			Threshold:     row.Threshold,
+			Deficit:       row.Threshold - row.Quantity,
+		})
+	}
+
+	utils.SuccessResponse(c, 200, "low stock fetched", response)
+}
+
+type ReceiveBatchRequest struct {
+	StoreID     int32  `json:"store_id" binding:"required" example:"1"`
+	VariationID int32  `json:"variation_id" binding:"required" example:"1"`
+	LotNumber   string `json:"lot_number" binding:"required,max=50" example:"LOT-2024-001"`
+	ExpiryDate  string `json:"expiry_date" binding:"required" example:"2024-12-31"`
+	Quantity    int32  `json:"quantity" binding:"required,gt=0" example:"24"`
+}
+
+type BatchResponse struct {
+	ID            int32  `json:"id"`
+	StoreID       int32  `json:"store_id"`
+	VariationID   int32  `json:"variation_id"`
+	LotNumber     string `json:"lot_number"`
+	ExpiryDate    string `json:"expiry_date"`
+	Quantity      int32  `json:"quantity"`
+	StoreQuantity int32  `json:"store_quantity"`
+}
+
+// ReceiveBatch godoc
+// @Summary Receive a batch
+// @Description Record a lot of a variation received into a store with its expiry date. The quantity is added to the store's stock.
+// @Tags inventory
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param body body ReceiveBatchRequest true "batch details"
+// @Success 201 {object} BatchResponse
+// @Failure 400
+// @Failure 401
+// @Failure 403
+// @Failure 500
+// @Router /api/v1/inventory/batch [post]
+func (h *Handler) receiveBatch(c *gin.Context) {
+	claims, ok := jwt.GetUserFromContext(c)
+	if !ok {
+		h.logger.Errorf("could not get user from context")
+		utils.ErrorResponse(c, 500, utils.SERVERERROR)
+		return
+	}
+
+	var req ReceiveBatchRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		h.logger.Errorf("error binding receive batch request data: %v", err)
+		utils.BindingErrorResponse(c, err)
+		return
+	}
+
+	expiry, err := time.Parse("2006-01-02", req.ExpiryDate)
+	if err != nil {
+		utils.ErrorResponse(c, 400, "expiry_date must be a date like 2024-12-31")
+		return
+	}
+
+	scope, ok := h.businessScope(c, claims)
+	if !ok {
+		return
+	}
+
+	if !auth.RequireStore(c, req.StoreID) {
+		return
+	}
+	if _, err := h.service.GetStoreInBusiness(c, db.GetStoreInBusinessParams{ID: req.StoreID, BusinessID: scope}); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			utils.ErrorResponse(c, 400, fmt.Sprintf("store with id %d does not exist", req.StoreID))
+			return
+		}
+		h.logger.Errorf("error getting store with id %d: %v", req.StoreID, err)
+		utils.ErrorResponse(c, 500, utils.SERVERERROR)
+		return
+	}
+
+	if _, err := h.service.GetVariationInBusiness(c, db.GetVariationInBusinessParams{ID: req.VariationID, BusinessID: scope}); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			utils.ErrorResponse(c, 400, fmt.Sprintf("variation with id %d does not exist", req.VariationID))
+			return
+		}
+		h.logger.Errorf("error getting variation with id %d: %v", req.VariationID, err)
+		utils.ErrorResponse(c, 500, utils.SERVERERROR)
+		return
+	}
+
+	batch, stock, err := h.service.ReceiveBatch(c, db.ReceiveInventoryBatchParams{
+		StoreID:     req.StoreID,
+		VariationID: req.VariationID,
+		LotNumber:   req.LotNumber,
+		ExpiryDate:  expiry,
+		Quantity:    req.Quantity,
+	})
+	if err != nil {
+		h.logger.Errorf("error receiving batch: %v", err)
+		utils.ErrorResponse(c, 500, utils.SERVERERROR)
+		return
+	}
+
+	_, err = h.service.LogActivity(c, db.LogActivityParams{
+		UserID:     int32(claims.UserID),
+		Action:     "Received Batch",
+		EntityType: "InventoryBatch",
+		EntityID:   batch.ID,
+		Details:    utils.WriteActivityDetails(claims.Username, claims.Email, fmt.Sprintf("Received %d of variation %d lot %s into store %d", req.Quantity, batch.VariationID, batch.LotNumber, batch.StoreID), batch.UpdatedAt.Time),
+		IpAddress:  sql.NullString{Valid: true, String: utils.GetClientIP(c)},
+		UserAgent:  sql.NullString{Valid: true, String: c.Request.UserAgent()},
+	})
+	if err != nil {
+		h.logger.Warnf("error logging receive batch activity: %v", err)
+	}
+
+	utils.SuccessResponse(c, 201, "batch received", BatchResponse{
+		ID:            batch.ID,
+		StoreID:       batch.StoreID,
+		VariationID:   batch.VariationID,
+		LotNumber:     batch.LotNumber,
+		ExpiryDate:    batch.ExpiryDate.Format("2006-01-02"),
+		Quantity:      batch.Quantity,
+		StoreQuantity: stock.Quantity,
+	})
+}
+
+type ExpiringBatchResponse struct {
+	BatchID       int32  `json:"batch_id"`
+	StoreID       int32  `json:"store_id"`
+	StoreName     string `json:"store_name"`
+	VariationID   int32  `json:"variation_id"`
+	Sku           string `json:"sku"`
+	VariationName string `json:"variation_name"`
+	ItemName      string `json:"item_name"`
+	LotNumber     string `json:"lot_number"`
+	ExpiryDate    string `json:"expiry_date"`
+	Quantity      int32  `json:"quantity"`
+	DaysLeft      int32  `json:"days_left"`
+}
+
+// ListExpiring godoc
+// @Summary List expiring stock
+// @Description List batches with stock left that expire within the window, soonest first. Days are counted in the business timezone and already expired batches have negative days_left.
+// @Tags inventory
+// @Produce json
+// @Security BearerAuth
+// @Param within_days query int false "Window in days, defaults to 7"
+// @Param store_id query int false "Only list batches of this store"
+// @Success 200 {object} []ExpiringBatchResponse
+// @Failure 400
+// @Failure 401
+// @Failure 403
+// @Failure 500
+// @Router /api/v1/inventory/expiring [get]
+func (h *Handler) listExpiring(c *gin.Context) {
+	claims, ok := jwt.GetUserFromContext(c)
+	if !ok {
+		h.logger.Errorf("could not get user from context")
+		utils.ErrorResponse(c, 500, utils.SERVERERROR)
+		return
+	}
+
+	withinDays, err := strconv.Atoi(c.DefaultQuery("within_days", "7"))
+	if err != nil || withinDays < 0 || withinDays > 365 {
+		utils.ErrorResponse(c, 400, "within_days must be between 0 and 365")
+		return
+	}
+
+	scope, ok := h.businessScope(c, claims)
+	if !ok {
+		return
+	}
+
+	params := db.ListExpiringBatchesParams{WithinDays: int32(withinDays), BusinessID: scope}
+	if sid := c.Query("store_id"); sid != "" {
+		id, err := strconv.Atoi(sid)
+		if err != nil {
+			utils.ErrorResponse(c, 400, utils.INVALID_REQUEST_DATA)
+			return
+		}
+		params.StoreID = sql.NullInt32{Int32: int32(id), Valid: true}
+	}
+
+	rows, err := h.service.ListExpiringBatches(c, params)
+	if err != nil {
+		h.logger.Errorf("error listing expiring batches: %v", err)
+		utils.ErrorResponse(c, 500, utils.SERVERERROR)
+		return
+	}
+
+	response := make([]ExpiringBatchResponse, 0, len(rows))
+	for _, row := range rows {
+		response = append(response, ExpiringBatchResponse{
+			BatchID:       row.ID,
+			StoreID:       row.StoreID,
+			StoreName:     row.StoreName,
+			VariationID:   row.VariationID,
+			Sku:           row.Sku,
+			VariationName: row.VariationName,
+			ItemName:      row.ItemName,
+			LotNumber:     row.LotNumber,
+			ExpiryDate:    row.ExpiryDate.Format("2006-01-02"),
+			Quantity:      row.Quantity,
+			DaysLeft:      row.DaysLeft,
+		})
+	}
+
+	utils.SuccessResponse(c, 200, "expiring stock fetched", response)
+}
+
+type CycleCountLineRequest struct {
+	VariationID     int32  `json:"variation_id" binding:"required" example:"1"`
+	CountedQuantity *int32 `json:"counted_quantity" binding:"required,gte=0" example:"10"`
+}
+
+type CycleCountRequest struct {
+	StoreID int32                   `json:"store_id" binding:"required" example:"1"`
+	Lines   []CycleCountLineRequest `json:"lines" binding:"required,min=1,dive"`
+	// adjust stock to the counted quantities, otherwise only report variances
+	Confirm bool `json:"confirm" example:"false"`
+}
+
+type CycleCountLineResponse struct {
+	VariationID     int32 `json:"variation_id"`
+	SystemQuantity  int32 `json:"system_quantity"`
+	CountedQuantity int32 `json:"counted_quantity"`
+	Variance        int32 `json:"variance"`
+	// set when the count was confirmed and stock was adjusted
+	AdjustmentID *int32 `json:"adjustment_id"`
+}
+
+type CycleCountResponse struct {
+	StoreID       int32                    `json:"store_id"`
+	Confirmed     bool                     `json:"confirmed"`
+	Discrepancies int                      `json:"discrepancies"`
+	Lines         []CycleCountLineResponse `json:"lines"`
+}
+
+// CycleCount godoc
+// @Summary Cycle count
+// @Description Compare counted quantities with the stock on record in a store and report the variances. Nothing changes unless confirm is set, then stock is adjusted to the counted quantities and each correction is recorded as a cycle_count adjustment.
+// @Tags inventory
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param body body CycleCountRequest true "counted quantities"
+// @Success 200 {object} CycleCountResponse
+// @Failure 400
+// @Failure 401
+// @Failure 403
+// @Failure 500
+// @Router /api/v1/inventory/cycle-count [post]
+func (h *Handler) cycleCount(c *gin.Context) {
+	claims, ok := jwt.GetUserFromContext(c)
+	if !ok {
+		h.logger.Errorf("could not get user from context")
+		utils.ErrorResponse(c, 500, utils.SERVERERROR)
+		return
+	}
+
+	var req CycleCountRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		h.logger.Errorf("error binding cycle count request data: %v", err)
+		utils.BindingErrorResponse(c, err)
+		return
+	}
+
+	scope, ok := h.businessScope(c, claims)
+	if !ok {
+		return
+	}
+
+	if !auth.RequireStore(c, req.StoreID) {
+		return
+	}
+	if _, err := h.service.GetStoreInBusiness(c, db.GetStoreInBusinessParams{ID: req.StoreID, BusinessID: scope}); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			utils.ErrorResponse(c, 400, fmt.Sprintf("store with id %d does not exist", req.StoreID))
+			return
+		}
+		h.logger.Errorf("error getting store with id %d: %v", req.StoreID, err)
+		utils.ErrorResponse(c, 500, utils.SERVERERROR)
+		return
+	}
+
+	lines := make([]CycleCountLine, 0, len(req.Lines))
+	for _, line := range req.Lines {
+		if _, err := h.service.GetVariationInBusiness(c, db.GetVariationInBusinessParams{ID: line.VariationID, BusinessID: scope}); err != nil {
+			if errors.Is(err, sql.ErrNoRows) {
+				utils.ErrorResponse(c, 400, fmt.Sprintf("variation with id %d does not exist", line.VariationID))
+				return
+			}
+			h.logger.Errorf("error getting variation with id %d: %v", line.VariationID, err)
+			utils.ErrorResponse(c, 500, utils.SERVERERROR)
+			return
+		}
+		lines = append(lines, CycleCountLine{VariationID: line.VariationID, CountedQuantity: *line.CountedQuantity})
+	}
+
+	results, err := h.service.CycleCount(c, CycleCountParams{
+		StoreID:   req.StoreID,
+		CountedBy: int32(claims.UserID),
+		Lines:     lines,
+		Confirm:   req.Confirm,
+	})
+	if err != nil {
+		if errors.Is(err, ErrDuplicateCountItem) {
+			utils.ErrorResponse(c, 400, "each variation can only be counted once")
+			return
+		}
+		h.logger.Errorf("error running cycle count: %v", err)
+		utils.ErrorResponse(c, 500, utils.SERVERERROR)
+		return
+	}
+
+	response := CycleCountResponse{
+		StoreID:   req.StoreID,
+		Confirmed: req.Confirm,
+		Lines:     make([]CycleCountLineResponse, 0, len(results)),
+	}
+	for _, r := range results {
+		line := CycleCountLineResponse{
+			VariationID:     r.VariationID,
+			SystemQuantity:  r.SystemQuantity,
+			CountedQuantity: r.CountedQuantity,
+			Variance:        r.Variance,
+		}
+		if r.Adjustment != nil {
+			line.AdjustmentID = &r.Adjustment.ID
+		}
+		if r.Variance != 0 {
+			response.Discrepancies++
+		}
+		response.Lines = append(response.Lines, line)
+	}
+
+	if req.Confirm {
+		_, err = h.service.LogActivity(c, db.LogActivityParams{
+			UserID:     int32(claims.UserID),
+			Action:     "Cycle Count",
+			EntityType: "Store",
+			EntityID:   req.StoreID,
+			Details:    utils.WriteActivityDetails(claims.Username, claims.Email, fmt.Sprintf("Counted %d variations in store %d and adjusted %d", len(results), req.StoreID, response.Discrepancies), time.Now()),
+			IpAddress:  sql.NullString{Valid: true, String: utils.GetClientIP(c)},
+			UserAgent:  sql.NullString{Valid: true, String: c.Request.UserAgent()},
+		})
+		if err != nil {
+			h.logger.Warnf("error logging cycle count activity: %v", err)
+		}
+	}
+
+	utils.SuccessResponse(c, 200, "cycle count", response)
+}
+
+type AdjustStockRequest struct {
+	StoreID     int32 `json:"store_id" binding:"required" example:"1"`
+	VariationID int32 `json:"variation_id" binding:"required" example:"1"`
+	// change to the stock on hand, negative to write stock off
+	Delta  int32  `json:"delta" binding:"required" example:"-2"`
+	Reason string `json:"reason" binding:"required,oneof=damage theft recount restock" example:"damage"`
+}
+
+type AdjustStockResponse struct {
+	AdjustmentID     int32  `json:"adjustment_id"`
+	StoreID          int32  `json:"store_id"`
+	VariationID      int32  `json:"variation_id"`
+	PreviousQuantity int32  `json:"previous_quantity"`
+	Delta            int32  `json:"delta"`
+	Quantity         int32  `json:"quantity"`
+	Reason           string `json:"reason"`
+}
+
+// AdjustStock godoc
+// @Summary Adjust stock
+// @Description Change the stock of a variation in a store by hand, e.g. for spoilage or a recount, and record the change as an adjustment. damage and theft can only lower stock, restock can only raise it. Stock cannot go below zero unless the business allows overselling.
+// @Tags inventory
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param body body AdjustStockRequest true "adjustment"
+// @Success 201 {object} AdjustStockResponse
+// @Failure 400
+// @Failure 401
+// @Failure 403
+// @Failure 409
+// @Failure 422
+// @Failure 500
+// @Router /api/v1/inventory/adjust [post]
+func (h *Handler) adjustStock(c *gin.Context) {
+	claims, ok := jwt.GetUserFromContext(c)
+	if !ok {
+		h.logger.Errorf("could not get user from context")
+		utils.ErrorResponse(c, 500, utils.SERVERERROR)
+		return
+	}
+
+	var req AdjustStockRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		h.logger.Errorf("error binding stock adjustment request data: %v", err)
+		utils.BindingErrorResponse(c, err)
+		return
+	}
+
+	scope, ok := h.businessScope(c, claims)
+	if !ok {
+		return
+	}
+
+	if !auth.RequireStore(c, req.StoreID) {
+		return
+	}
+	if _, err := h.service.GetStoreInBusiness(c, db.GetStoreInBusinessParams{ID: req.StoreID, BusinessID: scope}); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			utils.ErrorResponse(c, 400, fmt.Sprintf("store with id %d does not exist", req.StoreID))
+			return
+		}
+		h.logger.Errorf("error getting store with id %d: %v", req.StoreID, err)
+		utils.ErrorResponse(c, 500, utils.SERVERERROR)
+		return
+	}
+	if _, err := h.service.GetVariationInBusiness(c, db.GetVariationInBusinessParams{ID: req.VariationID, BusinessID: scope}); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			utils.ErrorResponse(c, 400, fmt.Sprintf("variation with id %d does not exist", req.VariationID))
+			return
+		}
+		h.logger.Errorf("error getting variation with id %d: %v", req.VariationID, err)
+		utils.ErrorResponse(c, 500, utils.SERVERERROR)
+		return
+	}
+
+	adjustment, stock, err := h.service.AdjustStock(c, AdjustStockParams{
+		StoreID:     req.StoreID,
+		VariationID: req.VariationID,
+		Delta:       req.Delta,
+		Reason:      req.Reason,
+		AdjustedBy:  int32(claims.UserID),
+	})
+	if err != nil {
+		switch {
+		case errors.Is(err, ErrInvalidAdjustmentReason):
+			utils.ErrorResponse(c, 400, fmt.Sprintf("invalid adjustment reason %q", req.Reason))
+		case errors.Is(err, ErrAdjustmentDirection):
+			utils.ErrorResponse(c, 400, fmt.Sprintf("a %s adjustment cannot have a delta of %d", req.Reason, req.Delta))
+		case errors.Is(err, ErrNegativeStock):
+			utils.ErrorResponse(c, 409, "adjustment would leave negative stock")
+		default:
+			h.logger.Errorf("error adjusting stock: %v", err)
+			utils.ErrorResponse(c, 500, utils.SERVERERROR)
+		}
+		return
+	}
+
+	_, err = h.service.LogActivity(c, db.LogActivityParams{
+		UserID:     int32(claims.UserID),
+		Action:     "Adjust Stock",
+		EntityType: "Store",
+		EntityID:   req.StoreID,
+		Details:    utils.WriteActivityDetails(claims.Username, claims.Email, fmt.Sprintf("Adjusted variation %d in store %d by %d (%s)", req.VariationID, req.StoreID, req.Delta, req.Reason), time.Now()),
+		IpAddress:  sql.NullString{Valid: true, String: utils.GetClientIP(c)},
+		UserAgent:  sql.NullString{Valid: true, String: c.Request.UserAgent()},
+	})
+	if err != nil {
+		h.logger.Warnf("error logging stock adjustment activity: %v", err)
+	}
+
+	utils.SuccessResponse(c, 201, "stock adjusted", AdjustStockResponse{
+		AdjustmentID:     adjustment.ID,
+		StoreID:          adjustment.StoreID,
+		VariationID:      adjustment.VariationID,
+		PreviousQuantity: adjustment.PreviousQuantity,
+		Delta:            adjustment.Quantity,
+		Quantity:         stock.Quantity,
+		Reason:           adjustment.Reason,
+	})
+}
+
+type SetMinKeepRequest struct {
+	StoreID     int32  `json:"store_id" binding:"required" example:"1"`
+	VariationID int32  `json:"variation_id" binding:"required" example:"1"`
+	MinKeep     *int32 `json:"min_keep" binding:"required,gte=0" example:"5"`
+}
+
+type MinKeepResponse struct {
+	StoreID     int32 `json:"store_id"`
+	VariationID int32 `json:"variation_id"`
+	Quantity    int32 `json:"quantity"`
+	MinKeep     int32 `json:"min_keep"`
+}
+
+// SetMinKeep godoc
+// @Summary Set minimum stock
+// @Description Set the stock of a variation a store keeps for itself. Transfers out of the store that would go below it are refused unless overridden. 0 removes the minimum.
+// @Tags inventory
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param body body SetMinKeepRequest true "minimum stock"
+// @Success 200 {object} MinKeepResponse
+// @Failure 400
+// @Failure 401
+// @Failure 403
+// @Failure 500
+// @Router /api/v1/inventory/min-keep [put]
+func (h *Handler) setMinKeep(c *gin.Context) {
+	claims, ok := jwt.GetUserFromContext(c)
+	if !ok {
+		h.logger.Errorf("could not get user from context")
+		utils.ErrorResponse(c, 500, utils.SERVERERROR)
+		return
+	}
+
+	var req SetMinKeepRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		h.logger.Errorf("error binding min keep request data: %v", err)
+		utils.BindingErrorResponse(c, err)
+		return
+	}
+
+	scope, ok := h.businessScope(c, claims)
+	if !ok {
+		return
+	}
+
+	if !auth.RequireStore(c, req.StoreID) {
+		return
+	}
+	if _, err := h.service.GetStoreInBusiness(c, db.GetStoreInBusinessParams{ID: req.StoreID, BusinessID: scope}); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			utils.ErrorResponse(c, 400, fmt.Sprintf("store with id %d does not exist", req.StoreID))
+			return
+		}
+		h.logger.Errorf("error getting store with id %d: %v", req.StoreID, err)
+		utils.ErrorResponse(c, 500, utils.SERVERERROR)
+		return
+	}
+
+	if _, err := h.service.GetVariationInBusiness(c, db.GetVariationInBusinessParams{ID: req.VariationID, BusinessID: scope}); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			utils.ErrorResponse(c, 400, fmt.Sprintf("variation with id %d does not exist", req.VariationID))
+			return
+		}
+		h.logger.Errorf("error getting variation with id %d: %v", req.VariationID, err)
+		utils.ErrorResponse(c, 500, utils.SERVERERROR)
+		return
+	}
+
+	stock, err := h.service.SetInventoryMinKeep(c, db.SetInventoryMinKeepParams{
+		StoreID:     req.StoreID,
+		VariationID: req.VariationID,
+		MinKeep:     *req.MinKeep,
+	})
+	if err != nil {
+		h.logger.Errorf("error setting min keep: %v", err)
+		utils.ErrorResponse(c, 500, utils.SERVERERROR)
+		return
+	}
+
+	_, err = h.service.LogActivity(c, db.LogActivityParams{
+		UserID:     int32(claims.UserID),
+		Action:     "Set Minimum Stock",
+		EntityType: "Inventory",
+		EntityID:   stock.ID,
+		Details:    utils.WriteActivityDetails(claims.Username, claims.Email, fmt.Sprintf("Set minimum stock of variation %d in store %d to %d", stock.VariationID, stock.StoreID, stock.MinKeep), stock.LastUpdated.Time),
+		IpAddress:  sql.NullString{Valid: true, String: utils.GetClientIP(c)},
+		UserAgent:  sql.NullString{Valid: true, String: c.Request.UserAgent()},
+	})
+	if err != nil {
+		h.logger.Warnf("error logging min keep activity: %v", err)
+	}
+
+	utils.SuccessResponse(c, 200, "minimum stock set", MinKeepResponse{
+		StoreID:     stock.StoreID,
+		VariationID: stock.VariationID,
+		Quantity:    stock.Quantity,
+		MinKeep:     stock.MinKeep,
+	})
+}
+
+type SetVariationPriceRequest struct {
+	BasePrice string `json:"base_price" binding:"required" example:"10.99"`
+}
+
+// SetVariationPrice godoc
+// @Summary Set variation price
+// @Description Change the base price of a variation. The change is recorded in its price history when tracking is enabled.
+// @Tags inventory
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "Variation ID"
+// @Param body body SetVariationPriceRequest true "new price"
+// @Success 200 {object} VariationResponse
+// @Failure 400
+// @Failure 401
+// @Failure 403
+// @Failure 404
+// @Failure 500
+// @Router /api/v1/inventory/variation/{id}/price [put]
+func (h *Handler) setVariationPrice(c *gin.Context) {
+	claims, ok := jwt.GetUserFromContext(c)
+	if !ok {
+		h.logger.Errorf("could not get user from context")
+		utils.ErrorResponse(c, 500, utils.SERVERERROR)
+		return
+	}
+
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		utils.ErrorResponse(c, 400, "invalid variation id")
+		return
+	}
+
+	var req SetVariationPriceRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		h.logger.Errorf("error binding variation price request data: %v", err)
+		utils.BindingErrorResponse(c, err)
+		return
+	}
+
+	if !h.variationInScope(c, claims, int32(id)) {
+		return
+	}
+
+	variation, err := h.service.SetVariationPrice(c, SetVariationPriceParams{
+		VariationID:   int32(id),
+		BasePrice:     req.BasePrice,
+		ChangedBy:     int32(claims.UserID),
+		RecordHistory: h.config.PriceHistory,
+	})
+	if err != nil {
+		if errors.Is(err, ErrInvalidPrice) {
+			utils.ErrorResponse(c, 400, err.Error())
+			return
+		}
+		h.logger.Errorf("error setting price of variation %d: %v", id, err)
+		utils.ErrorResponse(c, 500, utils.SERVERERROR)
+		return
+	}
+
+	_, err = h.service.LogActivity(c, db.LogActivityParams{
+		UserID:     int32(claims.UserID),
+		Action:     "Set Variation Price",
+		EntityType: "Variation",
+		EntityID:   variation.ID,
+		Details:    utils.WriteActivityDetails(claims.Username, claims.Email, fmt.Sprintf("Set price of variation %d to %s", variation.ID, variation.BasePrice), variation.UpdatedAt.Time),
+		IpAddress:  sql.NullString{Valid: true, String: utils.GetClientIP(c)},
+		UserAgent:  sql.NullString{Valid: true, String: c.Request.UserAgent()},
+	})
+	if err != nil {
+		h.logger.Warnf("error logging variation price activity: %v", err)
+	}
+
+	utils.SuccessResponse(c, 200, "price updated", VariationResponse{
+		ID:           variation.ID,
+		ItemID:       variation.ItemID,
+		Sku:          variation.Sku,
+		Name:         variation.Name,
+		UnitID:       variation.UnitID,
+		Size:         variation.Size.String,
+		ColorID:      variation.ColorID.Int32,
+		Barcode:      variation.Barcode.String,
+		IsActive:     variation.IsActive.Bool,
+		ReorderLevel: variation.ReorderLevel.Int32,
+		BasePrice:    variation.BasePrice,
+		CostPrice:    costPrice(variation.CostPrice),
+	})
+}
+
+type PriceHistoryResponse struct {
+	ID        int32     `json:"id"`
+	OldPrice  string    `json:"old_price"`
+	NewPrice  string    `json:"new_price"`
+	ChangedBy int32     `json:"changed_by"`
+	ChangedAt time.Time `json:"changed_at"`
+}
+
+// ListPriceHistory godoc
+// @Summary List price history
+// @Description List the base price changes of a variation, oldest first.
+// @Tags inventory
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "Variation ID"
+// @Success 200 {object} []PriceHistoryResponse
+// @Failure 400
+// @Failure 401
+// @Failure 403
+// @Failure 404
+// @Failure 500
+// @Router /api/v1/inventory/variation/{id}/price-history [get]
+func (h *Handler) listPriceHistory(c *gin.Context) {
+	claims, ok := jwt.GetUserFromContext(c)
+	if !ok {
+		h.logger.Errorf("could not get user from context")
+		utils.ErrorResponse(c, 500, utils.SERVERERROR)
+		return
+	}
+
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		utils.ErrorResponse(c, 400, "invalid variation id")
+		return
+	}
+
+	if !h.variationInScope(c, claims, int32(id)) {
+		return
+	}
+
+	history, err := h.service.ListPriceHistory(c, int32(id))
+	if err != nil {
+		h.logger.Errorf("error listing price history of variation %d: %v", id, err)
+		utils.ErrorResponse(c, 500, utils.SERVERERROR)
+		return
+	}
+
+	response := make([]PriceHistoryResponse, 0, len(history))
+	for _, entry := range history {
+		response = append(response, PriceHistoryResponse{
+			ID:        entry.ID,
+			OldPrice:  entry.OldPrice,
+			NewPrice:  entry.NewPrice,
+			ChangedBy: entry.ChangedBy,
+			ChangedAt: entry.ChangedAt,
+		})
+	}
+
+	utils.SuccessResponse(c, 200, "price history", response)
+}
+
+// variationInScope responds with 404 unless the variation belongs to the
+// business of the request.
+func (h *Handler) variationInScope(c *gin.Context, claims *jwt.Claims, id int32) bool {
+	scope, ok := h.businessScope(c, claims)
+	if !ok {
+		return false
+	}
+
+	if _, err := h.service.GetVariationInBusiness(c, db.GetVariationInBusinessParams{ID: id, BusinessID: scope}); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			utils.ErrorResponse(c, 404, fmt.Sprintf("variation with id %d does not exist", id))
+			return false
+		}
+		h.logger.Errorf("error getting variation with id %d: %v", id, err)
+		utils.ErrorResponse(c, 500, utils.SERVERERROR)
+		return false
+	}
+	return true
+}
+
+type PurchaseOrderLineRequest struct {
+	VariationID int32  `json:"variation_id" binding:"required" example:"1"`
+	Quantity    int32  `json:"quantity" binding:"required,gt=0" example:"24"`
+	UnitCost    string `json:"unit_cost" binding:"required" example:"4.50"`
+}
+
+type CreatePurchaseOrderRequest struct {
+	StoreID   int32                      `json:"store_id" binding:"required" example:"1"`
+	Supplier  string                     `json:"supplier" binding:"required,max=255" example:"Acme Beverages"`
+	Reference string                     `json:"reference" binding:"omitempty,max=100" example:"INV-1042"`
+	Notes     string                     `json:"notes" binding:"omitempty" example:"Deliver to the back entrance"`
+	Lines     []PurchaseOrderLineRequest `json:"lines" binding:"required,min=1,dive"`
+}
+
+type UpdatePurchaseOrderRequest struct {
+	Supplier  *string `json:"supplier" binding:"omitempty,min=1,max=255" example:"Acme Beverages"`
+	Reference *string `json:"reference" binding:"omitempty,max=100" example:"INV-1042"`
+	Notes     *string `json:"notes" example:"Deliver to the back entrance"`
+	// replaces every line of the order
+	Lines []PurchaseOrderLineRequest `json:"lines" binding:"omitempty,min=1,dive"`
+	// ordered places the order with the supplier, it can't be changed after
+	Status string `json:"status" binding:"omitempty,oneof=ordered" example:"ordered"`
+}
+
+type PurchaseReceiptLineRequest struct {
+	VariationID int32 `json:"variation_id" binding:"required" example:"1"`
+	Quantity    int32 `json:"quantity" binding:"required,gt=0" example:"12"`
+}
+
+type ReceivePurchaseOrderRequest struct {
+	// what was delivered, leave empty to receive everything outstanding
+	Lines []PurchaseReceiptLineRequest `json:"lines" binding:"omitempty,dive"`
+}
+
+type CancelPurchaseOrderRequest struct {
+	Reason string `json:"reason" binding:"omitempty,max=500" example:"Supplier is out of stock"`
+}
+
+type PurchaseOrderLineResponse struct {
+	VariationID      int32  `json:"variation_id"`
+	Quantity         int32  `json:"quantity"`
+	ReceivedQuantity int32  `json:"received_quantity"`
+	Outstanding      int32  `json:"outstanding"`
+	UnitCost         string `json:"unit_cost"`
+}
+
+type PurchaseOrderResponse struct {
+	ID           int32                       `json:"id"`
+	StoreID      int32                       `json:"store_id"`
+	Supplier     string                      `json:"supplier"`
+	Reference    string                      `json:"reference"`
+	Notes        string                      `json:"notes"`
+	Status       string                      `json:"status"`
+	CreatedBy    int32                       `json:"created_by"`
+	CreatedAt    time.Time                   `json:"created_at"`
+	OrderedAt    *time.Time                  `json:"ordered_at"`
+	ReceivedAt   *time.Time                  `json:"received_at"`
+	CancelledBy  *int32                      `json:"cancelled_by,omitempty"`
+	CancelledAt  *time.Time                  `json:"cancelled_at,omitempty"`
+	CancelReason string                      `json:"cancel_reason,omitempty"`
+	Lines        []PurchaseOrderLineResponse `json:"lines,omitempty"`
+}
+
+type PurchaseReceiptResponse struct {
+	ReceiptID   int32  `json:"receipt_id"`
+	VariationID int32  `json:"variation_id"`
+	Quantity    int32  `json:"quantity"`
+	UnitCost    string `json:"unit_cost"`
+	// stock of the variation in the order's store after the receipt
+	StockQuantity int32 `json:"stock_quantity"`
+}
+
+type ReceivePurchaseOrderResponse struct {
+	PurchaseOrderResponse
+	Receipts []PurchaseReceiptResponse `json:"receipts"`
+}
+
+func toPurchaseOrderResponse(order db.PurchaseOrder, lines []db.PurchaseOrderLine) PurchaseOrderResponse {
+	response := PurchaseOrderResponse{
+		ID:        order.ID,
+		StoreID:   order.StoreID,
+		Supplier:  order.Supplier,
+		Reference: order.Reference.String,
+		Notes:     order.Notes.String,
+		Status:    order.Status,
+		CreatedBy: order.CreatedBy,
+		CreatedAt: order.CreatedAt.Time,
+	}
+	if order.OrderedAt.Valid {
+		response.OrderedAt = &order.OrderedAt.Time
+	}
+	if order.ReceivedAt.Valid {
+		response.ReceivedAt = &order.ReceivedAt.Time
+	}
+	if order.CancelledAt.Valid {
+		response.CancelledBy = &order.CancelledBy.Int32
+		response.CancelledAt = &order.CancelledAt.Time
+		response.CancelReason = order.CancelReason.String
+	}
+	if lines != nil {
+		response.Lines = make([]PurchaseOrderLineResponse, 0, len(lines))
+		for _, line := range lines {
+			response.Lines = append(response.Lines, PurchaseOrderLineResponse{
+				VariationID:      line.VariationID,
+				Quantity:         line.Quantity,
+				ReceivedQuantity: line.ReceivedQuantity,
+				Outstanding:      line.Quantity - line.ReceivedQuantity,
+				UnitCost:         line.UnitCost,
+			})
+		}
+	}
+	return response
+}
+
+func toPurchaseOrderLines(lines []PurchaseOrderLineRequest) []PurchaseOrderLineInput {
+	inputs := make([]PurchaseOrderLineInput, 0, len(lines))
+	for _, line := range lines {
+		inputs = append(inputs, PurchaseOrderLineInput{
+			VariationID: line.VariationID,
+			Quantity:    line.Quantity,
+			UnitCost:    line.UnitCost,
+		})
+	}
+	return inputs
+}
+
+// checkPurchaseVariations answers 400 and returns false when a line orders a
+// variation outside the business scope.
+func (h *Handler) checkPurchaseVariations(c *gin.Context, scope sql.NullInt32, lines []PurchaseOrderLineRequest) bool {
+	for _, line := range lines {
+		if _, err := h.service.GetVariationInBusiness(c, db.GetVariationInBusinessParams{ID: line.VariationID, BusinessID: scope}); err != nil {
+			if errors.Is(err, sql.ErrNoRows) {
+				utils.ErrorResponse(c, 400, fmt.Sprintf("variation with id %d does not exist", line.VariationID))
+				return false
+			}
+			h.logger.Errorf("error getting variation with id %d: %v", line.VariationID, err)
+			utils.ErrorResponse(c, 500, utils.SERVERERROR)
+			return false
+		}
+	}
+	return true
+}
+
+// scopedPurchaseOrder loads the purchase order named in the path, answering
+// 404 for orders outside the business scope and 403 for orders of a store
+// the caller isn't assigned to.
+func (h *Handler) scopedPurchaseOrder(c *gin.Context, scope sql.NullInt32) (PurchaseOrderResult, bool) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		h.logger.Errorf("purchase order id str conv err: %v", err)
+		utils.ErrorResponse(c, 400, utils.INVALID_REQUEST_DATA)
+		return PurchaseOrderResult{}, false
+	}
+
+	result, err := h.service.GetPurchaseOrder(c, db.GetPurchaseOrderParams{ID: int32(id), BusinessID: scope})
+	if err != nil {
+		if errors.Is(err, ErrPurchaseOrderNotFound) {
+			utils.ErrorResponse(c, 404, fmt.Sprintf("purchase order with id %d does not exist", id))
+			return PurchaseOrderResult{}, false
+		}
+		h.logger.Errorf("error getting purchase order with id %d: %v", id, err)
+		utils.ErrorResponse(c, 500, utils.SERVERERROR)
+		return PurchaseOrderResult{}, false
+	}
+
+	if !auth.RequireStore(c, result.Order.StoreID) {
+		return PurchaseOrderResult{}, false
+	}
+	return result, true
+}
+
+// purchaseOrderError writes the response for an error of a purchase order
+// operation.
+func (h *Handler) purchaseOrderError(c *gin.Context, err error, action string) {
+	switch {
+	case errors.Is(err, ErrPurchaseOrderNotFound):
+		utils.ErrorResponse(c, 404, err.Error())
+	case errors.Is(err, ErrDuplicatePurchaseItem), errors.Is(err, ErrInvalidUnitCost), errors.Is(err, ErrPurchaseItemNotOrdered), errors.Is(err, ErrOverReceipt):
+		utils.ErrorResponse(c, 400, err.Error())
+	case errors.Is(err, ErrPurchaseOrderNotDraft), errors.Is(err, ErrPurchaseOrderNotOrdered), errors.Is(err, ErrPurchaseOrderReceived), errors.Is(err, ErrPurchaseOrderCancelled):
+		utils.ErrorResponse(c, 409, err.Error())
+	default:
+		h.logger.Errorf("error %s purchase order: %v", action, err)
+		utils.ErrorResponse(c, 500, utils.SERVERERROR)
+	}
+}
+
+// CreatePurchaseOrder godoc
+// @Summary Create a purchase order
+// @Description Draft a purchase order of stock for a store. The draft can be changed until it is ordered.
+// @Tags inventory
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param body body CreatePurchaseOrderRequest true "purchase order"
+// @Success 201 {object} PurchaseOrderResponse
+// @Failure 400
+// @Failure 401
+// @Failure 403
+// @Failure 422
+// @Failure 500
+// @Router /api/v1/inventory/purchase-order [post]
+func (h *Handler) createPurchaseOrder(c *gin.Context) {
+	claims, ok := jwt.GetUserFromContext(c)
+	if !ok {
+		h.logger.Errorf("could not get user from context")
+		utils.ErrorResponse(c, 500, utils.SERVERERROR)
+		return
+	}
 
-			req.Sku = fmt.Sprintf("%s-%s-%s-%s",
-				safePrefix(category.Name, 3),
-				safePrefix(brand.Name, 2),
-				safePrefix(item.Name, 2),
-				safePrefix(req.Name, 2),
-			)
-		} else {
-			req.Sku = fmt.Sprintf("%s-%s-%s",
-				safePrefix(category.Name, 3),
-				safePrefix(item.Name, 2),
-				safePrefix(req.Name, 2),
-			)
+	var req CreatePurchaseOrderRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		h.logger.Errorf("error binding purchase order request data: %v", err)
+		utils.BindingErrorResponse(c, err)
+		return
+	}
+
+	scope, ok := h.businessScope(c, claims)
+	if !ok {
+		return
+	}
+
+	if !auth.RequireStore(c, req.StoreID) {
+		return
+	}
+	if _, err := h.service.GetStoreInBusiness(c, db.GetStoreInBusinessParams{ID: req.StoreID, BusinessID: scope}); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			utils.ErrorResponse(c, 400, fmt.Sprintf("store with id %d does not exist", req.StoreID))
+			return
 		}
+		h.logger.Errorf("error getting store with id %d: %v", req.StoreID, err)
+		utils.ErrorResponse(c, 500, utils.SERVERERROR)
+		return
+	}
+	if !h.checkPurchaseVariations(c, scope, req.Lines) {
+		return
 	}
 
-	params := db.CreateVariationParams{
-		Name:         req.Name,
-		ItemID:       req.ItemID,
-		Sku:          req.Sku,
-		UnitID:       req.UnitID,
-		Size:         sql.NullString{String: req.Size, Valid: req.Size != ""},
-		ColorID:      sql.NullInt32{Int32: req.ColorID, Valid: req.ColorID != 0},
-		Barcode:      sql.NullString{String: req.Barcode, Valid: req.Barcode != ""},
-		ReorderLevel: sql.NullInt32{Int32: req.ReorderLevel, Valid: req.ReorderLevel != 0},
-		BasePrice:    req.BasePrice,
+	result, err := h.service.CreatePurchaseOrder(c, CreatePurchaseOrderParams{
+		StoreID:   req.StoreID,
+		Supplier:  req.Supplier,
+		Reference: sql.NullString{String: req.Reference, Valid: req.Reference != ""},
+		Notes:     sql.NullString{String: req.Notes, Valid: req.Notes != ""},
+		CreatedBy: int32(claims.UserID),
+		Lines:     toPurchaseOrderLines(req.Lines),
+	})
+	if err != nil {
+		h.purchaseOrderError(c, err, "creating")
+		return
 	}
 
-	variant, err := h.service.CreateVariation(c, params)
+	_, err = h.service.LogActivity(c, db.LogActivityParams{
+		UserID:     int32(claims.UserID),
+		Action:     "Created Purchase Order",
+		EntityType: "PurchaseOrder",
+		EntityID:   result.Order.ID,
+		Details:    utils.WriteActivityDetails(claims.Username, claims.Email, fmt.Sprintf("Drafted a purchase order of %d items from %s for store %d", len(result.Lines), result.Order.Supplier, result.Order.StoreID), result.Order.CreatedAt.Time),
+		IpAddress:  sql.NullString{Valid: true, String: utils.GetClientIP(c)},
+		UserAgent:  sql.NullString{Valid: true, String: c.Request.UserAgent()},
+	})
 	if err != nil {
-		if pgErr, ok := err.(*pq.Error); ok {
-			switch pgErr.Code {
-			case "23505": // unique_violation
-				utils.ErrorResponse(c, 400, fmt.Sprintf("variant with name %s already exists", req.Name))
-				return
-			}
+		h.logger.Warnf("error logging create purchase order activity: %v", err)
+	}
+
+	utils.SuccessResponse(c, 201, "purchase order created", toPurchaseOrderResponse(result.Order, result.Lines))
+}
+
+// ListPurchaseOrders godoc
+// @Summary List purchase orders
+// @Description List purchase orders, newest first. Staff assigned to stores have to name one of theirs.
+// @Tags inventory
+// @Produce json
+// @Security BearerAuth
+// @Param store_id query int false "Only list orders of this store"
+// @Param status query string false "Only list orders with this status" Enums(draft, ordered, partially_received, received, cancelled)
+// @Success 200 {object} []PurchaseOrderResponse
+// @Failure 400
+// @Failure 401
+// @Failure 403
+// @Failure 500
+// @Router /api/v1/inventory/purchase-order [get]
+func (h *Handler) listPurchaseOrders(c *gin.Context) {
+	claims, ok := jwt.GetUserFromContext(c)
+	if !ok {
+		h.logger.Errorf("could not get user from context")
+		utils.ErrorResponse(c, 500, utils.SERVERERROR)
+		return
+	}
+
+	scope, ok := h.businessScope(c, claims)
+	if !ok {
+		return
+	}
+
+	params := db.ListPurchaseOrdersParams{BusinessID: scope}
+	if sid := c.Query("store_id"); sid != "" {
+		id, err := strconv.Atoi(sid)
+		if err != nil || id <= 0 {
+			utils.ErrorResponse(c, 400, "invalid store_id")
+			return
+		}
+		params.StoreID = sql.NullInt32{Int32: int32(id), Valid: true}
+	}
+	// staff assigned to stores only see the orders of one of theirs
+	if params.StoreID.Valid {
+		if !auth.RequireStore(c, params.StoreID.Int32) {
+			return
 		}
+	} else if auth.GetStoreScope(c).Restricted {
+		utils.ErrorResponse(c, 400, "store_id is required")
+		return
+	}
+	switch status := c.Query("status"); status {
+	case "":
+	case PurchaseOrderDraft, PurchaseOrderOrdered, PurchaseOrderPartiallyReceived, PurchaseOrderReceived, PurchaseOrderCancelled:
+		params.Status = sql.NullString{String: status, Valid: true}
+	default:
+		utils.ErrorResponse(c, 400, "invalid status")
+		return
+	}
 
-		h.logger.Errorf("error creating a variant: %v", err)
+	orders, err := h.service.ListPurchaseOrders(c, params)
+	if err != nil {
+		h.logger.Errorf("error listing purchase orders: %v", err)
 		utils.ErrorResponse(c, 500, utils.SERVERERROR)
 		return
 	}
 
-	h.service.LogActivity(c, db.LogActivityParams{
+	response := make([]PurchaseOrderResponse, 0, len(orders))
+	for _, order := range orders {
+		response = append(response, toPurchaseOrderResponse(order, nil))
+	}
+
+	utils.SuccessResponse(c, 200, "purchase orders fetched", response)
+}
+
+// GetPurchaseOrder godoc
+// @Summary Get a purchase order
+// @Description Get a purchase order with its lines and what is still outstanding on each.
+// @Tags inventory
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "purchase order id"
+// @Success 200 {object} PurchaseOrderResponse
+// @Failure 400
+// @Failure 401
+// @Failure 403
+// @Failure 404
+// @Failure 500
+// @Router /api/v1/inventory/purchase-order/{id} [get]
+func (h *Handler) getPurchaseOrder(c *gin.Context) {
+	claims, ok := jwt.GetUserFromContext(c)
+	if !ok {
+		h.logger.Errorf("could not get user from context")
+		utils.ErrorResponse(c, 500, utils.SERVERERROR)
+		return
+	}
+
+	scope, ok := h.businessScope(c, claims)
+	if !ok {
+		return
+	}
+
+	result, ok := h.scopedPurchaseOrder(c, scope)
+	if !ok {
+		return
+	}
+
+	utils.SuccessResponse(c, 200, "purchase order fetched", toPurchaseOrderResponse(result.Order, result.Lines))
+}
+
+// UpdatePurchaseOrder godoc
+// @Summary Update a purchase order
+// @Description Change a draft purchase order. Lines, when given, replace the lines of the order. Setting status to ordered places the order with the supplier, after which it can be received but no longer changed.
+// @Tags inventory
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "purchase order id"
+// @Param body body UpdatePurchaseOrderRequest true "changes"
+// @Success 200 {object} PurchaseOrderResponse
+// @Failure 400
+// @Failure 401
+// @Failure 403
+// @Failure 404
+// @Failure 409
+// @Failure 422
+// @Failure 500
+// @Router /api/v1/inventory/purchase-order/{id} [patch]
+func (h *Handler) updatePurchaseOrder(c *gin.Context) {
+	claims, ok := jwt.GetUserFromContext(c)
+	if !ok {
+		h.logger.Errorf("could not get user from context")
+		utils.ErrorResponse(c, 500, utils.SERVERERROR)
+		return
+	}
+
+	var req UpdatePurchaseOrderRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		h.logger.Errorf("error binding update purchase order request data: %v", err)
+		utils.BindingErrorResponse(c, err)
+		return
+	}
+
+	scope, ok := h.businessScope(c, claims)
+	if !ok {
+		return
+	}
+
+	current, ok := h.scopedPurchaseOrder(c, scope)
+	if !ok {
+		return
+	}
+	if !h.checkPurchaseVariations(c, scope, req.Lines) {
+		return
+	}
+
+	args := UpdatePurchaseOrderParams{
+		ID:         current.Order.ID,
+		BusinessID: scope,
+		Supplier:   utils.ToNullString(req.Supplier),
+		Reference:  utils.ToNullString(req.Reference),
+		Notes:      utils.ToNullString(req.Notes),
+		Order:      req.Status == PurchaseOrderOrdered,
+	}
+	if len(req.Lines) > 0 {
+		args.Lines = toPurchaseOrderLines(req.Lines)
+	}
+
+	result, err := h.service.UpdatePurchaseOrder(c, args)
+	if err != nil {
+		h.purchaseOrderError(c, err, "updating")
+		return
+	}
+
+	description := fmt.Sprintf("Updated purchase order %d", result.Order.ID)
+	if args.Order {
+		description = fmt.Sprintf("Ordered purchase order %d from %s", result.Order.ID, result.Order.Supplier)
+	}
+	_, err = h.service.LogActivity(c, db.LogActivityParams{
 		UserID:     int32(claims.UserID),
-		EntityID:   variant.ID,
-		Action:     "Created Variant",
-		EntityType: "Variation",
-		Details:    utils.WriteActivityDetails(claims.Username, claims.Email, fmt.Sprintf("Created variant %s", variant.Name), variant.CreatedAt.Time),
+		Action:     "Updated Purchase Order",
+		EntityType: "PurchaseOrder",
+		EntityID:   result.Order.ID,
+		Details:    utils.WriteActivityDetails(claims.Username, claims.Email, description, time.Now()),
 		IpAddress:  sql.NullString{Valid: true, String: utils.GetClientIP(c)},
 		UserAgent:  sql.NullString{Valid: true, String: c.Request.UserAgent()},
 	})
+	if err != nil {
+		h.logger.Warnf("error logging update purchase order activity: %v", err)
+	}
 
-	utils.SuccessResponse(c, 201, "variant created", VariationResponse{
-		ID:        variant.ID,
-		ItemID:    variant.ItemID,
-		Sku:       variant.Sku,
-		Name:      variant.Name,
-		UnitID:    variant.UnitID,
-		Size:      variant.Size.String,
-		ColorID:   variant.ColorID.Int32,
-		Barcode:   variant.Barcode.String,
-		BasePrice: variant.BasePrice,
-		IsActive:  variant.IsActive.Bool,
+	utils.SuccessResponse(c, 200, "purchase order updated", toPurchaseOrderResponse(result.Order, result.Lines))
+}
+
+// ReceivePurchaseOrder godoc
+// @Summary Receive a purchase order
+// @Description Book goods delivered against an ordered purchase order. The received quantities are added to the stock of the order's store and recorded at the line's unit cost. Deliveries can be partial, a line can't receive more than is outstanding on it. Without lines everything outstanding is received.
+// @Tags inventory
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "purchase order id"
+// @Param body body ReceivePurchaseOrderRequest false "delivered quantities"
+// @Success 200 {object} ReceivePurchaseOrderResponse
+// @Failure 400
+// @Failure 401
+// @Failure 403
+// @Failure 404
+// @Failure 409
+// @Failure 422
+// @Failure 500
+// @Router /api/v1/inventory/purchase-order/{id}/receive [post]
+func (h *Handler) receivePurchaseOrder(c *gin.Context) {
+	claims, ok := jwt.GetUserFromContext(c)
+	if !ok {
+		h.logger.Errorf("could not get user from context")
+		utils.ErrorResponse(c, 500, utils.SERVERERROR)
+		return
+	}
+
+	var req ReceivePurchaseOrderRequest
+	if c.Request.ContentLength != 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			h.logger.Errorf("error binding receive purchase order request data: %v", err)
+			utils.BindingErrorResponse(c, err)
+			return
+		}
+	}
+
+	scope, ok := h.businessScope(c, claims)
+	if !ok {
+		return
+	}
+
+	current, ok := h.scopedPurchaseOrder(c, scope)
+	if !ok {
+		return
+	}
+
+	lines := make([]PurchaseReceiptLine, 0, len(req.Lines))
+	for _, line := range req.Lines {
+		lines = append(lines, PurchaseReceiptLine{VariationID: line.VariationID, Quantity: line.Quantity})
+	}
+
+	result, err := h.service.ReceivePurchaseOrder(c, ReceivePurchaseOrderParams{
+		ID:         current.Order.ID,
+		BusinessID: scope,
+		ReceivedBy: int32(claims.UserID),
+		Lines:      lines,
+	})
+	if err != nil {
+		h.purchaseOrderError(c, err, "receiving")
+		return
+	}
+
+	var units int32
+	receipts := make([]PurchaseReceiptResponse, 0, len(result.Receipts))
+	for _, receipt := range result.Receipts {
+		units += receipt.Quantity
+		receipts = append(receipts, PurchaseReceiptResponse{
+			ReceiptID:     receipt.ID,
+			VariationID:   receipt.VariationID,
+			Quantity:      receipt.Quantity,
+			UnitCost:      receipt.UnitCost,
+			StockQuantity: result.Stock[receipt.VariationID],
+		})
+	}
+
+	_, err = h.service.LogActivity(c, db.LogActivityParams{
+		UserID:     int32(claims.UserID),
+		Action:     "Received Purchase Order",
+		EntityType: "PurchaseOrder",
+		EntityID:   result.Order.ID,
+		Details:    utils.WriteActivityDetails(claims.Username, claims.Email, fmt.Sprintf("Received %d units of %d items on purchase order %d into store %d, the order is %s", units, len(receipts), result.Order.ID, result.Order.StoreID, result.Order.Status), time.Now()),
+		IpAddress:  sql.NullString{Valid: true, String: utils.GetClientIP(c)},
+		UserAgent:  sql.NullString{Valid: true, String: c.Request.UserAgent()},
+	})
+	if err != nil {
+		h.logger.Warnf("error logging receive purchase order activity: %v", err)
+	}
+
+	utils.SuccessResponse(c, 200, "purchase order received", ReceivePurchaseOrderResponse{
+		PurchaseOrderResponse: toPurchaseOrderResponse(result.Order, result.Lines),
+		Receipts:              receipts,
+	})
+}
+
+// CancelPurchaseOrder godoc
+// @Summary Cancel a purchase order
+// @Description Cancel a purchase order that hasn't been received in full. Stock already received stays in the store, nothing more can be received against the order. Orders received in full or already cancelled can't be cancelled.
+// @Tags inventory
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "purchase order id"
+// @Param body body CancelPurchaseOrderRequest false "why the order is cancelled"
+// @Success 200 {object} PurchaseOrderResponse
+// @Failure 400
+// @Failure 401
+// @Failure 403
+// @Failure 404
+// @Failure 409
+// @Failure 500
+// @Router /api/v1/inventory/purchase-order/{id}/cancel [post]
+// @Router /api/v1/purchasing/orders/{id}/cancel [post]
+func (h *Handler) cancelPurchaseOrder(c *gin.Context) {
+	claims, ok := jwt.GetUserFromContext(c)
+	if !ok {
+		h.logger.Errorf("could not get user from context")
+		utils.ErrorResponse(c, 500, utils.SERVERERROR)
+		return
+	}
+
+	var req CancelPurchaseOrderRequest
+	if c.Request.ContentLength != 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			h.logger.Errorf("error binding cancel purchase order request data: %v", err)
+			utils.BindingErrorResponse(c, err)
+			return
+		}
+	}
+
+	scope, ok := h.businessScope(c, claims)
+	if !ok {
+		return
+	}
+
+	current, ok := h.scopedPurchaseOrder(c, scope)
+	if !ok {
+		return
+	}
+
+	result, err := h.service.CancelPurchaseOrder(c, CancelPurchaseOrderParams{
+		ID:          current.Order.ID,
+		BusinessID:  scope,
+		CancelledBy: int32(claims.UserID),
+		Reason:      sql.NullString{String: req.Reason, Valid: req.Reason != ""},
+	})
+	if err != nil {
+		h.purchaseOrderError(c, err, "cancelling")
+		return
+	}
+
+	description := fmt.Sprintf("Cancelled purchase order %d from %s", result.Order.ID, result.Order.Supplier)
+	if req.Reason != "" {
+		description += ": " + req.Reason
+	}
+	_, err = h.service.LogActivity(c, db.LogActivityParams{
+		UserID:     int32(claims.UserID),
+		Action:     "Cancelled Purchase Order",
+		EntityType: "PurchaseOrder",
+		EntityID:   result.Order.ID,
+		Details:    utils.WriteActivityDetails(claims.Username, claims.Email, description, time.Now()),
+		IpAddress:  sql.NullString{Valid: true, String: utils.GetClientIP(c)},
+		UserAgent:  sql.NullString{Valid: true, String: c.Request.UserAgent()},
 	})
+	if err != nil {
+		h.logger.Warnf("error logging cancel purchase order activity: %v", err)
+	}
+
+	utils.SuccessResponse(c, 200, "purchase order cancelled", toPurchaseOrderResponse(result.Order, result.Lines))
 }
@@ -0,0 +1,126 @@
+package inventory
+
+import (
+	"context"
+	"database/sql"
+	db "herp/db/sqlc"
+	"herp/internal/auth"
+	"herp/internal/config"
+	"herp/pkg/jwt"
+	"herp/pkg/monitoring/logging"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+// ownerService answers GetOwnedBusinessID from owned, businesses by owner.
+// Nothing else of InventoryInterface is used by businessScope.
+type ownerService struct {
+	InventoryInterface
+	owned  map[int32][]int32
+	lookup int
+}
+
+func (s *ownerService) GetOwnedBusinessID(ctx context.Context, params db.GetOwnedBusinessIDParams) (int32, error) {
+	s.lookup++
+	for _, id := range s.owned[params.OwnerID] {
+		if !params.BusinessID.Valid || params.BusinessID.Int32 == id {
+			return id, nil
+		}
+	}
+	return 0, sql.ErrNoRows
+}
+
+func TestBusinessScope(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	tests := []struct {
+		name       string
+		disabled   bool
+		scope      auth.StoreScope
+		header     string
+		want       sql.NullInt32
+		wantStatus int
+		wantLookup bool
+	}{
+		{
+			name:       "admin gets their first business",
+			want:       sql.NullInt32{Int32: 10, Valid: true},
+			wantLookup: true,
+		},
+		{
+			name:       "admin picks an owned business",
+			header:     "11",
+			want:       sql.NullInt32{Int32: 11, Valid: true},
+			wantLookup: true,
+		},
+		{
+			name:       "admin can't pick another owner's business",
+			header:     "20",
+			wantStatus: http.StatusForbidden,
+			wantLookup: true,
+		},
+		{
+			name:  "user works in the business of their store",
+			scope: auth.StoreScope{Restricted: true, StoreIDs: []int32{5}, User: true, BusinessID: sql.NullInt32{Int32: 20, Valid: true}},
+			want:  sql.NullInt32{Int32: 20, Valid: true},
+		},
+		{
+			name:   "user picking their own business",
+			scope:  auth.StoreScope{Restricted: true, User: true, BusinessID: sql.NullInt32{Int32: 20, Valid: true}},
+			header: "20",
+			want:   sql.NullInt32{Int32: 20, Valid: true},
+		},
+		{
+			name:       "user can't pick another business",
+			scope:      auth.StoreScope{Restricted: true, User: true, BusinessID: sql.NullInt32{Int32: 20, Valid: true}},
+			header:     "10",
+			wantStatus: http.StatusForbidden,
+		},
+		{
+			// the id of a user is not an admin id, it never resolves through
+			// ownership
+			name:       "user without an assignment has no business",
+			scope:      auth.StoreScope{User: true},
+			wantStatus: http.StatusForbidden,
+		},
+		{
+			name:       "invalid header",
+			header:     "abc",
+			wantStatus: http.StatusBadRequest,
+		},
+		{
+			name:     "scoping disabled",
+			disabled: true,
+			scope:    auth.StoreScope{User: true},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			service := &ownerService{owned: map[int32][]int32{1: {10, 11}, 2: {20}}}
+			cfg := &config.Config{BusinessScope: !tt.disabled}
+			h := NewInventoryHandler(service, cfg, logging.NewLogger(cfg), nil)
+
+			w := httptest.NewRecorder()
+			c, _ := gin.CreateTestContext(w)
+			c.Request = httptest.NewRequest(http.MethodGet, "/inventory/item", nil)
+			if tt.header != "" {
+				c.Request.Header.Set("X-Business-ID", tt.header)
+			}
+			auth.SetStoreScope(c, tt.scope)
+
+			got, ok := h.businessScope(c, &jwt.Claims{UserID: 1, Username: "someone"})
+			if tt.wantStatus != 0 {
+				assert.False(t, ok)
+				assert.Equal(t, tt.wantStatus, w.Code)
+			} else {
+				assert.True(t, ok)
+				assert.Equal(t, tt.want, got)
+			}
+			assert.Equal(t, tt.wantLookup, service.lookup > 0)
+		})
+	}
+}
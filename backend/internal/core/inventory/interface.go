@@ -2,6 +2,7 @@ package inventory
 
 import (
 	"context"
+	"database/sql"
 	db "herp/db/sqlc"
 )
 
@@ -15,26 +16,33 @@ type Querier interface {
 	// DeleteBrand(ctx context.Context, is int32) error
 	// DeleteCategory(ctx context.Context, id int32) error
 	// DeleteInventory(ctx context.Context, id int32) error
-	// DeleteItem(ctx context.Context, id int32) error
+	DeleteItem(ctx context.Context, params db.DeleteItemParams) error
 	// DeleteItemImage(ctx context.Context, id int32) error
 	// DeleteVariation(ctx context.Context, id int32) error
-	GetBrand(ctx context.Context, id int32) (db.Brand, error)
-	GetCategory(ctx context.Context, id int32) (db.Category, error)
+	GetBrand(ctx context.Context, params db.GetBrandParams) (db.Brand, error)
+	GetCategory(ctx context.Context, params db.GetCategoryParams) (db.Category, error)
 	// GetInventoryByStore(ctx context.Context, storeID int32) ([]db.Inventory, error)
+	ListLowStockVariations(ctx context.Context, params db.ListLowStockVariationsParams) ([]db.ListLowStockVariationsRow, error)
 	// GetInventoryItem(ctx context.Context, params db.GetInventoryItemParams) (db.Inventory, error)
-	GetItem(ctx context.Context, id int32) (db.Item, error)
+	GetItem(ctx context.Context, params db.GetItemParams) (db.Item, error)
 	// GetItemImageByItem(ctx context.Context, itemID sql.NullInt32) ([]db.ItemImage, error)
 	// GetItemImagesByVariation(ctx context.Context, variationID sql.NullInt32) ([]db.ItemImage, error)
 	// GetVariation(ctx context.Context, id int32) (db.Variation, error)
-	// ListBrand(ctx context.Context) ([]db.Brand, error)
-	// ListCategories(ctx context.Context) ([]db.Category, error)
-	// ListItems(ctx context.Context) ([]db.Item, error)
+	ListBrands(ctx context.Context, businessID sql.NullInt32) ([]db.Brand, error)
+	ListCategories(ctx context.Context, businessID sql.NullInt32) ([]db.Category, error)
+	CountItemsByCategory(ctx context.Context, businessID sql.NullInt32) ([]db.CountItemsByCategoryRow, error)
+	ListItems(ctx context.Context, params db.ListItemsParams) ([]db.ListItemsRow, error)
 	// ListItemsByCategory(ctx context.Context, categoryID sql.NullInt32) ([]db.Item, error)
-	// ListVariationsByItem(ctx context.Context, itemID int32) ([]db.Variation, error)
+	ListVariationsByItem(ctx context.Context, itemID int32) ([]db.Variation, error)
 	// UpdateBrand(ctx context.Context, params db.UpdateBrandParams) ([]db.Brand, error)
-	// UpdateCategory(ctx context.Context, params db.UpdateCategoryParams) ([]db.Category, error)
+	UpdateCategory(ctx context.Context, params db.UpdateCategoryParams) (db.Category, error)
+	GetCategoryAncestors(ctx context.Context, id int32) ([]int32, error)
 	// updateInventoryQuantity(ctx context.Context, params db.UpdateInventoryQuantityParams) (db.Inventory, error)
-	// UpdateItem(ctx context.Context, params db.UpdateItemParams) (db.Item, error)
+	UpdateItem(ctx context.Context, params db.UpdateItemParams) (db.Item, error)
+	CountActiveVariationsByItem(ctx context.Context, itemID int32) (int64, error)
+	ListExpiringBatches(ctx context.Context, params db.ListExpiringBatchesParams) ([]db.ListExpiringBatchesRow, error)
+	GetStoreInBusiness(ctx context.Context, params db.GetStoreInBusinessParams) (int32, error)
+	GetVariationInBusiness(ctx context.Context, params db.GetVariationInBusinessParams) (int32, error)
 	// UpdateVariation(ctx context.Context, params db.UpdateVariationParams) (db.Variation, error)
 	// UpsertInventory(ctx context.Context, param db.UpsertInventoryParams) (db.Inventory, error) // Create Inventory
 	// UpdateUnit(ctx context.Context, args db.UpdateUnitParams) (db.Unit, error)
@@ -49,21 +57,62 @@ type Querier interface {
 	// UpdateColor(ctx context.Context, args db.UpdateColorParams) (db.Color, error)
 	LogActivity(ctx context.Context, params db.LogActivityParams) (db.ActivityLog, error)
 	// DeleteColor(ctx context.Context, id int32) (db.Color, error)
+	ReleaseExpiredReservations(ctx context.Context) (int64, error)
+	GetOwnedBusinessID(ctx context.Context, params db.GetOwnedBusinessIDParams) (int32, error)
+	SetInventoryMinKeep(ctx context.Context, params db.SetInventoryMinKeepParams) (db.Inventory, error)
+	ListPriceHistory(ctx context.Context, variationID int32) ([]db.PriceHistory, error)
+	SearchItems(ctx context.Context, arg db.SearchItemsParams) ([]db.SearchItemsRow, error)
+	CountSearchItems(ctx context.Context, arg db.CountSearchItemsParams) (int64, error)
+	ListSearchVariations(ctx context.Context, arg db.ListSearchVariationsParams) ([]db.ListSearchVariationsRow, error)
+	GetPurchaseOrder(ctx context.Context, params db.GetPurchaseOrderParams) (db.PurchaseOrder, error)
+	ListPurchaseOrderLines(ctx context.Context, purchaseOrderID int32) ([]db.PurchaseOrderLine, error)
+	ListPurchaseOrders(ctx context.Context, params db.ListPurchaseOrdersParams) ([]db.PurchaseOrder, error)
 }
 
 type InventoryInterface interface {
 	CreateBrand(ctx context.Context, params db.CreateBrandParams) (db.Brand, error)
 	LogActivity(ctx context.Context, params db.LogActivityParams) (db.ActivityLog, error)
 	CreateCategory(ctx context.Context, params db.CreateCategoryParams) (db.Category, error)
-	GetCategory(ctx context.Context, id int32) (db.Category, error)
+	GetCategory(ctx context.Context, params db.GetCategoryParams) (db.Category, error)
 	CreateItem(ctx context.Context, params db.CreateItemParams) (db.Item, error)
 	CreateItemWithVariations(ctx context.Context, params db.CreateItemParams, defaultUnitID int32, defaultPrice string) (db.Item, db.Variation, error)
-	GetBrand(ctx context.Context, id int32) (db.Brand, error)
+	DuplicateItem(ctx context.Context, params db.GetItemParams, name string) (db.Item, []db.Variation, error)
+	GetBrand(ctx context.Context, params db.GetBrandParams) (db.Brand, error)
 	CreateVariation(ctx context.Context, params db.CreateVariationParams) (db.Variation, error)
-	GetItem(ctx context.Context, id int32) (db.Item, error)
+	GetItem(ctx context.Context, params db.GetItemParams) (db.Item, error)
+	ListBrands(ctx context.Context, businessID sql.NullInt32) ([]db.Brand, error)
+	ListCategories(ctx context.Context, businessID sql.NullInt32) ([]db.Category, error)
+	CategoryTree(ctx context.Context, businessID sql.NullInt32) ([]CategoryNode, error)
+	CategoryChildren(ctx context.Context, params db.GetCategoryParams) ([]CategoryNode, error)
+	UpdateCategory(ctx context.Context, params db.UpdateCategoryParams) (db.Category, error)
+	ListItems(ctx context.Context, params db.ListItemsParams) ([]db.ListItemsRow, error)
+	ListVariationsByItem(ctx context.Context, itemID int32) ([]db.Variation, error)
+	UpdateItem(ctx context.Context, params db.UpdateItemParams) (db.Item, error)
+	DeleteItem(ctx context.Context, params db.DeleteItemParams) error
+	ListLowStockVariations(ctx context.Context, params db.ListLowStockVariationsParams) ([]db.ListLowStockVariationsRow, error)
+	ReceiveBatch(ctx context.Context, params db.ReceiveInventoryBatchParams) (db.InventoryBatch, db.Inventory, error)
+	ListExpiringBatches(ctx context.Context, params db.ListExpiringBatchesParams) ([]db.ListExpiringBatchesRow, error)
+	GetStoreInBusiness(ctx context.Context, params db.GetStoreInBusinessParams) (int32, error)
+	GetVariationInBusiness(ctx context.Context, params db.GetVariationInBusinessParams) (int32, error)
 	CreateUnit(ctx context.Context, args db.CreateUnitParams) (db.Unit, error)
 	GetUnitByID(ctx context.Context, id int32) (db.Unit, error)
 	CreateColor(ctx context.Context, name string) (db.Color, error)
 	GetColorByID(ctx context.Context, id int32) (db.Color, error)
 	GetColorByName(ctx context.Context, name string) (db.Color, error)
+	ReserveStock(ctx context.Context, args ReserveStockParams) (db.InventoryReservation, error)
+	ConsumeReservations(ctx context.Context, ids []int32) ([]db.InventoryReservation, error)
+	ReleaseExpiredReservations(ctx context.Context) (int64, error)
+	GetOwnedBusinessID(ctx context.Context, params db.GetOwnedBusinessIDParams) (int32, error)
+	CycleCount(ctx context.Context, args CycleCountParams) ([]CycleCountResult, error)
+	AdjustStock(ctx context.Context, args AdjustStockParams) (db.StockAdjustment, db.Inventory, error)
+	SetInventoryMinKeep(ctx context.Context, params db.SetInventoryMinKeepParams) (db.Inventory, error)
+	SetVariationPrice(ctx context.Context, args SetVariationPriceParams) (db.Variation, error)
+	ListPriceHistory(ctx context.Context, variationID int32) ([]db.PriceHistory, error)
+	SearchItems(ctx context.Context, args SearchItemsParams) ([]SearchResult, int64, error)
+	CreatePurchaseOrder(ctx context.Context, args CreatePurchaseOrderParams) (PurchaseOrderResult, error)
+	GetPurchaseOrder(ctx context.Context, params db.GetPurchaseOrderParams) (PurchaseOrderResult, error)
+	ListPurchaseOrders(ctx context.Context, params db.ListPurchaseOrdersParams) ([]db.PurchaseOrder, error)
+	UpdatePurchaseOrder(ctx context.Context, args UpdatePurchaseOrderParams) (PurchaseOrderResult, error)
+	ReceivePurchaseOrder(ctx context.Context, args ReceivePurchaseOrderParams) (PurchaseReceiptResult, error)
+	CancelPurchaseOrder(ctx context.Context, args CancelPurchaseOrderParams) (PurchaseOrderResult, error)
 }
@@ -0,0 +1,77 @@
+package inventory
+
+import (
+	"context"
+	db "herp/db/sqlc"
+	"herp/internal/config"
+	"herp/pkg/jwt"
+	"herp/pkg/monitoring/logging"
+	"net/http"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// itemService runs the catalog lookups against a catalogQuerier and records
+// the items it is asked to create, which take a transaction in Inventory.
+type itemService struct {
+	*Inventory
+	created []db.CreateItemParams
+}
+
+func (s *itemService) CreateItemWithVariations(ctx context.Context, args db.CreateItemParams, defaultUnitID int32, defaultPrice string) (db.Item, db.Variation, error) {
+	s.created = append(s.created, args)
+	return db.Item{ID: int32(len(s.created)), Name: args.Name, CategoryID: args.CategoryID, BrandID: args.BrandID, BusinessID: args.BusinessID}, db.Variation{}, nil
+}
+
+func TestCreateItemValidation(t *testing.T) {
+	q := newCatalogQuerier()
+	brand := q.addBrand(10, "Ours")
+	theirBrand := q.addBrand(20, "Theirs")
+	category := q.addCategory(10, "Drinks", 0)
+	theirCategory := q.addCategory(20, "Their drinks", 0)
+
+	tests := []struct {
+		name  string
+		body  string
+		want  int
+		brand int32
+	}{
+		{"category and no brand", `{"name":"Zobo","category_id":` + itoa(category.ID) + `}`, http.StatusCreated, 0},
+		{"category and zero brand", `{"name":"Zobo","category_id":` + itoa(category.ID) + `,"brand_id":0}`, http.StatusCreated, 0},
+		{"category and brand", `{"name":"Zobo","category_id":` + itoa(category.ID) + `,"brand_id":` + itoa(brand.ID) + `}`, http.StatusCreated, brand.ID},
+		{"missing category", `{"name":"Zobo","category_id":99}`, http.StatusBadRequest, 0},
+		{"category of another business", `{"name":"Zobo","category_id":` + itoa(theirCategory.ID) + `}`, http.StatusBadRequest, 0},
+		// the brand id is not taken for a category id
+		{"brand id as category", `{"name":"Zobo","category_id":99,"brand_id":` + itoa(category.ID) + `}`, http.StatusBadRequest, 0},
+		{"missing brand", `{"name":"Zobo","category_id":` + itoa(category.ID) + `,"brand_id":99}`, http.StatusBadRequest, 0},
+		{"brand of another business", `{"name":"Zobo","category_id":` + itoa(category.ID) + `,"brand_id":` + itoa(theirBrand.ID) + `}`, http.StatusBadRequest, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			service := &itemService{Inventory: NewInventory(q, nil)}
+			gin.SetMode(gin.TestMode)
+			cfg := &config.Config{BusinessScope: true}
+			h := NewInventoryHandler(service, cfg, logging.NewLogger(cfg), nil)
+			r := gin.New()
+			r.Use(func(c *gin.Context) {
+				c.Set("claims", &jwt.Claims{UserID: 1, Username: "owner", Email: "owner@example.com"})
+			})
+			r.POST("/inventory/item", h.createItem)
+
+			w := serve(r, http.MethodPost, "/inventory/item", tt.body)
+			require.Equal(t, tt.want, w.Code, w.Body.String())
+			if tt.want != http.StatusCreated {
+				assert.Empty(t, service.created)
+				return
+			}
+			require.Len(t, service.created, 1)
+			assert.Equal(t, category.ID, service.created[0].CategoryID)
+			assert.Equal(t, tt.brand != 0, service.created[0].BrandID.Valid)
+			assert.Equal(t, tt.brand, service.created[0].BrandID.Int32)
+			assert.Equal(t, nullInt(10), service.created[0].BusinessID)
+		})
+	}
+}
@@ -0,0 +1,126 @@
+package inventory
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"herp/db/dbtest"
+	db "herp/db/sqlc"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type lowStock struct {
+	store, variation, quantity, threshold int32
+}
+
+func TestListLowStockVariations(t *testing.T) {
+	conn := dbtest.Open(t)
+	q := db.New(conn)
+	ctx := context.Background()
+
+	owner := dbtest.Admin(t, conn, "owner")
+	businessID, branchID := dbtest.Business(t, conn, owner, "Palmwine Express")
+	dbtest.Exec(t, conn, `UPDATE business SET low_stock_threshold = 5 WHERE id = $1`, businessID)
+	bar := dbtest.Store(t, conn, branchID, "Bar")
+	kitchen := dbtest.Store(t, conn, branchID, "Kitchen")
+	palmWine := dbtest.Variation(t, conn, businessID, "PW-1L", "1500.00")
+	zobo := dbtest.Variation(t, conn, businessID, "ZB-50CL", "500.00")
+	chapman := dbtest.Variation(t, conn, businessID, "CH-50CL", "800.00")
+	dbtest.Stock(t, conn, bar, palmWine, 2)
+	dbtest.Stock(t, conn, bar, zobo, 5)
+	dbtest.Stock(t, conn, bar, chapman, 9)
+	dbtest.Stock(t, conn, kitchen, palmWine, 0)
+
+	// stock of another business is never listed
+	otherID, otherBranchID := dbtest.Business(t, conn, dbtest.Admin(t, conn, "other"), "Other Hotel")
+	otherStore := dbtest.Store(t, conn, otherBranchID, "Other Bar")
+	dbtest.Stock(t, conn, otherStore, dbtest.Variation(t, conn, otherID, "OT-1", "100.00"), 1)
+
+	list := func(params db.ListLowStockVariationsParams) []lowStock {
+		t.Helper()
+		params.BusinessID = nullInt(businessID)
+		rows, err := q.ListLowStockVariations(ctx, params)
+		require.NoError(t, err)
+		got := []lowStock{}
+		for _, row := range rows {
+			got = append(got, lowStock{row.StoreID, row.VariationID, row.Quantity, row.Threshold})
+		}
+		return got
+	}
+
+	// at or below the business threshold, lowest first
+	assert.Equal(t, []lowStock{
+		{kitchen, palmWine, 0, 5},
+		{bar, palmWine, 2, 5},
+		{bar, zobo, 5, 5},
+	}, list(db.ListLowStockVariationsParams{}))
+
+	assert.Equal(t, []lowStock{
+		{bar, palmWine, 2, 5},
+		{bar, zobo, 5, 5},
+	}, list(db.ListLowStockVariationsParams{StoreID: nullInt(bar)}))
+
+	// the threshold given overrides the business one
+	assert.Equal(t, []lowStock{
+		{kitchen, palmWine, 0, 1},
+	}, list(db.ListLowStockVariationsParams{Threshold: sql.NullInt32{Int32: 1, Valid: true}}))
+	assert.Equal(t, []lowStock{
+		{kitchen, palmWine, 0, 10},
+		{bar, palmWine, 2, 10},
+		{bar, zobo, 5, 10},
+		{bar, chapman, 9, 10},
+	}, list(db.ListLowStockVariationsParams{Threshold: sql.NullInt32{Int32: 10, Valid: true}}))
+
+	rows, err := q.ListLowStockVariations(ctx, db.ListLowStockVariationsParams{StoreID: nullInt(otherStore), BusinessID: nullInt(businessID)})
+	require.NoError(t, err)
+	assert.Empty(t, rows)
+}
+
+// lowStockQuerier returns rows, and the params it was asked with.
+type lowStockQuerier struct {
+	catalogQuerier
+	rows   []db.ListLowStockVariationsRow
+	params db.ListLowStockVariationsParams
+}
+
+func (q *lowStockQuerier) ListLowStockVariations(ctx context.Context, params db.ListLowStockVariationsParams) ([]db.ListLowStockVariationsRow, error) {
+	q.params = params
+	return q.rows, nil
+}
+
+func TestListLowStockHandler(t *testing.T) {
+	q := &lowStockQuerier{
+		catalogQuerier: *newCatalogQuerier(),
+		rows: []db.ListLowStockVariationsRow{
+			{StoreID: 3, StoreName: "Bar", StoreAddress: "1 Marina Road", VariationID: 7, Sku: "PW-1L", Quantity: 2, Threshold: 5},
+		},
+	}
+	h, r := newCatalogRouter(q, 1)
+	r.GET("/inventory/low-stock", h.listLowStock)
+
+	w := serve(r, http.MethodGet, "/inventory/low-stock?store_id=3&threshold=5", "")
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	assert.Equal(t, db.ListLowStockVariationsParams{
+		BusinessID: nullInt(10),
+		StoreID:    nullInt(3),
+		Threshold:  sql.NullInt32{Int32: 5, Valid: true},
+	}, q.params)
+
+	var body struct {
+		Data []LowStockResponse `json:"data"`
+	}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &body))
+	require.Len(t, body.Data, 1)
+	assert.Equal(t, "Bar", body.Data[0].StoreName)
+	assert.Equal(t, "1 Marina Road", body.Data[0].StoreAddress)
+	assert.Equal(t, int32(3), body.Data[0].Deficit)
+
+	for _, query := range []string{"?threshold=-1", "?threshold=few", "?store_id=bar"} {
+		w := serve(r, http.MethodGet, "/inventory/low-stock"+query, "")
+		assert.Equal(t, http.StatusBadRequest, w.Code, query)
+	}
+}
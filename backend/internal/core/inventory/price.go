@@ -0,0 +1,77 @@
+package inventory
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	db "herp/db/sqlc"
+	"math/big"
+)
+
+var ErrInvalidPrice = errors.New("price must be a non-negative number")
+
+type SetVariationPriceParams struct {
+	VariationID int32
+	BasePrice   string
+	ChangedBy   int32
+	// RecordHistory writes the change to the price history
+	RecordHistory bool
+}
+
+// SetVariationPrice changes the base price of a variation. When history is
+// recorded the change is written in the same transaction, setting the price
+// it already has records nothing.
+func (i *Inventory) SetVariationPrice(ctx context.Context, args SetVariationPriceParams) (db.Variation, error) {
+	price, ok := new(big.Rat).SetString(args.BasePrice)
+	if !ok || price.Sign() < 0 {
+		return db.Variation{}, ErrInvalidPrice
+	}
+
+	q, ok := i.queries.(*db.Queries)
+	if !ok {
+		return db.Variation{}, fmt.Errorf("invalid query type in inventory")
+	}
+
+	tx, err := i.db.BeginTx(ctx, nil)
+	if err != nil {
+		return db.Variation{}, err
+	}
+	defer tx.Rollback()
+
+	txQueries := q.WithTx(tx)
+
+	oldPrice, err := txQueries.GetVariationPriceForUpdate(ctx, args.VariationID)
+	if err != nil {
+		return db.Variation{}, err
+	}
+
+	variation, err := txQueries.UpdateVariationPrice(ctx, db.UpdateVariationPriceParams{
+		ID:        args.VariationID,
+		BasePrice: args.BasePrice,
+	})
+	if err != nil {
+		return db.Variation{}, err
+	}
+
+	// both come from the column, so they are formatted alike
+	if args.RecordHistory && variation.BasePrice != oldPrice {
+		if _, err := txQueries.CreatePriceHistory(ctx, db.CreatePriceHistoryParams{
+			VariationID: args.VariationID,
+			OldPrice:    oldPrice,
+			NewPrice:    variation.BasePrice,
+			ChangedBy:   args.ChangedBy,
+		}); err != nil {
+			return db.Variation{}, err
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return db.Variation{}, err
+	}
+	return variation, nil
+}
+
+// ListPriceHistory returns the price changes of a variation, oldest first.
+func (i *Inventory) ListPriceHistory(ctx context.Context, variationID int32) ([]db.PriceHistory, error) {
+	return i.queries.ListPriceHistory(ctx, variationID)
+}
@@ -0,0 +1,109 @@
+package inventory
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"herp/db/dbtest"
+	db "herp/db/sqlc"
+	"herp/internal/config"
+	"herp/pkg/jwt"
+	"herp/pkg/monitoring/logging"
+	"net/http"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSetVariationPriceHistory(t *testing.T) {
+	conn := dbtest.Open(t)
+	owner := dbtest.Admin(t, conn, "owner")
+	businessID, _ := dbtest.Business(t, conn, owner, "Palmwine Express")
+	variation := dbtest.Variation(t, conn, businessID, "PW-1L", "1500.00")
+	service := NewInventory(db.New(conn), conn)
+	ctx := context.Background()
+
+	set := func(price string, record bool) {
+		t.Helper()
+		_, err := service.SetVariationPrice(ctx, SetVariationPriceParams{
+			VariationID:   variation,
+			BasePrice:     price,
+			ChangedBy:     owner,
+			RecordHistory: record,
+		})
+		require.NoError(t, err)
+	}
+
+	set("1600", true)
+	set("1600.00", true) // the same price
+	set("1750.50", true)
+	set("1800", false) // history turned off
+	set("1700", true)
+
+	_, err := service.SetVariationPrice(ctx, SetVariationPriceParams{VariationID: variation, BasePrice: "-1", RecordHistory: true})
+	assert.ErrorIs(t, err, ErrInvalidPrice)
+
+	history, err := service.ListPriceHistory(ctx, variation)
+	require.NoError(t, err)
+	var changes []string
+	for _, entry := range history {
+		assert.Equal(t, owner, entry.ChangedBy)
+		changes = append(changes, entry.OldPrice+" -> "+entry.NewPrice)
+	}
+	assert.Equal(t, []string{
+		"1500.00 -> 1600.00",
+		"1600.00 -> 1750.50",
+		"1800.00 -> 1700.00",
+	}, changes)
+}
+
+func TestListPriceHistoryHandler(t *testing.T) {
+	conn := dbtest.Open(t)
+	owner := dbtest.Admin(t, conn, "owner")
+	businessID, _ := dbtest.Business(t, conn, owner, "Palmwine Express")
+	variation := dbtest.Variation(t, conn, businessID, "PW-1L", "1500.00")
+	other := dbtest.Admin(t, conn, "other")
+	theirs := dbtest.Variation(t, conn, dbtest.Insert(t, conn, `
+		INSERT INTO business (owner_id, name, country) VALUES ($1, 'Other', 'NG') RETURNING id`, other), "OT-1", "10.00")
+
+	// written out of order, returned oldest first
+	for _, row := range []struct{ old, new, at string }{
+		{"1600.00", "1700.00", "2026-03-02 09:00"},
+		{"1500.00", "1600.00", "2026-03-01 09:00"},
+		{"1700.00", "1650.00", "2026-03-03 09:00"},
+	} {
+		dbtest.Exec(t, conn, `
+			INSERT INTO price_history (variation_id, old_price, new_price, changed_by, changed_at)
+			VALUES ($1, $2, $3, $4, $5)`, variation, row.old, row.new, owner, row.at)
+	}
+
+	gin.SetMode(gin.TestMode)
+	cfg := &config.Config{BusinessScope: true}
+	h := NewInventoryHandler(NewInventory(db.New(conn), conn), cfg, logging.NewLogger(cfg), nil)
+	r := gin.New()
+	r.Use(func(c *gin.Context) {
+		c.Set("claims", &jwt.Claims{UserID: int(owner), Username: "owner", Email: "owner@example.com"})
+	})
+	r.GET("/inventory/variation/:id/price-history", h.listPriceHistory)
+
+	w := serve(r, http.MethodGet, fmt.Sprintf("/inventory/variation/%d/price-history", variation), "")
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	var body struct {
+		Data []PriceHistoryResponse `json:"data"`
+	}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &body))
+	var prices []string
+	for i, entry := range body.Data {
+		if i > 0 {
+			assert.True(t, entry.ChangedAt.After(body.Data[i-1].ChangedAt))
+		}
+		prices = append(prices, entry.NewPrice)
+	}
+	assert.Equal(t, []string{"1600.00", "1700.00", "1650.00"}, prices)
+
+	// the history of another business's variation isn't shown
+	w = serve(r, http.MethodGet, fmt.Sprintf("/inventory/variation/%d/price-history", theirs), "")
+	assert.Equal(t, http.StatusNotFound, w.Code)
+}
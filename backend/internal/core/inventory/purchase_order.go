@@ -0,0 +1,487 @@
+package inventory
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	db "herp/db/sqlc"
+	"math/big"
+	"sort"
+)
+
+// Statuses of a purchase order. A draft can be edited until it is ordered,
+// receiving goods moves it to partially_received and then received once
+// every line has been delivered in full. An order that isn't received in
+// full can be cancelled.
+const (
+	PurchaseOrderDraft             = "draft"
+	PurchaseOrderOrdered           = "ordered"
+	PurchaseOrderPartiallyReceived = "partially_received"
+	PurchaseOrderReceived          = "received"
+	PurchaseOrderCancelled         = "cancelled"
+)
+
+var (
+	ErrPurchaseOrderNotFound   = errors.New("purchase order not found")
+	ErrPurchaseOrderNotDraft   = errors.New("purchase order can only be changed while it is a draft")
+	ErrPurchaseOrderNotOrdered = errors.New("purchase order has not been ordered yet")
+	ErrPurchaseOrderReceived   = errors.New("purchase order has already been received")
+	ErrPurchaseOrderCancelled  = errors.New("purchase order has been cancelled")
+	ErrDuplicatePurchaseItem   = errors.New("item appears more than once in purchase order")
+	ErrPurchaseItemNotOrdered  = errors.New("item is not on the purchase order")
+	ErrOverReceipt             = errors.New("receipt exceeds the quantity outstanding")
+	ErrInvalidUnitCost         = errors.New("invalid unit cost")
+)
+
+type PurchaseOrderLineInput struct {
+	VariationID int32
+	Quantity    int32
+	UnitCost    string
+}
+
+type CreatePurchaseOrderParams struct {
+	StoreID   int32
+	Supplier  string
+	Reference sql.NullString
+	Notes     sql.NullString
+	CreatedBy int32
+	Lines     []PurchaseOrderLineInput
+}
+
+type UpdatePurchaseOrderParams struct {
+	ID         int32
+	BusinessID sql.NullInt32
+	Supplier   sql.NullString
+	Reference  sql.NullString
+	Notes      sql.NullString
+	// replaces the lines of the order when not nil
+	Lines []PurchaseOrderLineInput
+	// Order places the order with the supplier, after which it can be
+	// received but no longer changed
+	Order bool
+}
+
+type PurchaseReceiptLine struct {
+	VariationID int32
+	Quantity    int32
+}
+
+type ReceivePurchaseOrderParams struct {
+	ID         int32
+	BusinessID sql.NullInt32
+	ReceivedBy int32
+	// what was delivered, everything outstanding is received when empty
+	Lines []PurchaseReceiptLine
+}
+
+type CancelPurchaseOrderParams struct {
+	ID          int32
+	BusinessID  sql.NullInt32
+	CancelledBy int32
+	Reason      sql.NullString
+}
+
+type PurchaseOrderResult struct {
+	Order db.PurchaseOrder
+	Lines []db.PurchaseOrderLine
+}
+
+type PurchaseReceiptResult struct {
+	PurchaseOrderResult
+	Receipts []db.PurchaseOrderReceipt
+	// stock of each received variation in the order's store afterwards
+	Stock map[int32]int32
+}
+
+// checkPurchaseOrderLines rejects lines that repeat a variation or carry a
+// unit cost that isn't a non-negative number.
+func checkPurchaseOrderLines(lines []PurchaseOrderLineInput) error {
+	seen := make(map[int32]bool, len(lines))
+	for _, line := range lines {
+		if seen[line.VariationID] {
+			return ErrDuplicatePurchaseItem
+		}
+		seen[line.VariationID] = true
+
+		cost, ok := new(big.Rat).SetString(line.UnitCost)
+		if !ok || cost.Sign() < 0 {
+			return fmt.Errorf("%w: %q", ErrInvalidUnitCost, line.UnitCost)
+		}
+	}
+	return nil
+}
+
+func createPurchaseOrderLines(ctx context.Context, q *db.Queries, orderID int32, lines []PurchaseOrderLineInput) ([]db.PurchaseOrderLine, error) {
+	created := make([]db.PurchaseOrderLine, 0, len(lines))
+	for _, line := range lines {
+		orderLine, err := q.CreatePurchaseOrderLine(ctx, db.CreatePurchaseOrderLineParams{
+			PurchaseOrderID: orderID,
+			VariationID:     line.VariationID,
+			Quantity:        line.Quantity,
+			UnitCost:        line.UnitCost,
+		})
+		if err != nil {
+			return nil, err
+		}
+		created = append(created, orderLine)
+	}
+	sort.Slice(created, func(i, j int) bool { return created[i].VariationID < created[j].VariationID })
+	return created, nil
+}
+
+// CreatePurchaseOrder drafts a purchase order for a store with its lines in
+// one transaction.
+func (i *Inventory) CreatePurchaseOrder(ctx context.Context, args CreatePurchaseOrderParams) (PurchaseOrderResult, error) {
+	if err := checkPurchaseOrderLines(args.Lines); err != nil {
+		return PurchaseOrderResult{}, err
+	}
+
+	q, ok := i.queries.(*db.Queries)
+	if !ok {
+		return PurchaseOrderResult{}, fmt.Errorf("invalid query type in inventory")
+	}
+
+	tx, err := i.db.BeginTx(ctx, nil)
+	if err != nil {
+		return PurchaseOrderResult{}, err
+	}
+	defer tx.Rollback()
+
+	txQueries := q.WithTx(tx)
+
+	order, err := txQueries.CreatePurchaseOrder(ctx, db.CreatePurchaseOrderParams{
+		StoreID:   args.StoreID,
+		Supplier:  args.Supplier,
+		Reference: args.Reference,
+		Notes:     args.Notes,
+		CreatedBy: args.CreatedBy,
+	})
+	if err != nil {
+		return PurchaseOrderResult{}, err
+	}
+
+	lines, err := createPurchaseOrderLines(ctx, txQueries, order.ID, args.Lines)
+	if err != nil {
+		return PurchaseOrderResult{}, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return PurchaseOrderResult{}, err
+	}
+
+	return PurchaseOrderResult{Order: order, Lines: lines}, nil
+}
+
+// GetPurchaseOrder returns a purchase order with its lines.
+func (i *Inventory) GetPurchaseOrder(ctx context.Context, params db.GetPurchaseOrderParams) (PurchaseOrderResult, error) {
+	order, err := i.queries.GetPurchaseOrder(ctx, params)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return PurchaseOrderResult{}, ErrPurchaseOrderNotFound
+		}
+		return PurchaseOrderResult{}, err
+	}
+	lines, err := i.queries.ListPurchaseOrderLines(ctx, order.ID)
+	if err != nil {
+		return PurchaseOrderResult{}, err
+	}
+	return PurchaseOrderResult{Order: order, Lines: lines}, nil
+}
+
+func (i *Inventory) ListPurchaseOrders(ctx context.Context, params db.ListPurchaseOrdersParams) ([]db.PurchaseOrder, error) {
+	return i.queries.ListPurchaseOrders(ctx, params)
+}
+
+// UpdatePurchaseOrder changes a draft purchase order and, when asked, places
+// it with the supplier. Orders that have been placed can't be changed.
+func (i *Inventory) UpdatePurchaseOrder(ctx context.Context, args UpdatePurchaseOrderParams) (PurchaseOrderResult, error) {
+	if args.Lines != nil {
+		if err := checkPurchaseOrderLines(args.Lines); err != nil {
+			return PurchaseOrderResult{}, err
+		}
+	}
+
+	q, ok := i.queries.(*db.Queries)
+	if !ok {
+		return PurchaseOrderResult{}, fmt.Errorf("invalid query type in inventory")
+	}
+
+	tx, err := i.db.BeginTx(ctx, nil)
+	if err != nil {
+		return PurchaseOrderResult{}, err
+	}
+	defer tx.Rollback()
+
+	txQueries := q.WithTx(tx)
+
+	order, err := lockPurchaseOrder(ctx, txQueries, args.ID, args.BusinessID)
+	if err != nil {
+		return PurchaseOrderResult{}, err
+	}
+	if order.Status != PurchaseOrderDraft {
+		return PurchaseOrderResult{}, ErrPurchaseOrderNotDraft
+	}
+
+	order, err = txQueries.UpdatePurchaseOrder(ctx, db.UpdatePurchaseOrderParams{
+		ID:        order.ID,
+		Supplier:  args.Supplier,
+		Reference: args.Reference,
+		Notes:     args.Notes,
+	})
+	if err != nil {
+		return PurchaseOrderResult{}, err
+	}
+
+	var lines []db.PurchaseOrderLine
+	if args.Lines != nil {
+		if err := txQueries.DeletePurchaseOrderLines(ctx, order.ID); err != nil {
+			return PurchaseOrderResult{}, err
+		}
+		lines, err = createPurchaseOrderLines(ctx, txQueries, order.ID, args.Lines)
+	} else {
+		lines, err = txQueries.ListPurchaseOrderLines(ctx, order.ID)
+	}
+	if err != nil {
+		return PurchaseOrderResult{}, err
+	}
+
+	if args.Order {
+		order, err = txQueries.SetPurchaseOrderStatus(ctx, db.SetPurchaseOrderStatusParams{
+			ID:     order.ID,
+			Status: PurchaseOrderOrdered,
+		})
+		if err != nil {
+			return PurchaseOrderResult{}, err
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return PurchaseOrderResult{}, err
+	}
+
+	return PurchaseOrderResult{Order: order, Lines: lines}, nil
+}
+
+// ReceivePurchaseOrder books goods delivered against an ordered purchase
+// order in one transaction. Each received quantity is added to the stock of
+// the order's store and recorded with the line's unit cost, which also
+// becomes the cost price of the variation. Deliveries can be partial, but no
+// line can receive more than is outstanding on it. The order is received
+// once every line has been delivered in full.
+func (i *Inventory) ReceivePurchaseOrder(ctx context.Context, args ReceivePurchaseOrderParams) (PurchaseReceiptResult, error) {
+	q, ok := i.queries.(*db.Queries)
+	if !ok {
+		return PurchaseReceiptResult{}, fmt.Errorf("invalid query type in inventory")
+	}
+
+	tx, err := i.db.BeginTx(ctx, nil)
+	if err != nil {
+		return PurchaseReceiptResult{}, err
+	}
+	defer tx.Rollback()
+
+	txQueries := q.WithTx(tx)
+
+	order, err := lockPurchaseOrder(ctx, txQueries, args.ID, args.BusinessID)
+	if err != nil {
+		return PurchaseReceiptResult{}, err
+	}
+	if err := checkReceivable(order.Status); err != nil {
+		return PurchaseReceiptResult{}, err
+	}
+
+	lines, err := txQueries.ListPurchaseOrderLines(ctx, order.ID)
+	if err != nil {
+		return PurchaseReceiptResult{}, err
+	}
+
+	received := make(map[int32]int32, len(lines))
+	if len(args.Lines) == 0 {
+		for _, line := range lines {
+			if outstanding := line.Quantity - line.ReceivedQuantity; outstanding > 0 {
+				received[line.VariationID] = outstanding
+			}
+		}
+	}
+	for _, delivered := range args.Lines {
+		if _, ok := received[delivered.VariationID]; ok {
+			return PurchaseReceiptResult{}, ErrDuplicatePurchaseItem
+		}
+		received[delivered.VariationID] = delivered.Quantity
+	}
+
+	onOrder := make(map[int32]bool, len(lines))
+	for _, line := range lines {
+		onOrder[line.VariationID] = true
+	}
+	for variationID := range received {
+		if !onOrder[variationID] {
+			return PurchaseReceiptResult{}, fmt.Errorf("%w: variation %d", ErrPurchaseItemNotOrdered, variationID)
+		}
+	}
+
+	result := PurchaseReceiptResult{
+		Receipts: make([]db.PurchaseOrderReceipt, 0, len(received)),
+		Stock:    make(map[int32]int32, len(received)),
+	}
+	complete := true
+	// lines come ordered by variation, so inventory rows are locked in a
+	// stable order like transfers do
+	for idx, line := range lines {
+		quantity := received[line.VariationID]
+		if quantity > line.Quantity-line.ReceivedQuantity {
+			return PurchaseReceiptResult{}, fmt.Errorf("%w: variation %d has %d outstanding", ErrOverReceipt, line.VariationID, line.Quantity-line.ReceivedQuantity)
+		}
+		if quantity > 0 {
+			lines[idx], err = txQueries.ReceivePurchaseOrderLine(ctx, db.ReceivePurchaseOrderLineParams{
+				ID:       line.ID,
+				Quantity: quantity,
+			})
+			if err != nil {
+				return PurchaseReceiptResult{}, err
+			}
+
+			receipt, err := txQueries.CreatePurchaseOrderReceipt(ctx, db.CreatePurchaseOrderReceiptParams{
+				PurchaseOrderID: order.ID,
+				LineID:          line.ID,
+				StoreID:         order.StoreID,
+				VariationID:     line.VariationID,
+				Quantity:        quantity,
+				UnitCost:        line.UnitCost,
+				ReceivedBy:      args.ReceivedBy,
+			})
+			if err != nil {
+				return PurchaseReceiptResult{}, err
+			}
+			result.Receipts = append(result.Receipts, receipt)
+
+			if err := txQueries.SetVariationCostPrice(ctx, db.SetVariationCostPriceParams{
+				ID:        line.VariationID,
+				CostPrice: sql.NullString{String: line.UnitCost, Valid: true},
+			}); err != nil {
+				return PurchaseReceiptResult{}, err
+			}
+
+			stock, err := txQueries.IncrementInventoryQuantity(ctx, db.IncrementInventoryQuantityParams{
+				StoreID:     order.StoreID,
+				VariationID: line.VariationID,
+				Quantity:    quantity,
+			})
+			if err != nil {
+				return PurchaseReceiptResult{}, err
+			}
+			result.Stock[line.VariationID] = stock.Quantity
+		}
+		if lines[idx].ReceivedQuantity < lines[idx].Quantity {
+			complete = false
+		}
+	}
+
+	status := PurchaseOrderPartiallyReceived
+	if complete {
+		status = PurchaseOrderReceived
+	}
+	order, err = txQueries.SetPurchaseOrderStatus(ctx, db.SetPurchaseOrderStatusParams{
+		ID:     order.ID,
+		Status: status,
+	})
+	if err != nil {
+		return PurchaseReceiptResult{}, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return PurchaseReceiptResult{}, err
+	}
+
+	result.PurchaseOrderResult = PurchaseOrderResult{Order: order, Lines: lines}
+	return result, nil
+}
+
+// CancelPurchaseOrder cancels an order that hasn't been received in full.
+// Stock already received stays in the store and the order can't receive
+// anything more.
+func (i *Inventory) CancelPurchaseOrder(ctx context.Context, args CancelPurchaseOrderParams) (PurchaseOrderResult, error) {
+	q, ok := i.queries.(*db.Queries)
+	if !ok {
+		return PurchaseOrderResult{}, fmt.Errorf("invalid query type in inventory")
+	}
+
+	tx, err := i.db.BeginTx(ctx, nil)
+	if err != nil {
+		return PurchaseOrderResult{}, err
+	}
+	defer tx.Rollback()
+
+	txQueries := q.WithTx(tx)
+
+	order, err := lockPurchaseOrder(ctx, txQueries, args.ID, args.BusinessID)
+	if err != nil {
+		return PurchaseOrderResult{}, err
+	}
+	if err := checkCancellable(order.Status); err != nil {
+		return PurchaseOrderResult{}, err
+	}
+
+	order, err = txQueries.CancelPurchaseOrder(ctx, db.CancelPurchaseOrderParams{
+		ID:           order.ID,
+		CancelledBy:  args.CancelledBy,
+		CancelReason: args.Reason,
+	})
+	if err != nil {
+		return PurchaseOrderResult{}, err
+	}
+
+	lines, err := txQueries.ListPurchaseOrderLines(ctx, order.ID)
+	if err != nil {
+		return PurchaseOrderResult{}, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return PurchaseOrderResult{}, err
+	}
+
+	return PurchaseOrderResult{Order: order, Lines: lines}, nil
+}
+
+// checkReceivable refuses goods for an order that hasn't been placed, has
+// been received in full or has been cancelled.
+func checkReceivable(status string) error {
+	switch status {
+	case PurchaseOrderDraft:
+		return ErrPurchaseOrderNotOrdered
+	case PurchaseOrderReceived:
+		return ErrPurchaseOrderReceived
+	case PurchaseOrderCancelled:
+		return ErrPurchaseOrderCancelled
+	}
+	return nil
+}
+
+// checkCancellable refuses to cancel an order received in full or already
+// cancelled.
+func checkCancellable(status string) error {
+	switch status {
+	case PurchaseOrderReceived:
+		return ErrPurchaseOrderReceived
+	case PurchaseOrderCancelled:
+		return ErrPurchaseOrderCancelled
+	}
+	return nil
+}
+
+// lockPurchaseOrder locks a purchase order for the rest of the transaction,
+// so concurrent edits and deliveries are applied one after the other.
+func lockPurchaseOrder(ctx context.Context, q *db.Queries, id int32, businessID sql.NullInt32) (db.PurchaseOrder, error) {
+	order, err := q.GetPurchaseOrderForUpdate(ctx, db.GetPurchaseOrderForUpdateParams{
+		ID:         id,
+		BusinessID: businessID,
+	})
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return db.PurchaseOrder{}, ErrPurchaseOrderNotFound
+		}
+		return db.PurchaseOrder{}, err
+	}
+	return order, nil
+}
@@ -0,0 +1,191 @@
+package inventory
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	db "herp/db/sqlc"
+	"herp/internal/config"
+	"herp/pkg/jwt"
+	"herp/pkg/monitoring/logging"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCheckCancellable(t *testing.T) {
+	tests := []struct {
+		status string
+		want   error
+	}{
+		{PurchaseOrderDraft, nil},
+		{PurchaseOrderOrdered, nil},
+		{PurchaseOrderPartiallyReceived, nil},
+		{PurchaseOrderReceived, ErrPurchaseOrderReceived},
+		{PurchaseOrderCancelled, ErrPurchaseOrderCancelled},
+	}
+	for _, tt := range tests {
+		t.Run(tt.status, func(t *testing.T) {
+			assert.ErrorIs(t, checkCancellable(tt.status), tt.want)
+		})
+	}
+}
+
+func TestCheckReceivable(t *testing.T) {
+	tests := []struct {
+		status string
+		want   error
+	}{
+		{PurchaseOrderDraft, ErrPurchaseOrderNotOrdered},
+		{PurchaseOrderOrdered, nil},
+		{PurchaseOrderPartiallyReceived, nil},
+		{PurchaseOrderReceived, ErrPurchaseOrderReceived},
+		{PurchaseOrderCancelled, ErrPurchaseOrderCancelled},
+	}
+	for _, tt := range tests {
+		t.Run(tt.status, func(t *testing.T) {
+			assert.ErrorIs(t, checkReceivable(tt.status), tt.want)
+		})
+	}
+}
+
+// purchaseService keeps purchase orders in memory and applies the same
+// status rules as Inventory.
+type purchaseService struct {
+	InventoryInterface
+	orders     map[int32]*PurchaseOrderResult
+	activities []db.LogActivityParams
+}
+
+func (s *purchaseService) GetPurchaseOrder(ctx context.Context, params db.GetPurchaseOrderParams) (PurchaseOrderResult, error) {
+	order, ok := s.orders[params.ID]
+	if !ok {
+		return PurchaseOrderResult{}, ErrPurchaseOrderNotFound
+	}
+	return *order, nil
+}
+
+func (s *purchaseService) CancelPurchaseOrder(ctx context.Context, args CancelPurchaseOrderParams) (PurchaseOrderResult, error) {
+	order := s.orders[args.ID]
+	if err := checkCancellable(order.Order.Status); err != nil {
+		return PurchaseOrderResult{}, err
+	}
+	order.Order.Status = PurchaseOrderCancelled
+	order.Order.CancelledBy = sql.NullInt32{Int32: args.CancelledBy, Valid: true}
+	order.Order.CancelledAt = sql.NullTime{Time: time.Now(), Valid: true}
+	order.Order.CancelReason = args.Reason
+	return *order, nil
+}
+
+func (s *purchaseService) ReceivePurchaseOrder(ctx context.Context, args ReceivePurchaseOrderParams) (PurchaseReceiptResult, error) {
+	order := s.orders[args.ID]
+	if err := checkReceivable(order.Order.Status); err != nil {
+		return PurchaseReceiptResult{}, err
+	}
+	return PurchaseReceiptResult{PurchaseOrderResult: *order}, nil
+}
+
+func (s *purchaseService) LogActivity(ctx context.Context, params db.LogActivityParams) (db.ActivityLog, error) {
+	s.activities = append(s.activities, params)
+	return db.ActivityLog{}, nil
+}
+
+func newPurchaseOrderRouter(service *purchaseService) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	cfg := &config.Config{}
+	h := NewInventoryHandler(service, cfg, logging.NewLogger(cfg), nil)
+
+	r := gin.New()
+	r.Use(func(c *gin.Context) {
+		c.Set("claims", &jwt.Claims{UserID: 7, Username: "buyer", Email: "buyer@example.com"})
+	})
+	r.POST("/purchasing/orders/:id/cancel", h.cancelPurchaseOrder)
+	r.POST("/inventory/purchase-order/:id/receive", h.receivePurchaseOrder)
+	return r
+}
+
+func purchaseOrder(id int32, status string, quantity, received int32) *PurchaseOrderResult {
+	return &PurchaseOrderResult{
+		Order: db.PurchaseOrder{ID: id, StoreID: 1, Supplier: "Acme", Status: status},
+		Lines: []db.PurchaseOrderLine{{ID: id, PurchaseOrderID: id, VariationID: 3, Quantity: quantity, ReceivedQuantity: received, UnitCost: "4.50"}},
+	}
+}
+
+func TestCancelPurchaseOrder(t *testing.T) {
+	tests := []struct {
+		name       string
+		order      *PurchaseOrderResult
+		wantStatus int
+	}{
+		{"draft", purchaseOrder(1, PurchaseOrderDraft, 10, 0), http.StatusOK},
+		{"open", purchaseOrder(1, PurchaseOrderOrdered, 10, 0), http.StatusOK},
+		{"partially received", purchaseOrder(1, PurchaseOrderPartiallyReceived, 10, 4), http.StatusOK},
+		{"fully received", purchaseOrder(1, PurchaseOrderReceived, 10, 10), http.StatusConflict},
+		{"already cancelled", purchaseOrder(1, PurchaseOrderCancelled, 10, 0), http.StatusConflict},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			service := &purchaseService{orders: map[int32]*PurchaseOrderResult{1: tt.order}}
+			r := newPurchaseOrderRouter(service)
+
+			w := httptest.NewRecorder()
+			req := httptest.NewRequest(http.MethodPost, "/purchasing/orders/1/cancel", strings.NewReader(`{"reason":"supplier is out of stock"}`))
+			req.Header.Set("Content-Type", "application/json")
+			r.ServeHTTP(w, req)
+
+			require.Equal(t, tt.wantStatus, w.Code, w.Body.String())
+			if tt.wantStatus != http.StatusOK {
+				assert.Empty(t, service.activities)
+				return
+			}
+
+			var body struct {
+				Data PurchaseOrderResponse `json:"data"`
+			}
+			require.NoError(t, json.Unmarshal(w.Body.Bytes(), &body))
+			assert.Equal(t, PurchaseOrderCancelled, body.Data.Status)
+			assert.Equal(t, "supplier is out of stock", body.Data.CancelReason)
+			require.NotNil(t, body.Data.CancelledBy)
+			assert.Equal(t, int32(7), *body.Data.CancelledBy)
+			// what was received so far is kept
+			require.Len(t, body.Data.Lines, 1)
+			assert.Equal(t, tt.order.Lines[0].ReceivedQuantity, body.Data.Lines[0].ReceivedQuantity)
+
+			require.Len(t, service.activities, 1)
+			assert.Equal(t, "Cancelled Purchase Order", service.activities[0].Action)
+			assert.Equal(t, int32(1), service.activities[0].EntityID)
+		})
+	}
+}
+
+func TestCancelledPurchaseOrderCantBeReceived(t *testing.T) {
+	service := &purchaseService{orders: map[int32]*PurchaseOrderResult{
+		1: purchaseOrder(1, PurchaseOrderPartiallyReceived, 10, 4),
+	}}
+	r := newPurchaseOrderRouter(service)
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/purchasing/orders/1/cancel", nil))
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+
+	w = httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodPost, "/inventory/purchase-order/1/receive", strings.NewReader(`{"lines":[{"variation_id":3,"quantity":6}]}`))
+	req.Header.Set("Content-Type", "application/json")
+	r.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusConflict, w.Code)
+	assert.Contains(t, w.Body.String(), ErrPurchaseOrderCancelled.Error())
+}
+
+func TestCancelMissingPurchaseOrder(t *testing.T) {
+	r := newPurchaseOrderRouter(&purchaseService{orders: map[int32]*PurchaseOrderResult{}})
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/purchasing/orders/9/cancel", nil))
+	assert.Equal(t, http.StatusNotFound, w.Code)
+}
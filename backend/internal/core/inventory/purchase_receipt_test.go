@@ -0,0 +1,172 @@
+package inventory
+
+import (
+	"context"
+	"database/sql"
+	"herp/db/dbtest"
+	db "herp/db/sqlc"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type receiptFixture struct {
+	conn           *sql.DB
+	service        *Inventory
+	owner, store   int32
+	business       int32
+	palmWine, zobo int32
+	order          int32
+}
+
+// newReceiptFixture orders 12 palm wine and 6 zobo for a bar already holding
+// 3 palm wine.
+func newReceiptFixture(t *testing.T) receiptFixture {
+	t.Helper()
+	conn := dbtest.Open(t)
+	owner := dbtest.Admin(t, conn, "owner")
+	businessID, branchID := dbtest.Business(t, conn, owner, "Palmwine Express")
+	f := receiptFixture{
+		conn:     conn,
+		service:  NewInventory(db.New(conn), conn),
+		owner:    owner,
+		business: businessID,
+		store:    dbtest.Store(t, conn, branchID, "Bar"),
+		palmWine: dbtest.Variation(t, conn, businessID, "PW-1L", "1500.00"),
+		zobo:     dbtest.Variation(t, conn, businessID, "ZB-50CL", "500.00"),
+	}
+	dbtest.Stock(t, conn, f.store, f.palmWine, 3)
+
+	ctx := context.Background()
+	created, err := f.service.CreatePurchaseOrder(ctx, CreatePurchaseOrderParams{
+		StoreID:   f.store,
+		Supplier:  "Ogbomoso Tappers",
+		CreatedBy: owner,
+		Lines: []PurchaseOrderLineInput{
+			{VariationID: f.palmWine, Quantity: 12, UnitCost: "900.00"},
+			{VariationID: f.zobo, Quantity: 6, UnitCost: "250.50"},
+		},
+	})
+	require.NoError(t, err)
+	f.order = created.Order.ID
+	return f
+}
+
+func (f receiptFixture) place(t *testing.T) {
+	t.Helper()
+	ordered, err := f.service.UpdatePurchaseOrder(context.Background(), UpdatePurchaseOrderParams{ID: f.order, Order: true})
+	require.NoError(t, err)
+	require.Equal(t, PurchaseOrderOrdered, ordered.Order.Status)
+}
+
+func (f receiptFixture) receive(lines ...PurchaseReceiptLine) (PurchaseReceiptResult, error) {
+	return f.service.ReceivePurchaseOrder(context.Background(), ReceivePurchaseOrderParams{
+		ID:         f.order,
+		ReceivedBy: f.owner,
+		Lines:      lines,
+	})
+}
+
+func (f receiptFixture) stock(t *testing.T, variationID int32) int32 {
+	t.Helper()
+	var quantity int32
+	err := f.conn.QueryRow(`SELECT quantity FROM inventory WHERE store_id = $1 AND variation_id = $2`, f.store, variationID).Scan(&quantity)
+	if err == sql.ErrNoRows {
+		return 0
+	}
+	require.NoError(t, err)
+	return quantity
+}
+
+func (f receiptFixture) costPrice(t *testing.T, variationID int32) sql.NullString {
+	t.Helper()
+	var cost sql.NullString
+	require.NoError(t, f.conn.QueryRow(`SELECT cost_price FROM variation WHERE id = $1`, variationID).Scan(&cost))
+	return cost
+}
+
+func receivedQuantities(lines []db.PurchaseOrderLine) map[int32]int32 {
+	received := map[int32]int32{}
+	for _, line := range lines {
+		received[line.VariationID] = line.ReceivedQuantity
+	}
+	return received
+}
+
+func TestReceivePurchaseOrderFull(t *testing.T) {
+	f := newReceiptFixture(t)
+	f.place(t)
+
+	// nothing listed receives everything outstanding
+	result, err := f.receive()
+	require.NoError(t, err)
+	assert.Equal(t, PurchaseOrderReceived, result.Order.Status)
+	assert.Equal(t, map[int32]int32{f.palmWine: 12, f.zobo: 6}, receivedQuantities(result.Lines))
+	assert.Equal(t, map[int32]int32{f.palmWine: 15, f.zobo: 6}, result.Stock)
+	assert.Equal(t, int32(15), f.stock(t, f.palmWine))
+	assert.Equal(t, int32(6), f.stock(t, f.zobo))
+
+	require.Len(t, result.Receipts, 2)
+	costs := map[int32]string{}
+	for _, receipt := range result.Receipts {
+		costs[receipt.VariationID] = receipt.UnitCost
+	}
+	assert.Equal(t, map[int32]string{f.palmWine: "900.00", f.zobo: "250.50"}, costs)
+	assert.Equal(t, sql.NullString{String: "900.00", Valid: true}, f.costPrice(t, f.palmWine))
+
+	_, err = f.receive()
+	assert.ErrorIs(t, err, ErrPurchaseOrderReceived)
+	assert.Equal(t, int32(15), f.stock(t, f.palmWine))
+}
+
+func TestReceivePurchaseOrderPartial(t *testing.T) {
+	f := newReceiptFixture(t)
+	f.place(t)
+
+	result, err := f.receive(PurchaseReceiptLine{VariationID: f.palmWine, Quantity: 5})
+	require.NoError(t, err)
+	assert.Equal(t, PurchaseOrderPartiallyReceived, result.Order.Status)
+	assert.Equal(t, map[int32]int32{f.palmWine: 5, f.zobo: 0}, receivedQuantities(result.Lines))
+	assert.Equal(t, int32(8), f.stock(t, f.palmWine))
+	assert.Zero(t, f.stock(t, f.zobo))
+	assert.False(t, f.costPrice(t, f.zobo).Valid, "zobo not received yet")
+
+	// more than the 7 outstanding
+	_, err = f.receive(PurchaseReceiptLine{VariationID: f.palmWine, Quantity: 8})
+	assert.ErrorIs(t, err, ErrOverReceipt)
+	assert.Equal(t, int32(8), f.stock(t, f.palmWine))
+
+	result, err = f.receive(PurchaseReceiptLine{VariationID: f.zobo, Quantity: 6})
+	require.NoError(t, err)
+	assert.Equal(t, PurchaseOrderPartiallyReceived, result.Order.Status)
+
+	result, err = f.receive(PurchaseReceiptLine{VariationID: f.palmWine, Quantity: 7})
+	require.NoError(t, err)
+	assert.Equal(t, PurchaseOrderReceived, result.Order.Status)
+	assert.Equal(t, int32(15), f.stock(t, f.palmWine))
+	assert.Equal(t, int32(6), f.stock(t, f.zobo))
+
+	var receipts int
+	require.NoError(t, f.conn.QueryRow(`SELECT COUNT(*) FROM purchase_order_receipt WHERE purchase_order_id = $1`, f.order).Scan(&receipts))
+	assert.Equal(t, 3, receipts)
+}
+
+func TestReceivePurchaseOrderRejects(t *testing.T) {
+	f := newReceiptFixture(t)
+
+	_, err := f.receive()
+	assert.ErrorIs(t, err, ErrPurchaseOrderNotOrdered, "still a draft")
+
+	f.place(t)
+	other := dbtest.Variation(t, f.conn, f.business, "KN-1", "100.00")
+	_, err = f.receive(PurchaseReceiptLine{VariationID: other, Quantity: 1})
+	assert.ErrorIs(t, err, ErrPurchaseItemNotOrdered)
+	_, err = f.receive(
+		PurchaseReceiptLine{VariationID: f.zobo, Quantity: 1},
+		PurchaseReceiptLine{VariationID: f.zobo, Quantity: 1},
+	)
+	assert.ErrorIs(t, err, ErrDuplicatePurchaseItem)
+	assert.Equal(t, int32(3), f.stock(t, f.palmWine))
+	assert.Zero(t, f.stock(t, f.zobo))
+}
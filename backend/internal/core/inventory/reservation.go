@@ -0,0 +1,229 @@
+package inventory
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	db "herp/db/sqlc"
+	"time"
+)
+
+var (
+	ErrInsufficientStock   = errors.New("insufficient stock")
+	ErrReservationNotFound = errors.New("reservation not found")
+	ErrReservationInactive = errors.New("reservation is no longer active")
+)
+
+type ReserveStockParams struct {
+	StoreID     int32
+	VariationID int32
+	Quantity    int32
+	ReservedBy  int32
+	ExpiresAt   time.Time
+}
+
+// ReserveStock holds quantity of a variation in a store until the reservation
+// is consumed or expires. The inventory row is locked for the duration of the
+// transaction so concurrent reservations cannot oversell the last units.
+func (i *Inventory) ReserveStock(ctx context.Context, args ReserveStockParams) (db.InventoryReservation, error) {
+	q, ok := i.queries.(*db.Queries)
+	if !ok {
+		return db.InventoryReservation{}, fmt.Errorf("invalid query type in inventory")
+	}
+
+	tx, err := i.db.BeginTx(ctx, nil)
+	if err != nil {
+		return db.InventoryReservation{}, err
+	}
+	defer tx.Rollback()
+
+	txQueries := q.WithTx(tx)
+
+	stock, err := txQueries.GetInventoryItemForUpdate(ctx, db.GetInventoryItemForUpdateParams{
+		StoreID:     args.StoreID,
+		VariationID: args.VariationID,
+	})
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return db.InventoryReservation{}, ErrInsufficientStock
+		}
+		return db.InventoryReservation{}, err
+	}
+
+	reserved, err := txQueries.SumActiveReservations(ctx, db.SumActiveReservationsParams{
+		StoreID:     args.StoreID,
+		VariationID: args.VariationID,
+	})
+	if err != nil {
+		return db.InventoryReservation{}, err
+	}
+
+	if stock.Quantity-reserved < args.Quantity {
+		return db.InventoryReservation{}, ErrInsufficientStock
+	}
+
+	reservation, err := txQueries.CreateReservation(ctx, db.CreateReservationParams{
+		StoreID:     args.StoreID,
+		VariationID: args.VariationID,
+		Quantity:    args.Quantity,
+		ReservedBy:  args.ReservedBy,
+		ExpiresAt:   args.ExpiresAt,
+	})
+	if err != nil {
+		return db.InventoryReservation{}, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return db.InventoryReservation{}, err
+	}
+
+	return reservation, nil
+}
+
+// ConsumeReservations turns active reservations into real stock movements by
+// decrementing on-hand quantity. Either all reservations are consumed or none.
+func (i *Inventory) ConsumeReservations(ctx context.Context, ids []int32) ([]db.InventoryReservation, error) {
+	q, ok := i.queries.(*db.Queries)
+	if !ok {
+		return nil, fmt.Errorf("invalid query type in inventory")
+	}
+
+	tx, err := i.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	txQueries := q.WithTx(tx)
+
+	consumed, err := ConsumeReservationsTx(ctx, txQueries, ids)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, reservation := range consumed {
+		if _, err := ConsumeBatchesTx(ctx, txQueries, db.ConsumeInventoryBatchesParams{
+			StoreID:     reservation.StoreID,
+			VariationID: reservation.VariationID,
+			Quantity:    reservation.Quantity,
+			Reason:      MovementReservation,
+			ReferenceID: sql.NullInt32{Int32: reservation.ID, Valid: true},
+		}); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+
+	return consumed, nil
+}
+
+// ConsumeReservationsTx consumes reservations using queries bound to a caller
+// owned transaction, so other modules (e.g. POS) can consume reservations
+// atomically with their own writes. Batches are left to the caller, which
+// knows what the stock went to.
+func ConsumeReservationsTx(ctx context.Context, q *db.Queries, ids []int32) ([]db.InventoryReservation, error) {
+	consumed := make([]db.InventoryReservation, 0, len(ids))
+	for _, id := range ids {
+		reservation, err := q.GetReservationForUpdate(ctx, id)
+		if err != nil {
+			if errors.Is(err, sql.ErrNoRows) {
+				return nil, ErrReservationNotFound
+			}
+			return nil, err
+		}
+
+		if reservation.Status != "active" || !reservation.ExpiresAt.After(time.Now()) {
+			return nil, ErrReservationInactive
+		}
+
+		// lock the stock row before touching it
+		if _, err := q.GetInventoryItemForUpdate(ctx, db.GetInventoryItemForUpdateParams{
+			StoreID:     reservation.StoreID,
+			VariationID: reservation.VariationID,
+		}); err != nil {
+			return nil, err
+		}
+
+		reservation, err = q.ConsumeReservation(ctx, id)
+		if err != nil {
+			return nil, err
+		}
+
+		if _, err := q.DecrementInventoryQuantity(ctx, db.DecrementInventoryQuantityParams{
+			StoreID:     reservation.StoreID,
+			VariationID: reservation.VariationID,
+			Quantity:    reservation.Quantity,
+		}); err != nil {
+			return nil, err
+		}
+
+		consumed = append(consumed, reservation)
+	}
+
+	return consumed, nil
+}
+
+// DeductStockTx decrements on-hand quantity for a sale that is not backed by a
+// reservation. Stock held by other active reservations is not available. Like
+// ConsumeReservationsTx it does not touch batches.
+func DeductStockTx(ctx context.Context, q *db.Queries, storeID, variationID, quantity int32) error {
+	stock, err := q.GetInventoryItemForUpdate(ctx, db.GetInventoryItemForUpdateParams{
+		StoreID:     storeID,
+		VariationID: variationID,
+	})
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return ErrInsufficientStock
+		}
+		return err
+	}
+
+	reserved, err := q.SumActiveReservations(ctx, db.SumActiveReservationsParams{
+		StoreID:     storeID,
+		VariationID: variationID,
+	})
+	if err != nil {
+		return err
+	}
+
+	if stock.Quantity-reserved < quantity {
+		return ErrInsufficientStock
+	}
+
+	_, err = q.DecrementInventoryQuantity(ctx, db.DecrementInventoryQuantityParams{
+		StoreID:     storeID,
+		VariationID: variationID,
+		Quantity:    quantity,
+	})
+	return err
+}
+
+func (i *Inventory) ReleaseExpiredReservations(ctx context.Context) (int64, error) {
+	return i.queries.ReleaseExpiredReservations(ctx)
+}
+
+// StartReservationSweeper periodically releases expired reservations until ctx
+// is cancelled. The returned channel is closed once the sweeper has stopped.
+func (i *Inventory) StartReservationSweeper(ctx context.Context, interval time.Duration, onError func(error)) <-chan struct{} {
+	done := make(chan struct{})
+	ticker := time.NewTicker(interval)
+	go func() {
+		defer close(done)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				if _, err := i.ReleaseExpiredReservations(ctx); err != nil && onError != nil {
+					onError(err)
+				}
+			}
+		}
+	}()
+	return done
+}
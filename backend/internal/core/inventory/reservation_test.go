@@ -0,0 +1,222 @@
+package inventory
+
+import (
+	"context"
+	"database/sql"
+	"herp/db/dbtest"
+	db "herp/db/sqlc"
+	"herp/internal/auth"
+	"herp/internal/config"
+	"herp/pkg/jwt"
+	"herp/pkg/monitoring/logging"
+	"net/http"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type reservationFixture struct {
+	conn      *sql.DB
+	inventory *Inventory
+	storeID   int32
+	variation int32
+}
+
+func newReservationFixture(t *testing.T, stock int32) reservationFixture {
+	t.Helper()
+	conn := dbtest.Open(t)
+	owner := dbtest.Admin(t, conn, "owner")
+	businessID, branchID := dbtest.Business(t, conn, owner, "Palmwine Express")
+	f := reservationFixture{
+		conn:      conn,
+		inventory: NewInventory(db.New(conn), conn),
+		storeID:   dbtest.Store(t, conn, branchID, "Bar"),
+		variation: dbtest.Variation(t, conn, businessID, "MLK-1L", "10.00"),
+	}
+	dbtest.Stock(t, conn, f.storeID, f.variation, stock)
+	return f
+}
+
+func (f reservationFixture) reserve(ctx context.Context, quantity int32, expiresIn time.Duration) (db.InventoryReservation, error) {
+	return f.inventory.ReserveStock(ctx, ReserveStockParams{
+		StoreID:     f.storeID,
+		VariationID: f.variation,
+		Quantity:    quantity,
+		ReservedBy:  1,
+		ExpiresAt:   time.Now().Add(expiresIn),
+	})
+}
+
+func (f reservationFixture) onHand(t *testing.T) int32 {
+	t.Helper()
+	var quantity int32
+	require.NoError(t, f.conn.QueryRow(`SELECT quantity FROM inventory WHERE store_id = $1 AND variation_id = $2`, f.storeID, f.variation).Scan(&quantity))
+	return quantity
+}
+
+func TestReserveAndConsume(t *testing.T) {
+	f := newReservationFixture(t, 5)
+	ctx := context.Background()
+
+	reservation, err := f.reserve(ctx, 3, time.Hour)
+	require.NoError(t, err)
+	assert.Equal(t, "active", reservation.Status)
+	assert.Equal(t, int32(5), f.onHand(t), "held, not taken")
+
+	// only 2 are left to reserve
+	_, err = f.reserve(ctx, 3, time.Hour)
+	assert.ErrorIs(t, err, ErrInsufficientStock)
+	second, err := f.reserve(ctx, 2, time.Hour)
+	require.NoError(t, err)
+
+	consumed, err := f.inventory.ConsumeReservations(ctx, []int32{reservation.ID})
+	require.NoError(t, err)
+	require.Len(t, consumed, 1)
+	assert.Equal(t, "consumed", consumed[0].Status)
+	assert.Equal(t, int32(2), f.onHand(t))
+
+	_, err = f.inventory.ConsumeReservations(ctx, []int32{reservation.ID})
+	assert.ErrorIs(t, err, ErrReservationInactive)
+	_, err = f.inventory.ConsumeReservations(ctx, []int32{second.ID + 100})
+	assert.ErrorIs(t, err, ErrReservationNotFound)
+
+	// one bad id and none are consumed
+	_, err = f.inventory.ConsumeReservations(ctx, []int32{second.ID, reservation.ID})
+	assert.ErrorIs(t, err, ErrReservationInactive)
+	assert.Equal(t, int32(2), f.onHand(t))
+}
+
+func TestReservationExpiry(t *testing.T) {
+	f := newReservationFixture(t, 5)
+	ctx := context.Background()
+
+	expired, err := f.reserve(ctx, 4, -time.Minute)
+	require.NoError(t, err)
+
+	// an expired reservation holds nothing and can't be consumed
+	_, err = f.reserve(ctx, 5, time.Hour)
+	require.NoError(t, err)
+	_, err = f.inventory.ConsumeReservations(ctx, []int32{expired.ID})
+	assert.ErrorIs(t, err, ErrReservationInactive)
+	assert.Equal(t, int32(5), f.onHand(t))
+
+	released, err := f.inventory.ReleaseExpiredReservations(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), released)
+	var status string
+	require.NoError(t, f.conn.QueryRow(`SELECT status FROM inventory_reservation WHERE id = $1`, expired.ID).Scan(&status))
+	assert.Equal(t, "released", status)
+}
+
+// reserveService keeps store 5 and variation 8 in business 10 and store 6 and
+// variation 9 in business 20. Owner 1 owns business 10.
+type reserveService struct {
+	ownerService
+	reserved []ReserveStockParams
+}
+
+func (s *reserveService) GetStoreInBusiness(ctx context.Context, params db.GetStoreInBusinessParams) (int32, error) {
+	if (params.ID == 5 && params.BusinessID.Int32 == 10) || (params.ID == 6 && params.BusinessID.Int32 == 20) {
+		return params.ID, nil
+	}
+	return 0, sql.ErrNoRows
+}
+
+func (s *reserveService) GetVariationInBusiness(ctx context.Context, params db.GetVariationInBusinessParams) (int32, error) {
+	if (params.ID == 8 && params.BusinessID.Int32 == 10) || (params.ID == 9 && params.BusinessID.Int32 == 20) {
+		return params.ID, nil
+	}
+	return 0, sql.ErrNoRows
+}
+
+func (s *reserveService) ReserveStock(ctx context.Context, args ReserveStockParams) (db.InventoryReservation, error) {
+	s.reserved = append(s.reserved, args)
+	return db.InventoryReservation{ID: 1, StoreID: args.StoreID, VariationID: args.VariationID, Quantity: args.Quantity, Status: "active"}, nil
+}
+
+func (s *reserveService) LogActivity(ctx context.Context, params db.LogActivityParams) (db.ActivityLog, error) {
+	return db.ActivityLog{}, nil
+}
+
+func TestReserveStockScope(t *testing.T) {
+	tests := []struct {
+		name       string
+		scope      gin.HandlerFunc
+		body       string
+		wantStatus int
+	}{
+		{name: "owner's store", body: `{"store_id":5,"variation_id":8,"quantity":2}`, wantStatus: http.StatusCreated},
+		{name: "another business's store", body: `{"store_id":6,"variation_id":8,"quantity":2}`, wantStatus: http.StatusBadRequest},
+		{name: "another business's variation", body: `{"store_id":5,"variation_id":9,"quantity":2}`, wantStatus: http.StatusBadRequest},
+		{name: "staff of another business", scope: withStoreScope(20), body: `{"store_id":5,"variation_id":9,"quantity":2}`, wantStatus: http.StatusBadRequest},
+		{
+			name: "staff outside their store",
+			scope: func(c *gin.Context) {
+				auth.SetStoreScope(c, auth.StoreScope{Restricted: true, StoreIDs: []int32{6}, User: true, BusinessID: nullInt(20)})
+			},
+			body:       `{"store_id":5,"variation_id":8,"quantity":2}`,
+			wantStatus: http.StatusForbidden,
+		},
+		{
+			name: "staff of the store's business",
+			scope: func(c *gin.Context) {
+				auth.SetStoreScope(c, auth.StoreScope{Restricted: true, StoreIDs: []int32{6}, User: true, BusinessID: nullInt(20)})
+			},
+			body:       `{"store_id":6,"variation_id":9,"quantity":2}`,
+			wantStatus: http.StatusCreated,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			service := &reserveService{ownerService: ownerService{owned: map[int32][]int32{1: {10}}}}
+			cfg := &config.Config{BusinessScope: true}
+			h := NewInventoryHandler(service, cfg, logging.NewLogger(cfg), nil)
+			r := gin.New()
+			r.Use(func(c *gin.Context) {
+				c.Set("claims", &jwt.Claims{UserID: 1, Username: "owner"})
+			})
+			handlers := []gin.HandlerFunc{h.reserveStock}
+			if tt.scope != nil {
+				handlers = append([]gin.HandlerFunc{tt.scope}, handlers...)
+			}
+			r.POST("/inventory/reserve", handlers...)
+
+			w := serve(r, http.MethodPost, "/inventory/reserve", tt.body)
+			require.Equal(t, tt.wantStatus, w.Code, w.Body.String())
+			if tt.wantStatus == http.StatusCreated {
+				require.Len(t, service.reserved, 1)
+			} else {
+				assert.Empty(t, service.reserved)
+			}
+		})
+	}
+}
+
+// sweepQuerier counts the sweeps of expired reservations.
+type sweepQuerier struct {
+	Querier
+	sweeps atomic.Int32
+}
+
+func (q *sweepQuerier) ReleaseExpiredReservations(ctx context.Context) (int64, error) {
+	q.sweeps.Add(1)
+	return 0, nil
+}
+
+func TestReservationSweeper(t *testing.T) {
+	q := &sweepQuerier{}
+	ctx, cancel := context.WithCancel(context.Background())
+	done := NewInventory(q, nil).StartReservationSweeper(ctx, 5*time.Millisecond, func(err error) { t.Errorf("unexpected error: %v", err) })
+	assert.Eventually(t, func() bool { return q.sweeps.Load() >= 2 }, 2*time.Second, 5*time.Millisecond)
+
+	cancel()
+	select {
+	case <-done:
+	case <-time.After(2 * time.Second):
+		t.Fatal("reservation sweeper did not stop")
+	}
+}
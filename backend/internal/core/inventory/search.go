@@ -0,0 +1,95 @@
+package inventory
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	db "herp/db/sqlc"
+	"regexp"
+	"strings"
+)
+
+var ErrEmptySearch = errors.New("search must contain a letter or digit")
+
+var searchWordPattern = regexp.MustCompile(`[\p{L}\p{N}]+`)
+
+// searchQuery turns what a user typed into a tsquery matching every word as a
+// prefix, so "coke 50" finds "Coke 500ml" and a partial SKU finds its
+// variation. Only letters and digits are kept, the rest of the tsquery syntax
+// can't be typed in.
+func searchQuery(q string) string {
+	words := searchWordPattern.FindAllString(strings.ToLower(q), -1)
+	for i, word := range words {
+		words[i] = word + ":*"
+	}
+	return strings.Join(words, " & ")
+}
+
+type SearchItemsParams struct {
+	Query      string
+	BusinessID sql.NullInt32
+	Limit      int32
+	Offset     int32
+}
+
+// SearchResult is an item found by a search with all of its variations, the
+// ones matching the search themselves are flagged.
+type SearchResult struct {
+	Item       db.SearchItemsRow
+	Variations []db.ListSearchVariationsRow
+}
+
+// SearchItems searches the names and descriptions of items and the names,
+// SKUs and barcodes of their variations. It returns a page of the items found,
+// best match first, and how many were found in total.
+func (i *Inventory) SearchItems(ctx context.Context, args SearchItemsParams) ([]SearchResult, int64, error) {
+	query := searchQuery(args.Query)
+	if query == "" {
+		return nil, 0, ErrEmptySearch
+	}
+
+	items, err := i.queries.SearchItems(ctx, db.SearchItemsParams{
+		Query:      query,
+		BusinessID: args.BusinessID,
+		PageLimit:  args.Limit,
+		PageOffset: args.Offset,
+	})
+	if err != nil {
+		return nil, 0, err
+	}
+
+	total, err := i.queries.CountSearchItems(ctx, db.CountSearchItemsParams{
+		BusinessID: args.BusinessID,
+		Query:      query,
+	})
+	if err != nil {
+		return nil, 0, err
+	}
+
+	results := make([]SearchResult, 0, len(items))
+	if len(items) == 0 {
+		return results, total, nil
+	}
+
+	itemIDs := make([]int32, 0, len(items))
+	byItem := make(map[int32]int, len(items))
+	for n, item := range items {
+		itemIDs = append(itemIDs, item.ID)
+		byItem[item.ID] = n
+		results = append(results, SearchResult{Item: item, Variations: []db.ListSearchVariationsRow{}})
+	}
+
+	variations, err := i.queries.ListSearchVariations(ctx, db.ListSearchVariationsParams{
+		Query:   query,
+		ItemIds: itemIDs,
+	})
+	if err != nil {
+		return nil, 0, err
+	}
+	for _, variation := range variations {
+		n := byItem[variation.ItemID]
+		results[n].Variations = append(results[n].Variations, variation)
+	}
+
+	return results, total, nil
+}
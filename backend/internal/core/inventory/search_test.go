@@ -0,0 +1,85 @@
+package inventory
+
+import (
+	"context"
+	"database/sql"
+	"herp/db/dbtest"
+	db "herp/db/sqlc"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSearchQuery(t *testing.T) {
+	tests := map[string]string{
+		"coke":             "coke:*",
+		"Coke 50":          "coke:* & 50:*",
+		"  PALM   wine ":   "palm:* & wine:*",
+		"PW-1L":            "pw:* & 1l:*",
+		"zobo' | !chilled": "zobo:* & chilled:*",
+		"jollof (rice)":    "jollof:* & rice:*",
+		"":                 "",
+		"-- & |":           "",
+	}
+	for typed, want := range tests {
+		assert.Equal(t, want, searchQuery(typed), typed)
+	}
+}
+
+// namedVariation creates a variation in an item of the given name.
+func namedVariation(t *testing.T, conn *sql.DB, businessID int32, item, sku string) int32 {
+	t.Helper()
+	id := dbtest.Variation(t, conn, businessID, sku, "100.00")
+	dbtest.Exec(t, conn, `UPDATE item SET name = $1 WHERE id = (SELECT item_id FROM variation WHERE id = $2)`, item, id)
+	return id
+}
+
+func TestSearchItemsNameAndSKU(t *testing.T) {
+	conn := dbtest.Open(t)
+	owner := dbtest.Admin(t, conn, "owner")
+	businessID, _ := dbtest.Business(t, conn, owner, "Palmwine Express")
+	otherID, _ := dbtest.Business(t, conn, owner, "Other")
+	service := NewInventory(db.New(conn), conn)
+
+	byName := namedVariation(t, conn, businessID, "Palm Wine", "PW1L")
+	bySKU := namedVariation(t, conn, businessID, "Zobo", "PALMZ500")
+	namedVariation(t, conn, businessID, "Chapman", "CH50")
+	namedVariation(t, conn, otherID, "Palm Oil", "PO1L")
+
+	results, total, err := service.SearchItems(context.Background(), SearchItemsParams{
+		Query:      "palm",
+		BusinessID: sql.NullInt32{Int32: businessID, Valid: true},
+		Limit:      10,
+	})
+	require.NoError(t, err)
+	assert.EqualValues(t, 2, total)
+	require.Len(t, results, 2)
+
+	found := map[string]db.ListSearchVariationsRow{}
+	for _, result := range results {
+		require.Len(t, result.Variations, 1)
+		found[result.Item.Name] = result.Variations[0]
+	}
+	require.Contains(t, found, "Palm Wine", "name match")
+	require.Contains(t, found, "Zobo", "SKU match")
+	assert.Equal(t, byName, found["Palm Wine"].ID)
+	assert.False(t, found["Palm Wine"].Matched, "the item matched, not its variation")
+	assert.Equal(t, bySKU, found["Zobo"].ID)
+	assert.True(t, found["Zobo"].Matched)
+
+	// a partial SKU, in any case
+	results, _, err = service.SearchItems(context.Background(), SearchItemsParams{
+		Query:      "palmz",
+		BusinessID: sql.NullInt32{Int32: businessID, Valid: true},
+		Limit:      10,
+	})
+	require.NoError(t, err)
+	require.Len(t, results, 1)
+	assert.Equal(t, "Zobo", results[0].Item.Name)
+}
+
+func TestSearchItemsEmpty(t *testing.T) {
+	_, _, err := (&Inventory{}).SearchItems(context.Background(), SearchItemsParams{Query: " -- "})
+	assert.ErrorIs(t, err, ErrEmptySearch)
+}
@@ -0,0 +1,101 @@
+package inventory
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	db "herp/db/sqlc"
+	"strings"
+)
+
+// SeedUnit is a unit created on startup when missing.
+type SeedUnit struct {
+	Name      string
+	ShortCode string
+}
+
+// ParseSeedUnits reads units written as "name:short_code", the short code is
+// optional.
+func ParseSeedUnits(values []string) []SeedUnit {
+	units := make([]SeedUnit, 0, len(values))
+	for _, v := range values {
+		name, code, _ := strings.Cut(v, ":")
+		name = strings.TrimSpace(name)
+		if name == "" {
+			continue
+		}
+		units = append(units, SeedUnit{Name: name, ShortCode: strings.TrimSpace(code)})
+	}
+	return units
+}
+
+type SeedResult struct {
+	Units  []string
+	Colors []string
+}
+
+// Seed creates the units and colors that don't exist yet, so variations can
+// be created on a fresh install. Names are compared case insensitively,
+// running it again creates nothing.
+func (i *Inventory) Seed(ctx context.Context, units []SeedUnit, colors []string) (SeedResult, error) {
+	q, ok := i.queries.(*db.Queries)
+	if !ok {
+		return SeedResult{}, fmt.Errorf("invalid query type in inventory")
+	}
+
+	tx, err := i.db.BeginTx(ctx, nil)
+	if err != nil {
+		return SeedResult{}, err
+	}
+	defer tx.Rollback()
+
+	txQueries := q.WithTx(tx)
+	var result SeedResult
+
+	existingUnits, err := txQueries.ListUnits(ctx)
+	if err != nil {
+		return SeedResult{}, err
+	}
+	seen := make(map[string]bool, len(existingUnits))
+	for _, u := range existingUnits {
+		seen[strings.ToLower(u.Name)] = true
+	}
+	for _, u := range units {
+		if seen[strings.ToLower(u.Name)] {
+			continue
+		}
+		if _, err := txQueries.CreateUnit(ctx, db.CreateUnitParams{
+			Name:      u.Name,
+			ShortCode: sql.NullString{String: u.ShortCode, Valid: u.ShortCode != ""},
+		}); err != nil {
+			return SeedResult{}, fmt.Errorf("unit %s: %w", u.Name, err)
+		}
+		seen[strings.ToLower(u.Name)] = true
+		result.Units = append(result.Units, u.Name)
+	}
+
+	existingColors, err := txQueries.ListColors(ctx)
+	if err != nil {
+		return SeedResult{}, err
+	}
+	seen = make(map[string]bool, len(existingColors))
+	for _, c := range existingColors {
+		seen[strings.ToLower(c.Name)] = true
+	}
+	for _, name := range colors {
+		name = strings.TrimSpace(name)
+		if name == "" || seen[strings.ToLower(name)] {
+			continue
+		}
+		if _, err := txQueries.CreateColor(ctx, name); err != nil {
+			return SeedResult{}, fmt.Errorf("color %s: %w", name, err)
+		}
+		seen[strings.ToLower(name)] = true
+		result.Colors = append(result.Colors, name)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return SeedResult{}, err
+	}
+	return result, nil
+}
@@ -0,0 +1,46 @@
+package inventory
+
+import (
+	"context"
+	"herp/db/dbtest"
+	db "herp/db/sqlc"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseSeedUnits(t *testing.T) {
+	assert.Equal(t, []SeedUnit{
+		{Name: "Kilogram", ShortCode: "kg"},
+		{Name: "Piece"},
+		{Name: "Crate", ShortCode: "crt"},
+	}, ParseSeedUnits([]string{"Kilogram:kg", "Piece", " ", " Crate : crt "}))
+}
+
+func TestSeedTwice(t *testing.T) {
+	conn := dbtest.Open(t)
+	service := NewInventory(db.New(conn), conn)
+	ctx := context.Background()
+	units := ParseSeedUnits([]string{"Kilogram:kg", "Litre:l", "Piece:pcs"})
+	colors := []string{"Black", "White"}
+
+	// a unit added by hand before the first start is kept as it is
+	dbtest.Exec(t, conn, `INSERT INTO unit (name, short_code) VALUES ('litre', 'L')`)
+
+	seeded, err := service.Seed(ctx, units, colors)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"Kilogram", "Piece"}, seeded.Units)
+	assert.Equal(t, colors, seeded.Colors)
+
+	seeded, err = service.Seed(ctx, units, colors)
+	require.NoError(t, err)
+	assert.Empty(t, seeded.Units)
+	assert.Empty(t, seeded.Colors)
+
+	var unitCount, colorCount int
+	require.NoError(t, conn.QueryRow(`SELECT count(*) FROM unit`).Scan(&unitCount))
+	require.NoError(t, conn.QueryRow(`SELECT count(*) FROM color`).Scan(&colorCount))
+	assert.Equal(t, 3, unitCount)
+	assert.Equal(t, 2, colorCount)
+}
@@ -3,10 +3,18 @@ package inventory
 import (
 	"context"
 	"database/sql"
+	"errors"
 	"fmt"
 	db "herp/db/sqlc"
 )
 
+var (
+	ErrItemHasVariations = errors.New("item still has active variations")
+	// ErrRecordModified is returned by an update made from a version of an
+	// item that is no longer the current one.
+	ErrRecordModified = errors.New("record modified")
+)
+
 type Inventory struct {
 	db      *sql.DB
 	queries Querier
@@ -31,24 +39,74 @@ func (i *Inventory) CreateCategory(ctx context.Context, params db.CreateCategory
 	return i.queries.CreateCategory(ctx, params)
 }
 
-func (i *Inventory) GetCategory(ctx context.Context, id int32) (db.Category, error) {
-	return i.queries.GetCategory(ctx, id)
+func (i *Inventory) GetCategory(ctx context.Context, params db.GetCategoryParams) (db.Category, error) {
+	return i.queries.GetCategory(ctx, params)
 }
 
 func (i *Inventory) CreateItem(ctx context.Context, params db.CreateItemParams) (db.Item, error) {
 	return i.queries.CreateItem(ctx, params)
 }
 
-func (i *Inventory) GetBrand(ctx context.Context, id int32) (db.Brand, error) {
-	return i.queries.GetBrand(ctx, id)
+func (i *Inventory) GetBrand(ctx context.Context, params db.GetBrandParams) (db.Brand, error) {
+	return i.queries.GetBrand(ctx, params)
 }
 
 func (i *Inventory) CreateVariation(ctx context.Context, params db.CreateVariationParams) (db.Variation, error) {
 	return i.queries.CreateVariation(ctx, params)
 }
 
-func (i *Inventory) GetItem(ctx context.Context, id int32) (db.Item, error) {
-	return i.queries.GetItem(ctx, id)
+func (i *Inventory) GetItem(ctx context.Context, params db.GetItemParams) (db.Item, error) {
+	return i.queries.GetItem(ctx, params)
+}
+
+func (i *Inventory) ListBrands(ctx context.Context, businessID sql.NullInt32) ([]db.Brand, error) {
+	return i.queries.ListBrands(ctx, businessID)
+}
+
+func (i *Inventory) ListCategories(ctx context.Context, businessID sql.NullInt32) ([]db.Category, error) {
+	return i.queries.ListCategories(ctx, businessID)
+}
+
+// ListItems lists items with the number of variations each has, optionally
+// filtered by category and brand.
+func (i *Inventory) ListItems(ctx context.Context, params db.ListItemsParams) ([]db.ListItemsRow, error) {
+	return i.queries.ListItems(ctx, params)
+}
+
+func (i *Inventory) ListVariationsByItem(ctx context.Context, itemID int32) ([]db.Variation, error) {
+	return i.queries.ListVariationsByItem(ctx, itemID)
+}
+
+// UpdateItem updates an item. When params.ExpectedVersion is set and the item
+// has moved past it, nothing is changed and ErrRecordModified is returned.
+func (i *Inventory) UpdateItem(ctx context.Context, params db.UpdateItemParams) (db.Item, error) {
+	item, err := i.queries.UpdateItem(ctx, params)
+	if errors.Is(err, sql.ErrNoRows) && params.ExpectedVersion.Valid {
+		// the version didn't match if the item is there
+		if _, getErr := i.queries.GetItem(ctx, db.GetItemParams{ID: params.ID, BusinessID: params.BusinessID}); getErr == nil {
+			return db.Item{}, ErrRecordModified
+		}
+	}
+	return item, err
+}
+
+// DeleteItem deletes an item and its inactive variations. Items that still
+// have active variations are not deleted, they have to be deactivated first.
+func (i *Inventory) DeleteItem(ctx context.Context, params db.DeleteItemParams) error {
+	count, err := i.queries.CountActiveVariationsByItem(ctx, params.ID)
+	if err != nil {
+		return err
+	}
+	if count > 0 {
+		return ErrItemHasVariations
+	}
+	return i.queries.DeleteItem(ctx, params)
+}
+
+// ListLowStockVariations lists stock at or below the low stock threshold of
+// the business owning the store, or params.Threshold when it is set.
+func (i *Inventory) ListLowStockVariations(ctx context.Context, params db.ListLowStockVariationsParams) ([]db.ListLowStockVariationsRow, error) {
+	return i.queries.ListLowStockVariations(ctx, params)
 }
 
 // CreateItemWithVariations creates an item with variations.
@@ -95,6 +153,86 @@ func (i *Inventory) CreateItemWithVariations(ctx context.Context, args db.Create
 	return item, variation, nil
 }
 
+// DuplicateItem copies an item and its active variations under a new name.
+// The copies get new SKUs and no barcode, keep their base prices and start
+// with no stock in any store.
+func (i *Inventory) DuplicateItem(ctx context.Context, params db.GetItemParams, name string) (db.Item, []db.Variation, error) {
+	q, ok := i.queries.(*db.Queries)
+	if !ok {
+		return db.Item{}, nil, fmt.Errorf("invalid query type in inventory")
+	}
+
+	tx, err := i.db.BeginTx(ctx, nil)
+	if err != nil {
+		return db.Item{}, nil, err
+	}
+	defer tx.Rollback()
+
+	txQueries := q.WithTx(tx)
+
+	source, err := txQueries.GetItem(ctx, params)
+	if err != nil {
+		return db.Item{}, nil, err
+	}
+
+	item, err := txQueries.CreateItem(ctx, db.CreateItemParams{
+		BrandID:     source.BrandID,
+		CategoryID:  source.CategoryID,
+		Name:        name,
+		Description: source.Description,
+		ItemType:    source.ItemType,
+		NoVariants:  source.NoVariants,
+		BusinessID:  source.BusinessID,
+	})
+	if err != nil {
+		return db.Item{}, nil, err
+	}
+
+	sourceVariations, err := txQueries.ListVariationsByItem(ctx, source.ID)
+	if err != nil {
+		return db.Item{}, nil, err
+	}
+
+	variations := make([]db.Variation, 0, len(sourceVariations))
+	for _, v := range sourceVariations {
+		if v.IsActive.Valid && !v.IsActive.Bool {
+			continue
+		}
+		variation, err := txQueries.CreateVariation(ctx, db.CreateVariationParams{
+			ItemID:       item.ID,
+			Sku:          duplicateSku(v.Sku, item.ID),
+			Name:         v.Name,
+			UnitID:       v.UnitID,
+			Size:         v.Size,
+			ColorID:      v.ColorID,
+			BasePrice:    v.BasePrice,
+			ReorderLevel: v.ReorderLevel,
+			IsDefault:    v.IsDefault,
+		})
+		if err != nil {
+			return db.Item{}, nil, err
+		}
+		variations = append(variations, variation)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return db.Item{}, nil, err
+	}
+
+	return item, variations, nil
+}
+
+// duplicateSku derives the SKU of a copied variation by suffixing the new
+// item id, cutting the original SKU short to stay within the 50 characters
+// the column allows.
+func duplicateSku(sku string, itemID int32) string {
+	suffix := fmt.Sprintf("-%d", itemID)
+	if len(sku)+len(suffix) > 50 {
+		sku = sku[:50-len(suffix)]
+	}
+	return sku + suffix
+}
+
 func (i *Inventory) CreateUnit(ctx context.Context, args db.CreateUnitParams) (db.Unit, error) {
 	return i.queries.CreateUnit(ctx, args)
 }
@@ -111,3 +249,15 @@ func (i *Inventory) GetColorByID(ctx context.Context, id int32) (db.Color, error
 func (i *Inventory) GetColorByName(ctx context.Context, name string) (db.Color, error) {
 	return i.queries.GetColorByName(ctx, name)
 }
+
+// GetOwnedBusinessID returns the business the catalog is scoped to. If
+// params.BusinessID is not set the owner's first business is used.
+func (i *Inventory) GetOwnedBusinessID(ctx context.Context, params db.GetOwnedBusinessIDParams) (int32, error) {
+	return i.queries.GetOwnedBusinessID(ctx, params)
+}
+
+// SetInventoryMinKeep sets the stock a store keeps of a variation, transfers
+// out may not go below it.
+func (i *Inventory) SetInventoryMinKeep(ctx context.Context, params db.SetInventoryMinKeepParams) (db.Inventory, error) {
+	return i.queries.SetInventoryMinKeep(ctx, params)
+}
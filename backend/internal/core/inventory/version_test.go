@@ -0,0 +1,75 @@
+package inventory
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	db "herp/db/sqlc"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestUpdateItemVersion(t *testing.T) {
+	q := newCatalogQuerier()
+	item := q.addItem(10, "Palm wine", q.addCategory(10, "Drinks", 0).ID)
+	service := NewInventory(q, nil)
+	ctx := context.Background()
+
+	updated, err := service.UpdateItem(ctx, db.UpdateItemParams{
+		ID: item.ID, BusinessID: nullInt(10), Name: sql.NullString{String: "Fresh palm wine", Valid: true},
+		ExpectedVersion: sql.NullInt32{Int32: 1, Valid: true},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, int32(2), updated.Version)
+
+	// made from the copy read before the first update
+	_, err = service.UpdateItem(ctx, db.UpdateItemParams{
+		ID: item.ID, BusinessID: nullInt(10), Name: sql.NullString{String: "Old palm wine", Valid: true},
+		ExpectedVersion: sql.NullInt32{Int32: 1, Valid: true},
+	})
+	assert.ErrorIs(t, err, ErrRecordModified)
+	got, err := q.GetItem(ctx, db.GetItemParams{ID: item.ID})
+	require.NoError(t, err)
+	assert.Equal(t, "Fresh palm wine", got.Name)
+	assert.Equal(t, int32(2), got.Version)
+
+	// a missing item is still missing, whatever the version
+	_, err = service.UpdateItem(ctx, db.UpdateItemParams{
+		ID: item.ID + 100, BusinessID: nullInt(10), ExpectedVersion: sql.NullInt32{Int32: 1, Valid: true},
+	})
+	assert.ErrorIs(t, err, sql.ErrNoRows)
+
+	// without a version the update always goes through
+	updated, err = service.UpdateItem(ctx, db.UpdateItemParams{
+		ID: item.ID, BusinessID: nullInt(10), Name: sql.NullString{String: "Palm wine", Valid: true},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, int32(3), updated.Version)
+}
+
+func TestUpdateItemStaleVersion(t *testing.T) {
+	q := newCatalogQuerier()
+	item := q.addItem(10, "Palm wine", q.addCategory(10, "Drinks", 0).ID)
+	h, r := newCatalogRouter(q, 1)
+	r.PATCH("/inventory/item/:id", h.updateItem)
+
+	w := serve(r, http.MethodPatch, "/inventory/item/"+itoa(item.ID), `{"name":"Fresh palm wine","version":1}`)
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	var body struct {
+		Data UpdateItemResponse `json:"data"`
+	}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &body))
+	assert.Equal(t, int32(2), body.Data.Version)
+
+	w = serve(r, http.MethodPatch, "/inventory/item/"+itoa(item.ID), `{"name":"Old palm wine","version":1}`)
+	assert.Equal(t, http.StatusConflict, w.Code)
+	got, err := q.GetItem(context.Background(), db.GetItemParams{ID: item.ID})
+	require.NoError(t, err)
+	assert.Equal(t, "Fresh palm wine", got.Name)
+
+	w = serve(r, http.MethodPatch, "/inventory/item/"+itoa(item.ID), `{"name":"Palm wine","version":0}`)
+	assert.Equal(t, http.StatusUnprocessableEntity, w.Code)
+}
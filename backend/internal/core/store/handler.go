@@ -2,12 +2,15 @@ package store
 
 import (
 	"database/sql"
+	"errors"
 	"fmt"
 	db "herp/db/sqlc"
 	"herp/internal/auth"
+	"herp/internal/core/inventory"
 	"herp/internal/utils"
 	"herp/pkg/jwt"
 	"herp/pkg/monitoring/logging"
+	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/lib/pq"
@@ -31,8 +34,13 @@ func (h *Handler) RegisterRoutes(r *gin.RouterGroup, authSvc *auth.Service) {
 	{
 		store.POST("/", h.CreateStore)
 		store.GET("/:id", h.GetStoreByID)
-		store.PUT("/", h.UpdateStore)
+		store.PUT("/:id", h.UpdateStore)
 		store.DELETE("/:id", h.DeleteStore)
+		store.POST("/transfer", h.TransferStock)
+		store.POST("/:id/stocktake", h.OpenStocktake)
+		store.GET("/:id/stocktake/:session", h.GetStocktake)
+		store.PUT("/:id/stocktake/:session/lines", h.RecordStocktakeCounts)
+		store.POST("/:id/stocktake/:session/commit", h.CommitStocktake)
 	}
 }
 
@@ -61,6 +69,7 @@ type storeParams struct {
 // @Failure 400
 // @Failure 401
 // @Failure 403
+// @Failure 409
 // @Failure 500
 // @Router /store [post]
 func (h *Handler) CreateStore(c *gin.Context) {
@@ -74,7 +83,7 @@ func (h *Handler) CreateStore(c *gin.Context) {
 	var req storeParams
 	if err := c.ShouldBindJSON(&req); err != nil {
 		h.logger.Errorf("Failed to bind create store request error: %v", err)
-		c.JSON(400, gin.H{"error": "Invalid request"})
+		utils.ErrorResponse(c, 400, utils.INVALID_REQUEST_DATA)
 		return
 	}
 
@@ -97,18 +106,13 @@ func (h *Handler) CreateStore(c *gin.Context) {
 
 	store, err := h.service.CreateStore(c.Request.Context(), params)
 	if err != nil {
-		if pqErr, ok := err.(*pq.Error); ok {
-			switch pqErr.Code.Name() {
-			case "unique_violation":
-				if pqErr.Constraint == "unique_central_store_per_branch" {
-					utils.ErrorResponse(c, 400, "A branch can only have one central store")
-					return
-				}
-			}
+		if errors.Is(err, ErrCentralStoreExists) {
+			utils.ErrorResponse(c, 409, "A branch can only have one central store")
+			return
 		}
 
 		h.logger.Errorf("Failed to create store error: %v", err)
-		c.JSON(500, gin.H{"error": "Failed to create store"})
+		utils.ErrorResponse(c, 500, "Failed to create store")
 		return
 	}
 
@@ -148,20 +152,647 @@ func (h *Handler) GetStoreByID(c *gin.Context) {
 	_, err := fmt.Sscan(idParam, &id)
 	if err != nil {
 		h.logger.Errorf("Invalid store ID error: %v", err)
-		c.JSON(400, gin.H{"error": "Invalid store ID"})
+		utils.ErrorResponse(c, 400, "Invalid store ID")
 		return
 	}
 
 	store, err := h.service.GetStoreByID(c.Request.Context(), id)
 	if err != nil {
 		h.logger.Errorf("Failed to get store: %v", err)
-		c.JSON(500, gin.H{"error": "Failed to get store"})
+		utils.ErrorResponse(c, 500, "Failed to get store")
+		return
+	}
+
+	utils.SuccessResponse(c, 200, "store retrieved", store)
+}
+
+type updateStoreParams struct {
+	Name            *string `json:"name" example:"Main Street Store"`
+	Description     *string `json:"description"`
+	Address         *string `json:"address" example:"123 Main St, Cityville"`
+	Phone           *string `json:"phone" example:"+1234567890"`
+	Email           *string `json:"email" binding:"omitempty,email" example:""`
+	IsCentral       *bool   `json:"is_central" example:"false"`
+	IsActive        *bool   `json:"is_active" example:"true"`
+	AssignedUser    *int32  `json:"assigned_user" example:"1"`
+	AssignedManager *int32  `json:"assigned_manager" example:"1"`
+}
+
+// ownedStore loads a store that belongs to a branch of one of the caller's
+// businesses. Stores of other businesses are reported as not found.
+func (h *Handler) ownedStore(c *gin.Context, claims *jwt.Claims) (db.Store, bool) {
+	var id int32
+	if _, err := fmt.Sscan(c.Param("id"), &id); err != nil {
+		h.logger.Errorf("Invalid store ID error: %v", err)
+		utils.ErrorResponse(c, 400, "Invalid store ID")
+		return db.Store{}, false
+	}
+
+	store, err := h.service.GetStoreForOwner(c, db.GetStoreForOwnerParams{
+		ID:      id,
+		OwnerID: int32(claims.UserID),
+	})
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			utils.ErrorResponse(c, 404, fmt.Sprintf("store with id %d does not exist", id))
+			return db.Store{}, false
+		}
+		h.logger.Errorf("error getting store with id %d: %v", id, err)
+		utils.ErrorResponse(c, 500, utils.SERVERERROR)
+		return db.Store{}, false
+	}
+
+	return store, true
+}
+
+// UpdateStore godoc
+// @Summary Update a store
+// @Description Update the given fields of a store, fields left out are unchanged
+// @Tags store
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "store id"
+// @Param body body updateStoreParams true "store fields to update"
+// @Success 200 {object} storeParams
+// @Failure 400
+// @Failure 401
+// @Failure 403
+// @Failure 404
+// @Failure 409
+// @Failure 500
+// @Router /store/{id} [put]
+func (h *Handler) UpdateStore(c *gin.Context) {
+	claims, ok := jwt.GetUserFromContext(c)
+	if !ok {
+		h.logger.Errorf("could not get user from context")
+		utils.ErrorResponse(c, 500, utils.SERVERERROR)
+		return
+	}
+
+	var req updateStoreParams
+	if err := c.ShouldBindJSON(&req); err != nil {
+		h.logger.Errorf("error binding update store request data: %v", err)
+		utils.ErrorResponse(c, 400, utils.INVALID_REQUEST_DATA)
+		return
+	}
+
+	current, ok := h.ownedStore(c, claims)
+	if !ok {
+		return
+	}
+
+	params := db.UpdateStoreParams{ID: current.ID}
+	utils.PatchNullString(&params.Name, req.Name)
+	utils.PatchNullString(&params.Description, req.Description)
+	utils.PatchNullString(&params.Address, req.Address)
+	utils.PatchNullString(&params.Phone, req.Phone)
+	utils.PatchNullString(&params.Email, req.Email)
+	utils.PatchNullBool(&params.IsActive, req.IsActive)
+	utils.PatchNullInt32(&params.AssignedUser, req.AssignedUser)
+	utils.PatchNullInt32(&params.ManagerID, req.AssignedManager)
+	if req.IsCentral != nil {
+		params.StoreType = sql.NullString{String: "sub-store", Valid: true}
+		if *req.IsCentral {
+			params.StoreType.String = "central"
+		}
+	}
+
+	store, err := h.service.UpdateStore(c, params)
+	if err != nil {
+		if errors.Is(err, ErrCentralStoreExists) {
+			utils.ErrorResponse(c, 409, "A branch can only have one central store")
+			return
+		}
+		h.logger.Errorf("error updating store with id %d: %v", current.ID, err)
+		utils.ErrorResponse(c, 500, utils.SERVERERROR)
+		return
+	}
+
+	_, err = h.service.LogActivity(c, db.LogActivityParams{
+		UserID:     int32(claims.UserID),
+		Action:     "Updated Store",
+		EntityType: "Store",
+		EntityID:   store.ID,
+		Details:    utils.WriteActivityDetails(claims.Username, claims.Email, fmt.Sprintf("Updated store %s", store.Name), store.UpdatedAt.Time),
+		IpAddress:  sql.NullString{Valid: true, String: utils.GetClientIP(c)},
+		UserAgent:  sql.NullString{Valid: true, String: c.Request.UserAgent()},
+	})
+	if err != nil {
+		h.logger.Warnf("error logging update store activity: %v", err)
+	}
+
+	utils.SuccessResponse(c, 200, "store updated", storeParams{
+		Name:            store.Name,
+		BranchID:        store.BranchID,
+		Description:     store.Description.String,
+		Address:         store.Address,
+		Phone:           store.Phone,
+		Email:           store.Email,
+		StoreCode:       store.StoreCode,
+		IsCentral:       store.StoreType == "central",
+		IsActive:        store.IsActive.Bool,
+		AssignedUser:    store.AssignedUser.Int32,
+		AssignedManager: store.ManagerID.Int32,
+	})
+}
+
+// DeleteStore godoc
+// @Summary Delete a store
+// @Description Delete a store. A central store can't be deleted while its branch has sub-stores, and no store can be deleted while it holds stock.
+// @Tags store
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "store id"
+// @Success 200
+// @Failure 400
+// @Failure 401
+// @Failure 403
+// @Failure 404
+// @Failure 409
+// @Failure 500
+// @Router /store/{id} [delete]
+func (h *Handler) DeleteStore(c *gin.Context) {
+	claims, ok := jwt.GetUserFromContext(c)
+	if !ok {
+		h.logger.Errorf("could not get user from context")
+		utils.ErrorResponse(c, 500, utils.SERVERERROR)
+		return
+	}
+
+	store, ok := h.ownedStore(c, claims)
+	if !ok {
+		return
+	}
+
+	err := h.service.DeleteStore(c, store.ID)
+	if err != nil {
+		switch {
+		case errors.Is(err, ErrStoreHasSubStores):
+			utils.ErrorResponse(c, 409, "central store still has sub-stores, delete them first")
+		case errors.Is(err, ErrStoreHasInventory):
+			utils.ErrorResponse(c, 409, "store still holds inventory, transfer it out first")
+		default:
+			if pgErr, ok := err.(*pq.Error); ok && pgErr.Code == "23503" { // foreign_key_violation
+				utils.ErrorResponse(c, 409, "store has sales and can't be deleted, deactivate it instead")
+				return
+			}
+			h.logger.Errorf("error deleting store with id %d: %v", store.ID, err)
+			utils.ErrorResponse(c, 500, utils.SERVERERROR)
+		}
+		return
+	}
+
+	_, err = h.service.LogActivity(c, db.LogActivityParams{
+		UserID:     int32(claims.UserID),
+		Action:     "Deleted Store",
+		EntityType: "Store",
+		EntityID:   store.ID,
+		Details:    utils.WriteActivityDetails(claims.Username, claims.Email, fmt.Sprintf("Deleted store %s", store.Name), time.Now()),
+		IpAddress:  sql.NullString{Valid: true, String: utils.GetClientIP(c)},
+		UserAgent:  sql.NullString{Valid: true, String: c.Request.UserAgent()},
+	})
+	if err != nil {
+		h.logger.Warnf("error logging delete store activity: %v", err)
+	}
+
+	utils.SuccessResponse(c, 200, "store deleted", nil)
+}
+
+type TransferItemRequest struct {
+	VariationID int32 `json:"variation_id" binding:"required" example:"1"`
+	Quantity    int32 `json:"quantity" binding:"required,gt=0" example:"5"`
+}
+
+type TransferRequest struct {
+	FromStoreID int32                 `json:"from_store_id" binding:"required" example:"1"`
+	ToStoreID   int32                 `json:"to_store_id" binding:"required" example:"2"`
+	Items       []TransferItemRequest `json:"items" binding:"required,min=1,dive"`
+	// transfer even if the source store goes below its minimum stock
+	OverrideMinKeep bool `json:"override_min_keep" example:"false"`
+}
+
+type TransferItemResponse struct {
+	VariationID  int32 `json:"variation_id"`
+	Quantity     int32 `json:"quantity"`
+	FromQuantity int32 `json:"from_quantity"`
+	ToQuantity   int32 `json:"to_quantity"`
+	MinKeep      int32 `json:"min_keep"`
+	BelowMinKeep bool  `json:"below_min_keep"`
+}
+
+type TransferResponse struct {
+	ID          int32                  `json:"id"`
+	FromStoreID int32                  `json:"from_store_id"`
+	ToStoreID   int32                  `json:"to_store_id"`
+	Items       []TransferItemResponse `json:"items"`
+}
+
+// TransferStock godoc
+// @Summary Transfer stock between stores
+// @Description Move stock from one store to another, both must belong to a business of the caller. Returns the quantities left in both stores. Transfers that would take the source store below the minimum stock it keeps are refused unless override_min_keep is set, the lines that went below are then flagged.
+// @Tags store
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param body body TransferRequest true "transfer details"
+// @Success 201 {object} TransferResponse
+// @Failure 400
+// @Failure 401
+// @Failure 403
+// @Failure 404
+// @Failure 409
+// @Failure 500
+// @Router /store/transfer [post]
+func (h *Handler) TransferStock(c *gin.Context) {
+	claims, ok := jwt.GetUserFromContext(c)
+	if !ok {
+		h.logger.Errorf("could not get user from context")
+		utils.ErrorResponse(c, 500, utils.SERVERERROR)
+		return
+	}
+
+	var req TransferRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		h.logger.Errorf("error binding transfer request data: %v", err)
+		utils.ErrorResponse(c, 400, utils.INVALID_REQUEST_DATA)
+		return
+	}
+
+	lines := make([]TransferLine, 0, len(req.Items))
+	for _, item := range req.Items {
+		lines = append(lines, TransferLine{VariationID: item.VariationID, Quantity: item.Quantity})
+	}
+
+	result, err := h.service.TransferStock(c, TransferStockParams{
+		FromStoreID:     req.FromStoreID,
+		ToStoreID:       req.ToStoreID,
+		TransferredBy:   int32(claims.UserID),
+		OwnerID:         int32(claims.UserID),
+		Lines:           lines,
+		OverrideMinKeep: req.OverrideMinKeep,
+	})
+	if err != nil {
+		switch {
+		case errors.Is(err, ErrSameStore), errors.Is(err, ErrDuplicateTransferItem):
+			utils.ErrorResponse(c, 400, err.Error())
+		case errors.Is(err, ErrStoreNotFound):
+			utils.ErrorResponse(c, 404, err.Error())
+		case errors.Is(err, ErrStoreInactive), errors.Is(err, inventory.ErrInsufficientStock), errors.Is(err, ErrBelowMinKeep):
+			utils.ErrorResponse(c, 409, err.Error())
+		default:
+			h.logger.Errorf("error transferring stock: %v", err)
+			utils.ErrorResponse(c, 500, utils.SERVERERROR)
+		}
+		return
+	}
+
+	description := fmt.Sprintf("Transferred %d items from store %d to store %d", len(result.Lines), req.FromStoreID, req.ToStoreID)
+	var belowMinKeep int
+	for _, line := range result.Lines {
+		if line.BelowMinKeep {
+			belowMinKeep++
+		}
+	}
+	if belowMinKeep > 0 {
+		description += fmt.Sprintf(", overriding the minimum stock of %d items", belowMinKeep)
+	}
+
+	_, err = h.service.LogActivity(c, db.LogActivityParams{
+		UserID:     int32(claims.UserID),
+		Action:     "Transferred Stock",
+		EntityType: "StoreTransfer",
+		EntityID:   result.Transfer.ID,
+		Details:    utils.WriteActivityDetails(claims.Username, claims.Email, description, result.Transfer.CreatedAt.Time),
+		IpAddress:  sql.NullString{Valid: true, String: utils.GetClientIP(c)},
+		UserAgent:  sql.NullString{Valid: true, String: c.Request.UserAgent()},
+	})
+	if err != nil {
+		h.logger.Warnf("error logging transfer activity: %v", err)
+	}
+
+	items := make([]TransferItemResponse, 0, len(result.Lines))
+	for _, line := range result.Lines {
+		items = append(items, TransferItemResponse{
+			VariationID:  line.VariationID,
+			Quantity:     line.Quantity,
+			FromQuantity: line.FromQuantity,
+			ToQuantity:   line.ToQuantity,
+			MinKeep:      line.MinKeep,
+			BelowMinKeep: line.BelowMinKeep,
+		})
+	}
+
+	utils.SuccessResponse(c, 201, "stock transferred", TransferResponse{
+		ID:          result.Transfer.ID,
+		FromStoreID: result.Transfer.FromStoreID,
+		ToStoreID:   result.Transfer.ToStoreID,
+		Items:       items,
+	})
+}
+
+type StocktakeCountRequest struct {
+	VariationID     int32  `json:"variation_id" binding:"required" example:"1"`
+	CountedQuantity *int32 `json:"counted_quantity" binding:"required,gte=0" example:"10"`
+}
+
+type RecordStocktakeRequest struct {
+	Lines []StocktakeCountRequest `json:"lines" binding:"required,min=1,dive"`
+}
+
+type StocktakeLineResponse struct {
+	VariationID     int32  `json:"variation_id"`
+	SystemQuantity  int32  `json:"system_quantity"`
+	CountedQuantity *int32 `json:"counted_quantity"`
+	// counted minus system, null until the variation is counted
+	Variance *int32 `json:"variance"`
+	// set once the stocktake is committed and stock was adjusted
+	AdjustmentID *int32 `json:"adjustment_id"`
+}
+
+type StocktakeResponse struct {
+	ID            int32                   `json:"id"`
+	StoreID       int32                   `json:"store_id"`
+	Status        string                  `json:"status"`
+	OpenedBy      int32                   `json:"opened_by"`
+	CreatedAt     time.Time               `json:"created_at"`
+	CommittedBy   *int32                  `json:"committed_by"`
+	CommittedAt   *time.Time              `json:"committed_at"`
+	Counted       int                     `json:"counted"`
+	Discrepancies int                     `json:"discrepancies"`
+	Lines         []StocktakeLineResponse `json:"lines"`
+}
+
+func toStocktakeResponse(result StocktakeResult) StocktakeResponse {
+	stocktake := result.Stocktake
+	response := StocktakeResponse{
+		ID:        stocktake.ID,
+		StoreID:   stocktake.StoreID,
+		Status:    stocktake.Status,
+		OpenedBy:  stocktake.OpenedBy,
+		CreatedAt: stocktake.CreatedAt.Time,
+		Lines:     make([]StocktakeLineResponse, 0, len(result.Lines)),
+	}
+	if stocktake.CommittedBy.Valid {
+		response.CommittedBy = &stocktake.CommittedBy.Int32
+	}
+	if stocktake.CommittedAt.Valid {
+		response.CommittedAt = &stocktake.CommittedAt.Time
+	}
+
+	for _, line := range result.Lines {
+		lineResponse := StocktakeLineResponse{
+			VariationID:    line.VariationID,
+			SystemQuantity: line.SystemQuantity,
+		}
+		if variance, ok := StocktakeVariance(line); ok {
+			lineResponse.CountedQuantity = &line.CountedQuantity.Int32
+			lineResponse.Variance = &variance
+			response.Counted++
+			if variance != 0 {
+				response.Discrepancies++
+			}
+		}
+		if line.AdjustmentID.Valid {
+			lineResponse.AdjustmentID = &line.AdjustmentID.Int32
+		}
+		response.Lines = append(response.Lines, lineResponse)
+	}
+	return response
+}
+
+// stocktakeParams reads the store and stocktake ids from the path.
+func (h *Handler) stocktakeParams(c *gin.Context) (db.GetStocktakeParams, bool) {
+	var params db.GetStocktakeParams
+	if _, err := fmt.Sscan(c.Param("id"), &params.StoreID); err != nil {
+		utils.ErrorResponse(c, 400, "Invalid store ID")
+		return params, false
+	}
+	if _, err := fmt.Sscan(c.Param("session"), &params.ID); err != nil {
+		utils.ErrorResponse(c, 400, "Invalid stocktake ID")
+		return params, false
+	}
+	return params, true
+}
+
+// stocktakeError writes the response for an error of a stocktake operation.
+func (h *Handler) stocktakeError(c *gin.Context, err error, action string) {
+	switch {
+	case errors.Is(err, ErrStoreNotFound), errors.Is(err, ErrStocktakeNotFound):
+		utils.ErrorResponse(c, 404, err.Error())
+	case errors.Is(err, ErrDuplicateStocktakeItem):
+		utils.ErrorResponse(c, 400, err.Error())
+	case errors.Is(err, ErrStoreInactive), errors.Is(err, ErrStocktakeAlreadyOpen), errors.Is(err, ErrStocktakeCommitted):
+		utils.ErrorResponse(c, 409, err.Error())
+	default:
+		if pgErr, ok := err.(*pq.Error); ok && pgErr.Code == "23503" { // foreign_key_violation
+			utils.ErrorResponse(c, 400, "variation does not exist")
+			return
+		}
+		h.logger.Errorf("error %s stocktake: %v", action, err)
+		utils.ErrorResponse(c, 500, utils.SERVERERROR)
+	}
+}
+
+// OpenStocktake godoc
+// @Summary Open a stocktake
+// @Description Start a physical count of a store. The stock on hand is snapshotted into the lines of the session, counts are recorded against it and the variances are applied when the stocktake is committed. A store has one open stocktake at a time.
+// @Tags store
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "store id"
+// @Success 201 {object} StocktakeResponse
+// @Failure 400
+// @Failure 401
+// @Failure 403
+// @Failure 404
+// @Failure 409
+// @Failure 500
+// @Router /store/{id}/stocktake [post]
+func (h *Handler) OpenStocktake(c *gin.Context) {
+	claims, ok := jwt.GetUserFromContext(c)
+	if !ok {
+		h.logger.Errorf("could not get user from context")
+		utils.ErrorResponse(c, 500, utils.SERVERERROR)
+		return
+	}
+
+	store, ok := h.ownedStore(c, claims)
+	if !ok {
+		return
+	}
+
+	result, err := h.service.OpenStocktake(c, store.ID, int32(claims.UserID))
+	if err != nil {
+		h.stocktakeError(c, err, "opening")
 		return
 	}
 
-	c.JSON(200, store)
+	_, err = h.service.LogActivity(c, db.LogActivityParams{
+		UserID:     int32(claims.UserID),
+		Action:     "Opened Stocktake",
+		EntityType: "Stocktake",
+		EntityID:   result.Stocktake.ID,
+		Details:    utils.WriteActivityDetails(claims.Username, claims.Email, fmt.Sprintf("Opened a stocktake of store %s with %d lines", store.Name, len(result.Lines)), result.Stocktake.CreatedAt.Time),
+		IpAddress:  sql.NullString{Valid: true, String: utils.GetClientIP(c)},
+		UserAgent:  sql.NullString{Valid: true, String: c.Request.UserAgent()},
+	})
+	if err != nil {
+		h.logger.Warnf("error logging open stocktake activity: %v", err)
+	}
+
+	utils.SuccessResponse(c, 201, "stocktake opened", toStocktakeResponse(result))
 }
 
-func (h *Handler) UpdateStore(c *gin.Context) {}
+// GetStocktake godoc
+// @Summary Get a stocktake
+// @Description Get a stocktake with the variance of every counted line, to review it before it is committed.
+// @Tags store
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "store id"
+// @Param session path int true "stocktake id"
+// @Success 200 {object} StocktakeResponse
+// @Failure 400
+// @Failure 401
+// @Failure 403
+// @Failure 404
+// @Failure 500
+// @Router /store/{id}/stocktake/{session} [get]
+func (h *Handler) GetStocktake(c *gin.Context) {
+	claims, ok := jwt.GetUserFromContext(c)
+	if !ok {
+		h.logger.Errorf("could not get user from context")
+		utils.ErrorResponse(c, 500, utils.SERVERERROR)
+		return
+	}
 
-func (h *Handler) DeleteStore(c *gin.Context) {}
+	params, ok := h.stocktakeParams(c)
+	if !ok {
+		return
+	}
+	if _, ok := h.ownedStore(c, claims); !ok {
+		return
+	}
+
+	result, err := h.service.GetStocktake(c, params)
+	if err != nil {
+		h.stocktakeError(c, err, "getting")
+		return
+	}
+
+	utils.SuccessResponse(c, 200, "stocktake retrieved", toStocktakeResponse(result))
+}
+
+// RecordStocktakeCounts godoc
+// @Summary Record stocktake counts
+// @Description Record counted quantities on an open stocktake and get the variances back. Counting a variation again replaces its count, a variation the store did not stock when the count was opened is added with a system quantity of 0.
+// @Tags store
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "store id"
+// @Param session path int true "stocktake id"
+// @Param body body RecordStocktakeRequest true "counted quantities"
+// @Success 200 {object} StocktakeResponse
+// @Failure 400
+// @Failure 401
+// @Failure 403
+// @Failure 404
+// @Failure 409
+// @Failure 422
+// @Failure 500
+// @Router /store/{id}/stocktake/{session}/lines [put]
+func (h *Handler) RecordStocktakeCounts(c *gin.Context) {
+	claims, ok := jwt.GetUserFromContext(c)
+	if !ok {
+		h.logger.Errorf("could not get user from context")
+		utils.ErrorResponse(c, 500, utils.SERVERERROR)
+		return
+	}
+
+	params, ok := h.stocktakeParams(c)
+	if !ok {
+		return
+	}
+
+	var req RecordStocktakeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		h.logger.Errorf("error binding stocktake count request data: %v", err)
+		utils.BindingErrorResponse(c, err)
+		return
+	}
+
+	if _, ok := h.ownedStore(c, claims); !ok {
+		return
+	}
+
+	counts := make([]StocktakeCount, 0, len(req.Lines))
+	for _, line := range req.Lines {
+		counts = append(counts, StocktakeCount{VariationID: line.VariationID, CountedQuantity: *line.CountedQuantity})
+	}
+
+	result, err := h.service.RecordStocktakeCounts(c, params, counts)
+	if err != nil {
+		h.stocktakeError(c, err, "recording counts on")
+		return
+	}
+
+	utils.SuccessResponse(c, 200, "stocktake counts recorded", toStocktakeResponse(result))
+}
+
+// CommitStocktake godoc
+// @Summary Commit a stocktake
+// @Description Apply the variances of the counted lines as stocktake adjustments and close the stocktake. Variances are taken against the stock on hand when the count was opened, lines that were never counted are left alone. A stocktake can only be committed once.
+// @Tags store
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "store id"
+// @Param session path int true "stocktake id"
+// @Success 200 {object} StocktakeResponse
+// @Failure 400
+// @Failure 401
+// @Failure 403
+// @Failure 404
+// @Failure 409
+// @Failure 500
+// @Router /store/{id}/stocktake/{session}/commit [post]
+func (h *Handler) CommitStocktake(c *gin.Context) {
+	claims, ok := jwt.GetUserFromContext(c)
+	if !ok {
+		h.logger.Errorf("could not get user from context")
+		utils.ErrorResponse(c, 500, utils.SERVERERROR)
+		return
+	}
+
+	params, ok := h.stocktakeParams(c)
+	if !ok {
+		return
+	}
+	store, ok := h.ownedStore(c, claims)
+	if !ok {
+		return
+	}
+
+	result, err := h.service.CommitStocktake(c, params, int32(claims.UserID))
+	if err != nil {
+		h.stocktakeError(c, err, "committing")
+		return
+	}
+
+	response := toStocktakeResponse(result)
+	_, err = h.service.LogActivity(c, db.LogActivityParams{
+		UserID:     int32(claims.UserID),
+		Action:     "Committed Stocktake",
+		EntityType: "Stocktake",
+		EntityID:   result.Stocktake.ID,
+		Details:    utils.WriteActivityDetails(claims.Username, claims.Email, fmt.Sprintf("Committed the stocktake of store %s, counted %d variations and adjusted %d", store.Name, response.Counted, response.Discrepancies), result.Stocktake.CommittedAt.Time),
+		IpAddress:  sql.NullString{Valid: true, String: utils.GetClientIP(c)},
+		UserAgent:  sql.NullString{Valid: true, String: c.Request.UserAgent()},
+	})
+	if err != nil {
+		h.logger.Warnf("error logging commit stocktake activity: %v", err)
+	}
+
+	utils.SuccessResponse(c, 200, "stocktake committed", response)
+}
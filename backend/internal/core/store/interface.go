@@ -16,13 +16,25 @@ type Querier interface {
 	ListStores(ctx context.Context) ([]db.Store, error)
 	UpdateStore(ctx context.Context, params db.UpdateStoreParams) (db.Store, error)
 	SearchStoresByName(ctx context.Context, name sql.NullString) ([]db.Store, error)
+	GetStoreForOwner(ctx context.Context, params db.GetStoreForOwnerParams) (db.Store, error)
+	CountSubStoresByBranch(ctx context.Context, branchID int32) (int64, error)
+	CountStoreStock(ctx context.Context, storeID int32) (int64, error)
 	LogActivity(ctx context.Context, params db.LogActivityParams) (db.ActivityLog, error)
+	GetStocktake(ctx context.Context, params db.GetStocktakeParams) (db.Stocktake, error)
+	ListStocktakeLines(ctx context.Context, stocktakeID int32) ([]db.StocktakeLine, error)
 }
 
 type StoreInterface interface {
 	CreateStore(ctx context.Context, params db.CreateStoreParams) (db.Store, error)
 	DeleteStore(ctx context.Context, id int32) error
 	GetStoreByID(ctx context.Context, id int32) (db.Store, error)
+	GetCentralStoreByBranch(ctx context.Context, branchID int32) (db.Store, error)
+	GetStoreForOwner(ctx context.Context, params db.GetStoreForOwnerParams) (db.Store, error)
 	UpdateStore(ctx context.Context, params db.UpdateStoreParams) (db.Store, error)
+	TransferStock(ctx context.Context, args TransferStockParams) (TransferResult, error)
+	OpenStocktake(ctx context.Context, storeID, openedBy int32) (StocktakeResult, error)
+	GetStocktake(ctx context.Context, params db.GetStocktakeParams) (StocktakeResult, error)
+	RecordStocktakeCounts(ctx context.Context, params db.GetStocktakeParams, counts []StocktakeCount) (StocktakeResult, error)
+	CommitStocktake(ctx context.Context, params db.GetStocktakeParams, committedBy int32) (StocktakeResult, error)
 	LogActivity(ctx context.Context, params db.LogActivityParams) (db.ActivityLog, error)
 }
@@ -3,7 +3,16 @@ package store
 import (
 	"context"
 	"database/sql"
+	"errors"
 	db "herp/db/sqlc"
+
+	"github.com/lib/pq"
+)
+
+var (
+	ErrCentralStoreExists = errors.New("a branch can only have one central store")
+	ErrStoreHasSubStores  = errors.New("central store still has sub-stores in its branch")
+	ErrStoreHasInventory  = errors.New("store still holds inventory")
 )
 
 type Store struct {
@@ -15,11 +24,45 @@ func NewStore(db *sql.DB, queries Querier) *Store {
 	return &Store{db, queries}
 }
 
+// CreateStore creates a store, refusing a second central store in a branch.
 func (s *Store) CreateStore(ctx context.Context, params db.CreateStoreParams) (db.Store, error) {
-	return s.queries.CreateStore(ctx, params)
+	if params.StoreType == "central" {
+		if err := s.ensureNoOtherCentralStore(ctx, params.BranchID, 0); err != nil {
+			return db.Store{}, err
+		}
+	}
+
+	store, err := s.queries.CreateStore(ctx, params)
+	return store, centralStoreError(err)
 }
 
+// DeleteStore deletes a store. A central store is kept while its branch has
+// sub-stores, and no store is deleted while it still holds stock, the stock
+// has to be transferred out first.
 func (s *Store) DeleteStore(ctx context.Context, id int32) error {
+	store, err := s.queries.GetStoreByID(ctx, id)
+	if err != nil {
+		return err
+	}
+
+	if store.StoreType == "central" {
+		subStores, err := s.queries.CountSubStoresByBranch(ctx, store.BranchID)
+		if err != nil {
+			return err
+		}
+		if subStores > 0 {
+			return ErrStoreHasSubStores
+		}
+	}
+
+	stock, err := s.queries.CountStoreStock(ctx, store.ID)
+	if err != nil {
+		return err
+	}
+	if stock > 0 {
+		return ErrStoreHasInventory
+	}
+
 	return s.queries.DeleteStore(ctx, id)
 }
 
@@ -27,10 +70,61 @@ func (s *Store) GetStoreByID(ctx context.Context, id int32) (db.Store, error) {
 	return s.queries.GetStoreByID(ctx, id)
 }
 
+// GetStoreForOwner returns a store if it belongs to a branch of one of the
+// owner's businesses, or sql.ErrNoRows otherwise.
+func (s *Store) GetStoreForOwner(ctx context.Context, params db.GetStoreForOwnerParams) (db.Store, error) {
+	return s.queries.GetStoreForOwner(ctx, params)
+}
+
+// GetCentralStoreByBranch returns the central store of a branch, or
+// sql.ErrNoRows if the branch has none.
+func (s *Store) GetCentralStoreByBranch(ctx context.Context, branchID int32) (db.Store, error) {
+	return s.queries.GetCentralStoreByBranch(ctx, branchID)
+}
+
+// UpdateStore updates a store, refusing to make it central when its branch
+// already has another central store.
 func (s *Store) UpdateStore(ctx context.Context, params db.UpdateStoreParams) (db.Store, error) {
-	return s.queries.UpdateStore(ctx, params)
+	if params.StoreType.Valid && params.StoreType.String == "central" {
+		current, err := s.queries.GetStoreByID(ctx, params.ID)
+		if err != nil {
+			return db.Store{}, err
+		}
+		if err := s.ensureNoOtherCentralStore(ctx, current.BranchID, current.ID); err != nil {
+			return db.Store{}, err
+		}
+	}
+
+	store, err := s.queries.UpdateStore(ctx, params)
+	return store, centralStoreError(err)
 }
 
 func (s *Store) LogActivity(ctx context.Context, params db.LogActivityParams) (db.ActivityLog, error) {
 	return s.queries.LogActivity(ctx, params)
-}
\ No newline at end of file
+}
+
+// ensureNoOtherCentralStore returns ErrCentralStoreExists if the branch has a
+// central store other than storeID.
+func (s *Store) ensureNoOtherCentralStore(ctx context.Context, branchID, storeID int32) error {
+	central, err := s.queries.GetCentralStoreByBranch(ctx, branchID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil
+		}
+		return err
+	}
+	if central.ID != storeID {
+		return ErrCentralStoreExists
+	}
+	return nil
+}
+
+// centralStoreError maps a violation of unique_central_store_per_branch, which
+// a concurrent write can still hit after the check, to ErrCentralStoreExists.
+func centralStoreError(err error) error {
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) && pqErr.Code.Name() == "unique_violation" && pqErr.Constraint == "unique_central_store_per_branch" {
+		return ErrCentralStoreExists
+	}
+	return err
+}
@@ -0,0 +1,204 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	db "herp/db/sqlc"
+	"herp/internal/config"
+	"herp/pkg/jwt"
+	"herp/pkg/monitoring/logging"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/lib/pq"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// storeQuerier keeps stores in memory, all of them owned by owner 1.
+type storeQuerier struct {
+	Querier
+	stores     map[int32]db.Store
+	updates    []db.UpdateStoreParams
+	activities []db.LogActivityParams
+	// updateErr is returned by UpdateStore and CreateStore when set
+	updateErr error
+}
+
+func newStoreQuerier(stores ...db.Store) *storeQuerier {
+	q := &storeQuerier{stores: map[int32]db.Store{}}
+	for _, store := range stores {
+		q.stores[store.ID] = store
+	}
+	return q
+}
+
+func (q *storeQuerier) GetStoreByID(ctx context.Context, id int32) (db.Store, error) {
+	store, ok := q.stores[id]
+	if !ok {
+		return db.Store{}, sql.ErrNoRows
+	}
+	return store, nil
+}
+
+func (q *storeQuerier) GetStoreForOwner(ctx context.Context, params db.GetStoreForOwnerParams) (db.Store, error) {
+	if params.OwnerID != 1 {
+		return db.Store{}, sql.ErrNoRows
+	}
+	return q.GetStoreByID(ctx, params.ID)
+}
+
+func (q *storeQuerier) GetCentralStoreByBranch(ctx context.Context, branchID int32) (db.Store, error) {
+	for _, store := range q.stores {
+		if store.BranchID == branchID && store.StoreType == "central" {
+			return store, nil
+		}
+	}
+	return db.Store{}, sql.ErrNoRows
+}
+
+func (q *storeQuerier) CreateStore(ctx context.Context, params db.CreateStoreParams) (db.Store, error) {
+	if q.updateErr != nil {
+		return db.Store{}, q.updateErr
+	}
+	store := db.Store{ID: int32(len(q.stores) + 1), Name: params.Name, BranchID: params.BranchID, StoreType: params.StoreType}
+	q.stores[store.ID] = store
+	return store, nil
+}
+
+func (q *storeQuerier) UpdateStore(ctx context.Context, params db.UpdateStoreParams) (db.Store, error) {
+	if q.updateErr != nil {
+		return db.Store{}, q.updateErr
+	}
+	q.updates = append(q.updates, params)
+	store := q.stores[params.ID]
+	if params.StoreType.Valid {
+		store.StoreType = params.StoreType.String
+	}
+	q.stores[store.ID] = store
+	return store, nil
+}
+
+func (q *storeQuerier) LogActivity(ctx context.Context, params db.LogActivityParams) (db.ActivityLog, error) {
+	q.activities = append(q.activities, params)
+	return db.ActivityLog{}, nil
+}
+
+func branchStores() *storeQuerier {
+	return newStoreQuerier(
+		db.Store{ID: 1, Name: "Main", BranchID: 1, StoreType: "central"},
+		db.Store{ID: 2, Name: "Annex", BranchID: 1, StoreType: "sub-store"},
+		db.Store{ID: 3, Name: "Depot", BranchID: 2, StoreType: "sub-store"},
+	)
+}
+
+func central(central bool) sql.NullString {
+	if central {
+		return sql.NullString{String: "central", Valid: true}
+	}
+	return sql.NullString{String: "sub-store", Valid: true}
+}
+
+func TestUpdateStoreCentral(t *testing.T) {
+	tests := []struct {
+		name    string
+		params  db.UpdateStoreParams
+		wantErr error
+	}{
+		{"second central store in the branch", db.UpdateStoreParams{ID: 2, StoreType: central(true)}, ErrCentralStoreExists},
+		{"the central store itself", db.UpdateStoreParams{ID: 1, StoreType: central(true)}, nil},
+		{"first central store in another branch", db.UpdateStoreParams{ID: 3, StoreType: central(true)}, nil},
+		{"back to a sub-store", db.UpdateStoreParams{ID: 1, StoreType: central(false)}, nil},
+		{"type left out", db.UpdateStoreParams{ID: 2, Name: sql.NullString{String: "Annex 2", Valid: true}}, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			q := branchStores()
+			_, err := NewStore(nil, q).UpdateStore(context.Background(), tt.params)
+			if tt.wantErr != nil {
+				assert.ErrorIs(t, err, tt.wantErr)
+				assert.Empty(t, q.updates)
+				return
+			}
+			require.NoError(t, err)
+			assert.Len(t, q.updates, 1)
+		})
+	}
+}
+
+func TestCreateSecondCentralStore(t *testing.T) {
+	q := branchStores()
+	s := NewStore(nil, q)
+
+	_, err := s.CreateStore(context.Background(), db.CreateStoreParams{Name: "Other", BranchID: 1, StoreType: "central"})
+	assert.ErrorIs(t, err, ErrCentralStoreExists)
+
+	_, err = s.CreateStore(context.Background(), db.CreateStoreParams{Name: "Other", BranchID: 2, StoreType: "central"})
+	assert.NoError(t, err)
+}
+
+func TestCentralStoreConstraintViolation(t *testing.T) {
+	// another request made a store central between the check and the write
+	q := newStoreQuerier(db.Store{ID: 1, BranchID: 1, StoreType: "sub-store"})
+	q.updateErr = &pq.Error{Code: "23505", Constraint: "unique_central_store_per_branch"}
+
+	_, err := NewStore(nil, q).UpdateStore(context.Background(), db.UpdateStoreParams{ID: 1, StoreType: central(true)})
+	assert.ErrorIs(t, err, ErrCentralStoreExists)
+
+	q.updateErr = &pq.Error{Code: "23505", Constraint: "store_name_key"}
+	_, err = NewStore(nil, q).UpdateStore(context.Background(), db.UpdateStoreParams{ID: 1, StoreType: central(true)})
+	assert.NotErrorIs(t, err, ErrCentralStoreExists)
+}
+
+func TestUpdateStoreHandlerCentralConflict(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	q := branchStores()
+	cfg := &config.Config{}
+	h := NewHandler(NewStore(nil, q), logging.NewLogger(cfg))
+
+	r := gin.New()
+	r.Use(func(c *gin.Context) {
+		c.Set("claims", &jwt.Claims{UserID: 1, Username: "owner", Email: "owner@example.com"})
+	})
+	r.PUT("/store/:id", h.UpdateStore)
+
+	w := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodPut, "/store/2", strings.NewReader(`{"is_central":true}`))
+	req.Header.Set("Content-Type", "application/json")
+	r.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusConflict, w.Code, w.Body.String())
+	assert.Equal(t, "sub-store", q.stores[2].StoreType)
+	assert.Empty(t, q.activities)
+}
+
+func TestGetStoreByIDEnvelope(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	cfg := &config.Config{}
+	h := NewHandler(NewStore(nil, branchStores()), logging.NewLogger(cfg))
+	r := gin.New()
+	r.GET("/store/:id", h.GetStoreByID)
+
+	var body struct {
+		Status string   `json:"status"`
+		Error  string   `json:"error"`
+		Data   db.Store `json:"data"`
+	}
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/store/2", nil))
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &body))
+	assert.Equal(t, "success", body.Status)
+	assert.Equal(t, int32(2), body.Data.ID)
+
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/store/x", nil))
+	require.Equal(t, http.StatusBadRequest, w.Code)
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &body))
+	assert.Equal(t, "error", body.Status)
+	assert.Equal(t, "Invalid store ID", body.Error)
+}
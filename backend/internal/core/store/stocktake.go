@@ -0,0 +1,296 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	db "herp/db/sqlc"
+	"herp/internal/core/inventory"
+
+	"github.com/lib/pq"
+)
+
+// MovementStocktake is the reason recorded on adjustments and batch movements
+// that apply the variances of a stocktake.
+const MovementStocktake = "stocktake"
+
+const (
+	StocktakeOpen      = "open"
+	StocktakeCommitted = "committed"
+)
+
+var (
+	ErrStocktakeNotFound      = errors.New("stocktake not found")
+	ErrStocktakeAlreadyOpen   = errors.New("store already has an open stocktake")
+	ErrStocktakeCommitted     = errors.New("stocktake has already been committed")
+	ErrDuplicateStocktakeItem = errors.New("item appears more than once in count")
+)
+
+type StocktakeCount struct {
+	VariationID     int32
+	CountedQuantity int32
+}
+
+type StocktakeResult struct {
+	Stocktake db.Stocktake
+	Lines     []db.StocktakeLine
+}
+
+// StocktakeVariance is counted minus the stock on hand when the stocktake was
+// opened. ok is false while the line has not been counted.
+func StocktakeVariance(line db.StocktakeLine) (variance int32, ok bool) {
+	if !line.CountedQuantity.Valid {
+		return 0, false
+	}
+	return line.CountedQuantity.Int32 - line.SystemQuantity, true
+}
+
+// OpenStocktake starts a count of a store, snapshotting the stock it holds
+// into the lines of the session. A store has at most one open stocktake.
+func (s *Store) OpenStocktake(ctx context.Context, storeID, openedBy int32) (StocktakeResult, error) {
+	q, ok := s.queries.(*db.Queries)
+	if !ok {
+		return StocktakeResult{}, fmt.Errorf("invalid query type in store")
+	}
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return StocktakeResult{}, err
+	}
+	defer tx.Rollback()
+
+	txQueries := q.WithTx(tx)
+
+	store, err := txQueries.GetStoreByID(ctx, storeID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return StocktakeResult{}, fmt.Errorf("%w: %d", ErrStoreNotFound, storeID)
+		}
+		return StocktakeResult{}, err
+	}
+	if store.IsActive.Valid && !store.IsActive.Bool {
+		return StocktakeResult{}, fmt.Errorf("%w: %d", ErrStoreInactive, storeID)
+	}
+
+	stocktake, err := txQueries.CreateStocktake(ctx, db.CreateStocktakeParams{
+		StoreID:  storeID,
+		OpenedBy: openedBy,
+	})
+	if err != nil {
+		if pgErr, ok := err.(*pq.Error); ok && pgErr.Code == "23505" { // unique_violation
+			return StocktakeResult{}, ErrStocktakeAlreadyOpen
+		}
+		return StocktakeResult{}, err
+	}
+
+	if _, err := txQueries.SnapshotStocktakeLines(ctx, db.SnapshotStocktakeLinesParams{
+		StocktakeID: stocktake.ID,
+		StoreID:     storeID,
+	}); err != nil {
+		return StocktakeResult{}, err
+	}
+
+	lines, err := txQueries.ListStocktakeLines(ctx, stocktake.ID)
+	if err != nil {
+		return StocktakeResult{}, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return StocktakeResult{}, err
+	}
+
+	return StocktakeResult{Stocktake: stocktake, Lines: lines}, nil
+}
+
+// GetStocktake returns a stocktake of a store with its lines, so variances
+// can be reviewed before it is committed.
+func (s *Store) GetStocktake(ctx context.Context, params db.GetStocktakeParams) (StocktakeResult, error) {
+	stocktake, err := s.queries.GetStocktake(ctx, params)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return StocktakeResult{}, ErrStocktakeNotFound
+		}
+		return StocktakeResult{}, err
+	}
+	lines, err := s.queries.ListStocktakeLines(ctx, stocktake.ID)
+	if err != nil {
+		return StocktakeResult{}, err
+	}
+	return StocktakeResult{Stocktake: stocktake, Lines: lines}, nil
+}
+
+// RecordStocktakeCounts stores counted quantities on an open stocktake.
+// Counting a variation again replaces its count, and a variation that was not
+// in the snapshot is added with a system quantity of 0.
+func (s *Store) RecordStocktakeCounts(ctx context.Context, params db.GetStocktakeParams, counts []StocktakeCount) (StocktakeResult, error) {
+	q, ok := s.queries.(*db.Queries)
+	if !ok {
+		return StocktakeResult{}, fmt.Errorf("invalid query type in store")
+	}
+
+	seen := make(map[int32]bool, len(counts))
+	for _, count := range counts {
+		if seen[count.VariationID] {
+			return StocktakeResult{}, ErrDuplicateStocktakeItem
+		}
+		seen[count.VariationID] = true
+	}
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return StocktakeResult{}, err
+	}
+	defer tx.Rollback()
+
+	txQueries := q.WithTx(tx)
+
+	stocktake, err := lockOpenStocktake(ctx, txQueries, params)
+	if err != nil {
+		return StocktakeResult{}, err
+	}
+
+	for _, count := range counts {
+		if _, err := txQueries.RecordStocktakeCount(ctx, db.RecordStocktakeCountParams{
+			StocktakeID:     stocktake.ID,
+			VariationID:     count.VariationID,
+			CountedQuantity: sql.NullInt32{Int32: count.CountedQuantity, Valid: true},
+		}); err != nil {
+			return StocktakeResult{}, err
+		}
+	}
+
+	lines, err := txQueries.ListStocktakeLines(ctx, stocktake.ID)
+	if err != nil {
+		return StocktakeResult{}, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return StocktakeResult{}, err
+	}
+
+	return StocktakeResult{Stocktake: stocktake, Lines: lines}, nil
+}
+
+// CommitStocktake applies the variances of the counted lines as stocktake
+// adjustments and closes the session, all in one transaction. Variances are
+// taken against the snapshot rather than the current stock, so sales made
+// while the count ran are kept. Lines that were never counted are left
+// alone. Stock written off is also taken out of the variation's batches.
+func (s *Store) CommitStocktake(ctx context.Context, params db.GetStocktakeParams, committedBy int32) (StocktakeResult, error) {
+	q, ok := s.queries.(*db.Queries)
+	if !ok {
+		return StocktakeResult{}, fmt.Errorf("invalid query type in store")
+	}
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return StocktakeResult{}, err
+	}
+	defer tx.Rollback()
+
+	txQueries := q.WithTx(tx)
+
+	stocktake, err := lockOpenStocktake(ctx, txQueries, params)
+	if err != nil {
+		return StocktakeResult{}, err
+	}
+
+	// lines come ordered by variation, so inventory rows are locked in a
+	// stable order like transfers do
+	lines, err := txQueries.ListStocktakeLines(ctx, stocktake.ID)
+	if err != nil {
+		return StocktakeResult{}, err
+	}
+
+	for i, line := range lines {
+		variance, counted := StocktakeVariance(line)
+		if !counted || variance == 0 {
+			continue
+		}
+
+		var previous int32
+		stock, err := txQueries.GetInventoryItemForUpdate(ctx, db.GetInventoryItemForUpdateParams{
+			StoreID:     stocktake.StoreID,
+			VariationID: line.VariationID,
+		})
+		if err != nil && !errors.Is(err, sql.ErrNoRows) {
+			return StocktakeResult{}, err
+		}
+		if err == nil {
+			previous = stock.Quantity
+		}
+
+		if _, err := txQueries.IncrementInventoryQuantity(ctx, db.IncrementInventoryQuantityParams{
+			StoreID:     stocktake.StoreID,
+			VariationID: line.VariationID,
+			Quantity:    variance,
+		}); err != nil {
+			return StocktakeResult{}, err
+		}
+
+		adjustment, err := txQueries.CreateStockAdjustment(ctx, db.CreateStockAdjustmentParams{
+			StoreID:          stocktake.StoreID,
+			VariationID:      line.VariationID,
+			PreviousQuantity: previous,
+			Quantity:         variance,
+			Reason:           MovementStocktake,
+			AdjustedBy:       committedBy,
+		})
+		if err != nil {
+			return StocktakeResult{}, err
+		}
+
+		adjustmentID := sql.NullInt32{Int32: adjustment.ID, Valid: true}
+		if err := txQueries.SetStocktakeLineAdjustment(ctx, db.SetStocktakeLineAdjustmentParams{
+			ID:           line.ID,
+			AdjustmentID: adjustmentID,
+		}); err != nil {
+			return StocktakeResult{}, err
+		}
+		lines[i].AdjustmentID = adjustmentID
+
+		if variance < 0 {
+			if _, err := inventory.ConsumeBatchesTx(ctx, txQueries, db.ConsumeInventoryBatchesParams{
+				StoreID:     stocktake.StoreID,
+				VariationID: line.VariationID,
+				Quantity:    -variance,
+				Reason:      MovementStocktake,
+				ReferenceID: adjustmentID,
+			}); err != nil {
+				return StocktakeResult{}, err
+			}
+		}
+	}
+
+	stocktake, err = txQueries.CommitStocktake(ctx, db.CommitStocktakeParams{
+		ID:          stocktake.ID,
+		CommittedBy: sql.NullInt32{Int32: committedBy, Valid: true},
+	})
+	if err != nil {
+		return StocktakeResult{}, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return StocktakeResult{}, err
+	}
+
+	return StocktakeResult{Stocktake: stocktake, Lines: lines}, nil
+}
+
+// lockOpenStocktake locks a stocktake for the rest of the transaction, so a
+// count can't land on a session that is being committed and a session can't
+// be committed twice.
+func lockOpenStocktake(ctx context.Context, q *db.Queries, params db.GetStocktakeParams) (db.Stocktake, error) {
+	stocktake, err := q.GetStocktakeForUpdate(ctx, db.GetStocktakeForUpdateParams(params))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return db.Stocktake{}, ErrStocktakeNotFound
+		}
+		return db.Stocktake{}, err
+	}
+	if stocktake.Status != StocktakeOpen {
+		return db.Stocktake{}, ErrStocktakeCommitted
+	}
+	return stocktake, nil
+}
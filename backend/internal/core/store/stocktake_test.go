@@ -0,0 +1,171 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"herp/db/dbtest"
+	db "herp/db/sqlc"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type stocktakeFixture struct {
+	conn                 *sql.DB
+	service              *Store
+	owner, store, other  int32
+	palmWine, zobo, kola int32
+}
+
+// newStocktakeFixture has a bar holding 10 palm wine and 5 zobo, and no kola.
+func newStocktakeFixture(t *testing.T) stocktakeFixture {
+	t.Helper()
+	conn := dbtest.Open(t)
+	owner := dbtest.Admin(t, conn, "owner")
+	businessID, branchID := dbtest.Business(t, conn, owner, "Palmwine Express")
+	f := stocktakeFixture{
+		conn:     conn,
+		service:  NewStore(conn, db.New(conn)),
+		owner:    owner,
+		store:    dbtest.Store(t, conn, branchID, "Bar"),
+		other:    dbtest.Store(t, conn, branchID, "Shop"),
+		palmWine: dbtest.Variation(t, conn, businessID, "PW-1L", "1500.00"),
+		zobo:     dbtest.Variation(t, conn, businessID, "ZB-50CL", "500.00"),
+		kola:     dbtest.Variation(t, conn, businessID, "KN-1", "100.00"),
+	}
+	dbtest.Stock(t, conn, f.store, f.palmWine, 10)
+	dbtest.Stock(t, conn, f.store, f.zobo, 5)
+	return f
+}
+
+func (f stocktakeFixture) stock(t *testing.T, variationID int32) int32 {
+	t.Helper()
+	var quantity int32
+	err := f.conn.QueryRow(`SELECT quantity FROM inventory WHERE store_id = $1 AND variation_id = $2`, f.store, variationID).Scan(&quantity)
+	if err == sql.ErrNoRows {
+		return 0
+	}
+	require.NoError(t, err)
+	return quantity
+}
+
+func (f stocktakeFixture) adjustments(t *testing.T) int {
+	t.Helper()
+	var n int
+	require.NoError(t, f.conn.QueryRow(`SELECT COUNT(*) FROM stock_adjustment WHERE store_id = $1 AND reason = $2`, f.store, MovementStocktake).Scan(&n))
+	return n
+}
+
+// lineVariances maps the variations of lines to their variance, counted
+// lines only.
+func lineVariances(lines []db.StocktakeLine) map[int32]int32 {
+	variances := map[int32]int32{}
+	for _, line := range lines {
+		if variance, ok := StocktakeVariance(line); ok {
+			variances[line.VariationID] = variance
+		}
+	}
+	return variances
+}
+
+func TestStocktake(t *testing.T) {
+	f := newStocktakeFixture(t)
+	ctx := context.Background()
+
+	opened, err := f.service.OpenStocktake(ctx, f.store, f.owner)
+	require.NoError(t, err)
+	assert.Equal(t, StocktakeOpen, opened.Stocktake.Status)
+	snapshot := map[int32]int32{}
+	for _, line := range opened.Lines {
+		snapshot[line.VariationID] = line.SystemQuantity
+		assert.False(t, line.CountedQuantity.Valid)
+	}
+	assert.Equal(t, map[int32]int32{f.palmWine: 10, f.zobo: 5}, snapshot)
+
+	session := db.GetStocktakeParams{ID: opened.Stocktake.ID, StoreID: f.store}
+	_, err = f.service.RecordStocktakeCounts(ctx, session, []StocktakeCount{
+		{VariationID: f.palmWine, CountedQuantity: 7},
+		{VariationID: f.zobo, CountedQuantity: 5},
+	})
+	require.NoError(t, err)
+	// recounted, and kola found on a shelf
+	counted, err := f.service.RecordStocktakeCounts(ctx, session, []StocktakeCount{
+		{VariationID: f.palmWine, CountedQuantity: 8},
+		{VariationID: f.kola, CountedQuantity: 3},
+	})
+	require.NoError(t, err)
+
+	// variances are reported before anything is applied
+	review, err := f.service.GetStocktake(ctx, session)
+	require.NoError(t, err)
+	want := map[int32]int32{f.palmWine: -2, f.zobo: 0, f.kola: 3}
+	assert.Equal(t, want, lineVariances(counted.Lines))
+	assert.Equal(t, want, lineVariances(review.Lines))
+	assert.Equal(t, int32(10), f.stock(t, f.palmWine))
+	assert.Zero(t, f.adjustments(t))
+
+	// a palm wine sold while counting is kept
+	dbtest.Exec(t, f.conn, `UPDATE inventory SET quantity = quantity - 1 WHERE store_id = $1 AND variation_id = $2`, f.store, f.palmWine)
+
+	committed, err := f.service.CommitStocktake(ctx, session, f.owner)
+	require.NoError(t, err)
+	assert.Equal(t, StocktakeCommitted, committed.Stocktake.Status)
+	assert.Equal(t, int32(7), f.stock(t, f.palmWine))
+	assert.Equal(t, int32(5), f.stock(t, f.zobo))
+	assert.Equal(t, int32(3), f.stock(t, f.kola))
+	assert.Equal(t, 2, f.adjustments(t), "no adjustment without a variance")
+	for _, line := range committed.Lines {
+		assert.Equal(t, line.VariationID != f.zobo, line.AdjustmentID.Valid, "adjustment of %d", line.VariationID)
+	}
+}
+
+func TestStocktakeCommitTwice(t *testing.T) {
+	f := newStocktakeFixture(t)
+	ctx := context.Background()
+
+	opened, err := f.service.OpenStocktake(ctx, f.store, f.owner)
+	require.NoError(t, err)
+	session := db.GetStocktakeParams{ID: opened.Stocktake.ID, StoreID: f.store}
+	_, err = f.service.RecordStocktakeCounts(ctx, session, []StocktakeCount{{VariationID: f.palmWine, CountedQuantity: 8}})
+	require.NoError(t, err)
+
+	_, err = f.service.CommitStocktake(ctx, session, f.owner)
+	require.NoError(t, err)
+	_, err = f.service.CommitStocktake(ctx, session, f.owner)
+	assert.ErrorIs(t, err, ErrStocktakeCommitted)
+	assert.Equal(t, int32(8), f.stock(t, f.palmWine), "variance applied once")
+	assert.Equal(t, 1, f.adjustments(t))
+
+	// nor counted again
+	_, err = f.service.RecordStocktakeCounts(ctx, session, []StocktakeCount{{VariationID: f.palmWine, CountedQuantity: 1}})
+	assert.ErrorIs(t, err, ErrStocktakeCommitted)
+
+	// the store can be counted again
+	_, err = f.service.OpenStocktake(ctx, f.store, f.owner)
+	assert.NoError(t, err)
+}
+
+func TestStocktakeGuards(t *testing.T) {
+	f := newStocktakeFixture(t)
+	ctx := context.Background()
+
+	opened, err := f.service.OpenStocktake(ctx, f.store, f.owner)
+	require.NoError(t, err)
+	_, err = f.service.OpenStocktake(ctx, f.store, f.owner)
+	assert.ErrorIs(t, err, ErrStocktakeAlreadyOpen)
+
+	// another store can't reach the session
+	_, err = f.service.CommitStocktake(ctx, db.GetStocktakeParams{ID: opened.Stocktake.ID, StoreID: f.other}, f.owner)
+	assert.ErrorIs(t, err, ErrStocktakeNotFound)
+
+	session := db.GetStocktakeParams{ID: opened.Stocktake.ID, StoreID: f.store}
+	_, err = f.service.RecordStocktakeCounts(ctx, session, []StocktakeCount{
+		{VariationID: f.zobo, CountedQuantity: 1},
+		{VariationID: f.zobo, CountedQuantity: 2},
+	})
+	assert.ErrorIs(t, err, ErrDuplicateStocktakeItem)
+
+	_, err = f.service.OpenStocktake(ctx, 9999, f.owner)
+	assert.ErrorIs(t, err, ErrStoreNotFound)
+}
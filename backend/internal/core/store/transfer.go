@@ -0,0 +1,219 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	db "herp/db/sqlc"
+	"herp/internal/core/inventory"
+	"sort"
+)
+
+var (
+	ErrSameStore             = errors.New("cannot transfer stock to the same store")
+	ErrStoreNotFound         = errors.New("store not found")
+	ErrStoreInactive         = errors.New("store is not active")
+	ErrDuplicateTransferItem = errors.New("item appears more than once in transfer")
+	ErrBelowMinKeep          = errors.New("transfer would take the source store below its minimum stock")
+)
+
+type TransferLine struct {
+	VariationID int32
+	Quantity    int32
+}
+
+type TransferStockParams struct {
+	FromStoreID   int32
+	ToStoreID     int32
+	TransferredBy int32
+	// OwnerID is the admin both stores must belong to, a store of another
+	// owner is reported as not found
+	OwnerID int32
+	Lines   []TransferLine
+	// OverrideMinKeep lets the transfer take the source store below its
+	// minimum stock, the lines that do are flagged in the result
+	OverrideMinKeep bool
+}
+
+type TransferLineResult struct {
+	VariationID  int32
+	Quantity     int32
+	FromQuantity int32 // quantity left in the source store
+	ToQuantity   int32 // quantity now in the destination store
+	MinKeep      int32 // minimum stock of the source store
+	BelowMinKeep bool  // the transfer took the source store below MinKeep
+}
+
+type TransferResult struct {
+	Transfer db.StoreTransfer
+	Lines    []TransferLineResult
+}
+
+// TransferStock moves stock from one store to another in a single
+// transaction. Stock held by active reservations can't be transferred, and
+// the source can only go below what it has if its business allows
+// overselling. Nor can it go below the minimum stock kept for a variation,
+// unless the transfer overrides it.
+func (s *Store) TransferStock(ctx context.Context, args TransferStockParams) (TransferResult, error) {
+	if args.FromStoreID == args.ToStoreID {
+		return TransferResult{}, ErrSameStore
+	}
+
+	q, ok := s.queries.(*db.Queries)
+	if !ok {
+		return TransferResult{}, fmt.Errorf("invalid query type in store")
+	}
+
+	lines := make([]TransferLine, len(args.Lines))
+	copy(lines, args.Lines)
+	// duplicates end up next to each other, and the inventory rows are
+	// locked by variation within each store below
+	sort.Slice(lines, func(i, j int) bool { return lines[i].VariationID < lines[j].VariationID })
+	for i := 1; i < len(lines); i++ {
+		if lines[i].VariationID == lines[i-1].VariationID {
+			return TransferResult{}, ErrDuplicateTransferItem
+		}
+	}
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return TransferResult{}, err
+	}
+	defer tx.Rollback()
+
+	txQueries := q.WithTx(tx)
+
+	for _, id := range []int32{args.FromStoreID, args.ToStoreID} {
+		store, err := txQueries.GetStoreForOwner(ctx, db.GetStoreForOwnerParams{
+			ID:      id,
+			OwnerID: args.OwnerID,
+		})
+		if err != nil {
+			if errors.Is(err, sql.ErrNoRows) {
+				return TransferResult{}, fmt.Errorf("%w: %d", ErrStoreNotFound, id)
+			}
+			return TransferResult{}, err
+		}
+		if store.IsActive.Valid && !store.IsActive.Bool {
+			return TransferResult{}, fmt.Errorf("%w: %d", ErrStoreInactive, id)
+		}
+	}
+
+	allowOverselling, err := txQueries.GetStoreAllowOverselling(ctx, args.FromStoreID)
+	if err != nil {
+		return TransferResult{}, err
+	}
+
+	// Lock the rows of both stores before changing any, ordered by store and
+	// then variation like every transfer does, or two transfers between the
+	// same stores in opposite directions can each hold the row the other
+	// waits for. Adding nothing locks a row, and creates the ones the
+	// destination doesn't have yet so they are locked in order too.
+	stores := []int32{args.FromStoreID, args.ToStoreID}
+	if stores[0] > stores[1] {
+		stores[0], stores[1] = stores[1], stores[0]
+	}
+	for _, storeID := range stores {
+		for _, line := range lines {
+			if _, err := txQueries.IncrementInventoryQuantity(ctx, db.IncrementInventoryQuantityParams{
+				StoreID:     storeID,
+				VariationID: line.VariationID,
+				Quantity:    0,
+			}); err != nil {
+				return TransferResult{}, err
+			}
+		}
+	}
+
+	transfer, err := txQueries.CreateStoreTransfer(ctx, db.CreateStoreTransferParams{
+		FromStoreID:   args.FromStoreID,
+		ToStoreID:     args.ToStoreID,
+		TransferredBy: args.TransferredBy,
+	})
+	if err != nil {
+		return TransferResult{}, err
+	}
+
+	results := make([]TransferLineResult, 0, len(lines))
+	for _, line := range lines {
+		var available, minKeep int32
+		stock, err := txQueries.GetInventoryItemForUpdate(ctx, db.GetInventoryItemForUpdateParams{
+			StoreID:     args.FromStoreID,
+			VariationID: line.VariationID,
+		})
+		if err != nil && !errors.Is(err, sql.ErrNoRows) {
+			return TransferResult{}, err
+		}
+		if err == nil {
+			reserved, err := txQueries.SumActiveReservations(ctx, db.SumActiveReservationsParams{
+				StoreID:     args.FromStoreID,
+				VariationID: line.VariationID,
+			})
+			if err != nil {
+				return TransferResult{}, err
+			}
+			available = stock.Quantity - reserved
+			minKeep = stock.MinKeep
+		}
+		if available < line.Quantity && !allowOverselling {
+			return TransferResult{}, inventory.ErrInsufficientStock
+		}
+		belowMinKeep := minKeep > 0 && available-line.Quantity < minKeep
+		if belowMinKeep && !args.OverrideMinKeep {
+			return TransferResult{}, fmt.Errorf("%w: variation %d keeps %d", ErrBelowMinKeep, line.VariationID, minKeep)
+		}
+
+		if _, err := txQueries.CreateStoreTransferItem(ctx, db.CreateStoreTransferItemParams{
+			TransferID:  transfer.ID,
+			VariationID: line.VariationID,
+			Quantity:    line.Quantity,
+		}); err != nil {
+			return TransferResult{}, err
+		}
+
+		from, err := txQueries.IncrementInventoryQuantity(ctx, db.IncrementInventoryQuantityParams{
+			StoreID:     args.FromStoreID,
+			VariationID: line.VariationID,
+			Quantity:    -line.Quantity,
+		})
+		if err != nil {
+			return TransferResult{}, err
+		}
+
+		// batches stay behind, the destination store receives untracked stock
+		if _, err := inventory.ConsumeBatchesTx(ctx, txQueries, db.ConsumeInventoryBatchesParams{
+			StoreID:     args.FromStoreID,
+			VariationID: line.VariationID,
+			Quantity:    line.Quantity,
+			Reason:      inventory.MovementTransfer,
+			ReferenceID: sql.NullInt32{Int32: transfer.ID, Valid: true},
+		}); err != nil {
+			return TransferResult{}, err
+		}
+
+		to, err := txQueries.IncrementInventoryQuantity(ctx, db.IncrementInventoryQuantityParams{
+			StoreID:     args.ToStoreID,
+			VariationID: line.VariationID,
+			Quantity:    line.Quantity,
+		})
+		if err != nil {
+			return TransferResult{}, err
+		}
+
+		results = append(results, TransferLineResult{
+			VariationID:  line.VariationID,
+			Quantity:     line.Quantity,
+			FromQuantity: from.Quantity,
+			ToQuantity:   to.Quantity,
+			MinKeep:      minKeep,
+			BelowMinKeep: belowMinKeep,
+		})
+	}
+
+	if err := tx.Commit(); err != nil {
+		return TransferResult{}, err
+	}
+
+	return TransferResult{Transfer: transfer, Lines: results}, nil
+}
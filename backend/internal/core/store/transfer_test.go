@@ -0,0 +1,156 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"herp/db/dbtest"
+	db "herp/db/sqlc"
+	"herp/internal/core/inventory"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type transferFixture struct {
+	conn      *sql.DB
+	service   *Store
+	owner     int32
+	from, to  int32
+	variation int32
+}
+
+// newTransferFixture has a warehouse holding 10 of a variation and keeping
+// at least 4 of it, and an empty shop to transfer to.
+func newTransferFixture(t *testing.T) transferFixture {
+	t.Helper()
+	conn := dbtest.Open(t)
+	owner := dbtest.Admin(t, conn, "owner")
+	businessID, branchID := dbtest.Business(t, conn, owner, "Palmwine Express")
+	f := transferFixture{
+		conn:      conn,
+		service:   NewStore(conn, db.New(conn)),
+		owner:     owner,
+		from:      dbtest.Store(t, conn, branchID, "Warehouse"),
+		to:        dbtest.Store(t, conn, branchID, "Shop"),
+		variation: dbtest.Variation(t, conn, businessID, "PW-1L", "1500.00"),
+	}
+	dbtest.Stock(t, conn, f.from, f.variation, 10)
+	dbtest.Exec(t, conn, `UPDATE inventory SET min_keep = 4 WHERE store_id = $1 AND variation_id = $2`, f.from, f.variation)
+	return f
+}
+
+func (f transferFixture) transfer(quantity int32, override bool) (TransferResult, error) {
+	return f.service.TransferStock(context.Background(), TransferStockParams{
+		FromStoreID:     f.from,
+		ToStoreID:       f.to,
+		TransferredBy:   f.owner,
+		OwnerID:         f.owner,
+		Lines:           []TransferLine{{VariationID: f.variation, Quantity: quantity}},
+		OverrideMinKeep: override,
+	})
+}
+
+func (f transferFixture) stock(t *testing.T, storeID int32) int32 {
+	t.Helper()
+	var quantity int32
+	err := f.conn.QueryRow(`SELECT quantity FROM inventory WHERE store_id = $1 AND variation_id = $2`, storeID, f.variation).Scan(&quantity)
+	if err == sql.ErrNoRows {
+		return 0
+	}
+	require.NoError(t, err)
+	return quantity
+}
+
+func TestTransferStockMinKeep(t *testing.T) {
+	f := newTransferFixture(t)
+
+	// down to the minimum is fine
+	result, err := f.transfer(6, false)
+	require.NoError(t, err)
+	require.Len(t, result.Lines, 1)
+	assert.Equal(t, int32(4), result.Lines[0].FromQuantity)
+	assert.Equal(t, int32(4), result.Lines[0].MinKeep)
+	assert.False(t, result.Lines[0].BelowMinKeep)
+}
+
+func TestTransferStockBelowMinKeep(t *testing.T) {
+	f := newTransferFixture(t)
+
+	_, err := f.transfer(7, false)
+	assert.ErrorIs(t, err, ErrBelowMinKeep)
+
+	// nothing moved
+	assert.Equal(t, int32(10), f.stock(t, f.from))
+	assert.Equal(t, int32(0), f.stock(t, f.to))
+	var transfers int
+	require.NoError(t, f.conn.QueryRow(`SELECT count(*) FROM store_transfer`).Scan(&transfers))
+	assert.Zero(t, transfers)
+}
+
+func TestTransferStockOverrideMinKeep(t *testing.T) {
+	f := newTransferFixture(t)
+
+	result, err := f.transfer(7, true)
+	require.NoError(t, err)
+	require.Len(t, result.Lines, 1)
+	assert.Equal(t, int32(3), result.Lines[0].FromQuantity)
+	assert.Equal(t, int32(7), result.Lines[0].ToQuantity)
+	assert.True(t, result.Lines[0].BelowMinKeep, "the line that went below is flagged")
+
+	assert.Equal(t, int32(3), f.stock(t, f.from))
+	assert.Equal(t, int32(7), f.stock(t, f.to))
+
+	// the override doesn't let it take more than there is
+	_, err = f.transfer(4, true)
+	assert.ErrorIs(t, err, inventory.ErrInsufficientStock)
+	assert.Equal(t, int32(3), f.stock(t, f.from))
+}
+
+func TestTransferStockBothWaysConcurrently(t *testing.T) {
+	f := newTransferFixture(t)
+	ctx := context.Background()
+	// a second variation the shop holds and the warehouse doesn't, so each
+	// direction locks a row the other one creates
+	var businessID int32
+	require.NoError(t, f.conn.QueryRow(`SELECT business_id FROM item i JOIN variation v ON v.item_id = i.id WHERE v.id = $1`, f.variation).Scan(&businessID))
+	other := dbtest.Variation(t, f.conn, businessID, "PW-5L", "6000.00")
+	dbtest.Stock(t, f.conn, f.to, f.variation, 10)
+	dbtest.Stock(t, f.conn, f.to, other, 10)
+	// however the transfers interleave, none of them goes below a minimum
+	dbtest.Exec(t, f.conn, `UPDATE inventory SET min_keep = 0`)
+
+	const rounds = 8
+	errs := make([]error, 2*rounds)
+	start := make(chan struct{})
+	var wg sync.WaitGroup
+	for i := range rounds {
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			<-start
+			_, errs[2*i] = f.service.TransferStock(ctx, TransferStockParams{
+				FromStoreID: f.from, ToStoreID: f.to, TransferredBy: f.owner, OwnerID: f.owner,
+				Lines: []TransferLine{{VariationID: f.variation, Quantity: 1}},
+			})
+		}()
+		go func() {
+			defer wg.Done()
+			<-start
+			_, errs[2*i+1] = f.service.TransferStock(ctx, TransferStockParams{
+				FromStoreID: f.to, ToStoreID: f.from, TransferredBy: f.owner, OwnerID: f.owner,
+				Lines: []TransferLine{{VariationID: other, Quantity: 1}, {VariationID: f.variation, Quantity: 1}},
+			})
+		}()
+	}
+	close(start)
+	wg.Wait()
+
+	for _, err := range errs {
+		assert.NoError(t, err)
+	}
+	// as much of the variation went each way
+	assert.Equal(t, int32(10), f.stock(t, f.from))
+	assert.Equal(t, int32(10), f.stock(t, f.to))
+}
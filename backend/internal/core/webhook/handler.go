@@ -0,0 +1,472 @@
+package webhook
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	db "herp/db/sqlc"
+	"herp/internal/auth"
+	"herp/internal/utils"
+	"herp/pkg/jwt"
+	"herp/pkg/monitoring/logging"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+type Handler struct {
+	service WebhookInterface
+	logger  *logging.Logger
+}
+
+func NewHandler(service WebhookInterface, logger *logging.Logger) *Handler {
+	return &Handler{
+		service: service,
+		logger:  logger,
+	}
+}
+
+func (h *Handler) RegisterRoutes(r *gin.RouterGroup, authSvc *auth.Service) {
+	webhooks := r.Group("/admin/webhooks")
+	webhooks.Use(auth.AdminMiddleware(authSvc))
+	{
+		webhooks.GET("", h.ListWebhooks)
+		webhooks.POST("", h.CreateWebhook)
+		webhooks.GET("/:id", h.GetWebhook)
+		webhooks.PATCH("/:id", h.UpdateWebhook)
+		webhooks.DELETE("/:id", h.DeleteWebhook)
+		webhooks.GET("/:id/deliveries", h.ListDeliveries)
+	}
+}
+
+type CreateWebhookRequest struct {
+	URL    string   `json:"url" binding:"required,url" example:"https://example.com/hooks/herp"`
+	Events []string `json:"events" binding:"required,min=1,dive,oneof=sale.created refund.created inventory.low_stock" example:"sale.created"`
+	// generated when left empty
+	Secret string `json:"secret" binding:"omitempty,min=16"`
+	Active *bool  `json:"active" example:"true"`
+}
+
+type UpdateWebhookRequest struct {
+	URL    *string  `json:"url" binding:"omitempty,url"`
+	Events []string `json:"events" binding:"omitempty,min=1,dive,oneof=sale.created refund.created inventory.low_stock"`
+	Secret *string  `json:"secret" binding:"omitempty,min=16"`
+	Active *bool    `json:"active"`
+}
+
+type WebhookResponse struct {
+	ID     int32    `json:"id" example:"1"`
+	URL    string   `json:"url" example:"https://example.com/hooks/herp"`
+	Events []string `json:"events" example:"sale.created"`
+	// only returned when the subscription is created
+	Secret    string    `json:"secret,omitempty"`
+	Active    bool      `json:"active" example:"true"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+type DeliveryResponse struct {
+	ID         int32     `json:"id" example:"1"`
+	EventID    string    `json:"event_id" example:"5f0c6d0e-2b1a-4a57-9a59-1c1f3c9f4b7e"`
+	Event      string    `json:"event" example:"sale.created"`
+	Attempt    int32     `json:"attempt" example:"1"`
+	StatusCode *int32    `json:"status_code" example:"200"`
+	Error      string    `json:"error,omitempty"`
+	Delivered  bool      `json:"delivered" example:"true"`
+	CreatedAt  time.Time `json:"created_at"`
+}
+
+func webhookResponse(sub db.WebhookSubscription) WebhookResponse {
+	return WebhookResponse{
+		ID:        sub.ID,
+		URL:       sub.Url,
+		Events:    sub.Events,
+		Active:    sub.Active,
+		CreatedAt: sub.CreatedAt.Time,
+		UpdatedAt: sub.UpdatedAt.Time,
+	}
+}
+
+// businessScope resolves the business the caller manages webhooks of, the
+// one picked with the X-Business-ID header or else the first they own.
+// Subscriptions of other businesses are never visible to them.
+func (h *Handler) businessScope(c *gin.Context, claims *jwt.Claims) (int32, bool) {
+	var requested sql.NullInt32
+	if header := c.GetHeader("X-Business-ID"); header != "" {
+		id, err := strconv.Atoi(header)
+		if err != nil || id <= 0 {
+			utils.ErrorResponse(c, 400, "invalid X-Business-ID header")
+			return 0, false
+		}
+		requested = sql.NullInt32{Int32: int32(id), Valid: true}
+	}
+
+	businessID, err := h.service.GetOwnedBusinessID(c, db.GetOwnedBusinessIDParams{
+		OwnerID:    int32(claims.UserID),
+		BusinessID: requested,
+	})
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			utils.ErrorResponse(c, 403, "no business found for this account")
+			return 0, false
+		}
+		h.logger.Errorf("error resolving business scope: %v", err)
+		utils.ErrorResponse(c, 500, utils.SERVERERROR)
+		return 0, false
+	}
+	return businessID, true
+}
+
+func (h *Handler) logActivity(c *gin.Context, claims *jwt.Claims, action string, id int32, details string) {
+	if _, err := h.service.LogActivity(c, db.LogActivityParams{
+		UserID:     int32(claims.UserID),
+		Action:     action,
+		EntityID:   id,
+		EntityType: "Webhook",
+		Details:    utils.WriteActivityDetails(claims.Username, claims.Email, details, time.Now()),
+		IpAddress:  sql.NullString{Valid: true, String: utils.GetClientIP(c)},
+		UserAgent:  sql.NullString{Valid: true, String: c.Request.UserAgent()},
+	}); err != nil {
+		h.logger.Warnf("error logging webhook activity: %v", err)
+	}
+}
+
+// ListWebhooks godoc
+// @Summary List webhooks
+// @Description List the webhook subscriptions of the business, their secrets are not shown.
+// @Tags webhook
+// @Produce json
+// @Security BearerAuth
+// @Param X-Business-ID header int false "Business to list the webhooks of, defaults to the first one owned"
+// @Success 200 {object} []WebhookResponse
+// @Failure 401
+// @Failure 403
+// @Failure 500
+// @Router /api/v1/admin/webhooks [get]
+func (h *Handler) ListWebhooks(c *gin.Context) {
+	claims, ok := jwt.GetUserFromContext(c)
+	if !ok {
+		h.logger.Errorf("could not get user from context")
+		utils.ErrorResponse(c, 500, utils.SERVERERROR)
+		return
+	}
+	businessID, ok := h.businessScope(c, claims)
+	if !ok {
+		return
+	}
+
+	subs, err := h.service.ListSubscriptions(c, businessID)
+	if err != nil {
+		h.logger.Errorf("error listing webhooks: %v", err)
+		utils.ErrorResponse(c, 500, utils.SERVERERROR)
+		return
+	}
+
+	response := make([]WebhookResponse, 0, len(subs))
+	for _, sub := range subs {
+		response = append(response, webhookResponse(sub))
+	}
+	utils.SuccessResponse(c, 200, "webhooks", response)
+}
+
+// CreateWebhook godoc
+// @Summary Create a webhook
+// @Description Subscribe a URL to the events of the business. Deliveries are signed with an HMAC-SHA256 of the body keyed by the secret, sent as "sha256=<hex>" in the X-Webhook-Signature header. The secret is only returned here.
+// @Tags webhook
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param X-Business-ID header int false "Business to subscribe to, defaults to the first one owned"
+// @Param body body CreateWebhookRequest true "Subscription"
+// @Success 201 {object} WebhookResponse
+// @Failure 400
+// @Failure 401
+// @Failure 403
+// @Failure 500
+// @Router /api/v1/admin/webhooks [post]
+func (h *Handler) CreateWebhook(c *gin.Context) {
+	claims, ok := jwt.GetUserFromContext(c)
+	if !ok {
+		h.logger.Errorf("could not get user from context")
+		utils.ErrorResponse(c, 500, utils.SERVERERROR)
+		return
+	}
+
+	var req CreateWebhookRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.ErrorResponse(c, 400, err.Error())
+		return
+	}
+
+	businessID, ok := h.businessScope(c, claims)
+	if !ok {
+		return
+	}
+
+	secret := req.Secret
+	if secret == "" {
+		var err error
+		if secret, err = GenerateSecret(); err != nil {
+			h.logger.Errorf("error generating webhook secret: %v", err)
+			utils.ErrorResponse(c, 500, utils.SERVERERROR)
+			return
+		}
+	}
+
+	sub, err := h.service.CreateSubscription(c, db.CreateWebhookSubscriptionParams{
+		BusinessID: businessID,
+		Url:        req.URL,
+		Events:     req.Events,
+		Secret:     secret,
+		Active:     req.Active == nil || *req.Active,
+	})
+	if err != nil {
+		h.logger.Errorf("error creating webhook: %v", err)
+		utils.ErrorResponse(c, 500, utils.SERVERERROR)
+		return
+	}
+
+	h.logActivity(c, claims, "create_webhook", sub.ID, fmt.Sprintf("Created webhook %d to %s", sub.ID, sub.Url))
+
+	response := webhookResponse(sub)
+	response.Secret = sub.Secret
+	utils.SuccessResponse(c, 201, "webhook created", response)
+}
+
+// GetWebhook godoc
+// @Summary Get a webhook
+// @Tags webhook
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "Webhook ID"
+// @Param X-Business-ID header int false "Business of the webhook, defaults to the first one owned"
+// @Success 200 {object} WebhookResponse
+// @Failure 400
+// @Failure 401
+// @Failure 403
+// @Failure 404
+// @Failure 500
+// @Router /api/v1/admin/webhooks/{id} [get]
+func (h *Handler) GetWebhook(c *gin.Context) {
+	claims, ok := jwt.GetUserFromContext(c)
+	if !ok {
+		h.logger.Errorf("could not get user from context")
+		utils.ErrorResponse(c, 500, utils.SERVERERROR)
+		return
+	}
+
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		utils.ErrorResponse(c, 400, utils.INVALID_REQUEST_DATA)
+		return
+	}
+
+	businessID, ok := h.businessScope(c, claims)
+	if !ok {
+		return
+	}
+
+	sub, err := h.service.GetSubscription(c, db.GetWebhookSubscriptionParams{ID: int32(id), BusinessID: businessID})
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			utils.ErrorResponse(c, 404, "webhook not found")
+			return
+		}
+		h.logger.Errorf("error getting webhook %d: %v", id, err)
+		utils.ErrorResponse(c, 500, utils.SERVERERROR)
+		return
+	}
+
+	utils.SuccessResponse(c, 200, "webhook", webhookResponse(sub))
+}
+
+// UpdateWebhook godoc
+// @Summary Update a webhook
+// @Description Change the URL, events, secret or active flag of a subscription, fields left out are kept.
+// @Tags webhook
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "Webhook ID"
+// @Param X-Business-ID header int false "Business of the webhook, defaults to the first one owned"
+// @Param body body UpdateWebhookRequest true "Changes"
+// @Success 200 {object} WebhookResponse
+// @Failure 400
+// @Failure 401
+// @Failure 403
+// @Failure 404
+// @Failure 500
+// @Router /api/v1/admin/webhooks/{id} [patch]
+func (h *Handler) UpdateWebhook(c *gin.Context) {
+	claims, ok := jwt.GetUserFromContext(c)
+	if !ok {
+		h.logger.Errorf("could not get user from context")
+		utils.ErrorResponse(c, 500, utils.SERVERERROR)
+		return
+	}
+
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		utils.ErrorResponse(c, 400, utils.INVALID_REQUEST_DATA)
+		return
+	}
+
+	var req UpdateWebhookRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.ErrorResponse(c, 400, err.Error())
+		return
+	}
+
+	businessID, ok := h.businessScope(c, claims)
+	if !ok {
+		return
+	}
+
+	params := db.UpdateWebhookSubscriptionParams{ID: int32(id), BusinessID: businessID, Events: req.Events}
+	utils.PatchNullString(&params.Url, req.URL)
+	utils.PatchNullString(&params.Secret, req.Secret)
+	utils.PatchNullBool(&params.Active, req.Active)
+
+	sub, err := h.service.UpdateSubscription(c, params)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			utils.ErrorResponse(c, 404, "webhook not found")
+			return
+		}
+		h.logger.Errorf("error updating webhook %d: %v", id, err)
+		utils.ErrorResponse(c, 500, utils.SERVERERROR)
+		return
+	}
+
+	h.logActivity(c, claims, "update_webhook", sub.ID, fmt.Sprintf("Updated webhook %d", sub.ID))
+
+	utils.SuccessResponse(c, 200, "webhook updated", webhookResponse(sub))
+}
+
+// DeleteWebhook godoc
+// @Summary Delete a webhook
+// @Description Delete a subscription with its delivery history.
+// @Tags webhook
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "Webhook ID"
+// @Param X-Business-ID header int false "Business of the webhook, defaults to the first one owned"
+// @Success 200
+// @Failure 400
+// @Failure 401
+// @Failure 403
+// @Failure 404
+// @Failure 500
+// @Router /api/v1/admin/webhooks/{id} [delete]
+func (h *Handler) DeleteWebhook(c *gin.Context) {
+	claims, ok := jwt.GetUserFromContext(c)
+	if !ok {
+		h.logger.Errorf("could not get user from context")
+		utils.ErrorResponse(c, 500, utils.SERVERERROR)
+		return
+	}
+
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		utils.ErrorResponse(c, 400, utils.INVALID_REQUEST_DATA)
+		return
+	}
+
+	businessID, ok := h.businessScope(c, claims)
+	if !ok {
+		return
+	}
+
+	if err := h.service.DeleteSubscription(c, db.DeleteWebhookSubscriptionParams{ID: int32(id), BusinessID: businessID}); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			utils.ErrorResponse(c, 404, "webhook not found")
+			return
+		}
+		h.logger.Errorf("error deleting webhook %d: %v", id, err)
+		utils.ErrorResponse(c, 500, utils.SERVERERROR)
+		return
+	}
+
+	h.logActivity(c, claims, "delete_webhook", int32(id), fmt.Sprintf("Deleted webhook %d", id))
+
+	utils.SuccessResponse(c, 200, "webhook deleted", nil)
+}
+
+// ListDeliveries godoc
+// @Summary List webhook deliveries
+// @Description List the latest delivery attempts of a subscription, newest first.
+// @Tags webhook
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "Webhook ID"
+// @Param X-Business-ID header int false "Business of the webhook, defaults to the first one owned"
+// @Param limit query int false "Attempts to return, at most 200, defaults to 50"
+// @Success 200 {object} []DeliveryResponse
+// @Failure 400
+// @Failure 401
+// @Failure 403
+// @Failure 404
+// @Failure 500
+// @Router /api/v1/admin/webhooks/{id}/deliveries [get]
+func (h *Handler) ListDeliveries(c *gin.Context) {
+	claims, ok := jwt.GetUserFromContext(c)
+	if !ok {
+		h.logger.Errorf("could not get user from context")
+		utils.ErrorResponse(c, 500, utils.SERVERERROR)
+		return
+	}
+
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		utils.ErrorResponse(c, 400, utils.INVALID_REQUEST_DATA)
+		return
+	}
+	limit, err := strconv.Atoi(c.DefaultQuery("limit", "50"))
+	if err != nil || limit <= 0 || limit > 200 {
+		utils.ErrorResponse(c, 400, "limit must be between 1 and 200")
+		return
+	}
+
+	businessID, ok := h.businessScope(c, claims)
+	if !ok {
+		return
+	}
+	if _, err := h.service.GetSubscription(c, db.GetWebhookSubscriptionParams{ID: int32(id), BusinessID: businessID}); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			utils.ErrorResponse(c, 404, "webhook not found")
+			return
+		}
+		h.logger.Errorf("error getting webhook %d: %v", id, err)
+		utils.ErrorResponse(c, 500, utils.SERVERERROR)
+		return
+	}
+
+	deliveries, err := h.service.ListDeliveries(c, db.ListWebhookDeliveriesParams{
+		SubscriptionID: int32(id),
+		Limit:          int32(limit),
+	})
+	if err != nil {
+		h.logger.Errorf("error listing deliveries of webhook %d: %v", id, err)
+		utils.ErrorResponse(c, 500, utils.SERVERERROR)
+		return
+	}
+
+	response := make([]DeliveryResponse, 0, len(deliveries))
+	for _, d := range deliveries {
+		delivery := DeliveryResponse{
+			ID:        d.ID,
+			EventID:   d.EventID,
+			Event:     d.Event,
+			Attempt:   d.Attempt,
+			Error:     d.Error.String,
+			Delivered: d.Delivered,
+			CreatedAt: d.CreatedAt.Time,
+		}
+		if d.StatusCode.Valid {
+			delivery.StatusCode = &d.StatusCode.Int32
+		}
+		response = append(response, delivery)
+	}
+	utils.SuccessResponse(c, 200, "webhook deliveries", response)
+}
@@ -0,0 +1,30 @@
+package webhook
+
+import (
+	"context"
+	db "herp/db/sqlc"
+)
+
+type Querier interface {
+	CreateWebhookSubscription(ctx context.Context, arg db.CreateWebhookSubscriptionParams) (db.WebhookSubscription, error)
+	GetWebhookSubscription(ctx context.Context, arg db.GetWebhookSubscriptionParams) (db.WebhookSubscription, error)
+	ListWebhookSubscriptions(ctx context.Context, businessID int32) ([]db.WebhookSubscription, error)
+	ListActiveWebhookSubscriptions(ctx context.Context, arg db.ListActiveWebhookSubscriptionsParams) ([]db.WebhookSubscription, error)
+	UpdateWebhookSubscription(ctx context.Context, arg db.UpdateWebhookSubscriptionParams) (db.WebhookSubscription, error)
+	DeleteWebhookSubscription(ctx context.Context, arg db.DeleteWebhookSubscriptionParams) (int64, error)
+	CreateWebhookDelivery(ctx context.Context, arg db.CreateWebhookDeliveryParams) error
+	ListWebhookDeliveries(ctx context.Context, arg db.ListWebhookDeliveriesParams) ([]db.WebhookDelivery, error)
+	GetOwnedBusinessID(ctx context.Context, arg db.GetOwnedBusinessIDParams) (int32, error)
+	LogActivity(ctx context.Context, params db.LogActivityParams) (db.ActivityLog, error)
+}
+
+type WebhookInterface interface {
+	CreateSubscription(ctx context.Context, params db.CreateWebhookSubscriptionParams) (db.WebhookSubscription, error)
+	GetSubscription(ctx context.Context, params db.GetWebhookSubscriptionParams) (db.WebhookSubscription, error)
+	ListSubscriptions(ctx context.Context, businessID int32) ([]db.WebhookSubscription, error)
+	UpdateSubscription(ctx context.Context, params db.UpdateWebhookSubscriptionParams) (db.WebhookSubscription, error)
+	DeleteSubscription(ctx context.Context, params db.DeleteWebhookSubscriptionParams) error
+	ListDeliveries(ctx context.Context, params db.ListWebhookDeliveriesParams) ([]db.WebhookDelivery, error)
+	GetOwnedBusinessID(ctx context.Context, params db.GetOwnedBusinessIDParams) (int32, error)
+	LogActivity(ctx context.Context, params db.LogActivityParams) (db.ActivityLog, error)
+}
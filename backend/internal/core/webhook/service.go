@@ -0,0 +1,105 @@
+package webhook
+
+import (
+	"context"
+	"crypto/rand"
+	"database/sql"
+	"encoding/hex"
+	db "herp/db/sqlc"
+	"herp/pkg/webhooks"
+)
+
+// Webhook manages webhook subscriptions and is the store the dispatcher
+// reads them from.
+type Webhook struct {
+	db      *sql.DB
+	queries Querier
+}
+
+func NewWebhook(queries Querier, db *sql.DB) *Webhook {
+	return &Webhook{
+		db:      db,
+		queries: queries,
+	}
+}
+
+// GenerateSecret returns a random secret for signing the deliveries of a
+// subscription.
+func GenerateSecret() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
+
+func (w *Webhook) CreateSubscription(ctx context.Context, params db.CreateWebhookSubscriptionParams) (db.WebhookSubscription, error) {
+	return w.queries.CreateWebhookSubscription(ctx, params)
+}
+
+func (w *Webhook) GetSubscription(ctx context.Context, params db.GetWebhookSubscriptionParams) (db.WebhookSubscription, error) {
+	return w.queries.GetWebhookSubscription(ctx, params)
+}
+
+func (w *Webhook) ListSubscriptions(ctx context.Context, businessID int32) ([]db.WebhookSubscription, error) {
+	return w.queries.ListWebhookSubscriptions(ctx, businessID)
+}
+
+func (w *Webhook) UpdateSubscription(ctx context.Context, params db.UpdateWebhookSubscriptionParams) (db.WebhookSubscription, error) {
+	return w.queries.UpdateWebhookSubscription(ctx, params)
+}
+
+// DeleteSubscription deletes a subscription with its deliveries, a missing
+// one is sql.ErrNoRows.
+func (w *Webhook) DeleteSubscription(ctx context.Context, params db.DeleteWebhookSubscriptionParams) error {
+	deleted, err := w.queries.DeleteWebhookSubscription(ctx, params)
+	if err != nil {
+		return err
+	}
+	if deleted == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
+
+func (w *Webhook) ListDeliveries(ctx context.Context, params db.ListWebhookDeliveriesParams) ([]db.WebhookDelivery, error) {
+	return w.queries.ListWebhookDeliveries(ctx, params)
+}
+
+func (w *Webhook) GetOwnedBusinessID(ctx context.Context, params db.GetOwnedBusinessIDParams) (int32, error) {
+	return w.queries.GetOwnedBusinessID(ctx, params)
+}
+
+func (w *Webhook) LogActivity(ctx context.Context, params db.LogActivityParams) (db.ActivityLog, error) {
+	return w.queries.LogActivity(ctx, params)
+}
+
+// Subscriptions returns the active subscriptions of a business to an event,
+// for the dispatcher.
+func (w *Webhook) Subscriptions(ctx context.Context, businessID int32, event string) ([]webhooks.Subscription, error) {
+	rows, err := w.queries.ListActiveWebhookSubscriptions(ctx, db.ListActiveWebhookSubscriptionsParams{
+		BusinessID: businessID,
+		Event:      event,
+	})
+	if err != nil {
+		return nil, err
+	}
+	subs := make([]webhooks.Subscription, 0, len(rows))
+	for _, row := range rows {
+		subs = append(subs, webhooks.Subscription{ID: row.ID, URL: row.Url, Secret: row.Secret})
+	}
+	return subs, nil
+}
+
+// RecordAttempt keeps a delivery attempt of the dispatcher.
+func (w *Webhook) RecordAttempt(ctx context.Context, attempt webhooks.Attempt) error {
+	return w.queries.CreateWebhookDelivery(ctx, db.CreateWebhookDeliveryParams{
+		SubscriptionID: attempt.SubscriptionID,
+		EventID:        attempt.EventID,
+		Event:          attempt.Event,
+		Attempt:        int32(attempt.Attempt),
+		StatusCode:     sql.NullInt32{Int32: int32(attempt.StatusCode), Valid: attempt.StatusCode != 0},
+		Error:          sql.NullString{String: attempt.Error, Valid: attempt.Error != ""},
+		Delivered:      attempt.Delivered,
+	})
+}
@@ -1,6 +1,9 @@
 package docs
 
 import (
+	"herp/docs/swagger"
+	"strings"
+
 	"github.com/gin-gonic/gin"
 	swaggerFiles "github.com/swaggo/files"
 	ginSwagger "github.com/swaggo/gin-swagger"
@@ -24,7 +27,7 @@ func DefaultSwaggerConfig() SwaggerConfig {
 		Description: "This is the Hotel ERP API server. It provides endpoints for managing hotel operations including authentication, point of sale, inventory, and more.",
 		Version:     "1.0.0",
 		Host:        "localhost:9000",
-		BasePath:    "/api",
+		BasePath:    "/", // the documented paths start with /api/v1
 		Schemes:     []string{"http", "https"},
 		Enabled:     true,
 	}
@@ -77,11 +80,15 @@ func SetupRedocly(r *gin.Engine, config SwaggerConfig) {
 	})
 }
 
-// updateSwaggerSpec updates the swagger specification with config values
+// updateSwaggerSpec replaces the values generated into the swagger spec by
+// the config ones, so the served doc.json points "Try it out" at this server.
 func updateSwaggerSpec(config SwaggerConfig) {
-	// This would typically update the global swagger spec
-	// For now, we'll keep the hardcoded values in docs.go
-	// In a production system, you'd want to make this more dynamic
+	swagger.SwaggerInfo.Title = config.Title
+	swagger.SwaggerInfo.Description = config.Description
+	swagger.SwaggerInfo.Version = config.Version
+	swagger.SwaggerInfo.Host = config.Host
+	swagger.SwaggerInfo.BasePath = config.BasePath
+	swagger.SwaggerInfo.Schemes = config.Schemes
 }
 
 // generateRedocHTML generates HTML for Redocly documentation
@@ -104,20 +111,30 @@ func generateRedocHTML(config SwaggerConfig) string {
 </html>`
 }
 
-// APIDocsMiddleware adds API documentation metadata to responses
-func APIDocsMiddleware() gin.HandlerFunc {
+// APIDocsMiddleware adds API documentation metadata to responses, the docs
+// URL only when the docs are enabled.
+func APIDocsMiddleware(config SwaggerConfig) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		// Add API documentation headers
 		c.Header("X-API-Version", "1.0.0")
-		c.Header("X-API-Docs", "/docs/swagger/index.html")
+		if config.Enabled {
+			c.Header("X-API-Docs", "/docs/swagger/index.html")
+		}
 
 		c.Next()
 	}
 }
 
-// CORSForDocs enables CORS for documentation endpoints
+// CORSForDocs enables CORS for documentation endpoints, other routes are left
+// to their own CORS settings.
 func CORSForDocs() gin.HandlerFunc {
 	return func(c *gin.Context) {
+		path := c.Request.URL.Path
+		if !strings.HasPrefix(path, "/docs") && path != "/redoc" {
+			c.Next()
+			return
+		}
+
 		origin := c.Request.Header.Get("Origin")
 		c.Header("Access-Control-Allow-Origin", origin)
 		c.Header("Access-Control-Allow-Methods", "GET, POST, PUT, DELETE, OPTIONS")
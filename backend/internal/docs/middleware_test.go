@@ -0,0 +1,72 @@
+package docs
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newDocsRouter(config SwaggerConfig) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.Use(APIDocsMiddleware(config))
+	SetupSwagger(r, config)
+	SetupRedocly(r, config)
+	return r
+}
+
+func get(r *gin.Engine, path string) *httptest.ResponseRecorder {
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, path, nil))
+	return w
+}
+
+func TestSwaggerSpecFromConfig(t *testing.T) {
+	config := DefaultSwaggerConfig()
+	config.Host = "erp.example.com"
+	config.Schemes = []string{"https"}
+	config.Version = "1.1.0"
+	r := newDocsRouter(config)
+
+	w := get(r, "/docs/swagger/doc.json")
+	require.Equal(t, http.StatusOK, w.Code)
+	var spec struct {
+		Host     string   `json:"host"`
+		BasePath string   `json:"basePath"`
+		Schemes  []string `json:"schemes"`
+		Info     struct {
+			Version string `json:"version"`
+		} `json:"info"`
+	}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &spec))
+	assert.Equal(t, "erp.example.com", spec.Host)
+	assert.Equal(t, "/", spec.BasePath)
+	assert.Equal(t, []string{"https"}, spec.Schemes)
+	assert.Equal(t, "1.1.0", spec.Info.Version)
+}
+
+func TestDocsDisabled(t *testing.T) {
+	config := DefaultSwaggerConfig()
+	config.Enabled = false
+	r := newDocsRouter(config)
+
+	for _, path := range []string{"/docs/", "/docs/swagger/index.html", "/docs/swagger/doc.json", "/docs/health", "/redoc"} {
+		w := get(r, path)
+		assert.Equal(t, http.StatusNotFound, w.Code, path)
+		assert.Empty(t, w.Header().Get("X-API-Docs"), path)
+	}
+}
+
+func TestDocsEnabled(t *testing.T) {
+	r := newDocsRouter(DefaultSwaggerConfig())
+
+	w := get(r, "/docs/swagger/index.html")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "/docs/swagger/index.html", w.Header().Get("X-API-Docs"))
+	assert.Equal(t, http.StatusOK, get(r, "/redoc").Code)
+}
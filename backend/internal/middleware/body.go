@@ -0,0 +1,117 @@
+package middleware
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"mime"
+	"regexp"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// redactedValue replaces the values of sensitive fields in logged bodies.
+const redactedValue = "[REDACTED]"
+
+// sensitiveFields are the body fields that are never logged, a field is
+// sensitive when its name contains one of them. One-time codes are sent as
+// "code" or "otp", those names have to match exactly so barcodes and zip
+// codes are still logged.
+var sensitiveFields = []string{"password", "token", "secret", "authorization"}
+
+// sensitiveFieldPattern finds sensitive string fields in bodies that were cut
+// short and can't be parsed.
+var sensitiveFieldPattern = regexp.MustCompile(`(?i)("(?:[^"]*(?:password|token|secret|authorization)[^"]*|code|otp)"\s*:\s*)"(?:[^"\\]|\\.)*("|$)`)
+
+// responseBodyWriter keeps the first limit bytes written to the response.
+type responseBodyWriter struct {
+	gin.ResponseWriter
+	body  *bytes.Buffer
+	limit int
+}
+
+func (w responseBodyWriter) Write(b []byte) (int, error) {
+	if room := w.limit + 1 - w.body.Len(); room > 0 {
+		w.body.Write(b[:min(room, len(b))])
+	}
+	return w.ResponseWriter.Write(b)
+}
+
+// isJSON reports whether a content type is JSON. Multipart uploads and any
+// other content are never logged.
+func isJSON(contentType string) bool {
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	return err == nil && (mediaType == "application/json" || strings.HasSuffix(mediaType, "+json"))
+}
+
+// captureRequestBody reads up to limit+1 bytes of a JSON request body and
+// puts them back in front of the rest, so handlers still read all of it.
+func captureRequestBody(c *gin.Context, limit int) []byte {
+	if c.Request.Body == nil || !isJSON(c.GetHeader("Content-Type")) {
+		return nil
+	}
+	head, err := io.ReadAll(io.LimitReader(c.Request.Body, int64(limit)+1))
+	if err != nil {
+		return nil
+	}
+	c.Request.Body = struct {
+		io.Reader
+		io.Closer
+	}{io.MultiReader(bytes.NewReader(head), c.Request.Body), c.Request.Body}
+	return head
+}
+
+// loggedBody prepares a captured body for the log. A body that fits in limit
+// is logged as JSON with its sensitive fields redacted. A longer one is cut
+// at limit and logged as a string, redacted as far as its text allows.
+func loggedBody(body []byte, limit int) any {
+	if len(body) == 0 {
+		return nil
+	}
+
+	if len(body) <= limit {
+		var parsed any
+		if err := json.Unmarshal(body, &parsed); err == nil {
+			redacted, err := json.Marshal(redactFields(parsed))
+			if err == nil {
+				return json.RawMessage(redacted)
+			}
+		}
+		return sensitiveFieldPattern.ReplaceAllString(string(body), `${1}"`+redactedValue+`"`)
+	}
+
+	truncated := sensitiveFieldPattern.ReplaceAllString(string(body[:limit]), `${1}"`+redactedValue+`"`)
+	return truncated + "...(truncated)"
+}
+
+func redactFields(v any) any {
+	switch v := v.(type) {
+	case map[string]any:
+		for key, value := range v {
+			if isSensitiveField(key) {
+				v[key] = redactedValue
+				continue
+			}
+			v[key] = redactFields(value)
+		}
+	case []any:
+		for i, value := range v {
+			v[i] = redactFields(value)
+		}
+	}
+	return v
+}
+
+func isSensitiveField(name string) bool {
+	name = strings.ToLower(name)
+	if name == "code" || name == "otp" {
+		return true
+	}
+	for _, field := range sensitiveFields {
+		if strings.Contains(name, field) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,55 @@
+package middleware
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLoggedBodyRedaction(t *testing.T) {
+	body := `{"username":"ada","password":"hunter2","refresh_token":"abc","code":"123456",
+		"items":[{"barcode":"5012345678900","api_secret":"s3"}],"address":{"zip_code":"100001"}}`
+
+	logged, ok := loggedBody([]byte(body), 1024).(json.RawMessage)
+	assert.True(t, ok, "a body that fits is logged as JSON")
+
+	var got map[string]any
+	assert.NoError(t, json.Unmarshal(logged, &got))
+	assert.Equal(t, map[string]any{
+		"username":      "ada",
+		"password":      redactedValue,
+		"refresh_token": redactedValue,
+		"code":          redactedValue,
+		"items":         []any{map[string]any{"barcode": "5012345678900", "api_secret": redactedValue}},
+		"address":       map[string]any{"zip_code": "100001"},
+	}, got)
+}
+
+func TestLoggedBodyTruncation(t *testing.T) {
+	body := `{"username":"ada","password":"hunter2","notes":"` + strings.Repeat("x", 100) + `"}`
+
+	logged, ok := loggedBody([]byte(body), 40).(string)
+	assert.True(t, ok, "a body cut short is logged as text")
+	assert.Equal(t, `{"username":"ada","password":"[REDACTED]","...(truncated)`, logged)
+
+	// a sensitive value cut in the middle is still redacted
+	logged = loggedBody([]byte(body), 33).(string)
+	assert.Equal(t, `{"username":"ada","password":"[REDACTED]"...(truncated)`, logged)
+	assert.NotContains(t, logged, "hun")
+}
+
+func TestLoggedBodyEmpty(t *testing.T) {
+	assert.Nil(t, loggedBody(nil, 1024))
+	assert.Nil(t, loggedBody([]byte{}, 1024))
+}
+
+func TestIsJSON(t *testing.T) {
+	assert.True(t, isJSON("application/json"))
+	assert.True(t, isJSON("application/json; charset=utf-8"))
+	assert.True(t, isJSON("application/problem+json"))
+	assert.False(t, isJSON("multipart/form-data; boundary=x"))
+	assert.False(t, isJSON("text/plain"))
+	assert.False(t, isJSON(""))
+}
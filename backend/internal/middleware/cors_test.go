@@ -0,0 +1,107 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func newCORSRouter(cfg CORSConfig) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	v1 := r.Group("/api/v1", CORS(cfg))
+	v1.OPTIONS("/*path", func(c *gin.Context) { c.Status(http.StatusNoContent) })
+	v1.GET("/items", func(c *gin.Context) {
+		c.Header("X-RateLimit-Remaining", "9")
+		c.Status(http.StatusOK)
+	})
+	return r
+}
+
+func corsRequest(r *gin.Engine, method, origin string, headers map[string]string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, "/api/v1/items", nil)
+	if origin != "" {
+		req.Header.Set("Origin", origin)
+	}
+	for k, v := range headers {
+		req.Header.Set(k, v)
+	}
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	return w
+}
+
+func testCORSConfig() CORSConfig {
+	return CORSConfig{
+		AllowedOrigins: []string{"https://app.example.com"},
+		AllowedMethods: []string{"GET", "POST"},
+		AllowedHeaders: []string{"Authorization", "X-Business-ID", "Accept-Currency"},
+		ExposedHeaders: []string{"X-Request-ID", "X-RateLimit-Remaining", "Retry-After"},
+		MaxAge:         600,
+	}
+}
+
+func TestCORSAllowedOrigin(t *testing.T) {
+	cfg := testCORSConfig()
+	cfg.AllowCredentials = true
+	r := newCORSRouter(cfg)
+
+	w := corsRequest(r, http.MethodGet, "https://app.example.com", nil)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "https://app.example.com", w.Header().Get("Access-Control-Allow-Origin"))
+	assert.Equal(t, "true", w.Header().Get("Access-Control-Allow-Credentials"))
+	assert.Equal(t, "X-Request-ID, X-RateLimit-Remaining, Retry-After", w.Header().Get("Access-Control-Expose-Headers"))
+	assert.Equal(t, "Origin", w.Header().Get("Vary"))
+	assert.Equal(t, "9", w.Header().Get("X-RateLimit-Remaining"))
+}
+
+func TestCORSAnyOrigin(t *testing.T) {
+	cfg := testCORSConfig()
+	cfg.AllowedOrigins = []string{"*"}
+	cfg.AllowCredentials = true
+	r := newCORSRouter(cfg)
+
+	w := corsRequest(r, http.MethodGet, "https://elsewhere.example.com", nil)
+	assert.Equal(t, "*", w.Header().Get("Access-Control-Allow-Origin"))
+	assert.Empty(t, w.Header().Get("Access-Control-Allow-Credentials"), "never with any origin")
+	assert.NotEmpty(t, w.Header().Get("Access-Control-Expose-Headers"))
+}
+
+func TestCORSDisallowedOrigin(t *testing.T) {
+	r := newCORSRouter(testCORSConfig())
+
+	// served, without the headers the browser needs to hand it to the page
+	w := corsRequest(r, http.MethodGet, "https://evil.example.com", nil)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Empty(t, w.Header().Get("Access-Control-Allow-Origin"))
+	assert.Empty(t, w.Header().Get("Access-Control-Expose-Headers"))
+
+	w = corsRequest(r, http.MethodOptions, "https://evil.example.com", map[string]string{
+		"Access-Control-Request-Method": "POST",
+	})
+	assert.Equal(t, http.StatusForbidden, w.Code)
+	assert.Empty(t, w.Header().Get("Access-Control-Allow-Methods"))
+
+	// same origin requests carry no Origin and are left alone
+	w = corsRequest(r, http.MethodGet, "", nil)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Empty(t, w.Header().Get("Vary"))
+}
+
+func TestCORSPreflight(t *testing.T) {
+	r := newCORSRouter(testCORSConfig())
+
+	w := corsRequest(r, http.MethodOptions, "https://app.example.com", map[string]string{
+		"Access-Control-Request-Method":  "POST",
+		"Access-Control-Request-Headers": "X-Business-ID, Accept-Currency",
+	})
+	assert.Equal(t, http.StatusNoContent, w.Code)
+	assert.Equal(t, "https://app.example.com", w.Header().Get("Access-Control-Allow-Origin"))
+	assert.Equal(t, "GET, POST", w.Header().Get("Access-Control-Allow-Methods"))
+	assert.Equal(t, "Authorization, X-Business-ID, Accept-Currency", w.Header().Get("Access-Control-Allow-Headers"))
+	assert.Equal(t, "600", w.Header().Get("Access-Control-Max-Age"))
+	assert.Empty(t, w.Header().Get("Access-Control-Allow-Credentials"))
+}
@@ -0,0 +1,50 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRequestIDGenerated(t *testing.T) {
+	r, logs := newLoggedRouter(false, 0, RequestID())
+	var seen string
+	r.GET("/ping", func(c *gin.Context) {
+		seen = RequestIDFromContext(c)
+		c.Status(http.StatusNoContent)
+	})
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/ping", nil))
+
+	id := w.Header().Get(RequestIDHeader)
+	_, err := uuid.Parse(id)
+	require.NoError(t, err, "the generated ID is a UUID")
+	assert.Equal(t, id, seen, "handlers see the same ID")
+	assert.Equal(t, id, lastEntry(t, logs)["request_id"])
+
+	// every request gets its own
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/ping", nil))
+	assert.NotEqual(t, id, w.Header().Get(RequestIDHeader))
+}
+
+func TestRequestIDKept(t *testing.T) {
+	r, logs := newLoggedRouter(false, 0, RequestID())
+	r.GET("/ping", func(c *gin.Context) {
+		c.Status(http.StatusNoContent)
+	})
+
+	w := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodGet, "/ping", nil)
+	req.Header.Set(RequestIDHeader, "checkout-42")
+	r.ServeHTTP(w, req)
+
+	assert.Equal(t, "checkout-42", w.Header().Get(RequestIDHeader))
+	assert.Equal(t, "checkout-42", lastEntry(t, logs)["request_id"])
+}
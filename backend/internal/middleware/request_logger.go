@@ -1,6 +1,7 @@
 package middleware
 
 import (
+	"bytes"
 	"encoding/json"
 	"fmt"
 	"herp/internal/config"
@@ -25,11 +26,14 @@ type logEntry struct {
 	UserAgent    string   `json:"user_agent"`
 	Errors       []string `json:"errors,omitempty"`
 	RequestID    string   `json:"request_id,omitempty"`
+	RequestBody  any      `json:"request_body,omitempty"`
+	ResponseBody any      `json:"response_body,omitempty"`
 }
 
 // NewRequestLogger returns a Gin middleware that logs request/response details
 // to both stdout and the specified file. The directory for the log file will be
-// created if it does not exist.
+// created if it does not exist. When LogBodies is set JSON request and
+// response bodies are logged too, redacted and truncated.
 func NewRequestLogger(logFilePath string, c *config.Config) gin.HandlerFunc {
 	ginMode := c.GinMode
 	var writer io.Writer
@@ -60,15 +64,38 @@ func NewRequestLogger(logFilePath string, c *config.Config) gin.HandlerFunc {
 		}
 	}
 
+	return requestLogger(writer, c.LogBodies, c.LogBodyMaxSize)
+}
+
+// requestLogger writes a JSON line to writer for every request, with the
+// request and response bodies when logBodies is set.
+func requestLogger(writer io.Writer, logBodies bool, limit int) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		start := time.Now()
+		if !logBodies {
+			c.Next()
+			writeJSONLog(writer, c, start, nil, nil)
+			return
+		}
+
+		requestBody := captureRequestBody(c, limit)
+		w := &responseBodyWriter{
+			ResponseWriter: c.Writer,
+			body:           &bytes.Buffer{},
+			limit:          limit,
+		}
+		c.Writer = w
 		c.Next()
-		writeJSONLog(writer, c, start)
-	}
 
+		var responseBody []byte
+		if isJSON(w.Header().Get("Content-Type")) {
+			responseBody = w.body.Bytes()
+		}
+		writeJSONLog(writer, c, start, loggedBody(requestBody, limit), loggedBody(responseBody, limit))
+	}
 }
 
-func writeJSONLog(w io.Writer, c *gin.Context, start time.Time) {
+func writeJSONLog(w io.Writer, c *gin.Context, start time.Time, requestBody, responseBody any) {
 	latency := time.Since(start)
 	statusCode := c.Writer.Status()
 	clientIP := c.ClientIP()
@@ -92,10 +119,8 @@ func writeJSONLog(w io.Writer, c *gin.Context, start time.Time) {
 
 	// Capture HTTP status errors (4xx, 5xx)
 	if statusCode >= 400 {
-		// Try to capture response body for error details
+		// the body itself is only logged when LogBodies is set
 		if c.Writer.Size() > 0 {
-			// Note: We can't easily capture response body in middleware
-			// but we can log the status code and any error messages
 			errorDetails = append(errorDetails, fmt.Sprintf("HTTP %d", statusCode))
 		}
 	}
@@ -126,10 +151,9 @@ func writeJSONLog(w io.Writer, c *gin.Context, start time.Time) {
 		ClientIP:     clientIP,
 		UserAgent:    userAgent,
 		Errors:       errorDetails,
-	}
-
-	if reqID := c.GetHeader("X-Request-ID"); reqID != "" {
-		entry.RequestID = reqID
+		RequestID:    RequestIDFromContext(c),
+		RequestBody:  requestBody,
+		ResponseBody: responseBody,
 	}
 
 	enc := json.NewEncoder(w)
@@ -0,0 +1,141 @@
+package pos
+
+import (
+	"context"
+	"database/sql"
+	"herp/db/dbtest"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// coupon creates a code giving 5.00 off the sale, usable limit times.
+func (f saleFixture) coupon(t *testing.T, code string, limit int32) int32 {
+	t.Helper()
+	discount := dbtest.Insert(t, f.conn, `
+		INSERT INTO discounts (business_id, name, kind, value, scope, created_by)
+		VALUES ($1, 'Five off', 'fixed', 5, 'sale', 1)
+		RETURNING id`, f.businessID)
+	return dbtest.Insert(t, f.conn, `
+		INSERT INTO coupons (business_id, discount_id, code, usage_limit, created_by)
+		VALUES ($1, $2, $3, $4, 1)
+		RETURNING id`, f.businessID, discount, code, limit)
+}
+
+// redeem sells two of the fixture's variation with a coupon code, paying
+// the 15.00 left after it.
+func (f saleFixture) redeem(ctx context.Context, code string) (SaleResult, error) {
+	return f.pos.CreateSale(ctx, CreateSaleParams{
+		StoreID:     f.storeID,
+		CustomerID:  1,
+		CashierID:   1,
+		Lines:       []SaleLine{{VariationID: f.variation, Quantity: 2}},
+		Payments:    []SalePayment{{Method: "cash", Amount: 15}},
+		CouponCode:  code,
+		DiscountCap: 100,
+	})
+}
+
+func (f saleFixture) couponUses(t *testing.T, id int32) int32 {
+	t.Helper()
+	var used int32
+	require.NoError(t, f.conn.QueryRow(`SELECT used_count FROM coupons WHERE id = $1`, id).Scan(&used))
+	return used
+}
+
+func TestRedeemCoupon(t *testing.T) {
+	f := newSaleFixture(t)
+	ctx := context.Background()
+	dbtest.Stock(t, f.conn, f.storeID, f.variation, 10)
+	id := f.coupon(t, "WELCOME5", 2)
+
+	// codes are matched without regard to case
+	result, err := f.redeem(ctx, "welcome5")
+	require.NoError(t, err)
+	assert.Equal(t, "15.00", result.Sale.TotalAmount)
+	require.Len(t, result.Discounts, 1)
+	assert.Equal(t, sql.NullInt32{Int32: id, Valid: true}, result.Discounts[0].CouponID)
+	assert.Equal(t, int32(1), f.couponUses(t, id))
+
+	_, err = f.redeem(ctx, "WELCOME5")
+	require.NoError(t, err)
+	_, err = f.redeem(ctx, "WELCOME5")
+	assert.ErrorIs(t, err, ErrCouponExhausted)
+	assert.Equal(t, int32(2), f.couponUses(t, id))
+}
+
+func TestRedeemCouponRejects(t *testing.T) {
+	f := newSaleFixture(t)
+	ctx := context.Background()
+	dbtest.Stock(t, f.conn, f.storeID, f.variation, 10)
+
+	expired := f.coupon(t, "LASTWEEK", 5)
+	dbtest.Exec(t, f.conn, `UPDATE coupons SET expires_at = NOW() - INTERVAL '1 day' WHERE id = $1`, expired)
+	inactive := f.coupon(t, "PAUSED", 5)
+	dbtest.Exec(t, f.conn, `UPDATE coupons SET is_active = FALSE WHERE id = $1`, inactive)
+
+	for code, want := range map[string]error{
+		"NOSUCHCODE": ErrCouponInvalid,
+		"LASTWEEK":   ErrCouponExpired,
+		"PAUSED":     ErrCouponInvalid,
+	} {
+		_, err := f.redeem(ctx, code)
+		assert.ErrorIs(t, err, want, code)
+	}
+	assert.Zero(t, f.couponUses(t, expired))
+	assert.Zero(t, f.couponUses(t, inactive))
+}
+
+func TestRedeemCouponFailedSale(t *testing.T) {
+	f := newSaleFixture(t)
+	ctx := context.Background()
+	id := f.coupon(t, "ONCE", 1)
+
+	// nothing in stock, the sale fails and the coupon isn't used up
+	_, err := f.redeem(ctx, "ONCE")
+	require.Error(t, err)
+	assert.Zero(t, f.couponUses(t, id))
+
+	dbtest.Stock(t, f.conn, f.storeID, f.variation, 10)
+	_, err = f.redeem(ctx, "ONCE")
+	assert.NoError(t, err)
+}
+
+func TestRedeemCouponConcurrently(t *testing.T) {
+	f := newSaleFixture(t)
+	ctx := context.Background()
+	dbtest.Stock(t, f.conn, f.storeID, f.variation, 100)
+	id := f.coupon(t, "ONCE", 1)
+
+	const tills = 8
+	errs := make([]error, tills)
+	start := make(chan struct{})
+	var wg sync.WaitGroup
+	for i := range tills {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			<-start
+			_, errs[i] = f.redeem(ctx, "ONCE")
+		}()
+	}
+	close(start)
+	wg.Wait()
+
+	var made int
+	for _, err := range errs {
+		if err == nil {
+			made++
+			continue
+		}
+		assert.ErrorIs(t, err, ErrCouponExhausted)
+	}
+	assert.Equal(t, 1, made, "one sale redeems the coupon")
+	assert.Equal(t, int32(1), f.couponUses(t, id))
+
+	var discounted int
+	require.NoError(t, f.conn.QueryRow(`SELECT COUNT(*) FROM sale_discount WHERE coupon_id = $1`, id).Scan(&discounted))
+	assert.Equal(t, 1, discounted)
+}
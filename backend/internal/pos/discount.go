@@ -0,0 +1,422 @@
+package pos
+
+import (
+	"cmp"
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	db "herp/db/sqlc"
+	"math/big"
+	"slices"
+)
+
+const (
+	DiscountPercentage = "percentage"
+	DiscountFixed      = "fixed"
+
+	DiscountScopeSale     = "sale"
+	DiscountScopeItem     = "item"
+	DiscountScopeCategory = "category"
+)
+
+// manualDiscountName is what the discount a cashier enters by hand is called
+// in the breakdown of a sale.
+const manualDiscountName = "Manual discount"
+
+var (
+	ErrDiscountNotFound         = errors.New("discount not found")
+	ErrDiscountPercentage       = errors.New("percentage discount can't be over 100")
+	ErrDiscountTarget           = errors.New("item discounts need an item and category discounts a category, other discounts neither")
+	ErrDiscountWindow           = errors.New("discount has to end after it starts")
+	ErrDiscountItemNotFound     = errors.New("item of discount not found in business")
+	ErrDiscountCategoryNotFound = errors.New("category of discount not found in business")
+
+	ErrCouponInvalid          = errors.New("coupon code is not valid")
+	ErrCouponExpired          = errors.New("coupon code has expired")
+	ErrCouponExhausted        = errors.New("coupon code has been used up")
+	ErrCouponNotApplicable    = errors.New("coupon code does not apply to this sale")
+	ErrCouponNotFound         = errors.New("coupon not found")
+	ErrCouponDiscountNotFound = errors.New("discount of coupon not found in business")
+)
+
+// scopeOrder is the order rules are applied in, the narrowest first.
+var scopeOrder = map[string]int{
+	DiscountScopeItem:     0,
+	DiscountScopeCategory: 1,
+	DiscountScopeSale:     2,
+}
+
+// DiscountRule are the settings of a discount rule that are checked before
+// it is saved.
+type DiscountRule struct {
+	Kind       string
+	Value      string
+	Scope      string
+	ItemID     sql.NullInt32
+	CategoryID sql.NullInt32
+	StartsAt   sql.NullTime
+	EndsAt     sql.NullTime
+}
+
+// AppliedDiscount is an amount in cents taken off a sale. DiscountID is zero
+// for the discount entered by hand, CouponID is set when a coupon gave it.
+type AppliedDiscount struct {
+	DiscountID int32
+	CouponID   int32
+	Name       string
+	Amount     int64
+}
+
+// discountLine is a sale line as discount rules see it, what it costs in
+// cents and what a rule can match it on.
+type discountLine struct {
+	ItemID     int32
+	CategoryID int32
+	Amount     int64
+}
+
+// applyDiscounts works out how much each rule takes off a sale. Rules on an
+// item go first, then rules on a category and then rules on the whole sale,
+// each taking its share of what the lines still cost after the rules before
+// it, so a line can never go below zero. The manual discount comes last. The
+// minimum spend of a rule is checked against the subtotal before discounts.
+//
+// capPercent limits all discounts together to that percentage of the
+// subtotal. The discount that would go over it is cut down to what is left
+// and the ones after it are dropped.
+func applyDiscounts(rules []db.Discount, lines []discountLine, manual int64, capPercent int) ([]AppliedDiscount, error) {
+	remaining := make([]int64, len(lines))
+	var subtotal int64
+	for i, line := range lines {
+		remaining[i] = line.Amount
+		subtotal += line.Amount
+	}
+	capPercent = min(max(capPercent, 0), 100)
+	left := subtotal * int64(capPercent) / 100
+
+	ordered := slices.Clone(rules)
+	slices.SortStableFunc(ordered, func(a, b db.Discount) int {
+		return cmp.Compare(scopeOrder[a.Scope], scopeOrder[b.Scope])
+	})
+
+	applied := []AppliedDiscount{}
+	for _, rule := range ordered {
+		if left <= 0 {
+			break
+		}
+		if rule.MinSpend.Valid {
+			minSpend, ok := new(big.Rat).SetString(rule.MinSpend.String)
+			if !ok {
+				return nil, fmt.Errorf("invalid minimum spend %q of discount %d", rule.MinSpend.String, rule.ID)
+			}
+			if big.NewRat(subtotal, 100).Cmp(minSpend) < 0 {
+				continue
+			}
+		}
+		value, ok := new(big.Rat).SetString(rule.Value)
+		if !ok {
+			return nil, fmt.Errorf("invalid value %q of discount %d", rule.Value, rule.ID)
+		}
+
+		var matched []int
+		for i, line := range lines {
+			if discountMatches(rule, line) {
+				matched = append(matched, i)
+			}
+		}
+		amount := takeDiscount(rule.Kind, value, remaining, matched, left)
+		if amount == 0 {
+			continue
+		}
+		applied = append(applied, AppliedDiscount{DiscountID: rule.ID, Name: rule.Name, Amount: amount})
+		left -= amount
+	}
+
+	// left never exceeds what the lines still cost, every amount taken comes
+	// off both
+	if manual = min(max(manual, 0), left); manual > 0 {
+		applied = append(applied, AppliedDiscount{Name: manualDiscountName, Amount: manual})
+	}
+	return applied, nil
+}
+
+func discountMatches(rule db.Discount, line discountLine) bool {
+	switch rule.Scope {
+	case DiscountScopeItem:
+		return rule.ItemID.Valid && rule.ItemID.Int32 == line.ItemID
+	case DiscountScopeCategory:
+		return rule.CategoryID.Valid && rule.CategoryID.Int32 == line.CategoryID
+	default:
+		return true
+	}
+}
+
+// takeDiscount takes a discount off the matched lines, a percentage of each
+// line or a fixed amount spread over the lines in order, but never more than
+// limit. remaining is lowered by what is taken off each line and the total
+// taken is returned.
+func takeDiscount(kind string, value *big.Rat, remaining []int64, matched []int, limit int64) int64 {
+	var fixed int64
+	if kind == DiscountFixed {
+		fixed = roundRat(new(big.Rat).Mul(value, big.NewRat(100, 1)), "nearest")
+	}
+
+	var taken int64
+	for _, i := range matched {
+		if taken >= limit {
+			break
+		}
+		amount := fixed - taken
+		if kind == DiscountPercentage {
+			amount = roundRat(new(big.Rat).Mul(big.NewRat(remaining[i], 100), value), "nearest")
+		}
+		amount = max(min(amount, remaining[i], limit-taken), 0)
+		remaining[i] -= amount
+		taken += amount
+	}
+	return taken
+}
+
+func sumDiscounts(discounts []AppliedDiscount) int64 {
+	var total int64
+	for _, discount := range discounts {
+		total += discount.Amount
+	}
+	return total
+}
+
+// saleDiscounts applies the discount rules of the store's business that are
+// running now, the rule of the coupon the sale redeems and the manual
+// discount to the lines of a sale. A coupon whose rule takes nothing off
+// fails the sale with ErrCouponNotApplicable rather than being used up.
+func saleDiscounts(ctx context.Context, q *db.Queries, args CreateSaleParams) ([]AppliedDiscount, error) {
+	rules, err := q.ListActiveDiscountsForStore(ctx, args.StoreID)
+	if err != nil {
+		return nil, err
+	}
+
+	var coupon db.Coupon
+	if args.CouponCode != "" {
+		var rule db.Discount
+		coupon, rule, err = redeemCoupon(ctx, q, args.StoreID, args.CouponCode)
+		if err != nil {
+			return nil, err
+		}
+		rules = append(rules, rule)
+	}
+
+	lines := make([]discountLine, 0, len(args.Lines))
+	for _, line := range args.Lines {
+		dl := discountLine{Amount: toCents(line.UnitPrice) * int64(line.Quantity)}
+		if len(rules) > 0 {
+			// a variation that doesn't exist matches no rule, the sale
+			// fails on it further on
+			row, err := q.GetVariationItemCategory(ctx, line.VariationID)
+			if err != nil && !errors.Is(err, sql.ErrNoRows) {
+				return nil, err
+			}
+			dl.ItemID, dl.CategoryID = row.ItemID, row.CategoryID
+		}
+		lines = append(lines, dl)
+	}
+
+	applied, err := applyDiscounts(rules, lines, toCents(args.DiscountAmount), args.DiscountCap)
+	if err != nil {
+		return nil, err
+	}
+	if coupon.ID != 0 {
+		// rules with coupons are never applied on their own, so the coupon
+		// gave the discount of its rule
+		i := slices.IndexFunc(applied, func(d AppliedDiscount) bool { return d.DiscountID == coupon.DiscountID })
+		if i < 0 {
+			return nil, ErrCouponNotApplicable
+		}
+		applied[i].CouponID = coupon.ID
+	}
+	return applied, nil
+}
+
+// redeemCoupon checks a coupon code for a sale in a store and counts its use,
+// returning the coupon and the rule it gives. The use is counted in the
+// transaction of the sale, so a sale that fails doesn't use it up, and the
+// coupon stays locked until the sale is done.
+func redeemCoupon(ctx context.Context, q *db.Queries, storeID int32, code string) (db.Coupon, db.Discount, error) {
+	found, err := q.GetCouponForStore(ctx, db.GetCouponForStoreParams{StoreID: storeID, Code: code})
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return db.Coupon{}, db.Discount{}, ErrCouponInvalid
+		}
+		return db.Coupon{}, db.Discount{}, err
+	}
+	switch {
+	case found.Expired:
+		return db.Coupon{}, db.Discount{}, ErrCouponExpired
+	case !found.IsActive, !found.DiscountRunning:
+		return db.Coupon{}, db.Discount{}, ErrCouponInvalid
+	}
+
+	coupon, err := q.RedeemCoupon(ctx, found.ID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return db.Coupon{}, db.Discount{}, ErrCouponExhausted
+		}
+		return db.Coupon{}, db.Discount{}, err
+	}
+	rule, err := q.GetDiscount(ctx, db.GetDiscountParams{
+		ID:         coupon.DiscountID,
+		BusinessID: sql.NullInt32{Int32: coupon.BusinessID, Valid: true},
+	})
+	if err != nil {
+		return db.Coupon{}, db.Discount{}, err
+	}
+	return coupon, rule, nil
+}
+
+func createSaleDiscounts(ctx context.Context, q *db.Queries, saleID int32, discounts []AppliedDiscount) ([]db.SaleDiscount, error) {
+	created := make([]db.SaleDiscount, 0, len(discounts))
+	for _, discount := range discounts {
+		row, err := q.CreateSaleDiscount(ctx, db.CreateSaleDiscountParams{
+			SaleID:     saleID,
+			DiscountID: sql.NullInt32{Int32: discount.DiscountID, Valid: discount.DiscountID != 0},
+			Name:       discount.Name,
+			Amount:     formatCents(discount.Amount),
+			CouponID:   sql.NullInt32{Int32: discount.CouponID, Valid: discount.CouponID != 0},
+		})
+		if err != nil {
+			return nil, err
+		}
+		created = append(created, row)
+	}
+	return created, nil
+}
+
+// checkDiscount checks a rule before it is saved for a business, the item or
+// category it applies to has to be one of the business.
+func (p *POS) checkDiscount(ctx context.Context, businessID int32, rule DiscountRule) error {
+	if rule.Kind == DiscountPercentage {
+		value, ok := new(big.Rat).SetString(rule.Value)
+		if !ok {
+			return fmt.Errorf("invalid discount value %q", rule.Value)
+		}
+		if value.Cmp(big.NewRat(100, 1)) > 0 {
+			return ErrDiscountPercentage
+		}
+	}
+	if rule.ItemID.Valid != (rule.Scope == DiscountScopeItem) ||
+		rule.CategoryID.Valid != (rule.Scope == DiscountScopeCategory) {
+		return ErrDiscountTarget
+	}
+	if rule.StartsAt.Valid && rule.EndsAt.Valid && !rule.EndsAt.Time.After(rule.StartsAt.Time) {
+		return ErrDiscountWindow
+	}
+
+	scope := sql.NullInt32{Int32: businessID, Valid: true}
+	if rule.ItemID.Valid {
+		if _, err := p.queries.GetItem(ctx, db.GetItemParams{ID: rule.ItemID.Int32, BusinessID: scope}); err != nil {
+			if errors.Is(err, sql.ErrNoRows) {
+				return ErrDiscountItemNotFound
+			}
+			return err
+		}
+	}
+	if rule.CategoryID.Valid {
+		if _, err := p.queries.GetCategory(ctx, db.GetCategoryParams{ID: rule.CategoryID.Int32, BusinessID: scope}); err != nil {
+			if errors.Is(err, sql.ErrNoRows) {
+				return ErrDiscountCategoryNotFound
+			}
+			return err
+		}
+	}
+	return nil
+}
+
+func (p *POS) CreateDiscount(ctx context.Context, params db.CreateDiscountParams) (db.Discount, error) {
+	if err := p.checkDiscount(ctx, params.BusinessID, DiscountRule{
+		Kind:       params.Kind,
+		Value:      params.Value,
+		Scope:      params.Scope,
+		ItemID:     params.ItemID,
+		CategoryID: params.CategoryID,
+		StartsAt:   params.StartsAt,
+		EndsAt:     params.EndsAt,
+	}); err != nil {
+		return db.Discount{}, err
+	}
+	return p.queries.CreateDiscount(ctx, params)
+}
+
+func (p *POS) GetDiscount(ctx context.Context, params db.GetDiscountParams) (db.Discount, error) {
+	discount, err := p.queries.GetDiscount(ctx, params)
+	if errors.Is(err, sql.ErrNoRows) {
+		return db.Discount{}, ErrDiscountNotFound
+	}
+	return discount, err
+}
+
+func (p *POS) ListDiscounts(ctx context.Context, businessID sql.NullInt32) ([]db.Discount, error) {
+	return p.queries.ListDiscounts(ctx, businessID)
+}
+
+// UpdateDiscount replaces the settings of a discount in the business scope.
+func (p *POS) UpdateDiscount(ctx context.Context, businessID sql.NullInt32, params db.UpdateDiscountParams) (db.Discount, error) {
+	discount, err := p.GetDiscount(ctx, db.GetDiscountParams{ID: params.ID, BusinessID: businessID})
+	if err != nil {
+		return db.Discount{}, err
+	}
+	if err := p.checkDiscount(ctx, discount.BusinessID, DiscountRule{
+		Kind:       params.Kind,
+		Value:      params.Value,
+		Scope:      params.Scope,
+		ItemID:     params.ItemID,
+		CategoryID: params.CategoryID,
+		StartsAt:   params.StartsAt,
+		EndsAt:     params.EndsAt,
+	}); err != nil {
+		return db.Discount{}, err
+	}
+	return p.queries.UpdateDiscount(ctx, params)
+}
+
+// DeleteDiscount deletes a discount rule. Sales it was applied to keep their
+// breakdown under the name the rule had.
+func (p *POS) DeleteDiscount(ctx context.Context, params db.DeleteDiscountParams) error {
+	deleted, err := p.queries.DeleteDiscount(ctx, params)
+	if err != nil {
+		return err
+	}
+	if deleted == 0 {
+		return ErrDiscountNotFound
+	}
+	return nil
+}
+
+// CreateCoupon creates a coupon for a discount rule of the business. From
+// then on the rule is only applied to sales redeeming one of its coupons.
+func (p *POS) CreateCoupon(ctx context.Context, params db.CreateCouponParams) (db.Coupon, error) {
+	if _, err := p.queries.GetDiscount(ctx, db.GetDiscountParams{
+		ID:         params.DiscountID,
+		BusinessID: sql.NullInt32{Int32: params.BusinessID, Valid: true},
+	}); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return db.Coupon{}, ErrCouponDiscountNotFound
+		}
+		return db.Coupon{}, err
+	}
+	return p.queries.CreateCoupon(ctx, params)
+}
+
+func (p *POS) ListCoupons(ctx context.Context, businessID sql.NullInt32) ([]db.Coupon, error) {
+	return p.queries.ListCoupons(ctx, businessID)
+}
+
+func (p *POS) DeleteCoupon(ctx context.Context, params db.DeleteCouponParams) error {
+	deleted, err := p.queries.DeleteCoupon(ctx, params)
+	if err != nil {
+		return err
+	}
+	if deleted == 0 {
+		return ErrCouponNotFound
+	}
+	return nil
+}
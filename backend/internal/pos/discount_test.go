@@ -0,0 +1,147 @@
+package pos
+
+import (
+	"database/sql"
+	db "herp/db/sqlc"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// discountLines are 20.00 and 10.00 of two items in category 10 and 5.00 of
+// an item in category 20, 35.00 in all.
+var discountLines = []discountLine{
+	{ItemID: 1, CategoryID: 10, Amount: 2000},
+	{ItemID: 2, CategoryID: 10, Amount: 1000},
+	{ItemID: 3, CategoryID: 20, Amount: 500},
+}
+
+func saleRule(id int32, kind, value string) db.Discount {
+	return db.Discount{ID: id, Name: kind + " " + value, Kind: kind, Value: value, Scope: DiscountScopeSale}
+}
+
+func itemRule(id int32, kind, value string, item int32) db.Discount {
+	rule := saleRule(id, kind, value)
+	rule.Scope = DiscountScopeItem
+	rule.ItemID = sql.NullInt32{Int32: item, Valid: true}
+	return rule
+}
+
+func categoryRule(id int32, kind, value string, category int32) db.Discount {
+	rule := saleRule(id, kind, value)
+	rule.Scope = DiscountScopeCategory
+	rule.CategoryID = sql.NullInt32{Int32: category, Valid: true}
+	return rule
+}
+
+func minSpend(rule db.Discount, amount string) db.Discount {
+	rule.MinSpend = sql.NullString{String: amount, Valid: true}
+	return rule
+}
+
+// taken maps the discounts applied by id, 0 for the manual one, to their
+// amount.
+func taken(applied []AppliedDiscount) map[int32]int64 {
+	amounts := map[int32]int64{}
+	for _, discount := range applied {
+		amounts[discount.DiscountID] = discount.Amount
+	}
+	return amounts
+}
+
+func TestApplyDiscounts(t *testing.T) {
+	tests := []struct {
+		name   string
+		rules  []db.Discount
+		manual int64
+		want   map[int32]int64
+	}{
+		{"percentage off the sale", []db.Discount{saleRule(1, DiscountPercentage, "10")}, 0, map[int32]int64{1: 350}},
+		{"fixed off the sale", []db.Discount{saleRule(1, DiscountFixed, "5.00")}, 0, map[int32]int64{1: 500}},
+		{"fractional percentage", []db.Discount{saleRule(1, DiscountPercentage, "12.5")}, 0, map[int32]int64{1: 438}},
+		{"percentage off an item", []db.Discount{itemRule(1, DiscountPercentage, "50", 1)}, 0, map[int32]int64{1: 1000}},
+		{"fixed off an item", []db.Discount{itemRule(1, DiscountFixed, "2.50", 3)}, 0, map[int32]int64{1: 250}},
+		{"fixed over the item price", []db.Discount{itemRule(1, DiscountFixed, "8.00", 3)}, 0, map[int32]int64{1: 500}},
+		{"percentage off a category", []db.Discount{categoryRule(1, DiscountPercentage, "10", 10)}, 0, map[int32]int64{1: 300}},
+		{"fixed spread over a category", []db.Discount{categoryRule(1, DiscountFixed, "25.00", 10)}, 0, map[int32]int64{1: 2500}},
+		{"item not sold", []db.Discount{itemRule(1, DiscountPercentage, "50", 9)}, 0, map[int32]int64{}},
+		{"category not sold", []db.Discount{categoryRule(1, DiscountFixed, "5.00", 30)}, 0, map[int32]int64{}},
+		{"minimum spend met", []db.Discount{minSpend(saleRule(1, DiscountFixed, "5.00"), "35.00")}, 0, map[int32]int64{1: 500}},
+		{"minimum spend missed", []db.Discount{minSpend(saleRule(1, DiscountFixed, "5.00"), "35.01")}, 0, map[int32]int64{}},
+		{
+			// the item rule goes first though listed last, the sale
+			// rule takes 10% of what is left
+			"narrowest first",
+			[]db.Discount{saleRule(1, DiscountPercentage, "10"), itemRule(2, DiscountPercentage, "50", 1)},
+			0,
+			map[int32]int64{2: 1000, 1: 250},
+		},
+		{"manual after the rules", []db.Discount{saleRule(1, DiscountFixed, "5.00")}, 300, map[int32]int64{1: 500, 0: 300}},
+		{"manual over the subtotal", nil, 5000, map[int32]int64{0: 3500}},
+		{"negative manual", nil, -100, map[int32]int64{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			applied, err := applyDiscounts(tt.rules, discountLines, tt.manual, 100)
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, taken(applied))
+			assert.LessOrEqual(t, sumDiscounts(applied), int64(3500))
+		})
+	}
+}
+
+func TestApplyDiscountsOrder(t *testing.T) {
+	applied, err := applyDiscounts([]db.Discount{
+		saleRule(1, DiscountPercentage, "10"),
+		categoryRule(2, DiscountFixed, "1.00", 20),
+		itemRule(3, DiscountFixed, "1.00", 2),
+	}, discountLines, 100, 100)
+	require.NoError(t, err)
+	names := []string{}
+	for _, discount := range applied {
+		names = append(names, discount.Name)
+	}
+	assert.Equal(t, []string{"fixed 1.00", "fixed 1.00", "percentage 10", manualDiscountName}, names)
+	assert.Equal(t, []int32{3, 2, 1, 0}, []int32{applied[0].DiscountID, applied[1].DiscountID, applied[2].DiscountID, applied[3].DiscountID})
+}
+
+func TestApplyDiscountsStackingCap(t *testing.T) {
+	rules := []db.Discount{itemRule(1, DiscountPercentage, "50", 1), saleRule(2, DiscountPercentage, "10")}
+
+	// 1000 and then 250 fit under 50% of 3500
+	applied, err := applyDiscounts(rules, discountLines, 0, 50)
+	require.NoError(t, err)
+	assert.Equal(t, map[int32]int64{1: 1000, 2: 250}, taken(applied))
+
+	// at 30% the sale rule is cut down to the 50 left
+	applied, err = applyDiscounts(rules, discountLines, 0, 30)
+	require.NoError(t, err)
+	assert.Equal(t, map[int32]int64{1: 1000, 2: 50}, taken(applied))
+
+	// at 20% the item rule is cut down and everything after it dropped
+	applied, err = applyDiscounts(rules, discountLines, 500, 20)
+	require.NoError(t, err)
+	assert.Equal(t, map[int32]int64{1: 700}, taken(applied))
+
+	// the manual discount counts against the cap too
+	applied, err = applyDiscounts(nil, discountLines, 1000, 20)
+	require.NoError(t, err)
+	assert.Equal(t, map[int32]int64{0: 700}, taken(applied))
+
+	applied, err = applyDiscounts(rules, discountLines, 500, 0)
+	require.NoError(t, err)
+	assert.Empty(t, applied, "no discounts at all")
+
+	// out of range caps are clamped
+	applied, err = applyDiscounts(nil, discountLines, 5000, 150)
+	require.NoError(t, err)
+	assert.Equal(t, map[int32]int64{0: 3500}, taken(applied))
+}
+
+func TestApplyDiscountsInvalidRule(t *testing.T) {
+	_, err := applyDiscounts([]db.Discount{saleRule(1, DiscountPercentage, "ten")}, discountLines, 0, 100)
+	assert.Error(t, err)
+	_, err = applyDiscounts([]db.Discount{minSpend(saleRule(1, DiscountFixed, "1"), "lots")}, discountLines, 0, 100)
+	assert.Error(t, err)
+}
@@ -0,0 +1,113 @@
+package pos
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	db "herp/db/sqlc"
+	"herp/pkg/monitoring/logging"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// historyService answers every history request with one sale.
+type historyService struct {
+	POSInterface
+}
+
+func (historyService) SalesHistory(ctx context.Context, args SalesHistoryParams) (SalesHistory, error) {
+	return SalesHistory{
+		Timezone: "Africa/Lagos",
+		Sales: []HistorySale{{
+			Sale:  db.Sale{ID: 9, CustomerID: 1, TotalAmount: "10.75", TaxAmount: "0.75", DiscountAmount: "0.00", CreatedAt: sql.NullTime{Time: time.Now(), Valid: true}},
+			Items: []db.SaleItem{{VariationID: 3, Quantity: 2, UnitPrice: "5.00"}},
+		}},
+		Total: 1,
+	}, nil
+}
+
+// envelope is the standard response, with data left raw for the caller.
+type envelope struct {
+	Version string          `json:"version"`
+	Status  string          `json:"status"`
+	Message string          `json:"message"`
+	Error   string          `json:"error"`
+	Data    json.RawMessage `json:"data"`
+}
+
+func decodeEnvelope(t *testing.T, w *httptest.ResponseRecorder) envelope {
+	t.Helper()
+	var body envelope
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &body), w.Body.String())
+	assert.NotEmpty(t, body.Version)
+	return body
+}
+
+func newEnvelopeRouter() *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	cfg := testPOSConfig()
+	h := NewHandler(historyService{}, cfg, logging.NewLogger(cfg), nil, nil, nil)
+
+	r := gin.New()
+	r.Use(func(c *gin.Context) {
+		c.Set("claims", cashier())
+	})
+	r.GET("/pos/sales/history", h.getSalesHistory)
+	r.POST("/pos/items", createItem)
+	return r
+}
+
+func TestSalesHistoryEnvelope(t *testing.T) {
+	r := newEnvelopeRouter()
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/pos/sales/history", nil))
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+
+	body := decodeEnvelope(t, w)
+	assert.Equal(t, "success", body.Status)
+	assert.NotEmpty(t, body.Message)
+	var history SalesHistoryResponse
+	require.NoError(t, json.Unmarshal(body.Data, &history))
+	assert.Equal(t, "Africa/Lagos", history.Timezone)
+	assert.EqualValues(t, 1, history.Total)
+
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/pos/sales/history?store_id=x", nil))
+	require.Equal(t, http.StatusBadRequest, w.Code)
+	body = decodeEnvelope(t, w)
+	assert.Equal(t, "error", body.Status)
+	assert.Equal(t, "invalid store_id", body.Error)
+}
+
+func TestCreateItemEnvelope(t *testing.T) {
+	r := newEnvelopeRouter()
+	post := func(payload string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodPost, "/pos/items", strings.NewReader(payload))
+		req.Header.Set("Content-Type", "application/json")
+		r.ServeHTTP(w, req)
+		return w
+	}
+
+	w := post(`{"name":"Room service","price":45.99,"category":"Room Service"}`)
+	require.Equal(t, http.StatusCreated, w.Code, w.Body.String())
+	body := decodeEnvelope(t, w)
+	assert.Equal(t, "success", body.Status)
+	var item ItemResponse
+	require.NoError(t, json.Unmarshal(body.Data, &item))
+	assert.Equal(t, "Room service", item.Name)
+
+	w = post(`{"name":`)
+	require.Equal(t, http.StatusBadRequest, w.Code)
+	body = decodeEnvelope(t, w)
+	assert.Equal(t, "error", body.Status)
+	assert.NotEmpty(t, body.Error)
+}
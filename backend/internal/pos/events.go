@@ -0,0 +1,139 @@
+package pos
+
+import (
+	"context"
+	"database/sql"
+	db "herp/db/sqlc"
+	"herp/pkg/webhooks"
+	"time"
+)
+
+// EventPublisher is told about sales, refunds and stock running low after
+// they are committed, e.g. to deliver them as webhooks to the business of
+// the store. Publish must not block.
+type EventPublisher interface {
+	Publish(ctx context.Context, businessID int32, event string, data any)
+}
+
+type noEvents struct{}
+
+func (noEvents) Publish(context.Context, int32, string, any) {}
+
+// SetEventPublisher sets where events are published, by default they are
+// dropped.
+func (p *POS) SetEventPublisher(publisher EventPublisher) {
+	p.events = publisher
+}
+
+type SaleEventItem struct {
+	VariationID int32  `json:"variation_id"`
+	Quantity    int32  `json:"quantity"`
+	UnitPrice   string `json:"unit_price"`
+}
+
+// SaleEvent is the data of a sale.created event.
+type SaleEvent struct {
+	ID             int32           `json:"id"`
+	StoreID        int32           `json:"store_id"`
+	CustomerID     int32           `json:"customer_id"`
+	CashierID      int32           `json:"cashier_id"`
+	Subtotal       string          `json:"subtotal"`
+	DiscountAmount string          `json:"discount_amount"`
+	TaxAmount      string          `json:"tax_amount"`
+	TotalAmount    string          `json:"total_amount"`
+	Items          []SaleEventItem `json:"items"`
+	CreatedAt      time.Time       `json:"created_at"`
+}
+
+// RefundEvent is the data of a refund.created event.
+type RefundEvent struct {
+	ID          int32     `json:"id"`
+	SaleID      int32     `json:"sale_id"`
+	RefundedBy  int32     `json:"refunded_by"`
+	TotalAmount string    `json:"total_amount"`
+	SaleStatus  string    `json:"sale_status"`
+	CreatedAt   time.Time `json:"created_at"`
+}
+
+// LowStockEvent is the data of an inventory.low_stock event.
+type LowStockEvent struct {
+	StoreID       int32  `json:"store_id"`
+	StoreName     string `json:"store_name"`
+	VariationID   int32  `json:"variation_id"`
+	Sku           string `json:"sku"`
+	VariationName string `json:"variation_name"`
+	ItemName      string `json:"item_name"`
+	Quantity      int32  `json:"quantity"`
+	Threshold     int32  `json:"threshold"`
+}
+
+func (p *POS) publishSale(ctx context.Context, businessID int32, sale db.Sale, items []db.SaleItem) {
+	event := SaleEvent{
+		ID:             sale.ID,
+		StoreID:        sale.StoreID,
+		CustomerID:     sale.CustomerID,
+		CashierID:      sale.CashierID,
+		Subtotal:       sale.Subtotal,
+		DiscountAmount: sale.DiscountAmount,
+		TaxAmount:      sale.TaxAmount,
+		TotalAmount:    sale.TotalAmount,
+		Items:          make([]SaleEventItem, 0, len(items)),
+		CreatedAt:      sale.CreatedAt.Time,
+	}
+	for _, item := range items {
+		event.Items = append(event.Items, SaleEventItem{
+			VariationID: item.VariationID,
+			Quantity:    item.Quantity,
+			UnitPrice:   item.UnitPrice,
+		})
+	}
+	p.events.Publish(ctx, businessID, webhooks.SaleCreated, event)
+}
+
+func (p *POS) publishRefund(ctx context.Context, businessID int32, refund db.Refund, sale db.Sale) {
+	p.events.Publish(ctx, businessID, webhooks.RefundCreated, RefundEvent{
+		ID:          refund.ID,
+		SaleID:      refund.SaleID,
+		RefundedBy:  refund.RefundedBy,
+		TotalAmount: refund.TotalAmount,
+		SaleStatus:  sale.Status,
+		CreatedAt:   refund.CreatedAt.Time,
+	})
+}
+
+// lowStockSold returns the variations of a sale that are now at or below the
+// low stock threshold of the business in the store.
+func lowStockSold(ctx context.Context, q *db.Queries, storeID int32, items []db.SaleItem) ([]db.ListLowStockVariationsRow, error) {
+	rows, err := q.ListLowStockVariations(ctx, db.ListLowStockVariationsParams{
+		StoreID: sql.NullInt32{Int32: storeID, Valid: true},
+	})
+	if err != nil {
+		return nil, err
+	}
+	sold := make(map[int32]bool, len(items))
+	for _, item := range items {
+		sold[item.VariationID] = true
+	}
+	low := make([]db.ListLowStockVariationsRow, 0, len(rows))
+	for _, row := range rows {
+		if sold[row.VariationID] {
+			low = append(low, row)
+		}
+	}
+	return low, nil
+}
+
+func (p *POS) publishLowStock(ctx context.Context, businessID int32, rows []db.ListLowStockVariationsRow) {
+	for _, row := range rows {
+		p.events.Publish(ctx, businessID, webhooks.LowStock, LowStockEvent{
+			StoreID:       row.StoreID,
+			StoreName:     row.StoreName,
+			VariationID:   row.VariationID,
+			Sku:           row.Sku,
+			VariationName: row.VariationName,
+			ItemName:      row.ItemName,
+			Quantity:      row.Quantity,
+			Threshold:     row.Threshold,
+		})
+	}
+}
@@ -0,0 +1,50 @@
+package pos
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	db "herp/db/sqlc"
+)
+
+const paymentRoomCharge = "room_charge"
+
+var (
+	ErrFolioNotFound = errors.New("folio not found")
+	ErrFolioClosed   = errors.New("folio is closed")
+)
+
+// RoomChargePoster posts sales paid by room charge to a guest folio. q runs
+// in the transaction of the sale, a failed charge rolls the sale back.
+type RoomChargePoster interface {
+	PostCharge(ctx context.Context, q *db.Queries, folioID int32, amount string, saleID int32) error
+}
+
+// FolioPoster is the default RoomChargePoster, it records the charge on a
+// folio kept in the database. The folio has to be open and belong to the
+// business the sale was made in.
+type FolioPoster struct{}
+
+func (FolioPoster) PostCharge(ctx context.Context, q *db.Queries, folioID int32, amount string, saleID int32) error {
+	folio, err := q.GetFolioForSaleForUpdate(ctx, db.GetFolioForSaleForUpdateParams{
+		FolioID: folioID,
+		SaleID:  saleID,
+	})
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return fmt.Errorf("%w: %d", ErrFolioNotFound, folioID)
+		}
+		return err
+	}
+	if folio.Status != "open" {
+		return fmt.Errorf("%w: %d", ErrFolioClosed, folioID)
+	}
+
+	_, err = q.CreateFolioCharge(ctx, db.CreateFolioChargeParams{
+		FolioID: folio.ID,
+		SaleID:  saleID,
+		Amount:  amount,
+	})
+	return err
+}
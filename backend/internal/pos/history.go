@@ -0,0 +1,100 @@
+package pos
+
+import (
+	"context"
+	"database/sql"
+	db "herp/db/sqlc"
+	"time"
+)
+
+type SalesHistoryParams struct {
+	// StartDate and EndDate bound the days listed as YYYY-MM-DD, both
+	// inclusive, either can be empty to leave that side open
+	StartDate  string
+	EndDate    string
+	StoreID    sql.NullInt32
+	BusinessID sql.NullInt32
+	Limit      int32
+	Offset     int32
+}
+
+type HistorySale struct {
+	Sale  db.Sale
+	Items []db.SaleItem
+}
+
+// SalesHistory is a page of sales, newest first, with the number of sales
+// matching the filters over every page.
+type SalesHistory struct {
+	Timezone string
+	Sales    []HistorySale
+	Total    int64
+}
+
+// SalesHistory lists the sales of a store or of every store in
+// args.BusinessID. Days are taken in the timezone of the business the same
+// way the reports take them, so a sale made just after midnight local time
+// is listed on that day even when it is still the day before in UTC.
+func (p *POS) SalesHistory(ctx context.Context, args SalesHistoryParams) (SalesHistory, error) {
+	timezone, loc, err := p.reportLocation(ctx, args.StoreID, args.BusinessID)
+	if err != nil {
+		return SalesHistory{}, err
+	}
+
+	// sale timestamps are stored in UTC without a zone
+	var start, end sql.NullTime
+	var first time.Time
+	if args.StartDate != "" {
+		first, err = reportDay(args.StartDate, loc)
+		if err != nil {
+			return SalesHistory{}, err
+		}
+		start = sql.NullTime{Time: first.UTC(), Valid: true}
+	}
+	if args.EndDate != "" {
+		last, err := reportDay(args.EndDate, loc)
+		if err != nil {
+			return SalesHistory{}, err
+		}
+		if start.Valid && first.After(last) {
+			return SalesHistory{}, ErrInvalidReportRange
+		}
+		end = sql.NullTime{Time: last.AddDate(0, 0, 1).UTC(), Valid: true}
+	}
+
+	sales, err := p.queries.ListSales(ctx, db.ListSalesParams{
+		StartTime:  start,
+		EndTime:    end,
+		StoreID:    args.StoreID,
+		BusinessID: args.BusinessID,
+		PageLimit:  args.Limit,
+		PageOffset: args.Offset,
+	})
+	if err != nil {
+		return SalesHistory{}, err
+	}
+
+	total, err := p.queries.CountSales(ctx, db.CountSalesParams{
+		StartTime:  start,
+		EndTime:    end,
+		StoreID:    args.StoreID,
+		BusinessID: args.BusinessID,
+	})
+	if err != nil {
+		return SalesHistory{}, err
+	}
+
+	history := SalesHistory{
+		Timezone: timezone,
+		Sales:    make([]HistorySale, 0, len(sales)),
+		Total:    total,
+	}
+	for _, sale := range sales {
+		items, err := p.queries.ListSaleItems(ctx, sale.ID)
+		if err != nil {
+			return SalesHistory{}, err
+		}
+		history.Sales = append(history.Sales, HistorySale{Sale: sale, Items: items})
+	}
+	return history, nil
+}
@@ -0,0 +1,112 @@
+package pos
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"herp/internal/utils"
+	"herp/pkg/jwt"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+const maxIdempotencyKeyLength = 255
+
+// idempotentSale is what is kept in redis under an Idempotency-Key, the hash
+// of the request and, once the sale is made, its response. A record without
+// a response belongs to a request still in progress.
+type idempotentSale struct {
+	Hash     string        `json:"hash"`
+	Response *SaleResponse `json:"response,omitempty"`
+}
+
+// idempotencyClaim is an Idempotency-Key held by the current request.
+type idempotencyClaim struct {
+	redisKey string
+	hash     string
+}
+
+// claimIdempotencyKey reserves the Idempotency-Key of a sale request for
+// the current request. Keys are per account, so two terminals can't collide.
+// When the key was already used with the same request the response of the
+// sale it made is returned instead. A key used for another request, or by a
+// request still in progress, is answered with 409. No header gives a nil
+// claim.
+func (h *Handler) claimIdempotencyKey(c *gin.Context, claims *jwt.Claims, req CreateSaleRequest) (*idempotencyClaim, *SaleResponse, bool) {
+	key := c.GetHeader("Idempotency-Key")
+	if key == "" {
+		return nil, nil, true
+	}
+	if len(key) > maxIdempotencyKeyLength {
+		utils.ErrorResponse(c, 400, fmt.Sprintf("Idempotency-Key can be at most %d characters", maxIdempotencyKeyLength))
+		return nil, nil, false
+	}
+
+	body, _ := json.Marshal(req)
+	sum := sha256.Sum256(body)
+	claim := &idempotencyClaim{
+		redisKey: fmt.Sprintf("sale_idempotency:%d:%s:%s", claims.UserID, claims.Username, key),
+		hash:     hex.EncodeToString(sum[:]),
+	}
+
+	ttl := time.Duration(h.config.SaleIdempotencyTTL) * time.Minute
+	pending, _ := json.Marshal(idempotentSale{Hash: claim.hash})
+	claimed, err := h.redis.SetNX(c, claim.redisKey, pending, ttl)
+	if err != nil {
+		h.logger.Errorf("error claiming idempotency key: %v", err)
+		utils.ErrorResponse(c, 500, utils.SERVERERROR)
+		return nil, nil, false
+	}
+	if claimed {
+		return claim, nil, true
+	}
+
+	stored, err := h.redis.Get(c, claim.redisKey)
+	if err != nil {
+		// expired between the two calls, rare enough to let the client retry
+		h.logger.Warnf("error reading idempotency key: %v", err)
+		utils.ErrorResponse(c, 409, "request with this Idempotency-Key is still in progress")
+		return nil, nil, false
+	}
+	var previous idempotentSale
+	if err := json.Unmarshal([]byte(stored), &previous); err != nil {
+		h.logger.Errorf("error decoding idempotency key: %v", err)
+		utils.ErrorResponse(c, 500, utils.SERVERERROR)
+		return nil, nil, false
+	}
+	if previous.Hash != claim.hash {
+		utils.ErrorResponse(c, 409, "Idempotency-Key was already used for a different request")
+		return nil, nil, false
+	}
+	if previous.Response == nil {
+		utils.ErrorResponse(c, 409, "request with this Idempotency-Key is still in progress")
+		return nil, nil, false
+	}
+	return nil, previous.Response, true
+}
+
+// storeIdempotentSale keeps the response of the sale made under a claim, for
+// repeats of the request to be answered with.
+func (h *Handler) storeIdempotentSale(c *gin.Context, claim *idempotencyClaim, response SaleResponse) {
+	if claim == nil {
+		return
+	}
+	record, _ := json.Marshal(idempotentSale{Hash: claim.hash, Response: &response})
+	ttl := time.Duration(h.config.SaleIdempotencyTTL) * time.Minute
+	if err := h.redis.Set(c, claim.redisKey, record, ttl); err != nil {
+		h.logger.Warnf("failed to store response of idempotency key: %v", err)
+	}
+}
+
+// releaseIdempotencyKey gives up a claim when no sale was made, so the
+// request can be retried with the same key.
+func (h *Handler) releaseIdempotencyKey(c *gin.Context, claim *idempotencyClaim) {
+	if claim == nil {
+		return
+	}
+	if err := h.redis.Delete(c, claim.redisKey); err != nil {
+		h.logger.Warnf("failed to release idempotency key: %v", err)
+	}
+}
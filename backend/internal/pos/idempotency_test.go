@@ -0,0 +1,178 @@
+package pos
+
+import (
+	"context"
+	"encoding/json"
+	db "herp/db/sqlc"
+	"herp/internal/core/inventory"
+	"herp/pkg/monitoring/logging"
+	"herp/pkg/redis"
+	"herp/pkg/redis/redistest"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// idempotentSales makes numbered sales. While hold is set a sale waits for
+// it to be closed, after telling started it is under way.
+type idempotentSales struct {
+	POSInterface
+	mu      sync.Mutex
+	made    int32
+	err     error
+	hold    chan struct{}
+	started chan struct{}
+}
+
+func (s *idempotentSales) CreateSale(ctx context.Context, args CreateSaleParams) (SaleResult, error) {
+	if s.hold != nil {
+		s.started <- struct{}{}
+		<-s.hold
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.err != nil {
+		return SaleResult{}, s.err
+	}
+	s.made++
+	return SaleResult{Sale: db.Sale{ID: s.made, CustomerID: args.CustomerID, TotalAmount: "10.00", TaxAmount: "0.00", DiscountAmount: "0.00"}}, nil
+}
+
+func (s *idempotentSales) GetStoreInBusiness(ctx context.Context, params db.GetStoreInBusinessParams) (int32, error) {
+	return params.ID, nil
+}
+
+func (s *idempotentSales) count() int32 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.made
+}
+
+func newIdempotentRouter(t *testing.T, service POSInterface) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	cfg := testPOSConfig()
+	cfg.SaleIdempotencyTTL = 10
+	h := NewHandler(service, cfg, logging.NewLogger(cfg), nil, nil, redis.NewRedisFromClient(redistest.Client(t)))
+
+	r := gin.New()
+	r.Use(func(c *gin.Context) {
+		c.Set("claims", cashier())
+	})
+	r.POST("/pos/sales", h.createSale)
+	return r
+}
+
+func postIdempotentSale(r *gin.Engine, key, body string) *httptest.ResponseRecorder {
+	w := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodPost, "/pos/sales", strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	if key != "" {
+		req.Header.Set("Idempotency-Key", key)
+	}
+	r.ServeHTTP(w, req)
+	return w
+}
+
+func saleID(t *testing.T, w *httptest.ResponseRecorder) int {
+	t.Helper()
+	var body struct {
+		Data SaleResponse `json:"data"`
+	}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &body))
+	return body.Data.ID
+}
+
+func TestIdempotentSaleReplay(t *testing.T) {
+	service := &idempotentSales{}
+	r := newIdempotentRouter(t, service)
+	body := saleBody(1, 4)
+
+	first := postIdempotentSale(r, "tap-1", body)
+	require.Equal(t, http.StatusCreated, first.Code, first.Body.String())
+	second := postIdempotentSale(r, "tap-1", body)
+	require.Equal(t, http.StatusCreated, second.Code, second.Body.String())
+
+	assert.Equal(t, int32(1), service.count(), "one sale for both requests")
+	assert.Equal(t, saleID(t, first), saleID(t, second))
+	assert.Empty(t, first.Header().Get("Idempotent-Replayed"))
+	assert.Equal(t, "true", second.Header().Get("Idempotent-Replayed"))
+
+	// another key is another sale
+	third := postIdempotentSale(r, "tap-2", body)
+	require.Equal(t, http.StatusCreated, third.Code)
+	assert.Equal(t, 2, saleID(t, third))
+}
+
+func TestIdempotentSaleDoubleSubmit(t *testing.T) {
+	service := &idempotentSales{hold: make(chan struct{}), started: make(chan struct{}, 1)}
+	r := newIdempotentRouter(t, service)
+	body := saleBody(1, 4)
+
+	// the second tap lands while the first sale is still being made
+	var first *httptest.ResponseRecorder
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		first = postIdempotentSale(r, "tap-1", body)
+	}()
+	<-service.started
+
+	second := postIdempotentSale(r, "tap-1", body)
+	assert.Equal(t, http.StatusConflict, second.Code)
+	assert.Contains(t, second.Body.String(), "still in progress")
+
+	close(service.hold)
+	<-done
+	require.Equal(t, http.StatusCreated, first.Code, first.Body.String())
+
+	service.hold = nil
+	third := postIdempotentSale(r, "tap-1", body)
+	require.Equal(t, http.StatusCreated, third.Code)
+	assert.Equal(t, saleID(t, first), saleID(t, third))
+	assert.Equal(t, int32(1), service.count())
+}
+
+func TestIdempotentSaleDifferentBody(t *testing.T) {
+	service := &idempotentSales{}
+	r := newIdempotentRouter(t, service)
+
+	require.Equal(t, http.StatusCreated, postIdempotentSale(r, "tap-1", saleBody(1, 4)).Code)
+	w := postIdempotentSale(r, "tap-1", saleBody(1, 5))
+	assert.Equal(t, http.StatusConflict, w.Code)
+	assert.Contains(t, w.Body.String(), "different request")
+	assert.Equal(t, int32(1), service.count())
+}
+
+func TestIdempotentSaleFailureReleasesKey(t *testing.T) {
+	service := &idempotentSales{err: inventory.ErrInsufficientStock}
+	r := newIdempotentRouter(t, service)
+	body := saleBody(1, 4)
+
+	require.Equal(t, http.StatusConflict, postIdempotentSale(r, "tap-1", body).Code)
+
+	// restocked, the same key goes through
+	service.err = nil
+	w := postIdempotentSale(r, "tap-1", body)
+	require.Equal(t, http.StatusCreated, w.Code, w.Body.String())
+	assert.Empty(t, w.Header().Get("Idempotent-Replayed"))
+	assert.Equal(t, int32(1), service.count())
+}
+
+func TestIdempotentSaleWithoutKey(t *testing.T) {
+	service := &idempotentSales{}
+	r := newIdempotentRouter(t, service)
+
+	for range 2 {
+		require.Equal(t, http.StatusCreated, postIdempotentSale(r, "", saleBody(1, 4)).Code)
+	}
+	assert.Equal(t, int32(2), service.count())
+
+	w := postIdempotentSale(r, strings.Repeat("k", maxIdempotencyKeyLength+1), saleBody(1, 4))
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+}
@@ -0,0 +1,68 @@
+package pos
+
+import (
+	"context"
+	"database/sql"
+	db "herp/db/sqlc"
+)
+
+type Querier interface {
+	GetSaleForUpdate(ctx context.Context, arg db.GetSaleForUpdateParams) (db.Sale, error)
+	ListSaleItemsForUpdate(ctx context.Context, saleID int32) ([]db.SaleItem, error)
+	LogActivity(ctx context.Context, params db.LogActivityParams) (db.ActivityLog, error)
+	GetCustomerLoyaltyBalanceInBusiness(ctx context.Context, arg db.GetCustomerLoyaltyBalanceInBusinessParams) (int32, error)
+	UpsertLoyaltyRule(ctx context.Context, params db.UpsertLoyaltyRuleParams) (db.LoyaltyRule, error)
+	GetOwnedBusinessID(ctx context.Context, params db.GetOwnedBusinessIDParams) (int32, error)
+	GetStoreInBusiness(ctx context.Context, params db.GetStoreInBusinessParams) (int32, error)
+	GetSaleInBusiness(ctx context.Context, params db.GetSaleInBusinessParams) (db.Sale, error)
+	ListSaleItems(ctx context.Context, saleID int32) ([]db.SaleItem, error)
+	ListSaleRefundItems(ctx context.Context, saleID int32) ([]db.ListSaleRefundItemsRow, error)
+	ListSaleLoyaltyPoints(ctx context.Context, saleID int32) ([]db.LoyaltyPoint, error)
+	ListSaleActivity(ctx context.Context, saleID int32) ([]db.ActivityLog, error)
+	GetSaleReceiptHeader(ctx context.Context, id int32) (db.GetSaleReceiptHeaderRow, error)
+	ListSaleReceiptItems(ctx context.Context, saleID int32) ([]db.ListSaleReceiptItemsRow, error)
+	ListSalePayments(ctx context.Context, saleID int32) ([]db.SalePayment, error)
+	GetStoreReportTimezone(ctx context.Context, arg db.GetStoreReportTimezoneParams) (string, error)
+	GetBusinessTimezone(ctx context.Context, id int32) (string, error)
+	GetSalesSummary(ctx context.Context, arg db.GetSalesSummaryParams) (db.GetSalesSummaryRow, error)
+	ListSalesByPaymentMethod(ctx context.Context, arg db.ListSalesByPaymentMethodParams) ([]db.ListSalesByPaymentMethodRow, error)
+	ListSalesByCategory(ctx context.Context, arg db.ListSalesByCategoryParams) ([]db.ListSalesByCategoryRow, error)
+	ListMarginByItem(ctx context.Context, arg db.ListMarginByItemParams) ([]db.ListMarginByItemRow, error)
+	ListSales(ctx context.Context, arg db.ListSalesParams) ([]db.Sale, error)
+	CountSales(ctx context.Context, arg db.CountSalesParams) (int64, error)
+	GetItem(ctx context.Context, arg db.GetItemParams) (db.Item, error)
+	GetCategory(ctx context.Context, arg db.GetCategoryParams) (db.Category, error)
+	CreateDiscount(ctx context.Context, arg db.CreateDiscountParams) (db.Discount, error)
+	GetDiscount(ctx context.Context, arg db.GetDiscountParams) (db.Discount, error)
+	ListDiscounts(ctx context.Context, businessID sql.NullInt32) ([]db.Discount, error)
+	UpdateDiscount(ctx context.Context, arg db.UpdateDiscountParams) (db.Discount, error)
+	DeleteDiscount(ctx context.Context, arg db.DeleteDiscountParams) (int64, error)
+	CreateCoupon(ctx context.Context, arg db.CreateCouponParams) (db.Coupon, error)
+	ListCoupons(ctx context.Context, businessID sql.NullInt32) ([]db.Coupon, error)
+	DeleteCoupon(ctx context.Context, arg db.DeleteCouponParams) (int64, error)
+}
+
+type POSInterface interface {
+	CreateSale(ctx context.Context, args CreateSaleParams) (SaleResult, error)
+	GetSale(ctx context.Context, saleID int32, businessID sql.NullInt32) (db.Sale, error)
+	RefundSale(ctx context.Context, args RefundSaleParams) (RefundResult, error)
+	GetSaleTimeline(ctx context.Context, saleID int32, businessID sql.NullInt32) (db.Sale, []TimelineEvent, error)
+	GetReceipt(ctx context.Context, saleID int32, businessID sql.NullInt32) (Receipt, error)
+	SaleCurrency(ctx context.Context, saleID int32) (string, error)
+	DailyReport(ctx context.Context, args DailyReportParams) (DailyReport, error)
+	MarginReport(ctx context.Context, args MarginReportParams) (MarginReport, error)
+	SalesHistory(ctx context.Context, args SalesHistoryParams) (SalesHistory, error)
+	LogActivity(ctx context.Context, params db.LogActivityParams) (db.ActivityLog, error)
+	GetLoyaltyBalance(ctx context.Context, customerID int32, businessID sql.NullInt32) (int32, error)
+	SetLoyaltyRule(ctx context.Context, params db.UpsertLoyaltyRuleParams) (db.LoyaltyRule, error)
+	GetOwnedBusinessID(ctx context.Context, params db.GetOwnedBusinessIDParams) (int32, error)
+	GetStoreInBusiness(ctx context.Context, params db.GetStoreInBusinessParams) (int32, error)
+	CreateDiscount(ctx context.Context, params db.CreateDiscountParams) (db.Discount, error)
+	GetDiscount(ctx context.Context, params db.GetDiscountParams) (db.Discount, error)
+	ListDiscounts(ctx context.Context, businessID sql.NullInt32) ([]db.Discount, error)
+	UpdateDiscount(ctx context.Context, businessID sql.NullInt32, params db.UpdateDiscountParams) (db.Discount, error)
+	DeleteDiscount(ctx context.Context, params db.DeleteDiscountParams) error
+	CreateCoupon(ctx context.Context, params db.CreateCouponParams) (db.Coupon, error)
+	ListCoupons(ctx context.Context, businessID sql.NullInt32) ([]db.Coupon, error)
+	DeleteCoupon(ctx context.Context, params db.DeleteCouponParams) error
+}
@@ -0,0 +1,118 @@
+package pos
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"herp/db/dbtest"
+	db "herp/db/sqlc"
+	"herp/internal/config"
+	"herp/pkg/jwt"
+	"herp/pkg/monitoring/logging"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLoyaltyEarnAndReverse(t *testing.T) {
+	f := newSaleFixture(t)
+	ctx := context.Background()
+	dbtest.Stock(t, f.conn, f.storeID, f.variation, 10)
+	_, err := f.pos.SetLoyaltyRule(ctx, db.UpsertLoyaltyRuleParams{BusinessID: f.businessID, EarnRate: "1.0000", Rounding: "down"})
+	require.NoError(t, err)
+	business := sql.NullInt32{Int32: f.businessID, Valid: true}
+
+	sale, err := f.sell(ctx, 4)
+	require.NoError(t, err)
+	balance, err := f.pos.GetLoyaltyBalance(ctx, 1, business)
+	require.NoError(t, err)
+	assert.Equal(t, int32(40), balance, "a point per 1.00 of 40.00")
+
+	// a quarter of the sale refunded, a quarter of its points taken back
+	_, err = f.pos.RefundSale(ctx, RefundSaleParams{
+		SaleID: sale.Sale.ID, RefundedBy: 1, BusinessID: business,
+		Lines: []RefundLine{{VariationID: f.variation, Quantity: 1}},
+	})
+	require.NoError(t, err)
+	balance, err = f.pos.GetLoyaltyBalance(ctx, 1, business)
+	require.NoError(t, err)
+	assert.Equal(t, int32(30), balance)
+
+	// the rest refunded, nothing left
+	_, err = f.pos.RefundSale(ctx, RefundSaleParams{SaleID: sale.Sale.ID, RefundedBy: 1, BusinessID: business})
+	require.NoError(t, err)
+	balance, err = f.pos.GetLoyaltyBalance(ctx, 1, business)
+	require.NoError(t, err)
+	assert.Zero(t, balance)
+}
+
+func TestLoyaltyBalanceScope(t *testing.T) {
+	f := newSaleFixture(t)
+	ctx := context.Background()
+	dbtest.Stock(t, f.conn, f.storeID, f.variation, 10)
+	_, err := f.sell(ctx, 1)
+	require.NoError(t, err)
+	other, _ := dbtest.Business(t, f.conn, dbtest.Admin(t, f.conn, "other"), "Zobo Palace")
+
+	// bought here without a rule, no points yet
+	balance, err := f.pos.GetLoyaltyBalance(ctx, 1, sql.NullInt32{Int32: f.businessID, Valid: true})
+	require.NoError(t, err)
+	assert.Zero(t, balance)
+
+	_, err = f.pos.GetLoyaltyBalance(ctx, 1, sql.NullInt32{Int32: other, Valid: true})
+	assert.ErrorIs(t, err, ErrCustomerNotFound)
+	_, err = f.pos.GetLoyaltyBalance(ctx, 2, sql.NullInt32{Int32: f.businessID, Valid: true})
+	assert.ErrorIs(t, err, ErrCustomerNotFound)
+}
+
+// loyaltyService knows customer 3 in business 10, owned by owner 1.
+type loyaltyService struct {
+	POSInterface
+}
+
+func (s *loyaltyService) GetOwnedBusinessID(ctx context.Context, params db.GetOwnedBusinessIDParams) (int32, error) {
+	if params.OwnerID != 1 || (params.BusinessID.Valid && params.BusinessID.Int32 != 10) {
+		return 0, sql.ErrNoRows
+	}
+	return 10, nil
+}
+
+func (s *loyaltyService) GetLoyaltyBalance(ctx context.Context, customerID int32, businessID sql.NullInt32) (int32, error) {
+	if customerID != 3 || businessID != (sql.NullInt32{Int32: 10, Valid: true}) {
+		return 0, ErrCustomerNotFound
+	}
+	return 120, nil
+}
+
+func getLoyaltyBalance(path string) *httptest.ResponseRecorder {
+	gin.SetMode(gin.TestMode)
+	cfg := &config.Config{BusinessScope: true}
+	h := NewHandler(&loyaltyService{}, cfg, logging.NewLogger(cfg), nil, nil, nil)
+	r := gin.New()
+	r.Use(func(c *gin.Context) {
+		c.Set("claims", &jwt.Claims{UserID: 1, Username: "owner"})
+	})
+	r.GET("/pos/customers/:id/loyalty", h.getLoyaltyBalance)
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, path, nil))
+	return w
+}
+
+func TestGetLoyaltyBalanceHandler(t *testing.T) {
+	w := getLoyaltyBalance("/pos/customers/3/loyalty")
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	var body struct {
+		Data LoyaltyBalanceResponse `json:"data"`
+	}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &body))
+	assert.Equal(t, LoyaltyBalanceResponse{CustomerID: 3, Points: 120}, body.Data)
+
+	// a customer of another business
+	assert.Equal(t, http.StatusNotFound, getLoyaltyBalance("/pos/customers/4/loyalty").Code)
+	assert.Equal(t, http.StatusBadRequest, getLoyaltyBalance("/pos/customers/x/loyalty").Code)
+}
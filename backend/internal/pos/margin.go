@@ -0,0 +1,155 @@
+package pos
+
+import (
+	"context"
+	"database/sql"
+	db "herp/db/sqlc"
+	"strconv"
+)
+
+type MarginReportParams struct {
+	// StartDate and EndDate are the first and last day to report as
+	// YYYY-MM-DD, each defaults to today
+	StartDate  string
+	EndDate    string
+	StoreID    sql.NullInt32
+	BusinessID sql.NullInt32
+}
+
+// MarginTotals are the units sold net of refunds, the revenue they brought
+// in before sale discounts and tax, and what they cost. Units sold while
+// their variation had no cost are counted in UncostedQuantity and add
+// nothing to Cost.
+type MarginTotals struct {
+	Quantity         int32
+	UncostedQuantity int32
+	Revenue          string
+	Cost             string
+	Margin           string
+	MarginPercent    string
+}
+
+type ItemMargin struct {
+	ItemID int32
+	Name   string
+	MarginTotals
+}
+
+type CategoryMargin struct {
+	CategoryID int32 // 0 for items without a category
+	Name       string
+	MarginTotals
+	Items []ItemMargin
+}
+
+type MarginReport struct {
+	StartDate string
+	EndDate   string
+	Timezone  string
+	MarginTotals
+	ByCategory []CategoryMargin
+}
+
+// marginSum adds up the lines of a margin report.
+type marginSum struct {
+	quantity, uncosted int32
+	revenue, cost      float64
+}
+
+func (m *marginSum) add(row db.ListMarginByItemRow) {
+	revenue, _ := strconv.ParseFloat(row.Revenue, 64)
+	cost, _ := strconv.ParseFloat(row.Cost, 64)
+	m.quantity += row.Quantity
+	m.uncosted += row.UncostedQuantity
+	m.revenue += revenue
+	m.cost += cost
+}
+
+// totals works out the gross margin, revenue minus cost, and its share of
+// the revenue in percent.
+func (m marginSum) totals() MarginTotals {
+	margin := m.revenue - m.cost
+	percent := 0.0
+	if m.revenue != 0 {
+		percent = margin / m.revenue * 100
+	}
+	return MarginTotals{
+		Quantity:         m.quantity,
+		UncostedQuantity: m.uncosted,
+		Revenue:          formatAmount(m.revenue),
+		Cost:             formatAmount(m.cost),
+		Margin:           formatAmount(margin),
+		MarginPercent:    formatAmount(percent),
+	}
+}
+
+// MarginReport works out the gross margin of the sales made from the start
+// of args.StartDate to the end of args.EndDate, in the timezone of the
+// business, per category and item. Cost of goods sold uses the cost each
+// line was sold at, so later cost changes don't alter past margins.
+func (p *POS) MarginReport(ctx context.Context, args MarginReportParams) (MarginReport, error) {
+	timezone, loc, err := p.reportLocation(ctx, args.StoreID, args.BusinessID)
+	if err != nil {
+		return MarginReport{}, err
+	}
+
+	start, err := reportDay(args.StartDate, loc)
+	if err != nil {
+		return MarginReport{}, err
+	}
+	last, err := reportDay(args.EndDate, loc)
+	if err != nil {
+		return MarginReport{}, err
+	}
+	if start.After(last) {
+		return MarginReport{}, ErrInvalidReportRange
+	}
+	end := last.AddDate(0, 0, 1)
+
+	// sale timestamps are stored in UTC without a zone
+	rows, err := p.queries.ListMarginByItem(ctx, db.ListMarginByItemParams{
+		StartTime:  start.UTC(),
+		EndTime:    end.UTC(),
+		StoreID:    args.StoreID,
+		BusinessID: args.BusinessID,
+	})
+	if err != nil {
+		return MarginReport{}, err
+	}
+
+	report := MarginReport{
+		StartDate:  start.Format("2006-01-02"),
+		EndDate:    last.Format("2006-01-02"),
+		Timezone:   timezone,
+		ByCategory: []CategoryMargin{},
+	}
+
+	// rows come grouped by category
+	var total, category marginSum
+	for i, row := range rows {
+		if i == 0 || row.CategoryID != rows[i-1].CategoryID {
+			report.ByCategory = append(report.ByCategory, CategoryMargin{
+				CategoryID: row.CategoryID,
+				Name:       row.CategoryName,
+				Items:      []ItemMargin{},
+			})
+			category = marginSum{}
+		}
+
+		var item marginSum
+		item.add(row)
+		category.add(row)
+		total.add(row)
+
+		current := &report.ByCategory[len(report.ByCategory)-1]
+		current.Items = append(current.Items, ItemMargin{
+			ItemID:       row.ItemID,
+			Name:         row.ItemName,
+			MarginTotals: item.totals(),
+		})
+		current.MarginTotals = category.totals()
+	}
+	report.MarginTotals = total.totals()
+
+	return report, nil
+}
@@ -0,0 +1,78 @@
+package pos
+
+import (
+	"context"
+	"database/sql"
+	"herp/db/dbtest"
+	db "herp/db/sqlc"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMarginSumTotals(t *testing.T) {
+	var sum marginSum
+	sum.add(db.ListMarginByItemRow{Quantity: 3, Revenue: "30.00", Cost: "12.00"})
+	sum.add(db.ListMarginByItemRow{Quantity: 2, UncostedQuantity: 2, Revenue: "19.99", Cost: "0"})
+	assert.Equal(t, MarginTotals{
+		Quantity:         5,
+		UncostedQuantity: 2,
+		Revenue:          "49.99",
+		Cost:             "12.00",
+		Margin:           "37.99",
+		MarginPercent:    "76.00",
+	}, sum.totals())
+
+	// sold at a loss
+	loss := marginSum{quantity: 1, revenue: 8, cost: 10}
+	assert.Equal(t, "-2.00", loss.totals().Margin)
+	assert.Equal(t, "-25.00", loss.totals().MarginPercent)
+
+	// nothing sold has no margin to speak of
+	assert.Equal(t, MarginTotals{Revenue: "0.00", Cost: "0.00", Margin: "0.00", MarginPercent: "0.00"}, marginSum{}.totals())
+}
+
+func TestMarginReportCostChanges(t *testing.T) {
+	f := newSaleFixture(t)
+	ctx := context.Background()
+	dbtest.Stock(t, f.conn, f.storeID, f.variation, 20)
+	setCost := func(cost string) {
+		dbtest.Exec(t, f.conn, `UPDATE variation SET cost_price = $1 WHERE id = $2`, sql.NullString{String: cost, Valid: cost != ""}, f.variation)
+	}
+
+	// sold before its cost was known
+	_, err := f.sell(ctx, 1)
+	require.NoError(t, err)
+	setCost("4.00")
+	_, err = f.sell(ctx, 3)
+	require.NoError(t, err)
+	// a dearer delivery after the first sales
+	setCost("6.50")
+	_, err = f.sell(ctx, 2)
+	require.NoError(t, err)
+
+	report, err := f.pos.MarginReport(ctx, MarginReportParams{BusinessID: sql.NullInt32{Int32: f.businessID, Valid: true}})
+	require.NoError(t, err)
+
+	// 6 sold at 10.00, 3 cost 4.00 and 2 cost 6.50
+	want := MarginTotals{
+		Quantity:         6,
+		UncostedQuantity: 1,
+		Revenue:          "60.00",
+		Cost:             "25.00",
+		Margin:           "35.00",
+		MarginPercent:    "58.33",
+	}
+	assert.Equal(t, want, report.MarginTotals)
+	require.Len(t, report.ByCategory, 1)
+	assert.Equal(t, want, report.ByCategory[0].MarginTotals)
+	require.Len(t, report.ByCategory[0].Items, 1)
+	assert.Equal(t, want, report.ByCategory[0].Items[0].MarginTotals)
+
+	// later cost changes leave past sales alone
+	setCost("9.00")
+	again, err := f.pos.MarginReport(ctx, MarginReportParams{BusinessID: sql.NullInt32{Int32: f.businessID, Valid: true}})
+	require.NoError(t, err)
+	assert.Equal(t, want, again.MarginTotals)
+}
@@ -0,0 +1,68 @@
+package pos
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	db "herp/db/sqlc"
+	"slices"
+)
+
+var (
+	ErrPaymentMethodNotAllowed = errors.New("payment method is not accepted by this business")
+	ErrPaymentMismatch         = errors.New("payments do not add up to the sale total")
+	ErrFolioRequired           = errors.New("room charge payments need a folio")
+)
+
+type SalePayment struct {
+	Method    string
+	Amount    float64
+	Reference string
+	// FolioID is the guest folio a room charge is posted to
+	FolioID int32
+}
+
+// checkSalePayments checks the payments of a sale against its total in
+// cents. Every method has to be one the business accepts and the payments
+// together have to match the total to the cent.
+func checkSalePayments(settings db.GetStorePaymentSettingsRow, totalCents int64, payments []SalePayment) error {
+	var paid int64
+	for _, payment := range payments {
+		if !slices.Contains(settings.PaymentType, db.PaymentType(payment.Method)) {
+			return fmt.Errorf("%w: %s", ErrPaymentMethodNotAllowed, payment.Method)
+		}
+		if payment.Method == paymentRoomCharge && payment.FolioID == 0 {
+			return ErrFolioRequired
+		}
+		paid += toCents(payment.Amount)
+	}
+	if paid != totalCents {
+		return fmt.Errorf("%w: paid %s of %s", ErrPaymentMismatch, formatCents(paid), formatCents(totalCents))
+	}
+	return nil
+}
+
+// createSalePayments records the payments of a sale and posts room charges to
+// their folios through roomCharges.
+func createSalePayments(ctx context.Context, q *db.Queries, roomCharges RoomChargePoster, saleID int32, payments []SalePayment) ([]db.SalePayment, error) {
+	created := make([]db.SalePayment, 0, len(payments))
+	for _, payment := range payments {
+		p, err := q.CreateSalePayment(ctx, db.CreateSalePaymentParams{
+			SaleID:    saleID,
+			Method:    db.PaymentType(payment.Method),
+			Amount:    formatAmount(payment.Amount),
+			Reference: sql.NullString{String: payment.Reference, Valid: payment.Reference != ""},
+		})
+		if err != nil {
+			return nil, err
+		}
+		if payment.Method == paymentRoomCharge {
+			if err := roomCharges.PostCharge(ctx, q, payment.FolioID, p.Amount, saleID); err != nil {
+				return nil, err
+			}
+		}
+		created = append(created, p)
+	}
+	return created, nil
+}
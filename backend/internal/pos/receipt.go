@@ -0,0 +1,212 @@
+package pos
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	db "herp/db/sqlc"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// receiptWidth is how many characters fit on a line of an 80mm thermal
+// printer with its default font.
+const receiptWidth = 48
+
+type ReceiptLine struct {
+	VariationID int32
+	Name        string
+	Quantity    int32
+	UnitPrice   string
+	Amount      string
+}
+
+type ReceiptPayment struct {
+	Method    string
+	Amount    string
+	Reference string
+}
+
+// Receipt is a sale with the branding of the business it was made in.
+type Receipt struct {
+	SaleID       int32
+	Status       string
+	BusinessName string
+	LogoURL      string
+	Motto        string
+	Currency     string
+	StoreName    string
+	Address      string
+	Phone        string
+	Cashier      string
+	Lines        []ReceiptLine
+	Subtotal     string
+	Discount     string
+	Tax          string
+	Total        string
+	Payments     []ReceiptPayment
+	CreatedAt    time.Time
+}
+
+// SaleCurrency returns the currency of the business a sale was made in,
+// empty when the business has none set.
+func (p *POS) SaleCurrency(ctx context.Context, saleID int32) (string, error) {
+	header, err := p.queries.GetSaleReceiptHeader(ctx, saleID)
+	if err != nil {
+		return "", err
+	}
+	return header.Currency.String, nil
+}
+
+// GetReceipt builds the receipt of a sale. A sale outside businessID is
+// reported as not found.
+func (p *POS) GetReceipt(ctx context.Context, saleID int32, businessID sql.NullInt32) (Receipt, error) {
+	sale, err := p.queries.GetSaleInBusiness(ctx, db.GetSaleInBusinessParams{
+		ID:         saleID,
+		BusinessID: businessID,
+	})
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return Receipt{}, ErrSaleNotFound
+		}
+		return Receipt{}, err
+	}
+
+	header, err := p.queries.GetSaleReceiptHeader(ctx, sale.ID)
+	if err != nil {
+		return Receipt{}, err
+	}
+
+	items, err := p.queries.ListSaleReceiptItems(ctx, sale.ID)
+	if err != nil {
+		return Receipt{}, err
+	}
+
+	payments, err := p.queries.ListSalePayments(ctx, sale.ID)
+	if err != nil {
+		return Receipt{}, err
+	}
+
+	receipt := Receipt{
+		SaleID:       sale.ID,
+		Status:       sale.Status,
+		BusinessName: header.BusinessName,
+		LogoURL:      header.LogoUrl.String,
+		Motto:        header.Motto.String,
+		Currency:     header.Currency.String,
+		StoreName:    header.StoreName,
+		Address:      header.Address,
+		Phone:        header.Phone,
+		Cashier:      header.CashierName,
+		Lines:        make([]ReceiptLine, 0, len(items)),
+		Subtotal:     sale.Subtotal,
+		Discount:     sale.DiscountAmount,
+		Tax:          sale.TaxAmount,
+		Total:        sale.TotalAmount,
+		Payments:     make([]ReceiptPayment, 0, len(payments)),
+		CreatedAt:    sale.CreatedAt.Time,
+	}
+	for _, item := range items {
+		name := item.ItemName
+		if item.VariationName != "" && item.VariationName != item.ItemName {
+			name += " - " + item.VariationName
+		}
+		price, _ := strconv.ParseFloat(item.UnitPrice, 64)
+		receipt.Lines = append(receipt.Lines, ReceiptLine{
+			VariationID: item.VariationID,
+			Name:        name,
+			Quantity:    item.Quantity,
+			UnitPrice:   item.UnitPrice,
+			Amount:      formatAmount(price * float64(item.Quantity)),
+		})
+	}
+	for _, payment := range payments {
+		receipt.Payments = append(receipt.Payments, ReceiptPayment{
+			Method:    string(payment.Method),
+			Amount:    payment.Amount,
+			Reference: payment.Reference.String,
+		})
+	}
+
+	return receipt, nil
+}
+
+// Text lays the receipt out for an 80mm thermal printer, receiptWidth
+// characters a line.
+func (r Receipt) Text() string {
+	var b strings.Builder
+	rule := strings.Repeat("-", receiptWidth)
+
+	for _, line := range []string{r.BusinessName, r.Motto, r.StoreName, r.Address, r.Phone} {
+		if line != "" {
+			b.WriteString(receiptCenter(line) + "\n")
+		}
+	}
+	b.WriteString(rule + "\n")
+	b.WriteString(receiptRow(fmt.Sprintf("Receipt #%d", r.SaleID), r.CreatedAt.Format("2006-01-02 15:04")) + "\n")
+	if r.Cashier != "" {
+		b.WriteString(receiptTruncate("Cashier: "+r.Cashier) + "\n")
+	}
+	if r.Status != "completed" {
+		b.WriteString(receiptTruncate("Status: "+strings.ReplaceAll(r.Status, "_", " ")) + "\n")
+	}
+	b.WriteString(rule + "\n")
+
+	for _, line := range r.Lines {
+		b.WriteString(receiptTruncate(line.Name) + "\n")
+		b.WriteString(receiptRow(fmt.Sprintf("  %d x %s", line.Quantity, line.UnitPrice), line.Amount) + "\n")
+	}
+	b.WriteString(rule + "\n")
+
+	total := "TOTAL"
+	if r.Currency != "" {
+		total += " (" + r.Currency + ")"
+	}
+	b.WriteString(receiptRow("Subtotal", r.Subtotal) + "\n")
+	b.WriteString(receiptRow("Discount", r.Discount) + "\n")
+	b.WriteString(receiptRow("Tax", r.Tax) + "\n")
+	b.WriteString(receiptRow(total, r.Total) + "\n")
+
+	if len(r.Payments) > 0 {
+		b.WriteString(rule + "\n")
+		for _, payment := range r.Payments {
+			method := strings.ReplaceAll(payment.Method, "_", " ")
+			if payment.Reference != "" {
+				method += " (" + payment.Reference + ")"
+			}
+			b.WriteString(receiptRow(method, payment.Amount) + "\n")
+		}
+	}
+
+	b.WriteString(rule + "\n")
+	b.WriteString(receiptCenter("Thank you for your purchase") + "\n")
+	return b.String()
+}
+
+func receiptTruncate(s string) string {
+	if runes := []rune(s); len(runes) > receiptWidth {
+		return string(runes[:receiptWidth])
+	}
+	return s
+}
+
+func receiptCenter(s string) string {
+	s = receiptTruncate(s)
+	pad := (receiptWidth - len([]rune(s))) / 2
+	return strings.Repeat(" ", pad) + s
+}
+
+// receiptRow puts left and right on one line, right aligned to the edge.
+// left is cut short when both don't fit.
+func receiptRow(left, right string) string {
+	space := receiptWidth - len([]rune(right)) - 1
+	if space < 0 {
+		return receiptTruncate(right)
+	}
+	if runes := []rune(left); len(runes) > space {
+		left = string(runes[:space])
+	}
+	return left + strings.Repeat(" ", receiptWidth-len([]rune(left))-len([]rune(right))) + right
+}
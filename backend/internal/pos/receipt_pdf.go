@@ -0,0 +1,251 @@
+package pos
+
+import (
+	"bytes"
+	"fmt"
+	"math"
+	"net/http"
+	"strings"
+
+	"github.com/go-pdf/fpdf"
+)
+
+// PDF receipts are as wide as the 80mm rolls the text receipt is laid out
+// for, sizes are in mm.
+const (
+	receiptPDFWidth  = 80.0
+	receiptPDFMargin = 4.0
+	receiptPDFInner  = receiptPDFWidth - 2*receiptPDFMargin
+	// the logo is scaled to fit in a box this size
+	receiptLogoWidth  = 40.0
+	receiptLogoHeight = 20.0
+)
+
+// receiptLogoTypes are the image types a logo can be drawn from, by the MIME
+// type sniffed from its content.
+var receiptLogoTypes = map[string]string{
+	"image/png":  "PNG",
+	"image/jpeg": "JPG",
+	"image/gif":  "GIF",
+}
+
+// PDF lays the receipt out on a page as wide as a thermal printer roll and as
+// long as the receipt, with the same content as Text. logo is drawn above the
+// business name, it is left out when empty or when it isn't a PNG, JPEG or
+// GIF. The document is only written out by its Output, straight to the
+// writer given to it.
+func (r Receipt) PDF(logo []byte) (*fpdf.Fpdf, error) {
+	pdf := fpdf.NewCustom(&fpdf.InitType{
+		UnitStr: "mm",
+		Size:    fpdf.SizeType{Wd: receiptPDFWidth, Ht: receiptPDFWidth},
+	})
+	pdf.SetMargins(receiptPDFMargin, receiptPDFMargin, receiptPDFMargin)
+	pdf.SetAutoPageBreak(false, 0)
+	pdf.SetTitle(fmt.Sprintf("Receipt #%d", r.SaleID), true)
+
+	logoWidth, logoHeight, hasLogo := receiptPDFLogo(pdf, logo)
+
+	layout := receiptPDFLayout{pdf: pdf, tr: pdf.UnicodeTranslatorFromDescriptor("")}
+	r.layoutPDF(&layout)
+
+	// the page is made once the rows are known, so it ends with the receipt
+	height := 2*receiptPDFMargin + layout.height()
+	if hasLogo {
+		height += logoHeight + 2
+	}
+	pdf.AddPageFormat("P", fpdf.SizeType{Wd: receiptPDFWidth, Ht: height})
+	pdf.SetLineWidth(0.2)
+
+	y := receiptPDFMargin
+	if hasLogo {
+		pdf.ImageOptions("logo", (receiptPDFWidth-logoWidth)/2, y, logoWidth, logoHeight, false, fpdf.ImageOptions{}, 0, "")
+		y += logoHeight + 2
+	}
+	pdf.SetXY(receiptPDFMargin, y)
+	layout.draw()
+
+	return pdf, pdf.Error()
+}
+
+func (r Receipt) layoutPDF(l *receiptPDFLayout) {
+	l.text(r.BusinessName, "B", 12, true)
+	if r.Motto != "" {
+		l.text(r.Motto, "I", 9, true)
+	}
+	for _, line := range []string{r.StoreName, r.Address, r.Phone} {
+		if line != "" {
+			l.text(line, "", 9, true)
+		}
+	}
+	l.rule()
+	l.pair(fmt.Sprintf("Receipt #%d", r.SaleID), r.CreatedAt.Format("2006-01-02 15:04"), "", 9)
+	if r.Cashier != "" {
+		l.text("Cashier: "+r.Cashier, "", 9, false)
+	}
+	if r.Status != "completed" {
+		l.text("Status: "+strings.ReplaceAll(r.Status, "_", " "), "B", 9, false)
+	}
+	l.rule()
+
+	for _, line := range r.Lines {
+		l.text(line.Name, "", 9, false)
+		l.pair(fmt.Sprintf("  %d x %s", line.Quantity, line.UnitPrice), line.Amount, "", 9)
+	}
+	l.rule()
+
+	total := "TOTAL"
+	if r.Currency != "" {
+		total += " (" + r.Currency + ")"
+	}
+	l.pair("Subtotal", r.Subtotal, "", 9)
+	l.pair("Discount", r.Discount, "", 9)
+	l.pair("Tax", r.Tax, "", 9)
+	l.pair(total, r.Total, "B", 10)
+
+	if len(r.Payments) > 0 {
+		l.rule()
+		for _, payment := range r.Payments {
+			method := strings.ReplaceAll(payment.Method, "_", " ")
+			if payment.Reference != "" {
+				method += " (" + payment.Reference + ")"
+			}
+			l.pair(method, payment.Amount, "", 9)
+		}
+	}
+
+	l.rule()
+	l.text("Thank you for your purchase", "I", 9, true)
+}
+
+// receiptPDFLogo registers logo with the document and returns the size it is
+// drawn at. A logo that can't be read is skipped, the receipt is still
+// printed without it.
+func receiptPDFLogo(pdf *fpdf.Fpdf, logo []byte) (float64, float64, bool) {
+	imageType, ok := receiptLogoTypes[http.DetectContentType(logo)]
+	if len(logo) == 0 || !ok {
+		return 0, 0, false
+	}
+	info := pdf.RegisterImageOptionsReader("logo", fpdf.ImageOptions{ImageType: imageType}, bytes.NewReader(logo))
+	if !pdf.Ok() || info == nil || info.Width() <= 0 || info.Height() <= 0 {
+		pdf.ClearError()
+		return 0, 0, false
+	}
+	scale := math.Min(receiptLogoWidth/info.Width(), receiptLogoHeight/info.Height())
+	return info.Width() * scale, info.Height() * scale, true
+}
+
+// receiptPDFRow is a line of a PDF receipt, text with an optional amount
+// aligned right, or a rule between sections.
+type receiptPDFRow struct {
+	left   string
+	right  string
+	style  string
+	size   float64
+	center bool
+	rule   bool
+}
+
+func (row receiptPDFRow) height() float64 {
+	if row.rule {
+		return 3
+	}
+	// font sizes are in points, the line is a little taller than the font
+	return row.size * 0.5
+}
+
+// receiptPDFLayout collects the rows of a receipt before they are drawn, so
+// the page can be made as long as they need.
+type receiptPDFLayout struct {
+	pdf  *fpdf.Fpdf
+	tr   func(string) string
+	rows []receiptPDFRow
+}
+
+// text adds s, wrapped over as many rows as it takes.
+func (l *receiptPDFLayout) text(s, style string, size float64, center bool) {
+	l.pdf.SetFont("Helvetica", style, size)
+	for _, line := range l.wrap(l.tr(s)) {
+		l.rows = append(l.rows, receiptPDFRow{left: line, style: style, size: size, center: center})
+	}
+}
+
+// wrap splits s into lines as wide as the receipt at the current font. s is
+// already translated to the single byte encoding of the core fonts, and
+// words wider than a line are cut.
+func (l *receiptPDFLayout) wrap(s string) []string {
+	var lines []string
+	line := ""
+	for _, word := range strings.Fields(s) {
+		joined := word
+		if line != "" {
+			joined = line + " " + word
+		}
+		if l.pdf.GetStringWidth(joined) <= receiptPDFInner {
+			line = joined
+			continue
+		}
+		if line != "" {
+			lines = append(lines, line)
+		}
+		for len(word) > 1 && l.pdf.GetStringWidth(word) > receiptPDFInner {
+			n := len(word) - 1
+			for n > 1 && l.pdf.GetStringWidth(word[:n]) > receiptPDFInner {
+				n--
+			}
+			lines = append(lines, word[:n])
+			word = word[n:]
+		}
+		line = word
+	}
+	if line != "" || len(lines) == 0 {
+		lines = append(lines, line)
+	}
+	return lines
+}
+
+// pair adds left and right on one row, right aligned to the edge. left is
+// cut short when both don't fit.
+func (l *receiptPDFLayout) pair(left, right, style string, size float64) {
+	l.pdf.SetFont("Helvetica", style, size)
+	left, right = l.tr(left), l.tr(right)
+	space := receiptPDFInner - l.pdf.GetStringWidth(right) - 2
+	for len(left) > 0 && l.pdf.GetStringWidth(left) > space {
+		left = left[:len(left)-1]
+	}
+	l.rows = append(l.rows, receiptPDFRow{left: left, right: right, style: style, size: size})
+}
+
+func (l *receiptPDFLayout) rule() {
+	l.rows = append(l.rows, receiptPDFRow{rule: true})
+}
+
+func (l *receiptPDFLayout) height() float64 {
+	var height float64
+	for _, row := range l.rows {
+		height += row.height()
+	}
+	return height
+}
+
+func (l *receiptPDFLayout) draw() {
+	pdf := l.pdf
+	for _, row := range l.rows {
+		height := row.height()
+		y := pdf.GetY()
+		switch {
+		case row.rule:
+			pdf.Line(receiptPDFMargin, y+height/2, receiptPDFWidth-receiptPDFMargin, y+height/2)
+		case row.center:
+			pdf.SetFont("Helvetica", row.style, row.size)
+			pdf.CellFormat(receiptPDFInner, height, row.left, "", 0, "C", false, 0, "")
+		default:
+			pdf.SetFont("Helvetica", row.style, row.size)
+			pdf.CellFormat(receiptPDFInner, height, row.left, "", 0, "L", false, 0, "")
+			if row.right != "" {
+				pdf.SetX(receiptPDFMargin)
+				pdf.CellFormat(receiptPDFInner, height, row.right, "", 0, "R", false, 0, "")
+			}
+		}
+		pdf.SetXY(receiptPDFMargin, y+height)
+	}
+}
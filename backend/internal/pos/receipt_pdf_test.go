@@ -0,0 +1,80 @@
+package pos
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"herp/internal/config"
+	"herp/pkg/jwt"
+	"herp/pkg/monitoring/logging"
+	"herp/pkg/storage"
+	"image"
+	"image/png"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func logoPNG(t *testing.T) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	require.NoError(t, png.Encode(&buf, image.NewRGBA(image.Rect(0, 0, 40, 20))))
+	return buf.Bytes()
+}
+
+// getPDFReceipt asks for the PDF receipt of sale 5, with the uploads served
+// from files.
+func getPDFReceipt(t *testing.T, files storage.FileStore) *httptest.ResponseRecorder {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+	cfg := &config.Config{BusinessScope: true, UploadMaxSize: 1 << 20}
+	h := NewHandler(NewPOS(&receiptQuerier{}, nil), cfg, logging.NewLogger(cfg), files, nil, nil)
+
+	r := gin.New()
+	r.Use(func(c *gin.Context) {
+		c.Set("claims", &jwt.Claims{UserID: 1, Username: "owner"})
+	})
+	r.GET("/pos/sales/:id/receipt", h.getReceipt)
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/pos/sales/5/receipt?format=pdf", nil))
+	return w
+}
+
+func TestGetReceiptPDF(t *testing.T) {
+	local := storage.NewLocal(t.TempDir())
+	// the receipt's logo is uploads/logo.png
+	files := storage.NewPublic(local, "uploads")
+	require.NoError(t, local.Put(context.Background(), "logo.png", logoPNG(t), "image/png"))
+
+	w := getPDFReceipt(t, files)
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	assert.Equal(t, "application/pdf", w.Header().Get("Content-Type"))
+	assert.Equal(t, `attachment; filename="receipt-5.pdf"`, w.Header().Get("Content-Disposition"))
+	assert.True(t, bytes.HasPrefix(w.Body.Bytes(), []byte("%PDF-")))
+	assert.Contains(t, w.Body.String(), "/Subtype /Image", "logo drawn")
+}
+
+func TestGetReceiptPDFWithoutLogo(t *testing.T) {
+	// nothing uploaded, the receipt is printed without the logo
+	w := getPDFReceipt(t, storage.NewPublic(storage.NewLocal(t.TempDir()), "uploads"))
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	assert.True(t, bytes.HasPrefix(w.Body.Bytes(), []byte("%PDF-")))
+	assert.NotContains(t, w.Body.String(), "/Subtype /Image")
+}
+
+func TestReceiptPDFSkipsBadLogo(t *testing.T) {
+	receipt, err := NewPOS(&receiptQuerier{}, nil).GetReceipt(context.Background(), 5, sql.NullInt32{Int32: 10, Valid: true})
+	require.NoError(t, err)
+
+	for _, logo := range [][]byte{nil, []byte("<svg></svg>"), append(logoPNG(t)[:20:20], "broken"...)} {
+		doc, err := receipt.PDF(logo)
+		require.NoError(t, err)
+		var buf bytes.Buffer
+		require.NoError(t, doc.Output(&buf))
+		assert.NotContains(t, buf.String(), "/Subtype /Image")
+	}
+}
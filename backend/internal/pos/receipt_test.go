@@ -0,0 +1,155 @@
+package pos
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	db "herp/db/sqlc"
+	"herp/internal/config"
+	"herp/pkg/jwt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// receiptQuerier holds sale 5 of business 10, owned by owner 1.
+type receiptQuerier struct {
+	Querier
+}
+
+func (q *receiptQuerier) GetOwnedBusinessID(ctx context.Context, params db.GetOwnedBusinessIDParams) (int32, error) {
+	if params.OwnerID != 1 || (params.BusinessID.Valid && params.BusinessID.Int32 != 10) {
+		return 0, sql.ErrNoRows
+	}
+	return 10, nil
+}
+
+func (q *receiptQuerier) GetSaleInBusiness(ctx context.Context, params db.GetSaleInBusinessParams) (db.Sale, error) {
+	if params.ID != 5 || (params.BusinessID.Valid && params.BusinessID.Int32 != 10) {
+		return db.Sale{}, sql.ErrNoRows
+	}
+	return db.Sale{
+		ID:             5,
+		Status:         "completed",
+		Subtotal:       "4500.00",
+		DiscountAmount: "500.00",
+		TaxAmount:      "300.00",
+		TotalAmount:    "4300.00",
+		CreatedAt:      at(time.Date(2026, 3, 1, 9, 30, 0, 0, time.UTC)),
+	}, nil
+}
+
+func (q *receiptQuerier) GetSaleReceiptHeader(ctx context.Context, id int32) (db.GetSaleReceiptHeaderRow, error) {
+	return db.GetSaleReceiptHeaderRow{
+		BusinessName: "Palmwine Express",
+		LogoUrl:      sql.NullString{String: "uploads/logo.png", Valid: true},
+		Motto:        sql.NullString{String: "Fresh every morning", Valid: true},
+		Currency:     sql.NullString{String: "NGN", Valid: true},
+		StoreName:    "Bar",
+		Address:      "1 Marina Road",
+		Phone:        "0800000000",
+		CashierName:  "Ada Obi",
+	}, nil
+}
+
+func (q *receiptQuerier) ListSaleReceiptItems(ctx context.Context, saleID int32) ([]db.ListSaleReceiptItemsRow, error) {
+	return []db.ListSaleReceiptItemsRow{
+		{VariationID: 7, ItemName: "Palm wine", VariationName: "1 litre", Quantity: 2, UnitPrice: "1500.00"},
+		{VariationID: 8, ItemName: "Suya", VariationName: "Suya", Quantity: 1, UnitPrice: "1500.00"},
+		{VariationID: 9, ItemName: "A very long name for a bottle of something imported", VariationName: "75cl", Quantity: 0, UnitPrice: "0.00"},
+	}, nil
+}
+
+func (q *receiptQuerier) ListSalePayments(ctx context.Context, saleID int32) ([]db.SalePayment, error) {
+	return []db.SalePayment{
+		{Method: "cash", Amount: "2000.00"},
+		{Method: "bank_transfer", Amount: "2300.00", Reference: sql.NullString{String: "TRX-1", Valid: true}},
+	}, nil
+}
+
+func TestGetReceipt(t *testing.T) {
+	p := NewPOS(&receiptQuerier{}, nil)
+
+	receipt, err := p.GetReceipt(context.Background(), 5, sql.NullInt32{Int32: 10, Valid: true})
+	require.NoError(t, err)
+	assert.Equal(t, "Palmwine Express", receipt.BusinessName)
+	assert.Equal(t, "Ada Obi", receipt.Cashier)
+	assert.Equal(t, []ReceiptLine{
+		{VariationID: 7, Name: "Palm wine - 1 litre", Quantity: 2, UnitPrice: "1500.00", Amount: "3000.00"},
+		{VariationID: 8, Name: "Suya", Quantity: 1, UnitPrice: "1500.00", Amount: "1500.00"},
+		{VariationID: 9, Name: "A very long name for a bottle of something imported - 75cl", Quantity: 0, UnitPrice: "0.00", Amount: "0.00"},
+	}, receipt.Lines)
+	assert.Equal(t, []ReceiptPayment{
+		{Method: "cash", Amount: "2000.00"},
+		{Method: "bank_transfer", Amount: "2300.00", Reference: "TRX-1"},
+	}, receipt.Payments)
+
+	_, err = p.GetReceipt(context.Background(), 5, sql.NullInt32{Int32: 20, Valid: true})
+	assert.ErrorIs(t, err, ErrSaleNotFound)
+}
+
+func TestReceiptText(t *testing.T) {
+	receipt, err := NewPOS(&receiptQuerier{}, nil).GetReceipt(context.Background(), 5, sql.NullInt32{Int32: 10, Valid: true})
+	require.NoError(t, err)
+
+	text := receipt.Text()
+	lines := strings.Split(strings.TrimSuffix(text, "\n"), "\n")
+	for _, line := range lines {
+		assert.LessOrEqual(t, len([]rune(line)), receiptWidth, "%q is wider than the paper", line)
+	}
+
+	assert.Equal(t, "                Palmwine Express", lines[0])
+	assert.Contains(t, lines, receiptRow("Receipt #5", "2026-03-01 09:30"))
+	assert.Contains(t, lines, "Cashier: Ada Obi")
+	assert.Contains(t, lines, "Palm wine - 1 litre")
+	assert.Contains(t, lines, "  2 x 1500.00                            3000.00")
+	assert.Contains(t, lines, "A very long name for a bottle of something impor")
+	assert.Contains(t, lines, "Discount                                  500.00")
+	assert.Contains(t, lines, "TOTAL (NGN)                              4300.00")
+	assert.Contains(t, lines, "bank transfer (TRX-1)                    2300.00")
+	// completed sales don't show their status
+	assert.NotContains(t, text, "Status:")
+}
+
+func getReceipt(t *testing.T, path string) *httptest.ResponseRecorder {
+	t.Helper()
+	cfg := &config.Config{BusinessScope: true}
+	r := newPOSRouter(NewPOS(&receiptQuerier{}, nil), cfg, &jwt.Claims{UserID: 1, Username: "owner"})
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, path, nil))
+	return w
+}
+
+func TestGetReceiptFormats(t *testing.T) {
+	w := getReceipt(t, "/pos/sales/5/receipt")
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	var body struct {
+		Data ReceiptResponse `json:"data"`
+	}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &body))
+	assert.Equal(t, "Palmwine Express", body.Data.BusinessName)
+	assert.Equal(t, "uploads/logo.png", body.Data.LogoURL)
+	assert.Equal(t, "Fresh every morning", body.Data.Motto)
+	assert.Equal(t, "4300.00", body.Data.Total)
+	require.Len(t, body.Data.Items, 3)
+	assert.Equal(t, "3000.00", body.Data.Items[0].Amount)
+	require.Len(t, body.Data.Payments, 2)
+	assert.Equal(t, "TRX-1", body.Data.Payments[1].Reference)
+
+	w = getReceipt(t, "/pos/sales/5/receipt?format=text")
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	assert.True(t, strings.HasPrefix(w.Header().Get("Content-Type"), "text/plain"))
+	assert.Contains(t, w.Body.String(), "TOTAL (NGN)")
+
+	w = getReceipt(t, "/pos/sales/5/receipt?format=html")
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	// a sale of another business isn't found
+	w = getReceipt(t, "/pos/sales/6/receipt?format=text")
+	assert.Equal(t, http.StatusNotFound, w.Code)
+}
@@ -0,0 +1,187 @@
+package pos
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	db "herp/db/sqlc"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var (
+	ErrStoreNotFound      = errors.New("store not found")
+	ErrInvalidReportDate  = errors.New("date must be formatted as YYYY-MM-DD")
+	ErrInvalidReportRange = errors.New("start_date must not be after end_date")
+)
+
+type DailyReportParams struct {
+	// Date is the day to report as YYYY-MM-DD, today when empty
+	Date       string
+	StoreID    sql.NullInt32
+	BusinessID sql.NullInt32
+}
+
+type PaymentMethodTotal struct {
+	Method       string
+	Transactions int32
+	Amount       string
+}
+
+type CategoryTotal struct {
+	CategoryID int32
+	Name       string
+	Quantity   int32
+	Amount     string
+}
+
+// DailyReport is the end of day summary of sales, the Z-report. Gross sales
+// are before discount and tax, net sales are after discount and before tax.
+type DailyReport struct {
+	Date            string
+	Timezone        string
+	Transactions    int32
+	GrossSales      string
+	Discounts       string
+	Tax             string
+	NetSales        string
+	Total           string
+	AverageTicket   string
+	ByPaymentMethod []PaymentMethodTotal
+	ByCategory      []CategoryTotal
+}
+
+// DailyReport sums up the sales of one day, of a store or of every store in
+// args.BusinessID. The day runs midnight to midnight in the timezone of the
+// business, sales are summed in the database.
+func (p *POS) DailyReport(ctx context.Context, args DailyReportParams) (DailyReport, error) {
+	timezone, loc, err := p.reportLocation(ctx, args.StoreID, args.BusinessID)
+	if err != nil {
+		return DailyReport{}, err
+	}
+
+	start, err := reportDay(args.Date, loc)
+	if err != nil {
+		return DailyReport{}, err
+	}
+	end := start.AddDate(0, 0, 1)
+
+	// sale timestamps are stored in UTC without a zone
+	summary, err := p.queries.GetSalesSummary(ctx, db.GetSalesSummaryParams{
+		StartTime:  start.UTC(),
+		EndTime:    end.UTC(),
+		StoreID:    args.StoreID,
+		BusinessID: args.BusinessID,
+	})
+	if err != nil {
+		return DailyReport{}, err
+	}
+
+	methods, err := p.queries.ListSalesByPaymentMethod(ctx, db.ListSalesByPaymentMethodParams{
+		StartTime:  start.UTC(),
+		EndTime:    end.UTC(),
+		StoreID:    args.StoreID,
+		BusinessID: args.BusinessID,
+	})
+	if err != nil {
+		return DailyReport{}, err
+	}
+
+	categories, err := p.queries.ListSalesByCategory(ctx, db.ListSalesByCategoryParams{
+		StartTime:  start.UTC(),
+		EndTime:    end.UTC(),
+		StoreID:    args.StoreID,
+		BusinessID: args.BusinessID,
+	})
+	if err != nil {
+		return DailyReport{}, err
+	}
+
+	gross, _ := strconv.ParseFloat(summary.GrossSales, 64)
+	discounts, _ := strconv.ParseFloat(summary.Discounts, 64)
+	total, _ := strconv.ParseFloat(summary.Total, 64)
+	average := 0.0
+	if summary.Transactions > 0 {
+		average = total / float64(summary.Transactions)
+	}
+
+	report := DailyReport{
+		Date:            start.Format("2006-01-02"),
+		Timezone:        timezone,
+		Transactions:    summary.Transactions,
+		GrossSales:      summary.GrossSales,
+		Discounts:       summary.Discounts,
+		Tax:             summary.Tax,
+		NetSales:        formatAmount(gross - discounts),
+		Total:           summary.Total,
+		AverageTicket:   formatAmount(average),
+		ByPaymentMethod: make([]PaymentMethodTotal, 0, len(methods)),
+		ByCategory:      make([]CategoryTotal, 0, len(categories)),
+	}
+	for _, m := range methods {
+		report.ByPaymentMethod = append(report.ByPaymentMethod, PaymentMethodTotal{
+			Method:       string(m.Method),
+			Transactions: m.Transactions,
+			Amount:       m.Amount,
+		})
+	}
+	for _, c := range categories {
+		report.ByCategory = append(report.ByCategory, CategoryTotal{
+			CategoryID: c.CategoryID,
+			Name:       c.CategoryName,
+			Quantity:   c.Quantity,
+			Amount:     c.Amount,
+		})
+	}
+
+	return report, nil
+}
+
+// reportLocation returns the timezone reports of a store, or of every store
+// of a business, are taken in. Without either it is UTC.
+func (p *POS) reportLocation(ctx context.Context, storeID, businessID sql.NullInt32) (string, *time.Location, error) {
+	timezone := "UTC"
+	var err error
+	switch {
+	case storeID.Valid:
+		timezone, err = p.queries.GetStoreReportTimezone(ctx, db.GetStoreReportTimezoneParams{
+			StoreID:    storeID.Int32,
+			BusinessID: businessID,
+		})
+		if errors.Is(err, sql.ErrNoRows) {
+			return "", nil, ErrStoreNotFound
+		}
+	case businessID.Valid:
+		timezone, err = p.queries.GetBusinessTimezone(ctx, businessID.Int32)
+	}
+	if err != nil {
+		return "", nil, err
+	}
+	return timezone, parseTimezone(timezone), nil
+}
+
+// reportDay returns the midnight starting a day given as YYYY-MM-DD in loc,
+// today when date is empty.
+func reportDay(date string, loc *time.Location) (time.Time, error) {
+	day := time.Now().In(loc)
+	if date != "" {
+		var err error
+		day, err = time.ParseInLocation("2006-01-02", date, loc)
+		if err != nil {
+			return time.Time{}, ErrInvalidReportDate
+		}
+	}
+	return time.Date(day.Year(), day.Month(), day.Day(), 0, 0, 0, 0, loc), nil
+}
+
+// parseTimezone reads the timezone of a business. Businesses store a zone
+// name like "Africa/Lagos", the offsets they were once created with were
+// turned into names by migration 000036. Anything else is read as UTC.
+func parseTimezone(timezone string) *time.Location {
+	timezone = strings.TrimSpace(timezone)
+	if loc, err := time.LoadLocation(timezone); err == nil && timezone != "" {
+		return loc
+	}
+	return time.UTC
+}
@@ -0,0 +1,112 @@
+package pos
+
+import (
+	"context"
+	"database/sql"
+	"herp/db/dbtest"
+	db "herp/db/sqlc"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// lagos is UTC+1 all year, midnight there is 23:00 UTC the day before.
+const lagos = "Etc/GMT-1"
+
+func TestReportDayAcrossOffset(t *testing.T) {
+	loc := parseTimezone(lagos)
+
+	start, err := reportDay("2026-03-02", loc)
+	require.NoError(t, err)
+	assert.Equal(t, time.Date(2026, 3, 1, 23, 0, 0, 0, time.UTC), start.UTC())
+
+	// 23:30 UTC on the 1st is already the 2nd
+	late := time.Date(2026, 3, 1, 23, 30, 0, 0, time.UTC)
+	assert.False(t, late.Before(start))
+	assert.True(t, late.Before(start.AddDate(0, 0, 1)))
+	assert.Equal(t, "2026-03-02", late.In(loc).Format("2006-01-02"))
+
+	_, err = reportDay("02/03/2026", loc)
+	assert.ErrorIs(t, err, ErrInvalidReportDate)
+}
+
+func TestParseTimezone(t *testing.T) {
+	assert.Equal(t, "Africa/Lagos", parseTimezone(" Africa/Lagos ").String())
+	assert.Equal(t, lagos, parseTimezone(lagos).String())
+	// normalized by migration 000036, anything left is read as UTC
+	assert.Equal(t, time.UTC, parseTimezone("UTC +1"))
+	assert.Equal(t, time.UTC, parseTimezone(""))
+}
+
+// boundaryQuerier answers the daily report of a business in lagos with no
+// sales, keeping the window it was asked for.
+type boundaryQuerier struct {
+	Querier
+	summary db.GetSalesSummaryParams
+}
+
+func (q *boundaryQuerier) GetBusinessTimezone(ctx context.Context, id int32) (string, error) {
+	return lagos, nil
+}
+
+func (q *boundaryQuerier) GetSalesSummary(ctx context.Context, arg db.GetSalesSummaryParams) (db.GetSalesSummaryRow, error) {
+	q.summary = arg
+	return db.GetSalesSummaryRow{GrossSales: "0.00", Discounts: "0.00", Tax: "0.00", Total: "0.00"}, nil
+}
+
+func (q *boundaryQuerier) ListSalesByPaymentMethod(ctx context.Context, arg db.ListSalesByPaymentMethodParams) ([]db.ListSalesByPaymentMethodRow, error) {
+	return nil, nil
+}
+
+func (q *boundaryQuerier) ListSalesByCategory(ctx context.Context, arg db.ListSalesByCategoryParams) ([]db.ListSalesByCategoryRow, error) {
+	return nil, nil
+}
+
+func TestDailyReportWindow(t *testing.T) {
+	q := &boundaryQuerier{}
+	report, err := NewPOS(q, nil).DailyReport(context.Background(), DailyReportParams{
+		Date:       "2026-03-02",
+		BusinessID: sql.NullInt32{Int32: 1, Valid: true},
+	})
+	require.NoError(t, err)
+
+	assert.Equal(t, "2026-03-02", report.Date)
+	assert.Equal(t, lagos, report.Timezone)
+	assert.Equal(t, time.Date(2026, 3, 1, 23, 0, 0, 0, time.UTC), q.summary.StartTime)
+	assert.Equal(t, time.Date(2026, 3, 2, 23, 0, 0, 0, time.UTC), q.summary.EndTime)
+}
+
+func TestReportDayBoundary(t *testing.T) {
+	f := newSaleFixture(t)
+	ctx := context.Background()
+	business := sql.NullInt32{Int32: f.businessID, Valid: true}
+	dbtest.Stock(t, f.conn, f.storeID, f.variation, 10)
+	dbtest.Exec(t, f.conn, `UPDATE business SET timezone = $1 WHERE id = $2`, lagos, f.businessID)
+
+	// 22:30 UTC is 23:30 on the 1st in Lagos, 23:30 UTC is past midnight
+	sold := func(at string) int32 {
+		result, err := f.sell(ctx, 1)
+		require.NoError(t, err)
+		dbtest.Exec(t, f.conn, `UPDATE sale SET created_at = $1 WHERE id = $2`, at, result.Sale.ID)
+		return result.Sale.ID
+	}
+	evening := sold("2026-03-01 22:30:00")
+	night := sold("2026-03-01 23:30:00")
+
+	for day, want := range map[string]int32{"2026-03-01": evening, "2026-03-02": night} {
+		report, err := f.pos.DailyReport(ctx, DailyReportParams{Date: day, BusinessID: business})
+		require.NoError(t, err)
+		assert.Equal(t, int32(1), report.Transactions, day)
+
+		history, err := f.pos.SalesHistory(ctx, SalesHistoryParams{StartDate: day, EndDate: day, BusinessID: business, Limit: 10})
+		require.NoError(t, err)
+		require.Len(t, history.Sales, 1, day)
+		assert.Equal(t, want, history.Sales[0].Sale.ID, day)
+	}
+
+	history, err := f.pos.SalesHistory(ctx, SalesHistoryParams{StartDate: "2026-03-01", EndDate: "2026-03-02", BusinessID: business, Limit: 10})
+	require.NoError(t, err)
+	assert.Equal(t, int64(2), history.Total)
+}
@@ -1,30 +1,78 @@
 package pos
 
 import (
+	"database/sql"
+	"errors"
+	"fmt"
+	db "herp/db/sqlc"
 	"herp/internal/auth"
+	"herp/internal/config"
+	"herp/internal/core/inventory"
 	"herp/internal/utils"
+	"herp/pkg/currency"
+	"herp/pkg/jwt"
+	"herp/pkg/monitoring/logging"
+	"herp/pkg/redis"
+	"herp/pkg/storage"
+	"io"
 	"net/http"
 	"strconv"
 	"time"
 
 	"github.com/gin-gonic/gin"
+	"github.com/lib/pq"
 )
 
 // CreateSaleRequest represents the request payload for creating a sale
 // @Description Create sale request payload
 type CreateSaleRequest struct {
+	StoreID    int32      `json:"store_id" binding:"required" example:"1"`    // Store the sale is made from
 	CustomerID int        `json:"customer_id" binding:"required" example:"1"` // Customer ID
-	Items      []SaleItem `json:"items" binding:"required"`                   // List of items in the sale
-	Discount   float64    `json:"discount" example:"10.5"`                    // Discount amount
-	TaxRate    float64    `json:"tax_rate" example:"8.25"`                    // Tax rate percentage
+	Items      []SaleItem `json:"items" binding:"required,dive"`              // List of items in the sale
+	Discount   float64    `json:"discount" example:"10.5"`                    // Manual discount amount, taken off after the discount rules
+	// Tax rate percentage, defaults to the rate of the business and can only
+	// differ from it when overrides are enabled
+	TaxRate *float64 `json:"tax_rate" binding:"omitempty,gte=0,lte=100" example:"8.25"`
+	// Coupon code redeemed for the discount of its rule
+	CouponCode string `json:"coupon_code" binding:"omitempty,max=50" example:"SUMMER10"`
+	// Stock reservations held for this order, consumed when the sale is created
+	ReservationIDs []int32 `json:"reservation_ids" example:"1,2"`
+	// How the sale is paid, the amounts have to add up to the total
+	Payments []SalePaymentRequest `json:"payments" binding:"required,min=1,dive"`
+}
+
+// SalePaymentRequest represents one payment of a sale
+// @Description Sale payment details
+type SalePaymentRequest struct {
+	Method    string  `json:"method" binding:"required,oneof=cash pos room_charge transfer" example:"cash"` // Payment method, one the business accepts
+	Amount    float64 `json:"amount" binding:"required,gt=0" example:"30.23"`                               // Amount paid with this method
+	Reference string  `json:"reference" example:"TRX-20240115-001"`                                         // Reference of the payment, e.g. a transfer or terminal receipt
+	FolioID   int32   `json:"folio_id" binding:"required_if=Method room_charge" example:"12"`               // Guest folio a room charge is posted to
+}
+
+// SalePaymentResponse represents one payment of a sale
+// @Description Sale payment details
+type SalePaymentResponse struct {
+	Method    string `json:"method" example:"cash"`                // Payment method
+	Amount    string `json:"amount" example:"30.23"`               // Amount paid with this method
+	Reference string `json:"reference" example:"TRX-20240115-001"` // Reference of the payment
+}
+
+// SaleDiscountResponse represents a discount taken off a sale
+// @Description Sale discount details
+type SaleDiscountResponse struct {
+	DiscountID *int32 `json:"discount_id" example:"3"`         // Discount rule applied, null for the manual discount
+	CouponID   *int32 `json:"coupon_id,omitempty" example:"7"` // Coupon redeemed for the discount
+	Name       string `json:"name" example:"Happy hour"`       // Name of the discount
+	Amount     string `json:"amount" example:"5.00"`           // Amount taken off
 }
 
 // SaleItem represents an item in a sale
 // @Description Sale item details
 type SaleItem struct {
-	ItemID   int     `json:"item_id" binding:"required" example:"1"`   // Item ID
-	Quantity int     `json:"quantity" binding:"required" example:"2"`  // Quantity of the item
-	Price    float64 `json:"price" binding:"required" example:"25.99"` // Price per unit
+	ItemID   int     `json:"item_id" binding:"required" example:"1"` // Variation ID of the item sold
+	Quantity int     `json:"quantity" example:"2"`                   // Quantity of the item, checked against the sale limits
+	Price    float64 `json:"price" example:"25.99"`                  // Price per unit, set from the store price when selling
 }
 
 // SaleResponse represents the response payload for a sale
@@ -37,22 +85,19 @@ type SaleResponse struct {
 	DiscountAmount float64    `json:"discount_amount" example:"10.5"`            // Discount amount
 	Items          []SaleItem `json:"items"`                                     // List of items in the sale
 	CreatedAt      time.Time  `json:"created_at" example:"2024-01-15T10:30:00Z"` // Sale creation timestamp
+	// Payments of the sale
+	Payments []SalePaymentResponse `json:"payments,omitempty"`
+	// Discounts taken off the sale, the rules applied and the manual discount
+	Discounts []SaleDiscountResponse `json:"discounts,omitempty"`
+	// Total in the currency of the Accept-Currency header
+	ConvertedTotal *ConvertedAmountResponse `json:"converted_total,omitempty"`
 }
 
 // SalesHistoryResponse represents the response payload for sales history
-// @Description Sales history response payload
+// @Description Sales history response payload, the sales are the items of the page
 type SalesHistoryResponse struct {
-	Sales      []SaleResponse     `json:"sales"`      // List of sales
-	Pagination PaginationResponse `json:"pagination"` // Pagination information
-}
-
-// PaginationResponse represents pagination information
-// @Description Pagination response payload
-type PaginationResponse struct {
-	Page  int `json:"page" example:"1"`    // Current page number
-	Limit int `json:"limit" example:"20"`  // Number of items per page
-	Total int `json:"total" example:"100"` // Total number of items
-	Pages int `json:"pages" example:"5"`   // Total number of pages
+	utils.PaginatedResponse
+	Timezone string `json:"timezone" example:"Africa/Lagos"` // Timezone the days are taken in
 }
 
 // CreateItemRequest represents the request payload for creating an item
@@ -86,15 +131,150 @@ type ErrorResponse struct {
 	Error string `json:"error" example:"Invalid request"` // Error message
 }
 
-func RegisterRoutes(r *gin.RouterGroup, authSvc *auth.Service) {
+// ConvertedAmountResponse represents an amount in another currency
+// @Description Converted amount
+type ConvertedAmountResponse struct {
+	Currency string  `json:"currency" example:"USD"`  // Currency converted to
+	Amount   float64 `json:"amount" example:"37.51"`  // Converted amount
+	Rate     float64 `json:"rate" example:"0.000667"` // Rate used for the conversion
+}
+
+type Handler struct {
+	service POSInterface
+	config  *config.Config
+	logger  *logging.Logger
+	files   storage.FileStore
+	rates   *currency.Converter
+	redis   *redis.Redis
+}
+
+func NewHandler(service POSInterface, c *config.Config, l *logging.Logger, files storage.FileStore, rates *currency.Converter, r *redis.Redis) *Handler {
+	return &Handler{
+		service: service,
+		config:  c,
+		logger:  l,
+		files:   files,
+		rates:   rates,
+		redis:   r,
+	}
+}
+
+// acceptCurrency reads the currency totals should also be given in from the
+// Accept-Currency header. An empty string means none was asked for, an
+// unknown currency is answered with 400.
+func (h *Handler) acceptCurrency(c *gin.Context) (string, bool) {
+	header := c.GetHeader("Accept-Currency")
+	if header == "" {
+		return "", true
+	}
+	code, err := currency.Normalize(header)
+	if err == nil {
+		err = h.rates.Check(c, code)
+	}
+	if err != nil {
+		if errors.Is(err, currency.ErrUnknownCurrency) {
+			utils.ErrorResponse(c, 400, "invalid Accept-Currency header: "+err.Error())
+			return "", false
+		}
+		h.logger.Errorf("error checking currency %s: %v", code, err)
+		utils.ErrorResponse(c, 500, utils.SERVERERROR)
+		return "", false
+	}
+	return code, true
+}
+
+// convertTotal converts a total in the currency of the business to the one
+// asked for. The conversion is extra information, so when it fails the
+// total is just left unconverted.
+func (h *Handler) convertTotal(c *gin.Context, total float64, from, to string) *ConvertedAmountResponse {
+	if to == "" || from == "" {
+		return nil
+	}
+	amount, rate, err := h.rates.Convert(c, total, from, to)
+	if err != nil {
+		h.logger.Warnf("failed to convert total from %s to %s: %v", from, to, err)
+		return nil
+	}
+	return &ConvertedAmountResponse{Currency: to, Amount: amount, Rate: rate}
+}
+
+// businessScope resolves the business that sale reads are limited to, the
+// same way the inventory handler does. When scoping is disabled in config an
+// empty scope is returned and no filtering is applied.
+func (h *Handler) businessScope(c *gin.Context, claims *jwt.Claims) (sql.NullInt32, bool) {
+	if !h.config.BusinessScope {
+		return sql.NullInt32{}, true
+	}
+
+	var requested sql.NullInt32
+	if header := c.GetHeader("X-Business-ID"); header != "" {
+		id, err := strconv.Atoi(header)
+		if err != nil || id <= 0 {
+			utils.ErrorResponse(c, 400, "invalid X-Business-ID header")
+			return sql.NullInt32{}, false
+		}
+		requested = sql.NullInt32{Int32: int32(id), Valid: true}
+	}
+
+	if scope := auth.GetStoreScope(c); scope.User {
+		if !scope.BusinessID.Valid || (requested.Valid && requested.Int32 != scope.BusinessID.Int32) {
+			utils.ErrorResponse(c, 403, "no business found for this account")
+			return sql.NullInt32{}, false
+		}
+		return scope.BusinessID, true
+	}
+
+	businessID, err := h.service.GetOwnedBusinessID(c, db.GetOwnedBusinessIDParams{
+		OwnerID:    int32(claims.UserID),
+		BusinessID: requested,
+	})
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			utils.ErrorResponse(c, 403, "no business found for this account")
+			return sql.NullInt32{}, false
+		}
+		h.logger.Errorf("error resolving business scope: %v", err)
+		utils.ErrorResponse(c, 500, utils.SERVERERROR)
+		return sql.NullInt32{}, false
+	}
+
+	return sql.NullInt32{Int32: businessID, Valid: true}, true
+}
+
+func (h *Handler) RegisterRoutes(r *gin.RouterGroup, authSvc *auth.Service) {
 	pos := r.Group("/pos")
 	pos.Use(auth.AuthMiiddleware(authSvc))
+	pos.Use(auth.StoreScopeMiddleware(authSvc))
 
 	// Sales endpoint
 	sales := pos.Group("/sales")
 	{
-		sales.POST("", auth.PermissionMiddleware(authSvc, "pos:sell"), createSale)
-		sales.GET("/history", auth.PermissionMiddleware(authSvc, "pos:view"), getSalesHistory)
+		sales.POST("", auth.PermissionMiddleware(authSvc, "pos:sell"), h.createSale)
+		sales.GET("/history", auth.PermissionMiddleware(authSvc, "pos:view"), h.getSalesHistory)
+		sales.POST("/:id/refund", auth.PermissionMiddleware(authSvc, "pos:refund"), h.refundSale)
+		sales.GET("/:id/timeline", auth.PermissionMiddleware(authSvc, "pos:view"), h.getSaleTimeline)
+		sales.GET("/:id/receipt", auth.PermissionMiddleware(authSvc, "pos:view"), h.getReceipt)
+	}
+
+	pos.GET("/reports/daily", auth.PermissionMiddleware(authSvc, "pos:view"), h.getDailyReport)
+	pos.GET("/reports/margin", auth.PermissionMiddleware(authSvc, "pos:view"), h.getMarginReport)
+	pos.GET("/customers/:id/loyalty", auth.PermissionMiddleware(authSvc, "pos:view"), h.getLoyaltyBalance)
+	pos.PUT("/loyalty/rule", auth.PermissionMiddleware(authSvc, "business:update"), h.setLoyaltyRule)
+
+	discounts := pos.Group("/discounts")
+	{
+		discounts.POST("", auth.PermissionMiddleware(authSvc, "pos:manage_discounts"), h.createDiscount)
+		discounts.GET("", auth.PermissionMiddleware(authSvc, "pos:view"), h.listDiscounts)
+		discounts.GET("/:id", auth.PermissionMiddleware(authSvc, "pos:view"), h.getDiscount)
+		discounts.PUT("/:id", auth.PermissionMiddleware(authSvc, "pos:manage_discounts"), h.updateDiscount)
+		discounts.DELETE("/:id", auth.PermissionMiddleware(authSvc, "pos:manage_discounts"), h.deleteDiscount)
+	}
+
+	coupons := pos.Group("/coupons")
+	{
+		coupons.POST("", auth.PermissionMiddleware(authSvc, "pos:manage_discounts"), h.createCoupon)
+		coupons.GET("", auth.PermissionMiddleware(authSvc, "pos:view"), h.listCoupons)
+		coupons.DELETE("/:id", auth.PermissionMiddleware(authSvc, "pos:manage_discounts"), h.deleteCoupon)
 	}
 
 	// items endpoint
@@ -106,154 +286,1805 @@ func RegisterRoutes(r *gin.RouterGroup, authSvc *auth.Service) {
 
 // CreateSale godoc
 // @Summary Create sale
-// @Description Create a new sale transaction
+// @Description Create a new sale transaction. The sale can be split over several payments, each with a method the business accepts, that together match the total rounded the way the business rounds. Room charges are posted to the given guest folio, which has to be open. The discount rules of the business that are running are applied before the manual discount, together they can't take off more than the configured share of the subtotal. A coupon code adds the discount of its rule, its uses are counted so sales made at the same time can't redeem it past its limit.
 // @Tags pos
 // @Accept json
 // @Produce json
 // @Security BearerAuth
 // @Param body body CreateSaleRequest true "Sale details"
+// @Param Idempotency-Key header string false "Key making repeats of the request return the sale already made"
+// @Param Accept-Currency header string false "Currency to also give the total in"
 // @Success 201 {object} SaleResponse "Sale created successfully"
-// @Failure 400 {object} ErrorResponse "Bad request"
+// @Failure 400 {object} ErrorResponse "Bad request, or coupon code invalid, expired, used up or not applicable"
 // @Failure 401 {object} ErrorResponse "Unauthorized"
 // @Failure 403 {object} ErrorResponse "Forbidden"
+// @Failure 409 {object} ErrorResponse "Reservation expired or already used, folio closed, or Idempotency-Key reused"
+// @Failure 422 {object} ErrorResponse "Too many lines or invalid quantity"
 // @Failure 500 {object} ErrorResponse "Internal server error"
 // @Router /pos/sales [post]
-func createSale(c *gin.Context) {
+func (h *Handler) createSale(c *gin.Context) {
+	claims, ok := jwt.GetUserFromContext(c)
+	if !ok {
+		h.logger.Errorf("could not get user from context")
+		utils.ErrorResponse(c, 500, utils.SERVERERROR)
+		return
+	}
+
 	var req CreateSaleRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
 		utils.ErrorResponse(c, 400, err.Error())
 		return
 	}
 
-	// TODO: Implement actual sale creation logic
-	// For now, return a mock response
+	if err := validateSaleItems(req.Items, h.config.SaleMaxLines, h.config.SaleMaxQuantity); err != nil {
+		utils.ErrorResponse(c, 422, err.Error())
+		return
+	}
+
+	scope, ok := h.businessScope(c, claims)
+	if !ok {
+		return
+	}
+
+	if !auth.RequireStore(c, req.StoreID) {
+		return
+	}
+	if _, err := h.service.GetStoreInBusiness(c, db.GetStoreInBusinessParams{ID: req.StoreID, BusinessID: scope}); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			utils.ErrorResponse(c, 400, fmt.Sprintf("store with id %d does not exist", req.StoreID))
+			return
+		}
+		h.logger.Errorf("error getting store with id %d: %v", req.StoreID, err)
+		utils.ErrorResponse(c, 500, utils.SERVERERROR)
+		return
+	}
+
+	// checked before the sale is made so a bad header doesn't fail a
+	// sale that went through
+	convertTo, ok := h.acceptCurrency(c)
+	if !ok {
+		return
+	}
+
+	// a repeated request answers with the sale the first one made
+	idempotencyKey, replay, ok := h.claimIdempotencyKey(c, claims, req)
+	if !ok {
+		return
+	}
+	if replay != nil {
+		c.Header("Idempotent-Replayed", "true")
+		utils.SuccessResponse(c, 201, "", replay)
+		return
+	}
+
+	lines := make([]SaleLine, 0, len(req.Items))
+	for _, item := range req.Items {
+		lines = append(lines, SaleLine{
+			VariationID: int32(item.ItemID),
+			Quantity:    int32(item.Quantity),
+		})
+	}
+
+	payments := make([]SalePayment, 0, len(req.Payments))
+	for _, payment := range req.Payments {
+		payments = append(payments, SalePayment{
+			Method:    payment.Method,
+			Amount:    payment.Amount,
+			Reference: payment.Reference,
+			FolioID:   payment.FolioID,
+		})
+	}
+
+	result, err := h.service.CreateSale(c, CreateSaleParams{
+		StoreID:              req.StoreID,
+		CustomerID:           int32(req.CustomerID),
+		CashierID:            int32(claims.UserID),
+		Lines:                lines,
+		DiscountAmount:       req.Discount,
+		CouponCode:           req.CouponCode,
+		DiscountCap:          h.config.DiscountStackCap,
+		TaxRate:              req.TaxRate,
+		AllowTaxRateOverride: h.config.SaleTaxOverride,
+		ReservationIDs:       req.ReservationIDs,
+		Payments:             payments,
+	})
+	if err != nil {
+		h.releaseIdempotencyKey(c, idempotencyKey)
+		switch {
+		case errors.Is(err, ErrDuplicateSaleItem),
+			errors.Is(err, ErrSaleItemNotFound),
+			errors.Is(err, ErrCustomerNotFound),
+			errors.Is(err, ErrReservationMismatch),
+			errors.Is(err, inventory.ErrReservationNotFound),
+			errors.Is(err, ErrPaymentMethodNotAllowed),
+			errors.Is(err, ErrPaymentMismatch),
+			errors.Is(err, ErrFolioRequired),
+			errors.Is(err, ErrFolioNotFound),
+			errors.Is(err, ErrTaxRateOverride),
+			errors.Is(err, ErrCouponInvalid),
+			errors.Is(err, ErrCouponExpired),
+			errors.Is(err, ErrCouponExhausted),
+			errors.Is(err, ErrCouponNotApplicable):
+			utils.ErrorResponse(c, 400, err.Error())
+		case errors.Is(err, inventory.ErrReservationInactive),
+			errors.Is(err, inventory.ErrInsufficientStock),
+			errors.Is(err, ErrFolioClosed):
+			utils.ErrorResponse(c, 409, err.Error())
+		default:
+			h.logger.Errorf("error creating sale: %v", err)
+			utils.ErrorResponse(c, 500, utils.SERVERERROR)
+		}
+		return
+	}
+
+	sale := result.Sale
+	totalAmount, _ := strconv.ParseFloat(sale.TotalAmount, 64)
+	taxAmount, _ := strconv.ParseFloat(sale.TaxAmount, 64)
+	discountAmount, _ := strconv.ParseFloat(sale.DiscountAmount, 64)
 	response := SaleResponse{
-		ID:             1,
-		CustomerID:     req.CustomerID,
-		TotalAmount:    calculateTotal(req.Items, req.Discount, req.TaxRate),
-		TaxAmount:      calculateTax(req.Items, req.TaxRate),
-		DiscountAmount: req.Discount,
-		Items:          req.Items,
-		CreatedAt:      time.Now(),
+		ID:             int(sale.ID),
+		CustomerID:     int(sale.CustomerID),
+		TotalAmount:    totalAmount,
+		TaxAmount:      taxAmount,
+		DiscountAmount: discountAmount,
+		Items:          make([]SaleItem, 0, len(result.Items)),
+		CreatedAt:      sale.CreatedAt.Time,
+		Payments:       make([]SalePaymentResponse, 0, len(result.Payments)),
+		Discounts:      make([]SaleDiscountResponse, 0, len(result.Discounts)),
 	}
+	for _, item := range result.Items {
+		price, _ := strconv.ParseFloat(item.UnitPrice, 64)
+		response.Items = append(response.Items, SaleItem{
+			ItemID:   int(item.VariationID),
+			Quantity: int(item.Quantity),
+			Price:    price,
+		})
+	}
+	for _, payment := range result.Payments {
+		response.Payments = append(response.Payments, SalePaymentResponse{
+			Method:    string(payment.Method),
+			Amount:    payment.Amount,
+			Reference: payment.Reference.String,
+		})
+	}
+	for _, discount := range result.Discounts {
+		item := SaleDiscountResponse{Name: discount.Name, Amount: discount.Amount}
+		if discount.DiscountID.Valid {
+			item.DiscountID = &discount.DiscountID.Int32
+		}
+		if discount.CouponID.Valid {
+			item.CouponID = &discount.CouponID.Int32
+		}
+		response.Discounts = append(response.Discounts, item)
+	}
+	if convertTo != "" {
+		saleCurrency, err := h.service.SaleCurrency(c, sale.ID)
+		if err != nil {
+			h.logger.Warnf("failed to get currency of sale %d: %v", sale.ID, err)
+		}
+		response.ConvertedTotal = h.convertTotal(c, totalAmount, saleCurrency, convertTo)
+	}
+	h.storeIdempotentSale(c, idempotencyKey, response)
 
 	utils.SuccessResponse(c, 201, "", response)
 }
 
-// GetSalesHistory godoc
-// @Summary Get sales history
-// @Description Get sales history with optional filters
+// RefundRequest represents the request payload for refunding a sale
+// @Description Refund request payload, leave items empty to refund the whole sale
+type RefundRequest struct {
+	Items []RefundItemRequest `json:"items" binding:"dive"` // Lines to refund
+}
+
+// RefundItemRequest represents a line to refund
+// @Description Refund line details
+type RefundItemRequest struct {
+	ItemID   int32 `json:"item_id" binding:"required" example:"1"`       // Variation ID of the item sold
+	Quantity int32 `json:"quantity" binding:"required,gt=0" example:"1"` // Quantity to refund
+}
+
+// RefundLineResponse represents a sale line after a refund
+// @Description Refund line details
+type RefundLineResponse struct {
+	ItemID            int32  `json:"item_id" example:"1"`             // Variation ID of the item sold
+	RefundedQuantity  int32  `json:"refunded_quantity" example:"1"`   // Quantity refunded by this refund
+	RefundedAmount    string `json:"refunded_amount" example:"25.99"` // Amount refunded by this refund
+	RemainingQuantity int32  `json:"remaining_quantity" example:"1"`  // Quantity that can still be refunded
+}
+
+// RefundResponse represents the response payload for a refund
+// @Description Refund response payload
+type RefundResponse struct {
+	ID          int32                `json:"id" example:"1"`                           // Refund ID
+	SaleID      int32                `json:"sale_id" example:"1"`                      // Sale ID
+	TotalAmount string               `json:"total_amount" example:"25.99"`             // Total amount refunded
+	SaleStatus  string               `json:"sale_status" example:"partially_refunded"` // Sale status after the refund
+	Items       []RefundLineResponse `json:"items"`                                    // Sale lines after the refund
+}
+
+// RefundSale godoc
+// @Summary Refund sale
+// @Description Refund a sale fully or partially and restock the returned items
 // @Tags pos
+// @Accept json
 // @Produce json
 // @Security BearerAuth
-// @Param page query int false "Page number"
-// @Param limit query int false "Number of items per page"
-// @Param start_date query string false "Start date (YYYY-MM-DD)"
-// @Param end_date query string false "End date (YYYY-MM-DD)"
-// @Success 200 {object} SalesHistoryResponse "Sales history retrieved successfully"
+// @Param id path int true "Sale ID"
+// @Param body body RefundRequest false "Lines to refund"
+// @Success 201 {object} RefundResponse "Sale refunded successfully"
+// @Failure 400 {object} ErrorResponse "Bad request"
 // @Failure 401 {object} ErrorResponse "Unauthorized"
 // @Failure 403 {object} ErrorResponse "Forbidden"
+// @Failure 404 {object} ErrorResponse "Sale not found"
+// @Failure 409 {object} ErrorResponse "Already refunded"
 // @Failure 500 {object} ErrorResponse "Internal server error"
-// @Router /pos/sales/history [get]
-func getSalesHistory(c *gin.Context) {
-	// Parse query parameters
-	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
-	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "20"))
-	startDate := c.Query("start_date")
-	endDate := c.Query("end_date")
-
-	// TODO: Implement actual sales history retrieval logic
-	// For now, return a mock response
-	response := SalesHistoryResponse{
-		Sales: []SaleResponse{
-			{
-				ID:             1,
-				CustomerID:     1,
-				TotalAmount:    56.23,
-				TaxAmount:      4.27,
-				DiscountAmount: 10.5,
-				Items: []SaleItem{
-					{ItemID: 1, Quantity: 2, Price: 25.99},
-				},
-				CreatedAt: time.Now().Add(-24 * time.Hour),
-			},
-		},
-		Pagination: PaginationResponse{
-			Page:  page,
-			Limit: limit,
-			Total: 1,
-			Pages: 1,
-		},
-	}
-
-	// Log filters for debugging
-	if startDate != "" || endDate != "" {
-		// TODO: Apply date filters
-		_ = startDate
-		_ = endDate
-	}
-
-	c.JSON(http.StatusOK, response)
+// @Router /pos/sales/{id}/refund [post]
+func (h *Handler) refundSale(c *gin.Context) {
+	claims, ok := jwt.GetUserFromContext(c)
+	if !ok {
+		h.logger.Errorf("could not get user from context")
+		utils.ErrorResponse(c, 500, utils.SERVERERROR)
+		return
+	}
+
+	saleID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		utils.ErrorResponse(c, 400, utils.INVALID_REQUEST_DATA)
+		return
+	}
+
+	var req RefundRequest
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			h.logger.Errorf("error binding refund request data: %v", err)
+			utils.ErrorResponse(c, 400, utils.INVALID_REQUEST_DATA)
+			return
+		}
+	}
+
+	scope, ok := h.businessScope(c, claims)
+	if !ok {
+		return
+	}
+
+	// staff assigned to stores only refund sales made in one of theirs
+	sale, err := h.service.GetSale(c, int32(saleID), scope)
+	if err != nil {
+		if errors.Is(err, ErrSaleNotFound) {
+			utils.ErrorResponse(c, 404, err.Error())
+			return
+		}
+		h.logger.Errorf("error getting sale %d: %v", saleID, err)
+		utils.ErrorResponse(c, 500, utils.SERVERERROR)
+		return
+	}
+	if !auth.RequireStore(c, sale.StoreID) {
+		return
+	}
+
+	lines := make([]RefundLine, 0, len(req.Items))
+	for _, item := range req.Items {
+		lines = append(lines, RefundLine{VariationID: item.ItemID, Quantity: item.Quantity})
+	}
+
+	result, err := h.service.RefundSale(c, RefundSaleParams{
+		SaleID:     int32(saleID),
+		RefundedBy: int32(claims.UserID),
+		BusinessID: scope,
+		Lines:      lines,
+	})
+	if err != nil {
+		switch {
+		case errors.Is(err, ErrSaleNotFound):
+			utils.ErrorResponse(c, 404, err.Error())
+		case errors.Is(err, ErrRefundItemNotInSale), errors.Is(err, ErrRefundExceedsSold):
+			utils.ErrorResponse(c, 400, err.Error())
+		case errors.Is(err, ErrRefundExceedsBalance):
+			utils.ErrorResponse(c, 409, err.Error())
+		default:
+			h.logger.Errorf("error refunding sale %d: %v", saleID, err)
+			utils.ErrorResponse(c, 500, utils.SERVERERROR)
+		}
+		return
+	}
+
+	refunded := make(map[int32]db.RefundItem, len(result.Items))
+	for _, item := range result.Items {
+		refunded[item.SaleItemID] = item
+	}
+
+	items := make([]RefundLineResponse, 0, len(result.SaleItems))
+	for _, item := range result.SaleItems {
+		line := RefundLineResponse{
+			ItemID:            item.VariationID,
+			RefundedAmount:    "0.00",
+			RemainingQuantity: item.Quantity - item.RefundedQuantity,
+		}
+		if r, ok := refunded[item.ID]; ok {
+			line.RefundedQuantity = r.Quantity
+			line.RefundedAmount = r.Amount
+		}
+		items = append(items, line)
+	}
+
+	_, err = h.service.LogActivity(c, db.LogActivityParams{
+		UserID:     int32(claims.UserID),
+		Action:     "Refunded Sale",
+		EntityType: "Refund",
+		EntityID:   result.Refund.ID,
+		Details:    utils.WriteActivityDetails(claims.Username, claims.Email, fmt.Sprintf("Refunded %s on sale %d", result.Refund.TotalAmount, result.Sale.ID), result.Refund.CreatedAt.Time),
+		IpAddress:  sql.NullString{Valid: true, String: utils.GetClientIP(c)},
+		UserAgent:  sql.NullString{Valid: true, String: c.Request.UserAgent()},
+	})
+	if err != nil {
+		h.logger.Warnf("error logging refund activity: %v", err)
+	}
+
+	utils.SuccessResponse(c, 201, "sale refunded", RefundResponse{
+		ID:          result.Refund.ID,
+		SaleID:      result.Sale.ID,
+		TotalAmount: result.Refund.TotalAmount,
+		SaleStatus:  result.Sale.Status,
+		Items:       items,
+	})
 }
 
-// CreateItem godoc
-// @Summary Create item
-// @Description Create a new inventory item
+// TimelineEventResponse represents one event in the history of a sale
+// @Description Sale timeline event
+type TimelineEventResponse struct {
+	Type        string    `json:"type" example:"stock_deducted"`                  // sale_created, stock_deducted, refunded, stock_restocked, loyalty_points_earned, loyalty_points_reversed or activity
+	OccurredAt  time.Time `json:"occurred_at" example:"2024-01-15T10:30:00Z"`     // When the event happened
+	ActorID     int32     `json:"actor_id,omitempty" example:"1"`                 // User who caused the event
+	RefundID    int32     `json:"refund_id,omitempty" example:"1"`                // Refund the event belongs to
+	VariationID int32     `json:"variation_id,omitempty" example:"1"`             // Variation whose stock moved
+	Quantity    int32     `json:"quantity,omitempty" example:"-2"`                // Stock movement, negative when stock left the store
+	Amount      string    `json:"amount,omitempty" example:"25.99"`               // Money involved in the event
+	Points      int32     `json:"points,omitempty" example:"25"`                  // Loyalty points earned or reversed
+	Details     string    `json:"details,omitempty" example:"Refunded Sale: ..."` // Free text details
+}
+
+// SaleTimelineResponse represents the full history of a sale
+// @Description Sale timeline response payload
+type SaleTimelineResponse struct {
+	SaleID  int32                   `json:"sale_id" example:"1"`        // Sale ID
+	StoreID int32                   `json:"store_id" example:"1"`       // Store the sale was made from
+	Status  string                  `json:"status" example:"completed"` // Current sale status
+	Events  []TimelineEventResponse `json:"events"`                     // Events, oldest first
+}
+
+// GetSaleTimeline godoc
+// @Summary Get sale timeline
+// @Description Get everything that happened to a sale, its stock movements, refunds, loyalty points and logged activity, oldest first
 // @Tags pos
-// @Accept json
 // @Produce json
 // @Security BearerAuth
-// @Param body body CreateItemRequest true "Item details"
-// @Success 201 {object} ItemResponse "Item created successfully"
+// @Param id path int true "Sale ID"
+// @Param X-Business-ID header int false "Business to scope the sale to, defaults to the user's first business"
+// @Success 200 {object} SaleTimelineResponse "Sale timeline retrieved successfully"
 // @Failure 400 {object} ErrorResponse "Bad request"
 // @Failure 401 {object} ErrorResponse "Unauthorized"
 // @Failure 403 {object} ErrorResponse "Forbidden"
+// @Failure 404 {object} ErrorResponse "Sale not found"
 // @Failure 500 {object} ErrorResponse "Internal server error"
-// @Router /pos/items [post]
-func createItem(c *gin.Context) {
-	var req CreateItemRequest
-	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, ErrorResponse{Error: err.Error()})
+// @Router /pos/sales/{id}/timeline [get]
+func (h *Handler) getSaleTimeline(c *gin.Context) {
+	claims, ok := jwt.GetUserFromContext(c)
+	if !ok {
+		h.logger.Errorf("could not get user from context")
+		utils.ErrorResponse(c, 500, utils.SERVERERROR)
 		return
 	}
 
-	// TODO: Implement actual item creation logic
-	// For now, return a mock response
-	response := ItemResponse{
-		ID:            1,
-		Name:          req.Name,
-		Description:   req.Description,
-		Price:         req.Price,
-		Category:      req.Category,
-		SKU:           req.SKU,
-		StockQuantity: req.StockQuantity,
-		CreatedAt:     time.Now(),
-		UpdatedAt:     time.Now(),
+	saleID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		utils.ErrorResponse(c, 400, utils.INVALID_REQUEST_DATA)
+		return
+	}
+
+	scope, ok := h.businessScope(c, claims)
+	if !ok {
+		return
+	}
+
+	sale, events, err := h.service.GetSaleTimeline(c, int32(saleID), scope)
+	if err != nil {
+		if errors.Is(err, ErrSaleNotFound) {
+			utils.ErrorResponse(c, 404, err.Error())
+			return
+		}
+		h.logger.Errorf("error getting timeline of sale %d: %v", saleID, err)
+		utils.ErrorResponse(c, 500, utils.SERVERERROR)
+		return
 	}
 
-	c.JSON(http.StatusCreated, response)
+	response := SaleTimelineResponse{
+		SaleID:  sale.ID,
+		StoreID: sale.StoreID,
+		Status:  sale.Status,
+		Events:  make([]TimelineEventResponse, 0, len(events)),
+	}
+	for _, event := range events {
+		response.Events = append(response.Events, TimelineEventResponse(event))
+	}
+
+	utils.SuccessResponse(c, 200, "sale timeline", response)
 }
 
-// Helper functions for calculations
-func calculateTotal(items []SaleItem, discount, taxRate float64) float64 {
-	subtotal := 0.0
-	for _, item := range items {
-		subtotal += item.Price * float64(item.Quantity)
+// ReceiptLineResponse represents a line of a receipt
+// @Description Receipt line
+type ReceiptLineResponse struct {
+	ItemID    int32  `json:"item_id" example:"1"`             // Variation ID of the item sold
+	Name      string `json:"name" example:"Shoes - Black 42"` // Item and variation name
+	Quantity  int32  `json:"quantity" example:"2"`            // Quantity sold
+	UnitPrice string `json:"unit_price" example:"25.99"`      // Price per unit
+	Amount    string `json:"amount" example:"51.98"`          // Quantity times unit price
+}
+
+// ReceiptResponse represents the receipt of a sale
+// @Description Receipt response payload
+type ReceiptResponse struct {
+	SaleID       int32                 `json:"sale_id" example:"1"`                                 // Sale ID
+	Status       string                `json:"status" example:"completed"`                          // Sale status
+	BusinessName string                `json:"business_name" example:"Grand Hotel"`                 // Business the sale was made in
+	LogoURL      string                `json:"logo_url" example:"https://cdn.example.com/logo.png"` // Business logo
+	Motto        string                `json:"motto" example:"Rest well"`                           // Business motto
+	Currency     string                `json:"currency" example:"NGN"`                              // Currency of the amounts
+	StoreName    string                `json:"store_name" example:"Main Bar"`                       // Store the sale was made from
+	Address      string                `json:"address" example:"12 Marina Road"`                    // Store address
+	Phone        string                `json:"phone" example:"+2348000000000"`                      // Store phone
+	Cashier      string                `json:"cashier" example:"Jane Doe"`                          // Name of the cashier
+	Items        []ReceiptLineResponse `json:"items"`                                               // Lines of the sale
+	Subtotal     string                `json:"subtotal" example:"51.98"`                            // Amount before discount and tax
+	Discount     string                `json:"discount" example:"0.00"`                             // Discount amount
+	Tax          string                `json:"tax" example:"4.29"`                                  // Tax amount
+	Total        string                `json:"total" example:"56.27"`                               // Total paid
+	Payments     []SalePaymentResponse `json:"payments"`                                            // How the sale was paid
+	CreatedAt    time.Time             `json:"created_at" example:"2024-01-15T10:30:00Z"`           // When the sale was made
+	// Total in the currency of the Accept-Currency header
+	ConvertedTotal *ConvertedAmountResponse `json:"converted_total,omitempty"`
+}
+
+// GetReceipt godoc
+// @Summary Get sale receipt
+// @Description Get the receipt of a sale with the branding of its business, as JSON, as plain text laid out for 80mm thermal printers, or as a PDF of the same width with the business logo
+// @Tags pos
+// @Produce json
+// @Produce plain
+// @Produce application/pdf
+// @Security BearerAuth
+// @Param id path int true "Sale ID"
+// @Param format query string false "json, text or pdf, defaults to json"
+// @Param Accept-Currency header string false "Currency to also give the total in"
+// @Param X-Business-ID header int false "Business to scope the sale to, defaults to the user's first business"
+// @Success 200 {object} ReceiptResponse "Receipt retrieved successfully"
+// @Failure 400 {object} ErrorResponse "Bad request"
+// @Failure 401 {object} ErrorResponse "Unauthorized"
+// @Failure 403 {object} ErrorResponse "Forbidden"
+// @Failure 404 {object} ErrorResponse "Sale not found"
+// @Failure 500 {object} ErrorResponse "Internal server error"
+// @Router /pos/sales/{id}/receipt [get]
+func (h *Handler) getReceipt(c *gin.Context) {
+	claims, ok := jwt.GetUserFromContext(c)
+	if !ok {
+		h.logger.Errorf("could not get user from context")
+		utils.ErrorResponse(c, 500, utils.SERVERERROR)
+		return
+	}
+
+	saleID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		utils.ErrorResponse(c, 400, utils.INVALID_REQUEST_DATA)
+		return
+	}
+
+	format := c.DefaultQuery("format", "json")
+	if format != "json" && format != "text" && format != "pdf" {
+		utils.ErrorResponse(c, 400, "format must be json, text or pdf")
+		return
+	}
+
+	scope, ok := h.businessScope(c, claims)
+	if !ok {
+		return
+	}
+
+	convertTo, ok := h.acceptCurrency(c)
+	if !ok {
+		return
+	}
+
+	receipt, err := h.service.GetReceipt(c, int32(saleID), scope)
+	if err != nil {
+		if errors.Is(err, ErrSaleNotFound) {
+			utils.ErrorResponse(c, 404, err.Error())
+			return
+		}
+		h.logger.Errorf("error getting receipt of sale %d: %v", saleID, err)
+		utils.ErrorResponse(c, 500, utils.SERVERERROR)
+		return
+	}
+
+	if format == "text" {
+		c.String(200, receipt.Text())
+		return
+	}
+	if format == "pdf" {
+		doc, err := receipt.PDF(h.receiptLogo(c, receipt.LogoURL))
+		if err != nil {
+			h.logger.Errorf("error making pdf receipt of sale %d: %v", saleID, err)
+			utils.ErrorResponse(c, 500, utils.SERVERERROR)
+			return
+		}
+		c.Header("Content-Type", "application/pdf")
+		c.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="receipt-%d.pdf"`, receipt.SaleID))
+		c.Status(200)
+		if err := doc.Output(c.Writer); err != nil {
+			// part of the file may have been sent, all we can do is stop
+			c.Error(err)
+		}
+		return
+	}
+
+	response := ReceiptResponse{
+		SaleID:       receipt.SaleID,
+		Status:       receipt.Status,
+		BusinessName: receipt.BusinessName,
+		LogoURL:      receipt.LogoURL,
+		Motto:        receipt.Motto,
+		Currency:     receipt.Currency,
+		StoreName:    receipt.StoreName,
+		Address:      receipt.Address,
+		Phone:        receipt.Phone,
+		Cashier:      receipt.Cashier,
+		Items:        make([]ReceiptLineResponse, 0, len(receipt.Lines)),
+		Subtotal:     receipt.Subtotal,
+		Discount:     receipt.Discount,
+		Tax:          receipt.Tax,
+		Total:        receipt.Total,
+		Payments:     make([]SalePaymentResponse, 0, len(receipt.Payments)),
+		CreatedAt:    receipt.CreatedAt,
+	}
+	if convertTo != "" {
+		total, _ := strconv.ParseFloat(receipt.Total, 64)
+		response.ConvertedTotal = h.convertTotal(c, total, receipt.Currency, convertTo)
+	}
+	for _, line := range receipt.Lines {
+		response.Items = append(response.Items, ReceiptLineResponse{
+			ItemID:    line.VariationID,
+			Name:      line.Name,
+			Quantity:  line.Quantity,
+			UnitPrice: line.UnitPrice,
+			Amount:    line.Amount,
+		})
+	}
+	for _, payment := range receipt.Payments {
+		response.Payments = append(response.Payments, SalePaymentResponse(payment))
+	}
+
+	utils.SuccessResponse(c, 200, "receipt", response)
+}
+
+// receiptLogo reads the logo of a business from the uploads for its PDF
+// receipts. Receipts are printed without the logo when it can't be read, so
+// failures are only logged.
+func (h *Handler) receiptLogo(c *gin.Context, logoURL string) []byte {
+	if logoURL == "" || h.files == nil {
+		return nil
+	}
+	file, err := h.files.Open(c.Request.Context(), logoURL)
+	if err != nil {
+		if !errors.Is(err, storage.ErrNotFound) {
+			h.logger.Warnf("failed to open logo %s: %v", logoURL, err)
+		}
+		return nil
+	}
+	defer file.Close()
+
+	// logos are never larger than an upload may be
+	logo, err := io.ReadAll(io.LimitReader(file, h.config.UploadMaxSize+1))
+	if err != nil {
+		h.logger.Warnf("failed to read logo %s: %v", logoURL, err)
+		return nil
+	}
+	if int64(len(logo)) > h.config.UploadMaxSize {
+		h.logger.Warnf("logo %s is larger than %d bytes", logoURL, h.config.UploadMaxSize)
+		return nil
+	}
+	return logo
+}
+
+// PaymentMethodTotalResponse represents the sales paid with one method
+// @Description Payment method totals
+type PaymentMethodTotalResponse struct {
+	Method       string `json:"method" example:"cash"`     // Payment method
+	Transactions int32  `json:"transactions" example:"14"` // Sales with a payment by this method
+	Amount       string `json:"amount" example:"820.50"`   // Amount paid with this method
+}
+
+// CategoryTotalResponse represents the sales of one category
+// @Description Category totals
+type CategoryTotalResponse struct {
+	CategoryID int32  `json:"category_id" example:"1"` // Category ID
+	Name       string `json:"name" example:"Drinks"`   // Category name
+	Quantity   int32  `json:"quantity" example:"40"`   // Units sold
+	Amount     string `json:"amount" example:"410.00"` // Amount sold before discount and tax
+}
+
+// DailyReportResponse represents the end of day summary of sales
+// @Description Daily sales report (Z-report) payload
+type DailyReportResponse struct {
+	Date            string                       `json:"date" example:"2024-01-15"`       // Day reported
+	Timezone        string                       `json:"timezone" example:"Africa/Lagos"` // Timezone the day is taken in
+	Transactions    int32                        `json:"transactions" example:"20"`       // Number of sales
+	GrossSales      string                       `json:"gross_sales" example:"1050.00"`   // Sales before discount and tax
+	Discounts       string                       `json:"discounts" example:"50.00"`       // Discounts given
+	Tax             string                       `json:"tax" example:"75.00"`             // Tax collected
+	NetSales        string                       `json:"net_sales" example:"1000.00"`     // Sales after discount, before tax
+	Total           string                       `json:"total" example:"1075.00"`         // Amount charged
+	AverageTicket   string                       `json:"average_ticket" example:"53.75"`  // Average amount charged per sale
+	ByPaymentMethod []PaymentMethodTotalResponse `json:"by_payment_method"`               // Totals by payment method
+	ByCategory      []CategoryTotalResponse      `json:"by_category"`                     // Totals by item category
+}
+
+// GetDailyReport godoc
+// @Summary Daily sales report
+// @Description Get the end of day totals (Z-report) of a store or of every store in the business. The day is taken in the business timezone and defaults to today.
+// @Tags pos
+// @Produce json
+// @Security BearerAuth
+// @Param date query string false "Day to report (YYYY-MM-DD), defaults to today"
+// @Param store_id query int false "Store to report, defaults to every store"
+// @Param X-Business-ID header int false "Business to report on, defaults to the user's first business"
+// @Success 200 {object} DailyReportResponse "Daily report retrieved successfully"
+// @Failure 400 {object} ErrorResponse "Bad request"
+// @Failure 401 {object} ErrorResponse "Unauthorized"
+// @Failure 403 {object} ErrorResponse "Forbidden"
+// @Failure 404 {object} ErrorResponse "Store not found"
+// @Failure 500 {object} ErrorResponse "Internal server error"
+// @Router /pos/reports/daily [get]
+func (h *Handler) getDailyReport(c *gin.Context) {
+	claims, ok := jwt.GetUserFromContext(c)
+	if !ok {
+		h.logger.Errorf("could not get user from context")
+		utils.ErrorResponse(c, 500, utils.SERVERERROR)
+		return
+	}
+
+	var storeID sql.NullInt32
+	if raw := c.Query("store_id"); raw != "" {
+		id, err := strconv.Atoi(raw)
+		if err != nil || id <= 0 {
+			utils.ErrorResponse(c, 400, "invalid store_id")
+			return
+		}
+		storeID = sql.NullInt32{Int32: int32(id), Valid: true}
+	}
+	// staff assigned to stores only see the report of one of theirs
+	if storeID.Valid {
+		if !auth.RequireStore(c, storeID.Int32) {
+			return
+		}
+	} else if auth.GetStoreScope(c).Restricted {
+		utils.ErrorResponse(c, 400, "store_id is required")
+		return
+	}
+
+	scope, ok := h.businessScope(c, claims)
+	if !ok {
+		return
+	}
+
+	report, err := h.service.DailyReport(c, DailyReportParams{
+		Date:       c.Query("date"),
+		StoreID:    storeID,
+		BusinessID: scope,
+	})
+	if err != nil {
+		switch {
+		case errors.Is(err, ErrInvalidReportDate):
+			utils.ErrorResponse(c, 400, err.Error())
+		case errors.Is(err, ErrStoreNotFound):
+			utils.ErrorResponse(c, 404, err.Error())
+		default:
+			h.logger.Errorf("error building daily report: %v", err)
+			utils.ErrorResponse(c, 500, utils.SERVERERROR)
+		}
+		return
 	}
 
-	discountedTotal := subtotal - discount
-	if discountedTotal < 0 {
-		discountedTotal = 0
+	response := DailyReportResponse{
+		Date:            report.Date,
+		Timezone:        report.Timezone,
+		Transactions:    report.Transactions,
+		GrossSales:      report.GrossSales,
+		Discounts:       report.Discounts,
+		Tax:             report.Tax,
+		NetSales:        report.NetSales,
+		Total:           report.Total,
+		AverageTicket:   report.AverageTicket,
+		ByPaymentMethod: make([]PaymentMethodTotalResponse, 0, len(report.ByPaymentMethod)),
+		ByCategory:      make([]CategoryTotalResponse, 0, len(report.ByCategory)),
 	}
+	for _, m := range report.ByPaymentMethod {
+		response.ByPaymentMethod = append(response.ByPaymentMethod, PaymentMethodTotalResponse(m))
+	}
+	for _, category := range report.ByCategory {
+		response.ByCategory = append(response.ByCategory, CategoryTotalResponse(category))
+	}
+
+	utils.SuccessResponse(c, 200, "daily report", response)
+}
 
-	tax := discountedTotal * (taxRate / 100)
-	return discountedTotal + tax
+// MarginTotalsResponse represents the margin of a set of sales
+// @Description Margin totals
+type MarginTotalsResponse struct {
+	Quantity         int32  `json:"quantity" example:"40"`          // Units sold, net of refunds
+	UncostedQuantity int32  `json:"uncosted_quantity" example:"0"`  // Units sold while their cost was unknown, they add nothing to cost
+	Revenue          string `json:"revenue" example:"410.00"`       // Amount sold before discount and tax
+	Cost             string `json:"cost" example:"220.00"`          // Cost of goods sold, at the cost of each sale
+	Margin           string `json:"margin" example:"190.00"`        // Revenue minus cost
+	MarginPercent    string `json:"margin_percent" example:"46.34"` // Margin as a percentage of revenue
 }
 
-func calculateTax(items []SaleItem, taxRate float64) float64 {
-	subtotal := 0.0
+// ItemMarginResponse represents the margin of one item
+// @Description Item margin
+type ItemMarginResponse struct {
+	ItemID int32  `json:"item_id" example:"1"` // Item ID
+	Name   string `json:"name" example:"Cola"` // Item name
+	MarginTotalsResponse
+}
+
+// CategoryMarginResponse represents the margin of one category
+// @Description Category margin
+type CategoryMarginResponse struct {
+	CategoryID int32  `json:"category_id" example:"1"` // Category ID, 0 for items without one
+	Name       string `json:"name" example:"Drinks"`   // Category name
+	MarginTotalsResponse
+	Items []ItemMarginResponse `json:"items"` // Margin of each item sold
+}
+
+// MarginReportResponse represents the gross margin of sales over a period
+// @Description Margin report payload
+type MarginReportResponse struct {
+	StartDate string `json:"start_date" example:"2024-01-01"` // First day reported
+	EndDate   string `json:"end_date" example:"2024-01-31"`   // Last day reported
+	Timezone  string `json:"timezone" example:"Africa/Lagos"` // Timezone the days are taken in
+	MarginTotalsResponse
+	ByCategory []CategoryMarginResponse `json:"by_category"` // Margin by item category
+}
+
+// GetMarginReport godoc
+// @Summary Margin report
+// @Description Get the revenue, cost of goods sold and gross margin of the sales over a range of days, per category and item, of a store or of every store in the business. Cost is what each variation cost when it was sold. Days are taken in the business timezone and default to today.
+// @Tags pos
+// @Produce json
+// @Security BearerAuth
+// @Param start_date query string false "First day to report (YYYY-MM-DD), defaults to today"
+// @Param end_date query string false "Last day to report (YYYY-MM-DD), defaults to today"
+// @Param store_id query int false "Store to report, defaults to every store"
+// @Param X-Business-ID header int false "Business to report on, defaults to the user's first business"
+// @Success 200 {object} MarginReportResponse "Margin report retrieved successfully"
+// @Failure 400 {object} ErrorResponse "Bad request"
+// @Failure 401 {object} ErrorResponse "Unauthorized"
+// @Failure 403 {object} ErrorResponse "Forbidden"
+// @Failure 404 {object} ErrorResponse "Store not found"
+// @Failure 500 {object} ErrorResponse "Internal server error"
+// @Router /pos/reports/margin [get]
+func (h *Handler) getMarginReport(c *gin.Context) {
+	claims, ok := jwt.GetUserFromContext(c)
+	if !ok {
+		h.logger.Errorf("could not get user from context")
+		utils.ErrorResponse(c, 500, utils.SERVERERROR)
+		return
+	}
+
+	var storeID sql.NullInt32
+	if raw := c.Query("store_id"); raw != "" {
+		id, err := strconv.Atoi(raw)
+		if err != nil || id <= 0 {
+			utils.ErrorResponse(c, 400, "invalid store_id")
+			return
+		}
+		storeID = sql.NullInt32{Int32: int32(id), Valid: true}
+	}
+	// staff assigned to stores only see the report of one of theirs
+	if storeID.Valid {
+		if !auth.RequireStore(c, storeID.Int32) {
+			return
+		}
+	} else if auth.GetStoreScope(c).Restricted {
+		utils.ErrorResponse(c, 400, "store_id is required")
+		return
+	}
+
+	scope, ok := h.businessScope(c, claims)
+	if !ok {
+		return
+	}
+
+	report, err := h.service.MarginReport(c, MarginReportParams{
+		StartDate:  c.Query("start_date"),
+		EndDate:    c.Query("end_date"),
+		StoreID:    storeID,
+		BusinessID: scope,
+	})
+	if err != nil {
+		switch {
+		case errors.Is(err, ErrInvalidReportDate), errors.Is(err, ErrInvalidReportRange):
+			utils.ErrorResponse(c, 400, err.Error())
+		case errors.Is(err, ErrStoreNotFound):
+			utils.ErrorResponse(c, 404, err.Error())
+		default:
+			h.logger.Errorf("error building margin report: %v", err)
+			utils.ErrorResponse(c, 500, utils.SERVERERROR)
+		}
+		return
+	}
+
+	response := MarginReportResponse{
+		StartDate:            report.StartDate,
+		EndDate:              report.EndDate,
+		Timezone:             report.Timezone,
+		MarginTotalsResponse: MarginTotalsResponse(report.MarginTotals),
+		ByCategory:           make([]CategoryMarginResponse, 0, len(report.ByCategory)),
+	}
+	for _, category := range report.ByCategory {
+		categoryResponse := CategoryMarginResponse{
+			CategoryID:           category.CategoryID,
+			Name:                 category.Name,
+			MarginTotalsResponse: MarginTotalsResponse(category.MarginTotals),
+			Items:                make([]ItemMarginResponse, 0, len(category.Items)),
+		}
+		for _, item := range category.Items {
+			categoryResponse.Items = append(categoryResponse.Items, ItemMarginResponse{
+				ItemID:               item.ItemID,
+				Name:                 item.Name,
+				MarginTotalsResponse: MarginTotalsResponse(item.MarginTotals),
+			})
+		}
+		response.ByCategory = append(response.ByCategory, categoryResponse)
+	}
+
+	utils.SuccessResponse(c, 200, "margin report", response)
+}
+
+// LoyaltyBalanceResponse represents a customer's loyalty points
+// @Description Loyalty balance response payload
+type LoyaltyBalanceResponse struct {
+	CustomerID int32 `json:"customer_id" example:"1"` // Customer ID
+	Points     int32 `json:"points" example:"120"`    // Points currently held
+}
+
+// GetLoyaltyBalance godoc
+// @Summary Get loyalty balance
+// @Description Get the loyalty points a customer currently holds in the business, from the sales they made there
+// @Tags pos
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "Customer ID"
+// @Param X-Business-ID header int false "Business to get the balance in, defaults to the user's first business"
+// @Success 200 {object} LoyaltyBalanceResponse "Loyalty balance retrieved successfully"
+// @Failure 400 {object} ErrorResponse "Bad request"
+// @Failure 401 {object} ErrorResponse "Unauthorized"
+// @Failure 403 {object} ErrorResponse "Forbidden"
+// @Failure 404 {object} ErrorResponse "Customer not found in the business"
+// @Failure 500 {object} ErrorResponse "Internal server error"
+// @Router /pos/customers/{id}/loyalty [get]
+func (h *Handler) getLoyaltyBalance(c *gin.Context) {
+	claims, ok := jwt.GetUserFromContext(c)
+	if !ok {
+		h.logger.Errorf("could not get user from context")
+		utils.ErrorResponse(c, 500, utils.SERVERERROR)
+		return
+	}
+
+	customerID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		utils.ErrorResponse(c, 400, utils.INVALID_REQUEST_DATA)
+		return
+	}
+
+	scope, ok := h.businessScope(c, claims)
+	if !ok {
+		return
+	}
+
+	points, err := h.service.GetLoyaltyBalance(c, int32(customerID), scope)
+	if err != nil {
+		if errors.Is(err, ErrCustomerNotFound) {
+			utils.ErrorResponse(c, 404, err.Error())
+			return
+		}
+		h.logger.Errorf("error getting loyalty balance of customer %d: %v", customerID, err)
+		utils.ErrorResponse(c, 500, utils.SERVERERROR)
+		return
+	}
+
+	utils.SuccessResponse(c, 200, "loyalty balance", LoyaltyBalanceResponse{
+		CustomerID: int32(customerID),
+		Points:     points,
+	})
+}
+
+// LoyaltyRuleRequest represents the points earning rule of a business
+// @Description Loyalty rule request payload
+type LoyaltyRuleRequest struct {
+	BusinessID int32   `json:"business_id" binding:"required" example:"1"`                       // Business the rule applies to
+	EarnRate   float64 `json:"earn_rate" binding:"gte=0" example:"1"`                            // Points earned per currency unit
+	Rounding   string  `json:"rounding" binding:"required,oneof=down nearest up" example:"down"` // How fractional points are rounded
+}
+
+// LoyaltyRuleResponse represents the points earning rule of a business
+// @Description Loyalty rule response payload
+type LoyaltyRuleResponse struct {
+	BusinessID int32  `json:"business_id" example:"1"`
+	EarnRate   string `json:"earn_rate" example:"1.0000"`
+	Rounding   string `json:"rounding" example:"down"`
+}
+
+// SetLoyaltyRule godoc
+// @Summary Set loyalty rule
+// @Description Set how many points customers earn per currency unit spent in a business and how they are rounded
+// @Tags pos
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param body body LoyaltyRuleRequest true "Loyalty rule"
+// @Success 200 {object} LoyaltyRuleResponse "Loyalty rule saved"
+// @Failure 400 {object} ErrorResponse "Bad request"
+// @Failure 401 {object} ErrorResponse "Unauthorized"
+// @Failure 403 {object} ErrorResponse "Forbidden"
+// @Failure 500 {object} ErrorResponse "Internal server error"
+// @Router /pos/loyalty/rule [put]
+func (h *Handler) setLoyaltyRule(c *gin.Context) {
+	claims, ok := jwt.GetUserFromContext(c)
+	if !ok {
+		h.logger.Errorf("could not get user from context")
+		utils.ErrorResponse(c, 500, utils.SERVERERROR)
+		return
+	}
+
+	var req LoyaltyRuleRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		h.logger.Errorf("error binding loyalty rule request data: %v", err)
+		utils.ErrorResponse(c, 400, utils.INVALID_REQUEST_DATA)
+		return
+	}
+
+	if _, err := h.service.GetOwnedBusinessID(c, db.GetOwnedBusinessIDParams{
+		OwnerID:    int32(claims.UserID),
+		BusinessID: sql.NullInt32{Int32: req.BusinessID, Valid: true},
+	}); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			utils.ErrorResponse(c, 403, "you do not own this business")
+			return
+		}
+		h.logger.Errorf("error checking business ownership: %v", err)
+		utils.ErrorResponse(c, 500, utils.SERVERERROR)
+		return
+	}
+
+	rule, err := h.service.SetLoyaltyRule(c, db.UpsertLoyaltyRuleParams{
+		BusinessID: req.BusinessID,
+		EarnRate:   strconv.FormatFloat(req.EarnRate, 'f', 4, 64),
+		Rounding:   req.Rounding,
+	})
+	if err != nil {
+		h.logger.Errorf("error saving loyalty rule: %v", err)
+		utils.ErrorResponse(c, 500, utils.SERVERERROR)
+		return
+	}
+
+	_, err = h.service.LogActivity(c, db.LogActivityParams{
+		UserID:     int32(claims.UserID),
+		Action:     "Updated Loyalty Rule",
+		EntityType: "Business",
+		EntityID:   rule.BusinessID,
+		Details:    utils.WriteActivityDetails(claims.Username, claims.Email, fmt.Sprintf("Set loyalty earn rate to %s rounded %s", rule.EarnRate, rule.Rounding), rule.UpdatedAt.Time),
+		IpAddress:  sql.NullString{Valid: true, String: utils.GetClientIP(c)},
+		UserAgent:  sql.NullString{Valid: true, String: c.Request.UserAgent()},
+	})
+	if err != nil {
+		h.logger.Warnf("error logging loyalty rule activity: %v", err)
+	}
+
+	utils.SuccessResponse(c, 200, "loyalty rule saved", LoyaltyRuleResponse{
+		BusinessID: rule.BusinessID,
+		EarnRate:   rule.EarnRate,
+		Rounding:   rule.Rounding,
+	})
+}
+
+// DiscountRequest represents the settings of a discount rule
+// @Description Discount rule request payload
+type DiscountRequest struct {
+	Name       string     `json:"name" binding:"required,max=100" example:"Happy hour"`                 // Name shown in the discount breakdown of a sale
+	Kind       string     `json:"kind" binding:"required,oneof=percentage fixed" example:"percentage"`  // Percentage or fixed amount off
+	Value      float64    `json:"value" binding:"required,gt=0" example:"10"`                           // Percentage, at most 100, or amount taken off
+	Scope      string     `json:"scope" binding:"required,oneof=sale item category" example:"category"` // What the discount applies to
+	ItemID     *int32     `json:"item_id" example:"4"`                                                  // Item of an item discount, it covers all its variations
+	CategoryID *int32     `json:"category_id" example:"2"`                                              // Category of a category discount
+	MinSpend   *float64   `json:"min_spend" binding:"omitempty,gte=0" example:"50"`                     // Subtotal a sale needs before the discount applies
+	StartsAt   *time.Time `json:"starts_at" example:"2024-01-15T17:00:00Z"`                             // When the discount starts, leave empty to start now
+	EndsAt     *time.Time `json:"ends_at" example:"2024-01-15T19:00:00Z"`                               // When the discount ends, leave empty to run until disabled
+	IsActive   *bool      `json:"is_active" example:"true"`                                             // Whether the discount is applied, defaults to true
+}
+
+// CreateDiscountRequest represents the request payload for creating a
+// discount rule
+// @Description Create discount rule request payload
+type CreateDiscountRequest struct {
+	BusinessID int32 `json:"business_id" binding:"required" example:"1"` // Business the discount belongs to
+	DiscountRequest
+}
+
+// DiscountResponse represents a discount rule
+// @Description Discount rule response payload
+type DiscountResponse struct {
+	ID         int32      `json:"id" example:"3"`
+	BusinessID int32      `json:"business_id" example:"1"`
+	Name       string     `json:"name" example:"Happy hour"`
+	Kind       string     `json:"kind" example:"percentage"`
+	Value      string     `json:"value" example:"10.00"`
+	Scope      string     `json:"scope" example:"category"`
+	ItemID     *int32     `json:"item_id" example:"4"`
+	CategoryID *int32     `json:"category_id" example:"2"`
+	MinSpend   *string    `json:"min_spend" example:"50.00"`
+	StartsAt   *time.Time `json:"starts_at" example:"2024-01-15T17:00:00Z"`
+	EndsAt     *time.Time `json:"ends_at" example:"2024-01-15T19:00:00Z"`
+	IsActive   bool       `json:"is_active" example:"true"`
+	CreatedAt  time.Time  `json:"created_at" example:"2024-01-15T10:30:00Z"`
+	UpdatedAt  time.Time  `json:"updated_at" example:"2024-01-15T10:30:00Z"`
+}
+
+// discountParams converts the nullable settings of a discount request to
+// what the queries take.
+func discountParams(req DiscountRequest) (itemID, categoryID sql.NullInt32, minSpend sql.NullString, startsAt, endsAt sql.NullTime, isActive bool) {
+	if req.ItemID != nil {
+		itemID = sql.NullInt32{Int32: *req.ItemID, Valid: true}
+	}
+	if req.CategoryID != nil {
+		categoryID = sql.NullInt32{Int32: *req.CategoryID, Valid: true}
+	}
+	if req.MinSpend != nil {
+		minSpend = sql.NullString{String: formatAmount(*req.MinSpend), Valid: true}
+	}
+	if req.StartsAt != nil {
+		startsAt = sql.NullTime{Time: *req.StartsAt, Valid: true}
+	}
+	if req.EndsAt != nil {
+		endsAt = sql.NullTime{Time: *req.EndsAt, Valid: true}
+	}
+	isActive = req.IsActive == nil || *req.IsActive
+	return
+}
+
+func toDiscountResponse(discount db.Discount) DiscountResponse {
+	response := DiscountResponse{
+		ID:         discount.ID,
+		BusinessID: discount.BusinessID,
+		Name:       discount.Name,
+		Kind:       discount.Kind,
+		Value:      discount.Value,
+		Scope:      discount.Scope,
+		IsActive:   discount.IsActive,
+		CreatedAt:  discount.CreatedAt.Time,
+		UpdatedAt:  discount.UpdatedAt.Time,
+	}
+	if discount.ItemID.Valid {
+		response.ItemID = &discount.ItemID.Int32
+	}
+	if discount.CategoryID.Valid {
+		response.CategoryID = &discount.CategoryID.Int32
+	}
+	if discount.MinSpend.Valid {
+		response.MinSpend = &discount.MinSpend.String
+	}
+	if discount.StartsAt.Valid {
+		response.StartsAt = &discount.StartsAt.Time
+	}
+	if discount.EndsAt.Valid {
+		response.EndsAt = &discount.EndsAt.Time
+	}
+	return response
+}
+
+// discountError answers a failed discount request, reporting whether err was
+// one it knows.
+func discountError(c *gin.Context, err error) bool {
+	switch {
+	case errors.Is(err, ErrDiscountNotFound):
+		utils.ErrorResponse(c, 404, err.Error())
+	case errors.Is(err, ErrDiscountPercentage),
+		errors.Is(err, ErrDiscountTarget),
+		errors.Is(err, ErrDiscountWindow),
+		errors.Is(err, ErrDiscountItemNotFound),
+		errors.Is(err, ErrDiscountCategoryNotFound):
+		utils.ErrorResponse(c, 400, err.Error())
+	default:
+		return false
+	}
+	return true
+}
+
+func (h *Handler) logDiscountActivity(c *gin.Context, claims *jwt.Claims, action string, discount db.Discount, details string) {
+	_, err := h.service.LogActivity(c, db.LogActivityParams{
+		UserID:     int32(claims.UserID),
+		Action:     action,
+		EntityType: "Discount",
+		EntityID:   discount.ID,
+		Details:    utils.WriteActivityDetails(claims.Username, claims.Email, details, time.Now()),
+		IpAddress:  sql.NullString{Valid: true, String: utils.GetClientIP(c)},
+		UserAgent:  sql.NullString{Valid: true, String: c.Request.UserAgent()},
+	})
+	if err != nil {
+		h.logger.Warnf("error logging discount activity: %v", err)
+	}
+}
+
+// CreateDiscount godoc
+// @Summary Create discount rule
+// @Description Create a discount rule of a business. It takes a percentage or a fixed amount off the whole sale, the lines of an item or the lines of a category, once the sale spends at least the minimum and while it is running. Rules are applied to sales automatically.
+// @Tags pos
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param body body CreateDiscountRequest true "Discount rule"
+// @Success 201 {object} DiscountResponse "Discount rule created"
+// @Failure 400 {object} ErrorResponse "Bad request"
+// @Failure 401 {object} ErrorResponse "Unauthorized"
+// @Failure 403 {object} ErrorResponse "Forbidden"
+// @Failure 500 {object} ErrorResponse "Internal server error"
+// @Router /pos/discounts [post]
+func (h *Handler) createDiscount(c *gin.Context) {
+	claims, ok := jwt.GetUserFromContext(c)
+	if !ok {
+		h.logger.Errorf("could not get user from context")
+		utils.ErrorResponse(c, 500, utils.SERVERERROR)
+		return
+	}
+
+	var req CreateDiscountRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.BindingErrorResponse(c, err)
+		return
+	}
+
+	if _, err := h.service.GetOwnedBusinessID(c, db.GetOwnedBusinessIDParams{
+		OwnerID:    int32(claims.UserID),
+		BusinessID: sql.NullInt32{Int32: req.BusinessID, Valid: true},
+	}); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			utils.ErrorResponse(c, 403, "you do not own this business")
+			return
+		}
+		h.logger.Errorf("error checking business ownership: %v", err)
+		utils.ErrorResponse(c, 500, utils.SERVERERROR)
+		return
+	}
+
+	itemID, categoryID, minSpend, startsAt, endsAt, isActive := discountParams(req.DiscountRequest)
+	discount, err := h.service.CreateDiscount(c, db.CreateDiscountParams{
+		BusinessID: req.BusinessID,
+		Name:       req.Name,
+		Kind:       req.Kind,
+		Value:      formatAmount(req.Value),
+		Scope:      req.Scope,
+		ItemID:     itemID,
+		CategoryID: categoryID,
+		MinSpend:   minSpend,
+		StartsAt:   startsAt,
+		EndsAt:     endsAt,
+		IsActive:   isActive,
+		CreatedBy:  int32(claims.UserID),
+	})
+	if err != nil {
+		if discountError(c, err) {
+			return
+		}
+		h.logger.Errorf("error creating discount: %v", err)
+		utils.ErrorResponse(c, 500, utils.SERVERERROR)
+		return
+	}
+
+	h.logDiscountActivity(c, claims, "Created Discount", discount, fmt.Sprintf("Created %s discount %s of %s on %s", discount.Kind, discount.Name, discount.Value, discount.Scope))
+
+	utils.SuccessResponse(c, 201, "discount created", toDiscountResponse(discount))
+}
+
+// ListDiscounts godoc
+// @Summary List discount rules
+// @Description List the discount rules of the business
+// @Tags pos
+// @Produce json
+// @Security BearerAuth
+// @Param X-Business-ID header int false "Business to list the discounts of, defaults to the first one owned"
+// @Success 200 {array} DiscountResponse "Discount rules"
+// @Failure 401 {object} ErrorResponse "Unauthorized"
+// @Failure 403 {object} ErrorResponse "Forbidden"
+// @Failure 500 {object} ErrorResponse "Internal server error"
+// @Router /pos/discounts [get]
+func (h *Handler) listDiscounts(c *gin.Context) {
+	claims, ok := jwt.GetUserFromContext(c)
+	if !ok {
+		h.logger.Errorf("could not get user from context")
+		utils.ErrorResponse(c, 500, utils.SERVERERROR)
+		return
+	}
+
+	scope, ok := h.businessScope(c, claims)
+	if !ok {
+		return
+	}
+
+	discounts, err := h.service.ListDiscounts(c, scope)
+	if err != nil {
+		h.logger.Errorf("error listing discounts: %v", err)
+		utils.ErrorResponse(c, 500, utils.SERVERERROR)
+		return
+	}
+
+	response := make([]DiscountResponse, 0, len(discounts))
+	for _, discount := range discounts {
+		response = append(response, toDiscountResponse(discount))
+	}
+
+	utils.SuccessResponse(c, 200, "discounts", response)
+}
+
+// GetDiscount godoc
+// @Summary Get discount rule
+// @Description Get a discount rule of the business
+// @Tags pos
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "Discount ID"
+// @Success 200 {object} DiscountResponse "Discount rule"
+// @Failure 400 {object} ErrorResponse "Bad request"
+// @Failure 401 {object} ErrorResponse "Unauthorized"
+// @Failure 404 {object} ErrorResponse "Discount not found"
+// @Failure 500 {object} ErrorResponse "Internal server error"
+// @Router /pos/discounts/{id} [get]
+func (h *Handler) getDiscount(c *gin.Context) {
+	claims, ok := jwt.GetUserFromContext(c)
+	if !ok {
+		h.logger.Errorf("could not get user from context")
+		utils.ErrorResponse(c, 500, utils.SERVERERROR)
+		return
+	}
+
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		utils.ErrorResponse(c, 400, utils.INVALID_REQUEST_DATA)
+		return
+	}
+
+	scope, ok := h.businessScope(c, claims)
+	if !ok {
+		return
+	}
+
+	discount, err := h.service.GetDiscount(c, db.GetDiscountParams{ID: int32(id), BusinessID: scope})
+	if err != nil {
+		if discountError(c, err) {
+			return
+		}
+		h.logger.Errorf("error getting discount %d: %v", id, err)
+		utils.ErrorResponse(c, 500, utils.SERVERERROR)
+		return
+	}
+
+	utils.SuccessResponse(c, 200, "discount", toDiscountResponse(discount))
+}
+
+// UpdateDiscount godoc
+// @Summary Update discount rule
+// @Description Replace the settings of a discount rule of the business. Sales already made keep the discounts they got.
+// @Tags pos
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "Discount ID"
+// @Param body body DiscountRequest true "Discount rule"
+// @Success 200 {object} DiscountResponse "Discount rule updated"
+// @Failure 400 {object} ErrorResponse "Bad request"
+// @Failure 401 {object} ErrorResponse "Unauthorized"
+// @Failure 404 {object} ErrorResponse "Discount not found"
+// @Failure 500 {object} ErrorResponse "Internal server error"
+// @Router /pos/discounts/{id} [put]
+func (h *Handler) updateDiscount(c *gin.Context) {
+	claims, ok := jwt.GetUserFromContext(c)
+	if !ok {
+		h.logger.Errorf("could not get user from context")
+		utils.ErrorResponse(c, 500, utils.SERVERERROR)
+		return
+	}
+
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		utils.ErrorResponse(c, 400, utils.INVALID_REQUEST_DATA)
+		return
+	}
+
+	var req DiscountRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.BindingErrorResponse(c, err)
+		return
+	}
+
+	scope, ok := h.businessScope(c, claims)
+	if !ok {
+		return
+	}
+
+	itemID, categoryID, minSpend, startsAt, endsAt, isActive := discountParams(req)
+	discount, err := h.service.UpdateDiscount(c, scope, db.UpdateDiscountParams{
+		ID:         int32(id),
+		Name:       req.Name,
+		Kind:       req.Kind,
+		Value:      formatAmount(req.Value),
+		Scope:      req.Scope,
+		ItemID:     itemID,
+		CategoryID: categoryID,
+		MinSpend:   minSpend,
+		StartsAt:   startsAt,
+		EndsAt:     endsAt,
+		IsActive:   isActive,
+	})
+	if err != nil {
+		if discountError(c, err) {
+			return
+		}
+		h.logger.Errorf("error updating discount %d: %v", id, err)
+		utils.ErrorResponse(c, 500, utils.SERVERERROR)
+		return
+	}
+
+	h.logDiscountActivity(c, claims, "Updated Discount", discount, fmt.Sprintf("Updated discount %s to %s of %s on %s", discount.Name, discount.Kind, discount.Value, discount.Scope))
+
+	utils.SuccessResponse(c, 200, "discount updated", toDiscountResponse(discount))
+}
+
+// DeleteDiscount godoc
+// @Summary Delete discount rule
+// @Description Delete a discount rule of the business. Sales it was applied to keep it in their discount breakdown.
+// @Tags pos
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "Discount ID"
+// @Success 200 {string} string "discount deleted"
+// @Failure 400 {object} ErrorResponse "Bad request"
+// @Failure 401 {object} ErrorResponse "Unauthorized"
+// @Failure 404 {object} ErrorResponse "Discount not found"
+// @Failure 500 {object} ErrorResponse "Internal server error"
+// @Router /pos/discounts/{id} [delete]
+func (h *Handler) deleteDiscount(c *gin.Context) {
+	claims, ok := jwt.GetUserFromContext(c)
+	if !ok {
+		h.logger.Errorf("could not get user from context")
+		utils.ErrorResponse(c, 500, utils.SERVERERROR)
+		return
+	}
+
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		utils.ErrorResponse(c, 400, utils.INVALID_REQUEST_DATA)
+		return
+	}
+
+	scope, ok := h.businessScope(c, claims)
+	if !ok {
+		return
+	}
+
+	if err := h.service.DeleteDiscount(c, db.DeleteDiscountParams{ID: int32(id), BusinessID: scope}); err != nil {
+		if discountError(c, err) {
+			return
+		}
+		h.logger.Errorf("error deleting discount %d: %v", id, err)
+		utils.ErrorResponse(c, 500, utils.SERVERERROR)
+		return
+	}
+
+	h.logDiscountActivity(c, claims, "Deleted Discount", db.Discount{ID: int32(id)}, fmt.Sprintf("Deleted discount %d", id))
+
+	utils.SuccessResponse(c, 200, "discount deleted", nil)
+}
+
+// CreateCouponRequest represents the request payload for creating a coupon
+// @Description Create coupon request payload
+type CreateCouponRequest struct {
+	BusinessID int32      `json:"business_id" binding:"required" example:"1"`        // Business the coupon belongs to
+	DiscountID int32      `json:"discount_id" binding:"required" example:"3"`        // Discount rule the coupon gives
+	Code       string     `json:"code" binding:"required,max=50" example:"SUMMER10"` // Code redeemed at the till, matched regardless of case
+	UsageLimit *int32     `json:"usage_limit" binding:"omitempty,gt=0" example:"1"`  // How many sales can redeem the code, leave empty for no limit
+	ExpiresAt  *time.Time `json:"expires_at" example:"2024-08-31T23:59:59Z"`         // When the code stops working, leave empty to keep it working
+	IsActive   *bool      `json:"is_active" example:"true"`                          // Whether the code can be redeemed, defaults to true
+}
+
+// CouponResponse represents a coupon
+// @Description Coupon response payload
+type CouponResponse struct {
+	ID         int32      `json:"id" example:"7"`
+	BusinessID int32      `json:"business_id" example:"1"`
+	DiscountID int32      `json:"discount_id" example:"3"`
+	Code       string     `json:"code" example:"SUMMER10"`
+	UsageLimit *int32     `json:"usage_limit" example:"1"`
+	UsedCount  int32      `json:"used_count" example:"0"`
+	ExpiresAt  *time.Time `json:"expires_at" example:"2024-08-31T23:59:59Z"`
+	IsActive   bool       `json:"is_active" example:"true"`
+	CreatedAt  time.Time  `json:"created_at" example:"2024-01-15T10:30:00Z"`
+}
+
+func toCouponResponse(coupon db.Coupon) CouponResponse {
+	response := CouponResponse{
+		ID:         coupon.ID,
+		BusinessID: coupon.BusinessID,
+		DiscountID: coupon.DiscountID,
+		Code:       coupon.Code,
+		UsedCount:  coupon.UsedCount,
+		IsActive:   coupon.IsActive,
+		CreatedAt:  coupon.CreatedAt.Time,
+	}
+	if coupon.UsageLimit.Valid {
+		response.UsageLimit = &coupon.UsageLimit.Int32
+	}
+	if coupon.ExpiresAt.Valid {
+		response.ExpiresAt = &coupon.ExpiresAt.Time
+	}
+	return response
+}
+
+// CreateCoupon godoc
+// @Summary Create coupon
+// @Description Create a coupon code for a discount rule of a business. Once a rule has coupons it is only applied to sales that redeem one of them.
+// @Tags pos
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param body body CreateCouponRequest true "Coupon"
+// @Success 201 {object} CouponResponse "Coupon created"
+// @Failure 400 {object} ErrorResponse "Bad request or discount not found"
+// @Failure 401 {object} ErrorResponse "Unauthorized"
+// @Failure 403 {object} ErrorResponse "Forbidden"
+// @Failure 409 {object} ErrorResponse "Code already exists in the business"
+// @Failure 500 {object} ErrorResponse "Internal server error"
+// @Router /pos/coupons [post]
+func (h *Handler) createCoupon(c *gin.Context) {
+	claims, ok := jwt.GetUserFromContext(c)
+	if !ok {
+		h.logger.Errorf("could not get user from context")
+		utils.ErrorResponse(c, 500, utils.SERVERERROR)
+		return
+	}
+
+	var req CreateCouponRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.BindingErrorResponse(c, err)
+		return
+	}
+
+	if _, err := h.service.GetOwnedBusinessID(c, db.GetOwnedBusinessIDParams{
+		OwnerID:    int32(claims.UserID),
+		BusinessID: sql.NullInt32{Int32: req.BusinessID, Valid: true},
+	}); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			utils.ErrorResponse(c, 403, "you do not own this business")
+			return
+		}
+		h.logger.Errorf("error checking business ownership: %v", err)
+		utils.ErrorResponse(c, 500, utils.SERVERERROR)
+		return
+	}
+
+	params := db.CreateCouponParams{
+		BusinessID: req.BusinessID,
+		DiscountID: req.DiscountID,
+		Code:       req.Code,
+		IsActive:   req.IsActive == nil || *req.IsActive,
+		CreatedBy:  int32(claims.UserID),
+	}
+	if req.UsageLimit != nil {
+		params.UsageLimit = sql.NullInt32{Int32: *req.UsageLimit, Valid: true}
+	}
+	if req.ExpiresAt != nil {
+		params.ExpiresAt = sql.NullTime{Time: *req.ExpiresAt, Valid: true}
+	}
+
+	coupon, err := h.service.CreateCoupon(c, params)
+	if err != nil {
+		if errors.Is(err, ErrCouponDiscountNotFound) {
+			utils.ErrorResponse(c, 400, err.Error())
+			return
+		}
+		if pgErr, ok := err.(*pq.Error); ok && pgErr.Code == "23505" { // unique_violation
+			utils.ErrorResponse(c, 409, fmt.Sprintf("coupon with code %s already exists", req.Code))
+			return
+		}
+		h.logger.Errorf("error creating coupon: %v", err)
+		utils.ErrorResponse(c, 500, utils.SERVERERROR)
+		return
+	}
+
+	_, err = h.service.LogActivity(c, db.LogActivityParams{
+		UserID:     int32(claims.UserID),
+		Action:     "Created Coupon",
+		EntityType: "Coupon",
+		EntityID:   coupon.ID,
+		Details:    utils.WriteActivityDetails(claims.Username, claims.Email, fmt.Sprintf("Created coupon %s for discount %d", coupon.Code, coupon.DiscountID), coupon.CreatedAt.Time),
+		IpAddress:  sql.NullString{Valid: true, String: utils.GetClientIP(c)},
+		UserAgent:  sql.NullString{Valid: true, String: c.Request.UserAgent()},
+	})
+	if err != nil {
+		h.logger.Warnf("error logging coupon activity: %v", err)
+	}
+
+	utils.SuccessResponse(c, 201, "coupon created", toCouponResponse(coupon))
+}
+
+// ListCoupons godoc
+// @Summary List coupons
+// @Description List the coupons of the business with how often they were redeemed
+// @Tags pos
+// @Produce json
+// @Security BearerAuth
+// @Param X-Business-ID header int false "Business to list the coupons of, defaults to the first one owned"
+// @Success 200 {array} CouponResponse "Coupons"
+// @Failure 401 {object} ErrorResponse "Unauthorized"
+// @Failure 403 {object} ErrorResponse "Forbidden"
+// @Failure 500 {object} ErrorResponse "Internal server error"
+// @Router /pos/coupons [get]
+func (h *Handler) listCoupons(c *gin.Context) {
+	claims, ok := jwt.GetUserFromContext(c)
+	if !ok {
+		h.logger.Errorf("could not get user from context")
+		utils.ErrorResponse(c, 500, utils.SERVERERROR)
+		return
+	}
+
+	scope, ok := h.businessScope(c, claims)
+	if !ok {
+		return
+	}
+
+	coupons, err := h.service.ListCoupons(c, scope)
+	if err != nil {
+		h.logger.Errorf("error listing coupons: %v", err)
+		utils.ErrorResponse(c, 500, utils.SERVERERROR)
+		return
+	}
+
+	response := make([]CouponResponse, 0, len(coupons))
+	for _, coupon := range coupons {
+		response = append(response, toCouponResponse(coupon))
+	}
+
+	utils.SuccessResponse(c, 200, "coupons", response)
+}
+
+// DeleteCoupon godoc
+// @Summary Delete coupon
+// @Description Delete a coupon of the business, its code can't be redeemed anymore
+// @Tags pos
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "Coupon ID"
+// @Success 200 {string} string "coupon deleted"
+// @Failure 400 {object} ErrorResponse "Bad request"
+// @Failure 401 {object} ErrorResponse "Unauthorized"
+// @Failure 404 {object} ErrorResponse "Coupon not found"
+// @Failure 500 {object} ErrorResponse "Internal server error"
+// @Router /pos/coupons/{id} [delete]
+func (h *Handler) deleteCoupon(c *gin.Context) {
+	claims, ok := jwt.GetUserFromContext(c)
+	if !ok {
+		h.logger.Errorf("could not get user from context")
+		utils.ErrorResponse(c, 500, utils.SERVERERROR)
+		return
+	}
+
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		utils.ErrorResponse(c, 400, utils.INVALID_REQUEST_DATA)
+		return
+	}
+
+	scope, ok := h.businessScope(c, claims)
+	if !ok {
+		return
+	}
+
+	if err := h.service.DeleteCoupon(c, db.DeleteCouponParams{ID: int32(id), BusinessID: scope}); err != nil {
+		if errors.Is(err, ErrCouponNotFound) {
+			utils.ErrorResponse(c, 404, err.Error())
+			return
+		}
+		h.logger.Errorf("error deleting coupon %d: %v", id, err)
+		utils.ErrorResponse(c, 500, utils.SERVERERROR)
+		return
+	}
+
+	_, err = h.service.LogActivity(c, db.LogActivityParams{
+		UserID:     int32(claims.UserID),
+		Action:     "Deleted Coupon",
+		EntityType: "Coupon",
+		EntityID:   int32(id),
+		Details:    utils.WriteActivityDetails(claims.Username, claims.Email, fmt.Sprintf("Deleted coupon %d", id), time.Now()),
+		IpAddress:  sql.NullString{Valid: true, String: utils.GetClientIP(c)},
+		UserAgent:  sql.NullString{Valid: true, String: c.Request.UserAgent()},
+	})
+	if err != nil {
+		h.logger.Warnf("error logging coupon activity: %v", err)
+	}
+
+	utils.SuccessResponse(c, 200, "coupon deleted", nil)
+}
+
+// GetSalesHistory godoc
+// @Summary Get sales history
+// @Description Get the sales of a store or of every store in the business, newest first. Days are taken in the business timezone, start_date and end_date are both included and either can be left out.
+// @Tags pos
+// @Produce json
+// @Security BearerAuth
+// @Param page query int false "Page number"
+// @Param limit query int false "Number of items per page, at most 100"
+// @Param start_date query string false "First day to list (YYYY-MM-DD)"
+// @Param end_date query string false "Last day to list (YYYY-MM-DD)"
+// @Param store_id query int false "Store to list, defaults to every store"
+// @Param X-Business-ID header int false "Business to list, defaults to the user's first business"
+// @Success 200 {object} SalesHistoryResponse{items=[]SaleResponse} "Sales history retrieved successfully"
+// @Failure 400 {object} ErrorResponse "Bad request"
+// @Failure 401 {object} ErrorResponse "Unauthorized"
+// @Failure 403 {object} ErrorResponse "Forbidden"
+// @Failure 404 {object} ErrorResponse "Store not found"
+// @Failure 500 {object} ErrorResponse "Internal server error"
+// @Router /pos/sales/history [get]
+func (h *Handler) getSalesHistory(c *gin.Context) {
+	claims, ok := jwt.GetUserFromContext(c)
+	if !ok {
+		h.logger.Errorf("could not get user from context")
+		utils.ErrorResponse(c, 500, utils.SERVERERROR)
+		return
+	}
+
+	pagination, err := utils.ParsePagination(c)
+	if err != nil {
+		utils.ErrorResponse(c, 400, err.Error())
+		return
+	}
+
+	var storeID sql.NullInt32
+	if raw := c.Query("store_id"); raw != "" {
+		id, err := strconv.Atoi(raw)
+		if err != nil || id <= 0 {
+			utils.ErrorResponse(c, 400, "invalid store_id")
+			return
+		}
+		storeID = sql.NullInt32{Int32: int32(id), Valid: true}
+	}
+	// staff assigned to stores only see the sales of one of theirs
+	if storeID.Valid {
+		if !auth.RequireStore(c, storeID.Int32) {
+			return
+		}
+	} else if auth.GetStoreScope(c).Restricted {
+		utils.ErrorResponse(c, 400, "store_id is required")
+		return
+	}
+
+	scope, ok := h.businessScope(c, claims)
+	if !ok {
+		return
+	}
+
+	history, err := h.service.SalesHistory(c, SalesHistoryParams{
+		StartDate:  c.Query("start_date"),
+		EndDate:    c.Query("end_date"),
+		StoreID:    storeID,
+		BusinessID: scope,
+		Limit:      int32(pagination.Limit),
+		Offset:     int32(pagination.Offset),
+	})
+	if err != nil {
+		switch {
+		case errors.Is(err, ErrInvalidReportDate), errors.Is(err, ErrInvalidReportRange):
+			utils.ErrorResponse(c, 400, err.Error())
+		case errors.Is(err, ErrStoreNotFound):
+			utils.ErrorResponse(c, 404, err.Error())
+		default:
+			h.logger.Errorf("error listing sales history: %v", err)
+			utils.ErrorResponse(c, 500, utils.SERVERERROR)
+		}
+		return
+	}
+
+	sales := make([]SaleResponse, 0, len(history.Sales))
+	for _, entry := range history.Sales {
+		sale := entry.Sale
+		totalAmount, _ := strconv.ParseFloat(sale.TotalAmount, 64)
+		taxAmount, _ := strconv.ParseFloat(sale.TaxAmount, 64)
+		discountAmount, _ := strconv.ParseFloat(sale.DiscountAmount, 64)
+		saleResponse := SaleResponse{
+			ID:             int(sale.ID),
+			CustomerID:     int(sale.CustomerID),
+			TotalAmount:    totalAmount,
+			TaxAmount:      taxAmount,
+			DiscountAmount: discountAmount,
+			Items:          make([]SaleItem, 0, len(entry.Items)),
+			CreatedAt:      sale.CreatedAt.Time,
+		}
+		for _, item := range entry.Items {
+			price, _ := strconv.ParseFloat(item.UnitPrice, 64)
+			saleResponse.Items = append(saleResponse.Items, SaleItem{
+				ItemID:   int(item.VariationID),
+				Quantity: int(item.Quantity),
+				Price:    price,
+			})
+		}
+		sales = append(sales, saleResponse)
+	}
+
+	response := SalesHistoryResponse{
+		PaginatedResponse: pagination.Response(sales, history.Total),
+		Timezone:          history.Timezone,
+	}
+
+	utils.SuccessResponse(c, http.StatusOK, "Sales history retrieved successfully", response)
+}
+
+// CreateItem godoc
+// @Summary Create item
+// @Description Create a new inventory item
+// @Tags pos
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param body body CreateItemRequest true "Item details"
+// @Success 201 {object} ItemResponse "Item created successfully"
+// @Failure 400 {object} ErrorResponse "Bad request"
+// @Failure 401 {object} ErrorResponse "Unauthorized"
+// @Failure 403 {object} ErrorResponse "Forbidden"
+// @Failure 500 {object} ErrorResponse "Internal server error"
+// @Router /pos/items [post]
+func createItem(c *gin.Context) {
+	var req CreateItemRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	// TODO: Implement actual item creation logic
+	// For now, return a mock response
+	response := ItemResponse{
+		ID:            1,
+		Name:          req.Name,
+		Description:   req.Description,
+		Price:         req.Price,
+		Category:      req.Category,
+		SKU:           req.SKU,
+		StockQuantity: req.StockQuantity,
+		CreatedAt:     time.Now(),
+		UpdatedAt:     time.Now(),
+	}
+
+	utils.SuccessResponse(c, http.StatusCreated, "Item created successfully", response)
+}
+
+// validateSaleItems checks the lines of a sale against the configured limits,
+// maxLines distinct lines and maxQuantity units per line.
+func validateSaleItems(items []SaleItem, maxLines, maxQuantity int) error {
+	if len(items) == 0 {
+		return fmt.Errorf("a sale must have at least one item")
+	}
+	if len(items) > maxLines {
+		return fmt.Errorf("a sale can have at most %d lines, got %d", maxLines, len(items))
+	}
 	for _, item := range items {
-		subtotal += item.Price * float64(item.Quantity)
+		if item.Quantity <= 0 {
+			return fmt.Errorf("quantity of item %d must be a positive integer", item.ItemID)
+		}
+		if item.Quantity > maxQuantity {
+			return fmt.Errorf("quantity of item %d can be at most %d, got %d", item.ItemID, maxQuantity, item.Quantity)
+		}
 	}
-	return subtotal * (taxRate / 100)
+	return nil
 }
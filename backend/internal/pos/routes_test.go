@@ -0,0 +1,137 @@
+package pos
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	db "herp/db/sqlc"
+	"herp/internal/config"
+	"herp/pkg/jwt"
+	"herp/pkg/monitoring/logging"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// posService records the sales it is asked to make and answers with the
+// function set by the test.
+type posService struct {
+	POSInterface
+	sales      []CreateSaleParams
+	createSale func(args CreateSaleParams) (SaleResult, error)
+}
+
+func (s *posService) CreateSale(ctx context.Context, args CreateSaleParams) (SaleResult, error) {
+	s.sales = append(s.sales, args)
+	if s.createSale == nil {
+		return SaleResult{}, errors.New("unexpected sale")
+	}
+	return s.createSale(args)
+}
+
+// GetStoreInBusiness knows stores 1 and 2 of business 1 and store 3 of
+// business 2.
+func (s *posService) GetStoreInBusiness(ctx context.Context, params db.GetStoreInBusinessParams) (int32, error) {
+	business := map[int32]int32{1: 1, 2: 1, 3: 2}[params.ID]
+	if business == 0 || (params.BusinessID.Valid && params.BusinessID.Int32 != business) {
+		return 0, sql.ErrNoRows
+	}
+	return params.ID, nil
+}
+
+func testPOSConfig() *config.Config {
+	return &config.Config{SaleMaxLines: 3, SaleMaxQuantity: 50}
+}
+
+// newPOSRouter serves the sale and receipt routes for claims, behind middleware that
+// runs before the handlers, such as a store scope.
+func newPOSRouter(service POSInterface, cfg *config.Config, claims *jwt.Claims, middleware ...gin.HandlerFunc) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	h := NewHandler(service, cfg, logging.NewLogger(cfg), nil, nil, nil)
+
+	r := gin.New()
+	r.Use(func(c *gin.Context) {
+		c.Set("claims", claims)
+	})
+	r.Use(middleware...)
+	r.POST("/pos/sales", h.createSale)
+	r.POST("/pos/sales/:id/refund", h.refundSale)
+	r.GET("/pos/sales/:id/receipt", h.getReceipt)
+	return r
+}
+
+func cashier() *jwt.Claims {
+	return &jwt.Claims{UserID: 2, Username: "cashier", Email: "cashier@example.com"}
+}
+
+func postSale(r *gin.Engine, body string) *httptest.ResponseRecorder {
+	w := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodPost, "/pos/sales", strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	r.ServeHTTP(w, req)
+	return w
+}
+
+func saleBody(lines int, quantity int) string {
+	items := make([]string, lines)
+	for i := range items {
+		items[i] = fmt.Sprintf(`{"item_id":%d,"quantity":%d,"price":2.5}`, i+1, quantity)
+	}
+	return fmt.Sprintf(`{"store_id":1,"customer_id":1,"items":[%s],"payments":[{"method":"cash","amount":10}]}`, strings.Join(items, ","))
+}
+
+func TestValidateSaleItems(t *testing.T) {
+	tests := []struct {
+		name    string
+		items   []SaleItem
+		wantErr string
+	}{
+		{"within the limits", []SaleItem{{ItemID: 1, Quantity: 1}, {ItemID: 2, Quantity: 50}}, ""},
+		{"no lines", nil, "a sale must have at least one item"},
+		{"too many lines", []SaleItem{{ItemID: 1, Quantity: 1}, {ItemID: 2, Quantity: 1}, {ItemID: 3, Quantity: 1}, {ItemID: 4, Quantity: 1}}, "a sale can have at most 3 lines, got 4"},
+		{"zero quantity", []SaleItem{{ItemID: 1, Quantity: 0}}, "quantity of item 1 must be a positive integer"},
+		{"negative quantity", []SaleItem{{ItemID: 2, Quantity: -4}}, "quantity of item 2 must be a positive integer"},
+		{"quantity over the limit", []SaleItem{{ItemID: 3, Quantity: 51}}, "quantity of item 3 can be at most 50, got 51"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := validateSaleItems(tt.items, 3, 50)
+			if tt.wantErr == "" {
+				assert.NoError(t, err)
+				return
+			}
+			require.Error(t, err)
+			assert.Equal(t, tt.wantErr, err.Error())
+		})
+	}
+}
+
+func TestCreateSaleRejectsInvalidItems(t *testing.T) {
+	tests := []struct {
+		name    string
+		body    string
+		wantErr string
+	}{
+		{"negative quantity", saleBody(1, -1), "must be a positive integer"},
+		{"zero quantity", saleBody(1, 0), "must be a positive integer"},
+		{"quantity over the limit", saleBody(1, 51), "can be at most 50"},
+		{"too many lines", saleBody(4, 1), "at most 3 lines"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			service := &posService{}
+			r := newPOSRouter(service, testPOSConfig(), cashier())
+
+			w := postSale(r, tt.body)
+			assert.Equal(t, http.StatusUnprocessableEntity, w.Code, w.Body.String())
+			assert.Contains(t, w.Body.String(), tt.wantErr)
+			assert.Empty(t, service.sales)
+		})
+	}
+}
@@ -0,0 +1,204 @@
+package pos
+
+import (
+	"context"
+	"database/sql"
+	"herp/db/dbtest"
+	db "herp/db/sqlc"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// saleFixture is a business with one store selling one variation.
+type saleFixture struct {
+	conn       *sql.DB
+	pos        *POS
+	businessID int32
+	storeID    int32
+	variation  int32
+}
+
+func newSaleFixture(t *testing.T) saleFixture {
+	t.Helper()
+	conn := dbtest.Open(t)
+	owner := dbtest.Admin(t, conn, "owner")
+	businessID, branchID := dbtest.Business(t, conn, owner, "Palmwine Express")
+	return saleFixture{
+		conn:       conn,
+		pos:        NewPOS(db.New(conn), conn),
+		businessID: businessID,
+		storeID:    dbtest.Store(t, conn, branchID, "Bar"),
+		variation:  dbtest.Variation(t, conn, businessID, "MLK-1L", "10.00"),
+	}
+}
+
+// sell sells quantity of the fixture's variation at 10.00 each, paid in
+// cash.
+func (f saleFixture) sell(ctx context.Context, quantity int32) (SaleResult, error) {
+	return f.pos.CreateSale(ctx, CreateSaleParams{
+		StoreID:    f.storeID,
+		CustomerID: 1,
+		CashierID:  1,
+		Lines:      []SaleLine{{VariationID: f.variation, Quantity: quantity}},
+		Payments:   []SalePayment{{Method: "cash", Amount: float64(quantity) * 10}},
+	})
+}
+
+// batch receives a lot expiring in days days, created in the order it is
+// called.
+func (f saleFixture) batch(t *testing.T, lot string, days, quantity int32) int32 {
+	t.Helper()
+	return dbtest.Insert(t, f.conn, `
+		INSERT INTO inventory_batch (store_id, variation_id, lot_number, expiry_date, quantity, created_at)
+		VALUES ($1, $2, $3, CURRENT_DATE + $4::int, $5, clock_timestamp())
+		RETURNING id`, f.storeID, f.variation, lot, days, quantity)
+}
+
+func (f saleFixture) batchQuantity(t *testing.T, id int32) int32 {
+	t.Helper()
+	var quantity int32
+	require.NoError(t, f.conn.QueryRow(`SELECT quantity FROM inventory_batch WHERE id = $1`, id).Scan(&quantity))
+	return quantity
+}
+
+func TestCreateSaleDepletesBatches(t *testing.T) {
+	tests := []struct {
+		name     string
+		strategy string
+		// left in the batches received first, second and third
+		want []int32
+	}{
+		// the second lot expires first, then the third
+		{"fefo", "fefo", []int32{4, 0, 2}},
+		{"fifo", "fifo", []int32{0, 2, 4}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := newSaleFixture(t)
+			ctx := context.Background()
+			dbtest.Exec(t, f.conn, `UPDATE business SET depletion_strategy = $1 WHERE id = $2`, tt.strategy, f.businessID)
+
+			dbtest.Stock(t, f.conn, f.storeID, f.variation, 12)
+			batches := []int32{
+				f.batch(t, "L-1", 10, 4),
+				f.batch(t, "L-2", 3, 4),
+				f.batch(t, "L-3", 5, 4),
+			}
+
+			result, err := f.sell(ctx, 6)
+			require.NoError(t, err)
+
+			for i, id := range batches {
+				assert.Equal(t, tt.want[i], f.batchQuantity(t, id), "batch %d", i+1)
+			}
+
+			rows, err := f.conn.Query(`
+				SELECT batch_id, quantity FROM inventory_batch_movement
+				WHERE reason = 'sale' AND reference_id = $1 ORDER BY batch_id`, result.Sale.ID)
+			require.NoError(t, err)
+			defer rows.Close()
+			taken := map[int32]int32{}
+			for rows.Next() {
+				var batchID, quantity int32
+				require.NoError(t, rows.Scan(&batchID, &quantity))
+				taken[batchID] = quantity
+			}
+			require.NoError(t, rows.Err())
+			want := map[int32]int32{}
+			for i, id := range batches {
+				if left := tt.want[i]; left < 4 {
+					want[id] = 4 - left
+				}
+			}
+			assert.Equal(t, want, taken)
+		})
+	}
+}
+
+func TestCreateSaleCategoryStrategyOverridesBusiness(t *testing.T) {
+	f := newSaleFixture(t)
+	ctx := context.Background()
+	// the business sells first in first out, the category of the item
+	// soonest expiry first
+	dbtest.Exec(t, f.conn, `UPDATE business SET depletion_strategy = 'fifo' WHERE id = $1`, f.businessID)
+	dbtest.Exec(t, f.conn, `
+		UPDATE category SET depletion_strategy = 'fefo'
+		WHERE id = (SELECT i.category_id FROM item i JOIN variation v ON v.item_id = i.id WHERE v.id = $1)`, f.variation)
+
+	dbtest.Stock(t, f.conn, f.storeID, f.variation, 8)
+	older := f.batch(t, "L-1", 10, 4)
+	sooner := f.batch(t, "L-2", 3, 4)
+
+	_, err := f.sell(ctx, 3)
+	require.NoError(t, err)
+	assert.Equal(t, int32(4), f.batchQuantity(t, older))
+	assert.Equal(t, int32(1), f.batchQuantity(t, sooner))
+}
+
+func TestCreateSaleChargesStorePrice(t *testing.T) {
+	f := newSaleFixture(t)
+	ctx := context.Background()
+	dbtest.Stock(t, f.conn, f.storeID, f.variation, 5)
+	dbtest.Exec(t, f.conn, `INSERT INTO store_price (store_id, variation_id, price) VALUES ($1, $2, 12.50)`, f.storeID, f.variation)
+
+	result, err := f.pos.CreateSale(ctx, CreateSaleParams{
+		StoreID:    f.storeID,
+		CustomerID: 1,
+		CashierID:  1,
+		// whatever price comes with a line, the store price is charged
+		Lines:    []SaleLine{{VariationID: f.variation, Quantity: 2, UnitPrice: 0.01}},
+		Payments: []SalePayment{{Method: "cash", Amount: 25}},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "25.00", result.Sale.TotalAmount)
+	require.Len(t, result.Items, 1)
+	assert.Equal(t, "12.50", result.Items[0].UnitPrice)
+}
+
+func TestCreateSaleRejectsVariationOfOtherBusiness(t *testing.T) {
+	f := newSaleFixture(t)
+	ctx := context.Background()
+	otherBusiness, _ := dbtest.Business(t, f.conn, dbtest.Admin(t, f.conn, "other"), "Suya Spot")
+	variation := dbtest.Variation(t, f.conn, otherBusiness, "SUY-1", "1.00")
+	dbtest.Stock(t, f.conn, f.storeID, variation, 5)
+
+	_, err := f.pos.CreateSale(ctx, CreateSaleParams{
+		StoreID:    f.storeID,
+		CustomerID: 1,
+		CashierID:  1,
+		Lines:      []SaleLine{{VariationID: variation, Quantity: 1}},
+		Payments:   []SalePayment{{Method: "cash", Amount: 1}},
+	})
+	assert.ErrorIs(t, err, ErrSaleItemNotFound)
+}
+
+func TestCreateSaleRejectsCustomerOfOtherBusiness(t *testing.T) {
+	f := newSaleFixture(t)
+	ctx := context.Background()
+	dbtest.Stock(t, f.conn, f.storeID, f.variation, 5)
+	_, err := f.sell(ctx, 1)
+	require.NoError(t, err)
+
+	otherBusiness, branchID := dbtest.Business(t, f.conn, dbtest.Admin(t, f.conn, "other"), "Suya Spot")
+	store := dbtest.Store(t, f.conn, branchID, "Grill")
+	variation := dbtest.Variation(t, f.conn, otherBusiness, "SUY-1", "1.00")
+	dbtest.Stock(t, f.conn, store, variation, 5)
+	sell := func(customerID int32) error {
+		_, err := f.pos.CreateSale(ctx, CreateSaleParams{
+			StoreID:    store,
+			CustomerID: customerID,
+			CashierID:  1,
+			Lines:      []SaleLine{{VariationID: variation, Quantity: 1}},
+			Payments:   []SalePayment{{Method: "cash", Amount: 1}},
+		})
+		return err
+	}
+
+	// customer 1 bought from the fixture's business
+	assert.ErrorIs(t, sell(1), ErrCustomerNotFound)
+	// customer 2 never bought anywhere and becomes one of the other business
+	require.NoError(t, sell(2))
+	require.NoError(t, sell(2))
+}
@@ -0,0 +1,620 @@
+package pos
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	db "herp/db/sqlc"
+	"herp/internal/core/inventory"
+	"math"
+	"math/big"
+	"sort"
+	"strconv"
+)
+
+var (
+	ErrSaleNotFound         = errors.New("sale not found")
+	ErrDuplicateSaleItem    = errors.New("item appears more than once in sale")
+	ErrReservationMismatch  = errors.New("reservation does not match sale items")
+	ErrRefundItemNotInSale  = errors.New("item is not part of this sale")
+	ErrRefundExceedsSold    = errors.New("refund quantity exceeds quantity sold")
+	ErrRefundExceedsBalance = errors.New("item has already been refunded")
+	ErrCustomerNotFound     = errors.New("customer not found")
+	ErrSaleItemNotFound     = errors.New("item is not sold in this store")
+)
+
+type POS struct {
+	db          *sql.DB
+	queries     Querier
+	roomCharges RoomChargePoster
+	events      EventPublisher
+}
+
+func NewPOS(queries Querier, db *sql.DB) *POS {
+	return &POS{
+		queries:     queries,
+		db:          db,
+		roomCharges: FolioPoster{},
+		events:      noEvents{},
+	}
+}
+
+// SetRoomChargePoster replaces where room charges are posted, by default
+// they go to the folios in the database.
+func (p *POS) SetRoomChargePoster(poster RoomChargePoster) {
+	p.roomCharges = poster
+}
+
+type SaleLine struct {
+	VariationID int32
+	Quantity    int32
+	// UnitPrice is set by CreateSale to the price the store sells the
+	// variation at
+	UnitPrice float64
+}
+
+type CreateSaleParams struct {
+	StoreID    int32
+	CustomerID int32
+	CashierID  int32
+	Lines      []SaleLine
+	// DiscountAmount is the discount entered by hand, taken off after the
+	// discount rules of the business
+	DiscountAmount float64
+	// CouponCode is a coupon the sale redeems for the discount of its rule
+	CouponCode string
+	// DiscountCap is the percentage of the subtotal all discounts of the sale
+	// may take off together
+	DiscountCap int
+	// TaxRate overrides the tax rate percentage of the business, a different
+	// rate is refused unless AllowTaxRateOverride is set
+	TaxRate              *float64
+	AllowTaxRateOverride bool
+	ReservationIDs       []int32
+	// Payments have to add up to the total once it's rounded per the business
+	Payments []SalePayment
+}
+
+type SaleResult struct {
+	Sale      db.Sale
+	Items     []db.SaleItem
+	Payments  []db.SalePayment
+	Discounts []db.SaleDiscount
+}
+
+type RefundLine struct {
+	VariationID int32
+	Quantity    int32
+}
+
+type RefundSaleParams struct {
+	SaleID     int32
+	RefundedBy int32
+	// BusinessID limits the refund to sales of the business, when set
+	BusinessID sql.NullInt32
+	// Lines to refund, an empty list refunds everything still refundable
+	Lines []RefundLine
+}
+
+type RefundResult struct {
+	Refund    db.Refund
+	Sale      db.Sale
+	Items     []db.RefundItem
+	SaleItems []db.SaleItem
+}
+
+func (p *POS) LogActivity(ctx context.Context, params db.LogActivityParams) (db.ActivityLog, error) {
+	return p.queries.LogActivity(ctx, params)
+}
+
+// CreateSale records a sale with its payments and discounts and takes its
+// quantities out of store stock. Quantities covered by the given reservations are consumed from
+// them, the rest is deducted from what is available in the store.
+func (p *POS) CreateSale(ctx context.Context, args CreateSaleParams) (SaleResult, error) {
+	q, ok := p.queries.(*db.Queries)
+	if !ok {
+		return SaleResult{}, fmt.Errorf("invalid query type in pos")
+	}
+
+	needed := make(map[int32]int32, len(args.Lines))
+	for _, line := range args.Lines {
+		if _, ok := needed[line.VariationID]; ok {
+			return SaleResult{}, ErrDuplicateSaleItem
+		}
+		needed[line.VariationID] = line.Quantity
+	}
+
+	tx, err := p.db.BeginTx(ctx, nil)
+	if err != nil {
+		return SaleResult{}, err
+	}
+	defer tx.Rollback()
+
+	txQueries := q.WithTx(tx)
+
+	// lines are charged the price of the store, never one sent by the client
+	args.Lines, err = priceSaleLines(ctx, txQueries, args.StoreID, args.Lines)
+	if err != nil {
+		return SaleResult{}, err
+	}
+
+	// customers are only known to the businesses they bought from, a first
+	// sale makes them a customer of this one
+	businessID, err := txQueries.GetStoreBusinessID(ctx, args.StoreID)
+	if err != nil {
+		return SaleResult{}, err
+	}
+	otherBusiness, err := txQueries.IsCustomerOfOtherBusiness(ctx, db.IsCustomerOfOtherBusinessParams{
+		BusinessID: businessID,
+		CustomerID: args.CustomerID,
+	})
+	if err != nil {
+		return SaleResult{}, err
+	}
+	if otherBusiness {
+		return SaleResult{}, ErrCustomerNotFound
+	}
+
+	settings, err := txQueries.GetStorePaymentSettings(ctx, args.StoreID)
+	if err != nil {
+		return SaleResult{}, err
+	}
+	taxRate, err := saleTaxRate(settings.TaxRate, args.TaxRate, args.AllowTaxRateOverride)
+	if err != nil {
+		return SaleResult{}, err
+	}
+	discounts, err := saleDiscounts(ctx, txQueries, args)
+	if err != nil {
+		return SaleResult{}, err
+	}
+	totals := calculateSaleTotals(args.Lines, sumDiscounts(discounts), taxRate, settings.Rounding)
+	if err := checkSalePayments(settings, totals.Total, args.Payments); err != nil {
+		return SaleResult{}, err
+	}
+
+	reservations, err := inventory.ConsumeReservationsTx(ctx, txQueries, args.ReservationIDs)
+	if err != nil {
+		return SaleResult{}, err
+	}
+	for _, r := range reservations {
+		remaining, ok := needed[r.VariationID]
+		if !ok || r.StoreID != args.StoreID || r.Quantity > remaining {
+			return SaleResult{}, ErrReservationMismatch
+		}
+		needed[r.VariationID] = remaining - r.Quantity
+	}
+
+	for _, line := range args.Lines {
+		if qty := needed[line.VariationID]; qty > 0 {
+			if err := inventory.DeductStockTx(ctx, txQueries, args.StoreID, line.VariationID, qty); err != nil {
+				return SaleResult{}, err
+			}
+		}
+	}
+
+	sale, err := txQueries.CreateSale(ctx, db.CreateSaleParams{
+		StoreID:        args.StoreID,
+		CustomerID:     args.CustomerID,
+		CashierID:      args.CashierID,
+		Subtotal:       formatCents(totals.Subtotal),
+		DiscountAmount: formatCents(totals.Discount),
+		TaxAmount:      formatCents(totals.Tax),
+		TotalAmount:    formatCents(totals.Total),
+	})
+	if err != nil {
+		return SaleResult{}, err
+	}
+
+	items := make([]db.SaleItem, 0, len(args.Lines))
+	for _, line := range args.Lines {
+		item, err := txQueries.CreateSaleItem(ctx, db.CreateSaleItemParams{
+			SaleID:      sale.ID,
+			VariationID: line.VariationID,
+			Quantity:    line.Quantity,
+			UnitPrice:   formatAmount(line.UnitPrice),
+		})
+		if err != nil {
+			return SaleResult{}, err
+		}
+		items = append(items, item)
+
+		if _, err := inventory.ConsumeBatchesTx(ctx, txQueries, db.ConsumeInventoryBatchesParams{
+			StoreID:     args.StoreID,
+			VariationID: line.VariationID,
+			Quantity:    line.Quantity,
+			Reason:      inventory.MovementSale,
+			ReferenceID: sql.NullInt32{Int32: sale.ID, Valid: true},
+		}); err != nil {
+			return SaleResult{}, err
+		}
+	}
+
+	payments, err := createSalePayments(ctx, txQueries, p.roomCharges, sale.ID, args.Payments)
+	if err != nil {
+		return SaleResult{}, err
+	}
+
+	saleDiscounts, err := createSaleDiscounts(ctx, txQueries, sale.ID, discounts)
+	if err != nil {
+		return SaleResult{}, err
+	}
+
+	if err := earnLoyaltyPoints(ctx, txQueries, sale); err != nil {
+		return SaleResult{}, err
+	}
+
+	lowStock, err := lowStockSold(ctx, txQueries, args.StoreID, items)
+	if err != nil {
+		return SaleResult{}, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return SaleResult{}, err
+	}
+
+	p.publishSale(ctx, businessID, sale, items)
+	p.publishLowStock(ctx, businessID, lowStock)
+
+	return SaleResult{Sale: sale, Items: items, Payments: payments, Discounts: saleDiscounts}, nil
+}
+
+// GetSale returns a sale. A sale outside businessID is reported as not found.
+func (p *POS) GetSale(ctx context.Context, saleID int32, businessID sql.NullInt32) (db.Sale, error) {
+	sale, err := p.queries.GetSaleInBusiness(ctx, db.GetSaleInBusinessParams{
+		ID:         saleID,
+		BusinessID: businessID,
+	})
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return db.Sale{}, ErrSaleNotFound
+		}
+		return db.Sale{}, err
+	}
+	return sale, nil
+}
+
+// RefundSale refunds some or all lines of a sale and puts the returned
+// quantities back into the store's stock. The sale and its lines are locked so
+// concurrent refunds cannot refund the same quantity twice.
+func (p *POS) RefundSale(ctx context.Context, args RefundSaleParams) (RefundResult, error) {
+	q, ok := p.queries.(*db.Queries)
+	if !ok {
+		return RefundResult{}, fmt.Errorf("invalid query type in pos")
+	}
+
+	tx, err := p.db.BeginTx(ctx, nil)
+	if err != nil {
+		return RefundResult{}, err
+	}
+	defer tx.Rollback()
+
+	txQueries := q.WithTx(tx)
+
+	sale, err := txQueries.GetSaleForUpdate(ctx, db.GetSaleForUpdateParams{
+		ID:         args.SaleID,
+		BusinessID: args.BusinessID,
+	})
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return RefundResult{}, ErrSaleNotFound
+		}
+		return RefundResult{}, err
+	}
+
+	saleItems, err := txQueries.ListSaleItemsForUpdate(ctx, sale.ID)
+	if err != nil {
+		return RefundResult{}, err
+	}
+
+	byVariation := make(map[int32]int, len(saleItems))
+	for idx, item := range saleItems {
+		byVariation[item.VariationID] = idx
+	}
+
+	lines := args.Lines
+	if len(lines) == 0 {
+		for _, item := range saleItems {
+			if remaining := item.Quantity - item.RefundedQuantity; remaining > 0 {
+				lines = append(lines, RefundLine{VariationID: item.VariationID, Quantity: remaining})
+			}
+		}
+		if len(lines) == 0 {
+			return RefundResult{}, ErrRefundExceedsBalance
+		}
+	}
+
+	requested := make(map[int32]int32, len(lines))
+	for _, line := range lines {
+		idx, ok := byVariation[line.VariationID]
+		if !ok {
+			return RefundResult{}, ErrRefundItemNotInSale
+		}
+		requested[line.VariationID] += line.Quantity
+
+		item := saleItems[idx]
+		if requested[line.VariationID] > item.Quantity {
+			return RefundResult{}, ErrRefundExceedsSold
+		}
+		if requested[line.VariationID] > item.Quantity-item.RefundedQuantity {
+			return RefundResult{}, ErrRefundExceedsBalance
+		}
+	}
+
+	subtotal, err := parseCents(sale.Subtotal)
+	if err != nil {
+		return RefundResult{}, err
+	}
+	total, err := parseCents(sale.TotalAmount)
+	if err != nil {
+		return RefundResult{}, err
+	}
+	lineAmounts := make(map[int32]int64, len(requested))
+	for variationID, qty := range requested {
+		price, err := parseCents(saleItems[byVariation[variationID]].UnitPrice)
+		if err != nil {
+			return RefundResult{}, err
+		}
+		lineAmounts[variationID] = price * int64(qty)
+	}
+	amounts, refundTotal := refundAmounts(subtotal, total, lineAmounts)
+
+	refund, err := txQueries.CreateRefund(ctx, db.CreateRefundParams{
+		SaleID:      sale.ID,
+		RefundedBy:  args.RefundedBy,
+		TotalAmount: formatCents(refundTotal),
+	})
+	if err != nil {
+		return RefundResult{}, err
+	}
+
+	refundItems := make([]db.RefundItem, 0, len(requested))
+	fullyRefunded := true
+	for idx, item := range saleItems {
+		qty, ok := requested[item.VariationID]
+		if ok {
+			refundItem, err := txQueries.CreateRefundItem(ctx, db.CreateRefundItemParams{
+				RefundID:   refund.ID,
+				SaleItemID: item.ID,
+				Quantity:   qty,
+				Amount:     formatCents(amounts[item.VariationID]),
+			})
+			if err != nil {
+				return RefundResult{}, err
+			}
+			refundItems = append(refundItems, refundItem)
+
+			item, err = txQueries.AddSaleItemRefundedQuantity(ctx, db.AddSaleItemRefundedQuantityParams{
+				ID:               item.ID,
+				RefundedQuantity: qty,
+			})
+			if err != nil {
+				return RefundResult{}, err
+			}
+			saleItems[idx] = item
+
+			if _, err := txQueries.IncrementInventoryQuantity(ctx, db.IncrementInventoryQuantityParams{
+				StoreID:     sale.StoreID,
+				VariationID: item.VariationID,
+				Quantity:    qty,
+			}); err != nil {
+				return RefundResult{}, err
+			}
+		}
+
+		if item.RefundedQuantity < item.Quantity {
+			fullyRefunded = false
+		}
+	}
+
+	status := "partially_refunded"
+	if fullyRefunded {
+		status = "refunded"
+	}
+	sale, err = txQueries.UpdateSaleStatus(ctx, db.UpdateSaleStatusParams{
+		ID:     sale.ID,
+		Status: status,
+	})
+	if err != nil {
+		return RefundResult{}, err
+	}
+
+	share := 1.0
+	if total > 0 {
+		share = float64(refundTotal) / float64(total)
+	}
+	if err := reverseLoyaltyPoints(ctx, txQueries, sale, refund, share, fullyRefunded); err != nil {
+		return RefundResult{}, err
+	}
+	businessID, err := txQueries.GetStoreBusinessID(ctx, sale.StoreID)
+	if err != nil {
+		return RefundResult{}, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return RefundResult{}, err
+	}
+
+	p.publishRefund(ctx, businessID, refund, sale)
+
+	return RefundResult{
+		Refund:    refund,
+		Sale:      sale,
+		Items:     refundItems,
+		SaleItems: saleItems,
+	}, nil
+}
+
+// GetLoyaltyBalance returns the points a customer currently holds in a
+// business. Customers are only known to the businesses they bought from,
+// for any other business ErrCustomerNotFound is returned.
+func (p *POS) GetLoyaltyBalance(ctx context.Context, customerID int32, businessID sql.NullInt32) (int32, error) {
+	balance, err := p.queries.GetCustomerLoyaltyBalanceInBusiness(ctx, db.GetCustomerLoyaltyBalanceInBusinessParams{
+		CustomerID: customerID,
+		BusinessID: businessID,
+	})
+	if errors.Is(err, sql.ErrNoRows) {
+		return 0, ErrCustomerNotFound
+	}
+	return balance, err
+}
+
+func (p *POS) SetLoyaltyRule(ctx context.Context, params db.UpsertLoyaltyRuleParams) (db.LoyaltyRule, error) {
+	return p.queries.UpsertLoyaltyRule(ctx, params)
+}
+
+func (p *POS) GetOwnedBusinessID(ctx context.Context, params db.GetOwnedBusinessIDParams) (int32, error) {
+	return p.queries.GetOwnedBusinessID(ctx, params)
+}
+
+func (p *POS) GetStoreInBusiness(ctx context.Context, params db.GetStoreInBusinessParams) (int32, error) {
+	return p.queries.GetStoreInBusiness(ctx, params)
+}
+
+// earnLoyaltyPoints credits the sale's customer with points according to the
+// loyalty rule of the business the sale was made in. Businesses without a rule
+// don't earn points.
+func earnLoyaltyPoints(ctx context.Context, q *db.Queries, sale db.Sale) error {
+	rule, err := q.GetLoyaltyRuleForStore(ctx, sale.StoreID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil
+		}
+		return err
+	}
+
+	rate, _ := strconv.ParseFloat(rule.EarnRate, 64)
+	total, _ := strconv.ParseFloat(sale.TotalAmount, 64)
+	points := roundPoints(total*rate, rule.Rounding)
+	if points <= 0 {
+		return nil
+	}
+
+	_, err = q.CreateLoyaltyPoints(ctx, db.CreateLoyaltyPointsParams{
+		CustomerID: sale.CustomerID,
+		SaleID:     sale.ID,
+		Points:     points,
+	})
+	return err
+}
+
+// refundAmounts returns what each refunded line pays back and the refund
+// total, in cents. lines are the sold amounts of the lines being refunded.
+// Lines are refunded at what was actually paid for them, scaled by the sale
+// total over its subtotal, so discount and tax are refunded in proportion.
+// The total is rounded to the nearest cent and the lines take up the
+// rounding, largest remainder first, so they always add up to it.
+func refundAmounts(subtotal, total int64, lines map[int32]int64) (map[int32]int64, int64) {
+	ratio := big.NewRat(1, 1)
+	if subtotal > 0 {
+		ratio = big.NewRat(total, subtotal)
+	}
+
+	ids := make([]int32, 0, len(lines))
+	for id := range lines {
+		ids = append(ids, id)
+	}
+	sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+
+	amounts := make(map[int32]int64, len(lines))
+	remainders := make(map[int32]*big.Rat, len(lines))
+	exact := new(big.Rat)
+	var floored int64
+	for _, id := range ids {
+		amount := new(big.Rat).Mul(new(big.Rat).SetInt64(lines[id]), ratio)
+		exact.Add(exact, amount)
+		amounts[id] = roundRat(amount, "down")
+		remainders[id] = amount.Sub(amount, new(big.Rat).SetInt64(amounts[id]))
+		floored += amounts[id]
+	}
+
+	refundTotal := roundRat(exact, "nearest")
+	sort.SliceStable(ids, func(i, j int) bool { return remainders[ids[i]].Cmp(remainders[ids[j]]) > 0 })
+	for n := int64(0); n < refundTotal-floored; n++ {
+		amounts[ids[n%int64(len(ids))]]++
+	}
+	return amounts, refundTotal
+}
+
+// reverseLoyaltyPoints takes back the share of points earned on a sale that
+// matches the refunded share of its total. A full refund takes back whatever
+// is left. The customer's balance never goes below zero.
+func reverseLoyaltyPoints(ctx context.Context, q *db.Queries, sale db.Sale, refund db.Refund, share float64, full bool) error {
+	earned, err := q.GetSaleLoyaltyPoints(ctx, sale.ID)
+	if err != nil {
+		return err
+	}
+
+	remaining := earned.Earned - earned.Reversed
+	if remaining <= 0 {
+		return nil
+	}
+
+	points := remaining
+	if !full {
+		points = int32(math.Floor(float64(earned.Earned) * share))
+		if points > remaining {
+			points = remaining
+		}
+	}
+
+	balance, err := q.GetCustomerLoyaltyBalance(ctx, sale.CustomerID)
+	if err != nil {
+		return err
+	}
+	if points > balance {
+		points = balance
+	}
+	if points <= 0 {
+		return nil
+	}
+
+	_, err = q.CreateLoyaltyPoints(ctx, db.CreateLoyaltyPointsParams{
+		CustomerID: sale.CustomerID,
+		SaleID:     sale.ID,
+		RefundID:   sql.NullInt32{Int32: refund.ID, Valid: true},
+		Points:     -points,
+	})
+	return err
+}
+
+func roundPoints(points float64, rounding string) int32 {
+	// guard against float error turning 10 into 9.999999
+	const epsilon = 1e-9
+	switch rounding {
+	case "nearest":
+		return int32(math.Round(points))
+	case "up":
+		return int32(math.Ceil(points - epsilon))
+	default:
+		return int32(math.Floor(points + epsilon))
+	}
+}
+
+// priceSaleLines returns the lines at the store price of their variation, or
+// its base price when the store has none.
+func priceSaleLines(ctx context.Context, q *db.Queries, storeID int32, lines []SaleLine) ([]SaleLine, error) {
+	priced := make([]SaleLine, 0, len(lines))
+	for _, line := range lines {
+		price, err := q.GetVariationSalePrice(ctx, db.GetVariationSalePriceParams{
+			StoreID:     storeID,
+			VariationID: line.VariationID,
+		})
+		if err != nil {
+			if errors.Is(err, sql.ErrNoRows) {
+				return nil, fmt.Errorf("%w: %d", ErrSaleItemNotFound, line.VariationID)
+			}
+			return nil, err
+		}
+		line.UnitPrice, err = strconv.ParseFloat(price, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid price %q of variation %d", price, line.VariationID)
+		}
+		priced = append(priced, line)
+	}
+	return priced, nil
+}
+
+func formatAmount(amount float64) string {
+	return strconv.FormatFloat(amount, 'f', 2, 64)
+}
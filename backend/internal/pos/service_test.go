@@ -0,0 +1,106 @@
+package pos
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseCents(t *testing.T) {
+	tests := []struct {
+		amount string
+		want   int64
+	}{
+		{"12.34", 1234},
+		{"10", 1000},
+		{"0.00", 0},
+		{"0.1", 10},
+		{"0.005", 1},
+		{"-3.20", -320},
+		{"99999999.99", 9999999999},
+	}
+	for _, tt := range tests {
+		t.Run(tt.amount, func(t *testing.T) {
+			got, err := parseCents(tt.amount)
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+
+	_, err := parseCents("12,34")
+	assert.Error(t, err)
+}
+
+func TestRefundAmounts(t *testing.T) {
+	tests := []struct {
+		name      string
+		subtotal  int64
+		total     int64
+		lines     map[int32]int64
+		want      map[int32]int64
+		wantTotal int64
+	}{
+		{
+			name:      "no discount or tax",
+			subtotal:  1000,
+			total:     1000,
+			lines:     map[int32]int64{1: 600, 2: 400},
+			want:      map[int32]int64{1: 600, 2: 400},
+			wantTotal: 1000,
+		},
+		{
+			name:      "discount refunded in proportion",
+			subtotal:  1000,
+			total:     900,
+			lines:     map[int32]int64{1: 333, 2: 667},
+			want:      map[int32]int64{1: 300, 2: 600},
+			wantTotal: 900,
+		},
+		{
+			name:      "tax refunded in proportion",
+			subtotal:  1000,
+			total:     1075,
+			lines:     map[int32]int64{1: 1000},
+			want:      map[int32]int64{1: 1075},
+			wantTotal: 1075,
+		},
+		{
+			name:      "full refund adds up to the total paid",
+			subtotal:  300,
+			total:     100,
+			lines:     map[int32]int64{1: 100, 2: 100, 3: 100},
+			want:      map[int32]int64{1: 34, 2: 33, 3: 33},
+			wantTotal: 100,
+		},
+		{
+			name:      "partial refund rounds to the nearest cent",
+			subtotal:  300,
+			total:     100,
+			lines:     map[int32]int64{2: 200},
+			want:      map[int32]int64{2: 67},
+			wantTotal: 67,
+		},
+		{
+			name:      "free sale",
+			subtotal:  0,
+			total:     0,
+			lines:     map[int32]int64{1: 0},
+			want:      map[int32]int64{1: 0},
+			wantTotal: 0,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, total := refundAmounts(tt.subtotal, tt.total, tt.lines)
+			assert.Equal(t, tt.want, got)
+			assert.Equal(t, tt.wantTotal, total)
+
+			var sum int64
+			for _, amount := range got {
+				sum += amount
+			}
+			assert.Equal(t, total, sum)
+		})
+	}
+}
@@ -0,0 +1,102 @@
+package pos
+
+import (
+	"context"
+	"database/sql"
+	db "herp/db/sqlc"
+	"herp/internal/auth"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// storeSales keeps sale 1 in store 1 and sale 2 in store 2, and records the
+// refunds it is asked to make.
+type storeSales struct {
+	posService
+	refunds []RefundSaleParams
+}
+
+func (s *storeSales) GetSale(ctx context.Context, saleID int32, businessID sql.NullInt32) (db.Sale, error) {
+	if saleID != 1 && saleID != 2 {
+		return db.Sale{}, ErrSaleNotFound
+	}
+	return db.Sale{ID: saleID, StoreID: saleID}, nil
+}
+
+func (s *storeSales) RefundSale(ctx context.Context, args RefundSaleParams) (RefundResult, error) {
+	s.refunds = append(s.refunds, args)
+	return RefundResult{Refund: db.Refund{SaleID: args.SaleID, TotalAmount: "10.00"}, Sale: db.Sale{ID: args.SaleID}}, nil
+}
+
+func (s *storeSales) LogActivity(ctx context.Context, params db.LogActivityParams) (db.ActivityLog, error) {
+	return db.ActivityLog{}, nil
+}
+
+// assignedTo scopes the request to staff of storeID.
+func assignedTo(storeID int32) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		auth.SetStoreScope(c, auth.StoreScope{Restricted: true, StoreIDs: []int32{storeID}, User: true})
+	}
+}
+
+func refund(r *gin.Engine, saleID string) *httptest.ResponseRecorder {
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/pos/sales/"+saleID+"/refund", nil))
+	return w
+}
+
+func TestCashierStoreScope(t *testing.T) {
+	service := &storeSales{posService: posService{createSale: func(args CreateSaleParams) (SaleResult, error) {
+		return SaleResult{Sale: db.Sale{ID: 3, StoreID: args.StoreID, TotalAmount: "10.00", TaxAmount: "0.00", DiscountAmount: "0.00"}}, nil
+	}}}
+	r := newPOSRouter(service, testPOSConfig(), cashier(), assignedTo(1))
+
+	// store 1 is the cashier's
+	w := postSale(r, saleBody(1, 4))
+	require.Equal(t, http.StatusCreated, w.Code, w.Body.String())
+	w = refund(r, "1")
+	require.Equal(t, http.StatusCreated, w.Code, w.Body.String())
+
+	// store 2 is not
+	w = postSale(r, `{"store_id":2,"customer_id":1,"items":[{"item_id":1,"quantity":4,"price":2.5}],"payments":[{"method":"cash","amount":10}]}`)
+	assert.Equal(t, http.StatusForbidden, w.Code)
+	w = refund(r, "2")
+	assert.Equal(t, http.StatusForbidden, w.Code)
+	assert.Len(t, service.sales, 1)
+	require.Len(t, service.refunds, 1)
+	assert.Equal(t, int32(1), service.refunds[0].SaleID)
+
+	assert.Equal(t, http.StatusNotFound, refund(r, "9").Code)
+}
+
+func TestCreateSaleBusinessScope(t *testing.T) {
+	service := &posService{createSale: func(args CreateSaleParams) (SaleResult, error) {
+		return SaleResult{Sale: db.Sale{ID: 3, StoreID: args.StoreID, TotalAmount: "10.00", TaxAmount: "0.00", DiscountAmount: "0.00"}}, nil
+	}}
+	cfg := testPOSConfig()
+	cfg.BusinessScope = true
+	// staff of business 1 working in any of its stores
+	r := newPOSRouter(service, cfg, cashier(), func(c *gin.Context) {
+		auth.SetStoreScope(c, auth.StoreScope{User: true, BusinessID: sql.NullInt32{Int32: 1, Valid: true}})
+	})
+
+	w := postSale(r, saleBody(1, 4))
+	require.Equal(t, http.StatusCreated, w.Code, w.Body.String())
+
+	// store 3 belongs to business 2
+	w = postSale(r, `{"store_id":3,"customer_id":1,"items":[{"item_id":1,"quantity":4}],"payments":[{"method":"cash","amount":10}]}`)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Len(t, service.sales, 1)
+
+	// a customer of another business
+	service.createSale = func(args CreateSaleParams) (SaleResult, error) {
+		return SaleResult{}, ErrCustomerNotFound
+	}
+	w = postSale(r, saleBody(1, 4))
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+}
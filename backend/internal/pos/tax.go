@@ -0,0 +1,109 @@
+package pos
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"math/big"
+	"strconv"
+)
+
+var ErrTaxRateOverride = errors.New("tax rate differs from the tax rate of the business")
+
+// SaleTotals are the amounts of a sale in cents.
+type SaleTotals struct {
+	Subtotal int64
+	Discount int64
+	Tax      int64
+	Total    int64
+}
+
+// toCents converts an amount sent by a client to whole cents.
+func toCents(amount float64) int64 {
+	return int64(math.Round(amount * 100))
+}
+
+// formatCents formats whole cents as an amount with two decimals, e.g.
+// "-12.05", without going through a float.
+func formatCents(cents int64) string {
+	sign := ""
+	if cents < 0 {
+		sign = "-"
+		cents = -cents
+	}
+	return fmt.Sprintf("%s%d.%02d", sign, cents/100, cents%100)
+}
+
+// parseCents converts a stored amount, e.g. "12.34", to cents without going
+// through a float.
+func parseCents(amount string) (int64, error) {
+	r, ok := new(big.Rat).SetString(amount)
+	if !ok {
+		return 0, fmt.Errorf("invalid amount %q", amount)
+	}
+	return roundRat(r.Mul(r, big.NewRat(100, 1)), "nearest"), nil
+}
+
+// saleTaxRate returns the tax rate percentage a sale is taxed at, the one of
+// the business unless override is set. An override differing from the rate
+// of the business is only taken when allowed.
+func saleTaxRate(businessRate string, override *float64, allowOverride bool) (*big.Rat, error) {
+	rate, ok := new(big.Rat).SetString(businessRate)
+	if !ok {
+		return nil, fmt.Errorf("invalid business tax rate %q", businessRate)
+	}
+	if override == nil {
+		return rate, nil
+	}
+	// the shortest decimal form keeps 8.25 exactly 8.25
+	requested, ok := new(big.Rat).SetString(strconv.FormatFloat(*override, 'f', -1, 64))
+	if !ok {
+		return nil, fmt.Errorf("invalid tax rate %v", *override)
+	}
+	if requested.Cmp(rate) != 0 && !allowOverride {
+		return nil, fmt.Errorf("%w: %s", ErrTaxRateOverride, businessRate)
+	}
+	return requested, nil
+}
+
+// calculateSaleTotals adds up the lines, takes off the discount, in cents,
+// and taxes what remains at taxRate percent. Prices are taken as whole cents
+// and the tax is kept exact until the total is rounded to a cent the way the
+// business rounds, down, up or to the nearest. The tax takes up the rounding
+// so the amounts always add up.
+func calculateSaleTotals(lines []SaleLine, discount int64, taxRate *big.Rat, rounding string) SaleTotals {
+	var totals SaleTotals
+	for _, line := range lines {
+		totals.Subtotal += toCents(line.UnitPrice) * int64(line.Quantity)
+	}
+	totals.Discount = min(max(discount, 0), totals.Subtotal)
+
+	taxable := totals.Subtotal - totals.Discount
+	tax := new(big.Rat).Mul(big.NewRat(taxable, 100), taxRate)
+	total := tax.Add(tax, new(big.Rat).SetInt64(taxable))
+
+	totals.Total = roundRat(total, rounding)
+	totals.Tax = totals.Total - taxable
+	return totals
+}
+
+// roundRat rounds r to a whole number, down, up or to the nearest with
+// halves rounded away from zero.
+func roundRat(r *big.Rat, rounding string) int64 {
+	quo, rem := new(big.Int).QuoRem(r.Num(), r.Denom(), new(big.Int))
+	switch rounding {
+	case "up":
+		if rem.Sign() > 0 {
+			quo.Add(quo, big.NewInt(1))
+		}
+	case "down":
+		if rem.Sign() < 0 {
+			quo.Sub(quo, big.NewInt(1))
+		}
+	default:
+		if new(big.Int).Abs(new(big.Int).Lsh(rem, 1)).Cmp(r.Denom()) >= 0 {
+			quo.Add(quo, big.NewInt(int64(rem.Sign())))
+		}
+	}
+	return quo.Int64()
+}
@@ -0,0 +1,169 @@
+package pos
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRoundRat(t *testing.T) {
+	tests := []struct {
+		num, denom        int64
+		nearest, up, down int64
+	}{
+		{4, 1, 4, 4, 4},
+		{5, 2, 3, 3, 2},
+		{-5, 2, -3, -2, -3},
+		{7, 3, 2, 3, 2},
+		{8, 3, 3, 3, 2},
+		{-7, 3, -2, -2, -3},
+		{0, 1, 0, 0, 0},
+	}
+	for _, tt := range tests {
+		r := big.NewRat(tt.num, tt.denom)
+		assert.Equal(t, tt.nearest, roundRat(r, "nearest"), "%s nearest", r)
+		assert.Equal(t, tt.up, roundRat(r, "up"), "%s up", r)
+		assert.Equal(t, tt.down, roundRat(r, "down"), "%s down", r)
+		assert.Equal(t, tt.nearest, roundRat(r, ""), "%s without rounding set", r)
+	}
+}
+
+func TestFormatCents(t *testing.T) {
+	tests := map[int64]string{
+		0:                 "0.00",
+		5:                 "0.05",
+		-5:                "-0.05",
+		1205:              "12.05",
+		-1205:             "-12.05",
+		100:               "1.00",
+		9007199254740993:  "90071992547409.93", // past what a float64 holds exactly
+		-9007199254740993: "-90071992547409.93",
+	}
+	for cents, want := range tests {
+		assert.Equal(t, want, formatCents(cents), "%d cents", cents)
+	}
+}
+
+func TestCalculateSaleTotals(t *testing.T) {
+	tests := []struct {
+		name     string
+		lines    []SaleLine
+		discount int64
+		rate     string
+		// totals for nearest, up and down
+		nearest, up, down SaleTotals
+	}{
+		{
+			name:    "exact tax",
+			lines:   []SaleLine{{Quantity: 1, UnitPrice: 10}},
+			rate:    "7.5",
+			nearest: SaleTotals{Subtotal: 1000, Tax: 75, Total: 1075},
+			up:      SaleTotals{Subtotal: 1000, Tax: 75, Total: 1075},
+			down:    SaleTotals{Subtotal: 1000, Tax: 75, Total: 1075},
+		},
+		{
+			// 0.1 has no exact float, three of them are still 30 cents
+			name:    "float prices",
+			lines:   []SaleLine{{Quantity: 3, UnitPrice: 0.1}},
+			rate:    "7.5",
+			nearest: SaleTotals{Subtotal: 30, Tax: 2, Total: 32},
+			up:      SaleTotals{Subtotal: 30, Tax: 3, Total: 33},
+			down:    SaleTotals{Subtotal: 30, Tax: 2, Total: 32},
+		},
+		{
+			name:    "half a cent",
+			lines:   []SaleLine{{Quantity: 1, UnitPrice: 1}},
+			rate:    "2.5",
+			nearest: SaleTotals{Subtotal: 100, Tax: 3, Total: 103},
+			up:      SaleTotals{Subtotal: 100, Tax: 3, Total: 103},
+			down:    SaleTotals{Subtotal: 100, Tax: 2, Total: 102},
+		},
+		{
+			name:    "fractional rate",
+			lines:   []SaleLine{{Quantity: 3, UnitPrice: 19.99}},
+			rate:    "8.25",
+			nearest: SaleTotals{Subtotal: 5997, Tax: 495, Total: 6492},
+			up:      SaleTotals{Subtotal: 5997, Tax: 495, Total: 6492},
+			down:    SaleTotals{Subtotal: 5997, Tax: 494, Total: 6491},
+		},
+		{
+			name:     "discounted",
+			lines:    []SaleLine{{Quantity: 2, UnitPrice: 4.99}},
+			discount: 150,
+			rate:     "5",
+			nearest:  SaleTotals{Subtotal: 998, Discount: 150, Tax: 42, Total: 890},
+			up:       SaleTotals{Subtotal: 998, Discount: 150, Tax: 43, Total: 891},
+			down:     SaleTotals{Subtotal: 998, Discount: 150, Tax: 42, Total: 890},
+		},
+		{
+			name:     "discount over the subtotal",
+			lines:    []SaleLine{{Quantity: 1, UnitPrice: 5}},
+			discount: 700,
+			rate:     "7.5",
+			nearest:  SaleTotals{Subtotal: 500, Discount: 500},
+			up:       SaleTotals{Subtotal: 500, Discount: 500},
+			down:     SaleTotals{Subtotal: 500, Discount: 500},
+		},
+		{
+			name:     "negative discount",
+			lines:    []SaleLine{{Quantity: 1, UnitPrice: 5}},
+			discount: -100,
+			rate:     "0",
+			nearest:  SaleTotals{Subtotal: 500, Total: 500},
+			up:       SaleTotals{Subtotal: 500, Total: 500},
+			down:     SaleTotals{Subtotal: 500, Total: 500},
+		},
+		{
+			name:    "a cent",
+			lines:   []SaleLine{{Quantity: 1, UnitPrice: 0.01}},
+			rate:    "7.5",
+			nearest: SaleTotals{Subtotal: 1, Total: 1},
+			up:      SaleTotals{Subtotal: 1, Tax: 1, Total: 2},
+			down:    SaleTotals{Subtotal: 1, Total: 1},
+		},
+		{
+			name:    "several lines",
+			lines:   []SaleLine{{Quantity: 2, UnitPrice: 1500}, {Quantity: 1, UnitPrice: 499.99}},
+			rate:    "7.5",
+			nearest: SaleTotals{Subtotal: 349999, Tax: 26250, Total: 376249},
+			up:      SaleTotals{Subtotal: 349999, Tax: 26250, Total: 376249},
+			down:    SaleTotals{Subtotal: 349999, Tax: 26249, Total: 376248},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rate, ok := new(big.Rat).SetString(tt.rate)
+			require.True(t, ok)
+			for rounding, want := range map[string]SaleTotals{"nearest": tt.nearest, "up": tt.up, "down": tt.down} {
+				got := calculateSaleTotals(tt.lines, tt.discount, rate, rounding)
+				assert.Equal(t, want, got, rounding)
+				assert.Equal(t, got.Total, got.Subtotal-got.Discount+got.Tax, "%s doesn't add up", rounding)
+			}
+		})
+	}
+}
+
+func TestSaleTaxRate(t *testing.T) {
+	rate := func(v float64) *float64 { return &v }
+
+	got, err := saleTaxRate("7.5", nil, false)
+	require.NoError(t, err)
+	assert.Equal(t, big.NewRat(15, 2), got)
+
+	// the rate of the business may always be sent
+	got, err = saleTaxRate("7.50", rate(7.5), false)
+	require.NoError(t, err)
+	assert.Equal(t, big.NewRat(15, 2), got)
+
+	_, err = saleTaxRate("7.5", rate(8.25), false)
+	assert.ErrorIs(t, err, ErrTaxRateOverride)
+
+	got, err = saleTaxRate("7.5", rate(8.25), true)
+	require.NoError(t, err)
+	assert.Equal(t, big.NewRat(33, 4), got)
+
+	_, err = saleTaxRate("", nil, false)
+	assert.Error(t, err)
+}
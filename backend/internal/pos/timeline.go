@@ -0,0 +1,136 @@
+package pos
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	db "herp/db/sqlc"
+	"sort"
+	"time"
+)
+
+// TimelineEvent is one thing that happened to a sale. Quantity is a stock
+// movement caused by the event, negative when stock left the store.
+type TimelineEvent struct {
+	Type        string
+	OccurredAt  time.Time
+	ActorID     int32
+	RefundID    int32
+	VariationID int32
+	Quantity    int32
+	Amount      string
+	Points      int32
+	Details     string
+}
+
+// GetSaleTimeline assembles everything recorded about a sale, its stock
+// movements, refunds, loyalty points and activity log entries, oldest first.
+// A sale outside businessID is reported as not found.
+func (p *POS) GetSaleTimeline(ctx context.Context, saleID int32, businessID sql.NullInt32) (db.Sale, []TimelineEvent, error) {
+	sale, err := p.queries.GetSaleInBusiness(ctx, db.GetSaleInBusinessParams{
+		ID:         saleID,
+		BusinessID: businessID,
+	})
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return db.Sale{}, nil, ErrSaleNotFound
+		}
+		return db.Sale{}, nil, err
+	}
+
+	items, err := p.queries.ListSaleItems(ctx, sale.ID)
+	if err != nil {
+		return db.Sale{}, nil, err
+	}
+
+	refundItems, err := p.queries.ListSaleRefundItems(ctx, sale.ID)
+	if err != nil {
+		return db.Sale{}, nil, err
+	}
+
+	points, err := p.queries.ListSaleLoyaltyPoints(ctx, sale.ID)
+	if err != nil {
+		return db.Sale{}, nil, err
+	}
+
+	activity, err := p.queries.ListSaleActivity(ctx, sale.ID)
+	if err != nil {
+		return db.Sale{}, nil, err
+	}
+
+	events := []TimelineEvent{{
+		Type:       "sale_created",
+		OccurredAt: sale.CreatedAt.Time,
+		ActorID:    sale.CashierID,
+		Amount:     sale.TotalAmount,
+		Details:    fmt.Sprintf("sale of %d lines for customer %d", len(items), sale.CustomerID),
+	}}
+
+	for _, item := range items {
+		events = append(events, TimelineEvent{
+			Type:        "stock_deducted",
+			OccurredAt:  sale.CreatedAt.Time,
+			ActorID:     sale.CashierID,
+			VariationID: item.VariationID,
+			Quantity:    -item.Quantity,
+			Amount:      item.UnitPrice,
+		})
+	}
+
+	var lastRefundID int32
+	for _, item := range refundItems {
+		if item.RefundID != lastRefundID {
+			events = append(events, TimelineEvent{
+				Type:       "refunded",
+				OccurredAt: item.CreatedAt.Time,
+				ActorID:    item.RefundedBy,
+				RefundID:   item.RefundID,
+				Amount:     item.TotalAmount,
+			})
+			lastRefundID = item.RefundID
+		}
+		events = append(events, TimelineEvent{
+			Type:        "stock_restocked",
+			OccurredAt:  item.CreatedAt.Time,
+			ActorID:     item.RefundedBy,
+			RefundID:    item.RefundID,
+			VariationID: item.VariationID,
+			Quantity:    item.Quantity,
+			Amount:      item.Amount,
+		})
+	}
+
+	for _, entry := range points {
+		event := TimelineEvent{
+			Type:       "loyalty_points_earned",
+			OccurredAt: entry.CreatedAt.Time,
+			Points:     entry.Points,
+		}
+		if entry.RefundID.Valid {
+			event.Type = "loyalty_points_reversed"
+			event.RefundID = entry.RefundID.Int32
+		}
+		events = append(events, event)
+	}
+
+	for _, entry := range activity {
+		event := TimelineEvent{
+			Type:       "activity",
+			OccurredAt: entry.CreatedAt.Time,
+			ActorID:    entry.UserID,
+			Details:    fmt.Sprintf("%s: %s", entry.Action, entry.Details),
+		}
+		if entry.EntityType == "Refund" {
+			event.RefundID = entry.EntityID
+		}
+		events = append(events, event)
+	}
+
+	// stable so events recorded at the same instant keep the order above
+	sort.SliceStable(events, func(i, j int) bool {
+		return events[i].OccurredAt.Before(events[j].OccurredAt)
+	})
+
+	return sale, events, nil
+}
@@ -0,0 +1,112 @@
+package pos
+
+import (
+	"context"
+	"database/sql"
+	db "herp/db/sqlc"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// timelineQuerier holds one sale of business 10 and what was recorded
+// about it.
+type timelineQuerier struct {
+	Querier
+	sale        db.Sale
+	items       []db.SaleItem
+	refundItems []db.ListSaleRefundItemsRow
+	points      []db.LoyaltyPoint
+	activity    []db.ActivityLog
+}
+
+func (q *timelineQuerier) GetSaleInBusiness(ctx context.Context, params db.GetSaleInBusinessParams) (db.Sale, error) {
+	if params.ID != q.sale.ID || (params.BusinessID.Valid && params.BusinessID.Int32 != 10) {
+		return db.Sale{}, sql.ErrNoRows
+	}
+	return q.sale, nil
+}
+
+func (q *timelineQuerier) ListSaleItems(ctx context.Context, saleID int32) ([]db.SaleItem, error) {
+	return q.items, nil
+}
+
+func (q *timelineQuerier) ListSaleRefundItems(ctx context.Context, saleID int32) ([]db.ListSaleRefundItemsRow, error) {
+	return q.refundItems, nil
+}
+
+func (q *timelineQuerier) ListSaleLoyaltyPoints(ctx context.Context, saleID int32) ([]db.LoyaltyPoint, error) {
+	return q.points, nil
+}
+
+func (q *timelineQuerier) ListSaleActivity(ctx context.Context, saleID int32) ([]db.ActivityLog, error) {
+	return q.activity, nil
+}
+
+func at(t time.Time) sql.NullTime {
+	return sql.NullTime{Time: t, Valid: true}
+}
+
+func TestGetSaleTimeline(t *testing.T) {
+	created := time.Date(2026, 3, 1, 9, 0, 0, 0, time.UTC)
+	refunded := created.Add(2 * time.Hour)
+	resent := created.Add(3 * time.Hour)
+
+	// the lists are out of order on purpose, the timeline sorts them
+	q := &timelineQuerier{
+		sale: db.Sale{ID: 5, CashierID: 2, CustomerID: 9, TotalAmount: "30.00", CreatedAt: at(created)},
+		items: []db.SaleItem{
+			{SaleID: 5, VariationID: 3, Quantity: 2, UnitPrice: "10.00"},
+			{SaleID: 5, VariationID: 4, Quantity: 1, UnitPrice: "10.00"},
+		},
+		refundItems: []db.ListSaleRefundItemsRow{
+			{RefundID: 8, RefundedBy: 1, TotalAmount: "10.00", CreatedAt: at(refunded), VariationID: 3, Quantity: 1, Amount: "10.00"},
+		},
+		activity: []db.ActivityLog{
+			{UserID: 2, Action: "Resent Receipt", Details: "sent to customer@example.com", EntityType: "Sale", EntityID: 5, CreatedAt: at(resent)},
+			{UserID: 1, Action: "Refunded Sale", Details: "damaged", EntityType: "Refund", EntityID: 8, CreatedAt: at(refunded)},
+		},
+	}
+
+	sale, events, err := NewPOS(q, nil).GetSaleTimeline(context.Background(), 5, sql.NullInt32{Int32: 10, Valid: true})
+	require.NoError(t, err)
+	assert.Equal(t, int32(5), sale.ID)
+
+	var types []string
+	for _, event := range events {
+		types = append(types, event.Type)
+	}
+	assert.Equal(t, []string{
+		"sale_created",
+		"stock_deducted",
+		"stock_deducted",
+		"refunded",
+		"stock_restocked",
+		"activity",
+		"activity",
+	}, types)
+
+	for i := 1; i < len(events); i++ {
+		assert.False(t, events[i].OccurredAt.Before(events[i-1].OccurredAt), "event %d is before the one ahead of it", i)
+	}
+
+	assert.Equal(t, int32(-2), events[1].Quantity)
+	assert.Equal(t, int32(8), events[3].RefundID)
+	assert.Equal(t, int32(1), events[4].Quantity)
+	assert.Equal(t, "Refunded Sale: damaged", events[5].Details)
+	assert.Equal(t, int32(8), events[5].RefundID)
+	assert.Equal(t, "Resent Receipt: sent to customer@example.com", events[6].Details)
+	assert.Equal(t, resent, events[6].OccurredAt)
+}
+
+func TestGetSaleTimelineOtherBusiness(t *testing.T) {
+	q := &timelineQuerier{sale: db.Sale{ID: 5}}
+
+	_, _, err := NewPOS(q, nil).GetSaleTimeline(context.Background(), 5, sql.NullInt32{Int32: 20, Valid: true})
+	assert.ErrorIs(t, err, ErrSaleNotFound)
+
+	_, _, err = NewPOS(q, nil).GetSaleTimeline(context.Background(), 6, sql.NullInt32{Int32: 10, Valid: true})
+	assert.ErrorIs(t, err, ErrSaleNotFound)
+}
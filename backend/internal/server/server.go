@@ -20,6 +20,23 @@ type Server struct {
 	db         *sql.DB
 	router     *gin.Engine
 	port       string
+	checkers   []HealthChecker
+	workers    []worker
+}
+
+// worker is a background goroutine stopped on shutdown, done is closed once
+// it has returned.
+type worker struct {
+	name string
+	stop func()
+	done <-chan struct{}
+}
+
+// HealthChecker is a dependency the server can't serve requests without,
+// it is checked by Health and Readiness along with the database.
+type HealthChecker interface {
+	Name() string
+	HealthCheck(ctx context.Context) error
 }
 
 // Config holds server configuration
@@ -30,8 +47,9 @@ type Config struct {
 	ShutdownTimeout time.Duration
 }
 
-// New creates a new server instance
-func New(router *gin.Engine, db *sql.DB, cfg Config) *Server {
+// New creates a new server instance, the checkers are health checked along
+// with db
+func New(router *gin.Engine, db *sql.DB, cfg Config, checkers ...HealthChecker) *Server {
 	// Set default timeouts if not provided
 	if cfg.ReadTimeout == 0 {
 		cfg.ReadTimeout = 10 * time.Second
@@ -55,6 +73,7 @@ func New(router *gin.Engine, db *sql.DB, cfg Config) *Server {
 		db:         db,
 		router:     router,
 		port:       cfg.Port,
+		checkers:   checkers,
 	}
 }
 
@@ -114,6 +133,9 @@ func (s *Server) gracefulShutdown() error {
 		log.Println("HTTP server shutdown completed")
 	}
 
+	// Stop the background workers before the database they use is closed
+	s.stopWorkers(ctx)
+
 	// Close database connections
 	if s.db != nil {
 		log.Println("Closing database connections...")
@@ -159,9 +181,78 @@ func (s *Server) Health() error {
 			return fmt.Errorf("database health check failed: %w", err)
 		}
 	}
+	for _, checker := range s.checkers {
+		if err := checker.HealthCheck(context.Background()); err != nil {
+			return fmt.Errorf("%s health check failed: %w", checker.Name(), err)
+		}
+	}
 	return nil
 }
 
+// Readiness checks the database and every HealthChecker. It returns the
+// status of each by name, "ok" or the error, and whether all of them are up.
+func (s *Server) Readiness(ctx context.Context) (map[string]string, bool) {
+	statuses := make(map[string]string, len(s.checkers)+1)
+	ready := true
+	record := func(name string, err error) {
+		if err != nil {
+			statuses[name] = err.Error()
+			ready = false
+			return
+		}
+		statuses[name] = "ok"
+	}
+
+	if s.db != nil {
+		record("database", s.db.PingContext(ctx))
+	}
+	for _, checker := range s.checkers {
+		record(checker.Name(), checker.HealthCheck(ctx))
+	}
+	return statuses, ready
+}
+
+// readinessTimeout bounds the dependency checks of a readiness probe.
+const readinessTimeout = 3 * time.Second
+
+// Liveness answers the liveness probe, it checks no dependency.
+func Liveness(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{"status": "alive"})
+}
+
+// ReadinessHandler answers the readiness probe with the status of every
+// dependency, and 503 when any of them is down.
+func (s *Server) ReadinessHandler(c *gin.Context) {
+	ctx, cancel := context.WithTimeout(c.Request.Context(), readinessTimeout)
+	defer cancel()
+	checks, ready := s.Readiness(ctx)
+	if !ready {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"status": "unhealthy", "checks": checks})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"status": "healthy", "checks": checks})
+}
+
+// AddWorker registers a background goroutine that graceful shutdown stops,
+// after the HTTP server, and waits for until done is closed or the shutdown
+// times out.
+func (s *Server) AddWorker(name string, stop func(), done <-chan struct{}) {
+	s.workers = append(s.workers, worker{name: name, stop: stop, done: done})
+}
+
+func (s *Server) stopWorkers(ctx context.Context) {
+	for _, w := range s.workers {
+		w.stop()
+	}
+	for _, w := range s.workers {
+		select {
+		case <-w.done:
+		case <-ctx.Done():
+			log.Printf("Background worker %s did not stop in time", w.name)
+		}
+	}
+}
+
 // AddShutdownHook allows adding custom cleanup functions
 func (s *Server) AddShutdownHook(hook func()) {
 	// Register the shutdown handler
@@ -0,0 +1,128 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeChecker is a dependency that is down while err is set.
+type fakeChecker struct {
+	name string
+	err  error
+}
+
+func (f *fakeChecker) Name() string {
+	return f.name
+}
+
+func (f *fakeChecker) HealthCheck(ctx context.Context) error {
+	return f.err
+}
+
+type probeBody struct {
+	Status string            `json:"status"`
+	Checks map[string]string `json:"checks"`
+}
+
+func probe(t *testing.T, srv *Server, path string) (int, probeBody) {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.GET("/health/live", Liveness)
+	r.GET("/health/ready", srv.ReadinessHandler)
+	r.GET("/health", srv.ReadinessHandler)
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, path, nil))
+	var body probeBody
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &body))
+	return w.Code, body
+}
+
+func TestReadinessRedisDown(t *testing.T) {
+	redis := &fakeChecker{name: "redis"}
+	srv := New(gin.New(), nil, Config{Port: "0"}, redis)
+
+	for _, path := range []string{"/health/ready", "/health"} {
+		code, body := probe(t, srv, path)
+		assert.Equal(t, http.StatusOK, code, path)
+		assert.Equal(t, probeBody{Status: "healthy", Checks: map[string]string{"redis": "ok"}}, body)
+	}
+
+	redis.err = errors.New("dial tcp 127.0.0.1:6379: connect: connection refused")
+	for _, path := range []string{"/health/ready", "/health"} {
+		code, body := probe(t, srv, path)
+		assert.Equal(t, http.StatusServiceUnavailable, code, path)
+		assert.Equal(t, "unhealthy", body.Status)
+		assert.Equal(t, map[string]string{"redis": redis.err.Error()}, body.Checks)
+	}
+
+	// the process is still alive
+	code, body := probe(t, srv, "/health/live")
+	assert.Equal(t, http.StatusOK, code)
+	assert.Equal(t, "alive", body.Status)
+}
+
+func TestReadinessReportsEachDependency(t *testing.T) {
+	srv := New(gin.New(), nil, Config{Port: "0"},
+		&fakeChecker{name: "redis", err: errors.New("connection refused")},
+		&fakeChecker{name: "storage"},
+	)
+
+	checks, ready := srv.Readiness(context.Background())
+	assert.False(t, ready)
+	assert.Equal(t, map[string]string{"redis": "connection refused", "storage": "ok"}, checks)
+}
+
+func TestHealthFailingChecker(t *testing.T) {
+	failing := &fakeChecker{name: "redis", err: errors.New("connection refused")}
+	srv := New(gin.New(), nil, Config{Port: "0"}, &fakeChecker{name: "storage"}, failing)
+
+	err := srv.Health()
+	require.Error(t, err)
+	assert.ErrorIs(t, err, failing.err)
+	assert.Equal(t, "redis health check failed: connection refused", err.Error())
+
+	failing.err = nil
+	assert.NoError(t, srv.Health())
+}
+
+func TestHealthWithoutCheckers(t *testing.T) {
+	// the constructor still works without any
+	srv := New(gin.New(), nil, Config{Port: "0"})
+	assert.NoError(t, srv.Health())
+	checks, ready := srv.Readiness(context.Background())
+	assert.True(t, ready)
+	assert.Empty(t, checks)
+}
+
+func TestStopWorkers(t *testing.T) {
+	srv := New(gin.New(), nil, Config{})
+	var stopped []string
+	quick := make(chan struct{})
+	srv.AddWorker("quick", func() {
+		stopped = append(stopped, "quick")
+		close(quick)
+	}, quick)
+	// never reports done
+	srv.AddWorker("stuck", func() { stopped = append(stopped, "stuck") }, make(chan struct{}))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	srv.stopWorkers(ctx)
+
+	// every worker is told to stop, the shutdown gives up on the stuck one
+	assert.Equal(t, []string{"quick", "stuck"}, stopped)
+	assert.GreaterOrEqual(t, time.Since(start), 50*time.Millisecond)
+	assert.Less(t, time.Since(start), 2*time.Second)
+}
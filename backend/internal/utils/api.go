@@ -9,19 +9,25 @@ const(
 	INVALID_REQUEST_DATA = "invalid request data"
 	SERVERERROR = "an error ocurred, try again"
 )
-// Response structure for both success and error responses
+// Response structure for both success and error responses. Every handler
+// answers with it, through SuccessResponse ({message, data}) or
+// ErrorResponse ({error}), next to the API version and a "success" or
+// "error" status.
 type APIResponse struct {
 	Version string `json:"version"`
 	Status  string `json:"status"`
 	Message string `json:"message,omitempty"`
 	Data    any    `json:"data,omitempty"`
 	Error   string `json:"error,omitempty"`
+	// Code tells errors apart that clients handle differently, e.g. an
+	// expired token they can refresh
+	Code string `json:"code,omitempty"`
 }
 
 func getVersion() string {
     version := os.Getenv("API_VERSION")
     if version == "" {
-        return "1.0.0" // Fallback version if not set
+        return "1.1.0" // Fallback version if not set
     }
     return version
 }
@@ -45,3 +51,25 @@ func ErrorResponse(c *gin.Context, statusCode int, errorMsg string) {
 	})
 }
 
+// ErrorResponseWithCode sends an error response with a code clients can act
+// on
+func ErrorResponseWithCode(c *gin.Context, statusCode int, errorMsg, code string) {
+	c.JSON(statusCode, APIResponse{
+		Version: getVersion(),
+		Status:  "error",
+		Error:   errorMsg,
+		Code:    code,
+	})
+}
+
+// ErrorResponseWithData sends an error response that carries details about
+// the error, e.g. the rules a request broke
+func ErrorResponseWithData(c *gin.Context, statusCode int, errorMsg string, data any) {
+	c.JSON(statusCode, APIResponse{
+		Version: getVersion(),
+		Status:  "error",
+		Error:   errorMsg,
+		Data:    data,
+	})
+}
+
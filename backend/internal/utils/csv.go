@@ -0,0 +1,14 @@
+package utils
+
+import "strings"
+
+// CSVSafe makes a cell of an exported CSV safe to open in a spreadsheet.
+// Cells starting with a character spreadsheets read as the start of a
+// formula are prefixed with a quote, so a username like =HYPERLINK(...)
+// picked by whoever made a login attempt is shown as text instead of run.
+func CSVSafe(s string) string {
+	if s != "" && strings.ContainsRune("=+-@\t\r", rune(s[0])) {
+		return "'" + s
+	}
+	return s
+}
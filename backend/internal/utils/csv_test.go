@@ -0,0 +1,25 @@
+package utils
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCSVSafe(t *testing.T) {
+	tests := map[string]string{
+		"ada":                            "ada",
+		"":                               "",
+		"=HYPERLINK(\"http://x\",\"a\")": "'=HYPERLINK(\"http://x\",\"a\")",
+		"+2348000000000":                 "'+2348000000000",
+		"-1":                             "'-1",
+		"@SUM(A1)":                       "'@SUM(A1)",
+		"\tcmd":                          "'\tcmd",
+		"\rcmd":                          "'\rcmd",
+		"a=b":                            "a=b",
+		"Mozilla/5.0 (X11; Linux)":       "Mozilla/5.0 (X11; Linux)",
+	}
+	for in, want := range tests {
+		assert.Equal(t, want, CSVSafe(in), "%q", in)
+	}
+}
@@ -2,16 +2,15 @@ package utils
 
 import (
 	"bytes"
+	"crypto/rand"
 	"encoding/json"
 	"errors"
 	"fmt"
 	"herp/internal/config"
 
 	"io"
-	"math/rand"
 	"net/http"
 	"text/template"
-	"time"
 )
 
 type Plunk struct {
@@ -25,11 +24,26 @@ type EmailRequest struct {
 	Body    string `json:"body"`
 }
 
-// GenerateOTP generates a 7-digit numeric OTP
-func GenerateOTP() string {
-    rand.Seed(time.Now().UnixNano()) 
-    otp := rand.Intn(9000000) + 1000000 
-    return fmt.Sprintf("%07d", otp)
+// DefaultOTPLength is the number of digits of a code when none is configured.
+const DefaultOTPLength = 6
+
+// GenerateOTP generates a numeric OTP of length digits from crypto/rand.
+// Bytes of 250 and up are dropped so every digit is equally likely.
+func GenerateOTP(length int) string {
+	if length < 1 {
+		length = DefaultOTPLength
+	}
+	otp := make([]byte, 0, length)
+	buf := make([]byte, length)
+	for len(otp) < length {
+		rand.Read(buf)
+		for _, b := range buf {
+			if b < 250 && len(otp) < length {
+				otp = append(otp, '0'+b%10)
+			}
+		}
+	}
+	return string(otp)
 }
 
 
@@ -0,0 +1,144 @@
+package utils
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+const (
+	emailQueueKey = "email_queue"
+	emailRetryKey = "email_queue:retry" // sorted set scored by when to retry
+	emailDeadKey  = "email_queue:dead"
+	// dead letters kept for inspection, the oldest are dropped
+	emailDeadMax = 1000
+)
+
+type queuedEmail struct {
+	To        string `json:"to"`
+	Subject   string `json:"subject"`
+	Body      string `json:"body"`
+	Attempts  int    `json:"attempts"`
+	LastError string `json:"last_error,omitempty"`
+}
+
+// EmailQueue is an Emailer that queues emails in redis and sends them in the
+// background through another Emailer, so a provider that is down doesn't
+// fail requests. A failed email is retried after backoff, doubled after every
+// attempt, and moved to the email_queue:dead list after maxAttempts. Queued
+// emails survive restarts.
+type EmailQueue struct {
+	client      *redis.Client
+	sender      Emailer
+	maxAttempts int
+	backoff     time.Duration
+}
+
+func NewEmailQueue(client *redis.Client, sender Emailer, maxAttempts int, backoff time.Duration) *EmailQueue {
+	return &EmailQueue{
+		client:      client,
+		sender:      sender,
+		maxAttempts: maxAttempts,
+		backoff:     backoff,
+	}
+}
+
+// SendEmail queues an email, it only fails when redis can't be reached.
+func (q *EmailQueue) SendEmail(to, subject, htmlBody string) error {
+	job, err := json.Marshal(queuedEmail{To: to, Subject: subject, Body: htmlBody})
+	if err != nil {
+		return err
+	}
+	return q.client.LPush(context.Background(), emailQueueKey, job).Err()
+}
+
+// Start sends queued emails until ctx is done. Failures are passed to
+// onError. The returned channel is closed once the email being sent, if any,
+// is out and the queue has stopped.
+func (q *EmailQueue) Start(ctx context.Context, onError func(error)) <-chan struct{} {
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for ctx.Err() == nil {
+			if err := q.promoteRetries(ctx); err != nil && ctx.Err() == nil {
+				onError(fmt.Errorf("promote email retries: %w", err))
+			}
+
+			result, err := q.client.BRPop(ctx, time.Second, emailQueueKey).Result()
+			if err != nil {
+				if !errors.Is(err, redis.Nil) && ctx.Err() == nil {
+					onError(fmt.Errorf("read email queue: %w", err))
+					time.Sleep(time.Second)
+				}
+				continue
+			}
+			if err := q.send(ctx, result[1]); err != nil {
+				onError(err)
+			}
+		}
+	}()
+	return done
+}
+
+func (q *EmailQueue) send(ctx context.Context, raw string) error {
+	var job queuedEmail
+	if err := json.Unmarshal([]byte(raw), &job); err != nil {
+		q.client.LPush(ctx, emailDeadKey, raw)
+		return fmt.Errorf("decode queued email: %w", err)
+	}
+
+	err := q.sender.SendEmail(job.To, job.Subject, job.Body)
+	if err == nil {
+		return nil
+	}
+
+	job.Attempts++
+	job.LastError = err.Error()
+	data, _ := json.Marshal(job)
+	if job.Attempts >= q.maxAttempts {
+		pipe := q.client.TxPipeline()
+		pipe.LPush(ctx, emailDeadKey, data)
+		pipe.LTrim(ctx, emailDeadKey, 0, emailDeadMax-1)
+		if _, perr := pipe.Exec(ctx); perr != nil {
+			return fmt.Errorf("dead letter email to %s: %w", job.To, perr)
+		}
+		return fmt.Errorf("gave up sending email to %s after %d attempts: %w", job.To, job.Attempts, err)
+	}
+
+	retryAt := time.Now().Add(q.backoff << (job.Attempts - 1))
+	if zerr := q.client.ZAdd(ctx, emailRetryKey, redis.Z{Score: float64(retryAt.Unix()), Member: data}).Err(); zerr != nil {
+		return fmt.Errorf("queue retry of email to %s: %w", job.To, zerr)
+	}
+	return fmt.Errorf("sending email to %s failed, attempt %d of %d: %w", job.To, job.Attempts, q.maxAttempts, err)
+}
+
+// promoteRetries moves the emails due for a retry back onto the queue. Only
+// the instance removing an email from the retry set queues it, so several
+// instances can share the queue.
+func (q *EmailQueue) promoteRetries(ctx context.Context) error {
+	due, err := q.client.ZRangeByScore(ctx, emailRetryKey, &redis.ZRangeBy{
+		Min: "-inf",
+		Max: strconv.FormatInt(time.Now().Unix(), 10),
+	}).Result()
+	if err != nil {
+		return err
+	}
+	for _, job := range due {
+		removed, err := q.client.ZRem(ctx, emailRetryKey, job).Result()
+		if err != nil {
+			return err
+		}
+		if removed == 0 {
+			continue
+		}
+		if err := q.client.LPush(ctx, emailQueueKey, job).Err(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
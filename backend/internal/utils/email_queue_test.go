@@ -0,0 +1,139 @@
+package utils
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"herp/pkg/redis/redistest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// flakyEmailer fails its first failures sends and records who the rest went
+// to.
+type flakyEmailer struct {
+	mu       sync.Mutex
+	failures int
+	attempts int
+	sent     []string
+}
+
+func (e *flakyEmailer) SendEmail(to, subject, htmlBody string) error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.attempts++
+	if e.attempts <= e.failures {
+		return errors.New("provider unavailable")
+	}
+	e.sent = append(e.sent, to)
+	return nil
+}
+
+func (e *flakyEmailer) delivered() []string {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return append([]string(nil), e.sent...)
+}
+
+func TestEmailQueueEnqueue(t *testing.T) {
+	client := redistest.Client(t)
+	sender := &flakyEmailer{}
+	q := NewEmailQueue(client, sender, 3, time.Millisecond)
+
+	require.NoError(t, q.SendEmail("ada@example.com", "Verify", "<p>123456</p>"))
+
+	// queued, not sent
+	assert.Empty(t, sender.delivered())
+	queued, err := client.LRange(context.Background(), emailQueueKey, 0, -1).Result()
+	require.NoError(t, err)
+	require.Len(t, queued, 1)
+	var job queuedEmail
+	require.NoError(t, json.Unmarshal([]byte(queued[0]), &job))
+	assert.Equal(t, queuedEmail{To: "ada@example.com", Subject: "Verify", Body: "<p>123456</p>"}, job)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := q.Start(ctx, func(err error) { t.Errorf("unexpected error: %v", err) })
+	assert.Eventually(t, func() bool { return len(sender.delivered()) == 1 }, 5*time.Second, 10*time.Millisecond)
+	assert.Zero(t, client.LLen(context.Background(), emailQueueKey).Val())
+
+	// stops within the one second it blocks on the queue
+	cancel()
+	select {
+	case <-done:
+	case <-time.After(3 * time.Second):
+		t.Fatal("email queue did not stop")
+	}
+}
+
+func TestEmailQueueRetry(t *testing.T) {
+	client := redistest.Client(t)
+	sender := &flakyEmailer{failures: 2}
+	q := NewEmailQueue(client, sender, 3, time.Millisecond)
+	require.NoError(t, q.SendEmail("ada@example.com", "Reset", "<p>654321</p>"))
+
+	var mu sync.Mutex
+	var failed []error
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	q.Start(ctx, func(err error) {
+		mu.Lock()
+		defer mu.Unlock()
+		failed = append(failed, err)
+	})
+
+	// the third attempt goes through
+	assert.Eventually(t, func() bool { return len(sender.delivered()) == 1 }, 10*time.Second, 10*time.Millisecond)
+	mu.Lock()
+	assert.Len(t, failed, 2)
+	mu.Unlock()
+	assert.Zero(t, client.ZCard(context.Background(), emailRetryKey).Val())
+	assert.Zero(t, client.LLen(context.Background(), emailDeadKey).Val())
+}
+
+func TestEmailQueueDeadLetter(t *testing.T) {
+	client := redistest.Client(t)
+	ctx := context.Background()
+	sender := &flakyEmailer{failures: 10}
+	q := NewEmailQueue(client, sender, 2, time.Millisecond)
+	require.NoError(t, q.SendEmail("ada@example.com", "Reset", "<p>654321</p>"))
+
+	// first attempt, kept for a retry
+	raw := client.RPop(ctx, emailQueueKey).Val()
+	err := q.send(ctx, raw)
+	assert.ErrorContains(t, err, "attempt 1 of 2")
+	retries := client.ZRange(ctx, emailRetryKey, 0, -1).Val()
+	require.Len(t, retries, 1)
+
+	require.NoError(t, q.promoteRetries(ctx))
+	assert.Zero(t, client.ZCard(ctx, emailRetryKey).Val())
+	raw = client.RPop(ctx, emailQueueKey).Val()
+	require.NotEmpty(t, raw)
+
+	// second attempt, given up on
+	err = q.send(ctx, raw)
+	assert.ErrorContains(t, err, "gave up")
+	assert.Zero(t, client.ZCard(ctx, emailRetryKey).Val())
+	dead := client.LRange(ctx, emailDeadKey, 0, -1).Val()
+	require.Len(t, dead, 1)
+	var job queuedEmail
+	require.NoError(t, json.Unmarshal([]byte(dead[0]), &job))
+	assert.Equal(t, 2, job.Attempts)
+	assert.Equal(t, "provider unavailable", job.LastError)
+	assert.Equal(t, 2, sender.attempts)
+}
+
+func TestEmailQueueRetryNotDue(t *testing.T) {
+	client := redistest.Client(t)
+	ctx := context.Background()
+	q := NewEmailQueue(client, &flakyEmailer{failures: 1}, 3, time.Hour)
+	require.NoError(t, q.SendEmail("ada@example.com", "Reset", "<p>654321</p>"))
+
+	assert.Error(t, q.send(ctx, client.RPop(ctx, emailQueueKey).Val()))
+	require.NoError(t, q.promoteRetries(ctx))
+	assert.Equal(t, int64(1), client.ZCard(ctx, emailRetryKey).Val(), "retried an hour from now")
+	assert.Zero(t, client.LLen(ctx, emailQueueKey).Val())
+}
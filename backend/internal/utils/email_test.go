@@ -0,0 +1,55 @@
+package utils
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGenerateOTPLength(t *testing.T) {
+	for _, length := range []int{1, 4, 6, 8, 12} {
+		assert.Len(t, GenerateOTP(length), length)
+	}
+	// nothing configured falls back to the default
+	assert.Len(t, GenerateOTP(0), DefaultOTPLength)
+	assert.Len(t, GenerateOTP(-3), DefaultOTPLength)
+}
+
+func TestGenerateOTPCharset(t *testing.T) {
+	for n := 0; n < 1000; n++ {
+		otp := GenerateOTP(8)
+		assert.Empty(t, strings.Trim(otp, "0123456789"), "otp %q has a non digit", otp)
+	}
+}
+
+func TestGenerateOTPDistribution(t *testing.T) {
+	const codes = 10000
+	const length = 6
+
+	seen := make(map[string]bool, codes)
+	var counts [length][10]int
+	for n := 0; n < codes; n++ {
+		otp := GenerateOTP(length)
+		seen[otp] = true
+		for i, c := range otp {
+			counts[i][c-'0']++
+		}
+	}
+
+	// 10000 draws out of a million codes, a handful of repeats at most
+	assert.Greater(t, len(seen), codes-100)
+
+	// every digit turns up about a tenth of the time in every position, a
+	// modulo bias towards the low digits would be well outside the bound
+	for i := range counts {
+		chi := 0.0
+		expected := float64(codes) / 10
+		for _, count := range counts[i] {
+			d := float64(count) - expected
+			chi += d * d / expected
+		}
+		// 9 degrees of freedom, p < 0.0001
+		assert.Less(t, chi, 33.7, "digits at position %d: %v", i, counts[i])
+	}
+}
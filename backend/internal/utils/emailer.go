@@ -0,0 +1,83 @@
+package utils
+
+import (
+	"fmt"
+	"herp/internal/config"
+	"mime"
+	"net"
+	"net/http"
+	"net/smtp"
+	"strconv"
+	"strings"
+)
+
+// Emailer sends HTML emails.
+type Emailer interface {
+	SendEmail(to, subject, htmlBody string) error
+}
+
+// NewEmailer returns the Emailer picked by EMAIL_PROVIDER, plunk, smtp or
+// none.
+func NewEmailer(cfg *config.Config) (Emailer, error) {
+	switch cfg.EmailProvider {
+	case "plunk":
+		return &Plunk{HttpClient: http.DefaultClient, Config: cfg}, nil
+	case "smtp":
+		if cfg.SMTPHost == "" || cfg.EmailFrom == "" {
+			return nil, fmt.Errorf("smtp email needs SMTP_HOST and EMAIL_FROM")
+		}
+		return &SMTP{
+			Host:     cfg.SMTPHost,
+			Port:     cfg.SMTPPort,
+			Username: cfg.SMTPUsername,
+			Password: cfg.SMTPPassword,
+			From:     cfg.EmailFrom,
+		}, nil
+	case "none":
+		return NopEmailer{}, nil
+	default:
+		return nil, fmt.Errorf("unknown email provider %q", cfg.EmailProvider)
+	}
+}
+
+// SMTP sends emails through an SMTP server, upgrading to TLS with STARTTLS
+// when the server offers it. Username can be left empty for servers that
+// don't authenticate.
+type SMTP struct {
+	Host     string
+	Port     int
+	Username string
+	Password string
+	From     string
+}
+
+func (s *SMTP) SendEmail(to, subject, htmlBody string) error {
+	// header values can't carry line breaks, they would start new headers
+	to = stripLineBreaks(to)
+	var msg strings.Builder
+	msg.WriteString("From: " + stripLineBreaks(s.From) + "\r\n")
+	msg.WriteString("To: " + to + "\r\n")
+	msg.WriteString("Subject: " + mime.QEncoding.Encode("utf-8", stripLineBreaks(subject)) + "\r\n")
+	msg.WriteString("MIME-Version: 1.0\r\n")
+	msg.WriteString("Content-Type: text/html; charset=UTF-8\r\n")
+	msg.WriteString("\r\n")
+	msg.WriteString(htmlBody)
+
+	var auth smtp.Auth
+	if s.Username != "" {
+		auth = smtp.PlainAuth("", s.Username, s.Password, s.Host)
+	}
+	addr := net.JoinHostPort(s.Host, strconv.Itoa(s.Port))
+	return smtp.SendMail(addr, auth, s.From, []string{to}, []byte(msg.String()))
+}
+
+func stripLineBreaks(s string) string {
+	return strings.NewReplacer("\r", "", "\n", "").Replace(s)
+}
+
+// NopEmailer drops every email, for development and tests.
+type NopEmailer struct{}
+
+func (NopEmailer) SendEmail(to, subject, htmlBody string) error {
+	return nil
+}
@@ -0,0 +1,67 @@
+package utils
+
+import (
+	"errors"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	DefaultPageLimit = 20
+	MaxPageLimit     = 100
+)
+
+var (
+	ErrInvalidPage  = errors.New("page must be a positive number")
+	ErrInvalidLimit = errors.New("limit must be a positive number")
+)
+
+// Pagination is the page a list endpoint was asked for.
+type Pagination struct {
+	Page   int
+	Limit  int
+	Offset int
+}
+
+// ParsePagination reads the page and limit query parameters. Page defaults to
+// 1 and limit to DefaultPageLimit, a limit over MaxPageLimit is clamped to it.
+func ParsePagination(c *gin.Context) (Pagination, error) {
+	page, err := strconv.Atoi(c.DefaultQuery("page", "1"))
+	if err != nil || page < 1 {
+		return Pagination{}, ErrInvalidPage
+	}
+
+	limit, err := strconv.Atoi(c.DefaultQuery("limit", strconv.Itoa(DefaultPageLimit)))
+	if err != nil || limit < 1 {
+		return Pagination{}, ErrInvalidLimit
+	}
+	if limit > MaxPageLimit {
+		limit = MaxPageLimit
+	}
+
+	return Pagination{Page: page, Limit: limit, Offset: (page - 1) * limit}, nil
+}
+
+// PaginatedResponse is the envelope list endpoints answer a page with.
+type PaginatedResponse struct {
+	Items any   `json:"items"`
+	Page  int   `json:"page" example:"1"`    // Current page number
+	Limit int   `json:"limit" example:"20"`  // Number of items per page
+	Total int64 `json:"total" example:"100"` // Number of items over every page
+	Pages int   `json:"pages" example:"5"`   // Number of pages
+}
+
+// Response wraps a page of items, out of total, in a PaginatedResponse.
+func (p Pagination) Response(items any, total int64) PaginatedResponse {
+	response := PaginatedResponse{
+		Items: items,
+		Page:  p.Page,
+		Limit: p.Limit,
+		Total: total,
+	}
+	if p.Limit > 0 {
+		response.Pages = int((total + int64(p.Limit) - 1) / int64(p.Limit))
+	}
+	return response
+}
@@ -0,0 +1,79 @@
+package utils
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func parsePagination(query string) (Pagination, error) {
+	gin.SetMode(gin.TestMode)
+	c, _ := gin.CreateTestContext(httptest.NewRecorder())
+	c.Request = httptest.NewRequest(http.MethodGet, "/list?"+query, nil)
+	return ParsePagination(c)
+}
+
+func TestParsePaginationDefaults(t *testing.T) {
+	p, err := parsePagination("")
+	require.NoError(t, err)
+	assert.Equal(t, Pagination{Page: 1, Limit: DefaultPageLimit, Offset: 0}, p)
+
+	p, err = parsePagination("page=3&limit=15")
+	require.NoError(t, err)
+	assert.Equal(t, Pagination{Page: 3, Limit: 15, Offset: 30}, p)
+}
+
+func TestParsePaginationClamp(t *testing.T) {
+	p, err := parsePagination("page=2&limit=500")
+	require.NoError(t, err)
+	assert.Equal(t, Pagination{Page: 2, Limit: MaxPageLimit, Offset: MaxPageLimit}, p)
+
+	p, err = parsePagination("limit=100")
+	require.NoError(t, err)
+	assert.Equal(t, MaxPageLimit, p.Limit)
+}
+
+func TestParsePaginationInvalid(t *testing.T) {
+	tests := []struct {
+		query string
+		err   error
+	}{
+		{"page=0", ErrInvalidPage},
+		{"page=-1", ErrInvalidPage},
+		{"page=two", ErrInvalidPage},
+		{"page=", ErrInvalidPage},
+		{"limit=0", ErrInvalidLimit},
+		{"limit=-5", ErrInvalidLimit},
+		{"limit=lots", ErrInvalidLimit},
+		{"page=1.5", ErrInvalidPage},
+	}
+	for _, tt := range tests {
+		_, err := parsePagination(tt.query)
+		assert.ErrorIs(t, err, tt.err, tt.query)
+	}
+}
+
+func TestPaginationResponse(t *testing.T) {
+	p := Pagination{Page: 2, Limit: 20, Offset: 20}
+	tests := []struct {
+		total int64
+		pages int
+	}{
+		{0, 0},
+		{1, 1},
+		{20, 1},
+		{21, 2},
+		{100, 5},
+	}
+	for _, tt := range tests {
+		response := p.Response([]string{"a"}, tt.total)
+		assert.Equal(t, tt.pages, response.Pages, "%d items", tt.total)
+		assert.Equal(t, tt.total, response.Total)
+		assert.Equal(t, 2, response.Page)
+		assert.Equal(t, 20, response.Limit)
+	}
+}
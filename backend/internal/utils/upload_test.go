@@ -0,0 +1,235 @@
+package utils
+
+import (
+	"bytes"
+	"context"
+	"herp/pkg/storage"
+	"image"
+	"image/color"
+	"image/gif"
+	"image/png"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// memFiles is a FileStore in memory, serving files from /files.
+type memFiles struct {
+	files map[string][]byte
+	types map[string]string
+}
+
+func newMemFiles() *memFiles {
+	return &memFiles{files: map[string][]byte{}, types: map[string]string{}}
+}
+
+func (m *memFiles) Save(ctx context.Context, key string, r io.Reader, contentType string) (string, error) {
+	body, err := io.ReadAll(r)
+	if err != nil {
+		return "", err
+	}
+	m.files[key] = body
+	m.types[key] = contentType
+	return "/files/" + key, nil
+}
+
+func (m *memFiles) Open(ctx context.Context, fileURL string) (io.ReadCloser, error) {
+	body, ok := m.files[strings.TrimPrefix(fileURL, "/files/")]
+	if !ok {
+		return nil, storage.ErrNotFound
+	}
+	return io.NopCloser(bytes.NewReader(body)), nil
+}
+
+func (m *memFiles) Delete(ctx context.Context, key string) error {
+	delete(m.files, key)
+	return nil
+}
+
+// pngImage encodes a blank image of the given size.
+func pngImage(t *testing.T, width, height int) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	require.NoError(t, png.Encode(&buf, image.NewRGBA(image.Rect(0, 0, width, height))))
+	return buf.Bytes()
+}
+
+// uploadContext is a request carrying the file as the logo form field, or
+// no file at all when filename is empty.
+func uploadContext(t *testing.T, filename string, body []byte) *gin.Context {
+	t.Helper()
+	var form bytes.Buffer
+	writer := multipart.NewWriter(&form)
+	require.NoError(t, writer.WriteField("name", "Palmwine Express"))
+	if filename != "" {
+		part, err := writer.CreateFormFile("logo", filename)
+		require.NoError(t, err)
+		_, err = part.Write(body)
+		require.NoError(t, err)
+	}
+	require.NoError(t, writer.Close())
+
+	gin.SetMode(gin.TestMode)
+	c, _ := gin.CreateTestContext(httptest.NewRecorder())
+	c.Request = httptest.NewRequest(http.MethodPost, "/business", &form)
+	c.Request.Header.Set("Content-Type", writer.FormDataContentType())
+	return c
+}
+
+var imageOptions = UploadOptions{
+	MaxSize:      1 << 20,
+	AllowedTypes: []string{"image/jpeg", "image/png", "image/webp"},
+}
+
+func TestUploadFileSaves(t *testing.T) {
+	files := newMemFiles()
+	logo := pngImage(t, 16, 16)
+
+	uploaded, err := UploadFile(uploadContext(t, "logo.png", logo), files, "logo", "logos", imageOptions)
+	require.NoError(t, err)
+	assert.Regexp(t, `^/files/logos/\d+_logo\.png$`, uploaded.URL)
+	assert.Equal(t, uploaded.URL, uploaded.ThumbnailURL)
+
+	// saved whole, under the key the URL names
+	require.Len(t, files.files, 1)
+	key := strings.TrimPrefix(uploaded.URL, "/files/")
+	assert.Equal(t, logo, files.files[key])
+	assert.Equal(t, "image/png", files.types[key])
+
+	r, err := files.Open(context.Background(), uploaded.URL)
+	require.NoError(t, err)
+	defer r.Close()
+	saved, err := io.ReadAll(r)
+	require.NoError(t, err)
+	assert.Equal(t, logo, saved)
+}
+
+func TestUploadFileNoFile(t *testing.T) {
+	files := newMemFiles()
+
+	uploaded, err := UploadFile(uploadContext(t, "", nil), files, "logo", "logos", imageOptions)
+	require.NoError(t, err)
+	assert.Empty(t, uploaded.URL)
+	assert.Empty(t, files.files)
+}
+
+func TestUploadFileLocal(t *testing.T) {
+	dir := t.TempDir()
+	files := storage.NewPublic(storage.NewLocal(dir), "")
+	logo := pngImage(t, 16, 16)
+
+	uploaded, err := UploadFile(uploadContext(t, "logo.png", logo), files, "logo", "images", imageOptions)
+	require.NoError(t, err)
+	assert.Regexp(t, `^/images/\d+_logo\.png$`, uploaded.URL)
+
+	// the local store serves the file back by its URL
+	r, err := files.Open(context.Background(), uploaded.URL)
+	require.NoError(t, err)
+	defer r.Close()
+	saved, err := io.ReadAll(r)
+	require.NoError(t, err)
+	assert.Equal(t, logo, saved)
+}
+
+// webpImage is a 1x1 lossless WebP.
+var webpImage = []byte("RIFF\x1a\x00\x00\x00WEBPVP8L\x0d\x00\x00\x00\x2f\x00\x00\x00\x10\x07\x10\x11\x11\x88\x88\xfe\x07\x00")
+
+func gifImage(t *testing.T) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	require.NoError(t, gif.Encode(&buf, image.NewPaletted(image.Rect(0, 0, 4, 4), color.Palette{color.Black}), nil))
+	return buf.Bytes()
+}
+
+func TestUploadFileTypes(t *testing.T) {
+	tests := []struct {
+		name     string
+		filename string
+		body     func(t *testing.T) []byte
+		err      error
+	}{
+		{"png", "logo.png", func(t *testing.T) []byte { return pngImage(t, 8, 8) }, nil},
+		{"webp", "logo.webp", func(t *testing.T) []byte { return webpImage }, nil},
+		{"upper case extension", "LOGO.WEBP", func(t *testing.T) []byte { return webpImage }, nil},
+		{"gif", "logo.gif", gifImage, ErrFileType},
+		{"gif named png", "logo.png", gifImage, ErrFileType},
+		{"png named webp", "logo.webp", func(t *testing.T) []byte { return pngImage(t, 8, 8) }, ErrFileType},
+		{"text", "logo.png", func(t *testing.T) []byte { return []byte("not an image at all") }, ErrFileType},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			files := newMemFiles()
+			uploaded, err := UploadFile(uploadContext(t, tt.filename, tt.body(t)), files, "logo", "logos", imageOptions)
+			if tt.err != nil {
+				assert.ErrorIs(t, err, tt.err)
+				assert.NotErrorIs(t, err, ErrFileTooLarge)
+				assert.Empty(t, files.files, "a rejected file was saved")
+				return
+			}
+			require.NoError(t, err)
+			assert.NotEmpty(t, uploaded.URL)
+			assert.Len(t, files.files, 1)
+		})
+	}
+}
+
+func TestUploadFileTooLarge(t *testing.T) {
+	files := newMemFiles()
+	logo := pngImage(t, 64, 64)
+	options := imageOptions
+	options.MaxSize = int64(len(logo)) - 1
+
+	_, err := UploadFile(uploadContext(t, "logo.png", logo), files, "logo", "logos", options)
+	assert.ErrorIs(t, err, ErrFileTooLarge)
+	assert.NotErrorIs(t, err, ErrFileType)
+	assert.Contains(t, err.Error(), "max")
+	assert.Empty(t, files.files)
+
+	// right at the limit is fine
+	options.MaxSize = int64(len(logo))
+	_, err = UploadFile(uploadContext(t, "logo.png", logo), files, "logo", "logos", options)
+	assert.NoError(t, err)
+}
+
+func TestUploadFileAllowedTypesFromOptions(t *testing.T) {
+	options := imageOptions
+	options.AllowedTypes = []string{"image/png"}
+
+	_, err := UploadFile(uploadContext(t, "logo.webp", webpImage), newMemFiles(), "logo", "logos", options)
+	assert.ErrorIs(t, err, ErrFileType)
+
+	options.AllowedTypes = append(options.AllowedTypes, "image/gif")
+	_, err = UploadFile(uploadContext(t, "logo.gif", gifImage(t)), newMemFiles(), "logo", "logos", options)
+	assert.NoError(t, err)
+}
+
+func TestUploadFileThumbnail(t *testing.T) {
+	files := newMemFiles()
+	options := imageOptions
+	options.ThumbnailSize = 256
+
+	uploaded, err := UploadFile(uploadContext(t, "logo.png", pngImage(t, 1024, 512)), files, "logo", "logos", options)
+	require.NoError(t, err)
+	assert.Regexp(t, `^/files/logos/thumbs/\d+_logo\.png$`, uploaded.ThumbnailURL)
+	require.Len(t, files.files, 2, "the original and its thumbnail")
+
+	thumb := files.files[strings.TrimPrefix(uploaded.ThumbnailURL, "/files/")]
+	cfg, _, err := image.DecodeConfig(bytes.NewReader(thumb))
+	require.NoError(t, err)
+	assert.Equal(t, 256, cfg.Width)
+	assert.Equal(t, 128, cfg.Height)
+
+	// an image that already fits is its own thumbnail
+	files = newMemFiles()
+	uploaded, err = UploadFile(uploadContext(t, "logo.png", pngImage(t, 200, 100)), files, "logo", "logos", options)
+	require.NoError(t, err)
+	assert.Equal(t, uploaded.URL, uploaded.ThumbnailURL)
+	assert.Len(t, files.files, 1)
+}
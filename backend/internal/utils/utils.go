@@ -1,12 +1,19 @@
 package utils
 
 import (
+	"bytes"
 	"context"
 	"database/sql"
+	"errors"
 	"fmt"
+	"herp/internal/config"
+	"herp/pkg/imaging"
+	"herp/pkg/storage"
+	"io"
 	"net/http"
-	"os"
+	"path"
 	"path/filepath"
+	"slices"
 	"strings"
 	"time"
 
@@ -30,64 +37,124 @@ func WriteActivityDetails(username, email, action string, time time.Time) string
 	return fmt.Sprintf("User %s with email %s performed action: %s at %s", username, email, action, time)
 }
 
-// UploadFile validates and saves an uploaded file.
-// Returns the relative URL path (e.g. /images/123_logo.png) or an error.
-func UploadFile(c *gin.Context, fieldName string, saveDir string, maxSize int64) (string, error) {
+var (
+	ErrFileTooLarge = errors.New("file too large")
+	ErrFileType     = errors.New("file type not allowed")
+)
+
+// UploadOptions restricts what UploadFile accepts and sets the thumbnail
+// made of images.
+type UploadOptions struct {
+	MaxSize int64 // in bytes
+	// MIME types detected from the content, e.g. image/png
+	AllowedTypes []string
+	// longest edge of the thumbnail in pixels, 0 makes no thumbnail
+	ThumbnailSize int
+}
+
+// ImageUploadOptions are the options for uploaded images set in the config.
+func ImageUploadOptions(cfg *config.Config) UploadOptions {
+	return UploadOptions{
+		MaxSize:       cfg.UploadMaxSize,
+		AllowedTypes:  cfg.UploadImageTypes,
+		ThumbnailSize: cfg.UploadThumbnailSize,
+	}
+}
+
+// UploadedFile is where a saved upload is served from. ThumbnailURL is the
+// URL of the original when the image is already smaller than a thumbnail.
+type UploadedFile struct {
+	URL          string
+	ThumbnailURL string
+}
+
+// extensionsByType are the file extensions accepted for each content type.
+var extensionsByType = map[string][]string{
+	"image/jpeg": {".jpg", ".jpeg"},
+	"image/png":  {".png"},
+	"image/webp": {".webp"},
+	"image/gif":  {".gif"},
+}
+
+// UploadFile validates an uploaded file and saves it in files under saveDir,
+// together with a thumbnail under saveDir/thumbs when options ask for one.
+// Returns the URLs the files are served from (e.g. /images/123_logo.png when
+// kept on local disk), or empty URLs when no file was sent. Rejected files
+// fail with ErrFileTooLarge or ErrFileType.
+func UploadFile(c *gin.Context, files storage.FileStore, fieldName string, saveDir string, options UploadOptions) (UploadedFile, error) {
 	file, err := c.FormFile(fieldName)
 	if err != nil {
-		// No file provided
-		return "", err
+		if errors.Is(err, http.ErrMissingFile) || errors.Is(err, http.ErrNotMultipart) {
+			return UploadedFile{}, nil
+		}
+		return UploadedFile{}, err
 	}
 
 	// Check file size
-	if file.Size > maxSize {
-		return "", fmt.Errorf("file too large, max %d bytes allowed", maxSize)
+	if file.Size > options.MaxSize {
+		return UploadedFile{}, fmt.Errorf("%w, max %d bytes allowed", ErrFileTooLarge, options.MaxSize)
 	}
 
-	// Check file extension
-	ext := strings.ToLower(filepath.Ext(file.Filename))
-	allowedExt := map[string]bool{".jpg": true, ".jpeg": true, ".png": true}
-	if !allowedExt[ext] {
-		return "", fmt.Errorf("invalid file extension: only JPG/PNG allowed")
-	}
-
-	// Check MIME type
 	openedFile, err := file.Open()
 	if err != nil {
-		return "", fmt.Errorf("could not open uploaded file: %v", err)
+		return UploadedFile{}, fmt.Errorf("could not open uploaded file: %v", err)
 	}
 	defer openedFile.Close()
 
-	buffer := make([]byte, 512)
-	if _, err := openedFile.Read(buffer); err != nil {
-		return "", fmt.Errorf("could not read uploaded file: %v", err)
-	}
-
-	contentType := http.DetectContentType(buffer)
-	allowedMime := map[string]bool{
-		"image/jpeg": true,
-		"image/png":  true,
+	// The whole file is read once, the content type is sniffed from the
+	// same bytes that are saved
+	body, err := io.ReadAll(io.LimitReader(openedFile, options.MaxSize+1))
+	if err != nil {
+		return UploadedFile{}, fmt.Errorf("could not read uploaded file: %v", err)
 	}
-	if !allowedMime[contentType] {
-		return "", fmt.Errorf("invalid file type: only JPG/PNG allowed")
+	if int64(len(body)) > options.MaxSize {
+		return UploadedFile{}, fmt.Errorf("%w, max %d bytes allowed", ErrFileTooLarge, options.MaxSize)
 	}
 
-	// Ensure save directory exists
-	if _, statErr := os.Stat(saveDir); os.IsNotExist(statErr) {
-		os.MkdirAll(saveDir, os.ModePerm)
+	// Check MIME type, and that the extension agrees with it
+	contentType := http.DetectContentType(body)
+	if !slices.Contains(options.AllowedTypes, contentType) {
+		return UploadedFile{}, fmt.Errorf("%w: %s, allowed %s", ErrFileType, contentType, strings.Join(options.AllowedTypes, ", "))
+	}
+	ext := strings.ToLower(filepath.Ext(file.Filename))
+	if !slices.Contains(extensionsByType[contentType], ext) {
+		return UploadedFile{}, fmt.Errorf("%w: extension %q does not match %s", ErrFileType, ext, contentType)
+	}
+
+	var thumb []byte
+	var thumbType string
+	resized := false
+	if options.ThumbnailSize > 0 && strings.HasPrefix(contentType, "image/") {
+		thumb, thumbType, resized, err = imaging.Thumbnail(body, options.ThumbnailSize)
+		if err != nil {
+			if errors.Is(err, imaging.ErrTooManyPixels) {
+				return UploadedFile{}, fmt.Errorf("%w: %v", ErrFileTooLarge, err)
+			}
+			if errors.Is(err, imaging.ErrUnsupported) {
+				return UploadedFile{}, fmt.Errorf("%w: %v", ErrFileType, err)
+			}
+			return UploadedFile{}, fmt.Errorf("could not make thumbnail: %v", err)
+		}
 	}
 
-	// Generate unique filename
-	filename := fmt.Sprintf("%d_%s", time.Now().Unix(), filepath.Base(file.Filename))
-	filePath := filepath.Join(saveDir, filename)
+	// Generate unique key
+	name := fmt.Sprintf("%d_%s", time.Now().Unix(), filepath.Base(file.Filename))
+	key := path.Join(saveDir, name)
 
-	// Save file
-	if saveErr := c.SaveUploadedFile(file, filePath); saveErr != nil {
-		return "", fmt.Errorf("could not save file: %v", saveErr)
+	var uploaded UploadedFile
+	uploaded.URL, err = files.Save(c.Request.Context(), key, bytes.NewReader(body), contentType)
+	if err != nil {
+		return UploadedFile{}, fmt.Errorf("could not save file: %v", err)
+	}
+	uploaded.ThumbnailURL = uploaded.URL
+	if resized {
+		thumbKey := path.Join(saveDir, "thumbs", strings.TrimSuffix(name, filepath.Ext(name))+extensionsByType[thumbType][0])
+		uploaded.ThumbnailURL, err = files.Save(c.Request.Context(), thumbKey, bytes.NewReader(thumb), thumbType)
+		if err != nil {
+			return UploadedFile{}, fmt.Errorf("could not save thumbnail: %v", err)
+		}
 	}
-
-	// Return relative URL for serving via Gin Static
-	return "/" + filePath, nil
+	return uploaded, nil
 }
 
 // ToNullString converts a pointer to a string to a sql.NullString.
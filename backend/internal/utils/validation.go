@@ -0,0 +1,109 @@
+package utils
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"reflect"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
+	"github.com/go-playground/validator/v10"
+)
+
+// VALIDATION_FAILED is the error of requests whose fields failed validation.
+const VALIDATION_FAILED = "validation failed"
+
+// RegisterValidationFieldNames makes the validator report fields by their
+// json, or else form, name rather than the Go one, so the errors of
+// BindingErrorResponse match the request. Call it once before serving.
+func RegisterValidationFieldNames() {
+	v, ok := binding.Validator.Engine().(*validator.Validate)
+	if !ok {
+		return
+	}
+	v.RegisterTagNameFunc(func(field reflect.StructField) string {
+		for _, tag := range []string{"json", "form"} {
+			name, _, _ := strings.Cut(field.Tag.Get(tag), ",")
+			if name == "-" {
+				return ""
+			}
+			if name != "" {
+				return name
+			}
+		}
+		return field.Name
+	})
+}
+
+// ValidationErrors maps the fields of a request that failed validation to
+// the reason, e.g. {"email": "is required"}. Nested fields are keyed by
+// their path, like items[0].quantity. It returns nil when err doesn't come
+// from the validator.
+func ValidationErrors(err error) map[string]string {
+	var verrs validator.ValidationErrors
+	if !errors.As(err, &verrs) {
+		return nil
+	}
+	fields := make(map[string]string, len(verrs))
+	for _, fe := range verrs {
+		// the namespace starts with the name of the request struct
+		field := fe.Namespace()
+		if _, rest, ok := strings.Cut(field, "."); ok {
+			field = rest
+		}
+		fields[field] = validationReason(fe)
+	}
+	return fields
+}
+
+func validationReason(fe validator.FieldError) string {
+	switch fe.Tag() {
+	case "required", "required_if", "required_with", "required_without":
+		return "is required"
+	case "email":
+		return "must be a valid email address"
+	case "url":
+		return "must be a valid URL"
+	case "oneof":
+		return fmt.Sprintf("must be one of: %s", fe.Param())
+	case "min":
+		if fe.Kind() == reflect.String || fe.Kind() == reflect.Slice {
+			return fmt.Sprintf("must have at least %s characters or items", fe.Param())
+		}
+		return fmt.Sprintf("must be at least %s", fe.Param())
+	case "max":
+		if fe.Kind() == reflect.String || fe.Kind() == reflect.Slice {
+			return fmt.Sprintf("must have at most %s characters or items", fe.Param())
+		}
+		return fmt.Sprintf("must be at most %s", fe.Param())
+	case "len":
+		return fmt.Sprintf("must have a length of %s", fe.Param())
+	case "gt":
+		return fmt.Sprintf("must be greater than %s", fe.Param())
+	case "gte":
+		return fmt.Sprintf("must be at least %s", fe.Param())
+	case "lt":
+		return fmt.Sprintf("must be less than %s", fe.Param())
+	case "lte":
+		return fmt.Sprintf("must be at most %s", fe.Param())
+	case "eqfield":
+		return fmt.Sprintf("must match %s", fe.Param())
+	}
+	if fe.Param() != "" {
+		return fmt.Sprintf("failed the %s=%s check", fe.Tag(), fe.Param())
+	}
+	return fmt.Sprintf("failed the %s check", fe.Tag())
+}
+
+// BindingErrorResponse answers a request that failed to bind. Fields that
+// failed validation are listed by ValidationErrors with a 422, anything else,
+// e.g. malformed JSON, is a 400.
+func BindingErrorResponse(c *gin.Context, err error) {
+	if fields := ValidationErrors(err); fields != nil {
+		ErrorResponseWithData(c, http.StatusUnprocessableEntity, VALIDATION_FAILED, fields)
+		return
+	}
+	ErrorResponse(c, http.StatusBadRequest, INVALID_REQUEST_DATA)
+}
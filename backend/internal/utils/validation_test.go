@@ -0,0 +1,70 @@
+package utils
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type signupRequest struct {
+	Email    string `json:"email" binding:"required,email"`
+	Password string `json:"password" binding:"required,min=8"`
+	Role     string `json:"role" binding:"omitempty,oneof=admin cashier"`
+	Items    []struct {
+		Quantity int `json:"quantity" binding:"gt=0"`
+	} `json:"items" binding:"dive"`
+}
+
+func bindSignup(body string) *httptest.ResponseRecorder {
+	gin.SetMode(gin.TestMode)
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request = httptest.NewRequest(http.MethodPost, "/signup", strings.NewReader(body))
+	c.Request.Header.Set("Content-Type", "application/json")
+
+	var req signupRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		BindingErrorResponse(c, err)
+	}
+	return w
+}
+
+func TestBindingErrorFields(t *testing.T) {
+	RegisterValidationFieldNames()
+
+	w := bindSignup(`{"password":"short","role":"owner","items":[{"quantity":1},{"quantity":0}]}`)
+	require.Equal(t, http.StatusUnprocessableEntity, w.Code)
+	var body struct {
+		Error string            `json:"error"`
+		Data  map[string]string `json:"data"`
+	}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &body))
+	assert.Equal(t, VALIDATION_FAILED, body.Error)
+	assert.Equal(t, map[string]string{
+		"email":             "is required",
+		"password":          "must have at least 8 characters or items",
+		"role":              "must be one of: admin cashier",
+		"items[1].quantity": "must be greater than 0",
+	}, body.Data)
+
+	w = bindSignup(`{"email":"nope","password":"long enough"}`)
+	require.Equal(t, http.StatusUnprocessableEntity, w.Code)
+	body.Data = nil
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &body))
+	assert.Equal(t, map[string]string{"email": "must be a valid email address"}, body.Data)
+}
+
+func TestBindingErrorMalformed(t *testing.T) {
+	w := bindSignup(`{"email":`)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Contains(t, w.Body.String(), INVALID_REQUEST_DATA)
+	assert.NotContains(t, w.Body.String(), `"data"`)
+
+	assert.Nil(t, ValidationErrors(nil))
+}
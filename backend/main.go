@@ -1,24 +1,35 @@
 package main
 
 import (
+	"context"
 	"fmt"
 	db "herp/db/sqlc"
 	_ "herp/docs/swagger"
 	"herp/internal/auth"
 	"herp/internal/config"
+	"herp/internal/core/backup"
 	"herp/internal/core/business"
 	"herp/internal/core/ilogs"
 	"herp/internal/core/inventory"
 	"herp/internal/core/store"
+	"herp/internal/core/webhook"
 	"herp/internal/docs"
 	"herp/internal/middleware"
 	"herp/internal/pos"
 	"herp/internal/server"
+	"herp/internal/utils"
+	"herp/pkg/currency"
 	"herp/pkg/database"
+	"herp/pkg/jwt"
 	"herp/pkg/monitoring/logging"
+	"herp/pkg/monitoring/metrics"
+	"herp/pkg/password"
 	"herp/pkg/ratelimit"
 	"herp/pkg/redis"
+	"herp/pkg/storage"
+	"herp/pkg/webhooks"
 	"log"
+	"path/filepath"
 	"strings"
 	"time"
 
@@ -82,6 +93,10 @@ func main() {
 		}
 	}
 
+	if err := auth.SeedDefaults(context.Background(), dbs); err != nil {
+		log.Fatalf("Unable to seed default roles and permissions - %v", err)
+	}
+
 	// Initialize sqlc
 	log.Println("Setting up database queries")
 	queries := db.New(dbs)
@@ -103,13 +118,14 @@ func main() {
 
 	rs := redisClient.RawClient()
 
-	// Initialize rate limiter
-	rateLimiter := ratelimit.NewRateLimit(rs)
+	// Initialize rate limiter, a fixed window keeps memory flat under floods
+	// of requests, login limiting in the auth service keeps the sliding window
+	rateLimiter := ratelimit.NewRateLimitWithMode(rs, ratelimit.FixedWindow)
 
 	// Initialiaze services
 	authSvc := auth.NewService(
 		queries,
-		cfg.JWTSecret,
+		jwt.NewKeySet(cfg.JWTKeyID, cfg.JWTSecret, jwt.ParseKeys(cfg.JWTPreviousKeys)).WithIssuer(cfg.JWTIssuer, cfg.JWTAudience),
 		cfg.JWTRefreshSecret,
 		time.Duration(cfg.JWTExpiry)*time.Minute,
 		time.Duration(cfg.JWTRefreshExpiry)*time.Hour,
@@ -121,9 +137,27 @@ func main() {
 		cfg.IPRateLimit,
 		dbs,
 		logging.NewLogger(cfg),
+		password.Policy{
+			MinLength:     cfg.PasswordMinLength,
+			RequireUpper:  cfg.PasswordUpper,
+			RequireLower:  cfg.PasswordLower,
+			RequireDigit:  cfg.PasswordDigit,
+			RequireSymbol: cfg.PasswordSymbol,
+			History:       cfg.PasswordHistory,
+		},
+		cfg.RequireVerifiedEmail,
+		cfg.OTPLength,
+		cfg.BcryptCost,
 	)
 
 	r := gin.Default()
+	utils.RegisterValidationFieldNames()
+
+	// Record request count and duration for /metrics
+	r.Use(metrics.Middleware())
+	if err := metrics.RegisterDB(dbs); err != nil {
+		log.Fatalf("Failed to register database metrics: %v", err)
+	}
 
 	// Apply global IP rate limiting middleware
 	r.Use(ratelimit.IPRateLimitMiddleware(rateLimiter, cfg.IPRateLimit, time.Minute))
@@ -146,21 +180,32 @@ func main() {
 		c.Next()
 	})
 
+	// Give every request an ID for the logs, before the logger reads it
+	r.Use(middleware.RequestID())
+
 	// Register request logging middleware (stdout + file)
 	r.Use(middleware.NewRequestLogger("tmp/logs/logs.json", cfg))
 
 	// Setup API documentation
 	docsConfig := docs.DefaultSwaggerConfig()
 	docsConfig.Host = "localhost:" + cfg.Port
-	docsConfig.Enabled = true
+	if cfg.DocsHost != "" {
+		docsConfig.Host = cfg.DocsHost
+	}
+	docsConfig.Schemes = cfg.DocsSchemes
+	docsConfig.Version = strings.TrimPrefix(cfg.ApiVersion, "v")
+	docsConfig.Enabled = cfg.GinMode != gin.ReleaseMode
+	if cfg.DocsEnabled != nil {
+		docsConfig.Enabled = *cfg.DocsEnabled
+	}
 
 	// Add CORS for docs
 	r.Use(docs.CORSForDocs())
 
 	// Add API docs middleware
-	r.Use(docs.APIDocsMiddleware())
+	r.Use(docs.APIDocsMiddleware(docsConfig))
 
-	r.Static("/images", "./images")
+	r.Static("/images", filepath.Join(cfg.UploadDir, "images"))
 
 	// Setup Swagger documentation
 	docs.SetupSwagger(r, docsConfig)
@@ -169,31 +214,81 @@ func main() {
 	docs.SetupRedocly(r, docsConfig)
 
 	// register routes
-	v1 := r.Group("/api/v1")
+	v1 := r.Group("/api/v1", middleware.CORS(middleware.CORSConfig{
+		AllowedOrigins:   cfg.CORSAllowedOrigins,
+		AllowedMethods:   cfg.CORSAllowedMethods,
+		AllowedHeaders:   cfg.CORSAllowedHeaders,
+		ExposedHeaders:   cfg.CORSExposedHeaders,
+		AllowCredentials: cfg.CORSAllowCredentials,
+		MaxAge:           cfg.CORSMaxAge,
+	}))
+	// preflights are answered by the CORS middleware
+	v1.OPTIONS("/*path", func(c *gin.Context) { c.Status(204) })
 
 	logger := logging.NewLogger(cfg)
 
 	// public routes
-	authHandler := auth.NewHandler(authSvc, cfg, logger, cfg.GinMode)
+	sender, err := utils.NewEmailer(cfg)
+	if err != nil {
+		log.Fatalf("Failed to set up email: %v", err)
+	}
+	emailer := utils.NewEmailQueue(rs, sender, cfg.EmailMaxAttempts, time.Duration(cfg.EmailRetryBackoff)*time.Second)
+	authHandler := auth.NewHandler(authSvc, cfg, logger, cfg.GinMode, emailer)
 	v1.POST("/auth/login", authHandler.Login)
 	v1.POST("/auth/register", authHandler.RegisterAdmin)
 	v1.POST("/auth/verify-email", authHandler.VerifyEmail)
+	v1.POST("/auth/resend-verification", authHandler.ResendVerification)
 	v1.POST("/auth/forgot-password", authHandler.ForgotPassword)
 	v1.POST("/auth/reset-password", authHandler.ResetPassword)
+	v1.POST("/auth/accept-invite", authHandler.AcceptInvite)
+	// refresh only needs the refresh token, the access token has usually expired by then
+	v1.POST("/auth/refresh", authHandler.Refresh)
 
 	// secured routes (JWT required)
 	secured := v1.Group("")
 	secured.Use(auth.AuthMiiddleware(authSvc))
+	secured.Use(ratelimit.UserRateLimitMiddleware(rateLimiter, cfg.UserRateLimit, time.Minute))
 	secured.POST("/auth/logout", authHandler.Logout)
-	secured.POST("/auth/refresh", authHandler.Refresh)
+	secured.GET("/auth/me", authHandler.Me)
+	secured.PATCH("/auth/me", authHandler.UpdateMe)
+	secured.POST("/auth/change-password", authHandler.ChangePassword)
+	secured.GET("/auth/sessions", authHandler.ListSessions)
+	secured.DELETE("/auth/sessions/:id", authHandler.RevokeSession)
+	secured.GET("/users/:id/notification-preferences", authHandler.GetNotificationPreferences)
+	secured.PUT("/users/:id/notification-preferences", authHandler.UpdateNotificationPreferences)
+	secured.GET("/admin/config", auth.AdminMiddleware(authSvc), authHandler.GetConfig)
 
 	// Admin auth routes
-	adminHandler := auth.NewAdminHandler(authSvc)
+	adminHandler := auth.NewAdminHandler(authSvc, cfg, logger, emailer)
 	adminHandler.RegisterAdminRoutes(secured, authSvc)
 
+	// Uploaded files, logos of businesses and brands
+	var uploads storage.FileStore = storage.NewPublic(storage.NewLocal(cfg.UploadDir), "")
+	if cfg.UploadS3Bucket != "" {
+		uploadBucket := storage.NewS3(storage.S3Config{
+			Bucket:    cfg.UploadS3Bucket,
+			Region:    cfg.UploadS3Region,
+			Endpoint:  cfg.UploadS3Endpoint,
+			AccessKey: cfg.UploadS3AccessKey,
+			SecretKey: cfg.UploadS3SecretKey,
+		})
+		publicURL := cfg.UploadPublicURL
+		if publicURL == "" {
+			publicURL = uploadBucket.BaseURL()
+		}
+		uploads = storage.NewPublic(uploadBucket, publicURL)
+	}
+
+	// Exchange rates for converting amounts between currencies
+	var rateProvider currency.RateProvider = currency.NewStatic(cfg.CurrencyBase, currency.ParseRates(cfg.CurrencyRates))
+	if cfg.CurrencyRatesURL != "" {
+		rateProvider = currency.NewHTTP(cfg.CurrencyRatesURL)
+	}
+	rates := currency.NewConverter(rateProvider, redisClient, time.Duration(cfg.CurrencyRatesTTL)*time.Minute)
+
 	// Core business setup
 	businessService := business.NewBusiness(queries, dbs)
-	coreHandler := business.NewBusinessHandler(businessService, cfg, logger)
+	coreHandler := business.NewBusinessHandler(businessService, cfg, logger, uploads, rates)
 	coreHandler.RegisterRoutes(secured, authSvc)
 
 	// Logs routes
@@ -208,11 +303,52 @@ func main() {
 
 	// Inventory
 	inventoryService := inventory.NewInventory(queries, dbs)
-	inventoryHandler := inventory.NewInventoryHandler(inventoryService, logger)
+	seeded, err := inventoryService.Seed(context.Background(), inventory.ParseSeedUnits(cfg.SeedUnits), cfg.SeedColors)
+	if err != nil {
+		log.Fatalf("Failed to seed units and colors: %v", err)
+	}
+	if len(seeded.Units) > 0 || len(seeded.Colors) > 0 {
+		log.Printf("Seeded units %v and colors %v", seeded.Units, seeded.Colors)
+	}
+	inventoryHandler := inventory.NewInventoryHandler(inventoryService, cfg, logger, uploads)
 	inventoryHandler.RegisterRoutes(secured, authSvc)
 
+	// Webhooks, deliveries run in the background
+	webhookService := webhook.NewWebhook(queries, dbs)
+	dispatcher := webhooks.NewDispatcher(webhookService, webhooks.Options{
+		MaxAttempts: cfg.WebhookMaxAttempts,
+		Backoff:     time.Duration(cfg.WebhookBackoff) * time.Second,
+		Timeout:     time.Duration(cfg.WebhookTimeout) * time.Second,
+		QueueSize:   cfg.WebhookQueueSize,
+		Workers:     cfg.WebhookWorkers,
+	})
+	webhookHandler := webhook.NewHandler(webhookService, logger)
+	webhookHandler.RegisterRoutes(secured, authSvc)
+
 	// POS routes
-	pos.RegisterRoutes(secured, authSvc)
+	posService := pos.NewPOS(queries, dbs)
+	posService.SetEventPublisher(dispatcher)
+	posHandler := pos.NewHandler(posService, cfg, logger, uploads, rates, redisClient)
+	posHandler.RegisterRoutes(secured, authSvc)
+
+	// Backups
+	var backupStorage storage.Storage = storage.NewLocal(cfg.BackupDir)
+	if cfg.BackupS3Bucket != "" {
+		backupStorage = storage.NewS3(storage.S3Config{
+			Bucket:    cfg.BackupS3Bucket,
+			Region:    cfg.BackupS3Region,
+			Endpoint:  cfg.BackupS3Endpoint,
+			AccessKey: cfg.BackupS3AccessKey,
+			SecretKey: cfg.BackupS3SecretKey,
+		})
+	}
+	backupService := backup.NewBackup(dbs, queries, backupStorage, backup.Options{
+		Prefix:              cfg.BackupPrefix,
+		Retain:              cfg.BackupRetain,
+		IncludeTransactions: cfg.BackupTransactions,
+	})
+	backupHandler := backup.NewHandler(backupService, logger)
+	backupHandler.RegisterRoutes(secured, authSvc)
 
 
 	// Serve Nuxt static assets (JS/CSS/images)
@@ -229,6 +365,11 @@ func main() {
 			c.JSON(404, gin.H{"error": "API route not found"})
 			return
 		}
+		// disabled docs are not found rather than the frontend
+		if path == "/docs" || strings.HasPrefix(path, "/docs/") || path == "/redoc" {
+			c.Status(404)
+			return
+		}
 		c.File("../public/index.html")
 	})
 
@@ -240,23 +381,66 @@ func main() {
 		ShutdownTimeout: 30 * time.Second,
 	}
 
-	srv := server.New(r, dbs, serverConfig)
+	srv := server.New(r, dbs, serverConfig, redisClient)
+
+	// Release expired stock reservations in the background
+	sweepCtx, stopSweeper := context.WithCancel(context.Background())
+	sweeperDone := inventoryService.StartReservationSweeper(sweepCtx, time.Duration(cfg.ReservationSweep)*time.Second, func(err error) {
+		logger.Errorf("error releasing expired reservations: %v", err)
+	})
+	srv.AddWorker("reservation sweeper", stopSweeper, sweeperDone)
+
+	// Expired refresh tokens
+	tokenCtx, stopTokenCleanup := context.WithCancel(context.Background())
+	tokenCleanupDone := authSvc.StartTokenCleanup(tokenCtx, time.Hour, func(err error) {
+		logger.Errorf("error cleaning expired refresh tokens: %v", err)
+	})
+	srv.AddWorker("token cleanup", stopTokenCleanup, tokenCleanupDone)
+
+	// Webhook deliveries
+	webhookCtx, stopWebhooks := context.WithCancel(context.Background())
+	webhooksDone := dispatcher.Start(webhookCtx, func(err error) {
+		logger.Errorf("webhook delivery: %v", err)
+	})
+	srv.AddWorker("webhook dispatcher", stopWebhooks, webhooksDone)
 
-	// Add health check endpoint
-	// @Summary Health check
-	// @Description Check the health status of the API server
+	// Queued emails
+	emailCtx, stopEmails := context.WithCancel(context.Background())
+	emailsDone := emailer.Start(emailCtx, func(err error) {
+		logger.Errorf("email queue: %v", err)
+	})
+	srv.AddWorker("email queue", stopEmails, emailsDone)
+
+	// Scheduled backups
+	if cfg.BackupInterval > 0 {
+		backupCtx, stopBackups := context.WithCancel(context.Background())
+		backupsDone := backupService.StartScheduler(backupCtx, time.Duration(cfg.BackupInterval)*time.Hour, func(err error) {
+			logger.Errorf("scheduled backup failed: %v", err)
+		})
+		srv.AddWorker("backup scheduler", stopBackups, backupsDone)
+	}
+
+	// Add health check endpoints
+	// @Summary Liveness probe
+	// @Description Reports that the process is up, it checks no dependency
 	// @Tags health
 	// @Produce json
-	// @Success 200 {object} map[string]string "Service is healthy"
-	// @Failure 500 {object} map[string]string "Service is unhealthy"
-	// @Router /health [get]
-	r.GET("/health", func(c *gin.Context) {
-		if err := srv.Health(); err != nil {
-			c.JSON(500, gin.H{"status": "unhealthy", "error": err.Error()})
-			return
-		}
-		c.JSON(200, gin.H{"status": "healthy"})
-	})
+	// @Success 200 {object} map[string]string "Process is up"
+	// @Router /health/live [get]
+	r.GET("/health/live", server.Liveness)
+
+	// @Summary Readiness probe
+	// @Description Check the database and Redis, /health is kept as an alias
+	// @Tags health
+	// @Produce json
+	// @Success 200 {object} map[string]interface{} "Service is ready"
+	// @Failure 503 {object} map[string]interface{} "A dependency is down"
+	// @Router /health/ready [get]
+	r.GET("/health/ready", srv.ReadinessHandler)
+	r.GET("/health", srv.ReadinessHandler)
+
+	// Prometheus metrics
+	r.GET("/metrics", metrics.Handler())
 
 	// Start server with graceful shutdown
 	log.Printf("Starting Hotel ERP server version %s...", cfg.ApiVersion)
@@ -0,0 +1,112 @@
+package currency
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"herp/pkg/redis"
+	"math"
+	"strings"
+	"time"
+)
+
+var ErrUnknownCurrency = errors.New("unknown currency")
+
+// RateProvider returns how much of every currency it knows one unit of base
+// buys, base itself included. A base it doesn't know is ErrUnknownCurrency.
+type RateProvider interface {
+	Rates(ctx context.Context, base string) (map[string]float64, error)
+}
+
+// Converter converts amounts between currencies with the rates of a
+// provider, kept in redis for ttl so the provider isn't asked on every
+// conversion.
+type Converter struct {
+	provider RateProvider
+	redis    *redis.Redis
+	ttl      time.Duration
+}
+
+func NewConverter(provider RateProvider, r *redis.Redis, ttl time.Duration) *Converter {
+	return &Converter{provider: provider, redis: r, ttl: ttl}
+}
+
+// Normalize upper cases a currency code and checks it looks like an ISO 4217
+// code, three letters.
+func Normalize(code string) (string, error) {
+	code = strings.ToUpper(strings.TrimSpace(code))
+	if len(code) != 3 {
+		return "", fmt.Errorf("%w: %q", ErrUnknownCurrency, code)
+	}
+	for _, r := range code {
+		if r < 'A' || r > 'Z' {
+			return "", fmt.Errorf("%w: %q", ErrUnknownCurrency, code)
+		}
+	}
+	return code, nil
+}
+
+// Check reports ErrUnknownCurrency when the provider has no rates for code.
+func (c *Converter) Check(ctx context.Context, code string) error {
+	code, err := Normalize(code)
+	if err != nil {
+		return err
+	}
+	_, err = c.rates(ctx, code)
+	return err
+}
+
+// Rate is how much of to one unit of from buys.
+func (c *Converter) Rate(ctx context.Context, from, to string) (float64, error) {
+	from, err := Normalize(from)
+	if err != nil {
+		return 0, err
+	}
+	to, err = Normalize(to)
+	if err != nil {
+		return 0, err
+	}
+	rates, err := c.rates(ctx, from)
+	if err != nil {
+		return 0, err
+	}
+	rate, ok := rates[to]
+	if !ok {
+		return 0, fmt.Errorf("%w: %q", ErrUnknownCurrency, to)
+	}
+	return rate, nil
+}
+
+// Convert converts amount from one currency to another, rounded to two
+// decimals, and returns the rate used.
+func (c *Converter) Convert(ctx context.Context, amount float64, from, to string) (float64, float64, error) {
+	rate, err := c.Rate(ctx, from, to)
+	if err != nil {
+		return 0, 0, err
+	}
+	return math.Round(amount*rate*100) / 100, rate, nil
+}
+
+func ratesKey(base string) string {
+	return fmt.Sprintf("currency_rates:%s", base)
+}
+
+func (c *Converter) rates(ctx context.Context, base string) (map[string]float64, error) {
+	cacheKey := ratesKey(base)
+	if cached, err := c.redis.Get(ctx, cacheKey); err == nil {
+		var rates map[string]float64
+		if err := json.Unmarshal([]byte(cached), &rates); err == nil {
+			return rates, nil
+		}
+	}
+
+	rates, err := c.provider.Rates(ctx, base)
+	if err != nil {
+		return nil, err
+	}
+
+	jsonRates, _ := json.Marshal(rates)
+	c.redis.Set(ctx, cacheKey, jsonRates, c.ttl)
+	return rates, nil
+}
@@ -0,0 +1,97 @@
+package currency
+
+import (
+	"context"
+	"herp/pkg/redis"
+	"herp/pkg/redis/redistest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// stubProvider knows rates against NGN and counts how often it is asked.
+type stubProvider struct {
+	calls int
+}
+
+func (p *stubProvider) Rates(ctx context.Context, base string) (map[string]float64, error) {
+	p.calls++
+	return NewStatic("NGN", map[string]float64{"USD": 0.00065, "GHS": 0.0098}).Rates(ctx, base)
+}
+
+func newStubConverter(t *testing.T) (*Converter, *stubProvider) {
+	t.Helper()
+	provider := &stubProvider{}
+	return NewConverter(provider, redis.NewRedisFromClient(redistest.Client(t)), time.Hour), provider
+}
+
+func TestConvert(t *testing.T) {
+	c, _ := newStubConverter(t)
+	ctx := context.Background()
+
+	converted, rate, err := c.Convert(ctx, 10000, "NGN", "USD")
+	require.NoError(t, err)
+	assert.Equal(t, 0.00065, rate)
+	assert.Equal(t, 6.5, converted)
+
+	// lower case codes are fine, cross rates go through the base
+	converted, _, err = c.Convert(ctx, 6.5, "usd", "ghs")
+	require.NoError(t, err)
+	assert.Equal(t, 98.0, converted)
+
+	converted, rate, err = c.Convert(ctx, 123.456, "NGN", "NGN")
+	require.NoError(t, err)
+	assert.Equal(t, 1.0, rate)
+	assert.Equal(t, 123.46, converted)
+}
+
+func TestConvertUnknownCurrency(t *testing.T) {
+	c, _ := newStubConverter(t)
+	ctx := context.Background()
+
+	for _, tt := range []struct{ from, to string }{
+		{"NGN", "EUR"},
+		{"EUR", "NGN"},
+		{"NGN", "US"},
+		{"NGN", "U$D"},
+	} {
+		_, _, err := c.Convert(ctx, 1, tt.from, tt.to)
+		assert.ErrorIs(t, err, ErrUnknownCurrency, "%s to %s", tt.from, tt.to)
+	}
+	assert.ErrorIs(t, c.Check(ctx, "EUR"), ErrUnknownCurrency)
+	assert.NoError(t, c.Check(ctx, "ghs"))
+}
+
+func TestConvertCachesRates(t *testing.T) {
+	c, provider := newStubConverter(t)
+	ctx := context.Background()
+
+	for range 3 {
+		_, _, err := c.Convert(ctx, 100, "NGN", "USD")
+		require.NoError(t, err)
+	}
+	assert.Equal(t, 1, provider.calls, "rates are asked for once per base")
+
+	_, _, err := c.Convert(ctx, 100, "USD", "NGN")
+	require.NoError(t, err)
+	assert.Equal(t, 2, provider.calls)
+}
+
+func TestNormalize(t *testing.T) {
+	code, err := Normalize(" ngn ")
+	require.NoError(t, err)
+	assert.Equal(t, "NGN", code)
+
+	for _, bad := range []string{"", "NG", "NGNN", "N1N", "₦GN"} {
+		_, err := Normalize(bad)
+		assert.ErrorIs(t, err, ErrUnknownCurrency, bad)
+	}
+}
+
+func TestParseRates(t *testing.T) {
+	assert.Equal(t, map[string]float64{"USD": 0.00065, "GHS": 0.0098}, ParseRates([]string{
+		"usd:0.00065", " GHS : 0.0098 ", "EUR", "XOF:-1", "JPY:none", "EURO:1",
+	}))
+}
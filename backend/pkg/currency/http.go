@@ -0,0 +1,55 @@
+package currency
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// HTTP is a RateProvider fetching rates from an exchange rate API. The URL
+// has {base} replaced by the base currency and has to answer with a JSON
+// object holding the rates under "rates", as most free APIs do, e.g.
+// https://api.frankfurter.app/latest?from={base}.
+type HTTP struct {
+	url    string
+	client *http.Client
+}
+
+func NewHTTP(url string) *HTTP {
+	return &HTTP{url: url, client: &http.Client{Timeout: 10 * time.Second}}
+}
+
+func (h *HTTP) Rates(ctx context.Context, base string) (map[string]float64, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, strings.ReplaceAll(h.url, "{base}", base), nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := h.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("fetch rates of %s: %w", base, err)
+	}
+	defer resp.Body.Close()
+
+	switch {
+	case resp.StatusCode == http.StatusNotFound || resp.StatusCode == http.StatusUnprocessableEntity:
+		return nil, fmt.Errorf("%w: %q", ErrUnknownCurrency, base)
+	case resp.StatusCode != http.StatusOK:
+		return nil, fmt.Errorf("fetch rates of %s: unexpected status %d", base, resp.StatusCode)
+	}
+
+	var body struct {
+		Rates map[string]float64 `json:"rates"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		return nil, fmt.Errorf("decode rates of %s: %w", base, err)
+	}
+	if len(body.Rates) == 0 {
+		return nil, fmt.Errorf("%w: %q", ErrUnknownCurrency, base)
+	}
+	// some APIs leave the base out of its own rates
+	body.Rates[base] = 1
+	return body.Rates, nil
+}
@@ -0,0 +1,51 @@
+package currency
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHTTPRates(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Query().Get("from") {
+		case "NGN":
+			w.Write([]byte(`{"base":"NGN","rates":{"USD":0.00065,"GHS":0.0098}}`))
+		case "XXX":
+			w.WriteHeader(http.StatusNotFound)
+		default:
+			w.WriteHeader(http.StatusBadGateway)
+		}
+	}))
+	defer server.Close()
+	provider := NewHTTP(server.URL + "/latest?from={base}")
+
+	rates, err := provider.Rates(context.Background(), "NGN")
+	require.NoError(t, err)
+	// the base is added when the API leaves it out
+	assert.Equal(t, map[string]float64{"NGN": 1, "USD": 0.00065, "GHS": 0.0098}, rates)
+
+	_, err = provider.Rates(context.Background(), "XXX")
+	assert.ErrorIs(t, err, ErrUnknownCurrency)
+
+	_, err = provider.Rates(context.Background(), "USD")
+	require.Error(t, err)
+	assert.NotErrorIs(t, err, ErrUnknownCurrency)
+}
+
+func TestStaticRates(t *testing.T) {
+	provider := NewStatic("ngn", map[string]float64{"usd": 0.0005, "GHS": 0.01})
+
+	rates, err := provider.Rates(context.Background(), "USD")
+	require.NoError(t, err)
+	assert.Equal(t, 1.0, rates["USD"])
+	assert.Equal(t, 2000.0, rates["NGN"])
+	assert.Equal(t, 20.0, rates["GHS"])
+
+	_, err = provider.Rates(context.Background(), "EUR")
+	assert.ErrorIs(t, err, ErrUnknownCurrency)
+}
@@ -0,0 +1,58 @@
+package currency
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Static is a RateProvider with a fixed table of rates against one base
+// currency. Rates between two other currencies are crossed through the base.
+type Static struct {
+	base  string
+	rates map[string]float64
+}
+
+// NewStatic returns a provider with rates giving how much of each currency
+// one unit of base buys.
+func NewStatic(base string, rates map[string]float64) *Static {
+	base = strings.ToUpper(base)
+	table := make(map[string]float64, len(rates)+1)
+	for code, rate := range rates {
+		table[strings.ToUpper(code)] = rate
+	}
+	table[base] = 1
+	return &Static{base: base, rates: table}
+}
+
+func (s *Static) Rates(ctx context.Context, base string) (map[string]float64, error) {
+	baseRate, ok := s.rates[base]
+	if !ok {
+		return nil, fmt.Errorf("%w: %q", ErrUnknownCurrency, base)
+	}
+	rates := make(map[string]float64, len(s.rates))
+	for code, rate := range s.rates {
+		rates[code] = rate / baseRate
+	}
+	return rates, nil
+}
+
+// ParseRates reads rates written as "code:rate", entries that don't parse or
+// aren't positive are skipped.
+func ParseRates(values []string) map[string]float64 {
+	rates := make(map[string]float64, len(values))
+	for _, v := range values {
+		code, value, _ := strings.Cut(v, ":")
+		code, err := Normalize(code)
+		if err != nil {
+			continue
+		}
+		rate, err := strconv.ParseFloat(strings.TrimSpace(value), 64)
+		if err != nil || rate <= 0 {
+			continue
+		}
+		rates[code] = rate
+	}
+	return rates
+}
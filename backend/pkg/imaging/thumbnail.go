@@ -0,0 +1,69 @@
+package imaging
+
+import (
+	"bytes"
+	"errors"
+	"image"
+	_ "image/gif"
+	"image/jpeg"
+	"image/png"
+
+	"golang.org/x/image/draw"
+	_ "golang.org/x/image/webp"
+)
+
+// maxPixels bounds the images decoded, a small file can declare huge
+// dimensions and exhaust memory when decoded.
+const maxPixels = 40_000_000
+
+var (
+	ErrUnsupported   = errors.New("image could not be decoded")
+	ErrTooManyPixels = errors.New("image dimensions too large")
+)
+
+// Thumbnail scales an image down so its longest edge is at most maxEdge
+// pixels, keeping the aspect ratio. JPEG images stay JPEG, everything else is
+// encoded as PNG to keep transparency. ok is false when the image already fits
+// and was left alone.
+func Thumbnail(body []byte, maxEdge int) (thumb []byte, contentType string, ok bool, err error) {
+	cfg, format, err := image.DecodeConfig(bytes.NewReader(body))
+	if err != nil {
+		return nil, "", false, ErrUnsupported
+	}
+	if cfg.Width*cfg.Height > maxPixels {
+		return nil, "", false, ErrTooManyPixels
+	}
+	if cfg.Width <= maxEdge && cfg.Height <= maxEdge {
+		return nil, "", false, nil
+	}
+
+	src, _, err := image.Decode(bytes.NewReader(body))
+	if err != nil {
+		return nil, "", false, ErrUnsupported
+	}
+
+	width, height := fit(cfg.Width, cfg.Height, maxEdge)
+	dst := image.NewRGBA(image.Rect(0, 0, width, height))
+	draw.CatmullRom.Scale(dst, dst.Bounds(), src, src.Bounds(), draw.Src, nil)
+
+	var buf bytes.Buffer
+	if format == "jpeg" {
+		err = jpeg.Encode(&buf, dst, &jpeg.Options{Quality: 85})
+		contentType = "image/jpeg"
+	} else {
+		err = png.Encode(&buf, dst)
+		contentType = "image/png"
+	}
+	if err != nil {
+		return nil, "", false, err
+	}
+	return buf.Bytes(), contentType, true, nil
+}
+
+// fit scales width and height so the longest edge is maxEdge.
+func fit(width, height, maxEdge int) (int, int) {
+	if width >= height {
+		return maxEdge, max(1, height*maxEdge/width)
+	}
+	return max(1, width*maxEdge/height), maxEdge
+}
@@ -0,0 +1,79 @@
+package imaging
+
+import (
+	"bytes"
+	"encoding/binary"
+	"hash/crc32"
+	"image"
+	"image/jpeg"
+	"image/png"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func encodePNG(t *testing.T, width, height int) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	require.NoError(t, png.Encode(&buf, image.NewRGBA(image.Rect(0, 0, width, height))))
+	return buf.Bytes()
+}
+
+func encodeJPEG(t *testing.T, width, height int) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	require.NoError(t, jpeg.Encode(&buf, image.NewRGBA(image.Rect(0, 0, width, height)), nil))
+	return buf.Bytes()
+}
+
+func TestThumbnailBounds(t *testing.T) {
+	tests := []struct {
+		name          string
+		body          func(t *testing.T) []byte
+		width, height int
+		contentType   string
+	}{
+		{"landscape", func(t *testing.T) []byte { return encodePNG(t, 1200, 800) }, 256, 170, "image/png"},
+		{"portrait", func(t *testing.T) []byte { return encodePNG(t, 600, 1800) }, 85, 256, "image/png"},
+		{"square", func(t *testing.T) []byte { return encodePNG(t, 1000, 1000) }, 256, 256, "image/png"},
+		{"sliver", func(t *testing.T) []byte { return encodePNG(t, 4000, 2) }, 256, 1, "image/png"},
+		{"jpeg stays jpeg", func(t *testing.T) []byte { return encodeJPEG(t, 1024, 768) }, 256, 192, "image/jpeg"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			thumb, contentType, ok, err := Thumbnail(tt.body(t), 256)
+			require.NoError(t, err)
+			require.True(t, ok)
+			assert.Equal(t, tt.contentType, contentType)
+
+			cfg, _, err := image.DecodeConfig(bytes.NewReader(thumb))
+			require.NoError(t, err)
+			assert.Equal(t, tt.width, cfg.Width)
+			assert.Equal(t, tt.height, cfg.Height)
+			assert.LessOrEqual(t, max(cfg.Width, cfg.Height), 256)
+		})
+	}
+}
+
+func TestThumbnailAlreadySmall(t *testing.T) {
+	for _, size := range [][2]int{{256, 256}, {100, 40}} {
+		thumb, _, ok, err := Thumbnail(encodePNG(t, size[0], size[1]), 256)
+		require.NoError(t, err)
+		assert.False(t, ok, "%v resized", size)
+		assert.Nil(t, thumb)
+	}
+}
+
+func TestThumbnailRejects(t *testing.T) {
+	_, _, _, err := Thumbnail([]byte("not an image"), 256)
+	assert.ErrorIs(t, err, ErrUnsupported)
+
+	// a PNG header claiming 100000x100000 pixels, nothing is decoded
+	huge := encodePNG(t, 1, 1)
+	binary.BigEndian.PutUint32(huge[16:], 100000)
+	binary.BigEndian.PutUint32(huge[20:], 100000)
+	binary.BigEndian.PutUint32(huge[29:], crc32.ChecksumIEEE(huge[12:29]))
+	_, _, _, err = Thumbnail(huge, 256)
+	assert.ErrorIs(t, err, ErrTooManyPixels)
+}
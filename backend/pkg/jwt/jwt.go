@@ -1,6 +1,9 @@
 package jwt
 
 import (
+	"errors"
+	"fmt"
+	"strings"
 	"time"
 
 	"github.com/gin-gonic/gin"
@@ -9,6 +12,14 @@ import (
 
 type TokenType string
 
+// ErrTokenExpired is returned by ParseToken, possibly wrapped, when the token
+// is well formed and signed but past its expiry.
+var ErrTokenExpired = jwt.ErrTokenExpired
+
+// ErrUnknownKey is returned by ParseToken when the token names a key that
+// isn't in the key set, e.g. one that was retired.
+var ErrUnknownKey = errors.New("token signed with an unknown key")
+
 const (
 	AccessToken  TokenType = "access"
 	RefreshToken TokenType = "refresh"
@@ -21,10 +32,71 @@ type Claims struct {
 	Role        string    `json:"role"`
 	Permissions []string  `json:"permissions"`
 	TokenType   TokenType `json:"tokenType"`
+	SessionID   int       `json:"sessionId,omitempty"` // refresh token the access token was issued with
 	jwt.RegisteredClaims
 }
 
-func GenerateToken(userID int, username, email, role, secret string, permissions []string, tokenType TokenType, expiry time.Duration) (string, error) {
+// KeySet holds the keys tokens are signed and verified with, by key id. New
+// tokens are signed with the current key and carry its id in the kid header.
+// Tokens signed with a previous key keep verifying as long as that key stays
+// in the set, so the secret can be rotated without logging everyone out.
+type KeySet struct {
+	currentID string
+	keys      map[string][]byte
+	issuer    string
+	audience  string
+}
+
+// NewKeySet returns a key set signing with secret under id, and verifying
+// with it and the previous secrets, keyed by their ids.
+func NewKeySet(id, secret string, previous map[string]string) *KeySet {
+	keys := make(map[string][]byte, len(previous)+1)
+	for kid, s := range previous {
+		keys[kid] = []byte(s)
+	}
+	keys[id] = []byte(secret)
+	return &KeySet{currentID: id, keys: keys}
+}
+
+// WithIssuer makes tokens carry issuer and audience as their iss and aud
+// claims, and refuses tokens that don't carry the same. Either left empty
+// is neither set nor checked.
+func (k *KeySet) WithIssuer(issuer, audience string) *KeySet {
+	k.issuer = issuer
+	k.audience = audience
+	return k
+}
+
+// ParseKeys reads previous keys written as "kid:secret", entries without a
+// kid or secret are skipped.
+func ParseKeys(values []string) map[string]string {
+	keys := make(map[string]string, len(values))
+	for _, v := range values {
+		kid, secret, _ := strings.Cut(v, ":")
+		kid = strings.TrimSpace(kid)
+		if kid == "" || secret == "" {
+			continue
+		}
+		keys[kid] = secret
+	}
+	return keys
+}
+
+// key returns the secret a token is verified with. Tokens issued before key
+// ids were introduced carry none and are checked against the current key.
+func (k *KeySet) key(token *jwt.Token) (any, error) {
+	kid, _ := token.Header["kid"].(string)
+	if kid == "" {
+		kid = k.currentID
+	}
+	secret, ok := k.keys[kid]
+	if !ok {
+		return nil, fmt.Errorf("%w: %q", ErrUnknownKey, kid)
+	}
+	return secret, nil
+}
+
+func GenerateToken(userID int, username, email, role string, keys *KeySet, permissions []string, tokenType TokenType, expiry time.Duration, sessionID int) (string, error) {
 	expirationTime := time.Now().Add(expiry)
 
 	claims := &Claims{
@@ -34,21 +106,32 @@ func GenerateToken(userID int, username, email, role, secret string, permissions
 		Permissions: permissions,
 		Username:    username,
 		TokenType:   tokenType,
+		SessionID:   sessionID,
 		RegisteredClaims: jwt.RegisteredClaims{
+			Issuer:    keys.issuer,
 			ExpiresAt: jwt.NewNumericDate(expirationTime),
 			IssuedAt:  jwt.NewNumericDate(time.Now()),
 		},
 	}
+	if keys.audience != "" {
+		claims.Audience = jwt.ClaimStrings{keys.audience}
+	}
 
 	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-	return token.SignedString([]byte(secret))
+	token.Header["kid"] = keys.currentID
+	return token.SignedString(keys.keys[keys.currentID])
 }
 
-func ParseToken(tokenString, secret string) (*Claims, error) {
+func ParseToken(tokenString string, keys *KeySet) (*Claims, error) {
 	claims := &Claims{}
-	token, err := jwt.ParseWithClaims(tokenString, claims, func(token *jwt.Token) (any, error) {
-		return []byte(secret), nil
-	})
+	options := []jwt.ParserOption{jwt.WithValidMethods([]string{jwt.SigningMethodHS256.Alg()})}
+	if keys.issuer != "" {
+		options = append(options, jwt.WithIssuer(keys.issuer))
+	}
+	if keys.audience != "" {
+		options = append(options, jwt.WithAudience(keys.audience))
+	}
+	token, err := jwt.ParseWithClaims(tokenString, claims, keys.key, options...)
 
 	if err != nil {
 		return nil, err
@@ -0,0 +1,110 @@
+package jwt
+
+import (
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func issue(t *testing.T, keys *KeySet) string {
+	t.Helper()
+	token, err := GenerateToken(7, "ada", "ada@example.com", "cashier", keys, []string{"pos:sale"}, AccessToken, time.Hour, 3)
+	require.NoError(t, err)
+	return token
+}
+
+func kid(t *testing.T, token string) string {
+	t.Helper()
+	parsed, _, err := jwt.NewParser().ParseUnverified(token, &Claims{})
+	require.NoError(t, err)
+	id, _ := parsed.Header["kid"].(string)
+	return id
+}
+
+func TestKeyRotation(t *testing.T) {
+	old := NewKeySet("k1", "first-secret", nil)
+	token := issue(t, old)
+	assert.Equal(t, "k1", kid(t, token))
+
+	// k2 takes over, k1 is kept while its tokens run out
+	rotated := NewKeySet("k2", "second-secret", map[string]string{"k1": "first-secret"})
+	claims, err := ParseToken(token, rotated)
+	require.NoError(t, err)
+	assert.Equal(t, 7, claims.UserID)
+	assert.Equal(t, []string{"pos:sale"}, claims.Permissions)
+
+	fresh := issue(t, rotated)
+	assert.Equal(t, "k2", kid(t, fresh))
+	_, err = ParseToken(fresh, rotated)
+	require.NoError(t, err)
+
+	// k1 retired
+	retired := NewKeySet("k2", "second-secret", nil)
+	_, err = ParseToken(token, retired)
+	assert.ErrorIs(t, err, ErrUnknownKey)
+	_, err = ParseToken(fresh, retired)
+	assert.NoError(t, err)
+}
+
+func TestKeyRotationWrongSecret(t *testing.T) {
+	token := issue(t, NewKeySet("k1", "first-secret", nil))
+
+	// same kid, other secret
+	_, err := ParseToken(token, NewKeySet("k1", "forged-secret", nil))
+	assert.ErrorIs(t, err, jwt.ErrTokenSignatureInvalid)
+}
+
+func TestKeyRotationWithoutKid(t *testing.T) {
+	// tokens from before key ids are checked against the current key
+	claims := &Claims{UserID: 7, TokenType: AccessToken, RegisteredClaims: jwt.RegisteredClaims{
+		ExpiresAt: jwt.NewNumericDate(time.Now().Add(time.Hour)),
+	}}
+	token, err := jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString([]byte("first-secret"))
+	require.NoError(t, err)
+
+	_, err = ParseToken(token, NewKeySet("k1", "first-secret", nil))
+	assert.NoError(t, err)
+	_, err = ParseToken(token, NewKeySet("k2", "second-secret", map[string]string{"k1": "first-secret"}))
+	assert.Error(t, err)
+}
+
+func TestParseKeys(t *testing.T) {
+	assert.Equal(t, map[string]string{"k1": "first", "k2": "sec:ond"}, ParseKeys([]string{
+		"k1:first", " k2 :sec:ond", "k3", ":nokid", "k4:",
+	}))
+}
+
+func TestAudience(t *testing.T) {
+	production := func() *KeySet {
+		return NewKeySet("k1", "secret", nil).WithIssuer("herp", "herp-production")
+	}
+	token := issue(t, production())
+
+	claims, err := ParseToken(token, production())
+	require.NoError(t, err)
+	assert.Equal(t, "herp", claims.Issuer)
+	assert.Equal(t, jwt.ClaimStrings{"herp-production"}, claims.Audience)
+
+	// same secret, another environment
+	_, err = ParseToken(token, NewKeySet("k1", "secret", nil).WithIssuer("herp", "herp-staging"))
+	assert.ErrorIs(t, err, jwt.ErrTokenInvalidAudience)
+	_, err = ParseToken(token, NewKeySet("k1", "secret", nil).WithIssuer("other", "herp-production"))
+	assert.ErrorIs(t, err, jwt.ErrTokenInvalidIssuer)
+
+	// nothing configured, nothing checked
+	_, err = ParseToken(token, NewKeySet("k1", "secret", nil))
+	assert.NoError(t, err)
+}
+
+func TestAudienceMissing(t *testing.T) {
+	// issued before the claims were configured
+	token := issue(t, NewKeySet("k1", "secret", nil))
+
+	_, err := ParseToken(token, NewKeySet("k1", "secret", nil).WithIssuer("", "herp-production"))
+	assert.ErrorIs(t, err, jwt.ErrTokenRequiredClaimMissing)
+	_, err = ParseToken(token, NewKeySet("k1", "secret", nil).WithIssuer("herp", ""))
+	assert.ErrorIs(t, err, jwt.ErrTokenRequiredClaimMissing)
+}
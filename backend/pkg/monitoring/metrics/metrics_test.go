@@ -0,0 +1,47 @@
+package metrics
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMiddlewareCountsRequests(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.Use(Middleware())
+	r.GET("/items/:id", func(c *gin.Context) {
+		c.Status(http.StatusOK)
+	})
+	r.GET("/metrics", Handler())
+
+	ok := httpRequests.WithLabelValues("/items/:id", http.MethodGet, "200")
+	unmatched := httpRequests.WithLabelValues(unmatchedRoute, http.MethodGet, "404")
+	before, beforeUnmatched := testutil.ToFloat64(ok), testutil.ToFloat64(unmatched)
+
+	for _, path := range []string{"/items/1", "/items/2", "/items/3", "/nope/1", "/nope/2"} {
+		r.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, path, nil))
+	}
+
+	// requests are counted by route template, not by path
+	assert.Equal(t, before+3, testutil.ToFloat64(ok))
+	assert.Equal(t, beforeUnmatched+2, testutil.ToFloat64(unmatched))
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), `herp_http_requests_total{method="GET",route="/items/:id",status="200"}`)
+	assert.False(t, strings.Contains(w.Body.String(), "/items/1"), "paths are not labels")
+}
+
+func TestRateLimitRejected(t *testing.T) {
+	before := testutil.ToFloat64(rateLimitRejections.WithLabelValues("ip"))
+	RateLimitRejected("ip")
+	RateLimitRejected("ip")
+	assert.Equal(t, before+2, testutil.ToFloat64(rateLimitRejections.WithLabelValues("ip")))
+}
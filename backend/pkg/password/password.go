@@ -0,0 +1,68 @@
+package password
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+)
+
+// Policy is the set of rules a new password has to meet. History is how many
+// previous passwords of an account can't be reused, it is enforced by the
+// caller since it needs the account's stored hashes.
+type Policy struct {
+	MinLength     int
+	RequireUpper  bool
+	RequireLower  bool
+	RequireDigit  bool
+	RequireSymbol bool
+	History       int
+}
+
+// PolicyError lists the rules of a Policy a password does not meet.
+type PolicyError struct {
+	Unmet []string
+}
+
+func (e *PolicyError) Error() string {
+	return "password must " + strings.Join(e.Unmet, ", ")
+}
+
+// Validate checks a password against the policy and returns a *PolicyError
+// naming every rule it breaks, or nil.
+func (p Policy) Validate(password string) error {
+	var hasUpper, hasLower, hasDigit, hasSymbol bool
+	for _, r := range password {
+		switch {
+		case unicode.IsUpper(r):
+			hasUpper = true
+		case unicode.IsLower(r):
+			hasLower = true
+		case unicode.IsDigit(r):
+			hasDigit = true
+		case unicode.IsPunct(r), unicode.IsSymbol(r):
+			hasSymbol = true
+		}
+	}
+
+	var unmet []string
+	if len([]rune(password)) < p.MinLength {
+		unmet = append(unmet, fmt.Sprintf("be at least %d characters long", p.MinLength))
+	}
+	if p.RequireUpper && !hasUpper {
+		unmet = append(unmet, "contain an uppercase letter")
+	}
+	if p.RequireLower && !hasLower {
+		unmet = append(unmet, "contain a lowercase letter")
+	}
+	if p.RequireDigit && !hasDigit {
+		unmet = append(unmet, "contain a digit")
+	}
+	if p.RequireSymbol && !hasSymbol {
+		unmet = append(unmet, "contain a symbol")
+	}
+
+	if len(unmet) > 0 {
+		return &PolicyError{Unmet: unmet}
+	}
+	return nil
+}
@@ -0,0 +1,62 @@
+package password
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func strict() Policy {
+	return Policy{MinLength: 10, RequireUpper: true, RequireLower: true, RequireDigit: true, RequireSymbol: true}
+}
+
+func TestValidate(t *testing.T) {
+	tests := []struct {
+		name     string
+		password string
+		unmet    []string
+	}{
+		{"meets every rule", "Correct-Horse-9", nil},
+		{"too short", "Ab1!", []string{"be at least 10 characters long"}},
+		{"no uppercase", "correct-horse-9", []string{"contain an uppercase letter"}},
+		{"no lowercase", "CORRECT-HORSE-9", []string{"contain a lowercase letter"}},
+		{"no digit", "Correct-Horse-X", []string{"contain a digit"}},
+		{"no symbol", "CorrectHorse99", []string{"contain a symbol"}},
+		{"length counts characters, not bytes", "Ünïcödé-9xx", nil},
+		{"empty", "", []string{
+			"be at least 10 characters long",
+			"contain an uppercase letter",
+			"contain a lowercase letter",
+			"contain a digit",
+			"contain a symbol",
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := strict().Validate(tt.password)
+			if tt.unmet == nil {
+				assert.NoError(t, err)
+				return
+			}
+			var policyErr *PolicyError
+			require.ErrorAs(t, err, &policyErr)
+			assert.Equal(t, tt.unmet, policyErr.Unmet)
+		})
+	}
+}
+
+func TestValidateRulesOff(t *testing.T) {
+	// only the length is checked when no character class is required
+	p := Policy{MinLength: 8}
+	assert.NoError(t, p.Validate("password"))
+	assert.NoError(t, p.Validate("        "))
+	assert.Error(t, p.Validate("passwor"))
+
+	assert.NoError(t, Policy{}.Validate(""))
+}
+
+func TestPolicyErrorMessage(t *testing.T) {
+	err := &PolicyError{Unmet: []string{"be at least 10 characters long", "contain a digit"}}
+	assert.Equal(t, "password must be at least 10 characters long, contain a digit", err.Error())
+}
@@ -0,0 +1,62 @@
+package ratelimit
+
+import (
+	"context"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// incrementScript counts a request and starts the window on the first one.
+// It returns {count, milliseconds left in the window}.
+var incrementScript = redis.NewScript(`
+local count = redis.call('INCR', KEYS[1])
+if count == 1 then
+	redis.call('PEXPIRE', KEYS[1], ARGV[1])
+end
+return {count, redis.call('PTTL', KEYS[1])}
+`)
+
+// fixedKey keeps fixed window counters apart from sliding window sets, a key
+// switching modes would otherwise hit the wrong redis type.
+func fixedKey(key string) string {
+	return key + ":fixed"
+}
+
+func (r *RateLimiter) incrementFixed(ctx context.Context, key string, window time.Duration) (int, time.Duration, error) {
+	res, err := incrementScript.Run(ctx, r.client, []string{fixedKey(key)}, window.Milliseconds()).Int64Slice()
+	if err != nil {
+		return 0, 0, err
+	}
+	return int(res[0]), time.Duration(res[1]) * time.Millisecond, nil
+}
+
+// allowFixed counts every request, limited ones included, so the counter
+// says how hard a key is pushing. It is a single INCR so it is atomic. The
+// duration returned is what is left of the window.
+func (r *RateLimiter) allowFixed(ctx context.Context, key string, limit int, window time.Duration) (bool, int, time.Duration, error) {
+	count, ttl, err := r.incrementFixed(ctx, key, window)
+	if err != nil {
+		return false, 0, 0, err
+	}
+	return count <= limit, count, ttl, nil
+}
+
+func (r *RateLimiter) checkFixed(ctx context.Context, key string, limit int) (bool, int, time.Duration, error) {
+	count, err := r.client.Get(ctx, fixedKey(key)).Int()
+	if err == redis.Nil {
+		return false, 0, 0, nil
+	}
+	if err != nil {
+		return false, 0, 0, err
+	}
+	if count < limit {
+		return false, count, 0, nil
+	}
+
+	ttl, err := r.client.PTTL(ctx, fixedKey(key)).Result()
+	if err != nil {
+		return false, 0, 0, err
+	}
+	return true, count, ttl, nil
+}
@@ -2,45 +2,68 @@ package ratelimit
 
 import (
 	"fmt"
+	"herp/pkg/jwt"
+	"herp/pkg/monitoring/metrics"
+	"math"
 	"net/http"
 	"time"
 
 	"github.com/gin-gonic/gin"
 )
 
+// IPRateLimitMiddleware limits requests per client IP. It is meant for a
+// FixedWindow limiter, a sliding window would store every request of a flood.
 func IPRateLimitMiddleware(limiter *RateLimiter, limit int, window time.Duration) gin.HandlerFunc {
 	return func(c *gin.Context) {
-		ip := c.ClientIP()
-		key := fmt.Sprintf("middleware:ip:%s", ip)
-
-		exceeded, count, timeLeft, err := limiter.Check(c.Request.Context(), key, limit, window)
-		if err != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
-			c.Abort()
-			return
+		if allow(c, limiter, "ip", fmt.Sprintf("middleware:ip:%s", c.ClientIP()), limit, window) {
+			c.Next()
 		}
+	}
+}
 
-		if exceeded {
-			c.Header("X-RateLimit-Limit", fmt.Sprintf("%d", limit))
-			c.Header("X-RateLimit-Remaining", "0")
-			c.Header("X-RateLimit-Reset", fmt.Sprintf("%.0f", timeLeft.Seconds()))
-
-			c.JSON(http.StatusTooManyRequests, gin.H{
-				"error":       "Too many requests",
-				"retry_after": timeLeft.Seconds(),
-			})
-			c.Abort()
+// UserRateLimitMiddleware limits requests per authenticated user, so one
+// account can't get around IPRateLimitMiddleware by rotating addresses. It
+// goes after the auth middleware and lets requests without claims through.
+func UserRateLimitMiddleware(limiter *RateLimiter, limit int, window time.Duration) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		claims, ok := jwt.GetUserFromContext(c)
+		if !ok {
+			c.Next()
 			return
 		}
+		if allow(c, limiter, "user", fmt.Sprintf("middleware:user:%d", claims.UserID), limit, window) {
+			c.Next()
+		}
+	}
+}
 
-		// Increment counter
-		limiter.Increment(c.Request.Context(), key, window)
+// allow counts the request against key and sets the X-RateLimit headers so
+// clients can back off before they are limited. Limited requests get a 429
+// with Retry-After and are aborted, allow then returns false. Rejections are
+// counted in the metrics under name.
+func allow(c *gin.Context, limiter *RateLimiter, name, key string, limit int, window time.Duration) bool {
+	allowed, used, reset, err := limiter.Allow(c.Request.Context(), key, limit, window)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+		c.Abort()
+		return false
+	}
 
-		remaining := limit - count - 1
-		c.Header("X-RateLimit-Limit", fmt.Sprintf("%d", limit))
-		c.Header("X-RateLimit-Remaining", fmt.Sprintf("%d", remaining))
-		c.Header("X-RateLimit-Reset", fmt.Sprintf("%.0f", window.Seconds()))
+	// whole seconds, rounded up so clients don't come back too early
+	resetSeconds := fmt.Sprintf("%.0f", math.Ceil(max(reset, 0).Seconds()))
+	c.Header("X-RateLimit-Limit", fmt.Sprintf("%d", limit))
+	c.Header("X-RateLimit-Remaining", fmt.Sprintf("%d", max(limit-used, 0)))
+	c.Header("X-RateLimit-Reset", resetSeconds)
 
-		c.Next()
+	if !allowed {
+		metrics.RateLimitRejected(name)
+		c.Header("Retry-After", resetSeconds)
+		c.JSON(http.StatusTooManyRequests, gin.H{
+			"error":       "Too many requests",
+			"retry_after": reset.Seconds(),
+		})
+		c.Abort()
+		return false
 	}
+	return true
 }
@@ -0,0 +1,100 @@
+package ratelimit
+
+import (
+	"herp/pkg/jwt"
+	"herp/pkg/redis/redistest"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+// newUserRouter limits each user to limit requests a minute. The user is
+// taken from the X-User header the way the auth middleware would set it,
+// requests without one are anonymous.
+func newUserRouter(t *testing.T, limit int) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	limiter := NewRateLimitWithMode(redistest.Client(t), FixedWindow)
+
+	r := gin.New()
+	r.Use(func(c *gin.Context) {
+		switch c.GetHeader("X-User") {
+		case "1":
+			c.Set("claims", &jwt.Claims{UserID: 1, Username: "ada"})
+		case "2":
+			c.Set("claims", &jwt.Claims{UserID: 2, Username: "bayo"})
+		}
+	})
+	r.Use(UserRateLimitMiddleware(limiter, limit, time.Minute))
+	r.GET("/ping", func(c *gin.Context) {
+		c.String(http.StatusOK, "pong")
+	})
+	return r
+}
+
+func get(r *gin.Engine, user string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodGet, "/ping", nil)
+	if user != "" {
+		req.Header.Set("X-User", user)
+	}
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	return w
+}
+
+func TestUserRateLimitIndependentBuckets(t *testing.T) {
+	r := newUserRouter(t, 2)
+
+	for range 2 {
+		assert.Equal(t, http.StatusOK, get(r, "1").Code)
+	}
+	w := get(r, "1")
+	assert.Equal(t, http.StatusTooManyRequests, w.Code)
+	assert.NotEmpty(t, w.Header().Get("Retry-After"))
+
+	// the second user still has their whole allowance
+	for range 2 {
+		assert.Equal(t, http.StatusOK, get(r, "2").Code)
+	}
+	assert.Equal(t, http.StatusTooManyRequests, get(r, "2").Code)
+}
+
+func TestUserRateLimitAnonymous(t *testing.T) {
+	r := newUserRouter(t, 1)
+
+	for range 3 {
+		w := get(r, "")
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Empty(t, w.Header().Get("X-RateLimit-Limit"))
+	}
+}
+
+func TestRateLimitHeaders(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	limiter := NewRateLimitWithMode(redistest.Client(t), FixedWindow)
+	r := gin.New()
+	r.Use(IPRateLimitMiddleware(limiter, 3, time.Minute))
+	r.GET("/ping", func(c *gin.Context) {
+		c.String(http.StatusOK, "pong")
+	})
+
+	for _, remaining := range []string{"2", "1", "0"} {
+		w := get(r, "")
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Equal(t, "3", w.Header().Get("X-RateLimit-Limit"))
+		assert.Equal(t, remaining, w.Header().Get("X-RateLimit-Remaining"))
+		assert.NotEmpty(t, w.Header().Get("X-RateLimit-Reset"))
+		assert.Empty(t, w.Header().Get("Retry-After"))
+	}
+
+	w := get(r, "")
+	assert.Equal(t, http.StatusTooManyRequests, w.Code)
+	assert.Equal(t, "3", w.Header().Get("X-RateLimit-Limit"))
+	assert.Equal(t, "0", w.Header().Get("X-RateLimit-Remaining"))
+	reset := w.Header().Get("X-RateLimit-Reset")
+	assert.NotEmpty(t, reset)
+	assert.Equal(t, reset, w.Header().Get("Retry-After"))
+}
@@ -3,20 +3,96 @@ package ratelimit
 import (
 	"context"
 	"fmt"
+	"math/rand"
 	"time"
 
 	"github.com/redis/go-redis/v9"
 )
 
+// Mode is how a RateLimiter counts requests.
+type Mode int
+
+const (
+	// SlidingWindow keeps one sorted set member per request in the window.
+	// Limits are exact at any point in time, but memory grows with the
+	// request rate, which an attacker controls.
+	SlidingWindow Mode = iota
+	// FixedWindow keeps one counter per key that starts with the first
+	// request and expires after the window. Memory is constant per key, but
+	// up to twice the limit can get through around a window boundary.
+	FixedWindow
+)
+
+// RateLimiter counts requests per key in redis. Counts are requests used: a
+// key with limit N may make N requests in a window, the one after that is
+// limited.
 type RateLimiter struct {
 	client *redis.Client
+	mode   Mode
 }
 
+// NewRateLimit returns a sliding window limiter.
 func NewRateLimit(client *redis.Client) *RateLimiter {
-	return &RateLimiter{client: client}
+	return NewRateLimitWithMode(client, SlidingWindow)
+}
+
+func NewRateLimitWithMode(client *redis.Client, mode Mode) *RateLimiter {
+	return &RateLimiter{client: client, mode: mode}
+}
+
+// allowScript prunes the window, counts what is left and records the request
+// only if it is under the limit, all in one step so concurrent requests can't
+// both get the last slot. It returns {allowed, used, oldest entry score}, the
+// oldest entry being the next one to leave the window.
+var allowScript = redis.NewScript(`
+local key = KEYS[1]
+local now = tonumber(ARGV[1])
+local window = tonumber(ARGV[2])
+local limit = tonumber(ARGV[3])
+
+redis.call('ZREMRANGEBYSCORE', key, 0, now - window)
+local count = redis.call('ZCARD', key)
+if count >= limit then
+	local oldest = redis.call('ZRANGE', key, 0, 0, 'WITHSCORES')
+	return {0, count, tonumber(oldest[2]) or now}
+end
+
+redis.call('ZADD', key, now, ARGV[4])
+redis.call('PEXPIRE', key, window + 60000)
+local oldest = redis.call('ZRANGE', key, 0, 0, 'WITHSCORES')
+return {1, count + 1, tonumber(oldest[2]) or now}
+`)
+
+// Allow records a request for key if fewer than limit requests were made in
+// the window. It returns whether the request is allowed, the requests used
+// in the window including this one, and how long until the oldest request
+// leaves the window and frees a slot.
+func (r *RateLimiter) Allow(ctx context.Context, key string, limit int, window time.Duration) (bool, int, time.Duration, error) {
+	if r.mode == FixedWindow {
+		return r.allowFixed(ctx, key, limit, window)
+	}
+
+	now := time.Now()
+	res, err := allowScript.Run(ctx, r.client, []string{key},
+		now.UnixMilli(), window.Milliseconds(), limit, member(now),
+	).Int64Slice()
+	if err != nil {
+		return false, 0, 0, err
+	}
+
+	resetTime := time.UnixMilli(res[2]).Add(window)
+	return res[0] == 1, int(res[1]), time.Until(resetTime), nil
 }
 
+// Check reports whether key has used up its limit in the window, without
+// recording a request. It also returns the requests used and, when limited,
+// how long until the oldest one leaves the window. Use Allow to check and
+// record a request atomically.
 func (r *RateLimiter) Check(ctx context.Context, key string, limit int, window time.Duration) (bool, int, time.Duration, error) {
+	if r.mode == FixedWindow {
+		return r.checkFixed(ctx, key, limit)
+	}
+
 	now := time.Now()
 	windowStart := now.Add(-window)
 
@@ -29,8 +105,8 @@ func (r *RateLimiter) Check(ctx context.Context, key string, limit int, window t
 		return false, 0, 0, err
 	}
 
-	// Check if limit exceeded
-	if int(count) > limit {
+	// Check if limit reached
+	if int(count) >= limit {
 		// Get oldest entry to calculate reset time
 		oldest, err := r.client.ZRangeWithScores(ctx, key, 0, 0).Result()
 		if err != nil {
@@ -48,15 +124,20 @@ func (r *RateLimiter) Check(ctx context.Context, key string, limit int, window t
 	return false, int(count), 0, nil
 }
 
+// Increment records a request for key without checking the limit.
 func (r *RateLimiter) Increment(ctx context.Context, key string, window time.Duration) error {
+	if r.mode == FixedWindow {
+		_, _, err := r.incrementFixed(ctx, key, window)
+		return err
+	}
+
 	now := time.Now()
-	member := fmt.Sprintf("%d", now.UnixNano())
 	score := float64(now.UnixMilli())
 
 	// Add new entry
 	_, err := r.client.ZAdd(ctx, key, redis.Z{
 		Score:  score,
-		Member: member,
+		Member: member(now),
 	}).Result()
 	if err != nil {
 		return err
@@ -67,6 +148,12 @@ func (r *RateLimiter) Increment(ctx context.Context, key string, window time.Dur
 	return err
 }
 
+// member makes a unique sorted set member for a request, requests made in the
+// same nanosecond would otherwise count once.
+func member(now time.Time) string {
+	return fmt.Sprintf("%d-%d", now.UnixNano(), rand.Int63())
+}
+
 // BlockKey blocks a key for a specific duration
 func (r *RateLimiter) BlockKey(ctx context.Context, key string, duration time.Duration) error {
 	_, err := r.client.Set(ctx, key, "blocked", duration).Result()
@@ -89,6 +176,11 @@ func (r *RateLimiter) IsKeyBlocked(ctx context.Context, key string) (bool, time.
 
 // GetRemainingAttempts gets remaining attempts for a key
 func (r *RateLimiter) GetRemainingAttempts(ctx context.Context, key string, limit int, window time.Duration) (int, error) {
+	if r.mode == FixedWindow {
+		_, count, _, err := r.checkFixed(ctx, key, limit)
+		return max(limit-count, 0), err
+	}
+
 	now := time.Now()
 	windowStart := now.Add(-window)
 
@@ -0,0 +1,167 @@
+package ratelimit
+
+import (
+	"context"
+	"herp/pkg/redis/redistest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var modes = []struct {
+	name string
+	mode Mode
+}{
+	{"sliding window", SlidingWindow},
+	{"fixed window", FixedWindow},
+}
+
+func TestMemberIsUnique(t *testing.T) {
+	now := time.Now()
+	seen := map[string]bool{}
+	for range 1000 {
+		m := member(now)
+		assert.False(t, seen[m], "member %s made twice", m)
+		seen[m] = true
+	}
+}
+
+func TestAllowLimitIsExact(t *testing.T) {
+	for _, tt := range modes {
+		t.Run(tt.name, func(t *testing.T) {
+			limiter := NewRateLimitWithMode(redistest.Client(t), tt.mode)
+			ctx := context.Background()
+
+			for n := 1; n <= 3; n++ {
+				limited, used, _, err := limiter.Check(ctx, "key", 3, time.Minute)
+				require.NoError(t, err)
+				assert.False(t, limited, "request %d", n)
+				assert.Equal(t, n-1, used)
+
+				allowed, used, _, err := limiter.Allow(ctx, "key", 3, time.Minute)
+				require.NoError(t, err)
+				assert.True(t, allowed, "request %d", n)
+				assert.Equal(t, n, used)
+			}
+
+			// limited at exactly the limit, not one after it
+			limited, used, reset, err := limiter.Check(ctx, "key", 3, time.Minute)
+			require.NoError(t, err)
+			assert.True(t, limited)
+			assert.Equal(t, 3, used)
+			assert.InDelta(t, time.Minute.Seconds(), reset.Seconds(), 2)
+
+			allowed, _, _, err := limiter.Allow(ctx, "key", 3, time.Minute)
+			require.NoError(t, err)
+			assert.False(t, allowed)
+
+			remaining, err := limiter.GetRemainingAttempts(ctx, "key", 3, time.Minute)
+			require.NoError(t, err)
+			assert.Zero(t, remaining)
+
+			// other keys have their own count
+			allowed, _, _, err = limiter.Allow(ctx, "other", 3, time.Minute)
+			require.NoError(t, err)
+			assert.True(t, allowed)
+		})
+	}
+}
+
+func TestAllowWindowExpires(t *testing.T) {
+	for _, tt := range modes {
+		t.Run(tt.name, func(t *testing.T) {
+			limiter := NewRateLimitWithMode(redistest.Client(t), tt.mode)
+			ctx := context.Background()
+			window := 200 * time.Millisecond
+
+			for range 2 {
+				allowed, _, _, err := limiter.Allow(ctx, "key", 2, window)
+				require.NoError(t, err)
+				assert.True(t, allowed)
+			}
+			allowed, _, _, err := limiter.Allow(ctx, "key", 2, window)
+			require.NoError(t, err)
+			assert.False(t, allowed)
+
+			time.Sleep(window + 50*time.Millisecond)
+
+			allowed, _, _, err = limiter.Allow(ctx, "key", 2, window)
+			require.NoError(t, err)
+			assert.True(t, allowed)
+		})
+	}
+}
+
+func TestAllowConcurrent(t *testing.T) {
+	const (
+		limit    = 10
+		requests = 100
+	)
+	for _, tt := range modes {
+		t.Run(tt.name, func(t *testing.T) {
+			limiter := NewRateLimitWithMode(redistest.Client(t), tt.mode)
+			ctx := context.Background()
+
+			var allowed, errs atomic.Int32
+			var wg sync.WaitGroup
+			start := make(chan struct{})
+			for range requests {
+				wg.Add(1)
+				go func() {
+					defer wg.Done()
+					<-start
+					ok, _, _, err := limiter.Allow(ctx, "key", limit, time.Minute)
+					if err != nil {
+						errs.Add(1)
+						return
+					}
+					if ok {
+						allowed.Add(1)
+					}
+				}()
+			}
+			close(start)
+			wg.Wait()
+
+			require.Zero(t, errs.Load())
+			// no two requests got the last slot
+			assert.Equal(t, int32(limit), allowed.Load())
+		})
+	}
+}
+
+// BenchmarkAllow compares the modes under one key taking a flood of
+// requests. Besides the time per request it reports the memory the key takes
+// in Redis once the flood is over, which grows with the requests for the
+// sliding window and stays put for the fixed window.
+func BenchmarkAllow(b *testing.B) {
+	for _, tt := range modes {
+		b.Run(tt.name, func(b *testing.B) {
+			client := redistest.Client(b)
+			limiter := NewRateLimitWithMode(client, tt.mode)
+			ctx := context.Background()
+
+			b.ResetTimer()
+			for range b.N {
+				if _, _, _, err := limiter.Allow(ctx, "flood", b.N, time.Minute); err != nil {
+					b.Fatal(err)
+				}
+			}
+			b.StopTimer()
+
+			key := "flood"
+			if tt.mode == FixedWindow {
+				key = fixedKey(key)
+			}
+			bytes, err := client.MemoryUsage(ctx, key).Result()
+			if err != nil {
+				b.Fatal(err)
+			}
+			b.ReportMetric(float64(bytes), "key-bytes")
+		})
+	}
+}
@@ -46,6 +46,11 @@ func NewRedis(config RedisConfig) (*Redis, error) {
 	}, nil
 }
 
+// NewRedisFromClient wraps a client that is already connected.
+func NewRedisFromClient(client *redis.Client) *Redis {
+	return &Redis{client: client}
+}
+
 func (r *Redis) RawClient() *redis.Client {
     return r.client
 }
@@ -58,6 +63,11 @@ func (r *Redis) Set(ctx context.Context, key string, value any, expiration time.
 	return r.client.Set(ctx, key, value, expiration).Err()
 }
 
+// SetNX sets key only when it doesn't exist yet and reports whether it did.
+func (r *Redis) SetNX(ctx context.Context, key string, value any, expiration time.Duration) (bool, error) {
+	return r.client.SetNX(ctx, key, value, expiration).Result()
+}
+
 func (r *Redis) Delete(ctx context.Context, keys ...string) error {
 	return r.client.Del(ctx, keys...).Err()
 }
@@ -75,6 +85,16 @@ func (r *Redis) Decr(ctx context.Context, key string) error {
 	return r.client.Decr(ctx, key).Err()
 }
 
+// Name names Redis in health checks
+func (r *Redis) Name() string {
+	return "redis"
+}
+
+// HealthCheck pings Redis
+func (r *Redis) HealthCheck(ctx context.Context) error {
+	return r.client.Ping(ctx).Err()
+}
+
 func (c *Redis) Close() error {
 	return c.client.Close()
 }
@@ -0,0 +1,20 @@
+package redis
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHealthCheckDown(t *testing.T) {
+	// nothing listens on port 1
+	client := redis.NewClient(&redis.Options{Addr: "127.0.0.1:1", DialTimeout: time.Second, MaxRetries: -1})
+	r := &Redis{client: client}
+	defer r.Close()
+
+	assert.Equal(t, "redis", r.Name())
+	assert.Error(t, r.HealthCheck(context.Background()))
+}
@@ -0,0 +1,36 @@
+// Package redistest runs tests against a real Redis, for the behaviour that
+// lives in Lua scripts and key expiry. The server is the one TEST_REDIS_URL
+// points at, its database is flushed before each test. Tests using it are
+// skipped when the variable is not set.
+package redistest
+
+import (
+	"context"
+	"os"
+	"testing"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// Client returns a client of the test database with every key removed. The
+// database is shared by the packages under test, so they have to be run one
+// at a time, with go test -p 1.
+func Client(t testing.TB) *redis.Client {
+	t.Helper()
+	url := os.Getenv("TEST_REDIS_URL")
+	if url == "" {
+		t.Skip("TEST_REDIS_URL is not set")
+	}
+
+	opts, err := redis.ParseURL(url)
+	if err != nil {
+		t.Fatalf("parse TEST_REDIS_URL: %v", err)
+	}
+	client := redis.NewClient(opts)
+	t.Cleanup(func() { client.Close() })
+
+	if err := client.FlushDB(context.Background()).Err(); err != nil {
+		t.Fatalf("empty test redis: %v", err)
+	}
+	return client
+}
@@ -0,0 +1,64 @@
+package storage
+
+import (
+	"context"
+	"io"
+	"io/fs"
+	"net/url"
+	"strings"
+)
+
+// FileStore keeps files that are served to clients, such as logos. Save
+// returns the URL the file can be fetched from.
+type FileStore interface {
+	Save(ctx context.Context, key string, r io.Reader, contentType string) (string, error)
+	// Open opens a file by the URL Save returned for it, ErrNotFound for
+	// URLs not served from this store
+	Open(ctx context.Context, fileURL string) (io.ReadCloser, error)
+	Delete(ctx context.Context, key string) error
+}
+
+// Public is a FileStore on top of a Storage whose files are served from
+// baseURL, e.g. a bucket URL or a CDN in front of it. An empty baseURL gives
+// root relative URLs, for files the API serves itself.
+type Public struct {
+	storage Storage
+	baseURL string
+}
+
+func NewPublic(storage Storage, baseURL string) *Public {
+	return &Public{storage: storage, baseURL: strings.TrimSuffix(baseURL, "/")}
+}
+
+func (p *Public) Save(ctx context.Context, key string, r io.Reader, contentType string) (string, error) {
+	body, err := io.ReadAll(r)
+	if err != nil {
+		return "", err
+	}
+	if err := p.storage.Put(ctx, key, body, contentType); err != nil {
+		return "", err
+	}
+	return p.URL(key), nil
+}
+
+func (p *Public) Open(ctx context.Context, fileURL string) (io.ReadCloser, error) {
+	rest, ok := strings.CutPrefix(fileURL, p.baseURL+"/")
+	if !ok {
+		return nil, ErrNotFound
+	}
+	// keys never climb out of the store, whatever the URL says
+	key, err := url.PathUnescape(rest)
+	if err != nil || !fs.ValidPath(key) || key == "." {
+		return nil, ErrNotFound
+	}
+	return p.storage.Get(ctx, key)
+}
+
+func (p *Public) Delete(ctx context.Context, key string) error {
+	return p.storage.Delete(ctx, key)
+}
+
+// URL is where the file under key is served from.
+func (p *Public) URL(key string) string {
+	return p.baseURL + (&url.URL{Path: "/" + key}).EscapedPath()
+}
@@ -0,0 +1,64 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPublicURL(t *testing.T) {
+	tests := []struct {
+		baseURL string
+		key     string
+		want    string
+	}{
+		{"", "images/logo.png", "/images/logo.png"},
+		{"https://cdn.example.com/", "images/logo.png", "https://cdn.example.com/images/logo.png"},
+		{"https://cdn.example.com", "images/my logo.png", "https://cdn.example.com/images/my%20logo.png"},
+	}
+	for _, tt := range tests {
+		assert.Equal(t, tt.want, NewPublic(NewLocal(t.TempDir()), tt.baseURL).URL(tt.key))
+	}
+}
+
+func TestPublicSaveOpenDelete(t *testing.T) {
+	files := NewPublic(NewLocal(t.TempDir()), "https://cdn.example.com")
+	ctx := context.Background()
+
+	url, err := files.Save(ctx, "images/my logo.png", strings.NewReader("logo"), "image/png")
+	require.NoError(t, err)
+	assert.Equal(t, "https://cdn.example.com/images/my%20logo.png", url)
+
+	r, err := files.Open(ctx, url)
+	require.NoError(t, err)
+	body, err := io.ReadAll(r)
+	r.Close()
+	require.NoError(t, err)
+	assert.Equal(t, "logo", string(body))
+
+	require.NoError(t, files.Delete(ctx, "images/my logo.png"))
+	_, err = files.Open(ctx, url)
+	assert.True(t, errors.Is(err, ErrNotFound), "got %v", err)
+}
+
+func TestPublicOpenOutside(t *testing.T) {
+	dir := t.TempDir()
+	local := NewLocal(dir + "/files")
+	require.NoError(t, NewLocal(dir).Put(context.Background(), "secret.txt", []byte("secret"), "text/plain"))
+	files := NewPublic(local, "https://cdn.example.com")
+
+	for _, url := range []string{
+		"https://elsewhere.example.com/images/logo.png",
+		"https://cdn.example.com/../secret.txt",
+		"https://cdn.example.com/%2e%2e/secret.txt",
+		"https://cdn.example.com/",
+	} {
+		_, err := files.Open(context.Background(), url)
+		assert.ErrorIs(t, err, ErrNotFound, url)
+	}
+}
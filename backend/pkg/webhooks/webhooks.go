@@ -0,0 +1,244 @@
+package webhooks
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Events subscriptions can be made to.
+const (
+	SaleCreated   = "sale.created"
+	RefundCreated = "refund.created"
+	LowStock      = "inventory.low_stock"
+)
+
+var Events = []string{SaleCreated, RefundCreated, LowStock}
+
+var ErrQueueFull = errors.New("webhook queue is full")
+
+// SignatureHeader carries the hex HMAC-SHA256 of the body keyed by the secret
+// of the subscription, prefixed with "sha256=".
+const SignatureHeader = "X-Webhook-Signature"
+
+// Subscription is an endpoint events are delivered to.
+type Subscription struct {
+	ID     int32
+	URL    string
+	Secret string
+}
+
+// Attempt is one try at delivering an event to a subscription.
+type Attempt struct {
+	SubscriptionID int32
+	EventID        string
+	Event          string
+	Attempt        int
+	StatusCode     int // 0 when no response was received
+	Error          string
+	Delivered      bool
+}
+
+// Store gives the subscriptions of a business to an event and keeps the
+// delivery attempts.
+type Store interface {
+	Subscriptions(ctx context.Context, businessID int32, event string) ([]Subscription, error)
+	RecordAttempt(ctx context.Context, attempt Attempt) error
+}
+
+// Payload is the body POSTed to subscriptions.
+type Payload struct {
+	ID        string    `json:"id"`
+	Event     string    `json:"event"`
+	CreatedAt time.Time `json:"created_at"`
+	Data      any       `json:"data"`
+}
+
+type Options struct {
+	MaxAttempts int           // attempts per subscription before giving up
+	Backoff     time.Duration // wait before the second attempt, doubled after each
+	Timeout     time.Duration // of a single attempt
+	QueueSize   int
+	Workers     int
+}
+
+// job is an event to fan out to its subscriptions when sub is nil,
+// otherwise an attempt at delivering it to sub.
+type job struct {
+	eventID    string
+	businessID int32
+	event      string
+	body       []byte
+	sub        *Subscription
+	attempt    int
+}
+
+// Dispatcher delivers events to the subscriptions of a Store in the
+// background. Failed deliveries are retried with exponential backoff up to
+// MaxAttempts, every attempt is recorded. Retries still waiting when the
+// dispatcher stops are dropped.
+type Dispatcher struct {
+	store   Store
+	client  *http.Client
+	opts    Options
+	queue   chan job
+	ctx     context.Context
+	onError func(error)
+}
+
+func NewDispatcher(store Store, opts Options) *Dispatcher {
+	return &Dispatcher{
+		store:  store,
+		client: &http.Client{Timeout: opts.Timeout},
+		opts:   opts,
+		queue:  make(chan job, opts.QueueSize),
+		ctx:    context.Background(),
+	}
+}
+
+// Start runs the workers until ctx is done. Errors of the store and events
+// dropped on a full queue are passed to onError. The returned channel is
+// closed once every worker has finished its delivery and stopped.
+func (d *Dispatcher) Start(ctx context.Context, onError func(error)) <-chan struct{} {
+	d.ctx = ctx
+	d.onError = onError
+	var wg sync.WaitGroup
+	for range max(d.opts.Workers, 1) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for {
+				select {
+				case <-ctx.Done():
+					return
+				case j := <-d.queue:
+					d.run(j)
+				}
+			}
+		}()
+	}
+
+	done := make(chan struct{})
+	go func() {
+		wg.Wait()
+		close(done)
+	}()
+	return done
+}
+
+// Publish queues an event of a business for delivery to the subscriptions
+// of that business, it never blocks the caller.
+func (d *Dispatcher) Publish(ctx context.Context, businessID int32, event string, data any) {
+	payload := Payload{
+		ID:        uuid.NewString(),
+		Event:     event,
+		CreatedAt: time.Now().UTC(),
+		Data:      data,
+	}
+	body, err := json.Marshal(payload)
+	if err != nil {
+		d.fail(fmt.Errorf("encode %s event: %w", event, err))
+		return
+	}
+	d.enqueue(job{eventID: payload.ID, businessID: businessID, event: event, body: body})
+}
+
+// Sign returns the value of SignatureHeader for a body.
+func Sign(secret string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+func (d *Dispatcher) enqueue(j job) {
+	select {
+	case d.queue <- j:
+	default:
+		d.fail(fmt.Errorf("%w: dropped %s event %s", ErrQueueFull, j.event, j.eventID))
+	}
+}
+
+func (d *Dispatcher) fail(err error) {
+	if d.onError != nil {
+		d.onError(err)
+	}
+}
+
+func (d *Dispatcher) run(j job) {
+	if j.sub == nil {
+		subs, err := d.store.Subscriptions(d.ctx, j.businessID, j.event)
+		if err != nil {
+			d.fail(fmt.Errorf("list subscriptions of %s for business %d: %w", j.event, j.businessID, err))
+			return
+		}
+		for i := range subs {
+			next := j
+			next.sub = &subs[i]
+			next.attempt = 1
+			d.enqueue(next)
+		}
+		return
+	}
+
+	attempt := d.deliver(j)
+	if err := d.store.RecordAttempt(d.ctx, attempt); err != nil {
+		d.fail(fmt.Errorf("record webhook attempt: %w", err))
+	}
+	if attempt.Delivered || j.attempt >= d.opts.MaxAttempts {
+		return
+	}
+
+	next := j
+	next.attempt++
+	wait := d.opts.Backoff << (j.attempt - 1)
+	time.AfterFunc(wait, func() {
+		if d.ctx.Err() == nil {
+			d.enqueue(next)
+		}
+	})
+}
+
+func (d *Dispatcher) deliver(j job) Attempt {
+	attempt := Attempt{
+		SubscriptionID: j.sub.ID,
+		EventID:        j.eventID,
+		Event:          j.event,
+		Attempt:        j.attempt,
+	}
+
+	req, err := http.NewRequestWithContext(d.ctx, http.MethodPost, j.sub.URL, bytes.NewReader(j.body))
+	if err != nil {
+		attempt.Error = err.Error()
+		return attempt
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-Webhook-Event", j.event)
+	req.Header.Set("X-Webhook-ID", j.eventID)
+	req.Header.Set(SignatureHeader, Sign(j.sub.Secret, j.body))
+
+	resp, err := d.client.Do(req)
+	if err != nil {
+		attempt.Error = err.Error()
+		return attempt
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+
+	attempt.StatusCode = resp.StatusCode
+	attempt.Delivered = resp.StatusCode >= 200 && resp.StatusCode < 300
+	if !attempt.Delivered {
+		attempt.Error = fmt.Sprintf("unexpected status %d", resp.StatusCode)
+	}
+	return attempt
+}
@@ -0,0 +1,214 @@
+package webhooks
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeStore holds subscriptions by business and passes the recorded
+// attempts on to the test.
+type fakeStore struct {
+	mu       sync.Mutex
+	subs     map[int32][]Subscription
+	attempts chan Attempt
+}
+
+func newFakeStore(subs map[int32][]Subscription) *fakeStore {
+	return &fakeStore{subs: subs, attempts: make(chan Attempt, 16)}
+}
+
+func (s *fakeStore) Subscriptions(ctx context.Context, businessID int32, event string) ([]Subscription, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]Subscription(nil), s.subs[businessID]...), nil
+}
+
+func (s *fakeStore) RecordAttempt(ctx context.Context, attempt Attempt) error {
+	s.attempts <- attempt
+	return nil
+}
+
+func (s *fakeStore) next(t *testing.T) Attempt {
+	t.Helper()
+	select {
+	case attempt := <-s.attempts:
+		return attempt
+	case <-time.After(2 * time.Second):
+		t.Fatal("no delivery attempt recorded")
+		return Attempt{}
+	}
+}
+
+func startDispatcher(t *testing.T, store Store, opts Options) *Dispatcher {
+	t.Helper()
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+
+	d := NewDispatcher(store, opts)
+	d.Start(ctx, func(err error) { t.Errorf("dispatcher error: %v", err) })
+	return d
+}
+
+func testOptions() Options {
+	return Options{MaxAttempts: 3, Backoff: time.Millisecond, Timeout: time.Second, QueueSize: 16, Workers: 2}
+}
+
+func TestSign(t *testing.T) {
+	// HMAC-SHA256 of "{}" keyed by "secret"
+	assert.Equal(t, "sha256=77325902caca812dc259733aacd046b73817372c777b8d95b402647474516e13", Sign("secret", []byte("{}")))
+	assert.NotEqual(t, Sign("secret", []byte("{}")), Sign("other", []byte("{}")))
+	assert.NotEqual(t, Sign("secret", []byte("{}")), Sign("secret", []byte("[]")))
+}
+
+func TestDispatcherSignsDeliveries(t *testing.T) {
+	const secret = "0123456789abcdef"
+
+	received := make(chan *http.Request, 1)
+	bodies := make(chan []byte, 1)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		received <- r
+		bodies <- body
+	}))
+	defer server.Close()
+
+	store := newFakeStore(map[int32][]Subscription{1: {{ID: 7, URL: server.URL, Secret: secret}}})
+	d := startDispatcher(t, store, testOptions())
+
+	d.Publish(context.Background(), 1, SaleCreated, map[string]int{"id": 42})
+
+	attempt := store.next(t)
+	assert.True(t, attempt.Delivered)
+	assert.Equal(t, int32(7), attempt.SubscriptionID)
+	assert.Equal(t, 1, attempt.Attempt)
+	assert.Equal(t, http.StatusOK, attempt.StatusCode)
+
+	r := <-received
+	body := <-bodies
+	assert.Equal(t, Sign(secret, body), r.Header.Get(SignatureHeader))
+	assert.Equal(t, SaleCreated, r.Header.Get("X-Webhook-Event"))
+	assert.Equal(t, attempt.EventID, r.Header.Get("X-Webhook-ID"))
+	assert.Equal(t, "application/json", r.Header.Get("Content-Type"))
+
+	var payload struct {
+		ID    string         `json:"id"`
+		Event string         `json:"event"`
+		Data  map[string]int `json:"data"`
+	}
+	require.NoError(t, json.Unmarshal(body, &payload))
+	assert.Equal(t, attempt.EventID, payload.ID)
+	assert.Equal(t, SaleCreated, payload.Event)
+	assert.Equal(t, 42, payload.Data["id"])
+}
+
+func TestDispatcherRetriesFailedDeliveries(t *testing.T) {
+	var calls atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if calls.Add(1) < 3 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+	}))
+	defer server.Close()
+
+	store := newFakeStore(map[int32][]Subscription{1: {{ID: 1, URL: server.URL, Secret: "secret"}}})
+	d := startDispatcher(t, store, testOptions())
+
+	d.Publish(context.Background(), 1, RefundCreated, nil)
+
+	for n := 1; n <= 2; n++ {
+		attempt := store.next(t)
+		assert.Equal(t, n, attempt.Attempt)
+		assert.False(t, attempt.Delivered)
+		assert.Equal(t, http.StatusServiceUnavailable, attempt.StatusCode)
+		assert.Equal(t, "unexpected status 503", attempt.Error)
+	}
+	attempt := store.next(t)
+	assert.Equal(t, 3, attempt.Attempt)
+	assert.True(t, attempt.Delivered)
+	assert.Empty(t, attempt.Error)
+	assert.Equal(t, int32(3), calls.Load())
+}
+
+func TestDispatcherGivesUpAfterMaxAttempts(t *testing.T) {
+	var calls atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer server.Close()
+
+	store := newFakeStore(map[int32][]Subscription{1: {{ID: 1, URL: server.URL, Secret: "secret"}}})
+	opts := testOptions()
+	opts.MaxAttempts = 2
+	d := startDispatcher(t, store, opts)
+
+	d.Publish(context.Background(), 1, LowStock, nil)
+
+	assert.Equal(t, 1, store.next(t).Attempt)
+	assert.Equal(t, 2, store.next(t).Attempt)
+
+	select {
+	case attempt := <-store.attempts:
+		t.Fatalf("unexpected attempt %d after the last one", attempt.Attempt)
+	case <-time.After(50 * time.Millisecond):
+	}
+	assert.Equal(t, int32(2), calls.Load())
+}
+
+func TestDispatcherDeliversOnlyToTheBusiness(t *testing.T) {
+	var other atomic.Int32
+	otherServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		other.Add(1)
+	}))
+	defer otherServer.Close()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer server.Close()
+
+	store := newFakeStore(map[int32][]Subscription{
+		1: {{ID: 1, URL: server.URL, Secret: "secret"}},
+		2: {{ID: 2, URL: otherServer.URL, Secret: "secret"}},
+	})
+	d := startDispatcher(t, store, testOptions())
+
+	d.Publish(context.Background(), 1, SaleCreated, nil)
+
+	attempt := store.next(t)
+	assert.Equal(t, int32(1), attempt.SubscriptionID)
+	assert.True(t, attempt.Delivered)
+
+	select {
+	case attempt := <-store.attempts:
+		t.Fatalf("unexpected delivery to subscription %d", attempt.SubscriptionID)
+	case <-time.After(50 * time.Millisecond):
+	}
+	assert.Zero(t, other.Load())
+}
+
+func TestDispatcherStops(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	d := NewDispatcher(newFakeStore(nil), testOptions())
+	done := d.Start(ctx, func(err error) { t.Errorf("dispatcher error: %v", err) })
+
+	select {
+	case <-done:
+		t.Fatal("stopped before being cancelled")
+	default:
+	}
+
+	cancel()
+	select {
+	case <-done:
+	case <-time.After(2 * time.Second):
+		t.Fatal("dispatcher did not stop")
+	}
+}